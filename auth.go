@@ -3,6 +3,7 @@ package influxdb
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // AuthorizationKind is returned by (*Authorization).Kind().
@@ -23,6 +24,15 @@ type Authorization struct {
 	OrgID       ID           `json:"orgID"`
 	UserID      ID           `json:"userID,omitempty"`
 	Permissions []Permission `json:"permissions"`
+
+	// Scopes are the OAuth 2.0 scope strings, such as "read:buckets", the
+	// authorization was requested with. The permissions they grant were added
+	// to Permissions when the authorization was created.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// MaxBytesPerDay is the maximum number of bytes that may be written
+	// using this authorization within a 24 hour window. Zero is unlimited.
+	MaxBytesPerDay int64 `json:"maxBytesPerDay,omitempty"`
 	CRUDLog
 }
 
@@ -120,3 +130,30 @@ type AuthorizationFilter struct {
 	OrgID *ID
 	Org   *string
 }
+
+// ErrWriteUsageNotFound is the error message for a missing write usage.
+const ErrWriteUsageNotFound = "write usage not found"
+
+// WriteUsage is the number of bytes written using an authorization in each
+// hour of the last day, as counted to enforce its MaxBytesPerDay.
+type WriteUsage struct {
+	AuthorizationID ID               `json:"authorizationID"`
+	Hours           []WriteUsageHour `json:"hours"`
+}
+
+// WriteUsageHour is the number of bytes written in the hour starting at Start.
+type WriteUsageHour struct {
+	Start time.Time `json:"start"`
+	Bytes int64     `json:"bytes"`
+}
+
+// WriteUsageService stores the write usage of authorizations, so that their
+// MaxBytesPerDay is enforced across restarts.
+type WriteUsageService interface {
+	// FindWriteUsage returns the write usage of an authorization.
+	FindWriteUsage(ctx context.Context, authID ID) (*WriteUsage, error)
+
+	// PutWriteUsage replaces the write usage of an authorization. A usage
+	// without hours is removed.
+	PutWriteUsage(ctx context.Context, u *WriteUsage) error
+}
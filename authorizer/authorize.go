@@ -11,15 +11,25 @@ import (
 func isAllowedAll(a influxdb.Authorizer, permissions []influxdb.Permission) error {
 	for _, p := range permissions {
 		if !a.Allowed(p) {
-			return &influxdb.Error{
-				Code: influxdb.EUnauthorized,
-				Msg:  fmt.Sprintf("%s is unauthorized", p),
-			}
+			return unauthorizedError(p)
 		}
 	}
 	return nil
 }
 
+// unauthorizedError returns the error for a permission that is not allowed.
+// The name of the resource is included when it is known.
+func unauthorizedError(p influxdb.Permission) error {
+	msg := fmt.Sprintf("%s is unauthorized", p)
+	if p.Resource.Name != "" {
+		msg = fmt.Sprintf("unauthorized: %s %s", p.Action, p.Resource)
+	}
+	return &influxdb.Error{
+		Code: influxdb.EUnauthorized,
+		Msg:  msg,
+	}
+}
+
 func isAllowed(a influxdb.Authorizer, p influxdb.Permission) error {
 	return isAllowedAll(a, []influxdb.Permission{p})
 }
@@ -59,6 +69,12 @@ func IsAllowedAny(ctx context.Context, permissions []influxdb.Permission) error
 }
 
 func authorize(ctx context.Context, a influxdb.Action, rt influxdb.ResourceType, rid, oid *influxdb.ID) (influxdb.Authorizer, influxdb.Permission, error) {
+	return authorizeNamed(ctx, a, rt, rid, oid, "")
+}
+
+// authorizeNamed is authorize for a resource whose name is known. The name is
+// included in the error returned when the action is unauthorized.
+func authorizeNamed(ctx context.Context, a influxdb.Action, rt influxdb.ResourceType, rid, oid *influxdb.ID, name string) (influxdb.Authorizer, influxdb.Permission, error) {
 	var p *influxdb.Permission
 	var err error
 	if rid != nil && oid != nil {
@@ -73,6 +89,7 @@ func authorize(ctx context.Context, a influxdb.Action, rt influxdb.ResourceType,
 	if err != nil {
 		return nil, influxdb.Permission{}, err
 	}
+	p.Resource.Name = name
 	auth, err := icontext.GetAuthorizer(ctx)
 	if err != nil {
 		return nil, influxdb.Permission{}, err
@@ -32,7 +32,7 @@ func (s *BucketService) FindBucketByID(ctx context.Context, id influxdb.ID) (*in
 	if err != nil {
 		return nil, err
 	}
-	if _, _, err := AuthorizeReadBucket(ctx, b.Type, b.ID, b.OrgID); err != nil {
+	if err := authorizeReadBucket(ctx, b); err != nil {
 		return nil, err
 	}
 	return b, nil
@@ -47,7 +47,7 @@ func (s *BucketService) FindBucketByName(ctx context.Context, orgID influxdb.ID,
 	if err != nil {
 		return nil, err
 	}
-	if _, _, err := AuthorizeReadBucket(ctx, b.Type, b.ID, b.OrgID); err != nil {
+	if err := authorizeReadBucket(ctx, b); err != nil {
 		return nil, err
 	}
 	return b, nil
@@ -62,7 +62,7 @@ func (s *BucketService) FindBucket(ctx context.Context, filter influxdb.BucketFi
 	if err != nil {
 		return nil, err
 	}
-	if _, _, err := AuthorizeReadBucket(ctx, b.Type, b.ID, b.OrgID); err != nil {
+	if err := authorizeReadBucket(ctx, b); err != nil {
 		return nil, err
 	}
 	return b, nil
@@ -99,7 +99,7 @@ func (s *BucketService) UpdateBucket(ctx context.Context, id influxdb.ID, upd in
 	if err != nil {
 		return nil, err
 	}
-	if _, _, err := AuthorizeWrite(ctx, influxdb.BucketsResourceType, id, b.OrgID); err != nil {
+	if _, _, err := authorizeNamed(ctx, influxdb.WriteAction, influxdb.BucketsResourceType, &id, &b.OrgID, b.Name); err != nil {
 		return nil, err
 	}
 	return s.s.UpdateBucket(ctx, id, upd)
@@ -111,8 +111,19 @@ func (s *BucketService) DeleteBucket(ctx context.Context, id influxdb.ID) error
 	if err != nil {
 		return err
 	}
-	if _, _, err := AuthorizeWrite(ctx, influxdb.BucketsResourceType, id, b.OrgID); err != nil {
+	if _, _, err := authorizeNamed(ctx, influxdb.WriteAction, influxdb.BucketsResourceType, &id, &b.OrgID, b.Name); err != nil {
 		return err
 	}
 	return s.s.DeleteBucket(ctx, id)
 }
+
+// authorizeReadBucket authorizes reading the bucket, including the bucket's
+// name in the error returned when it is unauthorized.
+func authorizeReadBucket(ctx context.Context, b *influxdb.Bucket) error {
+	if b.Type == influxdb.BucketTypeSystem {
+		_, _, err := AuthorizeReadBucket(ctx, b.Type, b.ID, b.OrgID)
+		return err
+	}
+	_, _, err := authorizeNamed(ctx, influxdb.ReadAction, influxdb.BucketsResourceType, &b.ID, &b.OrgID, b.Name)
+	return err
+}
@@ -425,6 +425,38 @@ func TestBucketService_UpdateBucket(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "unauthorized to update named bucket",
+			fields: fields{
+				BucketService: &mock.BucketService{
+					FindBucketByIDFn: func(ctc context.Context, id influxdb.ID) (*influxdb.Bucket, error) {
+						return &influxdb.Bucket{
+							ID:    1,
+							OrgID: 10,
+							Name:  "my-bucket",
+						}, nil
+					},
+				},
+			},
+			args: args{
+				id: 1,
+				permissions: []influxdb.Permission{
+					{
+						Action: "read",
+						Resource: influxdb.Resource{
+							Type: influxdb.BucketsResourceType,
+							ID:   influxdbtesting.IDPtr(1),
+						},
+					},
+				},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "unauthorized: write bucket my-bucket (id: 0000000000000001)",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
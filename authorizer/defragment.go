@@ -0,0 +1,34 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.DefragmentService = (*DefragmentService)(nil)
+
+// DefragmentService wraps a influxdb.DefragmentService and authorizes actions
+// against it appropriately.
+type DefragmentService struct {
+	s influxdb.DefragmentService
+}
+
+// NewDefragmentService constructs an instance of an authorizing defragment service.
+func NewDefragmentService(s influxdb.DefragmentService) *DefragmentService {
+	return &DefragmentService{
+		s: s,
+	}
+}
+
+// Defragment checks the authorizer is an operator before defragmenting the bucket.
+func (s DefragmentService) Defragment(ctx context.Context, orgID, bucketID influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return s.s.Defragment(ctx, orgID, bucketID)
+}
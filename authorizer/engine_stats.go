@@ -0,0 +1,34 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.EngineStatsService = (*EngineStatsService)(nil)
+
+// EngineStatsService wraps a influxdb.EngineStatsService and authorizes actions
+// against it appropriately.
+type EngineStatsService struct {
+	s influxdb.EngineStatsService
+}
+
+// NewEngineStatsService constructs an instance of an authorizing engine stats service.
+func NewEngineStatsService(s influxdb.EngineStatsService) *EngineStatsService {
+	return &EngineStatsService{
+		s: s,
+	}
+}
+
+// EngineStats checks the authorizer is an operator before returning the engine statistics.
+func (s EngineStatsService) EngineStats(ctx context.Context) (*influxdb.EngineStats, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.EngineStats(ctx)
+}
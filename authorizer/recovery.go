@@ -0,0 +1,34 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.RecoveryService = (*RecoveryService)(nil)
+
+// RecoveryService wraps a influxdb.RecoveryService and authorizes actions
+// against it appropriately.
+type RecoveryService struct {
+	s influxdb.RecoveryService
+}
+
+// NewRecoveryService constructs an instance of an authorizing recovery service.
+func NewRecoveryService(s influxdb.RecoveryService) *RecoveryService {
+	return &RecoveryService{
+		s: s,
+	}
+}
+
+// RecoveryReport checks the authorizer is an operator before returning the report.
+func (s RecoveryService) RecoveryReport(ctx context.Context) (*influxdb.RecoveryReport, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.RecoveryReport(ctx)
+}
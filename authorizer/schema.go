@@ -0,0 +1,95 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+)
+
+var (
+	_ influxdb.SchemaService       = (*SchemaService)(nil)
+	_ influxdb.BucketSchemaService = (*BucketSchemaService)(nil)
+)
+
+// SchemaService wraps a influxdb.SchemaService and authorizes actions
+// against it appropriately.
+type SchemaService struct {
+	s influxdb.SchemaService
+}
+
+// NewSchemaService constructs an instance of an authorizing schema service.
+func NewSchemaService(s influxdb.SchemaService) *SchemaService {
+	return &SchemaService{
+		s: s,
+	}
+}
+
+// CreateMeasurementSchema checks to see if the authorizer on context has write access to the bucket provided.
+func (s *SchemaService) CreateMeasurementSchema(ctx context.Context, orgID, bucketID influxdb.ID, schema influxdb.MeasurementSchema) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if _, _, err := AuthorizeWrite(ctx, influxdb.BucketsResourceType, bucketID, orgID); err != nil {
+		return err
+	}
+	return s.s.CreateMeasurementSchema(ctx, orgID, bucketID, schema)
+}
+
+// FindMeasurementSchema checks to see if the authorizer on context has read access to the bucket provided.
+func (s *SchemaService) FindMeasurementSchema(ctx context.Context, orgID, bucketID influxdb.ID, measurement string) (*influxdb.MeasurementSchema, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if _, _, err := AuthorizeRead(ctx, influxdb.BucketsResourceType, bucketID, orgID); err != nil {
+		return nil, err
+	}
+	return s.s.FindMeasurementSchema(ctx, orgID, bucketID, measurement)
+}
+
+// ValidatePoint checks to see if the authorizer on context has read access to the bucket provided.
+func (s *SchemaService) ValidatePoint(ctx context.Context, orgID, bucketID influxdb.ID, p models.Point) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if _, _, err := AuthorizeRead(ctx, influxdb.BucketsResourceType, bucketID, orgID); err != nil {
+		return err
+	}
+	return s.s.ValidatePoint(ctx, orgID, bucketID, p)
+}
+
+// BucketSchemaService wraps a influxdb.BucketSchemaService and authorizes
+// actions against it appropriately.
+type BucketSchemaService struct {
+	s influxdb.BucketSchemaService
+}
+
+// NewBucketSchemaService constructs an instance of an authorizing bucket schema service.
+func NewBucketSchemaService(s influxdb.BucketSchemaService) *BucketSchemaService {
+	return &BucketSchemaService{
+		s: s,
+	}
+}
+
+// BucketSchema checks to see if the authorizer on context has read access to the bucket provided.
+func (s *BucketSchemaService) BucketSchema(ctx context.Context, orgID, bucketID influxdb.ID) ([]influxdb.BucketSchemaMeasurement, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if _, _, err := AuthorizeRead(ctx, influxdb.BucketsResourceType, bucketID, orgID); err != nil {
+		return nil, err
+	}
+	return s.s.BucketSchema(ctx, orgID, bucketID)
+}
+
+// TagValueHistogram checks to see if the authorizer on context has read access to the bucket provided.
+func (s *BucketSchemaService) TagValueHistogram(ctx context.Context, orgID, bucketID influxdb.ID, measurement, tagKey string, start, end int64, topN int) ([]influxdb.TagValueCount, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if _, _, err := AuthorizeRead(ctx, influxdb.BucketsResourceType, bucketID, orgID); err != nil {
+		return nil, err
+	}
+	return s.s.TagValueHistogram(ctx, orgID, bucketID, measurement, tagKey, start, end, topN)
+}
@@ -2,6 +2,8 @@ package authorizer
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/influxdata/influxdb"
 )
@@ -10,9 +12,19 @@ type OrganizationService interface {
 	FindResourceOrganizationID(ctx context.Context, rt influxdb.ResourceType, id influxdb.ID) (influxdb.ID, error)
 }
 
+// DefaultOrgLookupTimeout is the default timeout of the lookups of the
+// organization of a resource by a URMService.
+const DefaultOrgLookupTimeout = 5 * time.Second
+
 type URMService struct {
-	s          influxdb.UserResourceMappingService
-	orgService OrganizationService
+	s            influxdb.UserResourceMappingService
+	orgService   OrganizationService
+	groupService influxdb.GroupService
+
+	// OrgLookupTimeout is the timeout of each lookup of the organization of
+	// a resource, after which the call fails with an ETimeout error. It
+	// defaults to DefaultOrgLookupTimeout.
+	OrgLookupTimeout time.Duration
 }
 
 func NewURMService(orgSvc OrganizationService, s influxdb.UserResourceMappingService) *URMService {
@@ -22,25 +34,134 @@ func NewURMService(orgSvc OrganizationService, s influxdb.UserResourceMappingSer
 	}
 }
 
+// NewURMServiceWithGroups constructs an instance of an authorizing user resource
+// mapping service that also returns the mappings granted to the groups a user
+// is a member of.
+func NewURMServiceWithGroups(orgSvc OrganizationService, groupSvc influxdb.GroupService, s influxdb.UserResourceMappingService) *URMService {
+	return &URMService{
+		s:            s,
+		orgService:   orgSvc,
+		groupService: groupSvc,
+	}
+}
+
+// FindUserResourceMappings returns the page of the authorized mappings matching
+// filter selected by the offset and limit of opt, and the number of authorized
+// mappings. The limit defaults to, and may not exceed, URMMaxPageSize.
 func (s *URMService) FindUserResourceMappings(ctx context.Context, filter influxdb.UserResourceMappingFilter, opt ...influxdb.FindOptions) ([]*influxdb.UserResourceMapping, int, error) {
-	urms, _, err := s.s.FindUserResourceMappings(ctx, filter, opt...)
+	var opts influxdb.FindOptions
+	if len(opt) > 0 {
+		opts = opt[0]
+	}
+	if opts.Limit < 0 || opts.Limit > influxdb.URMMaxPageSize {
+		return nil, 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("limit must be between 1 and %d", influxdb.URMMaxPageSize),
+		}
+	} else if opts.Offset < 0 {
+		return nil, 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "offset must not be negative",
+		}
+	}
+	if opts.Limit == 0 {
+		opts.Limit = influxdb.URMMaxPageSize
+	}
+
+	// The mappings are paged once authorized, so that pages are not short of
+	// the mappings that are not authorized.
+	urms, _, err := s.s.FindUserResourceMappings(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
-	return AuthorizeFindUserResourceMappings(ctx, s.orgService, urms)
+
+	groupURMs, err := s.findGroupResourceMappings(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	urms = append(urms, groupURMs...)
+
+	urms, n, err := AuthorizeFindUserResourceMappings(ctx, timeoutOrgService{s}, urms)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if opts.Offset >= len(urms) {
+		return []*influxdb.UserResourceMapping{}, n, nil
+	}
+	urms = urms[opts.Offset:]
+	if len(urms) > opts.Limit {
+		urms = urms[:opts.Limit]
+	}
+	return urms, n, nil
+}
+
+// findGroupResourceMappings returns the mappings matching filter that are
+// granted to the groups the filtered user is a member of.
+func (s *URMService) findGroupResourceMappings(ctx context.Context, filter influxdb.UserResourceMappingFilter) ([]*influxdb.UserResourceMapping, error) {
+	if s.groupService == nil || !filter.UserID.Valid() || filter.GroupID != nil {
+		return nil, nil
+	}
+
+	groups, _, err := s.groupService.FindGroups(ctx, influxdb.GroupFilter{UserID: &filter.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	var urms []*influxdb.UserResourceMapping
+	for _, g := range groups {
+		f := filter
+		f.UserID = 0
+		f.GroupID = &g.ID
+		ms, _, err := s.s.FindUserResourceMappings(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		urms = append(urms, ms...)
+	}
+	return urms, nil
 }
 
 func (s *URMService) CreateUserResourceMapping(ctx context.Context, m *influxdb.UserResourceMapping) error {
-	orgID, err := s.orgService.FindResourceOrganizationID(ctx, m.ResourceType, m.ResourceID)
+	orgID, err := s.findResourceOrganizationID(ctx, m.ResourceType, m.ResourceID)
 	if err != nil {
 		return err
 	}
 	if _, _, err := AuthorizeWrite(ctx, m.ResourceType, m.ResourceID, orgID); err != nil {
 		return err
 	}
+	if m.GroupID != nil {
+		if err := s.authorizeGroupMapping(ctx, *m.GroupID, orgID); err != nil {
+			return err
+		}
+	}
 	return s.s.CreateUserResourceMapping(ctx, m)
 }
 
+// authorizeGroupMapping checks that a resource of the organization may be
+// mapped to the group: the group must belong to the same organization, and
+// the authorizer must be able to write the group as well as the resource.
+func (s *URMService) authorizeGroupMapping(ctx context.Context, groupID, orgID influxdb.ID) error {
+	if s.groupService == nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "resources cannot be mapped to groups",
+		}
+	}
+	g, err := s.groupService.FindGroupByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if g.OrgID != orgID {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("group %s does not belong to the organization of the resource", g.ID),
+		}
+	}
+	_, _, err = AuthorizeWrite(ctx, influxdb.GroupsResourceType, g.ID, g.OrgID)
+	return err
+}
+
 func (s *URMService) DeleteUserResourceMapping(ctx context.Context, resourceID influxdb.ID, userID influxdb.ID) error {
 	f := influxdb.UserResourceMappingFilter{ResourceID: resourceID, UserID: userID}
 	urms, _, err := s.s.FindUserResourceMappings(ctx, f)
@@ -49,16 +170,62 @@ func (s *URMService) DeleteUserResourceMapping(ctx context.Context, resourceID i
 	}
 
 	for _, urm := range urms {
-		orgID, err := s.orgService.FindResourceOrganizationID(ctx, urm.ResourceType, urm.ResourceID)
+		orgID, err := s.findResourceOrganizationID(ctx, urm.ResourceType, urm.ResourceID)
 		if err != nil {
 			return err
 		}
 		if _, _, err := AuthorizeWrite(ctx, urm.ResourceType, urm.ResourceID, orgID); err != nil {
 			return err
 		}
-		if err := s.s.DeleteUserResourceMapping(ctx, urm.ResourceID, urm.UserID); err != nil {
+		if err := s.s.DeleteUserResourceMapping(ctx, urm.ResourceID, urm.PrincipalID()); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// findResourceOrganizationID looks up the organization of a resource with the
+// timeout of the service. It returns when the timeout expires even if the
+// organization service ignores the cancellation of its context.
+func (s *URMService) findResourceOrganizationID(ctx context.Context, rt influxdb.ResourceType, id influxdb.ID) (influxdb.ID, error) {
+	timeout := s.OrgLookupTimeout
+	if timeout <= 0 {
+		timeout = DefaultOrgLookupTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		orgID influxdb.ID
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		orgID, err := s.orgService.FindResourceOrganizationID(ctx, rt, id)
+		done <- result{orgID: orgID, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.orgID, r.err
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			return 0, ctx.Err()
+		}
+		return 0, &influxdb.Error{
+			Code: influxdb.ETimeout,
+			Msg:  fmt.Sprintf("timed out looking up the organization of %s %s", rt, id),
+			Err:  ctx.Err(),
+		}
+	}
+}
+
+// timeoutOrgService looks up the organizations of resources with the timeout
+// of a URMService.
+type timeoutOrgService struct {
+	s *URMService
+}
+
+func (o timeoutOrgService) FindResourceOrganizationID(ctx context.Context, rt influxdb.ResourceType, id influxdb.ID) (influxdb.ID, error) {
+	return o.s.findResourceOrganizationID(ctx, rt, id)
+}
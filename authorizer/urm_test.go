@@ -3,6 +3,7 @@ package authorizer_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
@@ -256,3 +257,256 @@ func TestURMService_WriteUserResourceMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestURMService_FindUserResourceMappingsPaging(t *testing.T) {
+	const count = 2000
+	urms := make([]*influxdb.UserResourceMapping, 0, count)
+	for i := 1; i <= count; i++ {
+		urms = append(urms, &influxdb.UserResourceMapping{
+			ResourceID:   influxdb.ID(i),
+			ResourceType: influxdb.BucketsResourceType,
+			UserID:       100,
+		})
+	}
+	s := authorizer.NewURMService(&OrgService{OrgID: 10}, &mock.UserResourceMappingService{
+		FindMappingsFn: func(ctx context.Context, filter influxdb.UserResourceMappingFilter) ([]*influxdb.UserResourceMapping, int, error) {
+			return append([]*influxdb.UserResourceMapping(nil), urms...), len(urms), nil
+		},
+	})
+	ctx := influxdbcontext.SetAuthorizer(context.Background(), &Authorizer{[]influxdb.Permission{{
+		Action: "read",
+		Resource: influxdb.Resource{
+			Type:  influxdb.BucketsResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}}})
+
+	seen := make(map[influxdb.ID]bool)
+	for offset := 0; ; offset += 100 {
+		page, n, err := s.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{}, influxdb.FindOptions{Limit: 100, Offset: offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != count {
+			t.Fatalf("got %d mappings at offset %d, exp %d", n, offset, count)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) != 100 {
+			t.Fatalf("got page of %d mappings at offset %d, exp 100", len(page), offset)
+		}
+		for i, m := range page {
+			if exp := influxdb.ID(offset + i + 1); m.ResourceID != exp {
+				t.Fatalf("got resource %s at %d, exp %s", m.ResourceID, offset+i, exp)
+			}
+			seen[m.ResourceID] = true
+		}
+	}
+	if len(seen) != count {
+		t.Fatalf("got %d mappings, exp %d", len(seen), count)
+	}
+
+	// Pages default to, and may not exceed, the maximum page size.
+	page, _, err := s.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != influxdb.URMMaxPageSize {
+		t.Fatalf("got page of %d mappings, exp %d", len(page), influxdb.URMMaxPageSize)
+	}
+	_, _, err = s.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{}, influxdb.FindOptions{Limit: influxdb.URMMaxPageSize + 1})
+	if code := influxdb.ErrorCode(err); code != influxdb.EInvalid {
+		t.Fatalf("got error %v with code %q, exp code %q", err, code, influxdb.EInvalid)
+	}
+}
+
+// blockingOrgService blocks lookups for 10 seconds, ignoring their context.
+type blockingOrgService struct {
+	release chan struct{}
+}
+
+func (s *blockingOrgService) FindResourceOrganizationID(ctx context.Context, rt influxdb.ResourceType, id influxdb.ID) (influxdb.ID, error) {
+	select {
+	case <-time.After(10 * time.Second):
+	case <-s.release:
+	}
+	return 10, nil
+}
+
+func TestURMService_OrgLookupTimeout(t *testing.T) {
+	orgSvc := &blockingOrgService{release: make(chan struct{})}
+	defer close(orgSvc.release)
+	urmSvc := &mock.UserResourceMappingService{
+		CreateMappingFn: func(ctx context.Context, m *influxdb.UserResourceMapping) error {
+			return nil
+		},
+		DeleteMappingFn: func(ctx context.Context, rid, uid influxdb.ID) error {
+			return nil
+		},
+		FindMappingsFn: func(ctx context.Context, filter influxdb.UserResourceMappingFilter) ([]*influxdb.UserResourceMapping, int, error) {
+			return []*influxdb.UserResourceMapping{
+				{
+					ResourceID:   1,
+					ResourceType: influxdb.BucketsResourceType,
+					UserID:       100,
+				},
+			}, 1, nil
+		},
+	}
+
+	ctx := influxdbcontext.SetAuthorizer(context.Background(), &Authorizer{[]influxdb.Permission{{
+		Action: "write",
+		Resource: influxdb.Resource{
+			Type:  influxdb.BucketsResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}}})
+
+	checkTimeout := func(t *testing.T, max time.Duration, fn func() error) {
+		t.Helper()
+		start := time.Now()
+		err := fn()
+		if elapsed := time.Since(start); elapsed > max {
+			t.Fatalf("returned after %s, exp at most %s", elapsed, max)
+		}
+		if code := influxdb.ErrorCode(err); code != influxdb.ETimeout {
+			t.Fatalf("got error %v with code %q, exp code %q", err, code, influxdb.ETimeout)
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		s := authorizer.NewURMService(orgSvc, urmSvc)
+		checkTimeout(t, 6*time.Second, func() error {
+			return s.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{ResourceType: influxdb.BucketsResourceType, ResourceID: 1})
+		})
+	})
+
+	s := authorizer.NewURMService(orgSvc, urmSvc)
+	s.OrgLookupTimeout = 50 * time.Millisecond
+
+	t.Run("create urm", func(t *testing.T) {
+		checkTimeout(t, time.Second, func() error {
+			return s.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{ResourceType: influxdb.BucketsResourceType, ResourceID: 1})
+		})
+	})
+
+	t.Run("delete urm", func(t *testing.T) {
+		checkTimeout(t, time.Second, func() error {
+			return s.DeleteUserResourceMapping(ctx, 1, 100)
+		})
+	})
+
+	t.Run("find urms", func(t *testing.T) {
+		checkTimeout(t, time.Second, func() error {
+			_, _, err := s.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{})
+			return err
+		})
+	})
+}
+
+func TestURMService_GroupMappings(t *testing.T) {
+	ctx := context.Background()
+	svc := newKVSVC(t)
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "jane"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	bucket := &influxdb.Bucket{OrgID: org.ID, Name: "metrics"}
+	if err := svc.CreateBucket(ctx, bucket); err != nil {
+		t.Fatal(err)
+	}
+	group := &influxdb.Group{OrgID: org.ID, Name: "readers"}
+	if err := svc.CreateGroup(ctx, group); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddGroupMember(ctx, group.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	s := authorizer.NewURMServiceWithGroups(&OrgService{OrgID: org.ID}, svc, svc)
+
+	t.Run("create group urm requires write on the resource and the group", func(t *testing.T) {
+		m := &influxdb.UserResourceMapping{
+			GroupID:      &group.ID,
+			UserType:     influxdb.Member,
+			ResourceType: influxdb.BucketsResourceType,
+			ResourceID:   bucket.ID,
+		}
+
+		writeGroup := influxdb.Permission{
+			Action:   influxdb.WriteAction,
+			Resource: influxdb.Resource{Type: influxdb.GroupsResourceType, ID: &group.ID, OrgID: &org.ID},
+		}
+		writeBucket := influxdb.Permission{
+			Action:   influxdb.WriteAction,
+			Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, ID: &bucket.ID, OrgID: &org.ID},
+		}
+
+		groupWriter := influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{writeGroup}})
+		if err := s.CreateUserResourceMapping(groupWriter, m); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			t.Fatalf("expected unauthorized error, got %v", err)
+		}
+
+		bucketWriter := influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{writeBucket}})
+		if err := s.CreateUserResourceMapping(bucketWriter, m); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			t.Fatalf("expected unauthorized error, got %v", err)
+		}
+
+		writer := influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{writeGroup, writeBucket}})
+		if err := s.CreateUserResourceMapping(writer, m); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("create group urm rejects a group of another organization", func(t *testing.T) {
+		other := &influxdb.Organization{Name: "other"}
+		if err := svc.CreateOrganization(ctx, other); err != nil {
+			t.Fatal(err)
+		}
+		otherGroup := &influxdb.Group{OrgID: other.ID, Name: "others"}
+		if err := svc.CreateGroup(ctx, otherGroup); err != nil {
+			t.Fatal(err)
+		}
+
+		operator := influxdbcontext.SetAuthorizer(ctx, &Authorizer{influxdb.OperPermissions()})
+		err := s.CreateUserResourceMapping(operator, &influxdb.UserResourceMapping{
+			GroupID:      &otherGroup.ID,
+			UserType:     influxdb.Member,
+			ResourceType: influxdb.BucketsResourceType,
+			ResourceID:   bucket.ID,
+		})
+		if influxdb.ErrorCode(err) != influxdb.EInvalid {
+			t.Fatalf("expected invalid error, got %v", err)
+		}
+	})
+
+	t.Run("user mappings include the mappings of the user's groups", func(t *testing.T) {
+		reader := influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{{
+			Action:   influxdb.ReadAction,
+			Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &org.ID},
+		}}})
+		urms, _, err := s.FindUserResourceMappings(reader, influxdb.UserResourceMappingFilter{
+			UserID:       user.ID,
+			ResourceType: influxdb.BucketsResourceType,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []*influxdb.UserResourceMapping{{
+			GroupID:      &group.ID,
+			UserType:     influxdb.Member,
+			ResourceType: influxdb.BucketsResourceType,
+			ResourceID:   bucket.ID,
+		}}
+		if diff := cmp.Diff(urms, want); diff != "" {
+			t.Errorf("urms are different -got/+want\ndiff %s", diff)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 var (
@@ -75,10 +76,21 @@ type Resource struct {
 	Type  ResourceType `json:"type"`
 	ID    *ID          `json:"id,omitempty"`
 	OrgID *ID          `json:"orgID,omitempty"`
+	// Name is the optional name of the resource identified by ID. It is used
+	// for display only and does not affect which permissions match.
+	Name string `json:"name,omitempty"`
 }
 
 // String stringifies a resource
 func (r Resource) String() string {
+	if r.Name != "" {
+		kind := strings.TrimSuffix(string(r.Type), "s")
+		if r.ID != nil {
+			return fmt.Sprintf("%s %s (id: %s)", kind, r.Name, r.ID)
+		}
+		return fmt.Sprintf("%s %s", kind, r.Name)
+	}
+
 	if r.OrgID != nil && r.ID != nil {
 		return filepath.Join(string(OrgsResourceType), r.OrgID.String(), string(r.Type), r.ID.String())
 	}
@@ -129,6 +141,8 @@ const (
 	NotificationEndpointResourceType = ResourceType("notificationEndpoints") // 15
 	// ChecksResourceType gives permission to one or more Checks.
 	ChecksResourceType = ResourceType("checks") // 16
+	// GroupsResourceType gives permission to one or more groups.
+	GroupsResourceType = ResourceType("groups") // 17
 )
 
 // AllResourceTypes is the list of all known resource types.
@@ -150,6 +164,7 @@ var AllResourceTypes = []ResourceType{
 	NotificationRuleResourceType,     // 14
 	NotificationEndpointResourceType, // 15
 	ChecksResourceType,               // 16
+	GroupsResourceType,               // 17
 	// NOTE: when modifying this list, please update the swagger for components.schemas.Permission resource enum.
 }
 
@@ -167,6 +182,7 @@ var OrgResourceTypes = []ResourceType{
 	NotificationRuleResourceType,     // 14
 	NotificationEndpointResourceType, // 15
 	ChecksResourceType,               // 16
+	GroupsResourceType,               // 17
 }
 
 // Valid checks if the resource type is a member of the ResourceType enum.
@@ -194,6 +210,7 @@ func (t ResourceType) Valid() (err error) {
 	case NotificationRuleResourceType: // 14
 	case NotificationEndpointResourceType: // 15
 	case ChecksResourceType: // 16
+	case GroupsResourceType: // 17
 	default:
 		err = ErrInvalidResourceType
 	}
@@ -417,6 +417,19 @@ func TestPermission_String(t *testing.T) {
 			},
 			want: `write:buckets/0000000000000001`,
 		},
+		{
+			name: "valid permission with a name",
+			fields: fields{
+				Action: platform.WriteAction,
+				Resource: platform.Resource{
+					Type:  platform.BucketsResourceType,
+					OrgID: influxdbtesting.IDPtr(1),
+					ID:    validID(),
+					Name:  "my-bucket",
+				},
+			},
+			want: `write:bucket my-bucket (id: 0000000000000064)`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -21,3 +21,14 @@ type KVBackupService interface {
 	// Backup creates a live backup copy of the metadata database.
 	Backup(ctx context.Context, w io.Writer) error
 }
+
+// BackupManifestFilename is the name of the manifest uploaded with backups
+// written to object storage.
+const BackupManifestFilename = "manifest.json"
+
+// BackupManifest lists the files of a backup written to object storage, so
+// they can be found again on restore.
+type BackupManifest struct {
+	BackupID int      `json:"backupID"`
+	Files    []string `json:"files"`
+}
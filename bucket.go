@@ -74,6 +74,29 @@ var (
 	OpDeleteBucket   = "DeleteBucket"
 )
 
+// BucketCardinalityService reports the series cardinality of buckets.
+type BucketCardinalityService interface {
+	// SeriesCount returns the number of distinct series in the bucket.
+	SeriesCount(ctx context.Context, orgID, bucketID ID) (int64, error)
+}
+
+// BucketSizeService reports the disk space used by buckets.
+type BucketSizeService interface {
+	// EstimatedCompressedSize returns an estimate in bytes of the disk space
+	// used by the data of the bucket.
+	EstimatedCompressedSize(ctx context.Context, orgID, bucketID ID) (int64, error)
+}
+
+// BucketMetadataService stores metadata of buckets, such as their owner,
+// apart from their data.
+type BucketMetadataService interface {
+	// WriteMetadata replaces the metadata of the bucket.
+	WriteMetadata(ctx context.Context, orgID, bucketID ID, metadata map[string]string) error
+
+	// ReadMetadata returns the metadata of the bucket.
+	ReadMetadata(ctx context.Context, orgID, bucketID ID) (map[string]string, error)
+}
+
 // BucketService represents a service for managing bucket data.
 type BucketService interface {
 	// FindBucketByID returns a single bucket by ID.
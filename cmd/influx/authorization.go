@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influx/internal"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/jsonweb"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +36,7 @@ func cmdAuth(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 		authDeleteCmd(),
 		authFindCmd(),
 		authInactiveCmd(),
+		authInspectCmd(),
 	)
 
 	return cmd
@@ -579,6 +585,129 @@ func authorizationInactiveF(cmd *cobra.Command, args []string) error {
 	})
 }
 
+func authInspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect [token]",
+		Short: "Inspect a token",
+		Long: `Inspect the given token, or else the token given with the --token flag or the token the CLI
+is configured with.
+
+A JWT is decoded without verifying its signature and without calling the API, to print its
+header, claims and permissions. The authorization of any other token is found among the
+authorizations the token of the CLI can read. The inspected token is not sent to the API.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: authorizationInspectF,
+	}
+
+	registerPrintOptions(cmd, &authCRUDFlags.hideHeaders, &authCRUDFlags.json)
+
+	return cmd
+}
+
+func authorizationInspectF(cmd *cobra.Command, args []string) error {
+	v := flags.Token
+	if len(args) > 0 {
+		v = args[0]
+	}
+	if v == "" {
+		return errors.New("must specify the token to inspect")
+	}
+
+	// a JWT is three segments separated by periods
+	if strings.Count(v, ".") == 2 {
+		return inspectJWT(cmd.OutOrStdout(), v)
+	}
+
+	s, err := newAuthorizationService()
+	if err != nil {
+		return err
+	}
+
+	us, err := newUserService()
+	if err != nil {
+		return err
+	}
+
+	// The authorizations are found with the token of the CLI, and matched here
+	// so that the inspected token is not sent as a credential or parameter.
+	authorizations, _, err := s.FindAuthorizations(context.Background(), platform.AuthorizationFilter{})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range authorizations {
+		if a.Token != v {
+			continue
+		}
+
+		user, err := us.FindUserByID(context.Background(), a.UserID)
+		if err != nil {
+			return err
+		}
+
+		ps := make([]string, 0, len(a.Permissions))
+		for _, p := range a.Permissions {
+			ps = append(ps, p.String())
+		}
+
+		return writeTokens(cmd.OutOrStdout(), tokenPrintOpt{
+			jsonOut:     authCRUDFlags.json,
+			hideHeaders: authCRUDFlags.hideHeaders,
+			token: token{
+				ID:          a.ID,
+				Token:       a.Token,
+				Status:      string(a.Status),
+				UserName:    user.Name,
+				UserID:      user.ID,
+				Permissions: ps,
+			},
+		})
+	}
+
+	return errors.New("authorization of the token not found")
+}
+
+// inspectJWT writes the header, claims and permissions of the JWT, without
+// verifying its signature.
+func inspectJWT(w io.Writer, v string) error {
+	t, err := jsonweb.ParseUnverified(v)
+	if err != nil {
+		return fmt.Errorf("failed to decode JWT: %v", err)
+	}
+
+	ps := make([]string, 0, len(t.Token.Permissions))
+	for _, p := range t.Token.Permissions {
+		ps = append(ps, p.String())
+	}
+
+	if authCRUDFlags.json {
+		return writeJSON(w, struct {
+			Header      map[string]interface{} `json:"header"`
+			Claims      map[string]interface{} `json:"claims"`
+			Permissions []string               `json:"permissions"`
+		}{
+			Header:      t.Header,
+			Claims:      t.Claims,
+			Permissions: ps,
+		})
+	}
+
+	header, err := json.MarshalIndent(t.Header, "", "\t")
+	if err != nil {
+		return err
+	}
+	claims, err := json.MarshalIndent(t.Claims, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Header:\n%s\n\nClaims:\n%s\n\nPermissions:\n", header, claims)
+	for _, p := range ps {
+		fmt.Fprintf(w, "\t%s\n", p)
+	}
+	return nil
+}
+
 type tokenPrintOpt struct {
 	jsonOut     bool
 	deleted     bool
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/influxdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdAuth(t *testing.T) {
+	orgID, userID := influxdb.ID(9000), influxdb.ID(9001)
+
+	t.Run("inspect", func(t *testing.T) {
+		t.Run("jwt", func(t *testing.T) {
+			v, signErr := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"org_id":  orgID.String(),
+				"user_id": userID.String(),
+				"kid":     "some-key",
+				"permissions": []influxdb.Permission{
+					{
+						Action:   influxdb.ReadAction,
+						Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &orgID},
+					},
+					{
+						Action:   influxdb.WriteAction,
+						Resource: influxdb.Resource{Type: influxdb.TasksResourceType, OrgID: &orgID},
+					},
+				},
+			}).SignedString([]byte("some-secret"))
+			require.NoError(t, signErr)
+
+			tests := []struct {
+				name  string
+				flags []string
+			}{
+				{name: "text"},
+				{name: "json", flags: []string{"--json"}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					stdout := new(bytes.Buffer)
+					builder := newInfluxCmdBuilder(
+						in(new(bytes.Buffer)),
+						out(stdout),
+						err(ioutil.Discard),
+					)
+					cmd := builder.cmd(cmdAuth)
+					// no server is listening on the host, since the token is
+					// decoded without calling the API.
+					cmd.SetArgs(append([]string{"auth", "inspect", "--host=http://127.0.0.1:0", "--token=" + v}, tt.flags...))
+					defer func() { authCRUDFlags.json = false }()
+
+					require.NoError(t, cmd.Execute())
+
+					output := stdout.String()
+					assert.Contains(t, output, `"org_id": "`+orgID.String()+`"`)
+					assert.Contains(t, output, `"user_id": "`+userID.String()+`"`)
+					assert.Contains(t, output, `"alg": "HS256"`)
+					assert.Contains(t, output, "read:orgs/"+orgID.String()+"/buckets")
+					assert.Contains(t, output, "write:orgs/"+orgID.String()+"/tasks")
+				})
+			}
+		})
+
+		t.Run("opaque token", func(t *testing.T) {
+			const opaqueToken, cliToken = "opaque-token==", "cli-token=="
+			srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				if strings.Contains(r.URL.String(), opaqueToken) {
+					t.Errorf("inspected token sent in request %s", r.URL)
+				}
+				if r.Header.Get("Authorization") != "Token "+cliToken {
+					w.WriteHeader(nethttp.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case "/api/v2/authorizations":
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"authorizations": []map[string]interface{}{
+							{"id": influxdb.ID(1).String(), "token": "other-token", "status": "active", "orgID": orgID.String(), "userID": influxdb.ID(2).String()},
+							{
+								"id":     influxdb.ID(3).String(),
+								"token":  opaqueToken,
+								"status": "active",
+								"orgID":  orgID.String(),
+								"userID": userID.String(),
+								"permissions": []influxdb.Permission{
+									{
+										Action:   influxdb.ReadAction,
+										Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &orgID},
+									},
+								},
+							},
+						},
+					})
+				case "/api/v2/users/" + userID.String():
+					fmt.Fprintf(w, `{"id":%q,"name":"gopher"}`, userID)
+				default:
+					w.WriteHeader(nethttp.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			stdout := new(bytes.Buffer)
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(stdout),
+				err(ioutil.Discard),
+			)
+			cmd := builder.cmd(cmdAuth)
+			cmd.SetArgs([]string{"auth", "inspect", opaqueToken, "--host=" + srv.URL, "--token=" + cliToken, "--json"})
+			defer func() { authCRUDFlags.json = false }()
+
+			require.NoError(t, cmd.Execute())
+
+			var got token
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &got))
+			assert.Equal(t, influxdb.ID(3), got.ID)
+			assert.Equal(t, "gopher", got.UserName)
+			assert.Equal(t, userID, got.UserID)
+			assert.Equal(t, []string{"read:orgs/" + orgID.String() + "/buckets"}, got.Permissions)
+		})
+	})
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/pkg/s3"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
 )
@@ -20,17 +24,47 @@ func cmdBackup(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 		`Backs up data and meta data for the running InfluxDB instance.
 Downloaded files are written to the directory indicated by --path.
 The target directory, and any parent directories, are created automatically.
-Data file have extension .tsm; meta data is written to %s in the same directory.`,
-		bolt.DefaultFilename)
+Data file have extension .tsm; meta data is written to %s in the same directory.
+
+Alternatively, files are streamed to the S3 location indicated by --s3-bucket,
+such as s3://my-backups/influxdb/, without being written to local disk. A %s
+listing the files of the backup is uploaded alongside them. S3 credentials are
+read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables
+unless --s3-access-key and --s3-secret-key are given.`,
+		bolt.DefaultFilename, influxdb.BackupManifestFilename)
 
 	opts := flagOpts{
 		{
-			DestP:    &backupFlags.Path,
-			Flag:     "path",
-			Short:    'p',
-			EnvVar:   "PATH",
-			Desc:     "directory path to write backup files to",
-			Required: true,
+			DestP:  &backupFlags.Path,
+			Flag:   "path",
+			Short:  'p',
+			EnvVar: "PATH",
+			Desc:   "directory path to write backup files to",
+		},
+		{
+			DestP: &backupFlags.S3Bucket,
+			Flag:  "s3-bucket",
+			Desc:  "S3 location to upload backup files to, as s3://bucket/prefix/",
+		},
+		{
+			DestP: &backupFlags.S3.AccessKey,
+			Flag:  "s3-access-key",
+			Desc:  "S3 access key ID; defaults to $AWS_ACCESS_KEY_ID",
+		},
+		{
+			DestP: &backupFlags.S3.SecretKey,
+			Flag:  "s3-secret-key",
+			Desc:  "S3 secret access key; defaults to $AWS_SECRET_ACCESS_KEY",
+		},
+		{
+			DestP: &backupFlags.S3.Region,
+			Flag:  "s3-region",
+			Desc:  "S3 region; defaults to $AWS_REGION",
+		},
+		{
+			DestP: &backupFlags.S3.Endpoint,
+			Flag:  "s3-endpoint",
+			Desc:  "URL of an S3 compatible service to use instead of AWS",
 		},
 	}
 	opts.mustRegister(cmd)
@@ -39,7 +73,31 @@ Data file have extension .tsm; meta data is written to %s in the same directory.
 }
 
 var backupFlags struct {
-	Path string
+	Path     string
+	S3Bucket string
+	S3       s3Flags
+}
+
+// s3Flags are the options of the S3 client backups are uploaded with.
+type s3Flags struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Endpoint  string
+}
+
+// client returns an S3 client using the credentials and region given by the
+// flags, or the standard AWS environment variables.
+func (f s3Flags) client() *s3.Client {
+	creds := s3.CredentialsFromEnv()
+	if f.AccessKey != "" || f.SecretKey != "" {
+		creds = s3.Credentials{AccessKeyID: f.AccessKey, SecretAccessKey: f.SecretKey}
+	}
+	region := f.Region
+	if region == "" {
+		region = s3.RegionFromEnv()
+	}
+	return s3.NewClient(f.Endpoint, region, creds)
 }
 
 func newBackupService() (influxdb.BackupService, error) {
@@ -56,13 +114,8 @@ func backupF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("local flag not supported for backup command")
 	}
 
-	if backupFlags.Path == "" {
-		return fmt.Errorf("must specify path")
-	}
-
-	err := os.MkdirAll(backupFlags.Path, 0777)
-	if err != nil && !os.IsExist(err) {
-		return err
+	if (backupFlags.Path == "") == (backupFlags.S3Bucket == "") {
+		return fmt.Errorf("must specify one of path or s3-bucket")
 	}
 
 	backupService, err := newBackupService()
@@ -75,24 +128,69 @@ func backupF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	fmt.Printf("Backup ID %d contains %d files\n", id, len(backupFilenames))
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "Backup ID %d contains %d files\n", id, len(backupFilenames))
+
+	if backupFlags.S3Bucket != "" {
+		return backupToS3(ctx, w, backupService, id, backupFilenames)
+	}
+
+	err = os.MkdirAll(backupFlags.Path, 0777)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
 
 	for _, backupFilename := range backupFilenames {
 		dest := filepath.Join(backupFlags.Path, backupFilename)
-		w, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
 			return err
 		}
-		err = backupService.FetchBackupFile(ctx, id, backupFilename, w)
+		err = backupService.FetchBackupFile(ctx, id, backupFilename, f)
 		if err != nil {
-			return multierr.Append(fmt.Errorf("error fetching file %s: %v", backupFilename, err), w.Close())
+			return multierr.Append(fmt.Errorf("error fetching file %s: %v", backupFilename, err), f.Close())
 		}
-		if err = w.Close(); err != nil {
+		if err = f.Close(); err != nil {
 			return err
 		}
 	}
 
-	fmt.Printf("Backup complete")
+	fmt.Fprintln(w, "Backup complete")
+
+	return nil
+}
+
+// backupToS3 streams the files of a backup to the S3 location given by the
+// s3-bucket flag, followed by the manifest listing them. Progress is written to out.
+func backupToS3(ctx context.Context, out io.Writer, backupService influxdb.BackupService, id int, backupFilenames []string) error {
+	bucket, prefix, err := s3.ParseURL(backupFlags.S3Bucket)
+	if err != nil {
+		return err
+	}
+	client := backupFlags.S3.client()
+
+	for _, backupFilename := range backupFilenames {
+		r, w := io.Pipe()
+		go func(backupFilename string) {
+			w.CloseWithError(backupService.FetchBackupFile(ctx, id, backupFilename, w))
+		}(backupFilename)
+
+		err := client.PutObject(ctx, bucket, prefix+backupFilename, r)
+		r.CloseWithError(err)
+		if err != nil {
+			return fmt.Errorf("error uploading file %s: %v", backupFilename, err)
+		}
+	}
+
+	manifest, err := json.Marshal(influxdb.BackupManifest{BackupID: id, Files: backupFilenames})
+	if err != nil {
+		return err
+	}
+	if err := client.PutObject(ctx, bucket, prefix+influxdb.BackupManifestFilename, bytes.NewReader(manifest)); err != nil {
+		return fmt.Errorf("error uploading manifest: %v", err)
+	}
+
+	fmt.Fprintln(out, "Backup complete")
 
 	return nil
 }
@@ -31,6 +31,9 @@ type cmdBucketBuilder struct {
 	description string
 	org         organization
 	retention   time.Duration
+
+	minRetention string
+	maxRetention string
 }
 
 func newCmdBucketBuilder(svcsFn bucketSVCsFn, opts genericCLIOpts) *cmdBucketBuilder {
@@ -180,6 +183,8 @@ func (b *cmdBucketBuilder) cmdList() *cobra.Command {
 	b.org.register(cmd, false)
 	b.registerPrintFlags(cmd)
 	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The bucket ID")
+	cmd.Flags().StringVar(&b.minRetention, "min-retention", "", "Only list buckets that retain data for at least this duration (e.g. 7d). Infinite retention only matches 0.")
+	cmd.Flags().StringVar(&b.maxRetention, "max-retention", "", "Only list buckets that retain data for at most this duration (e.g. 30d). Infinite retention only matches 0.")
 
 	return cmd
 }
@@ -189,6 +194,15 @@ func (b *cmdBucketBuilder) cmdListRunEFn(cmd *cobra.Command, args []string) erro
 		return err
 	}
 
+	minRetention, err := parseRetentionFlag("min-retention", b.minRetention)
+	if err != nil {
+		return err
+	}
+	maxRetention, err := parseRetentionFlag("max-retention", b.maxRetention)
+	if err != nil {
+		return err
+	}
+
 	bktSVC, _, err := b.svcFn()
 	if err != nil {
 		return err
@@ -221,11 +235,57 @@ func (b *cmdBucketBuilder) cmdListRunEFn(cmd *cobra.Command, args []string) erro
 		return fmt.Errorf("failed to retrieve buckets: %s", err)
 	}
 
+	if minRetention != nil || maxRetention != nil {
+		filtered := buckets[:0]
+		for _, bkt := range buckets {
+			if retentionWithin(bkt.RetentionPeriod, minRetention, maxRetention) {
+				filtered = append(filtered, bkt)
+			}
+		}
+		buckets = filtered
+	}
+
 	return b.printBuckets(bucketPrintOpt{
 		buckets: buckets,
 	})
 }
 
+// parseRetentionFlag parses the value of a retention threshold flag, which
+// may use days and weeks as units. It returns nil if the flag is not set.
+func parseRetentionFlag(flag, v string) (*time.Duration, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	var d time.Duration
+	if v != "0" {
+		var err error
+		if d, err = http.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s %q: %v", flag, v, err)
+		}
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("%s %q must not be negative", flag, v)
+	}
+	return &d, nil
+}
+
+// retentionWithin reports whether a retention period is within the retention
+// thresholds that are set. An infinite retention period, which is 0, is only
+// within thresholds of 0.
+func retentionWithin(retention time.Duration, min, max *time.Duration) bool {
+	if retention == 0 {
+		return (min == nil || *min == 0) && (max == nil || *max == 0)
+	}
+	if min != nil && retention < *min {
+		return false
+	}
+	if max != nil && retention > *max {
+		return false
+	}
+	return true
+}
+
 func (b *cmdBucketBuilder) cmdUpdate() *cobra.Command {
 	cmd := b.newCmd("update", b.cmdUpdateRunEFn, true)
 	cmd.Short = "Update bucket"
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -349,6 +350,98 @@ func TestCmdBucket(t *testing.T) {
 		}
 	})
 
+	t.Run("list retention", func(t *testing.T) {
+		buckets := []*influxdb.Bucket{
+			{ID: 1, Name: "forever", OrgID: 3},
+			{ID: 2, Name: "day", OrgID: 3, RetentionPeriod: 24 * time.Hour},
+			{ID: 3, Name: "month", OrgID: 3, RetentionPeriod: 30 * 24 * time.Hour},
+		}
+
+		tests := []struct {
+			name     string
+			flags    []string
+			expected []string
+		}{
+			{
+				name:     "none",
+				expected: []string{"forever", "day", "month"},
+			},
+			{
+				name:     "min 0",
+				flags:    []string{"--min-retention=0"},
+				expected: []string{"forever", "day", "month"},
+			},
+			{
+				name:     "min days",
+				flags:    []string{"--min-retention=7d"},
+				expected: []string{"month"},
+			},
+			{
+				name:     "max days",
+				flags:    []string{"--max-retention=7d"},
+				expected: []string{"day"},
+			},
+			{
+				name:     "min and max",
+				flags:    []string{"--min-retention=24h", "--max-retention=4w"},
+				expected: []string{"day"},
+			},
+			{
+				name:     "max 0",
+				flags:    []string{"--max-retention=0"},
+				expected: []string{"forever"},
+			},
+		}
+
+		for _, tt := range tests {
+			fn := func(t *testing.T) {
+				defer addEnvVars(t, envVarsZeroMap)()
+
+				svc := mock.NewBucketService()
+				svc.FindBucketsFn = func(ctx context.Context, f influxdb.BucketFilter, opt ...influxdb.FindOptions) ([]*influxdb.Bucket, int, error) {
+					bkts := make([]*influxdb.Bucket, len(buckets))
+					copy(bkts, buckets)
+					return bkts, len(bkts), nil
+				}
+
+				var buf bytes.Buffer
+				builder := newInfluxCmdBuilder(
+					in(new(bytes.Buffer)),
+					out(&buf),
+				)
+				cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+					return newCmdBucketBuilder(fakeSVCFn(svc), opt).cmd()
+				})
+				cmd.SetArgs(append([]string{"bucket", "list", "--org-id=" + influxdb.ID(3).String(), "--json"}, tt.flags...))
+				require.NoError(t, cmd.Execute())
+
+				var got []influxdb.Bucket
+				require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+				var names []string
+				for _, bkt := range got {
+					names = append(names, bkt.Name)
+				}
+				assert.Equal(t, tt.expected, names)
+			}
+
+			t.Run(tt.name, fn)
+		}
+
+		t.Run("invalid", func(t *testing.T) {
+			defer addEnvVars(t, envVarsZeroMap)()
+
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(ioutil.Discard),
+			)
+			cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+				return newCmdBucketBuilder(fakeSVCFn(mock.NewBucketService()), opt).cmd()
+			})
+			cmd.SetArgs([]string{"bucket", "list", "--org-id=" + influxdb.ID(3).String(), "--min-retention=week"})
+			require.Error(t, cmd.Execute())
+		})
+	})
+
 	t.Run("update", func(t *testing.T) {
 		tests := []struct {
 			name     string
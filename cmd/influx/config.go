@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"strings"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influx/config"
@@ -35,9 +37,17 @@ type cmdConfigBuilder struct {
 	active bool
 	org    string
 
+	hostFilter string
+	orgFilter  string
+
+	output       string
+	redactTokens bool
+
 	json        bool
 	hideHeaders bool
 
+	consulPath string
+
 	svc config.ConfigsService
 }
 
@@ -45,43 +55,68 @@ func (b *cmdConfigBuilder) cmd() *cobra.Command {
 	cmd := b.newCmd("config", b.cmdSwitchActiveRunEFn, false)
 	cmd.Short = "Config management commands"
 	cmd.Args = cobra.ExactArgs(1)
+	cmd.PersistentPreRunE = b.consulPreRunE
+
+	opts := flagOpts{
+		{
+			DestP:      &b.consulPath,
+			Flag:       "config-consul-path",
+			Desc:       "Key of the configs in the Consul KV store, such as influx/configs/myorg, instead of the local configs file",
+			Persistent: true,
+		},
+	}
+	opts.mustRegister(cmd)
 
 	cmd.AddCommand(
 		b.cmdCreate(),
 		b.cmdDelete(),
 		b.cmdUpdate(),
 		b.cmdList(),
+		b.cmdExport(),
 	)
 	return cmd
 }
 
-func (b *cmdConfigBuilder) cmdSwitchActiveRunEFn(cmd *cobra.Command, args []string) error {
-	pp, err := b.svc.ParseConfigs()
+// consulPreRunE replaces the configs service with one of the configs in
+// Consul when a Consul path is set. The local configs are read when Consul
+// is unreachable.
+func (b *cmdConfigBuilder) consulPreRunE(*cobra.Command, []string) error {
+	if b.consulPath == "" {
+		return nil
+	}
+	svc, err := config.NewConsulConfigsSVC(b.consulPath, b.svc)
 	if err != nil {
 		return err
 	}
+	b.svc = svc
+	return nil
+}
+
+func (b *cmdConfigBuilder) cmdSwitchActiveRunEFn(cmd *cobra.Command, args []string) error {
 	b.name = args[0]
-	p0, ok := pp[b.name]
-	if !ok {
-		return &influxdb.Error{
-			Code: influxdb.ENotFound,
-			Msg:  fmt.Sprintf("name %q is not found", b.name),
+	var p config.Config
+	err := b.svc.UpdateConfigs(func(pp config.Configs) error {
+		if _, ok := pp[b.name]; !ok {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  fmt.Sprintf("name %q is not found", b.name),
+			}
 		}
-	}
-	pp[b.name] = p0
-
-	if err := pp.Switch(b.name); err != nil {
-		return err
-	}
 
-	if err = b.svc.WriteConfigs(pp); err != nil {
+		if err := pp.Switch(b.name); err != nil {
+			return err
+		}
+		p = pp[b.name]
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
 	return b.printConfigs(configPrintOpts{
 		config: cfg{
 			name:   b.name,
-			Config: pp[b.name],
+			Config: p,
 		},
 	})
 }
@@ -93,7 +128,7 @@ func (b *cmdConfigBuilder) cmdCreate() *cobra.Command {
 	b.registerPrintFlags(cmd)
 	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The config name (required)")
 	cmd.MarkFlagRequired("name")
-	cmd.Flags().StringVarP(&b.token, "token", "t", "", "The config token (required)")
+	cmd.Flags().StringVarP(&b.token, "token", "t", "", "The config token (required), read from stdin if -")
 	cmd.MarkFlagRequired("token")
 	cmd.Flags().StringVarP(&b.url, "url", "u", "", "The config url (required)")
 	cmd.MarkFlagRequired("url")
@@ -104,8 +139,7 @@ func (b *cmdConfigBuilder) cmdCreate() *cobra.Command {
 }
 
 func (b *cmdConfigBuilder) cmdCreateRunEFn(*cobra.Command, []string) error {
-	pp, err := b.svc.ParseConfigs()
-	if err != nil {
+	if err := b.readTokenFromStdin(); err != nil {
 		return err
 	}
 
@@ -115,21 +149,21 @@ func (b *cmdConfigBuilder) cmdCreateRunEFn(*cobra.Command, []string) error {
 		Org:    b.org,
 		Active: b.active,
 	}
-	if _, ok := pp[b.name]; ok {
-		return &influxdb.Error{
-			Code: influxdb.EConflict,
-			Msg:  fmt.Sprintf("name %q already exists", b.name),
+	err := b.svc.UpdateConfigs(func(pp config.Configs) error {
+		if _, ok := pp[b.name]; ok {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("name %q already exists", b.name),
+			}
 		}
-	}
 
-	pp[b.name] = p
-	if p.Active {
-		if err := pp.Switch(b.name); err != nil {
-			return err
+		pp[b.name] = p
+		if p.Active {
+			return pp.Switch(b.name)
 		}
-	}
-
-	if err = b.svc.WriteConfigs(pp); err != nil {
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -153,21 +187,20 @@ func (b *cmdConfigBuilder) cmdDelete() *cobra.Command {
 }
 
 func (b *cmdConfigBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) error {
-	pp, err := b.svc.ParseConfigs()
-	if err != nil {
-		return err
-	}
-
-	p, ok := pp[b.name]
-	if !ok {
-		return &influxdb.Error{
-			Code: influxdb.ENotFound,
-			Msg:  fmt.Sprintf("name %q is not found", b.name),
+	var p config.Config
+	err := b.svc.UpdateConfigs(func(pp config.Configs) error {
+		var ok bool
+		p, ok = pp[b.name]
+		if !ok {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  fmt.Sprintf("name %q is not found", b.name),
+			}
 		}
-	}
-	delete(pp, b.name)
-
-	if err = b.svc.WriteConfigs(pp); err != nil {
+		delete(pp, b.name)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -189,60 +222,85 @@ func (b *cmdConfigBuilder) cmdUpdate() *cobra.Command {
 	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The config name (required)")
 	cmd.MarkFlagRequired("name")
 
-	cmd.Flags().StringVarP(&b.token, "token", "t", "", "The config token (required)")
-	cmd.Flags().StringVarP(&b.url, "url", "u", "", "The config url (required)")
+	cmd.Flags().StringVarP(&b.token, "token", "t", "", "The new config token, read from stdin if -")
+	cmd.Flags().StringVarP(&b.url, "url", "u", "", "The new config url")
 	cmd.Flags().BoolVarP(&b.active, "active", "a", false, "Set it to be the active config")
 	cmd.Flags().StringVarP(&b.org, "org", "o", "", "The optional organization name")
 	return cmd
 }
 
 func (b *cmdConfigBuilder) cmdUpdateRunEFn(*cobra.Command, []string) error {
-	pp, err := b.svc.ParseConfigs()
-	if err != nil {
+	if err := b.readTokenFromStdin(); err != nil {
 		return err
 	}
 
-	p0, ok := pp[b.name]
-	if !ok {
-		return &influxdb.Error{
-			Code: influxdb.ENotFound,
-			Msg:  fmt.Sprintf("name %q is not found", b.name),
+	var p config.Config
+	err := b.svc.UpdateConfigs(func(pp config.Configs) error {
+		p0, ok := pp[b.name]
+		if !ok {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  fmt.Sprintf("name %q is not found", b.name),
+			}
 		}
-	}
-	if b.token != "" {
-		p0.Token = b.token
-	}
-	if b.url != "" {
-		p0.Host = b.url
-	}
-	if b.org != "" {
-		p0.Org = b.org
-	}
-
-	pp[b.name] = p0
-	if b.active {
-		if err := pp.Switch(b.name); err != nil {
-			return err
+		if b.token != "" {
+			p0.Token = b.token
+		}
+		if b.url != "" {
+			p0.Host = b.url
+		}
+		if b.org != "" {
+			p0.Org = b.org
 		}
-	}
 
-	if err = b.svc.WriteConfigs(pp); err != nil {
+		pp[b.name] = p0
+		if b.active {
+			if err := pp.Switch(b.name); err != nil {
+				return err
+			}
+		}
+		p = pp[b.name]
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
 	return b.printConfigs(configPrintOpts{
 		config: cfg{
 			name:   b.name,
-			Config: pp[b.name],
+			Config: p,
 		},
 	})
 }
 
+// readTokenFromStdin reads the token from stdin if the token flag is "-", so
+// that it is not exposed in the shell history.
+func (b *cmdConfigBuilder) readTokenFromStdin() error {
+	if b.token != "-" {
+		return nil
+	}
+	tok, err := ioutil.ReadAll(b.in)
+	if err != nil {
+		return fmt.Errorf("failed to read token from stdin: %v", err)
+	}
+	b.token = strings.TrimSpace(string(tok))
+	if b.token == "" {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "no token read from stdin",
+		}
+	}
+	return nil
+}
+
 func (b *cmdConfigBuilder) cmdList() *cobra.Command {
 	cmd := b.newCmd("list", b.cmdListRunEFn, false)
 	cmd.Aliases = []string{"ls"}
 	cmd.Short = "List configs"
 	b.registerPrintFlags(cmd)
+	cmd.Flags().StringVar(&b.hostFilter, "host-filter", "", "Only list the configs whose url contains the filter")
+	cmd.Flags().StringVar(&b.orgFilter, "org-filter", "", "Only list the configs whose org contains the filter")
 	return cmd
 }
 
@@ -251,8 +309,10 @@ func (b *cmdConfigBuilder) cmdListRunEFn(*cobra.Command, []string) error {
 	if err != nil {
 		return err
 	}
+	pp = pp.FilterByHost(b.hostFilter).FilterByOrg(b.orgFilter)
 
-	var cfgs []cfg
+	// The configs are not nil when none match, so that none are printed.
+	cfgs := make([]cfg, 0, len(pp))
 	for n, p := range pp {
 		cfgs = append(cfgs, cfg{
 			name:   n,
@@ -263,6 +323,50 @@ func (b *cmdConfigBuilder) cmdListRunEFn(*cobra.Command, []string) error {
 	return b.printConfigs(configPrintOpts{configs: cfgs})
 }
 
+// exportRedactedToken replaces the tokens of the configs exported with
+// --redact-tokens.
+const exportRedactedToken = "REDACTED"
+
+func (b *cmdConfigBuilder) cmdExport() *cobra.Command {
+	cmd := b.newCmd("export", b.cmdExportRunEFn, false)
+	cmd.Short = "Export configs as a configs file"
+	cmd.Long = `Export all configs in the format of the configs file, so that they can be copied to
+the configs file of another machine.`
+	cmd.Flags().StringVarP(&b.output, "output", "o", "", "File to write the configs to; defaults to stdout")
+	cmd.Flags().BoolVar(&b.redactTokens, "redact-tokens", false, "Replace the tokens of the configs with "+exportRedactedToken)
+	return cmd
+}
+
+func (b *cmdConfigBuilder) cmdExportRunEFn(*cobra.Command, []string) error {
+	pp, err := b.svc.ParseConfigs()
+	if err != nil {
+		return err
+	}
+
+	if b.redactTokens {
+		redacted := make(config.Configs, len(pp))
+		for name, p := range pp {
+			if p.Token != "" {
+				p.Token = exportRedactedToken
+			}
+			redacted[name] = p
+		}
+		pp = redacted
+	}
+
+	buf, err := config.EncodeConfigs(pp)
+	if err != nil {
+		return err
+	}
+
+	if b.output == "" {
+		_, err := b.w.Write(buf)
+		return err
+	}
+	// the configs file holds tokens, so it is only readable by its owner
+	return ioutil.WriteFile(b.output, buf, 0600)
+}
+
 func (b *cmdConfigBuilder) registerPrintFlags(cmd *cobra.Command) {
 	registerPrintOptions(cmd, &b.hideHeaders, &b.json)
 }
@@ -281,7 +385,7 @@ func (b *cmdConfigBuilder) printConfigs(opts configPrintOpts) error {
 
 	w.HideHeaders(b.hideHeaders)
 
-	headers := []string{"Active", "Name", "URL", "Org"}
+	headers := []string{"Active", "Previous", "Name", "URL", "Org"}
 	if opts.delete {
 		headers = append(headers, "Deleted")
 	}
@@ -291,15 +395,19 @@ func (b *cmdConfigBuilder) printConfigs(opts configPrintOpts) error {
 		opts.configs = append(opts.configs, opts.config)
 	}
 	for _, c := range opts.configs {
-		var active string
+		var active, previous string
 		if c.Active {
 			active = "*"
 		}
+		if c.PreviousActive {
+			previous = "*"
+		}
 		m := map[string]interface{}{
-			"Active": active,
-			"Name":   c.name,
-			"URL":    c.Host,
-			"Org":    c.Org,
+			"Active":   active,
+			"Previous": previous,
+			"Name":     c.name,
+			"URL":      c.Host,
+			"Org":      c.Org,
 		}
 		if opts.delete {
 			m["Deleted"] = true
@@ -323,3 +431,9 @@ type (
 		config.Config
 	}
 )
+
+// MarshalJSON encodes the config with its token masked, so that it is not
+// exposed in the output of the commands.
+func (c cfg) MarshalJSON() ([]byte, error) {
+	return c.Config.MarshalJSONSafe()
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb"
@@ -19,6 +22,52 @@ type Config struct {
 	Token  string `toml:"token" json:"token"`
 	Org    string `toml:"org" json:"org"`
 	Active bool   `toml:"active" json:"active"`
+	// PreviousActive is set on the config that was active before the last
+	// switch.
+	PreviousActive bool `toml:"previous,omitempty" json:"previous,omitempty"`
+	// Schema is the version of the schema of the config. Configs written
+	// before it was versioned have none.
+	Schema int `toml:"schema_version,omitempty" json:"schema_version,omitempty"`
+}
+
+// SchemaVersion is the version of the schema of the configs written by this
+// version of the CLI. It is incremented by changes to Config that older
+// versions cannot read.
+const SchemaVersion = 1
+
+// RedactedToken replaces a token too short to be masked in the output of
+// MarshalJSONSafe.
+const RedactedToken = "<redacted>"
+
+// minMaskedTokenLen is the length of the shortest token masked rather than
+// redacted, so that the characters shown are a small part of the token.
+const minMaskedTokenLen = 16
+
+// MarshalJSONSafe returns the JSON encoding of the config with its token
+// masked as "..." followed by its last 4 characters, or replaced by
+// RedactedToken if it is short. It is meant for output that may be logged or
+// recorded; json.Marshal encodes the full config.
+func (c Config) MarshalJSONSafe() ([]byte, error) {
+	c.Token = maskToken(c.Token)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func maskToken(token string) string {
+	switch {
+	case token == "":
+		return ""
+	case len(token) < minMaskedTokenLen:
+		return RedactedToken
+	default:
+		return "..." + token[len(token)-4:]
+	}
 }
 
 // DefaultConfig is default config without token
@@ -34,9 +83,13 @@ type Configs map[string]Config
 type ConfigsService interface {
 	WriteConfigs(pp Configs) error
 	ParseConfigs() (Configs, error)
+	// UpdateConfigs calls fn with the configs and writes them back, unless
+	// fn returns an error.
+	UpdateConfigs(fn func(pp Configs) error) error
 }
 
-// Switch to another config.
+// Switch to another config. The config active before the switch is marked
+// as previously active, unless it is the config switched to.
 func (pp *Configs) Switch(name string) error {
 	pc := *pp
 	if _, ok := pc[name]; !ok {
@@ -45,48 +98,191 @@ func (pp *Configs) Switch(name string) error {
 			Msg:  fmt.Sprintf(`config %q is not found`, name),
 		}
 	}
+	var switched bool
 	for k, v := range pc {
+		switched = switched || (v.Active && k != name)
+	}
+	for k, v := range pc {
+		if switched {
+			v.PreviousActive = v.Active && k != name
+		}
 		v.Active = k == name
 		pc[k] = v
 	}
 	return nil
 }
 
+// FilterByHost returns the configs whose host contains substr.
+func (pp Configs) FilterByHost(substr string) Configs {
+	return pp.filter(func(p Config) bool {
+		return strings.Contains(p.Host, substr)
+	})
+}
+
+// FilterByOrg returns the configs whose org contains substr.
+func (pp Configs) FilterByOrg(substr string) Configs {
+	return pp.filter(func(p Config) bool {
+		return strings.Contains(p.Org, substr)
+	})
+}
+
+func (pp Configs) filter(fn func(p Config) bool) Configs {
+	filtered := make(Configs)
+	for name, p := range pp {
+		if fn(p) {
+			filtered[name] = p
+		}
+	}
+	return filtered
+}
+
 // LocalConfigsSVC has the path and dir to write and parse configs.
+//
+// A configs file that is a PGP-armored message is decrypted with gpg before
+// it is parsed, and written back encrypted to the GPG key whose ID is in the
+// KeyIDFile of the dir.
 type LocalConfigsSVC struct {
 	Path string
 	Dir  string
+	// GPG is the gpg executable, found in the PATH when empty.
+	GPG string
 }
 
 // ParseConfigs from the local path.
 func (svc LocalConfigsSVC) ParseConfigs() (Configs, error) {
-	r, err := os.Open(svc.Path)
+	b, err := ioutil.ReadFile(svc.Path)
 	if err != nil {
 		return make(Configs), nil
 	}
-	return ParseConfigs(r)
+	if isEncrypted(b) {
+		if b, err = svc.decrypt(b); err != nil {
+			return make(Configs), err
+		}
+	}
+	return ParseConfigs(bytes.NewReader(b))
 }
 
-// WriteConfigs to the path.
+// WriteConfigs to the path. The configs are written to a temporary file
+// renamed to the path, holding the lock of the configs, so that concurrent
+// writes do not corrupt the file.
 func (svc LocalConfigsSVC) WriteConfigs(pp Configs) error {
+	unlock, err := svc.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return svc.replaceConfigs(pp)
+}
+
+// UpdateConfigs parses the configs from the path, calls fn with them and
+// writes them back. The lock of the configs is held throughout, so that
+// concurrent updates are not lost.
+func (svc LocalConfigsSVC) UpdateConfigs(fn func(pp Configs) error) error {
+	unlock, err := svc.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pp, err := svc.ParseConfigs()
+	if err != nil {
+		return err
+	}
+	if err := fn(pp); err != nil {
+		return err
+	}
+	return svc.replaceConfigs(pp)
+}
+
+// lock acquires the lock of the configs, and returns the function releasing
+// it. The lock is taken on a separate file, since the configs file is
+// replaced when written.
+func (svc LocalConfigsSVC) lock() (unlock func() error, err error) {
 	if err := os.MkdirAll(svc.Dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(svc.Path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		defer f.Close()
+		return unlockFile(f)
+	}, nil
+}
+
+// replaceConfigs writes the configs to a temporary file and renames it to the
+// path, encrypting them if the file it replaces is encrypted. The lock of the
+// configs must be held.
+func (svc LocalConfigsSVC) replaceConfigs(pp Configs) error {
+	b, err := writeConfigs(pp)
+	if err != nil {
 		return err
 	}
-	var b1, b2 bytes.Buffer
-	err := toml.NewEncoder(&b1).Encode(pp)
+	if old, err := ioutil.ReadFile(svc.Path); err == nil && isEncrypted(old) {
+		keyID, err := svc.keyID()
+		if err != nil {
+			return err
+		}
+		if b, err = svc.encrypt(b, keyID); err != nil {
+			return err
+		}
+	}
+
+	tmpPath := svc.Path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
 	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, svc.Path)
+}
+
+// EncodeConfigs returns the configs encoded as they are written to the
+// configs file, so that they can replace the configs file of another machine.
+func EncodeConfigs(pp Configs) ([]byte, error) {
+	return writeConfigs(pp)
+}
+
+// writeConfigs encodes configs with the current schema version, followed by
+// the cloud 2 clusters commented out.
+func writeConfigs(pp Configs) ([]byte, error) {
+	versioned := make(Configs, len(pp))
+	for name, p := range pp {
+		p.Schema = SchemaVersion
+		versioned[name] = p
+	}
+
+	var b1, b2 bytes.Buffer
+	err := toml.NewEncoder(&b1).Encode(versioned)
+	if err != nil {
+		return nil, err
+	}
 	// a list cloud 2 clusters, commented out
 	b1.WriteString("# \n")
 	pp = map[string]Config{
-		"us-central": {Host: "https://us-central1-1.gcp.cloud2.influxdata.com", Token: "XXX"},
-		"us-west":    {Host: "https://us-west-2-1.aws.cloud2.influxdata.com", Token: "XXX"},
-		"eu-central": {Host: "https://eu-central-1-1.aws.cloud2.influxdata.com", Token: "XXX"},
+		"us-central": {Host: "https://us-central1-1.gcp.cloud2.influxdata.com", Token: "XXX", Schema: SchemaVersion},
+		"us-west":    {Host: "https://us-west-2-1.aws.cloud2.influxdata.com", Token: "XXX", Schema: SchemaVersion},
+		"eu-central": {Host: "https://eu-central-1-1.aws.cloud2.influxdata.com", Token: "XXX", Schema: SchemaVersion},
 	}
 
 	if err := toml.NewEncoder(&b2).Encode(pp); err != nil {
-		return err
+		return nil, err
 	}
 	reader := bufio.NewReader(&b2)
 	for {
@@ -97,14 +293,46 @@ func (svc LocalConfigsSVC) WriteConfigs(pp Configs) error {
 		}
 		b1.WriteString("# " + string(line) + "\n")
 	}
-	return ioutil.WriteFile(svc.Path, b1.Bytes(), 0600)
+	return b1.Bytes(), nil
 }
 
-// ParseConfigs decodes configs from io readers
+// ParseConfigs decodes configs from io readers. Unknown fields of configs of
+// the current schema version are an error, and configs of a newer version
+// are parsed with a warning written to stderr.
 func ParseConfigs(r io.Reader) (Configs, error) {
+	return parseConfigs(r, os.Stderr)
+}
+
+func parseConfigs(r io.Reader, warnings io.Writer) (Configs, error) {
 	p := make(Configs)
-	_, err := toml.DecodeReader(r, &p)
-	return p, err
+	md, err := toml.DecodeReader(r, &p)
+	if err != nil {
+		return p, err
+	}
+
+	var names []string
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if schema := p[name].Schema; schema > SchemaVersion {
+			fmt.Fprintf(warnings, "Warning: config %q has schema version %d, newer than version %d of this version of influx; fields it does not know are ignored\n", name, schema, SchemaVersion)
+		}
+	}
+
+	for _, key := range md.Undecoded() {
+		if len(key) < 2 {
+			continue
+		}
+		if name := key[0]; p[name].Schema == SchemaVersion {
+			return p, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("unknown field %q in config %q", key[1:].String(), name),
+			}
+		}
+	}
+	return p, nil
 }
 
 // ParseActiveConfig returns the active config from the reader.
@@ -113,6 +341,11 @@ func ParseActiveConfig(r io.Reader) (Config, error) {
 	if err != nil {
 		return DefaultConfig, err
 	}
+	return ActiveConfig(pp)
+}
+
+// ActiveConfig returns the active config of the configs.
+func ActiveConfig(pp Configs) (Config, error) {
 	var activated Config
 	var hasActive bool
 	for _, p := range pp {
@@ -0,0 +1,51 @@
+//go:build go1.18
+// +build go1.18
+
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzParseConfigs checks that ParseConfigs does not panic on any input. Run
+// it with go test -fuzz=FuzzParseConfigs.
+func FuzzParseConfigs(f *testing.F) {
+	// The sources of TestParseActiveConfig. All but the first are valid TOML.
+	seeds := []string{
+		"bad [toml",
+		"",
+		`
+			[a1]
+			url = "host1"
+			active =true
+			[a2]
+			url = "host2"
+			active = true
+			`,
+		`
+			[a1]
+			url = "host1"
+			[a2]
+			url = "host2"
+			active = true
+			[a3]
+			url = "host3"
+			[a4]
+			url = "host4"
+			`,
+	}
+	for i, src := range seeds {
+		if _, err := ParseConfigs(bytes.NewBufferString(src)); i > 0 && err != nil {
+			f.Fatalf("parse configs of seed %d failed: %v", i, err)
+		}
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		_, err := ParseConfigs(bytes.NewBufferString(src))
+		if err != nil && err.Error() == "" {
+			t.Fatalf("parse configs of %q returned an error without a message", src)
+		}
+	})
+}
@@ -2,11 +2,19 @@ package config
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
+	"unicode"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
 	influxtesting "github.com/influxdata/influxdb/testing"
+	"pgregory.net/rapid"
 )
 
 func TestParseActiveConfig(t *testing.T) {
@@ -72,6 +80,74 @@ func TestParseActiveConfig(t *testing.T) {
 	}
 }
 
+func TestParseConfigsSchema(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		pp      Configs
+		warning string
+		err     error
+	}{
+		{
+			name: "current",
+			src: `
+			[a1]
+			url = "host1"
+			active = true
+			schema_version = 1
+			`,
+			pp: Configs{"a1": {Host: "host1", Active: true, Schema: 1}},
+		},
+		{
+			name: "future",
+			src: `
+			[a1]
+			url = "host1"
+			schema_version = 99
+			new_field = "value"
+			`,
+			pp:      Configs{"a1": {Host: "host1", Schema: 99}},
+			warning: `Warning: config "a1" has schema version 99, newer than version 1 of this version of influx; fields it does not know are ignored` + "\n",
+		},
+		{
+			name: "unknown field",
+			src: `
+			[a1]
+			url = "host1"
+			schema_version = 1
+			new_field = "value"
+			`,
+			pp: Configs{"a1": {Host: "host1", Schema: 1}},
+			err: &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  `unknown field "new_field" in config "a1"`,
+			},
+		},
+		{
+			name: "unversioned",
+			src: `
+			[a1]
+			url = "host1"
+			new_field = "value"
+			`,
+			pp: Configs{"a1": {Host: "host1"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			pp, err := parseConfigs(bytes.NewBufferString(c.src), &warnings)
+			influxtesting.ErrorsEqual(t, err, c.err)
+			if diff := cmp.Diff(pp, c.pp); diff != "" {
+				t.Fatalf("parse configs failed, diff %s", diff)
+			}
+			if got := warnings.String(); got != c.warning {
+				t.Fatalf("got warning %q, exp %q", got, c.warning)
+			}
+		})
+	}
+}
+
 func TestConfigsSwith(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -105,10 +181,38 @@ func TestConfigsSwith(t *testing.T) {
 				"a3": {Host: "host3"},
 			},
 			new: Configs{
-				"a1": {Host: "host1"},
+				"a1": {Host: "host1", PreviousActive: true},
+				"a2": {Host: "host2"},
+				"a3": {Host: "host3", Active: true},
+			},
+			err: nil,
+		},
+		{
+			name:   "switch replaces previous",
+			target: "a2",
+			old: Configs{
+				"a1": {Host: "host1", PreviousActive: true},
 				"a2": {Host: "host2"},
 				"a3": {Host: "host3", Active: true},
 			},
+			new: Configs{
+				"a1": {Host: "host1"},
+				"a2": {Host: "host2", Active: true},
+				"a3": {Host: "host3", PreviousActive: true},
+			},
+			err: nil,
+		},
+		{
+			name:   "switch to active",
+			target: "a3",
+			old: Configs{
+				"a1": {Host: "host1", PreviousActive: true},
+				"a3": {Host: "host3", Active: true},
+			},
+			new: Configs{
+				"a1": {Host: "host1", PreviousActive: true},
+				"a3": {Host: "host3", Active: true},
+			},
 			err: nil,
 		},
 	}
@@ -120,3 +224,229 @@ func TestConfigsSwith(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigMarshalJSONSafe(t *testing.T) {
+	cases := []struct {
+		name     string
+		token    string
+		previous bool
+		exp      string
+	}{
+		{
+			name:  "masked",
+			token: "VxgGvxJLaSVSzH1ud8OzpjGJQnMd2MQo7p7K2Mn9QYyVDLpZ-Ta8EBqP6KHZJ9X6bTkmsCGo2A3wRzGwvw3Amg==",
+			exp:   `{"url":"http://localhost:9999","token":"...mg==","org":"org1","active":true}`,
+		},
+		{
+			name:  "redacted",
+			token: "tok1",
+			exp:   `{"url":"http://localhost:9999","token":"<redacted>","org":"org1","active":true}`,
+		},
+		{
+			name: "no token",
+			exp:  `{"url":"http://localhost:9999","token":"","org":"org1","active":true}`,
+		},
+		{
+			name:     "previous",
+			token:    "tok1",
+			previous: true,
+			exp:      `{"url":"http://localhost:9999","token":"<redacted>","org":"org1","active":true,"previous":true}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := Config{Host: "http://localhost:9999", Token: c.token, Org: "org1", Active: true, PreviousActive: c.previous}
+			b, err := cfg.MarshalJSONSafe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.token != "" && bytes.Contains(b, []byte(c.token)) {
+				t.Fatalf("got %s, contains token %q", b, c.token)
+			}
+			if got := string(b); got != c.exp {
+				t.Fatalf("got %s, exp %s", got, c.exp)
+			}
+		})
+	}
+}
+
+func TestConfigsFilter(t *testing.T) {
+	pp := Configs{
+		"local":      {Host: "http://localhost:9999", Org: "dev"},
+		"local-8086": {Host: "http://localhost:8086", Org: "dev"},
+		"us-west":    {Host: "https://us-west-2-1.aws.cloud2.influxdata.com", Org: "prod"},
+		"eu-central": {Host: "https://eu-central-1-1.aws.cloud2.influxdata.com", Org: "prod-eu"},
+	}
+	cases := []struct {
+		name string
+		fn   func(Configs) Configs
+		exp  []string
+	}{
+		{
+			name: "host",
+			fn:   func(pp Configs) Configs { return pp.FilterByHost("localhost") },
+			exp:  []string{"local", "local-8086"},
+		},
+		{
+			name: "empty host",
+			fn:   func(pp Configs) Configs { return pp.FilterByHost("") },
+			exp:  []string{"eu-central", "local", "local-8086", "us-west"},
+		},
+		{
+			name: "org",
+			fn:   func(pp Configs) Configs { return pp.FilterByOrg("prod") },
+			exp:  []string{"eu-central", "us-west"},
+		},
+		{
+			name: "empty org",
+			fn:   func(pp Configs) Configs { return pp.FilterByOrg("") },
+			exp:  []string{"eu-central", "local", "local-8086", "us-west"},
+		},
+		{
+			name: "host and org",
+			fn:   func(pp Configs) Configs { return pp.FilterByHost("aws").FilterByOrg("eu") },
+			exp:  []string{"eu-central"},
+		},
+		{
+			name: "no match",
+			fn:   func(pp Configs) Configs { return pp.FilterByHost("gcp") },
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.fn(pp)
+			var names []string
+			for name, p := range got {
+				if p != pp[name] {
+					t.Fatalf("got config %v for %q, exp %v", p, name, pp[name])
+				}
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if diff := cmp.Diff(names, c.exp); diff != "" {
+				t.Fatalf("filter configs failed, diff %s", diff)
+			}
+		})
+	}
+}
+
+func TestLocalConfigsSVC_Concurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	svc := LocalConfigsSVC{Path: filepath.Join(dir, "configs"), Dir: dir}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for _, prefix := range []string{"a", "b"} {
+		prefix := prefix
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				name := fmt.Sprintf("%s%d", prefix, i)
+				errs <- svc.UpdateConfigs(func(pp Configs) error {
+					pp[name] = Config{Host: "http://" + name + ":9999", Token: name}
+					return nil
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The file is valid TOML and contains the configs of both goroutines.
+	b, err := ioutil.ReadFile(svc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ParseConfigs(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("parse configs of %q failed: %v", b, err)
+	}
+	exp := make(Configs)
+	for _, prefix := range []string{"a", "b"} {
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("%s%d", prefix, i)
+			exp[name] = Config{Host: "http://" + name + ":9999", Token: name, Schema: SchemaVersion}
+		}
+	}
+	if diff := cmp.Diff(exp, pp); diff != "" {
+		t.Fatalf("concurrent updates of configs failed, diff %s", diff)
+	}
+
+	// Concurrent writes replace the file with one of the configs written.
+	written := []Configs{
+		{"a": {Host: "http://a:9999", Token: "a", Schema: SchemaVersion}},
+		{"b": {Host: "http://b:9999", Token: "b", Active: true, Schema: SchemaVersion}},
+	}
+	errs = make(chan error, 2*n)
+	for _, pp := range written {
+		pp := pp
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				errs <- svc.WriteConfigs(pp)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pp, err = svc.ParseConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(pp, written[0]) && !cmp.Equal(pp, written[1]) {
+		t.Fatalf("got configs %v, exp one of %v", pp, written)
+	}
+}
+
+func TestConfigsRoundTrip(t *testing.T) {
+	// The TOML encoder does not escape control characters, so tokens and orgs
+	// are printable.
+	printable := rapid.RuneFrom(nil, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs)
+	config := rapid.Custom(func(t *rapid.T) Config {
+		return Config{
+			Host: rapid.StringMatching(`https?://[a-z0-9.-]{1,30}(:[0-9]{1,5})?(/[a-z0-9/._-]{0,20})?(\?[a-z0-9=&]{0,20})?(#[a-z0-9-]{0,10})?`).Draw(t, "url").(string),
+			Token: rapid.OneOf(
+				rapid.StringMatching(`[A-Za-z0-9+/_-]{0,198}={0,2}`),
+				rapid.StringOfN(printable, 0, 200, -1),
+			).Draw(t, "token").(string),
+			Org:            rapid.StringOf(printable).Draw(t, "org").(string),
+			Active:         rapid.Bool().Draw(t, "active").(bool),
+			PreviousActive: rapid.Bool().Draw(t, "previous").(bool),
+			// Configs are written with the current schema version.
+			Schema: SchemaVersion,
+		}
+	})
+	configs := rapid.MapOf(rapid.StringMatching(`[A-Za-z0-9_-]{1,20}`), config)
+
+	rapid.Check(t, func(t *rapid.T) {
+		pp := Configs(configs.Draw(t, "configs").(map[string]Config))
+		b, err := writeConfigs(pp)
+		if err != nil {
+			t.Fatalf("write configs failed: %v", err)
+		}
+		got, err := ParseConfigs(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("parse configs of %q failed: %v", b, err)
+		}
+		if diff := cmp.Diff(got, pp); diff != "" {
+			t.Fatalf("parse configs of %q failed, diff %s", b, diff)
+		}
+	})
+}
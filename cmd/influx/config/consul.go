@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/influxdata/influxdb"
+)
+
+// ConsulConfigsSVC keeps the configs in a key of the Consul KV store, in the
+// format of the local configs file. The Consul agent and its ACL token are
+// taken from the CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN environment variables.
+type ConsulConfigsSVC struct {
+	KV *api.KV
+	// Path is the key of the configs, such as influx/configs/myorg.
+	Path string
+	// Local are the configs parsed when Consul is unreachable.
+	Local ConfigsService
+}
+
+// NewConsulConfigsSVC returns a service of the configs at the path of the
+// Consul KV store, falling back to local when Consul is unreachable.
+func NewConsulConfigsSVC(path string, local ConfigsService) (*ConsulConfigsSVC, error) {
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &ConsulConfigsSVC{
+		KV:    client.KV(),
+		Path:  path,
+		Local: local,
+	}, nil
+}
+
+// ParseConfigs from the Consul KV store, or from the local configs if Consul
+// is unreachable.
+func (svc *ConsulConfigsSVC) ParseConfigs() (Configs, error) {
+	pp, _, err := svc.get()
+	if influxdb.ErrorCode(err) == influxdb.EUnavailable && svc.Local != nil {
+		return svc.Local.ParseConfigs()
+	}
+	return pp, err
+}
+
+// WriteConfigs to the Consul KV store.
+func (svc *ConsulConfigsSVC) WriteConfigs(pp Configs) error {
+	b, err := writeConfigs(pp)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.KV.Put(&api.KVPair{Key: svc.Path, Value: b}, nil); err != nil {
+		return svc.unreachable(err)
+	}
+	return nil
+}
+
+// UpdateConfigs reads the configs from the Consul KV store, calls fn with them
+// and writes them back, unless they were written by someone else in the
+// meantime, in which case the update is retried.
+func (svc *ConsulConfigsSVC) UpdateConfigs(fn func(pp Configs) error) error {
+	for {
+		pp, index, err := svc.get()
+		if err != nil {
+			return err
+		}
+		if err := fn(pp); err != nil {
+			return err
+		}
+
+		b, err := writeConfigs(pp)
+		if err != nil {
+			return err
+		}
+		// A ModifyIndex of 0 only writes the key if it does not exist.
+		ok, _, err := svc.KV.CAS(&api.KVPair{Key: svc.Path, Value: b, ModifyIndex: index}, nil)
+		if err != nil {
+			return svc.unreachable(err)
+		}
+		if ok {
+			return nil
+		}
+	}
+}
+
+// get returns the configs and the index of their last modification, which
+// is 0 if there are none.
+func (svc *ConsulConfigsSVC) get() (Configs, uint64, error) {
+	pair, _, err := svc.KV.Get(svc.Path, nil)
+	if err != nil {
+		return make(Configs), 0, svc.unreachable(err)
+	}
+	if pair == nil {
+		return make(Configs), 0, nil
+	}
+	pp, err := ParseConfigs(bytes.NewReader(pair.Value))
+	return pp, pair.ModifyIndex, err
+}
+
+func (svc *ConsulConfigsSVC) unreachable(err error) error {
+	return &influxdb.Error{
+		Code: influxdb.EUnavailable,
+		Msg:  fmt.Sprintf("failed to reach the configs %s in Consul", svc.Path),
+		Err:  err,
+	}
+}
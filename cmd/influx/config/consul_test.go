@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/consul/api"
+)
+
+// consulKV serves the KV endpoints of the Consul HTTP API used by
+// ConsulConfigsSVC, requiring an ACL token.
+type consulKV struct {
+	token string
+
+	mu    sync.Mutex
+	index uint64
+	pairs map[string]*api.KVPair
+}
+
+func (kv *consulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != kv.token {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		pair, ok := kv.pairs[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]*api.KVPair{pair})
+	case http.MethodPut:
+		value, _ := ioutil.ReadAll(r.Body)
+		if cas := r.URL.Query().Get("cas"); cas != "" {
+			var index uint64
+			if pair, ok := kv.pairs[key]; ok {
+				index = pair.ModifyIndex
+			}
+			if cas != strconv.FormatUint(index, 10) {
+				w.Write([]byte("false"))
+				return
+			}
+		}
+		kv.index++
+		kv.pairs[key] = &api.KVPair{Key: key, Value: value, ModifyIndex: kv.index}
+		w.Write([]byte("true"))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// setConsulEnv sets the address and token of the Consul agent, and returns
+// the function restoring them.
+func setConsulEnv(t *testing.T, addr, token string) (restore func()) {
+	t.Helper()
+	var restores []func()
+	for k, v := range map[string]string{
+		api.HTTPAddrEnvName:  addr,
+		api.HTTPTokenEnvName: token,
+	} {
+		old, ok := os.LookupEnv(k)
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+		k := k
+		restores = append(restores, func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+	return func() {
+		for _, fn := range restores {
+			fn()
+		}
+	}
+}
+
+func TestConsulConfigsSVC(t *testing.T) {
+	kv := &consulKV{token: "s3cret", pairs: make(map[string]*api.KVPair)}
+	ts := httptest.NewServer(kv)
+	defer ts.Close()
+	defer setConsulEnv(t, ts.URL, "s3cret")()
+
+	svc, err := NewConsulConfigsSVC("influx/configs/myorg", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, err := svc.ParseConfigs()
+	if err != nil {
+		t.Fatalf("failed to parse missing configs: %v", err)
+	}
+	if len(pp) != 0 {
+		t.Fatalf("unexpected configs: %v", pp)
+	}
+
+	want := Configs{
+		"default": {Host: "http://localhost:9999", Token: "token1", Org: "myorg", Active: true, Schema: SchemaVersion},
+	}
+	if err := svc.WriteConfigs(want); err != nil {
+		t.Fatalf("failed to write configs: %v", err)
+	}
+	err = svc.UpdateConfigs(func(pp Configs) error {
+		pp["other"] = Config{Host: "http://other:9999", Token: "token2"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to update configs: %v", err)
+	}
+	want["other"] = Config{Host: "http://other:9999", Token: "token2", Schema: SchemaVersion}
+
+	got, err := svc.ParseConfigs()
+	if err != nil {
+		t.Fatalf("failed to parse configs: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected configs (-want +got):\n%s", diff)
+	}
+	if _, ok := kv.pairs["influx/configs/myorg"]; !ok {
+		t.Errorf("configs were not written to their key: %v", kv.pairs)
+	}
+}
+
+func TestConsulConfigsSVC_Unreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+	defer setConsulEnv(t, ts.URL, "")()
+
+	local := Configs{"local": {Host: "http://localhost:9999", Active: true}}
+	svc, err := NewConsulConfigsSVC("influx/configs/myorg", &MockConfigService{
+		ParseConfigsFn: func() (Configs, error) {
+			return local, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.ParseConfigs()
+	if err != nil {
+		t.Fatalf("failed to fall back to the local configs: %v", err)
+	}
+	if diff := cmp.Diff(local, got); diff != "" {
+		t.Errorf("unexpected configs (-want +got):\n%s", diff)
+	}
+
+	if err := svc.WriteConfigs(local); err == nil {
+		t.Error("expected error writing configs to unreachable Consul")
+	}
+}
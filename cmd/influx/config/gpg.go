@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+)
+
+// pgpMessageHeader begins a PGP-armored message, such as a configs file
+// encrypted with gpg --armor.
+const pgpMessageHeader = "-----BEGIN PGP MESSAGE-----"
+
+// KeyIDFile is the name of the file in the configs dir holding the ID of the
+// GPG key that an encrypted configs file is encrypted to.
+const KeyIDFile = ".influxconfigkeyid"
+
+// defaultGPG is the gpg executable used when LocalConfigsSVC.GPG is empty.
+const defaultGPG = "gpg"
+
+// isEncrypted reports whether b is a PGP-armored message.
+func isEncrypted(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte(pgpMessageHeader))
+}
+
+func (svc LocalConfigsSVC) gpg() string {
+	if svc.GPG != "" {
+		return svc.GPG
+	}
+	return defaultGPG
+}
+
+// keyID returns the ID of the GPG key the configs are encrypted to, read from
+// the key ID file in the configs dir.
+func (svc LocalConfigsSVC) keyID() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(svc.Dir, KeyIDFile))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if id := strings.TrimSpace(string(b)); id != "" {
+		return id, nil
+	}
+	return "", &influxdb.Error{
+		Code: influxdb.EInvalid,
+		Msg:  fmt.Sprintf("configs file %s is encrypted, but %s holds no GPG key ID to encrypt it to", svc.Path, filepath.Join(svc.Dir, KeyIDFile)),
+	}
+}
+
+// decrypt decrypts the PGP-armored configs with gpg.
+func (svc LocalConfigsSVC) decrypt(b []byte) ([]byte, error) {
+	return svc.runGPG("decrypt", b, "--batch", "--quiet", "--decrypt")
+}
+
+// encrypt encrypts the configs with gpg to the key with the ID, as a
+// PGP-armored message.
+func (svc LocalConfigsSVC) encrypt(b []byte, keyID string) ([]byte, error) {
+	return svc.runGPG("encrypt", b, "--batch", "--quiet", "--yes", "--armor", "--encrypt", "--recipient", keyID)
+}
+
+func (svc LocalConfigsSVC) runGPG(op string, in []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(svc.gpg(), args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  fmt.Sprintf("failed to %s configs with %s: %s", op, svc.gpg(), msg),
+			Err:  err,
+		}
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// mockGPG "encrypts" to an armored base64 encoding of its input, and records
+// its arguments in a file next to it.
+const mockGPG = `#!/bin/sh
+echo "$@" >> "$0.args"
+case " $* " in
+*" --decrypt "*)
+	sed '1,/^$/d;$d' | base64 -d
+	;;
+*" --encrypt "*)
+	echo "-----BEGIN PGP MESSAGE-----"
+	echo
+	base64
+	echo "-----END PGP MESSAGE-----"
+	;;
+*)
+	exit 2
+	;;
+esac
+`
+
+func TestLocalConfigsSVC_Encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-config-gpg-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	gpg := filepath.Join(dir, "gpg")
+	if err := ioutil.WriteFile(gpg, []byte(mockGPG), 0700); err != nil {
+		t.Fatal(err)
+	}
+	fixture, err := ioutil.ReadFile(filepath.Join("testdata", "configs.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := LocalConfigsSVC{
+		Path: filepath.Join(dir, "configs"),
+		Dir:  dir,
+		GPG:  gpg,
+	}
+	if err := ioutil.WriteFile(svc.Path, fixture, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pp, err := svc.ParseConfigs()
+	if err != nil {
+		t.Fatalf("failed to parse encrypted configs: %v", err)
+	}
+	want := Configs{
+		"default": {
+			Host:   "http://localhost:9999",
+			Token:  "secret-token",
+			Org:    "myorg",
+			Active: true,
+			Schema: SchemaVersion,
+		},
+	}
+	if diff := cmp.Diff(want, pp); diff != "" {
+		t.Fatalf("unexpected configs (-want +got):\n%s", diff)
+	}
+
+	// The configs cannot be encrypted again without the key ID.
+	if err := svc.WriteConfigs(pp); err == nil {
+		t.Fatal("expected error writing encrypted configs without a key ID")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, KeyIDFile), []byte("0xDEADBEEF\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pp["other"] = Config{Host: "http://other:9999", Token: "other-token"}
+	if err := svc.WriteConfigs(pp); err != nil {
+		t.Fatalf("failed to write encrypted configs: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(svc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(b) || strings.Contains(string(b), "other-token") {
+		t.Fatalf("configs were not written encrypted:\n%s", b)
+	}
+	args, err := ioutil.ReadFile(gpg + ".args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--recipient 0xDEADBEEF") {
+		t.Errorf("configs were not encrypted to the key ID; gpg was run with:\n%s", args)
+	}
+
+	got, err := svc.ParseConfigs()
+	if err != nil {
+		t.Fatalf("failed to parse written configs: %v", err)
+	}
+	want["other"] = Config{Host: "http://other:9999", Token: "other-token", Schema: SchemaVersion}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected configs (-want +got):\n%s", diff)
+	}
+}
@@ -15,3 +15,16 @@ func (s *MockConfigService) WriteConfigs(pp Configs) error {
 func (s *MockConfigService) ParseConfigs() (Configs, error) {
 	return s.ParseConfigsFn()
 }
+
+// UpdateConfigs calls fn with the configs of the parse fn, and the write fn
+// with them.
+func (s *MockConfigService) UpdateConfigs(fn func(pp Configs) error) error {
+	pp, err := s.ParseConfigsFn()
+	if err != nil {
+		return err
+	}
+	if err := fn(pp); err != nil {
+		return err
+	}
+	return s.WriteConfigsFn(pp)
+}
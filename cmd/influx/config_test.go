@@ -2,8 +2,12 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -125,10 +129,11 @@ func TestCmdConfig(t *testing.T) {
 				},
 				expected: config.Configs{
 					"config1": {
-						Org:    "org2",
-						Active: false,
-						Token:  "tok2",
-						Host:   "http://localhost:8888",
+						Org:            "org2",
+						Active:         false,
+						PreviousActive: true,
+						Token:          "tok2",
+						Host:           "http://localhost:8888",
 					},
 					"default": {
 						Org:    "org1",
@@ -183,6 +188,8 @@ func TestCmdConfig(t *testing.T) {
 			original config.Configs
 			expected config.Configs
 			flags    []string
+			stdin    string
+			err      bool
 		}{
 			{
 				name: "basic",
@@ -236,6 +243,83 @@ func TestCmdConfig(t *testing.T) {
 					},
 				},
 			},
+			{
+				name: "token only",
+				flags: []string{
+					"--name", "default",
+					"--token", "tok3",
+				},
+				original: config.Configs{
+					"default": {
+						Org:    "org2",
+						Active: true,
+						Token:  "tok2",
+						Host:   "http://localhost:8888",
+					},
+				},
+				expected: config.Configs{
+					"default": {
+						Org:    "org2",
+						Active: true,
+						Token:  "tok3",
+						Host:   "http://localhost:8888",
+					},
+				},
+			},
+			{
+				name: "token from stdin",
+				flags: []string{
+					"--name", "default",
+					"--token", "-",
+				},
+				stdin: "tok3\n",
+				original: config.Configs{
+					"default": {
+						Org:    "org2",
+						Active: true,
+						Token:  "tok2",
+						Host:   "http://localhost:8888",
+					},
+				},
+				expected: config.Configs{
+					"default": {
+						Org:    "org2",
+						Active: true,
+						Token:  "tok3",
+						Host:   "http://localhost:8888",
+					},
+				},
+			},
+			{
+				name: "empty token from stdin",
+				flags: []string{
+					"--name", "default",
+					"--token", "-",
+				},
+				original: config.Configs{
+					"default": {
+						Org:   "org2",
+						Token: "tok2",
+						Host:  "http://localhost:8888",
+					},
+				},
+				err: true,
+			},
+			{
+				name: "not found",
+				flags: []string{
+					"--name", "other",
+					"--token", "tok3",
+				},
+				original: config.Configs{
+					"default": {
+						Org:   "org2",
+						Token: "tok2",
+						Host:  "http://localhost:8888",
+					},
+				},
+				err: true,
+			},
 		}
 		cmdFn := func(orginal, expected config.Configs) func(*globalFlags, genericCLIOpts) *cobra.Command {
 			svc := &config.MockConfigService{
@@ -264,11 +348,15 @@ func TestCmdConfig(t *testing.T) {
 		for _, tt := range tests {
 			fn := func(t *testing.T) {
 				builder := newInfluxCmdBuilder(
-					in(new(bytes.Buffer)),
+					in(bytes.NewBufferString(tt.stdin)),
 					out(ioutil.Discard),
 				)
 				cmd := builder.cmd(cmdFn(tt.original, tt.expected))
 				cmd.SetArgs(append([]string{"config", "set"}, tt.flags...))
+				if tt.err {
+					require.Error(t, cmd.Execute())
+					return
+				}
 				require.NoError(t, cmd.Execute())
 			}
 			t.Run(tt.name, fn)
@@ -402,5 +490,225 @@ func TestCmdConfig(t *testing.T) {
 			}
 			t.Run(tt.name, fn)
 		}
+
+		t.Run("table shows previous", func(t *testing.T) {
+			expected := config.Configs{
+				"a1": {Host: "http://host1:9999", Org: "org1", PreviousActive: true},
+				"a2": {Host: "http://host2:9999", Org: "org2", Active: true},
+				"a3": {Host: "http://host3:9999", Org: "org3"},
+			}
+
+			buf := new(bytes.Buffer)
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(buf),
+			)
+			cmd := builder.cmd(cmdFn(expected))
+			cmd.SetArgs([]string{"config", "list"})
+			require.NoError(t, cmd.Execute())
+
+			// The columns are aligned with tabs, so the marks are read at the
+			// offsets of the headers once the tabs are expanded.
+			lines := strings.Split(strings.TrimRight(expandTabs(buf.String()), "\n"), "\n")
+			require.Len(t, lines, 4)
+			require.Equal(t, []string{"Active", "Previous", "Name", "URL", "Org"}, strings.Fields(lines[0]))
+			previousCol, nameCol := strings.Index(lines[0], "Previous"), strings.Index(lines[0], "Name")
+			marks := make(map[string][2]string)
+			for _, line := range lines[1:] {
+				name := strings.Fields(line[nameCol:])[0]
+				marks[name] = [2]string{
+					strings.TrimSpace(line[:previousCol]),
+					strings.TrimSpace(line[previousCol:nameCol]),
+				}
+			}
+			require.Equal(t, map[string][2]string{
+				"a1": {"", "*"},
+				"a2": {"*", ""},
+				"a3": {"", ""},
+			}, marks)
+		})
+
+		t.Run("filters", func(t *testing.T) {
+			expected := config.Configs{
+				"local":   {Host: "http://localhost:9999", Org: "dev"},
+				"us-west": {Host: "https://us-west-2-1.aws.cloud2.influxdata.com", Org: "prod"},
+				"us-east": {Host: "https://us-east-1-1.aws.cloud2.influxdata.com", Org: "dev"},
+			}
+			filterTests := []struct {
+				name  string
+				flags []string
+				urls  []string
+			}{
+				{
+					name:  "host",
+					flags: []string{"--host-filter", "localhost"},
+					urls:  []string{"http://localhost:9999"},
+				},
+				{
+					name:  "host and org",
+					flags: []string{"--host-filter", "aws", "--org-filter", "dev"},
+					urls:  []string{"https://us-east-1-1.aws.cloud2.influxdata.com"},
+				},
+				{
+					name:  "no match",
+					flags: []string{"--org-filter", "staging"},
+					urls:  []string{},
+				},
+			}
+			for _, tt := range filterTests {
+				t.Run(tt.name, func(t *testing.T) {
+					buf := new(bytes.Buffer)
+					builder := newInfluxCmdBuilder(
+						in(new(bytes.Buffer)),
+						out(buf),
+					)
+					cmd := builder.cmd(cmdFn(expected))
+					cmd.SetArgs(append([]string{"config", "list", "--json"}, tt.flags...))
+					require.NoError(t, cmd.Execute())
+
+					var got []config.Config
+					require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+					urls := []string{}
+					for _, p := range got {
+						urls = append(urls, p.Host)
+					}
+					require.ElementsMatch(t, tt.urls, urls)
+				})
+			}
+		})
+
+		t.Run("json masks tokens", func(t *testing.T) {
+			const token = "VxgGvxJLaSVSzH1ud8OzpjGJQnMd2MQo7p7K2Mn9QYyVDLpZ-Ta8EBqP6KHZJ9X6bTkmsCGo2A3wRzGwvw3Amg=="
+			expected := config.Configs{
+				"default": {
+					Org:    "org1",
+					Active: true,
+					Token:  token,
+					Host:   "http://localhost:9999",
+				},
+			}
+
+			buf := new(bytes.Buffer)
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(buf),
+			)
+			cmd := builder.cmd(cmdFn(expected))
+			cmd.SetArgs([]string{"config", "list", "--json"})
+			require.NoError(t, cmd.Execute())
+
+			require.NotContains(t, buf.String(), token)
+			var got []map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+			require.Equal(t, []map[string]interface{}{{
+				"url":    "http://localhost:9999",
+				"token":  "...mg==",
+				"org":    "org1",
+				"active": true,
+			}}, got)
+		})
+	})
+
+	t.Run("export", func(t *testing.T) {
+		original := config.Configs{
+			"default": {
+				Org:    "org1",
+				Active: true,
+				Token:  "tok1",
+				Host:   "http://localhost:9999",
+			},
+			"tokenless": {
+				Org:  "org2",
+				Host: "http://localhost:8888",
+			},
+		}
+		cmdFn := func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+			builder := cmdConfigBuilder{
+				genericCLIOpts: opt,
+				globalFlags:    g,
+				svc: &config.MockConfigService{
+					ParseConfigsFn: func() (config.Configs, error) {
+						return original, nil
+					},
+				},
+			}
+			return builder.cmd()
+		}
+
+		withSchema := func(pp config.Configs) config.Configs {
+			versioned := make(config.Configs, len(pp))
+			for name, p := range pp {
+				p.Schema = config.SchemaVersion
+				versioned[name] = p
+			}
+			return versioned
+		}
+
+		t.Run("stdout", func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(buf),
+			)
+			cmd := builder.cmd(cmdFn)
+			cmd.SetArgs([]string{"config", "export"})
+			require.NoError(t, cmd.Execute())
+
+			got, err := config.ParseConfigs(buf)
+			require.NoError(t, err)
+			require.Equal(t, withSchema(original), got)
+		})
+
+		t.Run("redact tokens to file", func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "configs")
+
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(ioutil.Discard),
+			)
+			cmd := builder.cmd(cmdFn)
+			cmd.SetArgs([]string{"config", "export", "--redact-tokens", "--output", path})
+			require.NoError(t, cmd.Execute())
+
+			b, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.NotContains(t, string(b), "tok1")
+			require.Contains(t, string(b), `token = "REDACTED"`)
+
+			got, err := config.ParseConfigs(bytes.NewReader(b))
+			require.NoError(t, err)
+			expected := withSchema(original)
+			p := expected["default"]
+			p.Token = "REDACTED"
+			expected["default"] = p
+			require.Equal(t, expected, got)
+
+			// the configs that were exported are left as they were
+			require.Equal(t, "tok1", original["default"].Token)
+		})
 	})
 }
+
+// expandTabs replaces the tabs of the output of a table with spaces, to the
+// tab stops of its writer.
+func expandTabs(s string) string {
+	var b strings.Builder
+	var col int
+	for _, r := range s {
+		switch r {
+		case '\t':
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n':
+			b.WriteRune(r)
+			col = 0
+		default:
+			b.WriteRune(r)
+			col++
+		}
+	}
+	return b.String()
+}
@@ -193,6 +193,9 @@ func (b *cmdInfluxBuilder) cmd(childCmdFns ...func(f *globalFlags, opt genericCL
 		// is provided too, the flag will take precedence.
 		flags.Config = getConfigFromDefaultPath()
 	}
+	// The environment overrides the active config, and the flags, which are
+	// parsed later, override both.
+	flags.Config = configFromEnv(flags.Config, os.Getenv)
 
 	cmd.PersistentFlags().BoolVar(&flags.local, "local", false, "Run commands locally against the filesystem")
 	cmd.PersistentFlags().BoolVar(&flags.skipVerify, "skip-verify", false, "SkipVerify controls whether a client verifies the server's certificate chain and host name.")
@@ -225,6 +228,7 @@ func influxCmd(opts ...genericCLIOptFn) *cobra.Command {
 		cmdSecret,
 		cmdSetup,
 		cmdTask,
+		cmdTelegraf,
 		cmdUser,
 		cmdWrite,
 	)
@@ -267,18 +271,58 @@ func defaultConfigPath() (string, string, error) {
 }
 
 func getConfigFromDefaultPath() config.Config {
-	path, _, err := defaultConfigPath()
+	path, dir, err := defaultConfigPath()
 	if err != nil {
 		return config.DefaultConfig
 	}
-	r, err := os.Open(path)
+	var svc config.ConfigsService = config.LocalConfigsSVC{
+		Path: path,
+		Dir:  dir,
+	}
+	// The flags are not parsed yet, so only the environment can keep the
+	// configs in Consul.
+	if consulPath := os.Getenv("INFLUX_CONFIG_CONSUL_PATH"); consulPath != "" {
+		if consulSVC, err := config.NewConsulConfigsSVC(consulPath, svc); err == nil {
+			svc = consulSVC
+		}
+	}
+	pp, err := svc.ParseConfigs()
 	if err != nil {
 		return config.DefaultConfig
 	}
-	activated, _ := config.ParseActiveConfig(r)
+	activated, _ := config.ActiveConfig(pp)
 	return activated
 }
 
+// configEnvPrefixes are the prefixes of the environment variables that
+// override the token, host and org of the active config, in order of
+// precedence.
+var configEnvPrefixes = []string{"INFLUX_", "INFLUXDB_"}
+
+// configFromEnv returns c with its token, host and org replaced by those set
+// in the environment, such as $INFLUXDB_TOKEN.
+func configFromEnv(c config.Config, getenv func(string) string) config.Config {
+	lookup := func(name string) string {
+		for _, prefix := range configEnvPrefixes {
+			if v := getenv(prefix + name); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if token := lookup("TOKEN"); token != "" {
+		c.Token = token
+	}
+	if host := lookup("HOST"); host != "" {
+		c.Host = host
+	}
+	if org := lookup("ORG"); org != "" {
+		c.Org = org
+	}
+	return c
+}
+
 func migrateOldCredential() {
 	dir, err := fs.InfluxDir()
 	if err != nil {
@@ -3,8 +3,11 @@ package main
 import (
 	"bytes"
 	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influx/config"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -60,6 +63,36 @@ func Test_influx_cmd(t *testing.T) {
 				local:      true,
 			},
 		},
+		{
+			name: "influxdb env vars set",
+			args: []string{"--local=true", "--skip-verify=true"},
+			envVars: map[string]string{
+				"INFLUXDB_TOKEN": "TOKEN",
+				"INFLUXDB_HOST":  "HOST",
+			},
+			expected: globalFlags{
+				Config: config.Config{
+					Token: "TOKEN",
+					Host:  "HOST",
+				},
+				skipVerify: true,
+				local:      true,
+			},
+		},
+		{
+			name: "flags override env vars",
+			args: []string{"--token=TOKEN", "--host=HOST"},
+			envVars: map[string]string{
+				"INFLUXDB_TOKEN": "ENV_TOKEN",
+				"INFLUXDB_HOST":  "ENV_HOST",
+			},
+			expected: globalFlags{
+				Config: config.Config{
+					Token: "TOKEN",
+					Host:  "HOST",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,3 +125,86 @@ func Test_influx_cmd(t *testing.T) {
 		t.Run(tt.name, fn)
 	}
 }
+
+func Test_configFromEnv(t *testing.T) {
+	active := config.Config{
+		Token: "CONFIG_TOKEN",
+		Host:  "CONFIG_HOST",
+		Org:   "CONFIG_ORG",
+	}
+
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		expected config.Config
+	}{
+		{
+			name:     "none set",
+			expected: active,
+		},
+		{
+			name: "influxdb env vars set",
+			envVars: map[string]string{
+				"INFLUXDB_TOKEN": "TOKEN",
+				"INFLUXDB_HOST":  "HOST",
+				"INFLUXDB_ORG":   "ORG",
+			},
+			expected: config.Config{
+				Token: "TOKEN",
+				Host:  "HOST",
+				Org:   "ORG",
+			},
+		},
+		{
+			name: "influx env vars take precedence",
+			envVars: map[string]string{
+				"INFLUX_TOKEN":   "TOKEN",
+				"INFLUXDB_TOKEN": "OTHER_TOKEN",
+				"INFLUXDB_ORG":   "ORG",
+			},
+			expected: config.Config{
+				Token: "TOKEN",
+				Host:  "CONFIG_HOST",
+				Org:   "ORG",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		fn := func(t *testing.T) {
+			getenv := func(key string) string { return tt.envVars[key] }
+			assert.Equal(t, tt.expected, configFromEnv(active, getenv))
+		}
+
+		t.Run(tt.name, fn)
+	}
+}
+
+func Test_influx_cmd_envToken(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"tasks":[]}`))
+	}))
+	defer srv.Close()
+
+	defer addEnvVars(t, map[string]string{
+		"INFLUX_ORG_ID":  "",
+		"INFLUX_ORG":     "",
+		"INFLUXDB_TOKEN": "ENV_TOKEN",
+		"INFLUXDB_HOST":  srv.URL,
+	})()
+	httpClient = nil
+	defer func() { httpClient = nil }()
+
+	builder := newInfluxCmdBuilder(
+		in(new(bytes.Buffer)),
+		out(ioutil.Discard),
+	)
+	cmd := builder.cmd(cmdTask)
+	cmd.SetArgs([]string{"task", "list", "--org-id=" + influxdb.ID(1).String()})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, "Token ENV_TOKEN", gotAuth)
+}
@@ -27,6 +27,7 @@ type cmdOrgBuilder struct {
 
 	json        bool
 	hideHeaders bool
+	members     bool
 	description string
 	id          string
 	memberID    string
@@ -156,12 +157,13 @@ func (b *cmdOrgBuilder) cmdFind() *cobra.Command {
 	}
 	opts.mustRegister(cmd)
 	b.registerPrintFlags(cmd)
+	cmd.Flags().BoolVar(&b.members, "members", false, "Show the number of members of each organization")
 
 	return cmd
 }
 
 func (b *cmdOrgBuilder) findRunEFn(cmd *cobra.Command, args []string) error {
-	orgSvc, _, _, err := b.svcFn()
+	orgSvc, urmSVC, _, err := b.svcFn()
 	if err != nil {
 		return fmt.Errorf("failed to initialize org service client: %v", err)
 	}
@@ -184,7 +186,35 @@ func (b *cmdOrgBuilder) findRunEFn(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed find orgs: %v", err)
 	}
 
-	return b.printOrg(orgPrintOpt{orgs: orgs})
+	opts := orgPrintOpt{orgs: orgs}
+	if b.members {
+		opts.memberCounts, err = countMembers(context.Background(), urmSVC, orgs)
+		if err != nil {
+			return err
+		}
+	}
+	return b.printOrg(opts)
+}
+
+// countMembers returns the number of members of each of the orgs. The members
+// of each org are only looked up once.
+func countMembers(ctx context.Context, urmSVC influxdb.UserResourceMappingService, orgs []*influxdb.Organization) (map[influxdb.ID]int, error) {
+	counts := make(map[influxdb.ID]int, len(orgs))
+	for _, o := range orgs {
+		if _, ok := counts[o.ID]; ok {
+			continue
+		}
+		mappings, _, err := urmSVC.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+			ResourceType: influxdb.OrgsResourceType,
+			ResourceID:   o.ID,
+			UserType:     influxdb.Member,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find members of org %s: %v", o.ID, err)
+		}
+		counts[o.ID] = len(mappings)
+	}
+	return counts, nil
 }
 
 func (b *cmdOrgBuilder) cmdUpdate() *cobra.Command {
@@ -254,6 +284,13 @@ func (b *cmdOrgBuilder) printOrg(opts orgPrintOpt) error {
 		if opts.org != nil {
 			v = opts.org
 		}
+		if opts.memberCounts != nil {
+			orgs := make([]orgWithMembers, 0, len(opts.orgs))
+			for _, o := range opts.orgs {
+				orgs = append(orgs, orgWithMembers{Organization: o, MemberCount: opts.memberCounts[o.ID]})
+			}
+			v = orgs
+		}
 		return b.writeJSON(v)
 	}
 
@@ -266,6 +303,9 @@ func (b *cmdOrgBuilder) printOrg(opts orgPrintOpt) error {
 	if opts.deleted {
 		headers = append(headers, "Deleted")
 	}
+	if opts.memberCounts != nil {
+		headers = append(headers, "Members")
+	}
 	w.WriteHeaders(headers...)
 
 	if opts.org != nil {
@@ -280,6 +320,9 @@ func (b *cmdOrgBuilder) printOrg(opts orgPrintOpt) error {
 		if opts.deleted {
 			m["Deleted"] = true
 		}
+		if opts.memberCounts != nil {
+			m["Members"] = opts.memberCounts[o.ID]
+		}
 		w.Write(m)
 	}
 
@@ -636,7 +679,14 @@ func removeMember(ctx context.Context, w io.Writer, urmSVC influxdb.UserResource
 }
 
 type orgPrintOpt struct {
-	deleted bool
-	org     *influxdb.Organization
-	orgs    []*influxdb.Organization
+	deleted      bool
+	org          *influxdb.Organization
+	orgs         []*influxdb.Organization
+	memberCounts map[influxdb.ID]int
+}
+
+// orgWithMembers is an org with the number of its members, as printed in JSON.
+type orgWithMembers struct {
+	*influxdb.Organization
+	MemberCount int `json:"memberCount"`
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"reflect"
@@ -241,6 +242,82 @@ func TestCmdOrg(t *testing.T) {
 		}
 	})
 
+	t.Run("list members", func(t *testing.T) {
+		orgs := []*influxdb.Organization{
+			{ID: 1, Name: "org1"},
+			{ID: 2, Name: "org2"},
+		}
+		members := map[influxdb.ID]int{1: 2, 2: 1}
+
+		svc := mock.NewOrganizationService()
+		svc.FindOrganizationsF = func(ctx context.Context, f influxdb.OrganizationFilter, opt ...influxdb.FindOptions) ([]*influxdb.Organization, int, error) {
+			return orgs, len(orgs), nil
+		}
+
+		lookups := make(map[influxdb.ID]int)
+		urmSVC := mock.NewUserResourceMappingService()
+		urmSVC.FindMappingsFn = func(ctx context.Context, f influxdb.UserResourceMappingFilter) ([]*influxdb.UserResourceMapping, int, error) {
+			lookups[f.ResourceID]++
+			if f.ResourceType != influxdb.OrgsResourceType || f.UserType != influxdb.Member {
+				return nil, 0, fmt.Errorf("unexpected filter: %+v", f)
+			}
+			var mappings []*influxdb.UserResourceMapping
+			for i := 0; i < members[f.ResourceID]; i++ {
+				mappings = append(mappings, &influxdb.UserResourceMapping{
+					ResourceID:   f.ResourceID,
+					ResourceType: influxdb.OrgsResourceType,
+					UserID:       influxdb.ID(10 + i),
+					UserType:     influxdb.Member,
+				})
+			}
+			return mappings, len(mappings), nil
+		}
+
+		run := func(t *testing.T, flags ...string) string {
+			t.Helper()
+			defer addEnvVars(t, envVarsZeroMap)()
+			for k := range lookups {
+				delete(lookups, k)
+			}
+
+			var buf bytes.Buffer
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(&buf),
+			)
+			cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+				return newCmdOrgBuilder(fakeOrgUrmSVCsFn(svc, urmSVC), opt).cmd()
+			})
+			cmd.SetArgs(append([]string{"org", "list", "--members"}, flags...))
+			require.NoError(t, cmd.Execute())
+			assert.Equal(t, map[influxdb.ID]int{1: 1, 2: 1}, lookups)
+			return buf.String()
+		}
+
+		t.Run("table", func(t *testing.T) {
+			out := run(t)
+			assert.Regexp(t, `ID\s+Name\s+Members\n`, out)
+			assert.Regexp(t, influxdb.ID(1).String()+`\s+org1\s+2\n`, out)
+			assert.Regexp(t, influxdb.ID(2).String()+`\s+org2\s+1\n`, out)
+		})
+
+		t.Run("json", func(t *testing.T) {
+			var got []struct {
+				ID          influxdb.ID `json:"id"`
+				Name        string      `json:"name"`
+				MemberCount int         `json:"memberCount"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(run(t, "--json")), &got))
+			require.Len(t, got, 2)
+			assert.Equal(t, influxdb.ID(1), got[0].ID)
+			assert.Equal(t, "org1", got[0].Name)
+			assert.Equal(t, 2, got[0].MemberCount)
+			assert.Equal(t, influxdb.ID(2), got[1].ID)
+			assert.Equal(t, "org2", got[1].Name)
+			assert.Equal(t, 1, got[1].MemberCount)
+		})
+	})
+
 	t.Run("update", func(t *testing.T) {
 		tests := []struct {
 			name     string
@@ -49,6 +49,7 @@ type cmdPkgBuilder struct {
 	json                bool
 	name                string
 	org                 organization
+	stackID             string
 	quiet               bool
 	recurse             bool
 	urls                []string
@@ -96,6 +97,12 @@ func (b *cmdPkgBuilder) cmdPkgApply() *cobra.Command {
 	cmd := b.newCmd("pkg", b.pkgApplyRunEFn, true)
 	cmd.Short = "Apply a pkg to create resources"
 
+	b.registerPkgApplyFlags(cmd)
+
+	return cmd
+}
+
+func (b *cmdPkgBuilder) registerPkgApplyFlags(cmd *cobra.Command) {
 	b.org.register(cmd, false)
 	b.registerPkgFileFlags(cmd)
 	b.registerPkgPrintOpts(cmd)
@@ -105,11 +112,16 @@ func (b *cmdPkgBuilder) cmdPkgApply() *cobra.Command {
 	b.applyOpts.secrets = []string{}
 	cmd.Flags().StringSliceVar(&b.applyOpts.secrets, "secret", nil, "Secrets to provide alongside the package; format should --secret=SECRET_KEY=SECRET_VALUE --secret=SECRET_KEY_2=SECRET_VALUE_2")
 	cmd.Flags().StringSliceVar(&b.applyOpts.envRefs, "env-ref", nil, "Environment references to provide alongside the package; format should --env-ref=REF_KEY=REF_VALUE --env-ref=REF_KEY_2=REF_VALUE_2")
-
-	return cmd
 }
 
 func (b *cmdPkgBuilder) pkgApplyRunEFn(cmd *cobra.Command, args []string) error {
+	return b.applyPkg(false)
+}
+
+// applyPkg applies the pkg provided by the pkg file flags. When withStack is
+// set, a new stack is initialized for the pkg before it is applied, and the
+// applied resources are recorded on it.
+func (b *cmdPkgBuilder) applyPkg(withStack bool) error {
 	if err := b.org.validOrgFlags(&flags); err != nil {
 		return err
 	}
@@ -172,14 +184,48 @@ func (b *cmdPkgBuilder) pkgApplyRunEFn(cmd *cobra.Command, args []string) error
 		return errors.New("package has conflicts with existing resources and cannot safely apply")
 	}
 
-	summary, err := svc.Apply(context.Background(), influxOrgID, 0, pkg, pkger.ApplyWithEnvRefs(providedEnvRefs), pkger.ApplyWithSecrets(providedSecrets))
+	opts := []pkger.ApplyOptFn{
+		pkger.ApplyWithEnvRefs(providedEnvRefs),
+		pkger.ApplyWithSecrets(providedSecrets),
+	}
+
+	var stack pkger.Stack
+	if withStack {
+		const fakeUserID = 0 // is 0 because user is pulled from token...
+		stack, err = svc.InitStack(context.Background(), fakeUserID, pkger.Stack{
+			OrgID:       influxOrgID,
+			Name:        b.name,
+			Description: b.description,
+		})
+		if err != nil {
+			return err
+		}
+		opts = append(opts, pkger.ApplyWithStackID(stack.ID))
+	}
+
+	summary, err := svc.Apply(context.Background(), influxOrgID, 0, pkg, opts...)
 	if err != nil {
 		return err
 	}
 
-	b.printPkgSummary(summary)
+	if !withStack {
+		return b.printPkgSummary(summary)
+	}
 
-	return nil
+	if b.quiet {
+		return nil
+	}
+	if b.json {
+		return b.writeJSON(struct {
+			StackID string        `json:"stackID"`
+			Summary pkger.Summary `json:"summary"`
+		}{
+			StackID: stack.ID.String(),
+			Summary: summary,
+		})
+	}
+	fmt.Fprintf(b.w, "Stack ID: %s\n", stack.ID)
+	return b.printPkgSummary(summary)
 }
 
 func (b *cmdPkgBuilder) cmdPkgExport() *cobra.Command {
@@ -349,7 +395,11 @@ func (b *cmdPkgBuilder) cmdPkgValidate() *cobra.Command {
 func (b *cmdPkgBuilder) cmdStack() *cobra.Command {
 	cmd := b.newCmd("stack", nil, false)
 	cmd.Short = "Stack management commands"
-	cmd.AddCommand(b.cmdStackInit())
+	cmd.AddCommand(
+		b.cmdStackInit(),
+		b.cmdStackImport(),
+		b.cmdStackExport(),
+	)
 	return cmd
 }
 
@@ -411,6 +461,65 @@ func (b *cmdPkgBuilder) stackInitRunEFn(cmd *cobra.Command, args []string) error
 	return nil
 }
 
+func (b *cmdPkgBuilder) cmdStackImport() *cobra.Command {
+	cmd := b.newCmd("import", b.stackImportRunEFn, true)
+	cmd.Short = "Initialize a stack and apply a package to it"
+	cmd.Long = `Initialize a new stack and apply a package to it. The resources the package creates are
+recorded on the stack, so that the stack can be exported later on. The changes are previewed
+and must be confirmed, unless the --force flag is provided.`
+
+	b.registerPkgApplyFlags(cmd)
+	cmd.Flags().StringVarP(&b.name, "stack-name", "n", "", "Name given to created stack")
+	cmd.Flags().StringVarP(&b.description, "stack-description", "d", "", "Description given to created stack")
+
+	return cmd
+}
+
+func (b *cmdPkgBuilder) stackImportRunEFn(cmd *cobra.Command, args []string) error {
+	return b.applyPkg(true)
+}
+
+func (b *cmdPkgBuilder) cmdStackExport() *cobra.Command {
+	cmd := b.newCmd("export", b.stackExportRunEFn, true)
+	cmd.Short = "Export the resources of a stack as a package"
+
+	cmd.Flags().StringVar(&b.stackID, "id", "", "The ID of the stack to export")
+	cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVarP(&b.file, "file", "f", "", "output file for created pkg; defaults to std out if no file provided; the extension of provided file (.yml/.json) will dictate encoding")
+
+	return cmd
+}
+
+func (b *cmdPkgBuilder) stackExportRunEFn(cmd *cobra.Command, args []string) error {
+	stackID, err := influxdb.IDFromString(b.stackID)
+	if err != nil {
+		return ierror.Wrap(err, "invalid stack ID provided")
+	}
+
+	pkgSVC, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	stack, err := pkgSVC.ReadStack(context.Background(), *stackID)
+	if err != nil {
+		return err
+	}
+	if len(stack.Resources) == 0 {
+		return fmt.Errorf("stack %s has no resources to export", stack.ID)
+	}
+
+	resources := make([]pkger.ResourceToClone, 0, len(stack.Resources))
+	for _, r := range stack.Resources {
+		resources = append(resources, pkger.ResourceToClone{
+			Kind: r.Kind,
+			ID:   r.ID,
+		})
+	}
+
+	return b.writePkg(b.w, pkgSVC, b.file, pkger.CreateWithExistingResources(resources...))
+}
+
 func (b *cmdPkgBuilder) registerPkgPrintOpts(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&b.disableColor, "disable-color", "c", false, "Disable color in output")
 	cmd.Flags().BoolVar(&b.disableTableBorders, "disable-table-borders", false, "Disable table borders")
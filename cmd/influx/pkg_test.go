@@ -563,6 +563,135 @@ func TestCmdPkg(t *testing.T) {
 				t.Run(tt.name, fn)
 			}
 		})
+
+		t.Run("import and export", func(t *testing.T) {
+			// the fake service records the buckets applied to a stack, and
+			// exports the buckets of a stack by their IDs
+			stacks := make(map[influxdb.ID]pkger.Stack)
+			bucketNames := make(map[influxdb.ID]string)
+			newPkgSVC := func() *fakePkgSVC {
+				return &fakePkgSVC{
+					initStackFn: func(ctx context.Context, userID influxdb.ID, stack pkger.Stack) (pkger.Stack, error) {
+						stack.ID = 9000
+						stacks[stack.ID] = stack
+						return stack, nil
+					},
+					readStackFn: func(ctx context.Context, id influxdb.ID) (pkger.Stack, error) {
+						stack, ok := stacks[id]
+						if !ok {
+							return pkger.Stack{}, &influxdb.Error{Code: influxdb.ENotFound}
+						}
+						return stack, nil
+					},
+					dryRunFn: func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg) (pkger.Summary, pkger.Diff, error) {
+						var diff pkger.Diff
+						for _, b := range pkg.Summary().Buckets {
+							diff.Buckets = append(diff.Buckets, pkger.DiffBucket{Name: b.Name})
+						}
+						return pkg.Summary(), diff, nil
+					},
+					applyFn: func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg, opts ...pkger.ApplyOptFn) (pkger.Summary, error) {
+						var opt pkger.ApplyOpt
+						for _, o := range opts {
+							require.NoError(t, o(&opt))
+						}
+
+						stack := stacks[opt.StackID]
+						sum := pkg.Summary()
+						for i := range sum.Buckets {
+							id := influxdb.ID(i + 1)
+							sum.Buckets[i].ID = pkger.SafeID(id)
+							bucketNames[id] = sum.Buckets[i].Name
+							stack.Resources = append(stack.Resources, pkger.StackResource{
+								APIVersion: pkger.APIVersion,
+								ID:         id,
+								Kind:       pkger.KindBucket,
+								Name:       sum.Buckets[i].Name,
+							})
+						}
+						stacks[opt.StackID] = stack
+						return sum, nil
+					},
+					createFn: func(ctx context.Context, setters ...pkger.CreatePkgSetFn) (*pkger.Pkg, error) {
+						var opt pkger.CreateOpt
+						for _, o := range setters {
+							if err := o(&opt); err != nil {
+								return nil, err
+							}
+						}
+
+						var pkg pkger.Pkg
+						for _, rc := range opt.Resources {
+							pkg.Objects = append(pkg.Objects, pkger.Object{
+								APIVersion: pkger.APIVersion,
+								Kind:       rc.Kind,
+								Metadata:   pkger.Resource{"name": bucketNames[rc.ID]},
+							})
+						}
+						return &pkg, nil
+					},
+				}
+			}
+			pkgSVC := newPkgSVC()
+
+			execute := func(t *testing.T, args ...string) *bytes.Buffer {
+				t.Helper()
+
+				defer addEnvVars(t, envVarsZeroMap)()
+
+				outBuf := new(bytes.Buffer)
+				builder := newInfluxCmdBuilder(
+					in(new(bytes.Buffer)),
+					out(outBuf),
+				)
+				rootCmd := builder.cmd(func(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+					return newCmdPkgBuilder(fakeSVCFn(pkgSVC), opt).cmd()
+				})
+				rootCmd.SetArgs(args)
+
+				require.NoError(t, rootCmd.Execute())
+				return outBuf
+			}
+
+			outBuf := execute(t,
+				"pkg", "stack", "import",
+				"--org-id="+influxdb.ID(1).String(),
+				"--stack-name=foo",
+				"--file=../../pkger/testdata/bucket.json",
+				"--force=true",
+				"--json",
+			)
+
+			// the diff of the pkg is written before the stack and its summary
+			dec := json.NewDecoder(outBuf)
+			var diff pkger.Diff
+			require.NoError(t, dec.Decode(&diff))
+			assert.Len(t, diff.Buckets, 2)
+
+			var resp struct {
+				StackID string        `json:"stackID"`
+				Summary pkger.Summary `json:"summary"`
+			}
+			require.NoError(t, dec.Decode(&resp))
+			assert.Equal(t, influxdb.ID(9000).String(), resp.StackID)
+			require.Len(t, resp.Summary.Buckets, 2)
+
+			stack := stacks[9000]
+			assert.Equal(t, influxdb.ID(1), stack.OrgID)
+			assert.Equal(t, "foo", stack.Name)
+			require.Len(t, stack.Resources, 2)
+
+			outBuf = execute(t, "pkg", "stack", "export", "--id="+influxdb.ID(9000).String())
+
+			pkg, err := pkger.Parse(pkger.EncodingYAML, pkger.FromReader(outBuf), pkger.ValidWithoutResources(), pkger.ValidSkipParseError())
+			require.NoError(t, err)
+
+			var exported []string
+			for _, b := range pkg.Summary().Buckets {
+				exported = append(exported, b.Name)
+			}
+			assert.ElementsMatch(t, []string{"rucket_11", "display name"}, exported)
+		})
 	})
 }
 
@@ -704,6 +833,7 @@ func testPkgWritesToBuffer(newCmdFn func(w io.Writer) *cobra.Command, args pkgFi
 
 type fakePkgSVC struct {
 	initStackFn func(ctx context.Context, userID influxdb.ID, stack pkger.Stack) (pkger.Stack, error)
+	readStackFn func(ctx context.Context, id influxdb.ID) (pkger.Stack, error)
 	createFn    func(ctx context.Context, setters ...pkger.CreatePkgSetFn) (*pkger.Pkg, error)
 	dryRunFn    func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg) (pkger.Summary, pkger.Diff, error)
 	applyFn     func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg, opts ...pkger.ApplyOptFn) (pkger.Summary, error)
@@ -716,6 +846,13 @@ func (f *fakePkgSVC) InitStack(ctx context.Context, userID influxdb.ID, stack pk
 	panic("not implemented")
 }
 
+func (f *fakePkgSVC) ReadStack(ctx context.Context, id influxdb.ID) (pkger.Stack, error) {
+	if f.readStackFn != nil {
+		return f.readStackFn(ctx, id)
+	}
+	panic("not implemented")
+}
+
 func (f *fakePkgSVC) CreatePkg(ctx context.Context, setters ...pkger.CreatePkgSetFn) (*pkger.Pkg, error) {
 	if f.createFn != nil {
 		return f.createFn(ctx, setters...)
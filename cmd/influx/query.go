@@ -1,17 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/flux/repl"
 	_ "github.com/influxdata/flux/stdlib"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/query"
 	_ "github.com/influxdata/influxdb/query/stdlib"
 	"github.com/spf13/cobra"
 )
 
+const (
+	outputFormatTable = "table"
+	outputFormatCsv   = "csv"
+)
+
 var queryFlags struct {
-	org organization
+	org        organization
+	format     string
+	outputFile string
 }
 
 func cmdQuery(f *globalFlags, opts genericCLIOpts) *cobra.Command {
@@ -22,6 +40,8 @@ or execute a literal Flux query contained in a file by specifying the file prefi
 	cmd.Args = cobra.ExactArgs(1)
 
 	queryFlags.org.register(cmd, true)
+	cmd.Flags().StringVar(&queryFlags.format, "format", "", "Output format, either table or csv (annotated CSV). Defaults to table unless the output file has a '.csv' extension")
+	cmd.Flags().StringVar(&queryFlags.outputFile, "output-file", "", "Write the results to the file, reporting the bytes written on stderr")
 
 	return cmd
 }
@@ -35,6 +55,11 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	format, err := queryOutputFormat(queryFlags.format, queryFlags.outputFile)
+	if err != nil {
+		return err
+	}
+
 	q, err := repl.LoadQuery(args[0])
 	if err != nil {
 		return fmt.Errorf("failed to load query: %v", err)
@@ -50,6 +75,10 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if queryFlags.outputFile != "" || format != outputFormatTable {
+		return writeFluxQuery(cmd, q, orgID, format)
+	}
+
 	flux.FinalizeBuiltIns()
 
 	r, err := getFluxREPL(flags.Host, flags.Token, flags.skipVerify, orgID)
@@ -63,3 +92,158 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// queryOutputFormat returns the format the results of a query are written in.
+func queryOutputFormat(format, outputFile string) (string, error) {
+	switch format {
+	case outputFormatTable, outputFormatCsv:
+		return format, nil
+	case "":
+		if filepath.Ext(outputFile) == ".csv" {
+			return outputFormatCsv, nil
+		}
+		return outputFormatTable, nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// writeFluxQuery executes the query on the server and writes its results to
+// the output file, or else to stdout, as they are received.
+func writeFluxQuery(cmd *cobra.Command, q string, orgID platform.ID, format string) (err error) {
+	out := cmd.OutOrStdout()
+	var progress io.Writer
+	if queryFlags.outputFile != "" {
+		f, ferr := os.Create(queryFlags.outputFile)
+		if ferr != nil {
+			return fmt.Errorf("failed to create output file: %v", ferr)
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil && cerr != nil {
+				err = fmt.Errorf("failed to close output file: %v", cerr)
+			}
+		}()
+		out = f
+		progress = cmd.ErrOrStderr()
+	}
+
+	qs := &http.FluxQueryService{
+		Addr:               flags.Host,
+		Token:              flags.Token,
+		InsecureSkipVerify: flags.skipVerify,
+	}
+	results, err := qs.Query(context.Background(), &query.Request{
+		OrganizationID: orgID,
+		Compiler:       lang.FluxCompiler{Query: q},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer results.Release()
+
+	w := &queryResultWriter{w: bufio.NewWriter(out), progress: progress}
+	if format == outputFormatCsv {
+		err = w.writeCSV(results)
+	} else {
+		err = w.writeTables(results)
+	}
+	if err == nil {
+		err = w.flush()
+	}
+	if progress != nil {
+		fmt.Fprintln(progress)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write query results: %v", err)
+	}
+	return nil
+}
+
+// queryResultWriter writes the results of a query, flushing them after each
+// table so that large results are not held in memory, and reporting the
+// bytes written to progress, if it is not nil.
+type queryResultWriter struct {
+	w        *bufio.Writer
+	n        int64
+	progress io.Writer
+}
+
+func (w *queryResultWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *queryResultWriter) flush() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.progress != nil {
+		fmt.Fprintf(w.progress, "\rWrote %d bytes", w.n)
+	}
+	return nil
+}
+
+// writeCSV writes the results as annotated CSV.
+func (w *queryResultWriter) writeCSV(results flux.ResultIterator) error {
+	enc := csv.NewMultiResultEncoder(csv.DefaultEncoderConfig())
+	if _, err := enc.Encode(w, &flushingResultIterator{ResultIterator: results, flush: w.flush}); err != nil {
+		return err
+	}
+	// Errors in the results are encoded in the CSV, but the query failed.
+	return results.Err()
+}
+
+// writeTables writes the results as the tables printed by the REPL.
+func (w *queryResultWriter) writeTables(results flux.ResultIterator) error {
+	for results.More() {
+		result := results.Next()
+		if _, err := fmt.Fprintln(w, "Result:", result.Name()); err != nil {
+			return err
+		}
+		err := result.Tables().Do(func(tbl flux.Table) error {
+			if _, err := execute.NewFormatter(tbl, nil).WriteTo(w); err != nil {
+				return err
+			}
+			return w.flush()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
+
+// flushingResultIterator calls flush after each table of its results is
+// encoded.
+type flushingResultIterator struct {
+	flux.ResultIterator
+	flush func() error
+}
+
+func (it *flushingResultIterator) Next() flux.Result {
+	return &flushingResult{Result: it.ResultIterator.Next(), flush: it.flush}
+}
+
+type flushingResult struct {
+	flux.Result
+	flush func() error
+}
+
+func (r *flushingResult) Tables() flux.TableIterator {
+	return &flushingTableIterator{TableIterator: r.Result.Tables(), flush: r.flush}
+}
+
+type flushingTableIterator struct {
+	flux.TableIterator
+	flush func() error
+}
+
+func (it *flushingTableIterator) Do(f func(flux.Table) error) error {
+	return it.TableIterator.Do(func(tbl flux.Table) error {
+		if err := f(tbl); err != nil {
+			return err
+		}
+		return it.flush()
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdQuery(t *testing.T) {
+	orgID := influxdb.ID(9000)
+
+	// The results of the query, two tables of the same result.
+	results := strings.Join([]string{
+		"#datatype,string,long,dateTime:RFC3339,double,string,string",
+		"#group,false,false,false,false,true,true",
+		"#default,_result,,,,,",
+		",result,table,_time,_value,_field,host",
+		",,0,2019-11-01T00:00:00Z,1,v,a",
+		",,0,2019-11-01T00:00:10Z,2,v,a",
+		",,1,2019-11-01T00:00:00Z,3,v,b",
+		",,1,2019-11-01T00:00:10Z,4,v,b",
+		"",
+		"",
+	}, "\r\n")
+
+	const flux = `from(bucket: "b") |> range(start: 0)`
+
+	t.Run("output file", func(t *testing.T) {
+		srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			var req struct {
+				Query string `json:"query"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				return
+			}
+			if r.URL.Path != "/api/v2/query" || r.URL.Query().Get("orgID") != orgID.String() || req.Query != flux {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte(results))
+		}))
+		defer srv.Close()
+
+		dir := newTempDir(t)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "results.csv")
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		builder := newInfluxCmdBuilder(
+			in(new(bytes.Buffer)),
+			out(stdout),
+			err(stderr),
+			runEMiddlware(func(fn cobraRunEFn) cobraRunEFn { return fn }),
+		)
+		cmd := builder.cmd(cmdQuery)
+		cmd.SetArgs([]string{"query", flux,
+			"--host=" + srv.URL,
+			"--token=TOKEN",
+			"--org-id=" + orgID.String(),
+			"--output-file=" + path,
+		})
+		defer func() { queryFlags.outputFile = "" }()
+
+		require.NoError(t, cmd.Execute())
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, results, string(data))
+		assert.Empty(t, stdout.String())
+		// The progress is reported after each table and at the end.
+		assert.Equal(t, 3, strings.Count(stderr.String(), "\rWrote "))
+		assert.True(t, strings.HasSuffix(stderr.String(), fmt.Sprintf("\rWrote %d bytes\n", len(results))), stderr.String())
+	})
+
+	t.Run("output format", func(t *testing.T) {
+		tests := []struct {
+			format     string
+			outputFile string
+			expected   string
+			wantErr    bool
+		}{
+			{expected: outputFormatTable},
+			{outputFile: "results.txt", expected: outputFormatTable},
+			{outputFile: "results.csv", expected: outputFormatCsv},
+			{format: outputFormatTable, outputFile: "results.csv", expected: outputFormatTable},
+			{format: outputFormatCsv, expected: outputFormatCsv},
+			{format: "json", wantErr: true},
+		}
+		for _, tt := range tests {
+			format, err := queryOutputFormat(tt.format, tt.outputFile)
+			if tt.wantErr {
+				assert.Error(t, err)
+				continue
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format, "format %q, output file %q", tt.format, tt.outputFile)
+		}
+	})
+}
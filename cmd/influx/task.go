@@ -111,11 +111,13 @@ func taskCreateF(cmd *cobra.Command, args []string) error {
 }
 
 var taskFindFlags struct {
-	user    string
-	id      string
-	limit   int
-	headers bool
-	org     organization
+	user     string
+	id       string
+	limit    int
+	headers  bool
+	org      organization
+	active   bool
+	inactive bool
 }
 
 func taskFindCmd(opt genericCLIOpts) *cobra.Command {
@@ -129,6 +131,8 @@ func taskFindCmd(opt genericCLIOpts) *cobra.Command {
 	cmd.Flags().StringVarP(&taskFindFlags.user, "user-id", "n", "", "task owner ID")
 	cmd.Flags().IntVarP(&taskFindFlags.limit, "limit", "", influxdb.TaskDefaultPageSize, "the number of tasks to find")
 	cmd.Flags().BoolVar(&taskFindFlags.headers, "headers", true, "To print the table headers; defaults true")
+	cmd.Flags().BoolVar(&taskFindFlags.active, "active", false, "Only list active tasks")
+	cmd.Flags().BoolVar(&taskFindFlags.inactive, "inactive", false, "Only list inactive tasks")
 
 	return cmd
 }
@@ -137,6 +141,9 @@ func taskFindF(cmd *cobra.Command, args []string) error {
 	if err := taskFindFlags.org.validOrgFlags(&flags); err != nil {
 		return err
 	}
+	if taskFindFlags.active && taskFindFlags.inactive {
+		return fmt.Errorf("only one of --active and --inactive may be specified")
+	}
 
 	client, err := newHTTPClient()
 	if err != nil {
@@ -168,6 +175,15 @@ func taskFindF(cmd *cobra.Command, args []string) error {
 		filter.OrganizationID = id
 	}
 
+	if taskFindFlags.active {
+		status := string(influxdb.TaskActive)
+		filter.Status = &status
+	}
+	if taskFindFlags.inactive {
+		status := string(influxdb.TaskInactive)
+		filter.Status = &status
+	}
+
 	if taskFindFlags.limit < 1 || taskFindFlags.limit > influxdb.TaskMaxPageSize {
 		return fmt.Errorf("limit must be between 1 and %d", influxdb.TaskMaxPageSize)
 	}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdTask(t *testing.T) {
+	t.Run("list status", func(t *testing.T) {
+		tasks := []http.Task{
+			{ID: 1, OrganizationID: 3, OwnerID: 2, Name: "running", Status: string(influxdb.TaskActive)},
+			{ID: 2, OrganizationID: 3, OwnerID: 2, Name: "paused", Status: string(influxdb.TaskInactive)},
+		}
+
+		// the server filters the tasks by their status, as the task API does
+		srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			var resp struct {
+				Tasks []http.Task `json:"tasks"`
+			}
+			resp.Tasks = []http.Task{}
+			status := r.URL.Query().Get("status")
+			for _, task := range tasks {
+				if status == "" || task.Status == status {
+					resp.Tasks = append(resp.Tasks, task)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(resp)
+		}))
+		defer srv.Close()
+
+		tests := []struct {
+			name     string
+			flags    []string
+			expected []string
+		}{
+			{
+				name:     "all",
+				expected: []string{"running", "paused"},
+			},
+			{
+				name:     "active",
+				flags:    []string{"--active"},
+				expected: []string{"running"},
+			},
+			{
+				name:     "inactive",
+				flags:    []string{"--inactive"},
+				expected: []string{"paused"},
+			},
+		}
+
+		for _, tt := range tests {
+			fn := func(t *testing.T) {
+				defer addEnvVars(t, envVarsZeroMap)()
+				httpClient = nil
+				defer func() { httpClient = nil }()
+
+				var buf bytes.Buffer
+				builder := newInfluxCmdBuilder(
+					in(new(bytes.Buffer)),
+					out(&buf),
+				)
+				cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+					return cmdTask(g, opt)
+				})
+				cmd.SetArgs(append([]string{
+					"task", "list",
+					"--host=" + srv.URL,
+					"--token=token",
+					"--org-id=" + influxdb.ID(3).String(),
+					"--json",
+				}, tt.flags...))
+				require.NoError(t, cmd.Execute())
+
+				var got []http.Task
+				require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+				var names []string
+				for _, task := range got {
+					names = append(names, task.Name)
+				}
+				assert.Equal(t, tt.expected, names)
+			}
+
+			t.Run(tt.name, fn)
+		}
+
+		t.Run("active and inactive", func(t *testing.T) {
+			defer addEnvVars(t, envVarsZeroMap)()
+
+			builder := newInfluxCmdBuilder(
+				in(new(bytes.Buffer)),
+				out(ioutil.Discard),
+			)
+			cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+				return cmdTask(g, opt)
+			})
+			cmd.SetArgs([]string{
+				"task", "list",
+				"--org-id=" + influxdb.ID(3).String(),
+				"--active", "--inactive",
+			})
+			require.Error(t, cmd.Execute())
+		})
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type telegrafSVCsFn func() (influxdb.TelegrafConfigStore, influxdb.OrganizationService, error)
+
+func cmdTelegraf(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+	builder := newCmdTelegrafBuilder(newTelegrafSVCs, opt)
+	builder.globalFlags = f
+	return builder.cmd()
+}
+
+type cmdTelegrafBuilder struct {
+	genericCLIOpts
+	*globalFlags
+
+	svcFn telegrafSVCsFn
+
+	json        bool
+	hideHeaders bool
+	description string
+	dryRun      bool
+	file        string
+	name        string
+	org         organization
+}
+
+func newCmdTelegrafBuilder(svcFn telegrafSVCsFn, opt genericCLIOpts) *cmdTelegrafBuilder {
+	return &cmdTelegrafBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcFn,
+	}
+}
+
+func (b *cmdTelegrafBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("telegrafs", nil, false)
+	cmd.Aliases = []string{"telegraf"}
+	cmd.Short = "Telegraf configuration management commands"
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdFind(),
+		b.cmdImport(),
+	)
+	return cmd
+}
+
+func (b *cmdTelegrafBuilder) cmdFind() *cobra.Command {
+	cmd := b.newCmd("list", b.cmdFindRunEFn, true)
+	cmd.Short = "List telegraf configurations"
+	cmd.Aliases = []string{"find", "ls"}
+
+	b.org.register(cmd, false)
+	b.registerPrintFlags(cmd)
+
+	return cmd
+}
+
+func (b *cmdTelegrafBuilder) cmdFindRunEFn(cmd *cobra.Command, args []string) error {
+	if err := b.org.validOrgFlags(b.globalFlags); err != nil {
+		return err
+	}
+
+	teleSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	cfgs, _, err := teleSVC.FindTelegrafConfigs(context.Background(), influxdb.TelegrafConfigFilter{
+		OrgID: &orgID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve telegraf configurations: %v", err)
+	}
+
+	return b.printTelegrafs(cfgs...)
+}
+
+func (b *cmdTelegrafBuilder) cmdImport() *cobra.Command {
+	cmd := b.newCmd("import", b.cmdImportRunEFn, true)
+	cmd.Short = "Import a telegraf configuration from a TOML file"
+	cmd.Long = `Import a telegraf configuration from a TOML file. The name of the configuration is read from
+the name of its [agent] section, and otherwise taken from the --name flag.`
+
+	cmd.Flags().StringVarP(&b.file, "file", "f", "", "Path to the telegraf configuration file (required)")
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagFilename("file", "conf", "toml")
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "Name of the configuration, if its [agent] section does not name it")
+	cmd.Flags().StringVarP(&b.description, "description", "d", "", "Description of the configuration")
+	cmd.Flags().BoolVar(&b.dryRun, "dry-run", false, "Print the configuration that would be created without creating it")
+	b.org.register(cmd, false)
+	b.registerPrintFlags(cmd)
+
+	return cmd
+}
+
+func (b *cmdTelegrafBuilder) cmdImportRunEFn(cmd *cobra.Command, args []string) error {
+	if err := b.org.validOrgFlags(b.globalFlags); err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(b.file)
+	if err != nil {
+		return fmt.Errorf("failed to read telegraf configuration: %v", err)
+	}
+
+	name, err := telegrafAgentName(string(raw))
+	if err != nil {
+		return fmt.Errorf("failed to parse telegraf configuration %q: %v", b.file, err)
+	}
+	if name == "" {
+		name = b.name
+	}
+	if name == "" {
+		return errors.New("the telegraf configuration must be named by its [agent] section or the --name flag")
+	}
+
+	teleSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	cfg := &influxdb.TelegrafConfig{
+		OrgID:       orgID,
+		Name:        name,
+		Description: b.description,
+		Config:      string(raw),
+	}
+	if b.dryRun {
+		return b.writeJSON(cfg)
+	}
+
+	const fakeUserID = 0 // is 0 because user is pulled from token...
+	if err := teleSVC.CreateTelegrafConfig(context.Background(), cfg, fakeUserID); err != nil {
+		return fmt.Errorf("failed to create telegraf configuration: %v", err)
+	}
+
+	return b.printTelegrafs(cfg)
+}
+
+// telegrafAgentName returns the name given to a telegraf configuration by its
+// [agent] section, or "" if it does not name it.
+func telegrafAgentName(config string) (string, error) {
+	var cfg struct {
+		Agent struct {
+			Name string `toml:"name"`
+		} `toml:"agent"`
+	}
+	if _, err := toml.Decode(config, &cfg); err != nil {
+		return "", err
+	}
+	return cfg.Agent.Name, nil
+}
+
+func (b *cmdTelegrafBuilder) registerPrintFlags(cmd *cobra.Command) {
+	registerPrintOptions(cmd, &b.hideHeaders, &b.json)
+}
+
+func (b *cmdTelegrafBuilder) printTelegrafs(cfgs ...*influxdb.TelegrafConfig) error {
+	if b.json {
+		if cfgs == nil {
+			cfgs = []*influxdb.TelegrafConfig{}
+		}
+		return b.writeJSON(cfgs)
+	}
+
+	w := b.newTabWriter()
+	defer w.Flush()
+
+	w.HideHeaders(b.hideHeaders)
+
+	w.WriteHeaders("ID", "Organization ID", "Name", "Description")
+	for _, cfg := range cfgs {
+		w.Write(map[string]interface{}{
+			"ID":              cfg.ID.String(),
+			"Organization ID": cfg.OrgID.String(),
+			"Name":            cfg.Name,
+			"Description":     cfg.Description,
+		})
+	}
+
+	return nil
+}
+
+func newTelegrafSVCs() (influxdb.TelegrafConfigStore, influxdb.OrganizationService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	orgSvc := &http.OrganizationService{Client: httpClient}
+
+	return http.NewTelegrafService(httpClient), orgSvc, nil
+}
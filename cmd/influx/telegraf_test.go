@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdTelegraf(t *testing.T) {
+	orgID := influxdb.ID(9000)
+
+	fakeSVCFn := func(svc influxdb.TelegrafConfigStore) telegrafSVCsFn {
+		return func() (influxdb.TelegrafConfigStore, influxdb.OrganizationService, error) {
+			return svc, &mock.OrganizationService{
+				FindOrganizationF: func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+					return &influxdb.Organization{ID: orgID, Name: "influxdata"}, nil
+				},
+			}, nil
+		}
+	}
+
+	// newFakeStore returns a store that keeps the configurations created
+	// through it, so they are found by the list command.
+	newFakeStore := func() *mock.TelegrafConfigStore {
+		var cfgs []*influxdb.TelegrafConfig
+		svc := mock.NewTelegrafConfigStore()
+		svc.CreateTelegrafConfigF = func(ctx context.Context, tc *influxdb.TelegrafConfig, userID influxdb.ID) error {
+			tc.ID = influxdb.ID(len(cfgs) + 1)
+			cfgs = append(cfgs, tc)
+			return nil
+		}
+		svc.FindTelegrafConfigsF = func(ctx context.Context, filter influxdb.TelegrafConfigFilter, opt ...influxdb.FindOptions) ([]*influxdb.TelegrafConfig, int, error) {
+			var found []*influxdb.TelegrafConfig
+			for _, cfg := range cfgs {
+				if filter.OrgID == nil || cfg.OrgID == *filter.OrgID {
+					found = append(found, cfg)
+				}
+			}
+			return found, len(found), nil
+		}
+		return svc
+	}
+
+	writeConfig := func(t *testing.T, dir, config string) string {
+		t.Helper()
+
+		path := filepath.Join(dir, "telegraf.conf")
+		require.NoError(t, ioutil.WriteFile(path, []byte(config), os.ModePerm))
+		return path
+	}
+
+	execute := func(t *testing.T, svc influxdb.TelegrafConfigStore, args ...string) (*bytes.Buffer, error) {
+		t.Helper()
+
+		defer addEnvVars(t, envVarsZeroMap)()
+
+		outBuf := new(bytes.Buffer)
+		builder := newInfluxCmdBuilder(
+			in(new(bytes.Buffer)),
+			out(outBuf),
+		)
+		cmd := builder.cmd(func(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+			builder := newCmdTelegrafBuilder(fakeSVCFn(svc), opt)
+			builder.globalFlags = f
+			return builder.cmd()
+		})
+		cmd.SetArgs(args)
+
+		return outBuf, cmd.Execute()
+	}
+
+	const namedConfig = `
+[agent]
+  name = "system metrics"
+  interval = "10s"
+
+[[inputs.cpu]]
+
+[[outputs.influxdb_v2]]
+  urls = ["http://localhost:9999"]
+`
+
+	t.Run("import", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			config       string
+			flags        []string
+			expectedName string
+		}{
+			{
+				name:         "name from agent section",
+				config:       namedConfig,
+				expectedName: "system metrics",
+			},
+			{
+				name:         "agent section name takes precedence",
+				config:       namedConfig,
+				flags:        []string{"--name=other"},
+				expectedName: "system metrics",
+			},
+			{
+				name:         "name from flag",
+				config:       "[[inputs.cpu]]\n",
+				flags:        []string{"--name=cpu"},
+				expectedName: "cpu",
+			},
+		}
+
+		for _, tt := range tests {
+			fn := func(t *testing.T) {
+				dir := newTempDir(t)
+				defer os.RemoveAll(dir)
+				path := writeConfig(t, dir, tt.config)
+
+				svc := newFakeStore()
+				_, err := execute(t, svc, append([]string{
+					"telegrafs", "import",
+					"--org-id=" + orgID.String(),
+					"--file=" + path,
+				}, tt.flags...)...)
+				require.NoError(t, err)
+
+				outBuf, err := execute(t, svc, "telegrafs", "list", "--org-id="+orgID.String(), "--json")
+				require.NoError(t, err)
+
+				var cfgs []influxdb.TelegrafConfig
+				require.NoError(t, json.Unmarshal(outBuf.Bytes(), &cfgs))
+				require.Len(t, cfgs, 1)
+				assert.Equal(t, influxdb.ID(1), cfgs[0].ID)
+				assert.Equal(t, orgID, cfgs[0].OrgID)
+				assert.Equal(t, tt.expectedName, cfgs[0].Name)
+				assert.Equal(t, tt.config, cfgs[0].Config)
+			}
+
+			t.Run(tt.name, fn)
+		}
+
+		t.Run("dry run does not create the configuration", func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+			path := writeConfig(t, dir, namedConfig)
+
+			svc := newFakeStore()
+			outBuf, err := execute(t, svc,
+				"telegrafs", "import",
+				"--org-id="+orgID.String(),
+				"--file="+path,
+				"--dry-run",
+			)
+			require.NoError(t, err)
+
+			var cfg influxdb.TelegrafConfig
+			require.NoError(t, json.Unmarshal(outBuf.Bytes(), &cfg))
+			assert.Equal(t, "system metrics", cfg.Name)
+			assert.Equal(t, namedConfig, cfg.Config)
+			assert.Zero(t, svc.CreateTelegrafConfigCalls.Count())
+		})
+
+		t.Run("errors without a name", func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+			path := writeConfig(t, dir, "[[inputs.cpu]]\n")
+
+			svc := newFakeStore()
+			_, err := execute(t, svc,
+				"telegrafs", "import",
+				"--org-id="+orgID.String(),
+				"--file="+path,
+			)
+			require.Error(t, err)
+			assert.Zero(t, svc.CreateTelegrafConfigCalls.Count())
+		})
+
+		t.Run("errors on invalid toml", func(t *testing.T) {
+			dir := newTempDir(t)
+			defer os.RemoveAll(dir)
+			path := writeConfig(t, dir, "[agent\n")
+
+			svc := newFakeStore()
+			_, err := execute(t, svc,
+				"telegrafs", "import",
+				"--org-id="+orgID.String(),
+				"--file="+path,
+				"--name=cpu",
+			)
+			require.Error(t, err)
+			assert.Zero(t, svc.CreateTelegrafConfigCalls.Count())
+		})
+	})
+}
@@ -21,12 +21,14 @@ const (
 )
 
 type writeFlagsType struct {
-	org       organization
-	BucketID  string
-	Bucket    string
-	Precision string
-	Format    string
-	File      string
+	org        organization
+	BucketID   string
+	Bucket     string
+	Precision  string
+	Format     string
+	File       string
+	BatchSize  int
+	MaxRetries int
 }
 
 var writeFlags writeFlagsType
@@ -61,6 +63,17 @@ func cmdWrite(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 			Desc:       "Precision of the timestamps of the lines",
 			Persistent: true,
 		},
+		{
+			DestP: &writeFlags.BatchSize,
+			Flag:  "batch-size",
+			Desc:  "The maximum number of points to send in each request, if not 0",
+		},
+		{
+			DestP:   &writeFlags.MaxRetries,
+			Flag:    "max-retries",
+			Default: 3,
+			Desc:    "The maximum number of times to retry a failed request, waiting twice as long before each retry",
+		},
 	}
 	opts.mustRegister(cmd)
 	cmd.PersistentFlags().StringVar(&writeFlags.Format, "format", "", "Input format, either lp (Line Protocol) or csv (Comma Separated Values). Defaults to lp unless '.csv' extension")
@@ -174,8 +187,29 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if writeFlags.BatchSize < 0 {
+		return fmt.Errorf("invalid batch size")
+	}
+
+	// the total of the progress is only known for line protocol files
+	total := -1
+	if writeFlags.File != "" && writeFlags.Format != inputFormatCsv {
+		if total, err = countFilePoints(writeFlags.File); err != nil {
+			return err
+		}
+	}
+
 	// write to InfluxDB
 	s := write.Batcher{
+		MaxFlushLines: writeFlags.BatchSize,
+		MaxRetries:    writeFlags.MaxRetries,
+		Progress: func(points int) {
+			if total >= 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "\r%d/%d points", points, total)
+			} else {
+				fmt.Fprintf(cmd.ErrOrStderr(), "\r%d points", points)
+			}
+		},
 		Service: &http.WriteService{
 			Addr:               flags.Host,
 			Token:              flags.Token,
@@ -184,13 +218,30 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 		},
 	}
 	ctx = signals.WithStandardSignals(ctx)
-	if err := s.Write(ctx, orgID, bucketID, r); err != nil && err != context.Canceled {
+	err = s.Write(ctx, orgID, bucketID, r)
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil && err != context.Canceled {
 		return fmt.Errorf("failed to write data: %v", err)
 	}
 
 	return nil
 }
 
+// countFilePoints returns the number of points in the line protocol file.
+func countFilePoints(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %q: %v", path, err)
+	}
+	defer f.Close()
+
+	n, err := write.CountPoints(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %q: %v", path, err)
+	}
+	return n, nil
+}
+
 func fluxWriteDryrunF(cmd *cobra.Command, args []string) error {
 	// create line reader
 	r, closer, err := writeFlags.createLineReader(args)
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdWrite(t *testing.T) {
+	orgID, bucketID := influxdb.ID(9000), influxdb.ID(9001)
+
+	t.Run("batch size", func(t *testing.T) {
+		var (
+			mu       sync.Mutex
+			requests int
+			written  []string
+		)
+		srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			switch r.URL.Path {
+			case "/api/v2/buckets":
+				fmt.Fprintf(w, `{"buckets":[{"id":%q,"orgID":%q,"name":"b","retentionRules":[]}]}`, bucketID, orgID)
+			case "/api/v2/write":
+				if r.URL.Query().Get("bucket") != bucketID.String() {
+					w.WriteHeader(nethttp.StatusBadRequest)
+					return
+				}
+				gr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					w.WriteHeader(nethttp.StatusBadRequest)
+					return
+				}
+				data, err := ioutil.ReadAll(gr)
+				if err != nil {
+					w.WriteHeader(nethttp.StatusBadRequest)
+					return
+				}
+
+				mu.Lock()
+				requests++
+				written = append(written, strings.Fields(string(data))...)
+				mu.Unlock()
+				w.WriteHeader(nethttp.StatusNoContent)
+			default:
+				w.WriteHeader(nethttp.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+
+		var lines []string
+		for i := 0; i < 100; i++ {
+			lines = append(lines, fmt.Sprintf("m,host=h%d v=%di", i, i))
+		}
+		dir := newTempDir(t)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "points.lp")
+		require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+		stderr := new(bytes.Buffer)
+		builder := newInfluxCmdBuilder(
+			in(new(bytes.Buffer)),
+			out(ioutil.Discard),
+			err(stderr),
+			runEMiddlware(func(fn cobraRunEFn) cobraRunEFn { return fn }),
+		)
+		cmd := builder.cmd(cmdWrite)
+		cmd.SetArgs([]string{"write",
+			"--host=" + srv.URL,
+			"--token=TOKEN",
+			"--org-id=" + orgID.String(),
+			"--bucket-id=" + bucketID.String(),
+			"--file=" + path,
+			"--batch-size=10",
+		})
+		defer func() { writeFlags = writeFlagsType{} }()
+
+		require.NoError(t, cmd.Execute())
+
+		assert.Equal(t, 10, requests)
+		var expected []string
+		for _, l := range lines {
+			expected = append(expected, strings.Fields(l)...)
+		}
+		assert.Equal(t, expected, written)
+		assert.Contains(t, stderr.String(), "\r10/100 points")
+		assert.True(t, strings.HasSuffix(stderr.String(), "\r100/100 points\n"), stderr.String())
+	})
+}
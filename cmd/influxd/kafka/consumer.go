@@ -0,0 +1,241 @@
+// Package kafka implements a connector that consumes messages from a Kafka
+// topic and writes them to a bucket, and that produces messages of the points
+// written to the storage engine.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	kafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Message formats supported by the Consumer.
+const (
+	FormatLineProtocol = "line-protocol"
+	FormatJSON         = "json"
+)
+
+// Config configures the Kafka connector.
+type Config struct {
+	Brokers       []string
+	Topic         string
+	ConsumerGroup string
+
+	// Org and Bucket are the names or IDs of the organization and bucket
+	// messages are written to.
+	Org    string
+	Bucket string
+
+	// DLQTopic is the topic messages that cannot be parsed, or whose points
+	// are rejected, are written to. If empty, such messages are logged and
+	// dropped.
+	DLQTopic string
+
+	// Format is the format of messages, either FormatLineProtocol or
+	// FormatJSON.
+	Format string
+	JSON   JSONMapping
+
+	// ProducerTopic is the topic the points written to the storage engine are
+	// written to. If empty, they are not.
+	ProducerTopic string
+}
+
+// ConsumerEnabled returns whether messages are consumed from Topic. They are
+// unless only a producer topic is configured.
+func (c Config) ConsumerEnabled() bool {
+	return len(c.Brokers) > 0 && (c.Topic != "" || c.ProducerTopic == "")
+}
+
+// ProducerEnabled returns whether the points written to the storage engine are
+// written to ProducerTopic.
+func (c Config) ProducerEnabled() bool {
+	return len(c.Brokers) > 0 && c.ProducerTopic != ""
+}
+
+// Validate returns an error if the configuration is incomplete.
+func (c Config) Validate() error {
+	switch {
+	case len(c.Brokers) == 0:
+		return errors.New("kafka brokers are required")
+	case c.Topic == "":
+		return errors.New("kafka topic is required")
+	case c.ConsumerGroup == "":
+		return errors.New("kafka consumer group is required")
+	case c.Org == "" || c.Bucket == "":
+		return errors.New("kafka destination org and bucket are required")
+	}
+
+	switch c.Format {
+	case "", FormatLineProtocol:
+	case FormatJSON:
+		return c.JSON.Validate()
+	default:
+		return fmt.Errorf("unknown kafka message format %q", c.Format)
+	}
+	return nil
+}
+
+// MessageReader reads messages from a topic as a member of a consumer group.
+// It is implemented by *kafka.Reader.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// MessageWriter writes messages to a topic. It is implemented by
+// *kafka.Writer.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Consumer writes the messages read from a topic to a bucket. The offset of a
+// message is only committed once its points have been written, or it has been
+// written to the dead-letter topic, so no message is lost if the Consumer
+// stops.
+type Consumer struct {
+	Reader MessageReader
+	// DLQ receives the messages that cannot be parsed or whose points are
+	// rejected. It may be nil.
+	DLQ MessageWriter
+
+	PointsWriter storage.PointsWriter
+	OrgID        influxdb.ID
+	BucketID     influxdb.ID
+
+	// Parse converts the value of a message to line protocol.
+	Parse func(value []byte) ([]byte, error)
+
+	// RetryInterval is the initial interval between attempts to write the
+	// points of a message. It doubles after each failed attempt, up to
+	// MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	logger *zap.Logger
+}
+
+// NewConsumer returns a Consumer writing the messages read by r to the given
+// bucket. The value of each message is parsed according to config.
+func NewConsumer(config Config, r MessageReader, dlq MessageWriter, pw storage.PointsWriter, orgID, bucketID influxdb.ID) *Consumer {
+	parse := parseLineProtocol
+	if config.Format == FormatJSON {
+		parse = config.JSON.LineProtocol
+	}
+	return &Consumer{
+		Reader:           r,
+		DLQ:              dlq,
+		PointsWriter:     pw,
+		OrgID:            orgID,
+		BucketID:         bucketID,
+		Parse:            parse,
+		RetryInterval:    time.Second,
+		MaxRetryInterval: 30 * time.Second,
+		logger:           zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger on the Consumer.
+func (c *Consumer) WithLogger(log *zap.Logger) {
+	c.logger = log.With(zap.String("service", "kafka-consumer"))
+}
+
+// Run consumes messages until ctx is canceled or reading from the topic
+// fails.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msg, err := c.Reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if err := c.process(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if err := c.Reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// process writes the points of msg, retrying until they are written or ctx
+// is canceled. Messages that cannot be parsed, or whose points are rejected,
+// are sent to the dead-letter topic instead, as retrying them cannot succeed.
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) error {
+	log := c.logger.With(zap.Int("partition", msg.Partition), zap.Int64("offset", msg.Offset))
+
+	points, err := c.parsePoints(msg.Value)
+	if err != nil {
+		return c.deadLetter(ctx, log, msg, "Message cannot be parsed", err)
+	} else if len(points) == 0 {
+		return nil
+	}
+
+	interval := c.RetryInterval
+	for {
+		err := c.PointsWriter.WritePoints(ctx, points)
+		if err == nil {
+			return nil
+		} else if _, ok := err.(tsdb.PartialWriteError); ok {
+			// The other points were written, and the dropped points would be
+			// dropped again.
+			return c.deadLetter(ctx, log, msg, "Points of message were dropped", err)
+		} else if !influxdb.IsRetryable(err) {
+			return c.deadLetter(ctx, log, msg, "Points of message were rejected", err)
+		}
+		log.Error("Failed to write points; retrying", zap.Error(err), zap.Duration("retry_interval", interval))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > c.MaxRetryInterval {
+			interval = c.MaxRetryInterval
+		}
+	}
+}
+
+// deadLetter writes msg to the dead-letter topic, or drops it if there is
+// none.
+func (c *Consumer) deadLetter(ctx context.Context, log *zap.Logger, msg kafka.Message, reason string, err error) error {
+	if c.DLQ == nil {
+		log.Warn(reason+"; dropping message", zap.Error(err))
+		return nil
+	}
+	log.Info(reason+"; writing message to dead-letter topic", zap.Error(err))
+	return c.DLQ.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value})
+}
+
+func (c *Consumer) parsePoints(value []byte) ([]models.Point, error) {
+	lp, err := c.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	encoded := tsdb.EncodeName(c.OrgID, c.BucketID)
+	return models.ParsePoints(lp, models.EscapeMeasurement(encoded[:]))
+}
+
+func parseLineProtocol(value []byte) ([]byte, error) {
+	return value, nil
+}
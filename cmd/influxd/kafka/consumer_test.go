@@ -0,0 +1,336 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+func TestConsumer_Run(t *testing.T) {
+	r := newFakeReader(
+		kafka.Message{Offset: 0, Value: []byte("cpu,host=a usage=1 1000000000\n")},
+		kafka.Message{Offset: 1, Value: []byte("not line protocol")},
+		kafka.Message{Offset: 2, Value: []byte("cpu,host=b usage=2 2000000000\n")},
+	)
+	dlq := &fakeWriter{}
+	pw := &fakePointsWriter{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.onEmpty = cancel
+
+	c := NewConsumer(Config{}, r, dlq, pw, 1, 2)
+	if err := c.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := r.committed, []int64{0, 1, 2}; !equalInt64s(got, exp) {
+		t.Errorf("unexpected committed offsets: got %v, exp %v", got, exp)
+	}
+	if got := len(dlq.messages); got != 1 || string(dlq.messages[0].Value) != "not line protocol" {
+		t.Errorf("unexpected dead-letter messages: %v", dlq.messages)
+	}
+	if got := len(pw.points); got != 2 {
+		t.Fatalf("got %d points, exp 2", got)
+	}
+
+	name := tsdb.EncodeName(1, 2)
+	encoded := models.EscapeMeasurement(name[:])
+	if got := pw.points[0].Name(); string(got) != string(encoded) {
+		t.Errorf("unexpected point name: got %q, exp %q", got, encoded)
+	}
+}
+
+func TestConsumer_Run_RetriesWrite(t *testing.T) {
+	r := newFakeReader(kafka.Message{Offset: 7, Value: []byte("cpu usage=1 1000000000\n")})
+	pw := &fakePointsWriter{failures: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewConsumer(Config{}, r, nil, pw, 1, 2)
+	c.RetryInterval = time.Millisecond
+	c.MaxRetryInterval = time.Millisecond
+
+	// Nothing may be committed before the write succeeds.
+	pw.onWrite = func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if len(r.committed) != 0 {
+			t.Errorf("offset committed before points were written: %v", r.committed)
+		}
+	}
+	r.onEmpty = cancel
+
+	if err := c.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := pw.attempts, 3; got != exp {
+		t.Errorf("got %d write attempts, exp %d", got, exp)
+	}
+	if got, exp := r.committed, []int64{7}; !equalInt64s(got, exp) {
+		t.Errorf("unexpected committed offsets: got %v, exp %v", got, exp)
+	}
+}
+
+func TestConsumer_Run_RejectedWrite(t *testing.T) {
+	for _, err := range []error{
+		&influxdb.Error{Code: influxdb.EInvalid, Msg: "field type conflict"},
+		&influxdb.Error{Code: influxdb.ENotFound, Msg: "bucket not found"},
+		tsdb.PartialWriteError{Reason: "field type conflict", Dropped: 1},
+	} {
+		t.Run(err.Error(), func(t *testing.T) {
+			r := newFakeReader(
+				kafka.Message{Offset: 4, Value: []byte("cpu usage=1 1000000000\n")},
+				kafka.Message{Offset: 5, Value: []byte("cpu usage=2 2000000000\n")},
+			)
+			dlq := &fakeWriter{}
+			pw := &fakePointsWriter{failures: -1, err: err}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r.onEmpty = cancel
+
+			c := NewConsumer(Config{}, r, dlq, pw, 1, 2)
+			c.RetryInterval = time.Hour
+			if err := c.Run(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			// Rejected points are not retried, and the consumer moves on.
+			if got, exp := pw.attempts, 2; got != exp {
+				t.Errorf("got %d write attempts, exp %d", got, exp)
+			}
+			if got, exp := r.committed, []int64{4, 5}; !equalInt64s(got, exp) {
+				t.Errorf("unexpected committed offsets: got %v, exp %v", got, exp)
+			}
+			if got := len(dlq.messages); got != 2 {
+				t.Errorf("got %d dead-letter messages, exp 2", got)
+			}
+		})
+	}
+}
+
+func TestConsumer_Run_CanceledWithoutCommit(t *testing.T) {
+	r := newFakeReader(kafka.Message{Offset: 3, Value: []byte("cpu usage=1 1000000000\n")})
+	pw := &fakePointsWriter{failures: -1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pw.onWrite = func(error) { cancel() }
+
+	c := NewConsumer(Config{}, r, nil, pw, 1, 2)
+	c.RetryInterval = time.Hour
+	if err := c.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.committed) != 0 {
+		t.Errorf("unexpected committed offsets: %v", r.committed)
+	}
+}
+
+func TestConsumer_Run_JSON(t *testing.T) {
+	r := newFakeReader(kafka.Message{Value: []byte(`{"name":"cpu","host":"a","usage":1.5,"ts":"2019-01-01T00:00:00Z"}`)})
+	pw := &fakePointsWriter{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.onEmpty = cancel
+
+	config := Config{
+		Format: FormatJSON,
+		JSON:   JSONMapping{MeasurementKey: "name", TagKeys: []string{"host"}, TimeKey: "ts"},
+	}
+	c := NewConsumer(config, r, nil, pw, 1, 2)
+	if err := c.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(pw.points); got != 1 {
+		t.Fatalf("got %d points, exp 1", got)
+	}
+
+	p := pw.points[0]
+	if got, exp := p.Time(), time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(exp) {
+		t.Errorf("unexpected time: got %v, exp %v", got, exp)
+	}
+	if got := p.Tags().GetString("host"); got != "a" {
+		t.Errorf("unexpected host tag: %q", got)
+	}
+	if got := p.Tags().GetString(models.MeasurementTagKey); got != "cpu" {
+		t.Errorf("unexpected measurement: %q", got)
+	}
+}
+
+func TestJSONMapping_LineProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping JSONMapping
+		value   string
+		exp     string
+		err     bool
+	}{
+		{
+			name:    "all fields",
+			mapping: JSONMapping{MeasurementKey: "m", TagKeys: []string{"region", "host"}},
+			value:   `{"m":"cpu","host":"a b","region":"us","usage":10,"ok":true,"state":"up"}`,
+			exp:     "cpu,host=a\\ b,region=us ok=true,state=\"up\",usage=10\n",
+		},
+		{
+			name:    "selected fields",
+			mapping: JSONMapping{MeasurementKey: "m", FieldKeys: []string{"usage"}, TimeKey: "t"},
+			value:   `{"m":"cpu","usage":0.5,"other":1,"t":1000}`,
+			exp:     "cpu usage=0.5 1000\n",
+		},
+		{
+			name:    "missing measurement",
+			mapping: JSONMapping{MeasurementKey: "m"},
+			value:   `{"usage":1}`,
+			err:     true,
+		},
+		{
+			name:    "no fields",
+			mapping: JSONMapping{MeasurementKey: "m", TagKeys: []string{"host"}},
+			value:   `{"m":"cpu","host":"a"}`,
+			err:     true,
+		},
+		{
+			name:    "nested object",
+			mapping: JSONMapping{MeasurementKey: "m"},
+			value:   `{"m":"cpu","usage":{"user":1}}`,
+			err:     true,
+		},
+		{
+			name:    "invalid JSON",
+			mapping: JSONMapping{MeasurementKey: "m"},
+			value:   `{"m":`,
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.mapping.LineProtocol([]byte(tt.value))
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.exp {
+				t.Errorf("got %q, exp %q", got, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{Brokers: []string{"localhost:9092"}, Topic: "metrics", ConsumerGroup: "influxdb", Org: "org", Bucket: "bucket"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := valid
+	invalid.Format = FormatJSON
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for JSON format without measurement key")
+	}
+
+	invalid = valid
+	invalid.Bucket = ""
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for missing bucket")
+	}
+}
+
+func equalInt64s(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeReader returns its messages in order, then blocks until ctx is canceled.
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []int64
+	onEmpty   func()
+}
+
+func newFakeReader(msgs ...kafka.Message) *fakeReader {
+	return &fakeReader{messages: msgs}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	onEmpty := r.onEmpty
+	r.mu.Unlock()
+
+	if onEmpty != nil {
+		onEmpty()
+	}
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+type fakeWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+// fakePointsWriter fails its first failures writes, or every write if
+// failures is negative, with err or a generic error if it is nil.
+type fakePointsWriter struct {
+	failures int
+	err      error
+	attempts int
+	points   []models.Point
+	onWrite  func(err error)
+}
+
+func (w *fakePointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	w.attempts++
+	var err error
+	if w.failures < 0 || w.attempts <= w.failures {
+		err = w.err
+		if err == nil {
+			err = errors.New("write failed")
+		}
+	} else {
+		w.points = append(w.points, points...)
+	}
+	if w.onWrite != nil {
+		w.onWrite(err)
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+)
+
+// JSONMapping maps the properties of JSON messages to a point. Each message
+// must be a JSON object; the value of the MeasurementKey property is the
+// measurement of its point, the TagKeys properties are its tags and the
+// FieldKeys properties are its fields. If FieldKeys is empty, all other
+// properties are fields.
+type JSONMapping struct {
+	MeasurementKey string
+	TagKeys        []string
+	FieldKeys      []string
+
+	// TimeKey is the property holding the time of the point, either as an
+	// RFC3339 string or as a number of nanoseconds since the epoch. Points
+	// without a time are written at the time they are received.
+	TimeKey string
+}
+
+// Validate returns an error if the mapping has no measurement.
+func (m JSONMapping) Validate() error {
+	if m.MeasurementKey == "" {
+		return errors.New("kafka JSON measurement key is required")
+	}
+	return nil
+}
+
+// LineProtocol converts the JSON object in value to line protocol.
+func (m JSONMapping) LineProtocol(value []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	name, ok := obj[m.MeasurementKey].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("missing measurement property %q", m.MeasurementKey)
+	}
+
+	isTag := make(map[string]bool, len(m.TagKeys))
+	var tagKeys []string
+	for _, k := range m.TagKeys {
+		isTag[k] = true
+		if _, ok := obj[k]; ok {
+			tagKeys = append(tagKeys, k)
+		}
+	}
+	sort.Strings(tagKeys)
+
+	fieldKeys := m.FieldKeys
+	if len(fieldKeys) == 0 {
+		for k := range obj {
+			if k != m.MeasurementKey && k != m.TimeKey && !isTag[k] {
+				fieldKeys = append(fieldKeys, k)
+			}
+		}
+		sort.Strings(fieldKeys)
+	}
+
+	buf := append([]byte(nil), models.EscapeMeasurement([]byte(name))...)
+	for _, k := range tagKeys {
+		v, err := tagValue(obj[k])
+		if err != nil {
+			return nil, fmt.Errorf("tag %q: %v", k, err)
+		} else if v == "" {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = append(buf, escape.String(k)...)
+		buf = append(buf, '=')
+		buf = append(buf, escape.String(v)...)
+	}
+
+	n := 0
+	for _, k := range fieldKeys {
+		v, ok := obj[k]
+		if !ok || v == nil {
+			continue
+		}
+		if n == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, escape.String(k)...)
+		buf = append(buf, '=')
+
+		var err error
+		if buf, err = appendFieldValue(buf, v); err != nil {
+			return nil, fmt.Errorf("field %q: %v", k, err)
+		}
+		n++
+	}
+	if n == 0 {
+		return nil, errors.New("message has no fields")
+	}
+
+	if m.TimeKey != "" {
+		if v, ok := obj[m.TimeKey]; ok {
+			t, err := timeValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("time %q: %v", m.TimeKey, err)
+			}
+			buf = append(buf, ' ')
+			buf = strconv.AppendInt(buf, t, 10)
+		}
+	}
+	return append(buf, '\n'), nil
+}
+
+func tagValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// appendFieldValue appends v as a line protocol field value. Numbers are
+// written as floats so that a field has the same type in every message.
+func appendFieldValue(buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		buf = append(buf, '"')
+		buf = append(buf, models.EscapeStringField(v)...)
+		return append(buf, '"'), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendFloat(buf, f, 'f', -1, 64), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", v)
+}
+
+func timeValue(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return 0, err
+		}
+		return t.UnixNano(), nil
+	case json.Number:
+		return v.Int64()
+	}
+	return 0, fmt.Errorf("unsupported value %v", v)
+}
@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// Default values of the Producer.
+const (
+	DefaultProducerTimeout        = 10 * time.Second
+	DefaultProducerMaxMessageSize = 1024 * 1024
+)
+
+// Producer writes the points written to the storage engine to a topic as
+// line protocol. Its WritePoints method is registered as a write side effect
+// of the engine.
+type Producer struct {
+	Writer MessageWriter
+
+	// Timeout is the maximum duration of the write of the points of a write.
+	Timeout time.Duration
+
+	// MaxMessageSize is the size in bytes above which the points of a bucket
+	// are split across messages.
+	MaxMessageSize int
+}
+
+// NewProducer returns a Producer writing messages with w.
+func NewProducer(w MessageWriter) *Producer {
+	return &Producer{
+		Writer:         w,
+		Timeout:        DefaultProducerTimeout,
+		MaxMessageSize: DefaultProducerMaxMessageSize,
+	}
+}
+
+// NewKafkaProducer returns a Producer writing to the producer topic of config.
+func NewKafkaProducer(config Config) *Producer {
+	return NewProducer(kafka.NewWriter(kafka.WriterConfig{
+		Brokers: config.Brokers,
+		Topic:   config.ProducerTopic,
+	}))
+}
+
+// Close closes the writer of the Producer.
+func (p *Producer) Close() error {
+	return p.Writer.Close()
+}
+
+// WritePoints writes the points of a write to the topic, which are the points
+// stored by the engine. The messages of each bucket are keyed by the IDs of
+// the organization and bucket, as "<org ID>/<bucket ID>", and their value is
+// the line protocol of the points as they were written to the bucket.
+func (p *Producer) WritePoints(points []models.Point) error {
+	var msgs []kafka.Message
+	var keys []string
+	values := make(map[string]*bytes.Buffer)
+	for _, pt := range points {
+		lp, err := bucketPoint(pt)
+		if err != nil {
+			return err
+		}
+
+		org, bucket := tsdb.DecodeNameSlice(pt.Name())
+		key := org.String() + "/" + bucket.String()
+		buf := values[key]
+		if buf == nil {
+			buf = new(bytes.Buffer)
+			values[key] = buf
+			keys = append(keys, key)
+		} else if buf.Len()+len(lp) > p.MaxMessageSize {
+			msgs = append(msgs, kafka.Message{Key: []byte(key), Value: buf.Bytes()})
+			buf = new(bytes.Buffer)
+			values[key] = buf
+		}
+		buf.Write(lp)
+	}
+	for _, key := range keys {
+		msgs = append(msgs, kafka.Message{Key: []byte(key), Value: values[key].Bytes()})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+	return p.Writer.WriteMessages(ctx, msgs...)
+}
+
+// bucketPoint returns the line protocol of a point stored by the engine, as it
+// was written to its bucket.
+func bucketPoint(pt models.Point) ([]byte, error) {
+	tags := pt.Tags()
+	measurement := tags.Get(models.MeasurementTagKeyBytes)
+	field := tags.Get(models.FieldKeyTagKeyBytes)
+
+	bucketTags := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if !bytes.Equal(t.Key, models.MeasurementTagKeyBytes) && !bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			bucketTags = append(bucketTags, t)
+		}
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		return nil, err
+	}
+	bp, err := models.NewPoint(string(measurement), bucketTags, models.Fields{string(field): fields[string(field)]}, pt.Time())
+	if err != nil {
+		return nil, err
+	}
+	return append(bp.AppendString(nil), '\n'), nil
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestProducer_WritePoints(t *testing.T) {
+	points, err := parsePoints(1, 2, "cpu,host=a usage=1,idle=2 1000000000\nmem free=3i 2000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := parsePoints(1, 3, "disk used=4 3000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &fakeWriter{}
+	p := NewProducer(w)
+	if err := p.WritePoints(append(points, other...)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(w.messages), 2; got != exp {
+		t.Fatalf("got %d messages, exp %d", got, exp)
+	}
+	if got, exp := string(w.messages[0].Key), "0000000000000001/0000000000000002"; got != exp {
+		t.Errorf("unexpected key: got %q, exp %q", got, exp)
+	}
+	if got, exp := string(w.messages[0].Value), "cpu,host=a usage=1 1000000000\ncpu,host=a idle=2 1000000000\nmem free=3i 2000000000\n"; got != exp {
+		t.Errorf("unexpected value: got %q, exp %q", got, exp)
+	}
+	if got, exp := string(w.messages[1].Key), "0000000000000001/0000000000000003"; got != exp {
+		t.Errorf("unexpected key: got %q, exp %q", got, exp)
+	}
+	if got, exp := string(w.messages[1].Value), "disk used=4 3000000000\n"; got != exp {
+		t.Errorf("unexpected value: got %q, exp %q", got, exp)
+	}
+}
+
+func TestProducer_WritePoints_MaxMessageSize(t *testing.T) {
+	points, err := parsePoints(1, 2, "cpu usage=1 1\ncpu usage=2 2\ncpu usage=3 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &fakeWriter{}
+	p := NewProducer(w)
+	p.MaxMessageSize = len("cpu usage=1 1\n") * 2
+	if err := p.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	for _, m := range w.messages {
+		values = append(values, string(m.Value))
+	}
+	if got, exp := len(values), 2; got != exp {
+		t.Fatalf("got %d messages %q, exp %d", got, values, exp)
+	}
+	if got, exp := values[0]+values[1], "cpu usage=1 1\ncpu usage=2 2\ncpu usage=3 3\n"; got != exp {
+		t.Errorf("unexpected values: got %q, exp %q", got, exp)
+	}
+}
+
+// parsePoints returns the points of lp as the storage engine stores them.
+func parsePoints(org, bucket influxdb.ID, lp string) ([]models.Point, error) {
+	name := tsdb.EncodeName(org, bucket)
+	return models.ParsePointsString(lp, string(models.EscapeMeasurement(name[:])))
+}
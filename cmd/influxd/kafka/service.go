@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	kafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// lookupInterval is the interval at which the destination bucket is looked up
+// until it exists.
+const lookupInterval = 10 * time.Second
+
+// Service runs a Consumer for the destination bucket of its Config.
+type Service struct {
+	Config Config
+
+	OrganizationService influxdb.OrganizationService
+	BucketService       influxdb.BucketService
+	PointsWriter        storage.PointsWriter
+
+	logger *zap.Logger
+}
+
+// NewService returns a Service consuming messages as configured by config.
+func NewService(config Config, orgs influxdb.OrganizationService, buckets influxdb.BucketService, pw storage.PointsWriter) *Service {
+	return &Service{
+		Config:              config,
+		OrganizationService: orgs,
+		BucketService:       buckets,
+		PointsWriter:        pw,
+		logger:              zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger on the Service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.logger = log.With(zap.String("service", "kafka-consumer"))
+}
+
+// Run consumes messages until ctx is canceled. As the destination bucket may
+// not exist yet, for example before the instance is set up, it is looked up
+// periodically until it is found.
+func (s *Service) Run(ctx context.Context) error {
+	if err := s.Config.Validate(); err != nil {
+		return err
+	}
+
+	var bucket *influxdb.Bucket
+	for {
+		var err error
+		if bucket, err = s.findBucket(ctx); err == nil {
+			break
+		}
+		s.logger.Info("Waiting for destination bucket", zap.String("org", s.Config.Org), zap.String("bucket", s.Config.Bucket), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(lookupInterval):
+		}
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: s.Config.Brokers,
+		GroupID: s.Config.ConsumerGroup,
+		Topic:   s.Config.Topic,
+	})
+	defer r.Close()
+
+	var dlq MessageWriter
+	if s.Config.DLQTopic != "" {
+		w := kafka.NewWriter(kafka.WriterConfig{
+			Brokers: s.Config.Brokers,
+			Topic:   s.Config.DLQTopic,
+		})
+		defer w.Close()
+		dlq = w
+	}
+
+	c := NewConsumer(s.Config, r, dlq, s.PointsWriter, bucket.OrgID, bucket.ID)
+	c.logger = s.logger
+	s.logger.Info("Consuming messages", zap.String("topic", s.Config.Topic), zap.Stringer("bucket_id", bucket.ID))
+	return c.Run(ctx)
+}
+
+// findBucket finds the destination bucket. The organization and bucket may be
+// given by name or by ID.
+func (s *Service) findBucket(ctx context.Context) (*influxdb.Bucket, error) {
+	var orgID influxdb.ID
+	if err := orgID.DecodeFromString(s.Config.Org); err != nil {
+		org, err := s.OrganizationService.FindOrganization(ctx, influxdb.OrganizationFilter{Name: &s.Config.Org})
+		if err != nil {
+			return nil, err
+		}
+		orgID = org.ID
+	}
+
+	var bucketID influxdb.ID
+	if err := bucketID.DecodeFromString(s.Config.Bucket); err == nil {
+		b, err := s.BucketService.FindBucketByID(ctx, bucketID)
+		if err != nil {
+			return nil, err
+		}
+		if b.OrgID != orgID {
+			return nil, &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  "bucket not found in organization",
+			}
+		}
+		return b, nil
+	}
+	return s.BucketService.FindBucket(ctx, influxdb.BucketFilter{OrganizationID: &orgID, Name: &s.Config.Bucket})
+}
@@ -30,8 +30,17 @@ type Engine interface {
 	storage.BucketDeleter
 	prom.PrometheusCollector
 	influxdb.BackupService
+	influxdb.DefragmentService
+	influxdb.RecoveryService
+	influxdb.ReadinessService
+	influxdb.EngineStatsService
+	influxdb.BucketSchemaService
+	influxdb.BucketMetadataService
 
 	SeriesCardinality() int64
+	SeriesCount(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error)
+	EstimatedCompressedSize(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error)
+	FlushCache(ctx context.Context) error
 
 	WithLogger(log *zap.Logger)
 	Open(context.Context) error
@@ -114,6 +123,27 @@ func (t *TemporaryEngine) SeriesCardinality() int64 {
 	return t.engine.SeriesCardinality()
 }
 
+// SeriesCount returns the number of distinct series in the bucket.
+func (t *TemporaryEngine) SeriesCount(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	return t.engine.SeriesCount(ctx, orgID, bucketID)
+}
+
+// EstimatedCompressedSize returns an estimate of the disk space used by the
+// data of the bucket.
+func (t *TemporaryEngine) EstimatedCompressedSize(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	return t.engine.EstimatedCompressedSize(ctx, orgID, bucketID)
+}
+
+// WriteMetadata replaces the metadata of the bucket.
+func (t *TemporaryEngine) WriteMetadata(ctx context.Context, orgID, bucketID influxdb.ID, metadata map[string]string) error {
+	return t.engine.WriteMetadata(ctx, orgID, bucketID, metadata)
+}
+
+// ReadMetadata returns the metadata of the bucket.
+func (t *TemporaryEngine) ReadMetadata(ctx context.Context, orgID, bucketID influxdb.ID) (map[string]string, error) {
+	return t.engine.ReadMetadata(ctx, orgID, bucketID)
+}
+
 // DeleteBucketRangePredicate will delete a bucket from the range and predicate.
 func (t *TemporaryEngine) DeleteBucketRangePredicate(ctx context.Context, orgID, bucketID influxdb.ID, min, max int64, pred influxdb.Predicate) error {
 	return t.engine.DeleteBucketRangePredicate(ctx, orgID, bucketID, min, max, pred)
@@ -178,3 +208,35 @@ func (t *TemporaryEngine) FetchBackupFile(ctx context.Context, backupID int, bac
 func (t *TemporaryEngine) InternalBackupPath(backupID int) string {
 	return t.engine.InternalBackupPath(backupID)
 }
+
+func (t *TemporaryEngine) Defragment(ctx context.Context, orgID, bucketID influxdb.ID) error {
+	return t.engine.Defragment(ctx, orgID, bucketID)
+}
+
+func (t *TemporaryEngine) RecoveryReport(ctx context.Context) (*influxdb.RecoveryReport, error) {
+	return t.engine.RecoveryReport(ctx)
+}
+
+func (t *TemporaryEngine) EngineStats(ctx context.Context) (*influxdb.EngineStats, error) {
+	return t.engine.EngineStats(ctx)
+}
+
+// BucketSchema returns the measurements stored in the bucket, with the types
+// of their fields and their tag keys.
+func (t *TemporaryEngine) BucketSchema(ctx context.Context, orgID, bucketID influxdb.ID) ([]influxdb.BucketSchemaMeasurement, error) {
+	return t.engine.BucketSchema(ctx, orgID, bucketID)
+}
+
+// TagValueHistogram returns the topN values of the tag key of a measurement
+// stored in the bucket, with the number of series having each.
+func (t *TemporaryEngine) TagValueHistogram(ctx context.Context, orgID, bucketID influxdb.ID, measurement, tagKey string, start, end int64, topN int) ([]influxdb.TagValueCount, error) {
+	return t.engine.TagValueHistogram(ctx, orgID, bucketID, measurement, tagKey, start, end, topN)
+}
+
+func (t *TemporaryEngine) Ready(ctx context.Context) error {
+	return t.engine.Ready(ctx)
+}
+
+func (t *TemporaryEngine) FlushCache(ctx context.Context) error {
+	return t.engine.FlushCache(ctx)
+}
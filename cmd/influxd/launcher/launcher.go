@@ -1,8 +1,10 @@
 package launcher
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +14,7 @@ import (
 	_ "net/http/pprof" // needed to add pprof to our binary.
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +24,7 @@ import (
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/chronograf/server"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
+	"github.com/influxdata/influxdb/cmd/influxd/kafka"
 	"github.com/influxdata/influxdb/endpoints"
 	"github.com/influxdata/influxdb/gather"
 	"github.com/influxdata/influxdb/http"
@@ -37,6 +41,7 @@ import (
 	"github.com/influxdata/influxdb/pkger"
 	infprom "github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/query"
+	querycache "github.com/influxdata/influxdb/query/cache"
 	"github.com/influxdata/influxdb/query/control"
 	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
 	"github.com/influxdata/influxdb/snowflake"
@@ -51,13 +56,15 @@ import (
 	"github.com/influxdata/influxdb/task/backend/scheduler"
 	"github.com/influxdata/influxdb/telemetry"
 	"github.com/influxdata/influxdb/tenant"
+	"github.com/influxdata/influxdb/toml"
 	_ "github.com/influxdata/influxdb/tsdb/tsi1" // needed for tsi1
-	_ "github.com/influxdata/influxdb/tsdb/tsm1" // needed for tsm1
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/influxdata/influxdb/vault"
 	pzap "github.com/influxdata/influxdb/zap"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	jaegerconfig "github.com/uber/jaeger-client-go/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -140,12 +147,41 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Default: "",
 			Desc:    fmt.Sprintf("supported tracing types are %s, %s", LogTracing, JaegerTracing),
 		},
+		{
+			DestP:   &l.otelExporterEndpoint,
+			Flag:    "otel-exporter-endpoint",
+			Default: "",
+			Desc:    "URL of an OpenTelemetry collector to export traces to, such as http://localhost:55680; overrides tracing-type",
+		},
 		{
 			DestP:   &l.httpBindAddress,
 			Flag:    "http-bind-address",
 			Default: ":9999",
 			Desc:    "bind address for the REST HTTP API",
 		},
+		{
+			DestP:   &l.corsAllowOrigins,
+			Flag:    "http-cors-allow-origins",
+			Default: []string{},
+			Desc:    "comma-separated origins allowed to make cross-origin requests to the HTTP API, or \"*\" for any origin; preflight requests from these origins are answered with the CORS headers",
+		},
+		{
+			DestP:   &l.corsAllowHeaders,
+			Flag:    "http-cors-allow-headers",
+			Default: []string{},
+			Desc:    "comma-separated request headers allowed in cross-origin requests from http-cors-allow-origins; defaults to Accept, Content-Type, Content-Length, Accept-Encoding and Authorization",
+		},
+		{
+			DestP: &l.metricsToken,
+			Flag:  "metrics-token",
+			Desc:  "token required to scrape the /metrics endpoint; metrics are not authenticated if not set",
+		},
+		{
+			DestP:   &l.metricsMeasurementAllowlist,
+			Flag:    "metrics-measurement-allowlist",
+			Default: []string{},
+			Desc:    "comma-separated measurements whose writes and reads are reported by their own metrics; the activity of other measurements is reported under the _other measurement label",
+		},
 		{
 			DestP:   &l.boltPath,
 			Flag:    "bolt-path",
@@ -175,6 +211,228 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Default: filepath.Join(dir, "engine"),
 			Desc:    "path to persistent engine files",
 		},
+		{
+			DestP:   &l.maxCacheBytes,
+			Flag:    "storage-cache-max-memory-size",
+			Default: int(tsm1.DefaultCacheMaxMemorySize),
+			Desc:    "maximum number of bytes the storage engine's cache may use before it rejects writes",
+		},
+		{
+			DestP:   &l.cacheWarmupDuration,
+			Flag:    "storage-cache-warmup-duration",
+			Default: time.Duration(0),
+			Desc:    "load the data written within this duration into the storage engine's cache in the background at startup; 0 disables the warmup",
+		},
+		{
+			DestP:   &l.writeMaxBodySize,
+			Flag:    "storage-write-max-body-size",
+			Default: 25 << 20,
+			Desc:    "maximum number of bytes of the body of a write request, before decompression; larger requests are rejected with 413 Request Entity Too Large. 0 is unlimited",
+		},
+		{
+			DestP:   &l.maxCacheBytesPerOrg,
+			Flag:    "storage-max-cache-bytes-per-org",
+			Default: 0,
+			Desc:    "maximum number of bytes the writes to a single bucket of an organization may use in the storage engine's cache before they are written to disk; 0 disables the limit",
+		},
+		{
+			DestP:   &l.walMaxDiskBytes,
+			Flag:    "storage-wal-max-disk-bytes",
+			Default: 0,
+			Desc:    "maximum number of bytes of WAL segments on disk before the storage engine's cache is flushed to TSM files to remove them; 0 disables the limit",
+		},
+		{
+			DestP:   &l.retentionCheckInterval,
+			Flag:    "storage-retention-check-interval",
+			Default: storage.DefaultRetentionInterval,
+			Desc:    "interval at which the storage engine deletes data outside the retention period of each bucket; 0 disables retention",
+		},
+		{
+			DestP:   &l.strictSchema,
+			Flag:    "storage-strict-schema",
+			Default: false,
+			Desc:    "reject writes to measurements without a registered schema; writes to measurements with a schema are always validated against it",
+		},
+		{
+			DestP:   &l.compactionStrategy,
+			Flag:    "storage-compaction-strategy",
+			Default: tsm1.DefaultCompactionStrategyName,
+			Desc:    "strategy that plans which TSM files are compacted together: default or leveled",
+		},
+		{
+			DestP:   &l.recoveryMode,
+			Flag:    "storage-recovery-mode",
+			Default: false,
+			Desc:    "start the storage engine despite corrupt TSM files, skipping them; the skipped files are listed at /api/v2/engine/recovery-report",
+		},
+		{
+			DestP:   &l.encryptionKeyHex,
+			Flag:    "storage-encryption-key-hex",
+			Default: "",
+			Desc:    "64 hex character master key used to encrypt TSM files at rest with AES-256-GCM; files are not encrypted if empty",
+		},
+		{
+			DestP:   &l.tierHotPath,
+			Flag:    "storage-tier-hot-path",
+			Default: "",
+			Desc:    "path to the TSM files of data written within the last hour; defaults to the data directory of the engine path",
+		},
+		{
+			DestP:   &l.tierWarmPath,
+			Flag:    "storage-tier-warm-path",
+			Default: "",
+			Desc:    "path TSM files are moved to once their data is older than an hour; defaults to the hot tier path",
+		},
+		{
+			DestP:   &l.tierColdPath,
+			Flag:    "storage-tier-cold-path",
+			Default: "",
+			Desc:    "path TSM files are moved to once their data is older than a day; defaults to the warm tier path",
+		},
+		{
+			DestP:   &l.measurementPartition,
+			Flag:    "storage-measurement-partition",
+			Default: false,
+			Desc:    "write the data of each measurement of a bucket to TSM files in a subdirectory of its own, which are compacted separately from those of other measurements; cannot be combined with storage-encryption-key-hex",
+		},
+		{
+			DestP:   &l.queryMaxBodySize,
+			Flag:    "query-max-body-size",
+			Default: 10 << 20,
+			Desc:    "maximum number of bytes of the body of a query request; larger requests are rejected with 413 Request Entity Too Large. 0 is unlimited",
+		},
+		{
+			DestP:   &l.queryConcurrency,
+			Flag:    "query-concurrency",
+			Default: 10,
+			Desc:    "number of queries allowed to execute concurrently",
+		},
+		{
+			DestP:   &l.queryQueueSize,
+			Flag:    "query-queue-size",
+			Default: 10,
+			Desc:    "number of queries allowed to await execution before new queries are rejected",
+		},
+		{
+			DestP:   &l.queryMemoryBytes,
+			Flag:    "query-memory-bytes",
+			Default: 0,
+			Desc:    "maximum number of bytes a query is allowed to use at any given time. 0 is unlimited",
+		},
+		{
+			DestP:   &l.queryInitialMemoryBytes,
+			Flag:    "query-initial-memory-bytes",
+			Default: 0,
+			Desc:    "number of bytes allocated to a query when it starts; defaults to query-memory-bytes",
+		},
+		{
+			DestP:   &l.queryMaxMemoryBytes,
+			Flag:    "query-max-memory-bytes",
+			Default: 0,
+			Desc:    "maximum number of bytes allocated to all queries; must be at least query-concurrency * query-initial-memory-bytes. 0 is query-concurrency * query-memory-bytes",
+		},
+		{
+			DestP:   &l.queryResultCache.TTL,
+			Flag:    "query-result-cache-ttl",
+			Default: time.Duration(0),
+			Desc:    "time for which the results of identical queries in an organization are cached; results are invalidated when a bucket they read is written to. 0 disables the cache",
+		},
+		{
+			DestP:   &l.queryResultCache.MaxBytes,
+			Flag:    "query-result-cache-max-bytes",
+			Default: querycache.DefaultMaxBytes,
+			Desc:    "maximum number of bytes of query results held by the query result cache",
+		},
+		{
+			DestP:   &l.prometheusDefaultBucket,
+			Flag:    "prometheus-default-bucket",
+			Default: "",
+			Desc:    "bucket written to by Prometheus remote write requests to /api/v2/prometheus/write that do not specify a bucket",
+		},
+		{
+			DestP:   &l.influxqlBucketMapping,
+			Flag:    "influxql-db-bucket",
+			Default: []string{},
+			Desc:    "db=bucket mapping of an InfluxQL database to the bucket queried by the 1.x /query API; may be given more than once. Databases without a mapping query the bucket of the same name",
+		},
+		{
+			DestP:   &l.otlpReceiverEnabled,
+			Flag:    "otlp-receiver-enabled",
+			Default: false,
+			Desc:    "enable the OpenTelemetry metrics receiver at /v1/metrics",
+		},
+		{
+			DestP:   &l.kafkaConfig.Brokers,
+			Flag:    "kafka-brokers",
+			Default: []string{},
+			Desc:    "addresses of the Kafka brokers to consume messages from and produce messages to; setting this enables the Kafka connector",
+		},
+		{
+			DestP:   &l.kafkaConfig.Topic,
+			Flag:    "kafka-topic",
+			Default: "",
+			Desc:    "Kafka topic consumed by the Kafka connector",
+		},
+		{
+			DestP:   &l.kafkaConfig.ConsumerGroup,
+			Flag:    "kafka-consumer-group",
+			Default: "influxdb",
+			Desc:    "Kafka consumer group of the Kafka connector",
+		},
+		{
+			DestP:   &l.kafkaConfig.Org,
+			Flag:    "kafka-dest-org",
+			Default: "",
+			Desc:    "name or ID of the organization the Kafka connector writes to",
+		},
+		{
+			DestP:   &l.kafkaConfig.Bucket,
+			Flag:    "kafka-dest-bucket",
+			Default: "",
+			Desc:    "name or ID of the bucket the Kafka connector writes to",
+		},
+		{
+			DestP:   &l.kafkaConfig.DLQTopic,
+			Flag:    "kafka-dlq-topic",
+			Default: "",
+			Desc:    "Kafka topic messages that cannot be parsed or whose points are rejected are written to; if empty, such messages are dropped",
+		},
+		{
+			DestP:   &l.kafkaConfig.Format,
+			Flag:    "kafka-format",
+			Default: kafka.FormatLineProtocol,
+			Desc:    "format of the messages consumed by the Kafka connector: line-protocol or json",
+		},
+		{
+			DestP:   &l.kafkaConfig.JSON.MeasurementKey,
+			Flag:    "kafka-json-measurement",
+			Default: "",
+			Desc:    "property of JSON messages holding the measurement name",
+		},
+		{
+			DestP:   &l.kafkaConfig.JSON.TagKeys,
+			Flag:    "kafka-json-tags",
+			Default: []string{},
+			Desc:    "properties of JSON messages written as tags",
+		},
+		{
+			DestP:   &l.kafkaConfig.JSON.FieldKeys,
+			Flag:    "kafka-json-fields",
+			Default: []string{},
+			Desc:    "properties of JSON messages written as fields; if empty, all other properties are fields",
+		},
+		{
+			DestP:   &l.kafkaConfig.JSON.TimeKey,
+			Flag:    "kafka-json-time",
+			Default: "",
+			Desc:    "property of JSON messages holding the time as RFC3339 or nanoseconds since the epoch",
+		},
+		{
+			DestP:   &l.kafkaConfig.ProducerTopic,
+			Flag:    "kafka-producer-topic",
+			Default: "",
+			Desc:    "Kafka topic the points written to the storage engine are written to as line protocol; if only this topic is set, no messages are consumed",
+		},
 		{
 			DestP:   &l.secretStore,
 			Flag:    "secret-store",
@@ -279,10 +537,47 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Default: false,
 			Desc:    "disables the task scheduler",
 		},
+		{
+			DestP: &l.oidcConfig.IssuerURL,
+			Flag:  "oidc-issuer-url",
+			Desc:  "URL of the OpenID Connect provider; enables the /oauth2 authorization code flow endpoints",
+		},
+		{
+			DestP: &l.oidcConfig.ClientID,
+			Flag:  "oidc-client-id",
+			Desc:  "client id registered with the OpenID Connect provider",
+		},
+		{
+			DestP: &l.oidcConfig.ClientSecret,
+			Flag:  "oidc-client-secret",
+			Desc:  "client secret registered with the OpenID Connect provider",
+		},
+		{
+			DestP: &l.oidcConfig.RedirectURL,
+			Flag:  "oidc-redirect-url",
+			Desc:  "URL of this server's /oauth2/callback endpoint registered with the OpenID Connect provider",
+		},
+		{
+			DestP: &l.oidcConfig.DefaultOrg,
+			Flag:  "oidc-default-org",
+			Desc:  "name of the organization users logging in with OpenID Connect for the first time are added to as members",
+		},
+		{
+			DestP:   &l.oidcConfig.SessionLength,
+			Flag:    "oidc-session-length",
+			Default: http.DefaultOIDCSessionLength,
+			Desc:    "how long the authorization issued by an OpenID Connect login can be used",
+		},
+		{
+			DestP: &l.scopeMappingPath,
+			Flag:  "scope-mapping-path",
+			Desc:  "path to a JSON file mapping OAuth 2.0 scopes to the permissions they grant; replaces the default read:<type> and write:<type> scopes",
+		},
 	}
 
 	cli.BindOptions(cmd, opts)
 	cmd.AddCommand(inspect.NewCommand())
+	l.flags = cmd.Flags()
 }
 
 // Launcher represents the main program execution.
@@ -297,18 +592,61 @@ type Launcher struct {
 	sessionLength        int // in minutes
 	sessionRenewDisabled bool
 
-	logLevel          string
-	tracingType       string
-	reportingDisabled bool
-
-	httpBindAddress string
-	boltPath        string
-	enginePath      string
-	secretStore     string
+	flags                *pflag.FlagSet
+	logLevel             string
+	tracingType          string
+	otelExporterEndpoint string
+	reportingDisabled    bool
+
+	httpBindAddress  string
+	corsAllowOrigins []string
+	corsAllowHeaders []string
+	metricsToken     string
+	boltPath         string
+	enginePath       string
+	secretStore      string
+
+	maxCacheBytes       int
+	maxCacheBytesPerOrg int
+	cacheWarmupDuration time.Duration
+	writeMaxBodySize    int
+	walMaxDiskBytes     int
+
+	metricsMeasurementAllowlist []string
+
+	retentionCheckInterval time.Duration
+	strictSchema           bool
+
+	compactionStrategy string
+	recoveryMode       bool
+	encryptionKeyHex   string
+
+	tierHotPath  string
+	tierWarmPath string
+	tierColdPath string
+
+	measurementPartition bool
+
+	queryConcurrency        int
+	queryQueueSize          int
+	queryMemoryBytes        int
+	queryInitialMemoryBytes int
+	queryMaxMemoryBytes     int
+	queryMaxBodySize        int
+	queryResultCache        querycache.Config
+
+	prometheusDefaultBucket string
+	otlpReceiverEnabled     bool
+	influxqlBucketMapping   []string
+	kafkaConfig             kafka.Config
+	kafkaProducer           *kafka.Producer
 
 	enableNewMetaStore   bool
 	newMetaStoreReadOnly bool
 
+	oidcConfig       http.OIDCConfig
+	scopeMappingPath string
+
 	boltClient    *bolt.Client
 	kvStore       kv.Store
 	kvService     *kv.Service
@@ -330,9 +668,9 @@ type Launcher struct {
 	executor           *executor.Executor
 	taskControlService taskbackend.TaskControlService
 
-	jaegerTracerCloser io.Closer
-	log                *zap.Logger
-	reg                *prom.Registry
+	tracerCloser io.Closer
+	log          *zap.Logger
+	reg          *prom.Registry
 
 	Stdin      io.Reader
 	Stdout     io.Writer
@@ -417,17 +755,64 @@ func (m *Launcher) Shutdown(ctx context.Context) {
 		m.log.Error("Failed to close engine", zap.Error(err))
 	}
 
+	if m.kafkaProducer != nil {
+		m.log.Info("Stopping", zap.String("service", "kafka-producer"))
+		if err := m.kafkaProducer.Close(); err != nil {
+			m.log.Error("Failed to close Kafka producer", zap.Error(err))
+		}
+	}
+
 	m.wg.Wait()
 
-	if m.jaegerTracerCloser != nil {
-		if err := m.jaegerTracerCloser.Close(); err != nil {
-			m.log.Warn("Failed to closer Jaeger tracer", zap.Error(err))
+	if m.tracerCloser != nil {
+		if err := m.tracerCloser.Close(); err != nil {
+			m.log.Warn("Failed to close tracer", zap.Error(err))
 		}
 	}
 
 	m.log.Sync()
 }
 
+// configuredFlags returns the flags of fs not set to their default value, by
+// the command line or the environment, as a JSON object of their values. The
+// values of tokens, keys and secrets are redacted.
+func configuredFlags(fs *pflag.FlagSet) string {
+	flags := make(map[string]string)
+	if fs != nil {
+		fs.VisitAll(func(f *pflag.Flag) {
+			v := f.Value.String()
+			if v == f.DefValue {
+				return
+			}
+			if isSecretFlag(f.Name) {
+				v = redactedFlagValue
+			}
+			flags[f.Name] = v
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(flags); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactedFlagValue replaces the value of secret flags in logs.
+const redactedFlagValue = "<redacted>"
+
+// isSecretFlag reports whether the value of the flag named name is a secret.
+func isSecretFlag(name string) bool {
+	for _, s := range []string{"token", "key", "password"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, "-secret")
+}
+
 // Cancel executes the context cancel on the program. Used for testing.
 func (m *Launcher) Cancel() { m.cancel() }
 
@@ -474,15 +859,26 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		zap.String("version", info.Version),
 		zap.String("commit", info.Commit),
 		zap.String("build_date", info.Date),
+		zap.String("flags", configuredFlags(m.flags)),
 	)
 
-	switch m.tracingType {
-	case LogTracing:
+	switch {
+	case m.otelExporterEndpoint != "":
+		m.log.Info("Tracing via OpenTelemetry", zap.String("endpoint", m.otelExporterEndpoint))
+		tracer, closer, err := tracing.NewOTLPTracer(m.otelExporterEndpoint)
+		if err != nil {
+			m.log.Error("Failed to instantiate OpenTelemetry tracer", zap.Error(err))
+			break
+		}
+		opentracing.SetGlobalTracer(tracer)
+		m.tracerCloser = closer
+
+	case m.tracingType == LogTracing:
 		m.log.Info("Tracing via zap logging")
 		tracer := pzap.NewTracer(m.log, snowflake.NewIDGenerator())
 		opentracing.SetGlobalTracer(tracer)
 
-	case JaegerTracing:
+	case m.tracingType == JaegerTracing:
 		m.log.Info("Tracing via Jaeger")
 		cfg, err := jaegerconfig.FromEnv()
 		if err != nil {
@@ -495,7 +891,7 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 			break
 		}
 		opentracing.SetGlobalTracer(tracer)
-		m.jaegerTracerCloser = closer
+		m.tracerCloser = closer
 	}
 
 	m.boltClient = bolt.NewClient(m.log.With(zap.String("service", "bolt")))
@@ -620,13 +1016,60 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		return err
 	}
 
+	if m.maxCacheBytes > 0 {
+		m.StorageConfig.Engine.Cache.MaxMemorySize = toml.Size(m.maxCacheBytes)
+	}
+	if m.maxCacheBytesPerOrg > 0 {
+		m.StorageConfig.Engine.Cache.MaxMemorySizePerOrg = toml.Size(m.maxCacheBytesPerOrg)
+	}
+	if m.cacheWarmupDuration > 0 {
+		m.StorageConfig.Engine.Cache.WarmupDuration = toml.Duration(m.cacheWarmupDuration)
+	}
+	if m.walMaxDiskBytes > 0 {
+		m.StorageConfig.WAL.MaxDiskBytes = toml.Size(m.walMaxDiskBytes)
+	}
+	if len(m.metricsMeasurementAllowlist) > 0 {
+		m.StorageConfig.MetricsMeasurementAllowlist = m.metricsMeasurementAllowlist
+	}
+	m.StorageConfig.RetentionInterval = toml.Duration(m.retentionCheckInterval)
+	if _, err := tsm1.NewCompactionStrategy(m.compactionStrategy); err != nil {
+		m.log.Error("Invalid storage compaction strategy", zap.Error(err))
+		return err
+	}
+	m.StorageConfig.Engine.Compaction.Strategy = m.compactionStrategy
+	m.StorageConfig.Engine.RecoveryMode = m.recoveryMode
+	if _, err := tsm1.ParseEncryptionKey(m.encryptionKeyHex); err != nil {
+		m.log.Error("Invalid storage encryption key", zap.Error(err))
+		return err
+	}
+	m.StorageConfig.Engine.EncryptionKeyHex = m.encryptionKeyHex
+	if m.tierHotPath != "" {
+		m.StorageConfig.EnginePath = m.tierHotPath
+	}
+	m.StorageConfig.Engine.Tiers = tsm1.TierConfig{
+		WarmPath: m.tierWarmPath,
+		ColdPath: m.tierColdPath,
+	}
+	m.StorageConfig.Engine.MeasurementPartition = m.measurementPartition
+
+	// Points of measurements with a registered schema are validated against
+	// it when written.
+	schemaValidator := storage.NewSchemaValidator(m.kvService, m.strictSchema)
+	engineOptions := []storage.Option{storage.WithWriteValidator(schemaValidator), storage.WithRetentionEnforcer(bucketSvc)}
+
+	// The points written to the engine are streamed to Kafka.
+	if m.kafkaConfig.ProducerEnabled() {
+		m.kafkaProducer = kafka.NewKafkaProducer(m.kafkaConfig)
+		engineOptions = append(engineOptions, storage.WithWriteSideEffect("kafka-producer", m.kafkaProducer.WritePoints))
+	}
+
 	if m.testing {
 		// the testing engine will write/read into a temporary directory
-		engine := NewTemporaryEngine(m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+		engine := NewTemporaryEngine(m.StorageConfig, engineOptions...)
 		flushers = append(flushers, engine)
 		m.engine = engine
 	} else {
-		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, engineOptions...)
 	}
 	m.engine.WithLogger(m.log)
 	if err := m.engine.Open(ctx); err != nil {
@@ -644,15 +1087,27 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 
 	// TODO(cwolff): Figure out a good default per-query memory limit:
 	//   https://github.com/influxdata/influxdb/issues/13642
-	const (
-		concurrencyQuota         = 10
+	memoryBytesQuotaPerQuery := int64(m.queryMemoryBytes)
+	if memoryBytesQuotaPerQuery == 0 {
 		memoryBytesQuotaPerQuery = math.MaxInt64
-		QueueSize                = 10
+	}
+
+	var (
+		storageReader = storageflux.NewReader(readservice.NewStore(m.engine))
+		resultCache   *querycache.Cache
 	)
+	if m.queryResultCache.Enabled() {
+		resultCache = querycache.New(m.queryResultCache)
+		m.reg.MustRegister(resultCache.PrometheusCollectors()...)
+		storageReader = querycache.NewReader(storageReader)
+		pointsWriter = resultCache.PointsWriter(pointsWriter)
+		deleteService = resultCache.DeleteService(deleteService)
+		secretSvc = resultCache.SecretService(secretSvc)
+	}
 
 	deps, err := influxdb.NewDependencies(
-		storageflux.NewReader(readservice.NewStore(m.engine)),
-		m.engine,
+		storageReader,
+		pointsWriter,
 		authorizer.NewBucketService(bucketSvc, userResourceSvc),
 		authorizer.NewOrgService(orgSvc),
 		authorizer.NewSecretService(secretSvc),
@@ -664,11 +1119,13 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	}
 
 	m.queryController, err = control.New(control.Config{
-		ConcurrencyQuota:         concurrencyQuota,
-		MemoryBytesQuotaPerQuery: int64(memoryBytesQuotaPerQuery),
-		QueueSize:                QueueSize,
-		Logger:                   m.log.With(zap.String("service", "storage-reads")),
-		ExecutorDependencies:     []flux.Dependency{deps},
+		ConcurrencyQuota:                m.queryConcurrency,
+		InitialMemoryBytesQuotaPerQuery: int64(m.queryInitialMemoryBytes),
+		MemoryBytesQuotaPerQuery:        memoryBytesQuotaPerQuery,
+		MaxMemoryBytes:                  int64(m.queryMaxMemoryBytes),
+		QueueSize:                       m.queryQueueSize,
+		Logger:                          m.log.With(zap.String("service", "storage-reads")),
+		ExecutorDependencies:            []flux.Dependency{deps},
 	})
 	if err != nil {
 		m.log.Error("Failed to create query controller", zap.Error(err))
@@ -678,6 +1135,9 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	m.reg.MustRegister(m.queryController.PrometheusCollectors()...)
 
 	var storageQueryService = readservice.NewProxyQueryService(m.queryController)
+	if resultCache != nil {
+		storageQueryService = resultCache.ProxyQueryService(storageQueryService)
+	}
 	var taskSvc platform.TaskService
 	{
 		// create the task stack
@@ -827,10 +1287,47 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		log.Info("Stopping")
 	}(m.log)
 
+	if m.kafkaConfig.ConsumerEnabled() {
+		if err := m.kafkaConfig.Validate(); err != nil {
+			m.log.Error("Invalid Kafka connector configuration", zap.Error(err))
+			return err
+		}
+		kafkaService := kafka.NewService(m.kafkaConfig, orgSvc, bucketSvc, pointsWriter)
+		kafkaService.WithLogger(m.log)
+
+		m.wg.Add(1)
+		go func(log *zap.Logger) {
+			defer m.wg.Done()
+			log = log.With(zap.String("service", "kafka-consumer"))
+			if err := kafkaService.Run(ctx); err != nil {
+				log.Error("Failed Kafka connector", zap.Error(err))
+			}
+			log.Info("Stopping")
+		}(m.log)
+	}
+
+	// The bytes written by each authorization are persisted every minute, so
+	// that MaxBytesPerDay is enforced across restarts.
+	writeQuota := http.NewWriteQuota(nil,
+		http.WithWriteUsageService(m.kvService),
+		http.WithWriteQuotaLogger(m.log.With(zap.String("service", "write-quota"))),
+	)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		writeQuota.Run(ctx, http.DefaultWriteQuotaFlushInterval)
+	}()
+
 	m.httpServer = &nethttp.Server{
 		Addr: m.httpBindAddress,
 	}
 
+	influxqlBucketMapping, err := parseInfluxQLBucketMapping(m.influxqlBucketMapping)
+	if err != nil {
+		m.log.Error("Failed to parse InfluxQL bucket mapping", zap.Error(err))
+		return err
+	}
+
 	m.apibackend = &http.APIBackend{
 		AssetsPath:           m.assetsPath,
 		HTTPErrorHandler:     kithttp.ErrorHandler(0),
@@ -840,6 +1337,12 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		NewQueryService:      source.NewQueryService,
 		PointsWriter:         pointsWriter,
 		DeleteService:        deleteService,
+		DefragmentService:    m.engine,
+		RecoveryService:      m.engine,
+		ReadinessService:     m.engine,
+		EngineStatsService:   m.engine,
+		SchemaService:        m.kvService,
+		BucketSchemaService:  m.engine,
 		BackupService:        backupService,
 		KVBackupService:      m.kvService,
 		AuthorizationService: authSvc,
@@ -849,10 +1352,15 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		UserService:                     userSvc,
 		OrganizationService:             orgSvc,
 		UserResourceMappingService:      userResourceSvc,
+		GroupService:                    m.kvService,
+		OIDCIdentityService:             m.kvService,
 		LabelService:                    labelSvc,
 		DashboardService:                dashboardSvc,
 		DashboardOperationLogService:    dashboardLogSvc,
 		BucketOperationLogService:       bucketLogSvc,
+		BucketCardinalityService:        m.engine,
+		BucketSizeService:               m.engine,
+		BucketMetadataService:           m.engine,
 		UserOperationLogService:         userLogSvc,
 		OrganizationOperationLogService: orgLogSvc,
 		SourceService:                   sourceSvc,
@@ -874,6 +1382,28 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		OrgLookupService:                m.kvService,
 		WriteEventRecorder:              infprom.NewEventRecorder("write"),
 		QueryEventRecorder:              infprom.NewEventRecorder("query"),
+		WriteMaxBodySize:                int64(m.writeMaxBodySize),
+		QueryMaxBodySize:                int64(m.queryMaxBodySize),
+		PrometheusDefaultBucket:         m.prometheusDefaultBucket,
+		WriteQuota:                      writeQuota,
+		OTLPReceiverEnabled:             m.otlpReceiverEnabled,
+		InfluxQLBucketMapping:           influxqlBucketMapping,
+		CORSAllowOrigins:                m.corsAllowOrigins,
+		CORSAllowHeaders:                m.corsAllowHeaders,
+	}
+
+	if m.oidcConfig.IssuerURL != "" {
+		oidcConfig := m.oidcConfig
+		m.apibackend.OIDCConfig = &oidcConfig
+	}
+
+	if m.scopeMappingPath != "" {
+		scopes, err := loadScopeMapping(m.scopeMappingPath)
+		if err != nil {
+			m.log.Error("Failed to load scope mapping", zap.String("path", m.scopeMappingPath), zap.Error(err))
+			return err
+		}
+		m.apibackend.ScopeMapping = scopes
 	}
 
 	m.reg.MustRegister(m.apibackend.PrometheusCollectors()...)
@@ -917,12 +1447,15 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		platformHandler := http.NewPlatformHandler(m.apibackend, http.WithResourceHandler(pkgHTTPServer))
 
 		httpLogger := m.log.With(zap.String("service", "http"))
-		m.httpServer.Handler = http.NewHandlerFromRegistry(
-			"platform",
-			m.reg,
+		handlerOpts := []http.HandlerOptFn{
 			http.WithLog(httpLogger),
 			http.WithAPIHandler(platformHandler),
-		)
+		}
+		if m.metricsToken != "" {
+			handlerOpts = append(handlerOpts,
+				http.WithMetricsHandler(http.MetricsTokenHandler(m.reg.HTTPHandler(), m.metricsToken, m.apibackend.HTTPErrorHandler)))
+		}
+		m.httpServer.Handler = http.NewHandlerFromRegistry("platform", m.reg, handlerOpts...)
 
 		if logconf.Level == zap.DebugLevel {
 			m.httpServer.Handler = http.LoggingMW(httpLogger)(m.httpServer.Handler)
@@ -1013,6 +1546,35 @@ func (m *Launcher) UserService() platform.UserService {
 	return m.apibackend.UserService
 }
 
+// loadScopeMapping reads a scope to permissions mapping from the JSON file at path.
+func loadScopeMapping(path string) (platform.ScopeMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var scopes platform.ScopeMapping
+	if err := json.NewDecoder(f).Decode(&scopes); err != nil {
+		return nil, fmt.Errorf("decoding scope mapping: %v", err)
+	}
+	return scopes, nil
+}
+
+// parseInfluxQLBucketMapping parses the db=bucket entries mapping InfluxQL
+// databases to buckets.
+func parseInfluxQLBucketMapping(entries []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		i := strings.IndexByte(entry, '=')
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("invalid InfluxQL bucket mapping %q: expected db=bucket", entry)
+		}
+		mapping[entry[:i]] = entry[i+1:]
+	}
+	return mapping, nil
+}
+
 // UserResourceMappingService returns the internal user resource mapping service.
 func (m *Launcher) UserResourceMappingService() platform.UserResourceMappingService {
 	return m.apibackend.UserResourceMappingService
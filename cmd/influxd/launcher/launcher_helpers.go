@@ -3,10 +3,12 @@ package launcher
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	nethttp "net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -208,6 +210,50 @@ func (tl *TestLauncher) WritePointsOrFail(tb testing.TB, data string) {
 	}
 }
 
+// GetSchemaOrFail returns the names of the measurements of the bucket used
+// during setup, or fails if there is an error.
+func (tl *TestLauncher) GetSchemaOrFail(tb testing.TB) []string {
+	tb.Helper()
+	var resp struct {
+		Measurements []string `json:"measurements"`
+	}
+	tl.getJSONOrFail(tb, fmt.Sprintf("/api/v2/schema/buckets/%s/measurements", tl.Bucket.ID), &resp)
+	return resp.Measurements
+}
+
+// GetTagKeysOrFail returns the tag keys of the measurement of the bucket used
+// during setup, or fails if there is an error.
+func (tl *TestLauncher) GetTagKeysOrFail(tb testing.TB, measurement string) []string {
+	tb.Helper()
+	var resp struct {
+		TagKeys []string `json:"tagKeys"`
+	}
+	tl.getJSONOrFail(tb, fmt.Sprintf("/api/v2/schema/buckets/%s/measurements/%s/tags", tl.Bucket.ID, url.PathEscape(measurement)), &resp)
+	return resp.TagKeys
+}
+
+// getJSONOrFail decodes the JSON body of a GET request of rawurl, made with the
+// token of the authorization used during setup, into v.
+func (tl *TestLauncher) getJSONOrFail(tb testing.TB, rawurl string, v interface{}) {
+	tb.Helper()
+	resp, err := nethttp.DefaultClient.Do(tl.NewHTTPRequestOrFail(tb, "GET", rawurl, tl.Auth.Token, ""))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		tb.Fatalf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		tb.Fatalf("failed to decode response: %v, body: %s", err, body)
+	}
+}
+
 // MustExecuteQuery executes the provided query panicking if an error is encountered.
 // Callers of MustExecuteQuery must call Done on the returned QueryResults.
 func (tl *TestLauncher) MustExecuteQuery(query string) *QueryResults {
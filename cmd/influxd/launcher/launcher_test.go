@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	nethttp "net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	platform "github.com/influxdata/influxdb"
@@ -16,6 +19,349 @@ import (
 // Default context.
 var ctx = context.Background()
 
+func TestLauncher_StartupFlags(t *testing.T) {
+	l := launcher.NewTestLauncher()
+	if err := l.Run(ctx, "--vault-token", "s3cret", "--reporting-disabled"); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Shutdown(ctx)
+
+	var entry string
+	for _, line := range strings.Split(l.Stdout.String(), "\n") {
+		if strings.Contains(line, `msg="Welcome to InfluxDB"`) {
+			entry = line
+			break
+		}
+	}
+	if entry == "" {
+		t.Fatalf("no startup entry in log:\n%s", l.Stdout.String())
+	}
+	for _, field := range []string{"version=", "commit=", "build_date=", "flags="} {
+		if !strings.Contains(entry, field) {
+			t.Errorf("startup entry has no %s field: %s", strings.TrimSuffix(field, "="), entry)
+		}
+	}
+	if strings.Contains(entry, "s3cret") {
+		t.Errorf("startup entry contains secret: %s", entry)
+	}
+
+	m := regexp.MustCompile(`flags=("(?:[^"\\]|\\.)*")`).FindStringSubmatch(entry)
+	if m == nil {
+		t.Fatalf("startup entry has no quoted flags: %s", entry)
+	}
+	s, err := strconv.Unquote(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flags map[string]string
+	if err := json.Unmarshal([]byte(s), &flags); err != nil {
+		t.Fatalf("flags are not a JSON object: %v: %s", err, s)
+	}
+	for name, exp := range map[string]string{
+		"vault-token":        "<redacted>",
+		"reporting-disabled": "true",
+		"log-level":          "debug",
+		"http-bind-address":  "127.0.0.1:0",
+	} {
+		if got := flags[name]; got != exp {
+			t.Errorf("unexpected value of flag %s: got %q, exp %q", name, got, exp)
+		}
+	}
+	if _, ok := flags["store"]; ok {
+		t.Errorf("flag with default value store logged: %v", flags)
+	}
+}
+
+func TestLauncher_MetricsToken(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--metrics-token", "scraper-token")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// The unused memory of an organization is recorded when its queries finish.
+	l.FluxQueryOrFail(t, l.Org, l.Auth.Token, `from(bucket: "BUCKET") |> range(start: -1h)`)
+
+	for _, test := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "metrics token", token: "scraper-token", status: nethttp.StatusOK},
+		{name: "API token", token: l.Auth.Token, status: nethttp.StatusUnauthorized},
+		{name: "no token", status: nethttp.StatusUnauthorized},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := nethttp.NewRequest(nethttp.MethodGet, l.URL()+"/metrics", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.token != "" {
+				http.SetToken(test.token, req)
+			}
+			resp, err := nethttp.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("unexpected status code: got %d, exp %d: %s", resp.StatusCode, test.status, body)
+			}
+			if exp := "query_control_memory_unused_bytes"; test.status == nethttp.StatusOK && !strings.Contains(string(body), exp) {
+				t.Errorf("metrics do not contain %s:\n%s", exp, body)
+			}
+		})
+	}
+}
+
+func TestLauncher_Ready(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// Readiness probes are not authenticated.
+	resp, err := nethttp.Get(l.URL() + "/api/v2/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var ready struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ready); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusOK || ready.Status != "ready" {
+		t.Fatalf("unexpected readiness: got %d %q, exp %d %q", resp.StatusCode, ready.Status, nethttp.StatusOK, "ready")
+	}
+}
+
+func TestLauncher_MaxBodySize(t *testing.T) {
+	const limit = 64
+	l := launcher.RunTestLauncherOrFail(t, ctx,
+		"--storage-write-max-body-size", strconv.Itoa(limit),
+		"--query-max-body-size", strconv.Itoa(limit),
+	)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// pad returns s padded with spaces to n bytes.
+	pad := func(s string, n int) string {
+		return s + strings.Repeat(" ", n-len(s))
+	}
+	writePath := "/api/v2/write?org=" + l.Org.ID.String() + "&bucket=" + l.Bucket.ID.String()
+	queryPath := "/api/v2/query?orgID=" + l.Org.ID.String()
+	query := `{"query": "from(bucket: \"BUCKET\") |> range(start: -1h)"}`
+
+	for _, test := range []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "write at the limit", path: writePath, body: pad("cpu value=1", limit), status: nethttp.StatusNoContent},
+		{name: "write over the limit", path: writePath, body: pad("cpu value=1", limit+1), status: nethttp.StatusRequestEntityTooLarge},
+		{name: "query at the limit", path: queryPath, body: pad(query, limit), status: nethttp.StatusOK},
+		{name: "query over the limit", path: queryPath, body: pad(query, limit+1), status: nethttp.StatusRequestEntityTooLarge},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := l.NewHTTPRequestOrFail(t, nethttp.MethodPost, test.path, l.Auth.Token, test.body)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := nethttp.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("unexpected status code: got %d, exp %d: %s", resp.StatusCode, test.status, body)
+			}
+			if exp := "limit of 64 bytes"; test.status == nethttp.StatusRequestEntityTooLarge && !strings.Contains(string(body), exp) {
+				t.Errorf("error does not explain the limit: %s", body)
+			}
+		})
+	}
+}
+
+func TestLauncher_CORS(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx,
+		"--http-cors-allow-origins", "http://myapp.com,http://anotherapp.com",
+		"--http-cors-allow-headers", "Authorization,Content-Type",
+	)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	do := func(req *nethttp.Request) *nethttp.Response {
+		t.Helper()
+
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Preflight requests are not authenticated.
+	preflight, err := nethttp.NewRequest(nethttp.MethodOptions, l.URL()+"/api/v2/query", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preflight.Header.Set("Origin", "http://anotherapp.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	preflight.Header.Set("Access-Control-Request-Headers", "Authorization")
+	resp := do(preflight)
+	if resp.StatusCode != nethttp.StatusNoContent {
+		t.Fatalf("unexpected preflight status code: got %d, exp %d", resp.StatusCode, nethttp.StatusNoContent)
+	}
+	for k, exp := range map[string]string{
+		"Access-Control-Allow-Origin":  "http://anotherapp.com",
+		"Access-Control-Allow-Methods": "POST, GET, OPTIONS, PUT, DELETE",
+		"Access-Control-Allow-Headers": "Authorization, Content-Type",
+	} {
+		if got := resp.Header.Get(k); got != exp {
+			t.Errorf("unexpected preflight %s header: got %q, exp %q", k, got, exp)
+		}
+	}
+
+	for _, test := range []struct {
+		origin string
+		allow  string
+	}{
+		{origin: "http://myapp.com", allow: "http://myapp.com"},
+		{origin: "http://evil.com"},
+	} {
+		req := l.NewHTTPRequestOrFail(t, nethttp.MethodGet, "/api/v2/buckets", l.Auth.Token, "")
+		req.Header.Set("Origin", test.origin)
+		resp := do(req)
+		if resp.StatusCode != nethttp.StatusOK {
+			t.Fatalf("unexpected status code from %s: got %d, exp %d", test.origin, resp.StatusCode, nethttp.StatusOK)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != test.allow {
+			t.Errorf("unexpected Access-Control-Allow-Origin header from %s: got %q, exp %q", test.origin, got, test.allow)
+		}
+	}
+}
+
+func TestLauncher_BucketETag(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	path := "/api/v2/buckets/" + l.Bucket.ID.String()
+	do := func(method, body, ifMatch string) *nethttp.Response {
+		t.Helper()
+
+		req := l.NewHTTPRequestOrFail(t, method, path, l.Auth.Token, body)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(nethttp.MethodGet, "", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != nethttp.StatusOK || etag == "" {
+		t.Fatalf("unexpected response to get: status %d, ETag %q", resp.StatusCode, etag)
+	}
+
+	resp = do(nethttp.MethodPatch, `{"description":"first"}`, etag)
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("unexpected status code of update: got %d, exp %d", resp.StatusCode, nethttp.StatusOK)
+	}
+	if got := resp.Header.Get("ETag"); got == "" || got == etag {
+		t.Fatalf("unexpected ETag after update: got %q, old %q", got, etag)
+	}
+
+	// The bucket has changed since etag was read.
+	resp = do(nethttp.MethodPatch, `{"description":"second"}`, etag)
+	if resp.StatusCode != nethttp.StatusPreconditionFailed {
+		t.Fatalf("unexpected status code of stale update: got %d, exp %d", resp.StatusCode, nethttp.StatusPreconditionFailed)
+	}
+
+	resp = do(nethttp.MethodPatch, `{"description":"third"}`, "")
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("unexpected status code of unconditional update: got %d, exp %d", resp.StatusCode, nethttp.StatusOK)
+	}
+}
+
+func TestLauncher_WriteCreateBucket(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	write := func(query string) int {
+		t.Helper()
+
+		req := l.NewHTTPRequestOrFail(t, nethttp.MethodPost, "/api/v2/write?org="+l.Org.ID.String()+"&bucket=created"+query, l.Auth.Token, "m,k=v f=1i 946684800000000000")
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := write(""); code != nethttp.StatusNotFound {
+		t.Fatalf("unexpected status code of write to missing bucket: got %d, exp %d", code, nethttp.StatusNotFound)
+	}
+	if code := write("&create-bucket=true"); code != nethttp.StatusNoContent {
+		t.Fatalf("unexpected status code of write creating bucket: got %d, exp %d", code, nethttp.StatusNoContent)
+	}
+	// The bucket now exists, so the write proceeds normally.
+	if code := write("&create-bucket=true"); code != nethttp.StatusNoContent {
+		t.Fatalf("unexpected status code of write to created bucket: got %d, exp %d", code, nethttp.StatusNoContent)
+	}
+
+	name := "created"
+	b, err := l.BucketService(t).FindBucket(ctx, platform.BucketFilter{OrganizationID: &l.Org.ID, Name: &name})
+	if err != nil {
+		t.Fatalf("failed to find created bucket: %v", err)
+	}
+	if b.RetentionPeriod != platform.InfiniteRetention {
+		t.Errorf("unexpected retention period of created bucket: %v", b.RetentionPeriod)
+	}
+
+	res := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, `from(bucket: "created") |> range(start: 2000-01-01T00:00:00Z, stop: 2000-01-02T00:00:00Z)`)
+	if !strings.Contains(res, "2000-01-01T00:00:00Z,1,f,m,v") {
+		t.Errorf("unexpected query result from created bucket:\n%s", res)
+	}
+}
+
+func TestLauncher_WritePrecisionPath(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	req := l.NewHTTPRequestOrFail(t, nethttp.MethodPost, "/api/v2/write/s?org="+l.Org.ID.String()+"&bucket="+l.Bucket.ID.String(), l.Auth.Token, "m,k=v f=1i 946684800")
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusNoContent {
+		t.Fatalf("unexpected status code: got %d, exp %d", resp.StatusCode, nethttp.StatusNoContent)
+	}
+
+	res := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, `from(bucket: "BUCKET") |> range(start: 2000-01-01T00:00:00Z, stop: 2000-01-02T00:00:00Z)`)
+	if !strings.Contains(res, "2000-01-01T00:00:00Z,1,f,m,v") {
+		t.Errorf("unexpected query result:\n%s", res)
+	}
+}
+
 func TestLauncher_Setup(t *testing.T) {
 	l := launcher.NewTestLauncher()
 	if err := l.Run(ctx); err != nil {
@@ -45,6 +45,43 @@ func TestLauncher_Pkger(t *testing.T) {
 		assert.NotZero(t, newStack.CRUDLog)
 	})
 
+	t.Run("applying a package to a stack records its resources", func(t *testing.T) {
+		newStack, err := svc.InitStack(timedCtx(5*time.Second), l.User.ID, pkger.Stack{
+			OrgID: l.Org.ID,
+			Name:  "stack with resources",
+		})
+		require.NoError(t, err)
+
+		pkg, err := pkger.Parse(pkger.EncodingYAML, pkger.FromString(fmt.Sprintf(`
+apiVersion: %[1]s
+kind: Bucket
+metadata:
+  name: stack-bucket
+`, pkger.APIVersion)))
+		require.NoError(t, err)
+
+		sum, err := svc.Apply(timedCtx(5*time.Second), l.Org.ID, l.User.ID, pkg, pkger.ApplyWithStackID(newStack.ID))
+		require.NoError(t, err)
+		require.Len(t, sum.Buckets, 1)
+		defer func() {
+			require.NoError(t, l.BucketService(t).DeleteBucket(ctx, influxdb.ID(sum.Buckets[0].ID)))
+		}()
+
+		bkt, err := l.BucketService(t).FindBucketByName(ctx, l.Org.ID, "stack-bucket")
+		require.NoError(t, err)
+
+		stack, err := svc.ReadStack(timedCtx(5*time.Second), newStack.ID)
+		require.NoError(t, err)
+
+		expected := []pkger.StackResource{{
+			APIVersion: pkger.APIVersion,
+			ID:         bkt.ID,
+			Kind:       pkger.KindBucket,
+			Name:       "stack-bucket",
+		}}
+		assert.Equal(t, expected, stack.Resources)
+	})
+
 	t.Run("errors incurred during application of package rolls back to state before package", func(t *testing.T) {
 		svc := pkger.NewService(
 			pkger.WithBucketSVC(l.BucketService(t)),
@@ -3,14 +3,20 @@ package launcher_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	nethttp "net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
@@ -19,7 +25,13 @@ import (
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influxd/launcher"
 	phttp "github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/kit/prom/promtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/parquet"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
 )
 
 func TestPipeline_Write_Query_FieldKey(t *testing.T) {
@@ -105,6 +117,106 @@ func TestPipeline_WriteV2_Query(t *testing.T) {
 	res.HasTableCount(t, 1)
 }
 
+// This test writes some data and flushes the cache before querying it, so that
+// the data is read from TSM files.
+func TestPipeline_WriteV2_QueryAfterCachePurge(t *testing.T) {
+	be := launcher.RunTestLauncherOrFail(t, ctx)
+	be.SetupOrFail(t)
+	defer be.ShutdownOrFail(t, ctx)
+
+	be.WritePointsOrFail(t, `ctr,host=a n=1i,m=1.5 946684800000000000
+ctr,host=b n=2i 946684800000000000
+ctr,host=a n=3i 946684801000000000
+mem free=10i 946684800000000000`)
+
+	if err := be.Launcher.Engine().FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The series of the fields n and m of host a, n of host b, and free.
+	qs := fmt.Sprintf(`from(bucket:"%s") |> range(start:2000-01-01T00:00:00Z, stop:2000-01-02T00:00:00Z)`, be.Bucket.Name)
+	res := be.MustExecuteQuery(qs)
+	defer res.Done()
+	if got, exp := res.First(t).TablesN(), 4; got != exp {
+		t.Fatalf("got %d tables, exp %d", got, exp)
+	}
+
+	qs = fmt.Sprintf(`from(bucket:"%s")
+	|> range(start:2000-01-01T00:00:00Z, stop:2000-01-02T00:00:00Z)
+	|> filter(fn: (r) => r._measurement == "ctr" and r._field == "n")
+	|> keep(columns: ["_time", "_value", "host"])`, be.Bucket.Name)
+	exp := `,result,table,_time,_value,host` + "\r\n" +
+		`,_result,0,2000-01-01T00:00:00Z,1,a` + "\r\n" +
+		`,_result,0,2000-01-01T00:00:01Z,3,a` + "\r\n" +
+		`,_result,1,2000-01-01T00:00:00Z,2,b` + "\r\n\r\n"
+	if got := be.FluxQueryOrFail(t, be.Org, be.Auth.Token, qs); got != exp {
+		t.Fatalf("unexpected query results: -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+}
+
+// This test initializes a default launcher, writes points of measurements with
+// different tag keys, and checks the tag keys of each measurement while the
+// points are in the cache and after they are flushed to TSM files.
+func TestPipeline_Write_Schema_TagKeys(t *testing.T) {
+	be := launcher.RunTestLauncherOrFail(t, ctx)
+	be.SetupOrFail(t)
+	defer be.ShutdownOrFail(t, ctx)
+
+	be.WritePointsOrFail(t, `cpu,host=a,region=west usage=1.5 946684800000000000
+cpu,host=b,region=east usage=2.5 946684801000000000
+mem,host=a,dc=dc1 free=10i 946684800000000000
+disk,device=sda,path=/ used=20i 946684800000000000`)
+
+	tagKeys := func(measurement, start, stop string) []string {
+		t.Helper()
+		// The body of v1.tagKeys with a stop time, which is pushed down to
+		// the storage engine. It returns _start and _stop even without data.
+		qs := fmt.Sprintf(`from(bucket: "%s")
+	|> range(start: %s, stop: %s)
+	|> filter(fn: (r) => r._measurement == "%s")
+	|> keys()
+	|> keep(columns: ["_value"])
+	|> distinct()
+	|> filter(fn: (r) => r._value != "_start" and r._value != "_stop")`, be.Bucket.Name, start, stop, measurement)
+
+		keys := []string{}
+		for i, line := range strings.Split(be.FluxQueryOrFail(t, be.Org, be.Auth.Token, qs), "\r\n") {
+			if i == 0 || line == "" {
+				continue // header or end of table
+			}
+			row := strings.Split(line, ",")
+			keys = append(keys, row[len(row)-1])
+		}
+		return keys
+	}
+
+	tests := []struct {
+		measurement string
+		start, stop string
+		exp         []string
+	}{
+		{measurement: "cpu", start: "2000-01-01T00:00:00Z", stop: "2000-01-02T00:00:00Z", exp: []string{"_measurement", "host", "region", "_field"}},
+		{measurement: "mem", start: "2000-01-01T00:00:00Z", stop: "2000-01-02T00:00:00Z", exp: []string{"_measurement", "dc", "host", "_field"}},
+		{measurement: "disk", start: "2000-01-01T00:00:00Z", stop: "2000-01-02T00:00:00Z", exp: []string{"_measurement", "device", "path", "_field"}},
+		{measurement: "cpu", start: "2001-01-01T00:00:00Z", stop: "2001-01-02T00:00:00Z", exp: []string{}},
+	}
+
+	check := func(where string) {
+		t.Helper()
+		for _, tc := range tests {
+			if got := tagKeys(tc.measurement, tc.start, tc.stop); !cmp.Equal(got, tc.exp) {
+				t.Errorf("unexpected tag keys of %s from %s to %s in %s: -got/+exp\n%s", tc.measurement, tc.start, tc.stop, where, cmp.Diff(got, tc.exp))
+			}
+		}
+	}
+
+	check("cache")
+	if err := be.Launcher.Engine().FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("TSM")
+}
+
 // This test initializes a default launcher; writes some data; queries the data (success);
 // sets memory limits to the same read query; checks that the query fails because limits are exceeded.
 func TestPipeline_QueryMemoryLimits(t *testing.T) {
@@ -153,6 +265,110 @@ func TestPipeline_QueryMemoryLimits(t *testing.T) {
 	}
 }
 
+// This test initializes a launcher with query memory limits, writes more data
+// to the bucket of one organization than its queries may use, and checks that
+// only the queries of that organization fail when the queries of both
+// organizations run concurrently.
+func TestPipeline_QueryMemoryLimits_ConcurrentOrgs(t *testing.T) {
+	const (
+		concurrency    = 10
+		memoryBytes    = 64 * 1024
+		maxMemoryBytes = concurrency * memoryBytes
+		queries        = 50
+	)
+	l := launcher.RunTestLauncherOrFail(t, ctx,
+		"--query-concurrency", strconv.Itoa(concurrency),
+		"--query-queue-size", strconv.Itoa(2*queries),
+		"--query-memory-bytes", strconv.Itoa(memoryBytes),
+		"--query-max-memory-bytes", strconv.Itoa(maxMemoryBytes))
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// The onboarding authorization is an operator authorization, so it can
+	// create and write to a second organization.
+	small := &influxdb.OnboardingResults{
+		Org:  &influxdb.Organization{Name: "small-org"},
+		Auth: l.Auth,
+	}
+	if err := l.OrganizationService().CreateOrganization(ctx, small.Org); err != nil {
+		t.Fatal(err)
+	}
+	small.Bucket = &influxdb.Bucket{Name: "small-bucket", OrgID: small.Org.ID}
+	if err := l.BucketService(t).CreateBucket(ctx, small.Bucket); err != nil {
+		t.Fatal(err)
+	}
+	large := &influxdb.OnboardingResults{Org: l.Org, Bucket: l.Bucket, Auth: l.Auth}
+
+	var buf bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, "m,k=v f=%di %d\n", i, 946684800000000000+int64(i)*int64(time.Second))
+	}
+	l.WriteOrFail(t, large, buf.String())
+	l.WriteOrFail(t, small, "m,k=v f=1i 946684800000000000\nm,k=v f=2i 946684801000000000")
+
+	// Sorting buffers the whole table, unlike reading it. The result decoder
+	// of QueryAndNopConsume blocks on errors sent after the response starts,
+	// so check for the CSV error table instead.
+	query := func(to *influxdb.OnboardingResults) error {
+		qs := fmt.Sprintf(`from(bucket: "%s")
+	|> range(start: 2000-01-01T00:00:00Z, stop: 2000-01-02T00:00:00Z)
+	|> sort(columns: ["_value"], desc: true)`, to.Bucket.Name)
+		b, err := phttp.SimpleQuery(l.URL(), qs, to.Org.Name, l.Auth.Token)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(b, []byte(",error,reference")) {
+			return errors.New(string(b))
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	largeErrs := make(chan error, queries)
+	smallErrs := make(chan error, queries)
+	for i := 0; i < queries; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			largeErrs <- query(large)
+		}()
+		go func() {
+			defer wg.Done()
+			smallErrs <- query(small)
+		}()
+	}
+	wg.Wait()
+	close(largeErrs)
+	close(smallErrs)
+
+	for err := range largeErrs {
+		if err == nil {
+			t.Error("expected error from query of large org, got successful query execution")
+		} else if !strings.Contains(err.Error(), "allocation limit reached") {
+			t.Errorf("query of large org errored with unexpected error: %v", err)
+		}
+	}
+	for err := range smallErrs {
+		if err != nil {
+			t.Errorf("query of small org errored: %v", err)
+		}
+	}
+
+	// The unused memory is recorded when a query finishes, so record it once
+	// all memory is released.
+	if err := query(small); err != nil {
+		t.Fatal(err)
+	}
+	mfs, err := l.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := promtest.MustFindMetric(t, mfs, "query_control_memory_unused_bytes", map[string]string{"org": small.Org.ID.String()})
+	if got, exp := int64(m.GetGauge().GetValue()), int64(maxMemoryBytes-concurrency*memoryBytes); got != exp {
+		t.Errorf("unexpected memory unused bytes: got %d, exp %d", got, exp)
+	}
+}
+
 func TestPipeline_Query_LoadSecret_Success(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
@@ -203,6 +419,105 @@ from(bucket: "%s")
 	}
 }
 
+// This test rotates a secret and checks that queries read the new value, with
+// and without the query result cache.
+func TestPipeline_Query_LoadSecret_Rotation(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{name: "no cache"},
+		{name: "result cache", args: []string{"--query-result-cache-ttl", "1m"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testPipelineQueryLoadSecretRotation(t, tc.args...)
+		})
+	}
+}
+
+func testPipelineQueryLoadSecretRotation(t *testing.T, args ...string) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, args...)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// The onboarding authorization is an operator authorization, so it can
+	// create and query a second organization.
+	other := &influxdb.OnboardingResults{
+		Org:  &influxdb.Organization{Name: "other-org"},
+		Auth: l.Auth,
+	}
+	if err := l.OrganizationService().CreateOrganization(ctx, other.Org); err != nil {
+		t.Fatal(err)
+	}
+	other.Bucket = &influxdb.Bucket{Name: "other-bucket", OrgID: other.Org.ID}
+	if err := l.BucketService(t).CreateBucket(ctx, other.Bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	// write one point to each org so we can use it
+	l.WritePointsOrFail(t, fmt.Sprintf(`m,k=v1 f=%di %d`, 0, time.Now().UnixNano()))
+	l.WriteOrFail(t, other, fmt.Sprintf(`m,k=v1 f=%di %d`, 0, time.Now().UnixNano()))
+
+	const key = "mytoken"
+	putSecret := func(orgID influxdb.ID, value string) {
+		t.Helper()
+		if err := l.SecretService().PutSecret(ctx, orgID, key, value); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// token returns the value of the secret of the org read by a query.
+	token := func(orgID influxdb.ID, bucket string) string {
+		t.Helper()
+		req := &query.Request{
+			Authorization:  l.Auth,
+			OrganizationID: orgID,
+			Compiler: lang.FluxCompiler{
+				Query: fmt.Sprintf(`
+import "influxdata/influxdb/secrets"
+
+token = secrets.get(key: "mytoken")
+from(bucket: "%s")
+	|> range(start: -5m)
+	|> set(key: "token", value: token)
+`, bucket),
+			},
+		}
+		var token string
+		if err := l.QueryAndConsume(ctx, req, func(r flux.Result) error {
+			return r.Tables().Do(func(tbl flux.Table) error {
+				return tbl.Do(func(cr flux.ColReader) error {
+					j := execute.ColIdx("token", cr.Cols())
+					if j == -1 {
+						return errors.New("cannot find table column \"token\"")
+					}
+					for i := 0; i < cr.Len(); i++ {
+						token = execute.ValueForRow(cr, i, j).Str()
+					}
+					return nil
+				})
+			})
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return token
+	}
+
+	putSecret(l.Org.ID, "secrettoken")
+	putSecret(other.Org.ID, "othertoken")
+	if got, want := token(l.Org.ID, l.Bucket.Name), "secrettoken"; got != want {
+		t.Errorf("unexpected token -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+
+	putSecret(l.Org.ID, "rotatedtoken")
+	if got, want := token(l.Org.ID, l.Bucket.Name), "rotatedtoken"; got != want {
+		t.Errorf("unexpected token after rotation -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+	if got, want := token(other.Org.ID, other.Bucket.Name), "othertoken"; got != want {
+		t.Errorf("unexpected token of other org after rotation -want/+got:\n\t- %v\n\t+ %v", want, got)
+	}
+}
+
 func TestPipeline_Query_LoadSecret_Forbidden(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
@@ -405,3 +720,425 @@ from(bucket: "%s")
 		t.Fatal(err)
 	}
 }
+
+func TestPipeline_InfluxQLQuery(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--influxql-db-bucket", "telegraf=BUCKET")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Minute)
+	l.WritePointsOrFail(t, fmt.Sprintf("cpu,host=a usage_cpu=10 %d\ncpu,host=a usage_cpu=20 %d",
+		start.UnixNano(), start.Add(time.Second).UnixNano()))
+
+	influxQL := func(t *testing.T, q string) (int, influxqlResponse) {
+		t.Helper()
+
+		params := url.Values{"db": {"telegraf"}, "q": {q}}
+		req := l.NewHTTPRequestOrFail(t, "GET", "/query?"+params.Encode(), l.Auth.Token, "")
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res influxqlResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("response is not valid JSON: %v, body: %s", err, body)
+		}
+		return resp.StatusCode, res
+	}
+
+	t.Run("select", func(t *testing.T) {
+		code, res := influxQL(t, `SELECT mean("usage_cpu") FROM "cpu" WHERE time > now() - 1h GROUP BY time(1m)`)
+		if code != nethttp.StatusOK || res.Error != "" {
+			t.Fatalf("unexpected response: status %d, error %q", code, res.Error)
+		}
+
+		exp := []influxqlResult{{Series: []influxqlRow{{
+			Name:    "cpu",
+			Columns: []string{"time", "mean"},
+			Values:  [][]interface{}{{start.UTC().Format(time.RFC3339), 15.0}},
+		}}}}
+		if !cmp.Equal(res.Results, exp) {
+			t.Errorf("unexpected results -got/+exp\n%s", cmp.Diff(res.Results, exp))
+		}
+	})
+
+	t.Run("unsupported statement", func(t *testing.T) {
+		code, res := influxQL(t, `SHOW MEASUREMENTS`)
+		if code != nethttp.StatusBadRequest || res.Error == "" {
+			t.Fatalf("unexpected response: status %d, error %q", code, res.Error)
+		}
+	})
+}
+
+func TestPipeline_ParquetQuery(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	l.WritePointsOrFail(t, fmt.Sprintf("cpu,host=a usage_cpu=10 %d\ncpu,host=b usage_cpu=20 %d",
+		start.UnixNano(), start.Add(time.Second).UnixNano()))
+
+	q := fmt.Sprintf(`from(bucket: "%s") |> range(start: -1h) |> keep(columns: ["_time", "_value", "host"])`, l.Bucket.Name)
+	reqBody, err := json.Marshal(map[string]string{"query": q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := url.Values{"orgID": {l.Org.ID.String()}, "format": {"parquet"}}
+	req := l.NewHTTPRequestOrFail(t, "POST", "/api/v2/query?"+params.Encode(), l.Auth.Token, string(reqBody))
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("unexpected status %d, body: %s", resp.StatusCode, body)
+	}
+	if got, exp := resp.Header.Get("Content-Type"), "application/octet-stream"; got != exp {
+		t.Errorf("unexpected content type: got %q, exp %q", got, exp)
+	}
+	if got, exp := resp.Header.Get("Content-Disposition"), `attachment; filename="result.parquet"`; got != exp {
+		t.Errorf("unexpected content disposition: got %q, exp %q", got, exp)
+	}
+
+	w := parquet.NewWriter([]parquet.Column{
+		{Name: "_time", Type: parquet.Timestamp},
+		{Name: "_value", Type: parquet.Double},
+		{Name: "host", Type: parquet.String, Dictionary: true},
+	})
+	for _, row := range [][]interface{}{
+		{start.UTC(), 10.0, "a"},
+		{start.Add(time.Second).UTC(), 20.0, "b"},
+	} {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var exp bytes.Buffer
+	if _, err := w.WriteTo(&exp); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, exp.Bytes()) {
+		t.Errorf("unexpected file:\ngot  %x\nexp  %x", body, exp.Bytes())
+	}
+}
+
+func TestPipeline_QueryResultCache(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--query-result-cache-ttl", "1m")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	l.WritePointsOrFail(t, fmt.Sprintf("cpu,host=a usage_cpu=10 %d", start.UnixNano()))
+
+	q := fmt.Sprintf(`from(bucket: "%s") |> range(start: -1h) |> keep(columns: ["_value", "host"])`, l.Bucket.Name)
+	first := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, q)
+	if !strings.Contains(first, ",a") {
+		t.Fatalf("expected host a in result: %s", first)
+	}
+
+	// Write directly to the storage engine, bypassing the cache's
+	// invalidation, so that a query that reads the engine sees the point.
+	encoded := tsdb.EncodeName(l.Org.ID, l.Bucket.ID)
+	pts, err := models.ParsePoints([]byte(fmt.Sprintf("cpu,host=b usage_cpu=20 %d", start.Add(time.Second).UnixNano())), models.EscapeMeasurement(encoded[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Engine().WritePoints(ctx, pts); err != nil {
+		t.Fatal(err)
+	}
+
+	if second := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, q); second != first {
+		t.Fatalf("expected the cached result -got/+exp\n%s", cmp.Diff(second, first))
+	}
+
+	// Writing to the bucket through the API invalidates the result.
+	l.WritePointsOrFail(t, fmt.Sprintf("cpu,host=c usage_cpu=30 %d", start.Add(2*time.Second).UnixNano()))
+	third := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, q)
+	for _, host := range []string{",a", ",b", ",c"} {
+		if !strings.Contains(third, host) {
+			t.Errorf("expected host %s in result: %s", host[1:], third)
+		}
+	}
+}
+
+func TestPipeline_QueryTraceID(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--log-level", "debug")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	query := func(requestID string) string {
+		t.Helper()
+
+		q := fmt.Sprintf(`from(bucket: "%s") |> range(start: -1h)`, l.Bucket.Name)
+		body, err := json.Marshal(map[string]string{"query": q})
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := url.Values{"orgID": {l.Org.ID.String()}}
+		req := l.NewHTTPRequestOrFail(t, "POST", "/api/v2/query?"+params.Encode(), l.Auth.Token, string(body))
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != nethttp.StatusOK {
+			body, _ := ioutil.ReadAll(resp.Body)
+			t.Fatalf("unexpected status %d, body: %s", resp.StatusCode, body)
+		}
+		return resp.Header.Get("X-Influx-Trace-ID")
+	}
+
+	generated := query("")
+	if generated == "" {
+		t.Fatal("expected a generated X-Influx-Trace-ID header")
+	}
+	if other := query(""); other == generated {
+		t.Errorf("expected a new trace ID for each request, got %q twice", other)
+	}
+	if got, exp := query("support-1234"), "support-1234"; got != exp {
+		t.Errorf("unexpected X-Influx-Trace-ID header: got %q, exp %q", got, exp)
+	}
+
+	for _, id := range []string{generated, "support-1234"} {
+		if !strings.Contains(l.Stdout.String(), "trace_id="+id) {
+			t.Errorf("no log entry with trace_id %s:\n%s", id, l.Stdout.String())
+		}
+	}
+}
+
+func TestPipeline_QueryAccept(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, "cpu,host=a value=1 946684800000000000")
+	q := fmt.Sprintf(`from(bucket: "%s") |> range(start: 2000-01-01T00:00:00Z, stop: 2000-01-02T00:00:00Z) |> keep(columns: ["_value", "host"])`, l.Bucket.Name)
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{
+			contentType: "text/csv; charset=utf-8",
+			body:        ",result,table,_value,host\r\n,_result,0,1,a\r\n\r\n",
+		},
+		{
+			accept:      "*/*",
+			contentType: "text/csv; charset=utf-8",
+			body:        ",result,table,_value,host\r\n,_result,0,1,a\r\n\r\n",
+		},
+		{
+			accept:      "application/csv",
+			contentType: "application/csv; charset=utf-8",
+			body:        ",result,table,_value,host\r\n,_result,0,1,a\r\n\r\n",
+		},
+		{
+			accept:      "application/vnd.influx.annotated-csv",
+			contentType: "application/vnd.influx.annotated-csv; charset=utf-8",
+			body: "#group,false,false,false,true\r\n" +
+				"#datatype,string,long,double,string\r\n" +
+				"#default,_result,,,\r\n" +
+				",result,table,_value,host\r\n,,0,1,a\r\n\r\n",
+		},
+		{
+			accept:      "application/json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"type":"table","result":"_result","table":0,"columns":[{"name":"_value","type":"float","group":false},{"name":"host","type":"string","group":true}],"rows":[[1,"a"]]}` + "\n",
+		},
+	}
+	for _, tc := range tests {
+		req := l.NewHTTPRequestOrFail(t, "POST", "/api/v2/query?orgID="+l.Org.ID.String(), l.Auth.Token, q)
+		req.Header.Set("Content-Type", "application/vnd.flux")
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != nethttp.StatusOK {
+			t.Fatalf("Accept %q: unexpected status %d, body: %s", tc.accept, resp.StatusCode, body)
+		}
+		if got := resp.Header.Get("Content-Type"); got != tc.contentType {
+			t.Errorf("Accept %q: unexpected Content-Type: got %q, exp %q", tc.accept, got, tc.contentType)
+		}
+		if diff := cmp.Diff(tc.body, string(body)); diff != "" {
+			t.Errorf("Accept %q: unexpected body -exp/+got\n%s", tc.accept, diff)
+		}
+	}
+}
+
+func TestPipeline_QueryCompileError(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	q := fmt.Sprintf(`from(bucket: "%s")
+	|> range(start: -1h)
+	|> filter(fn: (r) => r._value > ))`, l.Bucket.Name)
+	req := l.NewHTTPRequestOrFail(t, "POST", "/api/v2/query?orgID="+l.Org.ID.String(), l.Auth.Token, q)
+	req.Header.Set("Content-Type", "application/vnd.flux")
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusBadRequest {
+		t.Fatalf("unexpected status %d, body: %s", resp.StatusCode, body)
+	}
+
+	var got struct {
+		Code    string `json:"code"`
+		Details []struct {
+			Line    int    `json:"line"`
+			Message string `json:"message"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != influxdb.EInvalid || len(got.Details) == 0 {
+		t.Fatalf("expected details of the compile error, got: %s", body)
+	}
+	for _, d := range got.Details {
+		if d.Line != 3 {
+			t.Errorf("unexpected line of error %q: got %d, exp 3", d.Message, d.Line)
+		}
+	}
+}
+
+func TestPipeline_QueryStream(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	l.WritePointsOrFail(t, fmt.Sprintf("cpu,host=a usage_cpu=10 %d\ncpu,host=b usage_cpu=20 %d",
+		start.UnixNano(), start.Add(time.Second).UnixNano()))
+
+	dial := func(t *testing.T) (context.Context, *websocket.Conn, func()) {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		header := nethttp.Header{}
+		phttp.SetToken(l.Auth.Token, &nethttp.Request{Header: header})
+		u := "ws" + strings.TrimPrefix(l.URL(), "http") + "/api/v2/query/stream?orgID=" + l.Org.ID.String()
+		conn, _, err := websocket.Dial(ctx, u, &websocket.DialOptions{HTTPHeader: header})
+		if err != nil {
+			cancel()
+			t.Fatal(err)
+		}
+		return ctx, conn, func() {
+			conn.Close(websocket.StatusNormalClosure, "")
+			cancel()
+		}
+	}
+
+	t.Run("results", func(t *testing.T) {
+		ctx, conn, closeConn := dial(t)
+		defer closeConn()
+
+		q := fmt.Sprintf(`from(bucket: "%s") |> range(start: -1h) |> keep(columns: ["_value", "host"])`, l.Bucket.Name)
+		if err := wsjson.Write(ctx, conn, map[string]string{"query": q}); err != nil {
+			t.Fatal(err)
+		}
+
+		var tables []query.TableJSON
+		for {
+			var table query.TableJSON
+			if err := wsjson.Read(ctx, conn, &table); err != nil {
+				if websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+					t.Fatalf("unexpected end of the stream: %v", err)
+				}
+				break
+			}
+			tables = append(tables, table)
+		}
+
+		exp := []query.TableJSON{
+			{
+				Type:    "table",
+				Result:  "_result",
+				Table:   0,
+				Columns: []query.TableJSONColumn{{Name: "_value", Type: "float"}, {Name: "host", Type: "string", Group: true}},
+				Rows:    [][]interface{}{{10.0, "a"}},
+			},
+			{
+				Type:    "table",
+				Result:  "_result",
+				Table:   1,
+				Columns: []query.TableJSONColumn{{Name: "_value", Type: "float"}, {Name: "host", Type: "string", Group: true}},
+				Rows:    [][]interface{}{{20.0, "b"}},
+			},
+		}
+		if !cmp.Equal(tables, exp) {
+			t.Errorf("unexpected tables -got/+exp\n%s", cmp.Diff(tables, exp))
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		ctx, conn, closeConn := dial(t)
+		defer closeConn()
+
+		if err := wsjson.Write(ctx, conn, map[string]string{"query": "from("}); err != nil {
+			t.Fatal(err)
+		}
+
+		var msg struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "error" || msg.Message == "" {
+			t.Errorf("expected an error message, got %+v", msg)
+		}
+		if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusInternalError {
+			t.Errorf("unexpected close of the stream: %v", err)
+		}
+	})
+}
+
+// influxqlResponse is the 1.x JSON response to an InfluxQL query.
+type influxqlResponse struct {
+	Results []influxqlResult `json:"results"`
+	Error   string           `json:"error"`
+}
+
+type influxqlResult struct {
+	StatementID int           `json:"statement_id"`
+	Series      []influxqlRow `json:"series"`
+}
+
+type influxqlRow struct {
+	Name    string          `json:"name"`
+	Columns []string        `json:"columns"`
+	Values  [][]interface{} `json:"values"`
+}
@@ -1,17 +1,23 @@
 package launcher_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	nethttp "net/http"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influxd/launcher"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/otlp"
+	"github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 )
@@ -53,6 +59,126 @@ func TestStorage_WriteAndQuery(t *testing.T) {
 	}
 }
 
+func TestStorage_PrometheusRemoteWrite(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--prometheus-default-bucket", "BUCKET")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	wr := prometheus.WriteRequest{TimeSeries: []prometheus.TimeSeries{{
+		Labels: []prometheus.Label{
+			{Name: "__name__", Value: "node_load1"},
+			{Name: "job", Value: "node"},
+		},
+		Samples: []prometheus.Sample{{Value: 0.5, Timestamp: 946684800000}},
+	}}}
+
+	req := l.NewHTTPRequestOrFail(t, "POST", fmt.Sprintf("/api/v2/prometheus/write?org=%s", l.Org.ID), l.Auth.Token, string(snappy.Encode(nil, wr.Marshal())))
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusNoContent {
+		t.Fatalf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+	}
+
+	qs := `from(bucket:"BUCKET") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z)`
+	exp := `,result,table,_start,_stop,_time,_value,_field,_measurement,job` + "\r\n" +
+		`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,0.5,_value,node_load1,node` + "\r\n\r\n"
+	if got := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs); !cmp.Equal(got, exp) {
+		t.Errorf("unexpected query results -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+}
+
+func TestStorage_OTLPMetrics(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--otlp-receiver-enabled")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	const ts = 946684800000000000
+	er := otlp.ExportMetricsRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Attributes: []otlp.Attribute{{Key: "host", Value: "a"}},
+		Metrics: []otlp.Metric{
+			{
+				Name:         "cpu_usage",
+				Type:         otlp.MetricTypeGauge,
+				NumberPoints: []otlp.NumberDataPoint{{Time: ts, Value: 0.5}},
+			},
+			{
+				Name:         "requests",
+				Type:         otlp.MetricTypeSum,
+				Monotonic:    true,
+				NumberPoints: []otlp.NumberDataPoint{{Time: ts, Value: 10}},
+			},
+			{
+				Name: "latency",
+				Type: otlp.MetricTypeHistogram,
+				HistogramPoints: []otlp.HistogramDataPoint{{
+					Time:           ts,
+					Count:          3,
+					Sum:            0.7,
+					BucketCounts:   []uint64{1, 2},
+					ExplicitBounds: []float64{0.1},
+				}},
+			},
+		},
+	}}}
+
+	req := l.NewHTTPRequestOrFail(t, "POST", fmt.Sprintf("/v1/metrics?org=%s&bucket=%s", l.Org.ID, l.Bucket.ID), l.Auth.Token, string(er.Marshal()))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+	}
+
+	for _, tt := range []struct {
+		measurement string
+		exp         string
+	}{
+		{
+			measurement: "cpu_usage",
+			exp: `,result,table,_start,_stop,_time,_value,_field,_measurement,host` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,0.5,_value,cpu_usage,a` + "\r\n\r\n",
+		},
+		{
+			measurement: "requests",
+			exp: `,result,table,_start,_stop,_time,_value,_field,_measurement,host,monotonic` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,10,_value,requests,a,true` + "\r\n\r\n",
+		},
+		{
+			measurement: "latency",
+			exp: `,result,table,_start,_stop,_time,_value,_field,_measurement,host` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,1,bucket_le_0.1,latency,a` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,3,bucket_le_inf,latency,a` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,3,count,latency,a` + "\r\n" +
+				`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,0.7,sum,latency,a` + "\r\n\r\n",
+		},
+	} {
+		qs := fmt.Sprintf(`from(bucket:"BUCKET") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z) |> filter(fn: (r) => r._measurement == %q) |> group() |> sort(columns: ["_field"])`, tt.measurement)
+		if got := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs); !cmp.Equal(got, tt.exp) {
+			t.Errorf("unexpected %s query results -got/+exp\n%s", tt.measurement, cmp.Diff(got, tt.exp))
+		}
+	}
+}
+
 func TestLauncher_WriteAndQuery(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
@@ -91,6 +217,149 @@ func TestLauncher_WriteAndQuery(t *testing.T) {
 	}
 }
 
+func TestStorage_EngineStats(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, "m,k=v f=100i 946684800000000000\nm,k=w f=101i 946684800000000000")
+
+	// The onboarding authorization is an operator authorization.
+	resp, err := nethttp.DefaultClient.Do(l.MustNewHTTPRequest("GET", "/api/v2/engine/stats", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+	}
+
+	var stats influxdb.EngineStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.CacheSize <= 0 {
+		t.Errorf("expected data in cache, got cache size %d", stats.CacheSize)
+	}
+	if stats.CacheCount != 2 {
+		t.Errorf("unexpected cache count: got %d, exp 2", stats.CacheCount)
+	}
+	if stats.OpenTSMFiles < 0 {
+		t.Errorf("unexpected open TSM files: %d", stats.OpenTSMFiles)
+	}
+	if stats.WALSegments < 1 || stats.WALSizeBytes <= 0 {
+		t.Errorf("expected written WAL segment, got %d segments of %d bytes", stats.WALSegments, stats.WALSizeBytes)
+	}
+}
+
+func TestStorage_BucketSchemaExportImport(t *testing.T) {
+	src := launcher.RunTestLauncherOrFail(t, ctx)
+	src.SetupOrFail(t)
+	defer src.ShutdownOrFail(t, ctx)
+
+	src.WritePointsOrFail(t, "cpu,host=a usage=1.5,cores=4i 946684800000000000\nmem,host=a,region=west free=10i 946684800000000000")
+
+	resp, err := nethttp.DefaultClient.Do(src.MustNewHTTPRequest("GET", "/api/v2/schema/buckets/"+src.Bucket.ID.String()+"/export", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != nethttp.StatusOK {
+		t.Fatalf("unexpected status code of export: %d, body: %s", resp.StatusCode, exported)
+	}
+
+	var schema influxdb.BucketSchema
+	if err := json.Unmarshal(exported, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.GeneratedAt.IsZero() {
+		t.Error("exported schema has no generatedAt time")
+	}
+	exp := []influxdb.BucketSchemaMeasurement{
+		{
+			MeasurementSchema: influxdb.MeasurementSchema{
+				Measurement: "cpu",
+				Fields: []influxdb.MeasurementSchemaField{
+					{Name: "cores", Type: influxdb.SchemaFieldTypeInteger},
+					{Name: "usage", Type: influxdb.SchemaFieldTypeFloat},
+				},
+			},
+			TagKeys: []string{"host"},
+		},
+		{
+			MeasurementSchema: influxdb.MeasurementSchema{
+				Measurement: "mem",
+				Fields: []influxdb.MeasurementSchemaField{
+					{Name: "free", Type: influxdb.SchemaFieldTypeInteger},
+				},
+			},
+			TagKeys: []string{"host", "region"},
+		},
+	}
+	if diff := cmp.Diff(exp, schema.Measurements); diff != "" {
+		t.Fatalf("unexpected exported measurements -exp/+got\n%s", diff)
+	}
+
+	dst := launcher.RunTestLauncherOrFail(t, ctx)
+	dst.SetupOrFail(t)
+	defer dst.ShutdownOrFail(t, ctx)
+
+	importSchema := func() int {
+		t.Helper()
+		resp, err := nethttp.DefaultClient.Do(dst.MustNewHTTPRequest("POST", "/api/v2/schema/buckets/"+dst.Bucket.ID.String()+"/import", string(exported)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := importSchema(); code != nethttp.StatusNoContent {
+		t.Fatalf("unexpected status code of import: got %d, exp %d", code, nethttp.StatusNoContent)
+	}
+	// The measurements now have a schema.
+	if code := importSchema(); code != nethttp.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status code of second import: got %d, exp %d", code, nethttp.StatusUnprocessableEntity)
+	}
+
+	// Points of the second instance are validated against the schema.
+	dst.WritePointsOrFail(t, "cpu,host=b usage=2.5,cores=8i 946684800000000000")
+	if err := dst.WritePoints("cpu,host=b usage=2i 946684800000000000"); err == nil {
+		t.Error("expected write of a field of another type than in the schema to fail")
+	}
+	if err := dst.WritePoints("mem,host=b used=1i 946684800000000000"); err == nil {
+		t.Error("expected write of a field not in the schema to fail")
+	}
+}
+
+func TestStorage_SchemaMeasurements(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, "cpu,host=a usage=1.5 946684800000000000\nmem,host=a,region=west free=10i 946684800000000000")
+
+	if diff := cmp.Diff([]string{"cpu", "mem"}, l.GetSchemaOrFail(t)); diff != "" {
+		t.Errorf("unexpected measurements -exp/+got\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"host", "region"}, l.GetTagKeysOrFail(t, "mem")); diff != "" {
+		t.Errorf("unexpected tag keys -exp/+got\n%s", diff)
+	}
+
+	resp, err := nethttp.DefaultClient.Do(l.MustNewHTTPRequest("GET", "/api/v2/schema/buckets/"+l.Bucket.ID.String()+"/measurements/disk/tags", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != nethttp.StatusNotFound {
+		t.Errorf("unexpected status code for tag keys of missing measurement: got %d, exp %d", resp.StatusCode, nethttp.StatusNotFound)
+	}
+}
+
 func TestLauncher_BucketDelete(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
@@ -247,3 +516,101 @@ func TestStorage_CacheSnapshot_Age(t *testing.T) {
 		t.Fatalf("got %d series in TSM files, expected %d", got, exp)
 	}
 }
+
+func TestStorage_TagValueHistogram(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, `cpu,host=a,region=west usage=1 946684800000000000
+cpu,host=b,region=west usage=1 946684800000000000
+cpu,host=c,region=east usage=1 946684800000000000
+cpu,host=d,region=north usage=1 946684800000000000`)
+
+	histogram := func(query string) (int, []byte) {
+		t.Helper()
+		resp, err := nethttp.DefaultClient.Do(l.MustNewHTTPRequest("GET", "/api/v2/schema/buckets/"+l.Bucket.ID.String()+"/measurements/cpu/tags/region/histogram"+query, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	code, body := histogram("?topN=2")
+	if code != nethttp.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", code, body)
+	}
+	var got struct {
+		Values []influxdb.TagValueCount `json:"values"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	exp := []influxdb.TagValueCount{{Value: "west", Count: 2}, {Value: "east", Count: 1}}
+	if diff := cmp.Diff(exp, got.Values); diff != "" {
+		t.Fatalf("unexpected histogram -exp/+got\n%s", diff)
+	}
+
+	if code, body := histogram("?topN=0"); code != nethttp.StatusBadRequest {
+		t.Fatalf("unexpected status code of invalid topN: %d, body: %s", code, body)
+	}
+}
+
+func TestStorage_BucketSize(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// Random strings hardly compress, so that the data of the bucket takes
+	// about the raw size of its values, a timestamp and 32 bytes each.
+	const n = 1000
+	const rawSize = n * (8 + 32)
+	rnd := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "log,host=a msg=\"%016x%016x\" %d\n", rnd.Uint64(), rnd.Uint64(), time.Unix(int64(i), 0).UnixNano())
+	}
+	l.WritePointsOrFail(t, sb.String())
+
+	size := func() int64 {
+		t.Helper()
+		resp, err := nethttp.DefaultClient.Do(l.MustNewHTTPRequest("GET", "/api/v2/buckets/"+l.Bucket.ID.String()+"/size", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != nethttp.StatusOK {
+			t.Fatalf("unexpected status code: %d, body: %s", resp.StatusCode, body)
+		}
+		var got struct {
+			Size int64 `json:"size"`
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Size
+	}
+
+	// The size of the data is that in the cache,
+	cached := size()
+	if err := l.Engine().FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// and once the cache is written to a TSM file, that of the file.
+	flushed := size()
+
+	for _, got := range []int64{cached, flushed} {
+		if got < rawSize/2 || got > rawSize*2 {
+			t.Errorf("unexpected bucket size: got %d, exp between %d and %d", got, rawSize/2, rawSize*2)
+		}
+	}
+}
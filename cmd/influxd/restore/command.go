@@ -1,17 +1,23 @@
 package restore
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
 	"github.com/influxdata/influxdb/http"
 	"github.com/influxdata/influxdb/internal/fs"
 	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/influxdata/influxdb/pkg/s3"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +28,11 @@ var Command = &cobra.Command{
 	Long: `
 This command restores data and metadata from a backup fileset.
 
+Backups uploaded to S3 with "influx backup --s3-bucket" are restored by giving
+the same location with --s3-bucket instead of --backup-path. The files listed
+by the manifest of the backup are downloaded to a temporary directory before
+being restored.
+
 Any existing metadata and data will be temporarily moved while restore runs
 and deleted after restore completes.
 
@@ -45,6 +56,12 @@ var flags struct {
 	credPath   string
 	backupPath string
 	rebuildTSI bool
+
+	s3Bucket    string
+	s3AccessKey string
+	s3SecretKey string
+	s3Region    string
+	s3Endpoint  string
 }
 
 func init() {
@@ -89,12 +106,59 @@ func init() {
 			Default: true,
 			Desc:    "if true, rebuild the TSI index and series file based on the given engine path (equivalent to influxd inspect build-tsi)",
 		},
+		{
+			DestP:   &flags.s3Bucket,
+			Flag:    "s3-bucket",
+			Default: "",
+			Desc:    "S3 location to download backup files from, as s3://bucket/prefix/",
+		},
+		{
+			DestP:   &flags.s3AccessKey,
+			Flag:    "s3-access-key",
+			Default: "",
+			Desc:    "S3 access key ID; defaults to $AWS_ACCESS_KEY_ID",
+		},
+		{
+			DestP:   &flags.s3SecretKey,
+			Flag:    "s3-secret-key",
+			Default: "",
+			Desc:    "S3 secret access key; defaults to $AWS_SECRET_ACCESS_KEY",
+		},
+		{
+			DestP:   &flags.s3Region,
+			Flag:    "s3-region",
+			Default: "",
+			Desc:    "S3 region; defaults to $AWS_REGION",
+		},
+		{
+			DestP:   &flags.s3Endpoint,
+			Flag:    "s3-endpoint",
+			Default: "",
+			Desc:    "URL of an S3 compatible service to use instead of AWS",
+		},
 	}
 
 	cli.BindOptions(Command, opts)
 }
 
 func restoreE(cmd *cobra.Command, args []string) error {
+	if flags.s3Bucket != "" {
+		if flags.backupPath != "" {
+			return fmt.Errorf("only one of backup-path and s3-bucket may be given")
+		}
+
+		dir, err := ioutil.TempDir("", "influxd-restore")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		if err := downloadS3Backup(context.Background(), dir); err != nil {
+			return fmt.Errorf("failed to download backup: %v", err)
+		}
+		flags.backupPath = dir
+	}
+
 	if flags.backupPath == "" {
 		return fmt.Errorf("no backup path given")
 	}
@@ -303,3 +367,51 @@ func restoreCred() error {
 	fmt.Printf("Restored credentials to %s from %s\n", flags.credPath, backupCred)
 	return nil
 }
+
+// downloadS3Backup downloads the manifest of the backup at the S3 location
+// given by the s3-bucket flag, and the files it lists, to dir.
+func downloadS3Backup(ctx context.Context, dir string) error {
+	bucket, prefix, err := s3.ParseURL(flags.s3Bucket)
+	if err != nil {
+		return err
+	}
+
+	creds := s3.CredentialsFromEnv()
+	if flags.s3AccessKey != "" || flags.s3SecretKey != "" {
+		creds = s3.Credentials{AccessKeyID: flags.s3AccessKey, SecretAccessKey: flags.s3SecretKey}
+	}
+	region := flags.s3Region
+	if region == "" {
+		region = s3.RegionFromEnv()
+	}
+	client := s3.NewClient(flags.s3Endpoint, region, creds)
+
+	var buf bytes.Buffer
+	if err := client.GetObject(ctx, bucket, prefix+influxdb.BackupManifestFilename, &buf); err != nil {
+		return fmt.Errorf("error downloading manifest: %v", err)
+	}
+	var manifest influxdb.BackupManifest
+	if err := json.Unmarshal(buf.Bytes(), &manifest); err != nil {
+		return fmt.Errorf("error decoding manifest: %v", err)
+	}
+
+	for _, name := range manifest.Files {
+		if err := downloadS3File(ctx, client, bucket, prefix+name, filepath.Join(dir, filepath.Base(name))); err != nil {
+			return fmt.Errorf("error downloading file %s: %v", name, err)
+		}
+	}
+	fmt.Printf("Downloaded %d files of backup %d from %s\n", len(manifest.Files), manifest.BackupID, flags.s3Bucket)
+	return nil
+}
+
+func downloadS3File(ctx context.Context, client *s3.Client, bucket, key, path string) error {
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if err := client.GetObject(ctx, bucket, key, w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,11 @@
+package influxdb
+
+import (
+	"context"
+)
+
+// DefragmentService reclaims the disk space of deleted data.
+type DefragmentService interface {
+	// Defragment rewrites the stored data of a bucket, dropping deleted data.
+	Defragment(ctx context.Context, orgID, bucketID ID) error
+}
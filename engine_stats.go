@@ -0,0 +1,33 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// EngineStatsService reports the internal statistics of the storage engine,
+// for operators who need more detail than its Prometheus metrics.
+type EngineStatsService interface {
+	// EngineStats returns the current statistics of the storage engine.
+	EngineStats(ctx context.Context) (*EngineStats, error)
+}
+
+// EngineStats are the internal statistics of the storage engine.
+type EngineStats struct {
+	CacheSize               int64 `json:"cacheSize"`
+	CacheCount              int   `json:"cacheCount"`
+	OpenTSMFiles            int   `json:"openTSMFiles"`
+	TSMFileSizeBytes        int64 `json:"tsmFileSizeBytes"`
+	ActiveCompactions       int   `json:"activeCompactions"`
+	CompactionLevel1Running int   `json:"compactionLevel1Running"`
+	CompactionLevel2Running int   `json:"compactionLevel2Running"`
+	CompactionFullRunning   int   `json:"compactionFullRunning"`
+	WALSegments             int   `json:"walSegments"`
+	WALSizeBytes            int64 `json:"walSizeBytes"`
+
+	// The quantiles of the durations of the most recent writes, in
+	// nanoseconds.
+	WriteLatencyP50 time.Duration `json:"writeLatencyP50"`
+	WriteLatencyP95 time.Duration `json:"writeLatencyP95"`
+	WriteLatencyP99 time.Duration `json:"writeLatencyP99"`
+}
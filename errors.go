@@ -25,6 +25,8 @@ const (
 	EUnauthorized        = "unauthorized"
 	EMethodNotAllowed    = "method not allowed"
 	ETooLarge            = "request too large"
+	ETimeout             = "timeout"
+	EPreconditionFailed  = "precondition failed"
 )
 
 // Error is the error struct of platform.
@@ -143,6 +145,18 @@ func ErrorCode(err error) string {
 	return EInternal
 }
 
+// IsRetryable returns whether an operation that failed with err may succeed if
+// it is retried. Errors without a code are internal errors and are retryable,
+// unlike errors caused by the request itself, such as invalid data or missing
+// authorization.
+func IsRetryable(err error) bool {
+	switch ErrorCode(err) {
+	case EInternal, EUnavailable, ETooManyRequests, ETimeout:
+		return true
+	}
+	return false
+}
+
 // ErrorOp returns the op of the error, if available; otherwise return empty string.
 func ErrorOp(err error) string {
 	if err == nil {
@@ -0,0 +1,66 @@
+package influxdb
+
+import (
+	"context"
+)
+
+// ErrGroupNotFound is the error msg for a missing group.
+const ErrGroupNotFound = "group not found"
+
+// ops for groups error and groups op logs.
+var (
+	OpFindGroupByID     = "FindGroupByID"
+	OpFindGroups        = "FindGroups"
+	OpCreateGroup       = "CreateGroup"
+	OpDeleteGroup       = "DeleteGroup"
+	OpAddGroupMember    = "AddGroupMember"
+	OpRemoveGroupMember = "RemoveGroupMember"
+)
+
+// Group is a named collection of users within an organization. A group may be
+// granted access to a resource with a UserResourceMapping, giving every
+// member of the group that access.
+type Group struct {
+	ID      ID     `json:"id,omitempty"`
+	OrgID   ID     `json:"orgID"`
+	Name    string `json:"name"`
+	Members []ID   `json:"members,omitempty"`
+}
+
+// HasMember returns true if the user is a member of the group.
+func (g *Group) HasMember(userID ID) bool {
+	for _, id := range g.Members {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupFilter represents a set of filters that restrict the returned groups.
+type GroupFilter struct {
+	ID     *ID
+	OrgID  *ID
+	UserID *ID
+}
+
+// GroupService represents a service for managing groups and their members.
+type GroupService interface {
+	// FindGroupByID returns a single group by ID.
+	FindGroupByID(ctx context.Context, id ID) (*Group, error)
+
+	// FindGroups returns a list of groups that match filter and the total count of matching groups.
+	FindGroups(ctx context.Context, filter GroupFilter, opt ...FindOptions) ([]*Group, int, error)
+
+	// CreateGroup creates a new group and sets g.ID with the new identifier.
+	CreateGroup(ctx context.Context, g *Group) error
+
+	// DeleteGroup removes a group by ID.
+	DeleteGroup(ctx context.Context, id ID) error
+
+	// AddGroupMember adds the user to the group.
+	AddGroupMember(ctx context.Context, groupID, userID ID) error
+
+	// RemoveGroupMember removes the user from the group.
+	RemoveGroupMember(ctx context.Context, groupID, userID ID) error
+}
@@ -32,6 +32,14 @@ type APIBackend struct {
 	// in a single points batch
 	MaxBatchSizeBytes int64
 
+	// WriteMaxBodySize is the maximum number of bytes of the body of a write
+	// request, before decompression. A value of zero specifies there is no limit.
+	WriteMaxBodySize int64
+
+	// QueryMaxBodySize is the maximum number of bytes of the body of a query
+	// request. A value of zero specifies there is no limit.
+	QueryMaxBodySize int64
+
 	// WriteParserMaxBytes specifies the maximum number of bytes that may be allocated when processing a single
 	// write request. A value of zero specifies there is no limit.
 	WriteParserMaxBytes int
@@ -44,6 +52,38 @@ type APIBackend struct {
 	// write request. A value of zero specifies there is no limit.
 	WriteParserMaxValues int
 
+	// WriteQuota enforces the MaxBytesPerDay of authorizations. If nil, a
+	// quota that keeps usage in memory only is used.
+	WriteQuota *WriteQuota
+
+	// PrometheusDefaultBucket is the bucket written to by Prometheus remote
+	// write requests that do not specify a bucket.
+	PrometheusDefaultBucket string
+
+	// InfluxQLBucketMapping maps the databases named by InfluxQL queries sent
+	// to the 1.x /query API to buckets.
+	InfluxQLBucketMapping map[string]string
+
+	// OTLPReceiverEnabled enables the OpenTelemetry metrics receiver at
+	// /v1/metrics.
+	OTLPReceiverEnabled bool
+
+	// CORSAllowOrigins are the origins allowed to make cross-origin requests,
+	// or "*" for any origin. If empty, the origin of any request is allowed
+	// with the default CORS headers, and preflight requests are not answered.
+	CORSAllowOrigins []string
+
+	// CORSAllowHeaders are the request headers allowed in cross-origin
+	// requests from CORSAllowOrigins. If empty, the default headers are allowed.
+	CORSAllowHeaders []string
+
+	// OIDCConfig enables the OpenID Connect authorization code flow when set.
+	OIDCConfig *OIDCConfig
+
+	// ScopeMapping translates the scopes of an authorization into permissions.
+	// If nil, influxdb.DefaultScopeMapping is used.
+	ScopeMapping influxdb.ScopeMapping
+
 	NewBucketService func(*influxdb.Source) (influxdb.BucketService, error)
 	NewQueryService  func(*influxdb.Source) (query.ProxyQueryService, error)
 
@@ -52,6 +92,12 @@ type APIBackend struct {
 
 	PointsWriter                    storage.PointsWriter
 	DeleteService                   influxdb.DeleteService
+	DefragmentService               influxdb.DefragmentService
+	RecoveryService                 influxdb.RecoveryService
+	ReadinessService                influxdb.ReadinessService
+	EngineStatsService              influxdb.EngineStatsService
+	SchemaService                   influxdb.SchemaService
+	BucketSchemaService             influxdb.BucketSchemaService
 	BackupService                   influxdb.BackupService
 	KVBackupService                 influxdb.KVBackupService
 	AuthorizationService            influxdb.AuthorizationService
@@ -60,10 +106,15 @@ type APIBackend struct {
 	UserService                     influxdb.UserService
 	OrganizationService             influxdb.OrganizationService
 	UserResourceMappingService      influxdb.UserResourceMappingService
+	GroupService                    influxdb.GroupService
+	OIDCIdentityService             influxdb.OIDCIdentityService
 	LabelService                    influxdb.LabelService
 	DashboardService                influxdb.DashboardService
 	DashboardOperationLogService    influxdb.DashboardOperationLogService
 	BucketOperationLogService       influxdb.BucketOperationLogService
+	BucketCardinalityService        influxdb.BucketCardinalityService
+	BucketSizeService               influxdb.BucketSizeService
+	BucketMetadataService           influxdb.BucketMetadataService
 	UserOperationLogService         influxdb.UserOperationLogService
 	OrganizationOperationLogService influxdb.OrganizationOperationLogService
 	SourceService                   influxdb.SourceService
@@ -118,7 +169,7 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	}
 
 	noAuthUserResourceMappingService := b.UserResourceMappingService
-	b.UserResourceMappingService = authorizer.NewURMService(b.OrgLookupService, b.UserResourceMappingService)
+	b.UserResourceMappingService = authorizer.NewURMServiceWithGroups(b.OrgLookupService, b.GroupService, b.UserResourceMappingService)
 	b.LabelService = authorizer.NewLabelServiceWithOrg(b.LabelService, b.OrgLookupService)
 
 	h.Mount("/api/v2", serveLinksHandler(b.HTTPErrorHandler))
@@ -145,15 +196,56 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	deleteBackend := NewDeleteBackend(b.Logger.With(zap.String("handler", "delete")), b)
 	h.Mount(prefixDelete, NewDeleteHandler(b.Logger, deleteBackend))
 
+	if b.DefragmentService != nil {
+		defragmentBackend := NewDefragmentBackend(b.Logger.With(zap.String("handler", "defragment")), b)
+		defragmentBackend.DefragmentService = authorizer.NewDefragmentService(b.DefragmentService)
+		h.Mount(prefixDefragment, NewDefragmentHandler(b.Logger, defragmentBackend))
+	}
+
+	if b.RecoveryService != nil {
+		recoveryBackend := NewRecoveryBackend(b.Logger.With(zap.String("handler", "recovery")), b)
+		recoveryBackend.RecoveryService = authorizer.NewRecoveryService(b.RecoveryService)
+		h.Mount(prefixRecoveryReport, NewRecoveryHandler(b.Logger, recoveryBackend))
+	}
+
+	if b.EngineStatsService != nil {
+		engineStatsBackend := NewEngineStatsBackend(b.Logger.With(zap.String("handler", "engine_stats")), b)
+		engineStatsBackend.EngineStatsService = authorizer.NewEngineStatsService(b.EngineStatsService)
+		h.Mount(prefixEngineStats, NewEngineStatsHandler(b.Logger, engineStatsBackend))
+	}
+
+	if b.SchemaService != nil && b.BucketSchemaService != nil {
+		schemaBackend := NewSchemaBackend(b.Logger.With(zap.String("handler", "schema")), b)
+		schemaBackend.BucketService = authorizer.NewBucketService(b.BucketService, b.UserResourceMappingService)
+		schemaBackend.SchemaService = authorizer.NewSchemaService(b.SchemaService)
+		schemaBackend.BucketSchemaService = authorizer.NewBucketSchemaService(b.BucketSchemaService)
+		h.Mount(prefixSchema, NewSchemaHandler(b.Logger, schemaBackend))
+	}
+
+	if b.ReadinessService != nil {
+		readinessBackend := NewReadinessBackend(b.Logger.With(zap.String("handler", "readiness")), b)
+		h.Mount(prefixReadiness, NewReadinessHandler(b.Logger, readinessBackend))
+	}
+
 	documentBackend := NewDocumentBackend(b.Logger.With(zap.String("handler", "document")), b)
 	documentBackend.DocumentService = authorizer.NewDocumentService(b.DocumentService)
 	h.Mount(prefixDocuments, NewDocumentHandler(documentBackend))
 
 	fluxBackend := NewFluxBackend(b.Logger.With(zap.String("handler", "query")), b)
-	h.Mount(prefixQuery, NewFluxHandler(b.Logger, fluxBackend))
+	h.Mount(prefixQuery, kithttp.MaxBodySize(b.QueryMaxBodySize, b.HTTPErrorHandler)(NewFluxHandler(b.Logger, fluxBackend)))
+
+	influxqlBackend := NewInfluxQLBackend(b.Logger.With(zap.String("handler", "influxql")), b)
+	h.Mount(prefixInfluxQLQuery, NewInfluxQLHandler(b.Logger, influxqlBackend))
 
 	h.Mount(prefixLabels, NewLabelHandler(b.Logger, b.LabelService, b.HTTPErrorHandler))
 
+	if b.OIDCConfig != nil {
+		oidcBackend := NewOIDCBackend(b.Logger.With(zap.String("handler", "oidc")), b)
+		oidcBackend.Config = *b.OIDCConfig
+		oidcBackend.UserResourceMappingService = noAuthUserResourceMappingService
+		h.Mount(prefixOAuth2, NewOIDCHandler(b.Logger, oidcBackend))
+	}
+
 	notificationEndpointBackend := NewNotificationEndpointBackend(b.Logger.With(zap.String("handler", "notificationEndpoint")), b)
 	notificationEndpointBackend.NotificationEndpointService = authorizer.NewNotificationEndpointService(b.NotificationEndpointService,
 		b.UserResourceMappingService, b.OrganizationService)
@@ -189,6 +281,7 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	h.Mount(prefixSources, NewSourceHandler(b.Logger, sourceBackend))
 
 	h.Mount("/api/v2/swagger.json", newSwaggerLoader(b.Logger.With(zap.String("service", "swagger-loader")), b.HTTPErrorHandler))
+	h.Mount(prefixOpenAPI, newOpenAPILoader(b.Logger.With(zap.String("service", "openapi-loader")), b.HTTPErrorHandler))
 
 	taskLogger := b.Logger.With(zap.String("handler", "bucket"))
 	taskBackend := NewTaskBackend(taskLogger, b)
@@ -217,12 +310,20 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	h.Mount(prefixBackup, NewBackupHandler(backupBackend))
 
 	writeBackend := NewWriteBackend(b.Logger.With(zap.String("handler", "write")), b)
-	h.Mount(prefixWrite, NewWriteHandler(b.Logger, writeBackend,
+	writeHandler := NewWriteHandler(b.Logger, writeBackend,
 		WithMaxBatchSizeBytes(b.MaxBatchSizeBytes),
 		WithParserMaxBytes(b.WriteParserMaxBytes),
 		WithParserMaxLines(b.WriteParserMaxLines),
 		WithParserMaxValues(b.WriteParserMaxValues),
-	))
+		WithPrometheusDefaultBucket(b.PrometheusDefaultBucket),
+		WithWriteQuota(b.WriteQuota),
+	)
+	limitedWriteHandler := kithttp.MaxBodySize(b.WriteMaxBodySize, b.HTTPErrorHandler)(writeHandler)
+	h.Mount(prefixWrite, limitedWriteHandler)
+	h.Mount(prefixPrometheusWrite, limitedWriteHandler)
+	if b.OTLPReceiverEnabled {
+		h.Mount(prefixOTLPMetrics, limitedWriteHandler)
+	}
 
 	for _, o := range opts {
 		o(h)
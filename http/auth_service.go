@@ -27,6 +27,9 @@ type AuthorizationBackend struct {
 	OrganizationService  platform.OrganizationService
 	UserService          platform.UserService
 	LookupService        platform.LookupService
+
+	// ScopeMapping translates the scopes of an authorization into permissions.
+	ScopeMapping platform.ScopeMapping
 }
 
 // NewAuthorizationBackend returns a new instance of AuthorizationBackend.
@@ -39,6 +42,7 @@ func NewAuthorizationBackend(log *zap.Logger, b *APIBackend) *AuthorizationBacke
 		OrganizationService:  b.OrganizationService,
 		UserService:          b.UserService,
 		LookupService:        b.LookupService,
+		ScopeMapping:         b.ScopeMapping,
 	}
 }
 
@@ -52,6 +56,7 @@ type AuthorizationHandler struct {
 	UserService          platform.UserService
 	AuthorizationService platform.AuthorizationService
 	LookupService        platform.LookupService
+	ScopeMapping         platform.ScopeMapping
 }
 
 // NewAuthorizationHandler returns a new instance of AuthorizationHandler.
@@ -65,6 +70,10 @@ func NewAuthorizationHandler(log *zap.Logger, b *AuthorizationBackend) *Authoriz
 		OrganizationService:  b.OrganizationService,
 		UserService:          b.UserService,
 		LookupService:        b.LookupService,
+		ScopeMapping:         b.ScopeMapping,
+	}
+	if h.ScopeMapping == nil {
+		h.ScopeMapping = platform.DefaultScopeMapping()
 	}
 
 	h.HandlerFunc("POST", "/api/v2/authorizations", h.handlePostAuthorization)
@@ -76,31 +85,35 @@ func NewAuthorizationHandler(log *zap.Logger, b *AuthorizationBackend) *Authoriz
 }
 
 type authResponse struct {
-	ID          platform.ID          `json:"id"`
-	Token       string               `json:"token"`
-	Status      platform.Status      `json:"status"`
-	Description string               `json:"description"`
-	OrgID       platform.ID          `json:"orgID"`
-	Org         string               `json:"org"`
-	UserID      platform.ID          `json:"userID"`
-	User        string               `json:"user"`
-	Permissions []permissionResponse `json:"permissions"`
-	Links       map[string]string    `json:"links"`
-	CreatedAt   time.Time            `json:"createdAt"`
-	UpdatedAt   time.Time            `json:"updatedAt"`
+	ID             platform.ID          `json:"id"`
+	Token          string               `json:"token"`
+	Status         platform.Status      `json:"status"`
+	Description    string               `json:"description"`
+	OrgID          platform.ID          `json:"orgID"`
+	Org            string               `json:"org"`
+	UserID         platform.ID          `json:"userID"`
+	User           string               `json:"user"`
+	Permissions    []permissionResponse `json:"permissions"`
+	Scopes         []string             `json:"scopes,omitempty"`
+	MaxBytesPerDay int64                `json:"maxBytesPerDay,omitempty"`
+	Links          map[string]string    `json:"links"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt"`
 }
 
 func newAuthResponse(a *platform.Authorization, org *platform.Organization, user *platform.User, ps []permissionResponse) *authResponse {
 	res := &authResponse{
-		ID:          a.ID,
-		Token:       a.Token,
-		Status:      a.Status,
-		Description: a.Description,
-		OrgID:       a.OrgID,
-		UserID:      a.UserID,
-		User:        user.Name,
-		Org:         org.Name,
-		Permissions: ps,
+		ID:             a.ID,
+		Token:          a.Token,
+		Status:         a.Status,
+		Description:    a.Description,
+		OrgID:          a.OrgID,
+		UserID:         a.UserID,
+		User:           user.Name,
+		Org:            org.Name,
+		Permissions:    ps,
+		Scopes:         a.Scopes,
+		MaxBytesPerDay: a.MaxBytesPerDay,
 		Links: map[string]string{
 			"self": fmt.Sprintf("/api/v2/authorizations/%s", a.ID),
 			"user": fmt.Sprintf("/api/v2/users/%s", a.UserID),
@@ -113,12 +126,14 @@ func newAuthResponse(a *platform.Authorization, org *platform.Organization, user
 
 func (a *authResponse) toPlatform() *platform.Authorization {
 	res := &platform.Authorization{
-		ID:          a.ID,
-		Token:       a.Token,
-		Status:      a.Status,
-		Description: a.Description,
-		OrgID:       a.OrgID,
-		UserID:      a.UserID,
+		ID:             a.ID,
+		Token:          a.Token,
+		Status:         a.Status,
+		Description:    a.Description,
+		OrgID:          a.OrgID,
+		UserID:         a.UserID,
+		Scopes:         a.Scopes,
+		MaxBytesPerDay: a.MaxBytesPerDay,
 		CRUDLog: platform.CRUDLog{
 			CreatedAt: a.CreatedAt,
 			UpdatedAt: a.UpdatedAt,
@@ -137,7 +152,6 @@ type permissionResponse struct {
 
 type resourceResponse struct {
 	platform.Resource
-	Name         string `json:"name,omitempty"`
 	Organization string `json:"org,omitempty"`
 }
 
@@ -220,6 +234,16 @@ func (h *AuthorizationHandler) handlePostAuthorization(w http.ResponseWriter, r
 		return
 	}
 
+	// scopes are expanded into the permissions they grant now, so that a
+	// later change to the scope mapping cannot widen the authorization. The
+	// requester must hold them as it must any other permission.
+	scopePerms, err := h.ScopeMapping.Permissions(auth.OrgID, auth.Scopes)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	auth.Permissions = append(auth.Permissions, scopePerms...)
+
 	if err := h.AuthorizationService.CreateAuthorization(ctx, auth); err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
@@ -240,29 +264,35 @@ func (h *AuthorizationHandler) handlePostAuthorization(w http.ResponseWriter, r
 }
 
 type postAuthorizationRequest struct {
-	Status      platform.Status       `json:"status"`
-	OrgID       platform.ID           `json:"orgID"`
-	UserID      *platform.ID          `json:"userID,omitempty"`
-	Description string                `json:"description"`
-	Permissions []platform.Permission `json:"permissions"`
+	Status         platform.Status       `json:"status"`
+	OrgID          platform.ID           `json:"orgID"`
+	UserID         *platform.ID          `json:"userID,omitempty"`
+	Description    string                `json:"description"`
+	Permissions    []platform.Permission `json:"permissions"`
+	Scopes         []string              `json:"scopes,omitempty"`
+	MaxBytesPerDay int64                 `json:"maxBytesPerDay,omitempty"`
 }
 
 func (p *postAuthorizationRequest) toPlatform(userID platform.ID) *platform.Authorization {
 	return &platform.Authorization{
-		OrgID:       p.OrgID,
-		Status:      p.Status,
-		Description: p.Description,
-		Permissions: p.Permissions,
-		UserID:      userID,
+		OrgID:          p.OrgID,
+		Status:         p.Status,
+		Description:    p.Description,
+		Permissions:    p.Permissions,
+		Scopes:         p.Scopes,
+		UserID:         userID,
+		MaxBytesPerDay: p.MaxBytesPerDay,
 	}
 }
 
 func newPostAuthorizationRequest(a *platform.Authorization) (*postAuthorizationRequest, error) {
 	res := &postAuthorizationRequest{
-		OrgID:       a.OrgID,
-		Description: a.Description,
-		Permissions: a.Permissions,
-		Status:      a.Status,
+		OrgID:          a.OrgID,
+		Description:    a.Description,
+		Permissions:    a.Permissions,
+		Scopes:         a.Scopes,
+		Status:         a.Status,
+		MaxBytesPerDay: a.MaxBytesPerDay,
 	}
 
 	if a.UserID.Valid() {
@@ -281,10 +311,10 @@ func (p *postAuthorizationRequest) SetDefaults() {
 }
 
 func (p *postAuthorizationRequest) Validate() error {
-	if len(p.Permissions) == 0 {
+	if len(p.Permissions) == 0 && len(p.Scopes) == 0 {
 		return &platform.Error{
 			Code: platform.EInvalid,
-			Msg:  "authorization must include permissions",
+			Msg:  "authorization must include permissions or scopes",
 		}
 	}
 
@@ -304,6 +334,13 @@ func (p *postAuthorizationRequest) Validate() error {
 		}
 	}
 
+	if p.MaxBytesPerDay < 0 {
+		return &platform.Error{
+			Code: platform.EInvalid,
+			Msg:  "max bytes per day must not be negative",
+		}
+	}
+
 	if p.Status == "" {
 		p.Status = platform.Active
 	}
@@ -8,10 +8,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/influxdata/httprouter"
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
 	pcontext "github.com/influxdata/influxdb/context"
 	"github.com/influxdata/influxdb/inmem"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
@@ -741,6 +743,84 @@ func TestService_handlePostAuthorization(t *testing.T) {
 	}
 }
 
+func TestService_handlePostAuthorization_scopes(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &platform.Organization{Name: "o1"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &platform.User{Name: "u1"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	authorizationBackend := NewMockAuthorizationBackend(t)
+	authorizationBackend.HTTPErrorHandler = kithttp.ErrorHandler(0)
+	authorizationBackend.AuthorizationService = authorizer.NewAuthorizationService(svc)
+	authorizationBackend.UserService = svc
+	authorizationBackend.OrganizationService = svc
+	authorizationBackend.LookupService = svc
+	h := NewAuthorizationHandler(zaptest.NewLogger(t), authorizationBackend)
+
+	session := &platform.Authorization{
+		Status: platform.Active,
+		UserID: user.ID,
+		OrgID:  org.ID,
+		Permissions: []platform.Permission{
+			{Action: platform.WriteAction, Resource: platform.Resource{Type: platform.AuthorizationsResourceType, OrgID: &org.ID}},
+			{Action: platform.WriteAction, Resource: platform.Resource{Type: platform.UsersResourceType, ID: &user.ID}},
+			{Action: platform.ReadAction, Resource: platform.Resource{Type: platform.BucketsResourceType, OrgID: &org.ID}},
+		},
+	}
+
+	post := func(scopes ...string) *httptest.ResponseRecorder {
+		b, err := json.Marshal(map[string]interface{}{"orgID": org.ID, "scopes": scopes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", "http://any.url", bytes.NewReader(b))
+		r = r.WithContext(pcontext.SetAuthorizer(ctx, session))
+		w := httptest.NewRecorder()
+		h.handlePostAuthorization(w, r)
+		return w
+	}
+
+	t.Run("scopes are stored as permissions", func(t *testing.T) {
+		w := post("read:buckets")
+		if got, want := w.Code, http.StatusCreated; got != want {
+			t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+		}
+
+		var res authResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		a, err := svc.FindAuthorizationByID(ctx, res.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []platform.Permission{
+			{Action: platform.ReadAction, Resource: platform.Resource{Type: platform.BucketsResourceType, OrgID: &org.ID}},
+		}
+		if !reflect.DeepEqual(a.Permissions, want) {
+			t.Errorf("unexpected permissions: got %v want %v", a.Permissions, want)
+		}
+	})
+
+	t.Run("scopes the requester does not hold are forbidden", func(t *testing.T) {
+		if got, want := post("write:buckets").Code, http.StatusForbidden; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+	})
+}
+
 func TestService_handleDeleteAuthorization(t *testing.T) {
 	type fields struct {
 		AuthorizationService platform.AuthorizationService
@@ -875,6 +955,9 @@ func initAuthorizationService(f platformtesting.AuthorizationFields, t *testing.
 	svc.TimeGenerator = f.TimeGenerator
 
 	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
 
 	for _, u := range f.Users {
 		if err := svc.PutUser(ctx, u); err != nil {
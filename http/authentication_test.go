@@ -2,18 +2,23 @@ package http_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	influxdb "github.com/influxdata/influxdb"
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
 	platformhttp "github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/inmem"
 	"github.com/influxdata/influxdb/jsonweb"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/kv"
 	"github.com/influxdata/influxdb/mock"
 	"go.uber.org/zap/zaptest"
 )
@@ -397,3 +402,102 @@ func TestAuthenticationHandler_NoAuthRoutes(t *testing.T) {
 		})
 	}
 }
+
+// TestAuthenticationHandler_Scopes creates a token with a scope through the
+// authorizations API and uses it with the buckets API, both behind the
+// authentication middleware.
+func TestAuthenticationHandler_Scopes(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "user", Status: influxdb.Active}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	operator := &influxdb.Authorization{
+		OrgID:       org.ID,
+		UserID:      user.ID,
+		Permissions: append(influxdb.OwnerPermissions(org.ID), influxdb.MePermissions(user.ID)...),
+	}
+	if err := svc.CreateAuthorization(ctx, operator); err != nil {
+		t.Fatal(err)
+	}
+
+	apiBackend := &platformhttp.APIBackend{
+		HTTPErrorHandler:           kithttp.ErrorHandler(0),
+		AuthorizationService:       authorizer.NewAuthorizationService(svc),
+		BucketService:              authorizer.NewBucketService(svc, svc),
+		UserResourceMappingService: svc,
+		LabelService:               svc,
+		UserService:                svc,
+		OrganizationService:        svc,
+		LookupService:              svc,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/api/v2/authorizations", platformhttp.NewAuthorizationHandler(zaptest.NewLogger(t), platformhttp.NewAuthorizationBackend(zaptest.NewLogger(t), apiBackend)))
+	mux.Handle("/api/v2/buckets", platformhttp.NewBucketHandler(zaptest.NewLogger(t), platformhttp.NewBucketBackend(zaptest.NewLogger(t), apiBackend)))
+
+	h := platformhttp.NewAuthenticationHandler(zaptest.NewLogger(t), kithttp.ErrorHandler(0))
+	h.Handler = mux
+	h.AuthorizationService = svc
+	h.SessionService = mock.NewSessionService()
+	h.UserService = svc
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://any.url"+path, strings.NewReader(body))
+		platformhttp.SetToken(token, r)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("POST", "/api/v2/authorizations", operator.Token, fmt.Sprintf(`{"orgID": %q, "scopes": ["read:buckets"]}`, org.ID))
+	if got, want := w.Code, http.StatusCreated; got != want {
+		t.Fatalf("unexpected status code creating authorization: got %d want %d: %s", got, want, w.Body.String())
+	}
+	var scoped struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&scoped); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{
+			name:   "read scope allows listing buckets",
+			method: "GET",
+			path:   "/api/v2/buckets?orgID=" + org.ID.String(),
+			code:   http.StatusOK,
+		},
+		{
+			name:   "read scope does not allow creating buckets",
+			method: "POST",
+			path:   "/api/v2/buckets",
+			body:   fmt.Sprintf(`{"orgID": %q, "name": "metrics"}`, org.ID),
+			code:   http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.method, tt.path, scoped.Token, tt.body)
+			if got, want := w.Code, tt.code; got != want {
+				t.Errorf("expected status code to be %d got %d: %s", want, got, w.Body.String())
+			}
+		})
+	}
+}
@@ -28,6 +28,9 @@ type BucketBackend struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	BucketCardinalityService   influxdb.BucketCardinalityService
+	BucketSizeService          influxdb.BucketSizeService
+	BucketMetadataService      influxdb.BucketMetadataService
 }
 
 // NewBucketBackend returns a new instance of BucketBackend.
@@ -42,6 +45,9 @@ func NewBucketBackend(log *zap.Logger, b *APIBackend) *BucketBackend {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		OrganizationService:        b.OrganizationService,
+		BucketCardinalityService:   b.BucketCardinalityService,
+		BucketSizeService:          b.BucketSizeService,
+		BucketMetadataService:      b.BucketMetadataService,
 	}
 }
 
@@ -57,18 +63,23 @@ type BucketHandler struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	BucketCardinalityService   influxdb.BucketCardinalityService
+	BucketSizeService          influxdb.BucketSizeService
+	BucketMetadataService      influxdb.BucketMetadataService
 }
 
 const (
-	prefixBuckets          = "/api/v2/buckets"
-	bucketsIDPath          = "/api/v2/buckets/:id"
-	bucketsIDLogPath       = "/api/v2/buckets/:id/logs"
-	bucketsIDMembersPath   = "/api/v2/buckets/:id/members"
-	bucketsIDMembersIDPath = "/api/v2/buckets/:id/members/:userID"
-	bucketsIDOwnersPath    = "/api/v2/buckets/:id/owners"
-	bucketsIDOwnersIDPath  = "/api/v2/buckets/:id/owners/:userID"
-	bucketsIDLabelsPath    = "/api/v2/buckets/:id/labels"
-	bucketsIDLabelsIDPath  = "/api/v2/buckets/:id/labels/:lid"
+	prefixBuckets            = "/api/v2/buckets"
+	bucketsIDPath            = "/api/v2/buckets/:id"
+	bucketsIDLogPath         = "/api/v2/buckets/:id/logs"
+	bucketsIDCardinalityPath = "/api/v2/buckets/:id/cardinality"
+	bucketsIDSizePath        = "/api/v2/buckets/:id/size"
+	bucketsIDMembersPath     = "/api/v2/buckets/:id/members"
+	bucketsIDMembersIDPath   = "/api/v2/buckets/:id/members/:userID"
+	bucketsIDOwnersPath      = "/api/v2/buckets/:id/owners"
+	bucketsIDOwnersIDPath    = "/api/v2/buckets/:id/owners/:userID"
+	bucketsIDLabelsPath      = "/api/v2/buckets/:id/labels"
+	bucketsIDLabelsIDPath    = "/api/v2/buckets/:id/labels/:lid"
 )
 
 // NewBucketHandler returns a new instance of BucketHandler.
@@ -84,12 +95,21 @@ func NewBucketHandler(log *zap.Logger, b *BucketBackend) *BucketHandler {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		OrganizationService:        b.OrganizationService,
+		BucketCardinalityService:   b.BucketCardinalityService,
+		BucketSizeService:          b.BucketSizeService,
+		BucketMetadataService:      b.BucketMetadataService,
 	}
 
 	h.HandlerFunc("POST", prefixBuckets, h.handlePostBucket)
 	h.HandlerFunc("GET", prefixBuckets, h.handleGetBuckets)
 	h.HandlerFunc("GET", bucketsIDPath, h.handleGetBucket)
 	h.HandlerFunc("GET", bucketsIDLogPath, h.handleGetBucketLog)
+	if h.BucketCardinalityService != nil {
+		h.HandlerFunc("GET", bucketsIDCardinalityPath, h.handleGetBucketCardinality)
+	}
+	if h.BucketSizeService != nil {
+		h.HandlerFunc("GET", bucketsIDSizePath, h.handleGetBucketSize)
+	}
 	h.HandlerFunc("PATCH", bucketsIDPath, h.handlePatchBucket)
 	h.HandlerFunc("DELETE", bucketsIDPath, h.handleDeleteBucket)
 
@@ -218,9 +238,10 @@ func newBucket(pb *influxdb.Bucket) *bucket {
 
 // bucketUpdate is used for serialization/deserialization with retention rules.
 type bucketUpdate struct {
-	Name           *string         `json:"name,omitempty"`
-	Description    *string         `json:"description,omitempty"`
-	RetentionRules []retentionRule `json:"retentionRules,omitempty"`
+	Name           *string           `json:"name,omitempty"`
+	Description    *string           `json:"description,omitempty"`
+	RetentionRules []retentionRule   `json:"retentionRules,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
 }
 
 func (b *bucketUpdate) OK() error {
@@ -274,8 +295,9 @@ func newBucketUpdate(pb *influxdb.BucketUpdate) *bucketUpdate {
 
 type bucketResponse struct {
 	bucket
-	Links  map[string]string `json:"links"`
-	Labels []influxdb.Label  `json:"labels"`
+	Links    map[string]string `json:"links"`
+	Labels   []influxdb.Label  `json:"labels"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 func NewBucketResponse(b *influxdb.Bucket, labels []*influxdb.Label) *bucketResponse {
@@ -413,6 +435,7 @@ func (h *BucketHandler) handleGetBucket(w http.ResponseWriter, r *http.Request)
 
 	h.log.Debug("Bucket retrieved", zap.String("bucket", fmt.Sprint(b)))
 
+	kithttp.SetETag(w, b.UpdatedAt)
 	h.api.Respond(w, http.StatusOK, NewBucketResponse(b, labels))
 }
 
@@ -458,6 +481,74 @@ func newBucketLogResponse(id influxdb.ID, es []*influxdb.OperationLogEntry) *ope
 	}
 }
 
+type bucketCardinalityResponse struct {
+	BucketID    influxdb.ID `json:"bucketID"`
+	SeriesCount int64       `json:"seriesCount"`
+}
+
+// handleGetBucketCardinality is the HTTP handler for the GET /api/v2/buckets/:id/cardinality route.
+func (h *BucketHandler) handleGetBucketCardinality(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	// finding the bucket ensures the requester may read it.
+	b, err := h.BucketService.FindBucketByID(ctx, id)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	n, err := h.BucketCardinalityService.SeriesCount(ctx, b.OrgID, b.ID)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, bucketCardinalityResponse{
+		BucketID:    b.ID,
+		SeriesCount: n,
+	})
+}
+
+type bucketSizeResponse struct {
+	BucketID influxdb.ID `json:"bucketID"`
+	Size     int64       `json:"size"`
+}
+
+// handleGetBucketSize is the HTTP handler for the GET /api/v2/buckets/:id/size route.
+func (h *BucketHandler) handleGetBucketSize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	// finding the bucket ensures the requester may read it.
+	b, err := h.BucketService.FindBucketByID(ctx, id)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	size, err := h.BucketSizeService.EstimatedCompressedSize(ctx, b.OrgID, b.ID)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, bucketSizeResponse{
+		BucketID: b.ID,
+		Size:     size,
+	})
+}
+
 // handleDeleteBucket is the HTTP handler for the DELETE /api/v2/buckets/:id route.
 func (h *BucketHandler) handleDeleteBucket(w http.ResponseWriter, r *http.Request) {
 	id, err := decodeIDFromCtx(r.Context(), "id")
@@ -578,25 +669,62 @@ func (h *BucketHandler) handlePatchBucket(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if reqBody.Name != nil {
+	if reqBody.Name != nil || kithttp.HasIfMatch(r) {
 		b, err := h.BucketService.FindBucketByID(r.Context(), id)
 		if err != nil {
 			h.api.Err(w, err)
 			return
 		}
-		b.Name = *reqBody.Name
-		if err := validBucketName(b); err != nil {
+		if err := kithttp.CheckIfMatch(r, b.UpdatedAt); err != nil {
 			h.api.Err(w, err)
 			return
 		}
+		if reqBody.Name != nil {
+			b.Name = *reqBody.Name
+			if err := validBucketName(b); err != nil {
+				h.api.Err(w, err)
+				return
+			}
+		}
 	}
 
-	b, err := h.BucketService.UpdateBucket(r.Context(), id, *reqBody.toInfluxDB())
+	if reqBody.Metadata != nil && h.BucketMetadataService == nil {
+		h.api.Err(w, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "bucket metadata is not supported",
+		})
+		return
+	}
+
+	upd := reqBody.toInfluxDB()
+	if reqBody.Name == nil && reqBody.Description == nil && len(reqBody.RetentionRules) == 0 {
+		// Only the metadata is updated, which must not reset the retention
+		// period of the bucket.
+		upd.RetentionPeriod = nil
+	}
+
+	// The bucket is updated even if only its metadata is, which ensures the
+	// requester may write it.
+	b, err := h.BucketService.UpdateBucket(r.Context(), id, *upd)
 	if err != nil {
 		h.api.Err(w, err)
 		return
 	}
 
+	var metadata map[string]string
+	if h.BucketMetadataService != nil {
+		if reqBody.Metadata != nil {
+			if err := h.BucketMetadataService.WriteMetadata(r.Context(), b.OrgID, b.ID, reqBody.Metadata); err != nil {
+				h.api.Err(w, err)
+				return
+			}
+		}
+		if metadata, err = h.BucketMetadataService.ReadMetadata(r.Context(), b.OrgID, b.ID); err != nil {
+			h.api.Err(w, err)
+			return
+		}
+	}
+
 	// TODO: should move to service to encapsulate labels and what any other dependencies. Future
 	// 	work for service definition
 	labels, err := h.LabelService.FindResourceLabels(r.Context(), influxdb.LabelMappingFilter{
@@ -609,7 +737,13 @@ func (h *BucketHandler) handlePatchBucket(w http.ResponseWriter, r *http.Request
 	}
 	h.log.Debug("Bucket updated", zap.String("bucket", fmt.Sprint(b)))
 
-	h.api.Respond(w, http.StatusOK, NewBucketResponse(b, labels))
+	res := NewBucketResponse(b, labels)
+	if len(metadata) > 0 {
+		res.Metadata = metadata
+	}
+
+	kithttp.SetETag(w, b.UpdatedAt)
+	h.api.Respond(w, http.StatusOK, res)
 }
 
 // BucketService connects to Influx via HTTP using tokens to manage buckets
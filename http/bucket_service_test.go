@@ -8,9 +8,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
 	platform "github.com/influxdata/influxdb"
@@ -367,6 +369,165 @@ func TestService_handleGetBucket(t *testing.T) {
 	}
 }
 
+func TestService_handleGetBucketCardinality(t *testing.T) {
+	bucketID := platformtesting.MustIDBase16("020f755c3c082000")
+	orgID := platformtesting.MustIDBase16("020f755c3c082001")
+
+	type fields struct {
+		BucketService            platform.BucketService
+		BucketCardinalityService platform.BucketCardinalityService
+	}
+	type wants struct {
+		statusCode int
+		body       string
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		wants  wants
+	}{
+		{
+			name: "get series count of a bucket",
+			fields: fields{
+				BucketService: &mock.BucketService{
+					FindBucketByIDFn: func(ctx context.Context, id platform.ID) (*platform.Bucket, error) {
+						return &platform.Bucket{ID: id, OrgID: orgID, Name: "hello"}, nil
+					},
+				},
+				BucketCardinalityService: &mock.BucketCardinalityService{
+					SeriesCountFn: func(ctx context.Context, oid, bid platform.ID) (int64, error) {
+						if oid != orgID || bid != bucketID {
+							return 0, fmt.Errorf("unexpected org %s or bucket %s", oid, bid)
+						}
+						return 100, nil
+					},
+				},
+			},
+			wants: wants{
+				statusCode: http.StatusOK,
+				body:       `{"bucketID": "020f755c3c082000", "seriesCount": 100}`,
+			},
+		},
+		{
+			name: "bucket not found",
+			fields: fields{
+				BucketService: &mock.BucketService{
+					FindBucketByIDFn: func(ctx context.Context, id platform.ID) (*platform.Bucket, error) {
+						return nil, &platform.Error{
+							Code: platform.ENotFound,
+							Msg:  "bucket not found",
+						}
+					},
+				},
+				BucketCardinalityService: &mock.BucketCardinalityService{},
+			},
+			wants: wants{
+				statusCode: http.StatusNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucketBackend := NewMockBucketBackend(t)
+			bucketBackend.HTTPErrorHandler = kithttp.ErrorHandler(0)
+			bucketBackend.BucketService = tt.fields.BucketService
+			bucketBackend.BucketCardinalityService = tt.fields.BucketCardinalityService
+			h := NewBucketHandler(zaptest.NewLogger(t), bucketBackend)
+
+			r := httptest.NewRequest("GET", "http://any.url/api/v2/buckets/020f755c3c082000/cardinality", nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wants.statusCode {
+				t.Errorf("%q. handleGetBucketCardinality() = %v, want %v", tt.name, res.StatusCode, tt.wants.statusCode)
+			}
+			if tt.wants.body != "" {
+				if eq, diff, err := jsonEqual(string(body), tt.wants.body); err != nil {
+					t.Errorf("%q, handleGetBucketCardinality(). error unmarshaling json %v", tt.name, err)
+				} else if !eq {
+					t.Errorf("%q. handleGetBucketCardinality() = ***%s***", tt.name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestService_handleGetBucketSize(t *testing.T) {
+	bucketID := platformtesting.MustIDBase16("020f755c3c082000")
+	orgID := platformtesting.MustIDBase16("020f755c3c082001")
+
+	bucketBackend := NewMockBucketBackend(t)
+	bucketBackend.HTTPErrorHandler = kithttp.ErrorHandler(0)
+	bucketBackend.BucketService = &mock.BucketService{
+		FindBucketByIDFn: func(ctx context.Context, id platform.ID) (*platform.Bucket, error) {
+			if id != bucketID {
+				return nil, &platform.Error{
+					Code: platform.ENotFound,
+					Msg:  "bucket not found",
+				}
+			}
+			return &platform.Bucket{ID: id, OrgID: orgID, Name: "hello"}, nil
+		},
+	}
+	bucketBackend.BucketSizeService = &mock.BucketSizeService{
+		EstimatedCompressedSizeFn: func(ctx context.Context, oid, bid platform.ID) (int64, error) {
+			if oid != orgID || bid != bucketID {
+				return 0, fmt.Errorf("unexpected org %s or bucket %s", oid, bid)
+			}
+			return 4096, nil
+		},
+	}
+	h := NewBucketHandler(zaptest.NewLogger(t), bucketBackend)
+
+	tests := []struct {
+		name       string
+		bucketID   string
+		statusCode int
+		body       string
+	}{
+		{
+			name:       "get size of a bucket",
+			bucketID:   "020f755c3c082000",
+			statusCode: http.StatusOK,
+			body:       `{"bucketID": "020f755c3c082000", "size": 4096}`,
+		},
+		{
+			name:       "bucket not found",
+			bucketID:   "020f755c3c082002",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://any.url/api/v2/buckets/"+tt.bucketID+"/size", nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+
+			if res.StatusCode != tt.statusCode {
+				t.Errorf("%q. handleGetBucketSize() = %v, want %v", tt.name, res.StatusCode, tt.statusCode)
+			}
+			if tt.body != "" {
+				if eq, diff, err := jsonEqual(string(body), tt.body); err != nil {
+					t.Errorf("%q, handleGetBucketSize(). error unmarshaling json %v", tt.name, err)
+				} else if !eq {
+					t.Errorf("%q. handleGetBucketSize() = ***%s***", tt.name, diff)
+				}
+			}
+		})
+	}
+}
+
 func TestService_handlePostBucket(t *testing.T) {
 	type fields struct {
 		BucketService       platform.BucketService
@@ -985,6 +1146,103 @@ func TestService_handlePatchBucket(t *testing.T) {
 	}
 }
 
+func TestService_handlePatchBucketMetadata(t *testing.T) {
+	bucketID := platformtesting.MustIDBase16("020f755c3c082000")
+	orgID := platformtesting.MustIDBase16("020f755c3c082001")
+
+	stored := make(map[string]string)
+	bucketBackend := NewMockBucketBackend(t)
+	bucketBackend.HTTPErrorHandler = kithttp.ErrorHandler(0)
+	bucketBackend.BucketService = &mock.BucketService{
+		UpdateBucketFn: func(ctx context.Context, id platform.ID, upd platform.BucketUpdate) (*platform.Bucket, error) {
+			if id != bucketID {
+				return nil, &platform.Error{
+					Code: platform.ENotFound,
+					Msg:  "bucket not found",
+				}
+			}
+			if upd.RetentionPeriod != nil {
+				return nil, fmt.Errorf("unexpected update of retention period to %v", *upd.RetentionPeriod)
+			}
+			return &platform.Bucket{ID: id, OrgID: orgID, Name: "hello"}, nil
+		},
+	}
+	bucketBackend.BucketMetadataService = &mock.BucketMetadataService{
+		WriteMetadataFn: func(ctx context.Context, oid, bid platform.ID, metadata map[string]string) error {
+			if oid != orgID || bid != bucketID {
+				return fmt.Errorf("unexpected org %s or bucket %s", oid, bid)
+			}
+			stored = metadata
+			return nil
+		},
+		ReadMetadataFn: func(ctx context.Context, oid, bid platform.ID) (map[string]string, error) {
+			return stored, nil
+		},
+	}
+	h := NewBucketHandler(zaptest.NewLogger(t), bucketBackend)
+
+	tests := []struct {
+		name       string
+		bucketID   string
+		body       string
+		statusCode int
+		metadata   map[string]string
+	}{
+		{
+			name:       "write metadata of a bucket",
+			bucketID:   "020f755c3c082000",
+			body:       `{"metadata": {"owner": "team-infra", "sla": "99.9%"}}`,
+			statusCode: http.StatusOK,
+			metadata:   map[string]string{"owner": "team-infra", "sla": "99.9%"},
+		},
+		{
+			name:       "remove metadata of a bucket",
+			bucketID:   "020f755c3c082000",
+			body:       `{"metadata": {}}`,
+			statusCode: http.StatusOK,
+			metadata:   map[string]string{},
+		},
+		{
+			name:       "bucket not found",
+			bucketID:   "020f755c3c082002",
+			body:       `{"metadata": {"owner": "team-infra"}}`,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "http://any.url/api/v2/buckets/"+tt.bucketID, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+
+			if res.StatusCode != tt.statusCode {
+				t.Fatalf("%q. handlePatchBucket() = %v, want %v: %s", tt.name, res.StatusCode, tt.statusCode, body)
+			}
+			if tt.metadata == nil {
+				return
+			}
+
+			if !cmp.Equal(stored, tt.metadata) {
+				t.Errorf("%q. handlePatchBucket() stored metadata -got/+want %s", tt.name, cmp.Diff(stored, tt.metadata))
+			}
+			var resBody struct {
+				Metadata map[string]string `json:"metadata"`
+			}
+			if err := json.Unmarshal(body, &resBody); err != nil {
+				t.Fatal(err)
+			}
+			if len(resBody.Metadata)+len(tt.metadata) > 0 && !cmp.Equal(resBody.Metadata, tt.metadata) {
+				t.Errorf("%q. handlePatchBucket() response metadata = %v, want %v", tt.name, resBody.Metadata, tt.metadata)
+			}
+		})
+	}
+}
+
 func TestService_handlePostBucketMember(t *testing.T) {
 	type fields struct {
 		UserService platform.UserService
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/zap"
+)
+
+// DefragmentBackend is all services and associated parameters required to construct
+// the DefragmentHandler.
+type DefragmentBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	DefragmentService   influxdb.DefragmentService
+	BucketService       influxdb.BucketService
+	OrganizationService influxdb.OrganizationService
+}
+
+// NewDefragmentBackend returns a new instance of DefragmentBackend.
+func NewDefragmentBackend(log *zap.Logger, b *APIBackend) *DefragmentBackend {
+	return &DefragmentBackend{
+		log: log,
+
+		HTTPErrorHandler:    b.HTTPErrorHandler,
+		DefragmentService:   b.DefragmentService,
+		BucketService:       b.BucketService,
+		OrganizationService: b.OrganizationService,
+	}
+}
+
+// DefragmentHandler receives requests to defragment the stored data of a bucket.
+type DefragmentHandler struct {
+	influxdb.HTTPErrorHandler
+	*httprouter.Router
+
+	log *zap.Logger
+
+	DefragmentService   influxdb.DefragmentService
+	BucketService       influxdb.BucketService
+	OrganizationService influxdb.OrganizationService
+}
+
+const (
+	prefixDefragment = "/api/v2/engine/defragment"
+)
+
+// NewDefragmentHandler creates a new handler at /api/v2/engine/defragment to receive
+// defragment requests.
+func NewDefragmentHandler(log *zap.Logger, b *DefragmentBackend) *DefragmentHandler {
+	h := &DefragmentHandler{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		Router:           NewRouter(b.HTTPErrorHandler),
+		log:              log,
+
+		DefragmentService:   b.DefragmentService,
+		BucketService:       b.BucketService,
+		OrganizationService: b.OrganizationService,
+	}
+
+	h.HandlerFunc(http.MethodPost, prefixDefragment, h.handleDefragment)
+	return h
+}
+
+func (h *DefragmentHandler) handleDefragment(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "DefragmentHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	org, err := queryOrganization(ctx, r, h.OrganizationService)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	bucket, err := queryBucket(ctx, r, h.BucketService)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.DefragmentService.Defragment(ctx, org.ID, bucket.ID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Defragmented",
+		zap.String("orgID", org.ID.String()),
+		zap.String("bucketID", bucket.ID.String()),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	pcontext "github.com/influxdata/influxdb/context"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+type defragmentServiceFunc func(ctx context.Context, orgID, bucketID influxdb.ID) error
+
+func (fn defragmentServiceFunc) Defragment(ctx context.Context, orgID, bucketID influxdb.ID) error {
+	return fn(ctx, orgID, bucketID)
+}
+
+func TestDefragment(t *testing.T) {
+	const orgID, bucketID = influxdb.ID(1), influxdb.ID(2)
+
+	tests := []struct {
+		name       string
+		authorizer influxdb.Authorizer
+		statusCode int
+		called     bool
+	}{
+		{
+			name:       "operator",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OperPermissions()},
+			statusCode: http.StatusNoContent,
+			called:     true,
+		},
+		{
+			name:       "bucket owner",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OwnerPermissions(orgID)},
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			svc := defragmentServiceFunc(func(ctx context.Context, gotOrgID, gotBucketID influxdb.ID) error {
+				if gotOrgID != orgID || gotBucketID != bucketID {
+					t.Errorf("unexpected bucket defragmented: org %s bucket %s", gotOrgID, gotBucketID)
+				}
+				called = true
+				return nil
+			})
+
+			b := &DefragmentBackend{
+				log:               zaptest.NewLogger(t),
+				HTTPErrorHandler:  kithttp.ErrorHandler(0),
+				DefragmentService: authorizer.NewDefragmentService(svc),
+				OrganizationService: &mock.OrganizationService{
+					FindOrganizationF: func(ctx context.Context, f influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+						return &influxdb.Organization{ID: orgID, Name: "org1"}, nil
+					},
+				},
+				BucketService: &mock.BucketService{
+					FindBucketFn: func(ctx context.Context, f influxdb.BucketFilter) (*influxdb.Bucket, error) {
+						return &influxdb.Bucket{ID: bucketID, OrgID: orgID, Name: "bucket1"}, nil
+					},
+				},
+			}
+			h := NewDefragmentHandler(zaptest.NewLogger(t), b)
+
+			r := httptest.NewRequest("POST", "http://any.tld"+prefixDefragment+"?org=org1&bucket=bucket1", nil)
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), tt.authorizer))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != tt.statusCode {
+				t.Errorf("got status code %d, exp %d", got, tt.statusCode)
+			}
+			if called != tt.called {
+				t.Errorf("got called %v, exp %v", called, tt.called)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/zap"
+)
+
+// EngineStatsBackend is all services and associated parameters required to construct
+// the EngineStatsHandler.
+type EngineStatsBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	EngineStatsService influxdb.EngineStatsService
+}
+
+// NewEngineStatsBackend returns a new instance of EngineStatsBackend.
+func NewEngineStatsBackend(log *zap.Logger, b *APIBackend) *EngineStatsBackend {
+	return &EngineStatsBackend{
+		log: log,
+
+		HTTPErrorHandler:   b.HTTPErrorHandler,
+		EngineStatsService: b.EngineStatsService,
+	}
+}
+
+// EngineStatsHandler reports the internal statistics of the storage engine.
+type EngineStatsHandler struct {
+	influxdb.HTTPErrorHandler
+	*httprouter.Router
+
+	log *zap.Logger
+
+	EngineStatsService influxdb.EngineStatsService
+}
+
+const (
+	prefixEngineStats = "/api/v2/engine/stats"
+)
+
+// NewEngineStatsHandler creates a new handler at /api/v2/engine/stats to
+// report the statistics of the storage engine.
+func NewEngineStatsHandler(log *zap.Logger, b *EngineStatsBackend) *EngineStatsHandler {
+	h := &EngineStatsHandler{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		Router:           NewRouter(b.HTTPErrorHandler),
+		log:              log,
+
+		EngineStatsService: b.EngineStatsService,
+	}
+
+	h.HandlerFunc(http.MethodGet, prefixEngineStats, h.handleGetEngineStats)
+	return h
+}
+
+func (h *EngineStatsHandler) handleGetEngineStats(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "EngineStatsHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	stats, err := h.EngineStatsService.EngineStats(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, stats); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	pcontext "github.com/influxdata/influxdb/context"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"go.uber.org/zap/zaptest"
+)
+
+type engineStatsServiceFunc func(ctx context.Context) (*influxdb.EngineStats, error)
+
+func (fn engineStatsServiceFunc) EngineStats(ctx context.Context) (*influxdb.EngineStats, error) {
+	return fn(ctx)
+}
+
+func TestEngineStats(t *testing.T) {
+	stats := &influxdb.EngineStats{
+		CacheSize:               4096,
+		CacheCount:              3,
+		OpenTSMFiles:            2,
+		CompactionLevel1Running: 1,
+		WALSegments:             1,
+		WALSizeBytes:            512,
+	}
+
+	tests := []struct {
+		name       string
+		authorizer influxdb.Authorizer
+		statusCode int
+	}{
+		{
+			name:       "operator",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OperPermissions()},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "org owner",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OwnerPermissions(1)},
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := engineStatsServiceFunc(func(ctx context.Context) (*influxdb.EngineStats, error) {
+				return stats, nil
+			})
+
+			b := &EngineStatsBackend{
+				log:                zaptest.NewLogger(t),
+				HTTPErrorHandler:   kithttp.ErrorHandler(0),
+				EngineStatsService: authorizer.NewEngineStatsService(svc),
+			}
+			h := NewEngineStatsHandler(zaptest.NewLogger(t), b)
+
+			r := httptest.NewRequest("GET", "http://any.tld"+prefixEngineStats, nil)
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), tt.authorizer))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != tt.statusCode {
+				t.Fatalf("got status code %d, exp %d", got, tt.statusCode)
+			}
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var got influxdb.EngineStats
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != *stats {
+				t.Fatalf("got stats %+v, exp %+v", got, *stats)
+			}
+		})
+	}
+}
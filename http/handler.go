@@ -2,11 +2,13 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	_ "net/http/pprof" // used for debug pprof at the default path.
 
 	"github.com/go-chi/chi"
+	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/prom"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/prometheus/client_golang/prometheus"
@@ -86,6 +88,21 @@ func WithReadyHandler(h http.Handler) HandlerOptFn {
 	}
 }
 
+// MetricsTokenHandler returns a handler serving the requests authorized with
+// token to the metrics handler next. Other requests get an unauthorized error,
+// so that metrics are only exposed to the scrapers that have token, rather
+// than to any user of the API.
+func MetricsTokenHandler(next http.Handler, token string, h platform.HTTPErrorHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, err := GetToken(r)
+		if err != nil || subtle.ConstantTimeCompare([]byte(t), []byte(token)) != 1 {
+			UnauthorizedError(r.Context(), h, w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // NewHandlerFromRegistry creates a new handler with the given name,
 // and sets the /metrics endpoint to use the metrics from the given registry,
 // after self-registering h's metrics.
@@ -109,6 +126,7 @@ func NewHandlerFromRegistry(name string, reg *prom.Registry, opts ...HandlerOptF
 
 	r := chi.NewRouter()
 	r.Use(
+		kithttp.RequestID,
 		kithttp.Trace(name),
 		kithttp.Metrics(name, h.requests, h.requestDur),
 	)
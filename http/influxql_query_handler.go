@@ -0,0 +1,214 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/http/metric"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
+	transpiler "github.com/influxdata/influxdb/query/influxql"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+// prefixInfluxQLQuery is the path of the InfluxDB 1.x query API, which is
+// served for compatibility with 1.x clients.
+const prefixInfluxQLQuery = "/query"
+
+// InfluxQLBackend is all services and associated parameters required to
+// construct the InfluxQLHandler.
+type InfluxQLBackend struct {
+	influxdb.HTTPErrorHandler
+	log                *zap.Logger
+	QueryEventRecorder metric.EventRecorder
+
+	OrganizationService influxdb.OrganizationService
+	ProxyQueryService   query.ProxyQueryService
+
+	// BucketMapping maps the databases named by queries to buckets. A
+	// database without a mapping is assumed to name a bucket.
+	BucketMapping map[string]string
+}
+
+// NewInfluxQLBackend returns a new instance of InfluxQLBackend.
+func NewInfluxQLBackend(log *zap.Logger, b *APIBackend) *InfluxQLBackend {
+	return &InfluxQLBackend{
+		HTTPErrorHandler:   b.HTTPErrorHandler,
+		log:                log,
+		QueryEventRecorder: b.QueryEventRecorder,
+
+		OrganizationService: b.OrganizationService,
+		ProxyQueryService:   b.InfluxQLService,
+		BucketMapping:       b.InfluxQLBucketMapping,
+	}
+}
+
+// InfluxQLHandler serves InfluxQL queries sent to the 1.x query API. Queries
+// are transpiled to Flux and their results are encoded in the 1.x JSON
+// format. Only SELECT statements are supported.
+type InfluxQLHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	Now                 func() time.Time
+	OrganizationService influxdb.OrganizationService
+	ProxyQueryService   query.ProxyQueryService
+	BucketMapping       map[string]string
+
+	EventRecorder metric.EventRecorder
+}
+
+// NewInfluxQLHandler returns a new handler at /query for InfluxQL queries.
+func NewInfluxQLHandler(log *zap.Logger, b *InfluxQLBackend) *InfluxQLHandler {
+	h := &InfluxQLHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		Now:                 time.Now,
+		OrganizationService: b.OrganizationService,
+		ProxyQueryService:   b.ProxyQueryService,
+		BucketMapping:       b.BucketMapping,
+		EventRecorder:       b.QueryEventRecorder,
+	}
+
+	h.HandlerFunc("GET", prefixInfluxQLQuery, h.handleQuery)
+	h.HandlerFunc("POST", prefixInfluxQLQuery, h.handleQuery)
+	return h
+}
+
+// handleQuery executes the InfluxQL query in the q parameter against the
+// bucket mapped to the db parameter. Errors are returned in the 1.x format,
+// as a JSON object with an error property.
+func (h *InfluxQLHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "InfluxQLHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	log := h.log.With(logger.TraceFields(ctx)...)
+	if id, _, found := tracing.InfoFromContext(ctx); found {
+		w.Header().Set(traceIDHeader, id)
+	}
+
+	var orgID influxdb.ID
+	sw := kithttp.NewStatusResponseWriter(w)
+	w = sw
+	defer func() {
+		h.EventRecorder.Record(ctx, metric.Event{
+			OrgID:         orgID,
+			Endpoint:      r.URL.Path,
+			ResponseBytes: sw.ResponseBytes(),
+			Status:        sw.Code(),
+		})
+	}()
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		h.handleInfluxQLError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	q := r.FormValue("q")
+	if q == "" {
+		h.handleInfluxQLError(w, http.StatusBadRequest, errors.New(`missing required parameter "q"`))
+		return
+	}
+	parsed, err := influxql.ParseQuery(q)
+	if err != nil {
+		h.handleInfluxQLError(w, http.StatusBadRequest, fmt.Errorf("error parsing query: %s", err))
+		return
+	}
+	for _, stmt := range parsed.Statements {
+		if _, ok := stmt.(*influxql.SelectStatement); !ok {
+			h.handleInfluxQLError(w, http.StatusBadRequest, fmt.Errorf("only SELECT statements are supported: %s", stmt))
+			return
+		}
+	}
+
+	db := r.FormValue("db")
+	if db == "" {
+		h.handleInfluxQLError(w, http.StatusBadRequest, errors.New("database name required"))
+		return
+	}
+	bucket, ok := h.BucketMapping[db]
+	if !ok {
+		bucket = db
+	}
+
+	org, err := h.findOrganization(r, a)
+	if err != nil {
+		h.handleInfluxQLError(w, http.StatusBadRequest, err)
+		return
+	}
+	orgID = org.ID
+
+	auth, err := queryAuthorization(a, org.ID)
+	if err != nil {
+		h.handleInfluxQLError(w, http.StatusUnauthorized, err)
+		return
+	}
+	ctx = pcontext.SetAuthorizer(ctx, auth)
+
+	now := h.Now()
+	dialect := &transpiler.Dialect{Encoding: transpiler.JSON}
+	req := &query.ProxyRequest{
+		Request: query.Request{
+			Authorization:  auth,
+			OrganizationID: org.ID,
+			Compiler: &transpiler.Compiler{
+				Now:    &now,
+				Query:  q,
+				Bucket: bucket,
+			},
+			Source: r.Header.Get("User-Agent"),
+		},
+		Dialect: dialect,
+	}
+	dialect.SetHeaders(w)
+
+	cw := iocounter.Writer{Writer: w}
+	if _, err := h.ProxyQueryService.Query(ctx, &cw, req); err != nil {
+		if cw.Count() == 0 {
+			// Only write the error IFF nothing has been written to w.
+			h.handleInfluxQLError(w, http.StatusBadRequest, err)
+			return
+		}
+		_ = tracing.LogError(span, err)
+		log.Info("Error writing response to client",
+			zap.String("handler", "influxql"),
+			zap.Error(err),
+		)
+	}
+}
+
+// findOrganization returns the organization given by the org or orgID
+// parameter of r. 1.x clients do not know about organizations, so if neither
+// is given the organization of the token authorizing the request is used.
+func (h *InfluxQLHandler) findOrganization(r *http.Request, a influxdb.Authorizer) (*influxdb.Organization, error) {
+	params := r.URL.Query()
+	if params.Get(Org) == "" && params.Get(OrgID) == "" {
+		auth, ok := a.(*influxdb.Authorization)
+		if !ok {
+			return nil, errors.New("org or orgID parameter is required")
+		}
+		return h.OrganizationService.FindOrganizationByID(r.Context(), auth.OrgID)
+	}
+	return queryOrganization(r.Context(), r, h.OrganizationService)
+}
+
+func (h *InfluxQLHandler) handleInfluxQLError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(transpiler.Response{Err: err.Error()})
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/influxdb"
+	httpmock "github.com/influxdata/influxdb/http/mock"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/query"
+	transpiler "github.com/influxdata/influxdb/query/influxql"
+	querymock "github.com/influxdata/influxdb/query/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestInfluxQLHandler_handleQuery(t *testing.T) {
+	const response = `{"results":[{"statement_id":0}]}`
+
+	tests := []struct {
+		name   string
+		params url.Values
+		code   int
+		bucket string
+		err    string
+	}{
+		{
+			name:   "mapped database",
+			params: url.Values{"db": {"telegraf"}, "q": {`SELECT mean("usage_cpu") FROM "cpu"`}},
+			code:   http.StatusOK,
+			bucket: "metrics",
+		},
+		{
+			name:   "unmapped database",
+			params: url.Values{"db": {"other"}, "q": {`SELECT mean("usage_cpu") FROM "cpu"`}},
+			code:   http.StatusOK,
+			bucket: "other",
+		},
+		{
+			name:   "missing query",
+			params: url.Values{"db": {"telegraf"}},
+			code:   http.StatusBadRequest,
+			err:    `missing required parameter "q"`,
+		},
+		{
+			name:   "missing database",
+			params: url.Values{"q": {`SELECT mean("usage_cpu") FROM "cpu"`}},
+			code:   http.StatusBadRequest,
+			err:    "database name required",
+		},
+		{
+			name:   "unsupported statement",
+			params: url.Values{"db": {"telegraf"}, "q": {`SELECT * FROM "cpu"; DROP MEASUREMENT "cpu"`}},
+			code:   http.StatusBadRequest,
+			err:    `only SELECT statements are supported: DROP MEASUREMENT cpu`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := mock.NewOrganizationService()
+			orgs.FindOrganizationByIDF = func(ctx context.Context, id influxdb.ID) (*influxdb.Organization, error) {
+				return testOrg("043e0780ee2b1000"), nil
+			}
+
+			var bucket string
+			b := &InfluxQLBackend{
+				HTTPErrorHandler:    DefaultErrorHandler,
+				log:                 zaptest.NewLogger(t),
+				QueryEventRecorder:  noopEventRecorder{},
+				OrganizationService: orgs,
+				ProxyQueryService: &querymock.ProxyQueryService{
+					QueryF: func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+						bucket = req.Request.Compiler.(*transpiler.Compiler).Bucket
+						_, err := io.WriteString(w, response)
+						return flux.Statistics{}, err
+					},
+				},
+				BucketMapping: map[string]string{"telegraf": "metrics"},
+			}
+			h := httpmock.NewAuthMiddlewareHandler(NewInfluxQLHandler(zaptest.NewLogger(t), b), bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"))
+
+			r := httptest.NewRequest("GET", "http://localhost:9999/query?"+tt.params.Encode(), nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got, want := w.Code, tt.code; got != want {
+				t.Fatalf("unexpected status code: got %d want %d, body: %s", got, want, w.Body.String())
+			}
+			if got, want := bucket, tt.bucket; got != want {
+				t.Errorf("unexpected bucket: got %q want %q", got, want)
+			}
+
+			var res transpiler.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("response is not valid JSON: %v, body: %s", err, w.Body.String())
+			}
+			if got, want := res.Err, tt.err; got != want {
+				t.Errorf("unexpected error: got %q want %q", got, want)
+			}
+		})
+	}
+}
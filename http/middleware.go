@@ -10,6 +10,7 @@ import (
 	"time"
 
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/logger"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +37,11 @@ func LoggingMW(log *zap.Logger) kithttp.Middleware {
 					errReferenceField = zap.String("error_code", errReference)
 				}
 
+				traceIDField := zap.Skip()
+				if traceID := w.Header().Get(kithttp.TraceIDHeader); traceID != "" {
+					traceIDField = zap.String(logger.RequestTraceIDKey, traceID)
+				}
+
 				fields := []zap.Field{
 					zap.String("method", r.Method),
 					zap.String("host", r.Host),
@@ -51,6 +57,7 @@ func LoggingMW(log *zap.Logger) kithttp.Middleware {
 					zap.Duration("took", time.Since(start)),
 					errField,
 					errReferenceField,
+					traceIDField,
 				}
 
 				invalidMethodFn, ok := mapURLPath(r.URL.Path)
@@ -134,6 +141,7 @@ var blacklistEndpoints = map[string]isValidMethodFn{
 	usersPasswordPath:                ignoreMethod(),
 	"/api/v2/packages/apply":         ignoreMethod(),
 	prefixWrite:                      ignoreMethod("POST"),
+	writePrecisionPath:               ignoreMethod("POST"),
 	organizationsIDSecretsPath:       ignoreMethod("PATCH"),
 	organizationsIDSecretsDeletePath: ignoreMethod("POST"),
 	prefixSetup:                      ignoreMethod("POST"),
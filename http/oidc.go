@@ -0,0 +1,629 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	prefixOAuth2        = "/oauth2"
+	prefixOIDCAuthorize = "/oauth2/authorize"
+	prefixOIDCCallback  = "/oauth2/callback"
+	prefixOIDCLogout    = "/oauth2/logout"
+
+	oidcStateCookie = "oidc_state"
+	oidcNonceCookie = "oidc_nonce"
+
+	// DefaultOIDCSessionLength is how long the authorization minted by a
+	// login can be used if OIDCConfig.SessionLength is not set.
+	DefaultOIDCSessionLength = time.Hour
+
+	// oidcKeysRefetchInterval is the minimum time between fetches of the
+	// provider's signing keys when an id token is signed with an unknown key.
+	oidcKeysRefetchInterval = time.Minute
+)
+
+// OIDCConfig configures the OpenID Connect authorization code flow.
+type OIDCConfig struct {
+	// IssuerURL is the URL of the OpenID provider. The provider's
+	// configuration is discovered from IssuerURL/.well-known/openid-configuration.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	// DefaultOrg is the name of the organization users are added to as
+	// members on their first login. If empty, new users belong to no
+	// organization and cannot log in until they are added to one.
+	DefaultOrg string
+
+	// SessionLength is how long the authorization minted by a login can be
+	// used. It defaults to DefaultOIDCSessionLength.
+	SessionLength time.Duration
+}
+
+// OIDCBackend is all services and associated parameters required to construct
+// the OIDCHandler.
+type OIDCBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	Config     OIDCConfig
+	HTTPClient *http.Client
+
+	AuthorizationService       influxdb.AuthorizationService
+	OrganizationService        influxdb.OrganizationService
+	UserService                influxdb.UserService
+	UserResourceMappingService influxdb.UserResourceMappingService
+	OIDCIdentityService        influxdb.OIDCIdentityService
+}
+
+// NewOIDCBackend returns a new instance of OIDCBackend.
+func NewOIDCBackend(log *zap.Logger, b *APIBackend) *OIDCBackend {
+	return &OIDCBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		AuthorizationService:       b.AuthorizationService,
+		OrganizationService:        b.OrganizationService,
+		UserService:                b.UserService,
+		UserResourceMappingService: b.UserResourceMappingService,
+		OIDCIdentityService:        b.OIDCIdentityService,
+	}
+}
+
+// OIDCHandler implements the OpenID Connect authorization code flow. Users
+// authenticated by the provider are matched to an influxdb.User by the
+// influxdb.OIDCIdentity of their issuer and subject claims, and are issued an
+// authorization for their organization that expires with their session.
+type OIDCHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	config OIDCConfig
+	client *http.Client
+	clock  clock.Clock
+
+	AuthorizationService       influxdb.AuthorizationService
+	OrganizationService        influxdb.OrganizationService
+	UserService                influxdb.UserService
+	UserResourceMappingService influxdb.UserResourceMappingService
+	OIDCIdentityService        influxdb.OIDCIdentityService
+
+	mu          sync.Mutex
+	provider    *oidcProvider
+	keys        map[string]*rsa.PublicKey // signing keys of the provider by id
+	keysFetched time.Time                 // when keys were last fetched
+}
+
+// NewOIDCHandler returns a new instance of OIDCHandler.
+func NewOIDCHandler(log *zap.Logger, b *OIDCBackend) *OIDCHandler {
+	h := &OIDCHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		config: b.Config,
+		client: b.HTTPClient,
+		clock:  clock.New(),
+
+		AuthorizationService:       b.AuthorizationService,
+		OrganizationService:        b.OrganizationService,
+		UserService:                b.UserService,
+		UserResourceMappingService: b.UserResourceMappingService,
+		OIDCIdentityService:        b.OIDCIdentityService,
+	}
+	if h.client == nil {
+		h.client = http.DefaultClient
+	}
+	if h.config.SessionLength <= 0 {
+		h.config.SessionLength = DefaultOIDCSessionLength
+	}
+
+	h.HandlerFunc("GET", prefixOIDCAuthorize, h.handleAuthorize)
+	h.HandlerFunc("POST", prefixOIDCCallback, h.handleCallback)
+	h.HandlerFunc("POST", prefixOIDCLogout, h.handleLogout)
+	return h
+}
+
+// Prefix provides the route prefix.
+func (*OIDCHandler) Prefix() string {
+	return prefixOAuth2
+}
+
+// oidcProvider is the subset of the provider metadata used by the handler.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover fetches and caches the provider metadata.
+func (h *OIDCHandler) discover(ctx context.Context) (*oidcProvider, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.provider != nil {
+		return h.provider, nil
+	}
+
+	var p oidcProvider
+	u := strings.TrimSuffix(h.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := h.getJSON(ctx, u, &p); err != nil {
+		return nil, err
+	}
+
+	if p.Issuer != h.config.IssuerURL {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  fmt.Sprintf("oidc issuer %q does not match configured issuer %q", p.Issuer, h.config.IssuerURL),
+		}
+	}
+
+	h.provider = &p
+	return h.provider, nil
+}
+
+// signingKey returns the provider's signing key with the id kid. The keys are
+// fetched again when kid is unknown, as the provider may have rotated them,
+// but at most once every oidcKeysRefetchInterval.
+func (h *OIDCHandler) signingKey(ctx context.Context, p *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if key, ok := h.keys[kid]; ok {
+		return key, nil
+	}
+
+	now := h.clock.Now()
+	if h.keys != nil && now.Sub(h.keysFetched) < oidcKeysRefetchInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := h.fetchKeys(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	h.keys, h.keysFetched = keys, now
+
+	key, ok := h.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys fetches the RSA signing keys of the provider by id.
+func (h *OIDCHandler) fetchKeys(ctx context.Context, p *oidcProvider) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := h.getJSON(ctx, p.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (h *OIDCHandler) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  fmt.Sprintf("unexpected status %d fetching %s", resp.StatusCode, u),
+		}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (h *OIDCHandler) oauth2Config(p *oidcProvider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     h.config.ClientID,
+		ClientSecret: h.config.ClientSecret,
+		RedirectURL:  h.config.RedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthorizationEndpoint,
+			TokenURL: p.TokenEndpoint,
+		},
+		Scopes: []string{"openid", "profile"},
+	}
+}
+
+// handleAuthorize is the HTTP handler for the GET /oauth2/authorize route.
+func (h *OIDCHandler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	p, err := h.discover(ctx)
+	if err != nil {
+		h.log.Error("Failed to discover oidc provider", zap.Error(err))
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	state, err := randomOIDCValue()
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	nonce, err := randomOIDCValue()
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	setOIDCCookie(w, oidcStateCookie, state)
+	setOIDCCookie(w, oidcNonceCookie, nonce)
+
+	u := h.oauth2Config(p).AuthCodeURL(state,
+		oauth2.SetAuthURLParam("response_mode", "form_post"),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// randomOIDCValue returns a random value for the state or nonce of a login.
+func randomOIDCValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// setOIDCCookie sets a cookie holding the state or nonce of a login until the
+// callback. The provider posts the callback from its own site, so the cookie
+// must be SameSite=None, which browsers only accept on secure cookies.
+func setOIDCCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     prefixOAuth2,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// clearOIDCCookie removes a cookie set by setOIDCCookie.
+func clearOIDCCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     prefixOAuth2,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+type oidcAuthResponse struct {
+	ID        influxdb.ID `json:"id"`
+	Token     string      `json:"token"`
+	OrgID     influxdb.ID `json:"orgID"`
+	UserID    influxdb.ID `json:"userID"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+// handleCallback is the HTTP handler for the POST /oauth2/callback route.
+func (h *OIDCHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}, w)
+		return
+	}
+
+	c, err := r.Cookie(oidcStateCookie)
+	if err != nil || c.Value == "" || c.Value != r.PostForm.Get("state") {
+		h.log.Info("Invalid oidc state")
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	nonce, err := r.Cookie(oidcNonceCookie)
+	if err != nil || nonce.Value == "" {
+		h.log.Info("Missing oidc nonce")
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	p, err := h.discover(ctx)
+	if err != nil {
+		h.log.Error("Failed to discover oidc provider", zap.Error(err))
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	tok, err := h.oauth2Config(p).Exchange(context.WithValue(ctx, oauth2.HTTPClient, h.client), r.PostForm.Get("code"))
+	if err != nil {
+		h.log.Info("Failed to exchange oidc code", zap.Error(err))
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	sub, err := h.verifyIDToken(ctx, p, rawIDToken, nonce.Value)
+	if err != nil {
+		h.log.Info("Invalid oidc id token", zap.Error(err))
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	a, sess, err := h.authorize(ctx, p.Issuer, sub)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	clearOIDCCookie(w, oidcStateCookie)
+	clearOIDCCookie(w, oidcNonceCookie)
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, oidcAuthResponse{
+		ID:        a.ID,
+		Token:     a.Token,
+		OrgID:     a.OrgID,
+		UserID:    a.UserID,
+		ExpiresAt: sess.ExpiresAt,
+	}); err != nil {
+		logEncodingError(h.log, r, err)
+	}
+}
+
+// verifyIDToken verifies the signature and claims of the id token, including
+// that it was issued for the login with the nonce, and returns its subject.
+func (h *OIDCHandler) verifyIDToken(ctx context.Context, p *oidcProvider, raw, nonce string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return h.signingKey(ctx, p, kid)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return "", fmt.Errorf("unexpected issuer %v", claims["iss"])
+	}
+
+	var audOK bool
+	switch aud := claims["aud"].(type) {
+	case string:
+		audOK = aud == h.config.ClientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == h.config.ClientID {
+				audOK = true
+			}
+		}
+	}
+	if !audOK {
+		return "", fmt.Errorf("unexpected audience %v", claims["aud"])
+	}
+
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return "", fmt.Errorf("unexpected nonce")
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", fmt.Errorf("id token is missing subject")
+	}
+	return sub, nil
+}
+
+// authorize finds or creates the user of the identity of the subject of the
+// issuer and mints an authorization for the first organization the user
+// belongs to, which expires with the session of the login.
+func (h *OIDCHandler) authorize(ctx context.Context, issuer, sub string) (*influxdb.Authorization, *influxdb.OIDCSession, error) {
+	u, err := h.findOrCreateUser(ctx, issuer, sub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	urms, _, err := h.UserResourceMappingService.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+		UserID:       u.ID,
+		ResourceType: influxdb.OrgsResourceType,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(urms) == 0 {
+		return nil, nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  fmt.Sprintf("user %q is not a member of any organization", u.Name),
+		}
+	}
+
+	urm := urms[0]
+	ps := influxdb.MemberPermissions(urm.ResourceID)
+	if urm.UserType == influxdb.Owner {
+		ps = influxdb.OwnerPermissions(urm.ResourceID)
+	}
+	ps = append(ps, influxdb.MePermissions(u.ID)...)
+
+	a := &influxdb.Authorization{
+		OrgID:       urm.ResourceID,
+		UserID:      u.ID,
+		Status:      influxdb.Active,
+		Description: fmt.Sprintf("oidc session for %s", u.Name),
+		Permissions: ps,
+	}
+	if err := h.AuthorizationService.CreateAuthorization(ctx, a); err != nil {
+		return nil, nil, err
+	}
+
+	sess := &influxdb.OIDCSession{
+		AuthorizationID: a.ID,
+		UserID:          u.ID,
+		ExpiresAt:       h.clock.Now().Add(h.config.SessionLength),
+	}
+	if err := h.OIDCIdentityService.CreateOIDCSession(ctx, sess); err != nil {
+		if err := h.AuthorizationService.DeleteAuthorization(ctx, a.ID); err != nil {
+			h.log.Error("Failed to delete authorization of oidc session", zap.Stringer("authorization_id", a.ID), zap.Error(err))
+		}
+		return nil, nil, err
+	}
+	return a, sess, nil
+}
+
+// findOrCreateUser returns the user of the identity of the subject of the
+// issuer. On the subject's first login, a new user named after the subject and
+// issuer is created for it, and added to the default organization if one is
+// configured. Existing users are never matched by name, as a local user may
+// have the name of any subject.
+func (h *OIDCHandler) findOrCreateUser(ctx context.Context, issuer, sub string) (*influxdb.User, error) {
+	i, err := h.OIDCIdentityService.FindOIDCIdentity(ctx, issuer, sub)
+	if err == nil {
+		return h.UserService.FindUserByID(ctx, i.UserID)
+	}
+	if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	var org *influxdb.Organization
+	if h.config.DefaultOrg != "" {
+		if org, err = h.OrganizationService.FindOrganization(ctx, influxdb.OrganizationFilter{Name: &h.config.DefaultOrg}); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &influxdb.User{Name: oidcUserName(issuer, sub), Status: influxdb.Active}
+	if err := h.UserService.CreateUser(ctx, u); err != nil {
+		return nil, err
+	}
+
+	if err := h.createIdentity(ctx, issuer, sub, u, org); err != nil {
+		if err := h.UserService.DeleteUser(ctx, u.ID); err != nil {
+			h.log.Error("Failed to delete user of oidc identity", zap.Stringer("user_id", u.ID), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	h.log.Info("Created user for oidc identity", zap.Stringer("user_id", u.ID), zap.String("issuer", issuer))
+	return u, nil
+}
+
+// createIdentity adds the new user u to org, if it is not nil, and links it to
+// the subject of the issuer.
+func (h *OIDCHandler) createIdentity(ctx context.Context, issuer, sub string, u *influxdb.User, org *influxdb.Organization) error {
+	if org != nil {
+		if err := h.UserResourceMappingService.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+			UserID:       u.ID,
+			UserType:     influxdb.Member,
+			MappingType:  influxdb.UserMappingType,
+			ResourceType: influxdb.OrgsResourceType,
+			ResourceID:   org.ID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return h.OIDCIdentityService.CreateOIDCIdentity(ctx, &influxdb.OIDCIdentity{
+		Issuer:  issuer,
+		Subject: sub,
+		UserID:  u.ID,
+	})
+}
+
+// oidcUserName returns the name of the user created for the subject of the
+// issuer.
+func oidcUserName(issuer, sub string) string {
+	return sub + "@" + issuer
+}
+
+// handleLogout is the HTTP handler for the POST /oauth2/logout route. It
+// revokes the authorization used to make the request, which must have been
+// minted by a login.
+func (h *OIDCHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	t, err := GetToken(r)
+	if err != nil {
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	a, err := h.AuthorizationService.FindAuthorizationByToken(ctx, t)
+	if err != nil {
+		UnauthorizedError(ctx, h, w)
+		return
+	}
+
+	// Other authorizations, such as API tokens, are never revoked by logging
+	// out.
+	if _, err := h.OIDCIdentityService.FindOIDCSession(ctx, a.ID); err != nil {
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			err = &influxdb.Error{
+				Code: influxdb.EForbidden,
+				Msg:  "authorization was not issued by an oidc login",
+			}
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.AuthorizationService.DeleteAuthorization(ctx, a.ID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
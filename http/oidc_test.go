@@ -0,0 +1,435 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+// oidcProviderFixture is a minimal in-process OpenID provider that issues an
+// id token for a fixed subject when presented with a fixed code.
+type oidcProviderFixture struct {
+	*httptest.Server
+	clientID string
+	subject  string
+	code     string
+
+	mu         sync.Mutex
+	key        *rsa.PrivateKey
+	kid        string
+	nonce      string // the nonce put in the id token
+	keyFetches int    // number of requests for the signing keys
+}
+
+func (f *oidcProviderFixture) setNonce(nonce string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nonce = nonce
+}
+
+// rotateKey replaces the signing key of the provider with a new one.
+func (f *oidcProviderFixture) rotateKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key, f.kid = key, kid
+}
+
+func (f *oidcProviderFixture) fetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.keyFetches
+}
+
+func newOIDCProviderFixture(t *testing.T, clientID, subject string) *oidcProviderFixture {
+	f := &oidcProviderFixture{clientID: clientID, subject: subject, code: "the-code"}
+	f.rotateKey(t, "test")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		key, kid := f.key, f.kid
+		f.keyFetches++
+		f.mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": kid,
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != f.code {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		key, kid, nonce := f.key, f.kid, f.nonce
+		f.mu.Unlock()
+
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   f.URL,
+			"aud":   f.clientID,
+			"sub":   f.subject,
+			"nonce": nonce,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		tok.Header["kid"] = kid
+		idToken, err := tok.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// oidcTest is an OIDCHandler logging in with a provider fixture, backed by a
+// store of its own.
+type oidcTest struct {
+	t        *testing.T
+	provider *oidcProviderFixture
+	svc      *kv.Service
+	clock    *clock.Mock
+	org      *influxdb.Organization
+	h        *OIDCHandler
+}
+
+// newOIDCTest returns a new oidcTest. The users of new identities are added to
+// its organization if defaultOrg is set.
+func newOIDCTest(t *testing.T, defaultOrg bool) *oidcTest {
+	ctx := context.Background()
+
+	provider := newOIDCProviderFixture(t, "influxdb", "jane")
+
+	clk := clock.NewMock()
+	clk.Set(time.Now())
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore(), kv.ServiceConfig{Clock: clk})
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+
+	config := OIDCConfig{
+		IssuerURL:   provider.URL,
+		ClientID:    "influxdb",
+		RedirectURL: "http://localhost:9999/oauth2/callback",
+	}
+	if defaultOrg {
+		config.DefaultOrg = org.Name
+	}
+	b := &OIDCBackend{
+		HTTPErrorHandler:           DefaultErrorHandler,
+		log:                        zaptest.NewLogger(t),
+		Config:                     config,
+		AuthorizationService:       svc,
+		OrganizationService:        svc,
+		UserService:                svc,
+		UserResourceMappingService: svc,
+		OIDCIdentityService:        svc,
+	}
+	h := NewOIDCHandler(zaptest.NewLogger(t), b)
+	h.clock = clk
+
+	return &oidcTest{t: t, provider: provider, svc: svc, clock: clk, org: org, h: h}
+}
+
+func (tt *oidcTest) Close() { tt.provider.Close() }
+
+// authorize starts a login, returning its state and nonce.
+func (tt *oidcTest) authorize() (string, string) {
+	t := tt.t
+	w := httptest.NewRecorder()
+	tt.h.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:9999/oauth2/authorize", nil))
+	if got, want := w.Code, http.StatusFound; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loc.Scheme+"://"+loc.Host+loc.Path, tt.provider.URL+"/authorize"; got != want {
+		t.Errorf("unexpected redirect: got %s want %s", got, want)
+	}
+	if got, want := loc.Query().Get("client_id"), "influxdb"; got != want {
+		t.Errorf("unexpected client_id: got %s want %s", got, want)
+	}
+
+	state, nonce := loc.Query().Get("state"), loc.Query().Get("nonce")
+	if state == "" || nonce == "" {
+		t.Fatal("expected state and nonce in redirect")
+	}
+
+	// The provider posts the callback cross-site, so the cookies must be
+	// sent with it.
+	cookies := w.Result().Cookies()
+	if got, want := len(cookies), 2; got != want {
+		t.Fatalf("unexpected number of cookies: got %d want %d", got, want)
+	}
+	for _, c := range cookies {
+		if !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteNoneMode {
+			t.Errorf("expected cookie %s to be secure, http only and SameSite=None: %+v", c.Name, c)
+		}
+	}
+	return state, nonce
+}
+
+func (tt *oidcTest) callback(state, stateCookie, nonceCookie string) *httptest.ResponseRecorder {
+	form := url.Values{"code": {tt.provider.code}, "state": {state}}
+	r := httptest.NewRequest("POST", "http://localhost:9999/oauth2/callback", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: stateCookie})
+	r.AddCookie(&http.Cookie{Name: oidcNonceCookie, Value: nonceCookie})
+
+	w := httptest.NewRecorder()
+	tt.h.ServeHTTP(w, r)
+	return w
+}
+
+// login logs in, returning the response to the callback.
+func (tt *oidcTest) login() *httptest.ResponseRecorder {
+	state, nonce := tt.authorize()
+	tt.provider.setNonce(nonce)
+	return tt.callback(state, state, nonce)
+}
+
+// mustLogin logs in, returning the authorization minted.
+func (tt *oidcTest) mustLogin() oidcAuthResponse {
+	t := tt.t
+	w := tt.login()
+	if got, want := w.Code, http.StatusCreated; got != want {
+		t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+	}
+
+	var res oidcAuthResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func (tt *oidcTest) logout(method, token string) int {
+	r := httptest.NewRequest(method, "http://localhost:9999/oauth2/logout", nil)
+	SetToken(token, r)
+	w := httptest.NewRecorder()
+	tt.h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestOIDCHandler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("state mismatch is unauthorized", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		state, nonce := tt.authorize()
+		tt.provider.setNonce(nonce)
+		if got, want := tt.callback(state, "other", nonce).Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+	})
+
+	t.Run("nonce mismatch is unauthorized", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		state, nonce := tt.authorize()
+		tt.provider.setNonce("other")
+		if got, want := tt.callback(state, state, nonce).Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+	})
+
+	t.Run("user without an organization is forbidden", func(t *testing.T) {
+		tt := newOIDCTest(t, false)
+		defer tt.Close()
+
+		// A local user named after the subject is never logged in as.
+		local := &influxdb.User{Name: tt.provider.subject}
+		if err := tt.svc.CreateUser(ctx, local); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.svc.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+			UserID:       local.ID,
+			UserType:     influxdb.Owner,
+			ResourceType: influxdb.OrgsResourceType,
+			ResourceID:   tt.org.ID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := tt.login().Code, http.StatusForbidden; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+
+		// A new user is created for the identity on first login.
+		i, err := tt.svc.FindOIDCIdentity(ctx, tt.provider.URL, tt.provider.subject)
+		if err != nil {
+			t.Fatalf("expected identity to be created: %v", err)
+		}
+		if i.UserID == local.ID {
+			t.Fatal("expected identity not to be linked to the local user")
+		}
+	})
+
+	t.Run("login and logout", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		// The new user of the identity is added to the default organization
+		// on first login.
+		res := tt.mustLogin()
+		i, err := tt.svc.FindOIDCIdentity(ctx, tt.provider.URL, tt.provider.subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.UserID != i.UserID || res.OrgID != tt.org.ID {
+			t.Errorf("unexpected authorization: %+v", res)
+		}
+		if got, want := res.ExpiresAt, tt.clock.Now().Add(DefaultOIDCSessionLength); !got.Equal(want) {
+			t.Errorf("unexpected expiry: got %s want %s", got, want)
+		}
+
+		a, err := tt.svc.FindAuthorizationByToken(ctx, res.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !a.Allowed(influxdb.Permission{
+			Action:   influxdb.ReadAction,
+			Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &tt.org.ID},
+		}) {
+			t.Error("expected authorization to allow reading org buckets")
+		}
+
+		// The user logs in again as the same user.
+		if again := tt.mustLogin(); again.UserID != res.UserID {
+			t.Errorf("expected second login as user %s, got %s", res.UserID, again.UserID)
+		}
+
+		// Logging out changes state, so it is not done by GET.
+		if got, want := tt.logout("GET", res.Token), http.StatusMethodNotAllowed; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+		if got, want := tt.logout("POST", res.Token), http.StatusNoContent; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+		if _, err := tt.svc.FindAuthorizationByToken(ctx, res.Token); influxdb.ErrorCode(err) != influxdb.ENotFound {
+			t.Errorf("expected authorization to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("logout does not revoke other authorizations", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		res := tt.mustLogin()
+		other := &influxdb.Authorization{
+			OrgID:       tt.org.ID,
+			UserID:      res.UserID,
+			Permissions: influxdb.MePermissions(res.UserID),
+		}
+		if err := tt.svc.CreateAuthorization(ctx, other); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := tt.logout("POST", other.Token), http.StatusForbidden; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+		if _, err := tt.svc.FindAuthorizationByToken(ctx, other.Token); err != nil {
+			t.Errorf("expected authorization to be kept, got %v", err)
+		}
+	})
+
+	t.Run("session expires", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		res := tt.mustLogin()
+		tt.clock.Add(DefaultOIDCSessionLength)
+		if _, err := tt.svc.FindAuthorizationByToken(ctx, res.Token); influxdb.ErrorCode(err) != influxdb.ENotFound {
+			t.Fatalf("expected expired authorization not to be found, got %v", err)
+		}
+
+		// The next login removes the expired authorization.
+		next := tt.mustLogin()
+		if _, err := tt.svc.FindAuthorizationByID(ctx, res.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+			t.Errorf("expected expired authorization to be deleted, got %v", err)
+		}
+		if _, err := tt.svc.FindAuthorizationByToken(ctx, next.Token); err != nil {
+			t.Errorf("expected new authorization to be found, got %v", err)
+		}
+	})
+
+	t.Run("signing keys are fetched again after rotation", func(t *testing.T) {
+		tt := newOIDCTest(t, true)
+		defer tt.Close()
+
+		tt.mustLogin()
+		if got, want := tt.provider.fetches(), 1; got != want {
+			t.Fatalf("unexpected number of key fetches: got %d want %d", got, want)
+		}
+
+		// An unknown key is refetched at most once per interval.
+		tt.clock.Add(oidcKeysRefetchInterval)
+		tt.provider.rotateKey(t, "rotated")
+		tt.mustLogin()
+
+		tt.provider.rotateKey(t, "rotated-again")
+		if got, want := tt.login().Code, http.StatusUnauthorized; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+		if got, want := tt.provider.fetches(), 2; got != want {
+			t.Fatalf("unexpected number of key fetches: got %d want %d", got, want)
+		}
+
+		tt.clock.Add(oidcKeysRefetchInterval)
+		tt.mustLogin()
+	})
+}
@@ -238,6 +238,7 @@ func (h *OrgHandler) handleGetOrg(w http.ResponseWriter, r *http.Request) {
 	}
 	h.log.Debug("Org retrieved", zap.String("org", fmt.Sprint(org)))
 
+	kithttp.SetETag(w, org.UpdatedAt)
 	h.API.Respond(w, http.StatusOK, newOrgResponse(*org))
 }
 
@@ -299,6 +300,18 @@ func (h *OrgHandler) handlePatchOrg(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if kithttp.HasIfMatch(r) {
+		org, err := h.OrgSVC.FindOrganizationByID(r.Context(), id)
+		if err != nil {
+			h.API.Err(w, err)
+			return
+		}
+		if err := kithttp.CheckIfMatch(r, org.UpdatedAt); err != nil {
+			h.API.Err(w, err)
+			return
+		}
+	}
+
 	org, err := h.OrgSVC.UpdateOrganization(r.Context(), id, upd)
 	if err != nil {
 		h.API.Err(w, err)
@@ -306,6 +319,7 @@ func (h *OrgHandler) handlePatchOrg(w http.ResponseWriter, r *http.Request) {
 	}
 	h.log.Debug("Org updated", zap.String("org", fmt.Sprint(org)))
 
+	kithttp.SetETag(w, org.UpdatedAt)
 	h.API.Respond(w, http.StatusOK, newOrgResponse(*org))
 }
 
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/otlp"
+	"go.uber.org/zap"
+)
+
+// prefixOTLPMetrics is the path OpenTelemetry exporters send metrics to using
+// the OTLP/HTTP protocol.
+const prefixOTLPMetrics = "/v1/metrics"
+
+const contentTypeProtobuf = "application/x-protobuf"
+
+// handleOTLPMetrics receives an OTLP metrics export request, converts its
+// data points to line protocol and writes them to the bucket given by the
+// bucket query parameter.
+func (h *WriteHandler) handleOTLPMetrics(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "WriteHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	w, event, record := h.recordWrite(ctx, w, r)
+	defer record()
+
+	handleError := func(err error, code, message string) {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: code,
+			Op:   "http/handleOTLPMetrics",
+			Msg:  message,
+			Err:  err,
+		}, w)
+	}
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != contentTypeProtobuf {
+		handleError(err, influxdb.EInvalid, fmt.Sprintf("unsupported content type; only %s is accepted", contentTypeProtobuf))
+		return
+	}
+
+	bucketName := r.URL.Query().Get("bucket")
+	if bucketName == "" {
+		handleError(nil, influxdb.EInvalid, "bucket is required")
+		return
+	}
+
+	log := h.log.With(zap.String("org", r.URL.Query().Get("org")), zap.String("bucket", bucketName))
+
+	org, err := queryOrganization(ctx, r, h.OrganizationService)
+	if err != nil {
+		log.Info("Failed to find organization", zap.Error(err))
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	event.OrgID = org.ID
+	span.LogKV("org_id", org.ID)
+
+	bucket, err := h.findWriteBucket(ctx, a, org, bucketName, "http/handleOTLPMetrics")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	span.LogKV("bucket_id", bucket.ID)
+
+	data, err := readWriteRequest(ctx, r.Body, r.Header.Get("Content-Encoding"), h.maxBatchSizeBytes)
+	if err != nil {
+		log.Error("Error reading body", zap.Error(err))
+
+		code := influxdb.EInvalid
+		if errors.Is(err, ErrMaxBatchSizeExceeded) || influxdb.ErrorCode(err) == influxdb.ETooLarge {
+			code = influxdb.ETooLarge
+		}
+		handleError(err, code, "unable to read data")
+		return
+	}
+
+	event.RequestBytes = len(data)
+
+	var req otlp.ExportMetricsRequest
+	if err := req.Unmarshal(data); err != nil {
+		handleError(err, influxdb.EInvalid, "unable to decode metrics export request")
+		return
+	}
+
+	lp, err := req.LineProtocol(nil)
+	if err != nil {
+		handleError(err, influxdb.EInvalid, "unable to convert metrics export request")
+		return
+	}
+
+	if !h.writeLineProtocol(ctx, w, log, a, org.ID, bucket.ID, lp, len(data), "http/handleOTLPMetrics") {
+		return
+	}
+
+	// OTLP exporters expect an ExportMetricsServiceResponse, which is empty
+	// when all data points were accepted.
+	w.Header().Set("Content-Type", contentTypeProtobuf)
+	w.WriteHeader(http.StatusOK)
+}
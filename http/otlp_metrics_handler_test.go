@@ -0,0 +1,100 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http/metric"
+	httpmock "github.com/influxdata/influxdb/http/mock"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/otlp"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWriteHandler_handleOTLPMetrics(t *testing.T) {
+	er := otlp.ExportMetricsRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Attributes: []otlp.Attribute{{Key: "host", Value: "a"}},
+		Metrics: []otlp.Metric{{
+			Name:         "requests",
+			Type:         otlp.MetricTypeSum,
+			Monotonic:    true,
+			NumberPoints: []otlp.NumberDataPoint{{Time: 1000, Value: 10}},
+		}},
+	}}}
+	body := er.Marshal()
+
+	newHandler := func(pw *mock.PointsWriter) http.Handler {
+		orgs := mock.NewOrganizationService()
+		orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+			return testOrg("043e0780ee2b1000"), nil
+		}
+		buckets := mock.NewBucketService()
+		buckets.FindBucketFn = func(ctx context.Context, filter influxdb.BucketFilter) (*influxdb.Bucket, error) {
+			return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+		}
+
+		b := &APIBackend{
+			HTTPErrorHandler:    DefaultErrorHandler,
+			Logger:              zaptest.NewLogger(t),
+			OrganizationService: orgs,
+			BucketService:       buckets,
+			PointsWriter:        pw,
+			WriteEventRecorder:  &metric.NopEventRecorder{},
+		}
+		writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b))
+		return httpmock.NewAuthMiddlewareHandler(writeHandler, bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"))
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		code        int
+		points      int
+	}{
+		{
+			name:        "no bucket",
+			url:         "http://localhost:9999/v1/metrics?org=043e0780ee2b1000",
+			contentType: "application/x-protobuf",
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "unsupported content type",
+			url:         "http://localhost:9999/v1/metrics?org=043e0780ee2b1000&bucket=04504b356e23b000",
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "write",
+			url:         "http://localhost:9999/v1/metrics?org=043e0780ee2b1000&bucket=04504b356e23b000",
+			contentType: "application/x-protobuf",
+			code:        http.StatusOK,
+			points:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw := &mock.PointsWriter{}
+			r := httptest.NewRequest("POST", tt.url, bytes.NewReader(body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			newHandler(pw).ServeHTTP(w, r)
+
+			if got, want := w.Code, tt.code; got != want {
+				t.Fatalf("unexpected status code: got %d want %d, body: %s", got, want, w.Body.String())
+			}
+			if got, want := len(pw.Points), tt.points; got != want {
+				t.Fatalf("got %d points, want %d", got, want)
+			}
+			if tt.points > 0 {
+				if got, want := pw.Points[0].Tags().GetString("monotonic"), "true"; got != want {
+					t.Errorf("unexpected monotonic tag: got %q want %q", got, want)
+				}
+			}
+		})
+	}
+}
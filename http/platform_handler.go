@@ -29,11 +29,26 @@ func NewPlatformHandler(b *APIBackend, opts ...APIHandlerOptFn) *PlatformHandler
 	h.RegisterNoAuthRoute("POST", "/api/v2/setup")
 	h.RegisterNoAuthRoute("GET", "/api/v2/setup")
 	h.RegisterNoAuthRoute("GET", "/api/v2/swagger.json")
+	h.RegisterNoAuthRoute("GET", prefixOpenAPI)
+	if b.ReadinessService != nil {
+		h.RegisterNoAuthRoute("GET", prefixReadiness)
+	}
+
+	if b.OIDCConfig != nil {
+		h.RegisterNoAuthRoute("GET", prefixOIDCAuthorize)
+		h.RegisterNoAuthRoute("POST", prefixOIDCCallback)
+		h.RegisterNoAuthRoute("GET", prefixOIDCLogout)
+	}
 
 	assetHandler := NewAssetHandler()
 	assetHandler.Path = b.AssetsPath
 
-	wrappedHandler := kithttp.SetCORS(h)
+	var wrappedHandler http.Handler
+	if len(b.CORSAllowOrigins) > 0 {
+		wrappedHandler = kithttp.CORS(b.CORSAllowOrigins, b.CORSAllowHeaders)(h)
+	} else {
+		wrappedHandler = kithttp.SetCORS(h)
+	}
 	wrappedHandler = kithttp.SkipOptions(wrappedHandler)
 
 	return &PlatformHandler{
@@ -54,6 +69,8 @@ func (h *PlatformHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// of the platform API.
 	if !strings.HasPrefix(r.URL.Path, "/v1") &&
 		!strings.HasPrefix(r.URL.Path, "/api/v2") &&
+		r.URL.Path != prefixInfluxQLQuery &&
+		!strings.HasPrefix(r.URL.Path, prefixOAuth2+"/") &&
 		!strings.HasPrefix(r.URL.Path, "/chronograf/") {
 		h.AssetHandler.ServeHTTP(w, r)
 		return
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/prometheus"
+	"go.uber.org/zap"
+)
+
+const prefixPrometheusWrite = "/api/v2/prometheus/write"
+
+// WithPrometheusDefaultBucket sets the bucket written to by Prometheus remote
+// write requests that do not specify a bucket.
+func WithPrometheusDefaultBucket(bucket string) WriteHandlerOption {
+	return func(w *WriteHandler) {
+		w.prometheusDefaultBucket = bucket
+	}
+}
+
+// handlePrometheusWrite receives a Prometheus remote write request, converts
+// its samples to line protocol and writes them to the bucket given by the
+// bucket query parameter, or the default bucket.
+func (h *WriteHandler) handlePrometheusWrite(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "WriteHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	w, event, record := h.recordWrite(ctx, w, r)
+	defer record()
+
+	handleError := func(err error, code, message string) {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: code,
+			Op:   "http/handlePrometheusWrite",
+			Msg:  message,
+			Err:  err,
+		}, w)
+	}
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	bucketName := r.URL.Query().Get("bucket")
+	if bucketName == "" {
+		bucketName = h.prometheusDefaultBucket
+	}
+	if bucketName == "" {
+		handleError(nil, influxdb.EInvalid, "bucket is required when no default Prometheus bucket is configured")
+		return
+	}
+
+	log := h.log.With(zap.String("org", r.URL.Query().Get("org")), zap.String("bucket", bucketName))
+
+	org, err := queryOrganization(ctx, r, h.OrganizationService)
+	if err != nil {
+		log.Info("Failed to find organization", zap.Error(err))
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	event.OrgID = org.ID
+	span.LogKV("org_id", org.ID)
+
+	bucket, err := h.findWriteBucket(ctx, a, org, bucketName, "http/handlePrometheusWrite")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	span.LogKV("bucket_id", bucket.ID)
+
+	data, err := h.readPrometheusWriteRequest(r)
+	if err != nil {
+		log.Error("Error reading body", zap.Error(err))
+
+		code := influxdb.EInvalid
+		if errors.Is(err, ErrMaxBatchSizeExceeded) || influxdb.ErrorCode(err) == influxdb.ETooLarge {
+			code = influxdb.ETooLarge
+		}
+		handleError(err, code, "unable to read data")
+		return
+	}
+
+	event.RequestBytes = len(data)
+
+	var req prometheus.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		handleError(err, influxdb.EInvalid, "unable to decode remote write request")
+		return
+	}
+
+	lp, err := req.LineProtocol(nil)
+	if err != nil {
+		handleError(err, influxdb.EInvalid, "unable to convert remote write request")
+		return
+	}
+
+	if !h.writeLineProtocol(ctx, w, log, a, org.ID, bucket.ID, lp, len(data), "http/handlePrometheusWrite") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readPrometheusWriteRequest returns the uncompressed body of a remote write
+// request. Prometheus compresses requests with the snappy block format.
+func (h *WriteHandler) readPrometheusWriteRequest(r *http.Request) ([]byte, error) {
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding != "snappy" {
+		return readWriteRequest(r.Context(), r.Body, encoding, h.maxBatchSizeBytes)
+	}
+
+	compressed, err := readWriteRequest(r.Context(), r.Body, "", h.maxBatchSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, err
+	} else if h.maxBatchSizeBytes > 0 && int64(n) > h.maxBatchSizeBytes {
+		return nil, ErrMaxBatchSizeExceeded
+	}
+	return snappy.Decode(nil, compressed)
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http/metric"
+	httpmock "github.com/influxdata/influxdb/http/mock"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/prometheus"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWriteHandler_handlePrometheusWrite(t *testing.T) {
+	wr := prometheus.WriteRequest{TimeSeries: []prometheus.TimeSeries{{
+		Labels:  []prometheus.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+		Samples: []prometheus.Sample{{Value: 1, Timestamp: 1000}},
+	}}}
+	body := snappy.Encode(nil, wr.Marshal())
+
+	newHandler := func(pw *mock.PointsWriter, opts ...WriteHandlerOption) http.Handler {
+		orgs := mock.NewOrganizationService()
+		orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+			return testOrg("043e0780ee2b1000"), nil
+		}
+		buckets := mock.NewBucketService()
+		buckets.FindBucketFn = func(ctx context.Context, filter influxdb.BucketFilter) (*influxdb.Bucket, error) {
+			if filter.Name != nil && *filter.Name != "prom" {
+				return nil, &influxdb.Error{Code: influxdb.ENotFound, Msg: "bucket not found"}
+			}
+			return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+		}
+
+		b := &APIBackend{
+			HTTPErrorHandler:    DefaultErrorHandler,
+			Logger:              zaptest.NewLogger(t),
+			OrganizationService: orgs,
+			BucketService:       buckets,
+			PointsWriter:        pw,
+			WriteEventRecorder:  &metric.NopEventRecorder{},
+		}
+		writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b), opts...)
+		return httpmock.NewAuthMiddlewareHandler(writeHandler, bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"))
+	}
+
+	write := func(h http.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://localhost:9999/api/v2/prometheus/write?org=043e0780ee2b1000", bytes.NewReader(body))
+		r.Header.Set("Content-Encoding", "snappy")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("no default bucket", func(t *testing.T) {
+		pw := &mock.PointsWriter{}
+		if got, want := write(newHandler(pw)).Code, http.StatusBadRequest; got != want {
+			t.Fatalf("unexpected status code: got %d want %d", got, want)
+		}
+	})
+
+	t.Run("default bucket", func(t *testing.T) {
+		pw := &mock.PointsWriter{}
+		w := write(newHandler(pw, WithPrometheusDefaultBucket("prom")))
+		if got, want := w.Code, http.StatusNoContent; got != want {
+			t.Fatalf("unexpected status code: got %d want %d, body: %s", got, want, w.Body.String())
+		}
+
+		if len(pw.Points) != 1 {
+			t.Fatalf("got %d points, want 1", len(pw.Points))
+		}
+		p := pw.Points[0]
+		if got, want := p.Tags().GetString("job"), "node"; got != want {
+			t.Errorf("unexpected job tag: got %q want %q", got, want)
+		}
+		if got, want := p.UnixNano(), int64(1e9); got != want {
+			t.Errorf("unexpected time: got %d want %d", got, want)
+		}
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -43,6 +44,15 @@ type QueryRequest struct {
 
 	Org *influxdb.Organization `json:"-"`
 
+	// Format is the format of the response, either "csv" or "parquet". It is
+	// set with the format query parameter and defaults to "csv".
+	Format string `json:"-"`
+
+	// MediaType is the media type of the response negotiated with the
+	// Accept header of the request, which is empty unless one of the
+	// query media types is accepted. The format takes precedence over it.
+	MediaType string `json:"-"`
+
 	// PreferNoContent specifies if the Response to this request should
 	// contain any result. This is done for avoiding unnecessary
 	// bandwidth consumption in certain cases. For example, when the
@@ -111,6 +121,16 @@ func (r QueryRequest) Validate() error {
 		return fmt.Errorf("bucket parameter is required for influxql queries")
 	}
 
+	switch r.Format {
+	case "", "csv":
+	case "parquet":
+		if r.Type == "influxql" {
+			return fmt.Errorf("parquet format is not supported for influxql queries")
+		}
+	default:
+		return fmt.Errorf(`unknown query format: %s`, r.Format)
+	}
+
 	if len(r.Dialect.CommentPrefix) > 1 {
 		return fmt.Errorf("invalid dialect comment prefix: must be length 0 or 1")
 	}
@@ -301,6 +321,10 @@ func (r QueryRequest) proxyRequest(now func() time.Time) (*query.ProxyRequest, e
 		if r.Type == "influxql" {
 			// Use default transpiler dialect
 			dialect = &transpiler.Dialect{}
+		} else if r.Format == "parquet" {
+			dialect = query.NewParquetDialect()
+		} else if r.Format == "" && r.MediaType == queryMediaTypeJSON {
+			dialect = query.NewTableJSONDialect()
 		} else {
 			// TODO(nathanielc): Use commentPrefix and dateTimeFormat
 			// once they are supported.
@@ -309,10 +333,23 @@ func (r QueryRequest) proxyRequest(now func() time.Time) (*query.ProxyRequest, e
 				Delimiter:   delimiter,
 				Annotations: r.Dialect.Annotations,
 			}
+			if r.Format == "" {
+				switch r.MediaType {
+				case queryMediaTypeCSV:
+					encConfig.Annotations = nil
+				case queryMediaTypeAnnotatedCSV:
+					encConfig.Annotations = []string{"group", "datatype", "default"}
+				}
+			}
 			if r.PreferNoContentWithError {
 				dialect = &query.NoContentWithErrorDialect{
 					ResultEncoderConfig: encConfig,
 				}
+			} else if r.Format == "" && r.MediaType != "" {
+				dialect = &mediaTypeCSVDialect{
+					Dialect:   csv.Dialect{ResultEncoderConfig: encConfig},
+					MediaType: r.MediaType,
+				}
 			} else {
 				dialect = &csv.Dialect{
 					ResultEncoderConfig: encConfig,
@@ -348,7 +385,12 @@ func QueryRequestFromProxyRequest(req *query.ProxyRequest) (*QueryRequest, error
 	default:
 		return nil, fmt.Errorf("unsupported compiler %T", c)
 	}
-	switch d := req.Dialect.(type) {
+	dialect := req.Dialect
+	if d, ok := dialect.(*mediaTypeCSVDialect); ok {
+		qr.MediaType = d.MediaType
+		dialect = &d.Dialect
+	}
+	switch d := dialect.(type) {
 	case *csv.Dialect:
 		var header = !d.ResultEncoderConfig.NoHeader
 		qr.Dialect.Header = &header
@@ -360,12 +402,67 @@ func QueryRequestFromProxyRequest(req *query.ProxyRequest) (*QueryRequest, error
 		qr.PreferNoContent = true
 	case *query.NoContentWithErrorDialect:
 		qr.PreferNoContentWithError = true
+	case *query.ParquetDialect:
+		qr.Format = "parquet"
+	case *query.TableJSONDialect:
+		qr.MediaType = queryMediaTypeJSON
 	default:
 		return nil, fmt.Errorf("unsupported dialect %T", d)
 	}
 	return qr, nil
 }
 
+// The media types of query responses which may be negotiated with the Accept
+// header, in addition to the default text/csv.
+const (
+	queryMediaTypeCSV          = "application/csv"
+	queryMediaTypeAnnotatedCSV = "application/vnd.influx.annotated-csv"
+	queryMediaTypeJSON         = "application/json"
+)
+
+// negotiateQueryMediaType returns the query media type most preferred by the
+// Accept header, or an empty string if it accepts none of them, such as when
+// it is empty or only accepts the default text/csv. A type as preferred as
+// one before it in the header is not chosen over it.
+func negotiateQueryMediaType(accept string) string {
+	var best string
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		switch mt {
+		case queryMediaTypeCSV, queryMediaTypeAnnotatedCSV, queryMediaTypeJSON:
+			best, bestQ = mt, q
+		case "text/csv", "text/*", "*/*":
+			best, bestQ = "", q
+		}
+	}
+	return best
+}
+
+// mediaTypeCSVDialect is a CSV dialect whose responses have the media type
+// negotiated with the Accept header of the request.
+type mediaTypeCSVDialect struct {
+	csv.Dialect
+	MediaType string
+}
+
+func (d *mediaTypeCSVDialect) SetHeaders(w http.ResponseWriter) {
+	d.Dialect.SetHeaders(w)
+	w.Header().Set("Content-Type", d.MediaType+"; charset=utf-8")
+}
+
 func decodeQueryRequest(ctx context.Context, r *http.Request, svc influxdb.OrganizationService) (*QueryRequest, int, error) {
 	var req QueryRequest
 	body := &countReader{Reader: r.Body}
@@ -392,6 +489,8 @@ func decodeQueryRequest(ctx context.Context, r *http.Request, svc influxdb.Organ
 			return nil, body.bytesRead, err
 		}
 	}
+	req.Format = r.URL.Query().Get("format")
+	req.MediaType = negotiateQueryMediaType(r.Header.Get("Accept"))
 
 	switch hv := r.Header.Get(query.PreferHeaderKey); hv {
 	case query.PreferNoContentHeaderValue:
@@ -431,18 +530,26 @@ func decodeProxyQueryRequest(ctx context.Context, r *http.Request, auth influxdb
 		return nil, n, err
 	}
 
-	var token *influxdb.Authorization
+	token, err := queryAuthorization(auth, req.Org.ID)
+	if err != nil {
+		return pr, n, err
+	}
+
+	pr.Request.Authorization = token
+	return pr, n, nil
+}
+
+// queryAuthorization returns the authorization a query for orgID made by auth
+// is executed with.
+func queryAuthorization(auth influxdb.Authorizer, orgID influxdb.ID) (*influxdb.Authorization, error) {
 	switch a := auth.(type) {
 	case *influxdb.Authorization:
-		token = a
+		return a, nil
 	case *influxdb.Session:
-		token = a.EphemeralAuth(req.Org.ID)
+		return a.EphemeralAuth(orgID), nil
 	case *jsonweb.Token:
-		token = a.EphemeralAuth(req.Org.ID)
+		return a.EphemeralAuth(orgID), nil
 	default:
-		return pr, n, influxdb.ErrAuthorizerNotSupported
+		return nil, influxdb.ErrAuthorizerNotSupported
 	}
-
-	pr.Request.Authorization = token
-	return pr, n, nil
 }
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/influxdb"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/query"
+)
+
+// fluxErrorDetail is an error in the source of a Flux query, at the line and
+// column where it starts, which are omitted when unknown.
+type fluxErrorDetail struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// fluxCompileError is the body of the response to a Flux query which fails
+// to compile, with the details of each error in its source.
+type fluxCompileError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details []fluxErrorDetail `json:"details"`
+}
+
+// fluxCompileErrorDetails returns the errors in the source of the Flux query
+// of the request, if err is a failure of the query to compile. The compiler
+// only reports the first error, so the source is parsed again for all of them.
+func fluxCompileErrorDetails(req *query.ProxyRequest, err error) []fluxErrorDetail {
+	if influxdb.ErrorCode(err) != influxdb.EInvalid {
+		return nil
+	}
+
+	var pkg *ast.Package
+	switch c := req.Request.Compiler.(type) {
+	case lang.FluxCompiler:
+		pkg = parser.ParseSource(c.Query)
+	case lang.ASTCompiler:
+		pkg = c.AST
+	}
+	if pkg == nil || ast.Check(pkg) == 0 {
+		return nil
+	}
+
+	v := &fluxErrorVisitor{}
+	ast.Walk(v, pkg)
+	return v.details
+}
+
+// fluxErrorVisitor collects the errors of the nodes of an AST. The parser
+// does not locate some of the nodes with errors, which are then reported at
+// the location of their closest located ancestor.
+type fluxErrorVisitor struct {
+	located []ast.SourceLocation
+	details []fluxErrorDetail
+}
+
+func (v *fluxErrorVisitor) Visit(n ast.Node) ast.Visitor {
+	loc := n.Location()
+	if loc.Start.Line == 0 && len(v.located) > 0 {
+		loc = v.located[len(v.located)-1]
+	}
+	v.located = append(v.located, loc)
+
+	for _, e := range n.Errs() {
+		v.details = append(v.details, fluxErrorDetail{
+			Line:    loc.Start.Line,
+			Column:  loc.Start.Column,
+			Message: e.Msg,
+		})
+	}
+	return v
+}
+
+func (v *fluxErrorVisitor) Done(n ast.Node) {
+	v.located = v.located[:len(v.located)-1]
+}
+
+// encodeFluxCompileError writes the failure of a Flux query to compile as a
+// bad request, along with the details of the errors in its source.
+func encodeFluxCompileError(w http.ResponseWriter, err error, details []fluxErrorDetail) {
+	w.Header().Set(kithttp.PlatformErrorCodeHeader, influxdb.EInvalid)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	b, _ := json.Marshal(fluxCompileError{
+		Code:    influxdb.EInvalid,
+		Message: err.Error(),
+		Details: details,
+	})
+	_, _ = w.Write(b)
+}
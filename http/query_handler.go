@@ -104,6 +104,7 @@ func NewFluxHandler(log *zap.Logger, b *FluxBackend) *FluxHandler {
 	// query reponses can optionally be gzip encoded
 	qh := gziphandler.GzipHandler(http.HandlerFunc(h.handleQuery))
 	h.Handler("POST", prefixQuery, qh)
+	h.HandlerFunc("GET", prefixQueryStream, h.handleQueryStream)
 	h.HandlerFunc("POST", "/api/v2/query/ast", h.postFluxAST)
 	h.HandlerFunc("POST", "/api/v2/query/analyze", h.postQueryAnalyze)
 	h.HandlerFunc("GET", "/api/v2/query/suggestions", h.getFluxSuggestions)
@@ -152,8 +153,12 @@ func (h *FluxHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 	req, n, err := decodeProxyQueryRequest(ctx, r, a, h.OrganizationService)
 	if err != nil && err != influxdb.ErrAuthorizerNotSupported {
+		code := influxdb.EInvalid
+		if influxdb.ErrorCode(err) == influxdb.ETooLarge {
+			code = influxdb.ETooLarge
+		}
 		err := &influxdb.Error{
-			Code: influxdb.EInvalid,
+			Code: code,
 			Msg:  "failed to decode request body",
 			Op:   op,
 			Err:  err,
@@ -184,6 +189,10 @@ func (h *FluxHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if _, err := h.ProxyQueryService.Query(ctx, &cw, req); err != nil {
 		if cw.Count() == 0 {
 			// Only record the error headers IFF nothing has been written to w.
+			if details := fluxCompileErrorDetails(req, err); len(details) > 0 {
+				encodeFluxCompileError(w, err, details)
+				return
+			}
 			h.HandleHTTPError(ctx, err, w)
 			return
 		}
@@ -371,12 +380,15 @@ func (s *FluxService) Query(ctx context.Context, w io.Writer, r *query.ProxyRequ
 	}
 	params := url.Values{}
 	params.Set(OrgID, r.Request.OrganizationID.String())
-	u.RawQuery = params.Encode()
 
 	qreq, err := QueryRequestFromProxyRequest(r)
 	if err != nil {
 		return flux.Statistics{}, tracing.LogError(span, err)
 	}
+	if qreq.Format != "" {
+		params.Set("format", qreq.Format)
+	}
+	u.RawQuery = params.Encode()
 	var body bytes.Buffer
 	if err := json.NewEncoder(&body).Encode(qreq); err != nil {
 		return flux.Statistics{}, tracing.LogError(span, err)
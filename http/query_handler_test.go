@@ -447,6 +447,59 @@ func TestFluxHandler_PostQuery_Errors(t *testing.T) {
 			t.Fatalf("expected error message to mention 'some query error', got %s", ierr.Err.Error())
 		}
 	})
+
+	t.Run("valid request but query fails to compile", func(t *testing.T) {
+		org := influxdb.Organization{Name: t.Name()}
+		if err := orgSVC.CreateOrganization(context.Background(), &org); err != nil {
+			t.Fatal(err)
+		}
+
+		q := "a = 1\nb = 2\nc = )\nd = (\n"
+		req, err := http.NewRequest("POST", "/api/v2/query?orgID="+org.ID.String(), strings.NewReader(q))
+		if err != nil {
+			t.Fatal(err)
+		}
+		authz := &influxdb.Authorization{}
+		req = req.WithContext(icontext.SetAuthorizer(req.Context(), authz))
+		req.Header.Set("Content-Type", "application/vnd.flux")
+
+		w := httptest.NewRecorder()
+		h.handleQuery(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected bad request status, got %d", w.Code)
+		}
+
+		var got fluxCompileError
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Logf("failed to json unmarshal into compile error: %q", w.Body.Bytes())
+			t.Fatal(err)
+		}
+		want := fluxCompileError{
+			Code:    influxdb.EInvalid,
+			Message: "some query error",
+			Details: []fluxErrorDetail{
+				{Line: 3, Column: 5, Message: "invalid statement @3:5-3:6: )"},
+				{Line: 4, Column: 5, Message: "expected RPAREN, got EOF"},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected compile error -want/+got:\n%s", diff)
+		}
+	})
+
+	t.Run("GET is not allowed", func(t *testing.T) {
+		// A query in the URL could be run by any page that links to it.
+		req := httptest.NewRequest("GET", "/api/v2/query?query=buckets()&format=parquet", nil)
+		req = req.WithContext(icontext.SetAuthorizer(req.Context(), &influxdb.Authorization{}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected method not allowed status, got %d", w.Code)
+		}
+	})
 }
 
 func TestFluxService_Query_gzip(t *testing.T) {
@@ -0,0 +1,167 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
+	"go.uber.org/zap"
+	"nhooyr.io/websocket"
+)
+
+const prefixQueryStream = "/api/v2/query/stream"
+
+// queryStreamMessage is a message sent over the WebSocket of a query stream
+// other than a query request or a table of the results.
+type queryStreamMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+}
+
+// handleQueryStream upgrades the request to a WebSocket over which the client
+// sends a query request, in the JSON format of the body of /api/v2/query. The
+// tables of the results are sent as they are produced, each in a message
+// encoded by query.TableJSONEncoder, after which the connection is closed. The
+// client can send a message of type "cancel" to stop the query.
+func (h *FluxHandler) handleQueryStream(w http.ResponseWriter, r *http.Request) {
+	const op = "http/handleQueryStream"
+	span, r := tracing.ExtractFromHTTPRequest(r, "FluxHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	log := h.log.With(logger.TraceFields(ctx)...)
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "authorization is invalid or missing in the query request",
+			Op:   op,
+			Err:  err,
+		}, w)
+		return
+	}
+	org, err := queryOrganization(ctx, r, h.OrganizationService)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	auth, err := queryAuthorization(a, org.ID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has already written the error response.
+		log.Info("Failed to upgrade query stream", zap.Error(err))
+		return
+	}
+	defer conn.Close(websocket.StatusInternalError, "")
+
+	req, err := readQueryStreamRequest(ctx, conn, org)
+	if err != nil {
+		closeQueryStream(ctx, conn, websocket.StatusInvalidFramePayloadData, err)
+		return
+	}
+	req.Request.Authorization = auth
+	req.Request.Source = r.Header.Get("User-Agent")
+	req.Dialect = query.NewTableJSONDialect()
+
+	// The query is canceled when the client cancels it or the connection
+	// fails, while the messages are sent with the context of the request.
+	qctx, cancel := context.WithCancel(pcontext.SetAuthorizer(ctx, auth))
+	defer cancel()
+	canceled := make(chan struct{})
+	go func() {
+		if waitQueryStreamCancel(ctx, conn) {
+			close(canceled)
+		}
+		cancel()
+	}()
+
+	sw := &queryStreamWriter{ctx: ctx, conn: conn}
+	if _, err := h.ProxyQueryService.Query(qctx, sw, req); err != nil {
+		select {
+		case <-canceled:
+			conn.Close(websocket.StatusNormalClosure, "query canceled")
+		default:
+			_ = tracing.LogError(span, err)
+			log.Info("Error streaming query results", zap.Error(err))
+			closeQueryStream(ctx, conn, websocket.StatusInternalError, err)
+		}
+		return
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// readQueryStreamRequest reads the query request of a query stream in org.
+func readQueryStreamRequest(ctx context.Context, conn *websocket.Conn, org *influxdb.Organization) (*query.ProxyRequest, error) {
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var qr QueryRequest
+	if err := json.Unmarshal(data, &qr); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "failed to decode query request",
+			Err:  err,
+		}
+	}
+	qr = qr.WithDefaults()
+	qr.Org = org
+	return qr.ProxyRequest()
+}
+
+// waitQueryStreamCancel reads the messages of a query stream until the client
+// cancels the query, and reports whether it did, or the connection fails.
+func waitQueryStreamCancel(ctx context.Context, conn *websocket.Conn) bool {
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return false
+		}
+		var m queryStreamMessage
+		if err := json.Unmarshal(data, &m); err == nil && m.Type == "cancel" {
+			return true
+		}
+	}
+}
+
+// closeQueryStream sends err in a message of type "error" and closes conn
+// with code.
+func closeQueryStream(ctx context.Context, conn *websocket.Conn, code websocket.StatusCode, err error) {
+	msg, _ := json.Marshal(queryStreamMessage{Type: "error", Message: err.Error()})
+	_ = conn.Write(ctx, websocket.MessageText, msg)
+	conn.Close(code, "")
+}
+
+// queryStreamWriter sends each line written to it as a text message.
+type queryStreamWriter struct {
+	ctx  context.Context
+	conn *websocket.Conn
+	buf  []byte
+}
+
+func (w *queryStreamWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.conn.Write(w.ctx, websocket.MessageText, w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
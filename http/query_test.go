@@ -451,6 +451,39 @@ func Test_decodeQueryRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "valid query request with parquet format",
+			args: args{
+				r: httptest.NewRequest("POST", "/?format=parquet", bytes.NewBufferString(`{"query": "from()"}`)),
+				svc: &mock.OrganizationService{
+					FindOrganizationF: func(ctx context.Context, filter platform.OrganizationFilter) (*platform.Organization, error) {
+						return &platform.Organization{
+							ID: func() platform.ID { s, _ := platform.IDFromString("deadbeefdeadbeef"); return *s }(),
+						}, nil
+					},
+				},
+			},
+			want: &QueryRequest{
+				Query:  "from()",
+				Type:   "flux",
+				Format: "parquet",
+				Dialect: QueryDialect{
+					Delimiter:      ",",
+					DateTimeFormat: "RFC3339",
+					Header:         func(x bool) *bool { return &x }(true),
+				},
+				Org: &platform.Organization{
+					ID: func() platform.ID { s, _ := platform.IDFromString("deadbeefdeadbeef"); return *s }(),
+				},
+			},
+		},
+		{
+			name: "unknown format",
+			args: args{
+				r: httptest.NewRequest("POST", "/?format=xlsx", bytes.NewBufferString(`{"query": "from()"}`)),
+			},
+			wantErr: true,
+		},
 		{
 			name: "error decoding json",
 			args: args{
@@ -629,3 +662,27 @@ func Test_decodeProxyQueryRequest(t *testing.T) {
 		})
 	}
 }
+
+func Test_negotiateQueryMediaType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ""},
+		{accept: "*/*", want: ""},
+		{accept: "text/csv", want: ""},
+		{accept: "application/csv", want: "application/csv"},
+		{accept: "application/vnd.influx.annotated-csv", want: "application/vnd.influx.annotated-csv"},
+		{accept: "application/json; charset=utf-8", want: "application/json"},
+		{accept: "image/png, application/json", want: "application/json"},
+		{accept: "application/json;q=0.5, application/csv", want: "application/csv"},
+		{accept: "application/json, */*", want: "application/json"},
+		{accept: "*/*, application/json", want: ""},
+		{accept: "application/json;q=0", want: ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateQueryMediaType(tt.accept); got != tt.want {
+			t.Errorf("negotiateQueryMediaType(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/zap"
+)
+
+// ReadinessBackend is all services and associated parameters required to construct
+// the ReadinessHandler.
+type ReadinessBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	ReadinessService influxdb.ReadinessService
+}
+
+// NewReadinessBackend returns a new instance of ReadinessBackend.
+func NewReadinessBackend(log *zap.Logger, b *APIBackend) *ReadinessBackend {
+	return &ReadinessBackend{
+		log: log,
+
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		ReadinessService: b.ReadinessService,
+	}
+}
+
+// ReadinessHandler reports whether the storage engine is ready to accept
+// writes. Unlike /ready, which only reports that the HTTP server is up, it is
+// meant for readiness probes.
+type ReadinessHandler struct {
+	influxdb.HTTPErrorHandler
+	*httprouter.Router
+
+	log *zap.Logger
+
+	ReadinessService influxdb.ReadinessService
+}
+
+const (
+	prefixReadiness = "/api/v2/ready"
+)
+
+// NewReadinessHandler creates a new handler at /api/v2/ready to report the
+// readiness of the storage engine.
+func NewReadinessHandler(log *zap.Logger, b *ReadinessBackend) *ReadinessHandler {
+	h := &ReadinessHandler{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		Router:           NewRouter(b.HTTPErrorHandler),
+		log:              log,
+
+		ReadinessService: b.ReadinessService,
+	}
+
+	h.HandlerFunc(http.MethodGet, prefixReadiness, h.handleGetReadiness)
+	return h
+}
+
+type readinessResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func (h *ReadinessHandler) handleGetReadiness(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "ReadinessHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	code, res := http.StatusOK, readinessResponse{Status: "ready"}
+	if err := h.ReadinessService.Ready(ctx); err != nil {
+		code, res = http.StatusServiceUnavailable, readinessResponse{Status: "starting", Message: err.Error()}
+	}
+
+	if err := encodeResponse(ctx, w, code, res); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
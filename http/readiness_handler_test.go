@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"go.uber.org/zap/zaptest"
+)
+
+type readinessServiceFunc func(ctx context.Context) error
+
+func (fn readinessServiceFunc) Ready(ctx context.Context) error {
+	return fn(ctx)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		response   readinessResponse
+	}{
+		{
+			name:       "ready",
+			statusCode: http.StatusOK,
+			response:   readinessResponse{Status: "ready"},
+		},
+		{
+			name:       "starting",
+			err:        errors.New("engine is closed"),
+			statusCode: http.StatusServiceUnavailable,
+			response:   readinessResponse{Status: "starting", Message: "engine is closed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &ReadinessBackend{
+				log:              zaptest.NewLogger(t),
+				HTTPErrorHandler: kithttp.ErrorHandler(0),
+				ReadinessService: readinessServiceFunc(func(ctx context.Context) error {
+					return tt.err
+				}),
+			}
+			h := NewReadinessHandler(zaptest.NewLogger(t), b)
+
+			r := httptest.NewRequest("GET", "http://any.tld"+prefixReadiness, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != tt.statusCode {
+				t.Fatalf("got status code %d, exp %d", got, tt.statusCode)
+			}
+			var got readinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.response {
+				t.Errorf("got response %+v, exp %+v", got, tt.response)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/zap"
+)
+
+// RecoveryBackend is all services and associated parameters required to construct
+// the RecoveryHandler.
+type RecoveryBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	RecoveryService influxdb.RecoveryService
+}
+
+// NewRecoveryBackend returns a new instance of RecoveryBackend.
+func NewRecoveryBackend(log *zap.Logger, b *APIBackend) *RecoveryBackend {
+	return &RecoveryBackend{
+		log: log,
+
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		RecoveryService:  b.RecoveryService,
+	}
+}
+
+// RecoveryHandler reports the corrupt files skipped by the storage engine.
+type RecoveryHandler struct {
+	influxdb.HTTPErrorHandler
+	*httprouter.Router
+
+	log *zap.Logger
+
+	RecoveryService influxdb.RecoveryService
+}
+
+const (
+	prefixRecoveryReport = "/api/v2/engine/recovery-report"
+)
+
+// NewRecoveryHandler creates a new handler at /api/v2/engine/recovery-report to
+// report the files skipped in recovery mode.
+func NewRecoveryHandler(log *zap.Logger, b *RecoveryBackend) *RecoveryHandler {
+	h := &RecoveryHandler{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		Router:           NewRouter(b.HTTPErrorHandler),
+		log:              log,
+
+		RecoveryService: b.RecoveryService,
+	}
+
+	h.HandlerFunc(http.MethodGet, prefixRecoveryReport, h.handleGetRecoveryReport)
+	return h
+}
+
+func (h *RecoveryHandler) handleGetRecoveryReport(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "RecoveryHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	report, err := h.RecoveryService.RecoveryReport(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, report); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	pcontext "github.com/influxdata/influxdb/context"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"go.uber.org/zap/zaptest"
+)
+
+type recoveryServiceFunc func(ctx context.Context) (*influxdb.RecoveryReport, error)
+
+func (fn recoveryServiceFunc) RecoveryReport(ctx context.Context) (*influxdb.RecoveryReport, error) {
+	return fn(ctx)
+}
+
+func TestRecoveryReport(t *testing.T) {
+	min, max := time.Unix(10, 0).UTC(), time.Unix(20, 0).UTC()
+	report := &influxdb.RecoveryReport{
+		RecoveryMode: true,
+		SkippedFiles: []influxdb.SkippedFile{
+			{Path: "/engine/data/000000002-000000001.tsm", Size: 1024, MinTime: &min, MaxTime: &max, Error: "unexpected checksum"},
+			{Path: "/engine/data/000000003-000000001.tsm", Size: 12, Error: "invalid indexStart"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		authorizer influxdb.Authorizer
+		statusCode int
+	}{
+		{
+			name:       "operator",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OperPermissions()},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "org owner",
+			authorizer: &influxdb.Authorization{UserID: user1ID, Status: influxdb.Active, Permissions: influxdb.OwnerPermissions(1)},
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := recoveryServiceFunc(func(ctx context.Context) (*influxdb.RecoveryReport, error) {
+				return report, nil
+			})
+
+			b := &RecoveryBackend{
+				log:              zaptest.NewLogger(t),
+				HTTPErrorHandler: kithttp.ErrorHandler(0),
+				RecoveryService:  authorizer.NewRecoveryService(svc),
+			}
+			h := NewRecoveryHandler(zaptest.NewLogger(t), b)
+
+			r := httptest.NewRequest("GET", "http://any.tld"+prefixRecoveryReport, nil)
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), tt.authorizer))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != tt.statusCode {
+				t.Fatalf("got status code %d, exp %d", got, tt.statusCode)
+			}
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			var got influxdb.RecoveryReport
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, report) {
+				t.Fatalf("got report %+v, exp %+v", got, report)
+			}
+		})
+	}
+}
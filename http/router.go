@@ -35,7 +35,6 @@ func newBaseChiRouter(errorHandler platform.HTTPErrorHandler) chi.Router {
 	router.MethodNotAllowed(bh.methodNotAllowed)
 	router.Use(kithttp.SkipOptions)
 	router.Use(middleware.StripSlashes)
-	router.Use(kithttp.SetCORS)
 	return router
 }
 
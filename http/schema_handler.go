@@ -0,0 +1,332 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap"
+)
+
+// SchemaBackend is all services and associated parameters required to construct
+// the SchemaHandler.
+type SchemaBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	BucketService       influxdb.BucketService
+	SchemaService       influxdb.SchemaService
+	BucketSchemaService influxdb.BucketSchemaService
+}
+
+// NewSchemaBackend returns a new instance of SchemaBackend.
+func NewSchemaBackend(log *zap.Logger, b *APIBackend) *SchemaBackend {
+	return &SchemaBackend{
+		log: log,
+
+		HTTPErrorHandler:    b.HTTPErrorHandler,
+		BucketService:       b.BucketService,
+		SchemaService:       b.SchemaService,
+		BucketSchemaService: b.BucketSchemaService,
+	}
+}
+
+// SchemaHandler exports the schema of the data of a bucket, and imports it as
+// the measurement schemas of another bucket. It also reports how the values of
+// the tags of a bucket are distributed among its series.
+type SchemaHandler struct {
+	*httprouter.Router
+	api *kithttp.API
+	log *zap.Logger
+
+	BucketService       influxdb.BucketService
+	SchemaService       influxdb.SchemaService
+	BucketSchemaService influxdb.BucketSchemaService
+}
+
+const (
+	prefixSchema              = "/api/v2/schema"
+	schemaBucketsIDExportPath = "/api/v2/schema/buckets/:id/export"
+	schemaBucketsIDImportPath = "/api/v2/schema/buckets/:id/import"
+	schemaMeasurementsPath    = "/api/v2/schema/buckets/:id/measurements"
+	schemaTagKeysPath         = "/api/v2/schema/buckets/:id/measurements/:name/tags"
+	schemaTagHistogramPath    = "/api/v2/schema/buckets/:id/measurements/:name/tags/:key/histogram"
+
+	// defaultTagHistogramTopN is the number of tag values in a histogram
+	// unless the topN parameter is given.
+	defaultTagHistogramTopN = 20
+)
+
+// NewSchemaHandler creates a new handler at /api/v2/schema to export and
+// import the schemas of buckets.
+func NewSchemaHandler(log *zap.Logger, b *SchemaBackend) *SchemaHandler {
+	h := &SchemaHandler{
+		Router: NewRouter(b.HTTPErrorHandler),
+		api:    kithttp.NewAPI(kithttp.WithLog(log)),
+		log:    log,
+
+		BucketService:       b.BucketService,
+		SchemaService:       b.SchemaService,
+		BucketSchemaService: b.BucketSchemaService,
+	}
+
+	h.HandlerFunc(http.MethodGet, schemaBucketsIDExportPath, h.handleExportBucketSchema)
+	h.HandlerFunc(http.MethodPost, schemaBucketsIDImportPath, h.handleImportBucketSchema)
+	h.HandlerFunc(http.MethodGet, schemaMeasurementsPath, h.handleGetMeasurements)
+	h.HandlerFunc(http.MethodGet, schemaTagKeysPath, h.handleGetTagKeys)
+	h.HandlerFunc(http.MethodGet, schemaTagHistogramPath, h.handleGetTagValueHistogram)
+	return h
+}
+
+// handleExportBucketSchema is the HTTP handler for the GET /api/v2/schema/buckets/:id/export route.
+func (h *SchemaHandler) handleExportBucketSchema(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SchemaHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	b, err := h.findBucket(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	measurements, err := h.BucketSchemaService.BucketSchema(ctx, b.OrgID, b.ID)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, influxdb.BucketSchema{
+		GeneratedAt:   time.Now().UTC(),
+		SourceVersion: influxdb.GetBuildInfo().Version,
+		Measurements:  measurements,
+	})
+}
+
+// handleImportBucketSchema is the HTTP handler for the POST /api/v2/schema/buckets/:id/import route.
+// It registers the schema of each measurement of an exported bucket schema,
+// none of which may have a schema already.
+func (h *SchemaHandler) handleImportBucketSchema(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SchemaHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	var schema influxdb.BucketSchema
+	if err := h.api.DecodeJSON(r.Body, &schema); err != nil {
+		h.api.Err(w, err)
+		return
+	}
+	if err := schema.Valid(); err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	b, err := h.findBucket(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	// Check every measurement before registering any, so that a conflict
+	// does not leave the schema partially imported.
+	for _, m := range schema.Measurements {
+		_, err := h.SchemaService.FindMeasurementSchema(ctx, b.OrgID, b.ID, m.Measurement)
+		if err == nil {
+			h.api.Err(w, &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("measurement %q already has a schema", m.Measurement),
+			})
+			return
+		}
+		if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			h.api.Err(w, err)
+			return
+		}
+	}
+
+	for _, m := range schema.Measurements {
+		if err := h.SchemaService.CreateMeasurementSchema(ctx, b.OrgID, b.ID, m.MeasurementSchema); err != nil {
+			h.api.Err(w, err)
+			return
+		}
+	}
+	h.log.Debug("Bucket schema imported", zap.Stringer("bucket", b.ID), zap.Int("measurements", len(schema.Measurements)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type measurementsResponse struct {
+	Measurements []string `json:"measurements"`
+}
+
+// handleGetMeasurements is the HTTP handler for the GET /api/v2/schema/buckets/:id/measurements route.
+func (h *SchemaHandler) handleGetMeasurements(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SchemaHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	b, err := h.findBucket(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	measurements, err := h.BucketSchemaService.BucketSchema(ctx, b.OrgID, b.ID)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	names := make([]string, 0, len(measurements))
+	for _, m := range measurements {
+		names = append(names, m.Measurement)
+	}
+	h.api.Respond(w, http.StatusOK, measurementsResponse{Measurements: names})
+}
+
+type tagKeysResponse struct {
+	Measurement string   `json:"measurement"`
+	TagKeys     []string `json:"tagKeys"`
+}
+
+// handleGetTagKeys is the HTTP handler for the GET /api/v2/schema/buckets/:id/measurements/:name/tags route.
+func (h *SchemaHandler) handleGetTagKeys(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SchemaHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	name := httprouter.ParamsFromContext(ctx).ByName("name")
+	b, err := h.findBucket(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	measurements, err := h.BucketSchemaService.BucketSchema(ctx, b.OrgID, b.ID)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	for _, m := range measurements {
+		if m.Measurement != name {
+			continue
+		}
+		tagKeys := m.TagKeys
+		if tagKeys == nil {
+			tagKeys = []string{}
+		}
+		h.api.Respond(w, http.StatusOK, tagKeysResponse{Measurement: name, TagKeys: tagKeys})
+		return
+	}
+	h.api.Err(w, &influxdb.Error{
+		Code: influxdb.ENotFound,
+		Msg:  fmt.Sprintf("measurement %q not found", name),
+	})
+}
+
+type tagValueHistogramResponse struct {
+	Measurement string                   `json:"measurement"`
+	TagKey      string                   `json:"tagKey"`
+	Values      []influxdb.TagValueCount `json:"values"`
+}
+
+// handleGetTagValueHistogram is the HTTP handler for the GET /api/v2/schema/buckets/:id/measurements/:name/tags/:key/histogram route.
+func (h *SchemaHandler) handleGetTagValueHistogram(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SchemaHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	req, err := decodeTagValueHistogramRequest(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	b, err := h.findBucket(r)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	values, err := h.BucketSchemaService.TagValueHistogram(ctx, b.OrgID, b.ID, req.Measurement, req.TagKey, req.Start, req.Stop, req.TopN)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, tagValueHistogramResponse{
+		Measurement: req.Measurement,
+		TagKey:      req.TagKey,
+		Values:      values,
+	})
+}
+
+type tagValueHistogramRequest struct {
+	Measurement string
+	TagKey      string
+	Start, Stop int64
+	TopN        int
+}
+
+func decodeTagValueHistogramRequest(r *http.Request) (*tagValueHistogramRequest, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	req := &tagValueHistogramRequest{
+		Measurement: params.ByName("name"),
+		TagKey:      params.ByName("key"),
+		Start:       models.MinNanoTime,
+		Stop:        models.MaxNanoTime,
+		TopN:        defaultTagHistogramTopN,
+	}
+
+	qp := r.URL.Query()
+	if s := qp.Get("topN"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid topN %q, must be a positive integer", s),
+			}
+		}
+		req.TopN = n
+	}
+
+	for _, p := range []struct {
+		name string
+		t    *int64
+	}{
+		{name: "start", t: &req.Start},
+		{name: "stop", t: &req.Stop},
+	} {
+		s := qp.Get(p.name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid RFC3339Nano for %s, please format your time with RFC3339Nano format, example: 2009-01-02T23:00:00Z", p.name),
+				Err:  err,
+			}
+		}
+		*p.t = t.UnixNano()
+	}
+	return req, nil
+}
+
+// findBucket returns the bucket with the ID of the request, which ensures
+// the requester may read it.
+func (h *SchemaHandler) findBucket(r *http.Request) (*influxdb.Bucket, error) {
+	id, err := decodeIDFromCtx(r.Context(), "id")
+	if err != nil {
+		return nil, err
+	}
+	return h.BucketService.FindBucketByID(r.Context(), id)
+}
@@ -12,6 +12,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// prefixOpenAPI is the path of the OpenAPI 3.0 specification of the API.
+const prefixOpenAPI = "/api/v2/openapi.json"
+
 var _ http.Handler = (*swaggerLoader)(nil)
 
 // swaggerLoader manages loading the swagger asset and serving it as JSON.
@@ -27,15 +30,28 @@ type swaggerLoader struct {
 
 	// The error loading the swagger asset.
 	loadErr error
+
+	// The error code returned when the swagger asset is missing.
+	missingCode string
 }
 
 func newSwaggerLoader(log *zap.Logger, h influxdb.HTTPErrorHandler) *swaggerLoader {
 	return &swaggerLoader{
 		log:              log,
 		HTTPErrorHandler: h,
+		missingCode:      influxdb.EInternal,
 	}
 }
 
+// newOpenAPILoader returns a loader serving the OpenAPI 3.0 specification of
+// swagger.yml as JSON. Developer binaries built without the asset, which
+// cannot locate swagger.yml on disk, fail with 503 Service Unavailable.
+func newOpenAPILoader(log *zap.Logger, h influxdb.HTTPErrorHandler) *swaggerLoader {
+	s := newSwaggerLoader(log, h)
+	s.missingCode = influxdb.EUnavailable
+	return s
+}
+
 func (s *swaggerLoader) initialize() {
 	swagger, err := s.asset(Asset("swagger.yml"))
 	if err != nil {
@@ -58,7 +74,7 @@ func (s *swaggerLoader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.HandleHTTPError(r.Context(), &influxdb.Error{
 			Err:  s.loadErr,
 			Msg:  "this developer binary not built with assets",
-			Code: influxdb.EInternal,
+			Code: s.missingCode,
 		}, w)
 		return
 	}
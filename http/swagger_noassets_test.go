@@ -0,0 +1,26 @@
+//go:build !assets
+// +build !assets
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestOpenAPILoader_Missing(t *testing.T) {
+	defer setSwaggerPath(t, filepath.Join(t.Name(), "missing.yml"))()
+
+	h := newOpenAPILoader(zaptest.NewLogger(t), kithttp.ErrorHandler(0))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, prefixOpenAPI, nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status code: got %d, exp %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+	}
+}
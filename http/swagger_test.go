@@ -2,10 +2,16 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"go.uber.org/zap/zaptest"
 )
 
 func TestValidSwagger(t *testing.T) {
@@ -23,3 +29,47 @@ func TestValidSwagger(t *testing.T) {
 		t.Errorf("invalid swagger specification: %v", err)
 	}
 }
+
+// setSwaggerPath points the loaders of developer binaries at path and
+// returns a function restoring the previous path.
+func setSwaggerPath(t *testing.T, path string) func() {
+	t.Helper()
+
+	old, ok := os.LookupEnv("INFLUXDB_VALID_SWAGGER_PATH")
+	if err := os.Setenv("INFLUXDB_VALID_SWAGGER_PATH", path); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if ok {
+			os.Setenv("INFLUXDB_VALID_SWAGGER_PATH", old)
+		} else {
+			os.Unsetenv("INFLUXDB_VALID_SWAGGER_PATH")
+		}
+	}
+}
+
+func TestOpenAPILoader(t *testing.T) {
+	defer setSwaggerPath(t, "./swagger.yml")()
+
+	h := newOpenAPILoader(zaptest.NewLogger(t), kithttp.ErrorHandler(0))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, prefixOpenAPI, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: got %d, exp %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got, exp := rec.Header().Get("Content-Type"), "application/json"; got != exp {
+		t.Errorf("unexpected content type: got %q, exp %q", got, exp)
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid JSON specification: %v", err)
+	}
+	if got, exp := spec["openapi"], "3.0.0"; got != exp {
+		t.Errorf("unexpected openapi version: got %v, exp %q", got, exp)
+	}
+	if _, ok := spec["paths"].(map[string]interface{})["/openapi.json"]; !ok {
+		t.Error("specification does not describe /openapi.json")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"strconv"
 
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
@@ -148,11 +150,19 @@ func newGetMembersHandler(b MemberBackend) http.HandlerFunc {
 		}
 
 		opts := influxdb.FindOptions{}
-		mappings, _, err := b.UserResourceMappingService.FindUserResourceMappings(ctx, filter)
+		mappings, n, err := b.UserResourceMappingService.FindUserResourceMappings(ctx, filter, req.opts)
 		if err != nil {
 			b.HandleHTTPError(ctx, err, w)
 			return
 		}
+		if next := req.opts.Offset + len(mappings); len(mappings) > 0 && next < n {
+			u := url.URL{Path: r.URL.Path}
+			qp := r.URL.Query()
+			qp.Set("limit", strconv.Itoa(req.opts.Limit))
+			qp.Set("offset", strconv.Itoa(next))
+			u.RawQuery = qp.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.String()))
+		}
 
 		users := make([]*influxdb.User, 0, len(mappings))
 		for _, m := range mappings {
@@ -179,6 +189,7 @@ func newGetMembersHandler(b MemberBackend) http.HandlerFunc {
 type getMembersRequest struct {
 	MemberID   influxdb.ID
 	ResourceID influxdb.ID
+	opts       influxdb.FindOptions
 }
 
 func decodeGetMembersRequest(ctx context.Context, r *http.Request) (*getMembersRequest, error) {
@@ -196,13 +207,56 @@ func decodeGetMembersRequest(ctx context.Context, r *http.Request) (*getMembersR
 		return nil, err
 	}
 
+	opts, err := decodeMembersFindOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &getMembersRequest{
 		ResourceID: i,
+		opts:       opts,
 	}
 
 	return req, nil
 }
 
+// decodeMembersFindOptions decodes the offset and limit of a page of members.
+// The limit defaults to influxdb.URMMaxPageSize.
+func decodeMembersFindOptions(r *http.Request) (influxdb.FindOptions, error) {
+	opts := influxdb.FindOptions{Limit: influxdb.URMMaxPageSize}
+	qp := r.URL.Query()
+
+	if offset := qp.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			return opts, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "offset is invalid",
+			}
+		}
+		opts.Offset = o
+	}
+
+	if limit := qp.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return opts, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "limit is invalid",
+			}
+		}
+		if l < 1 || l > influxdb.URMMaxPageSize {
+			return opts, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("limit must be between 1 and %d", influxdb.URMMaxPageSize),
+			}
+		}
+		opts.Limit = l
+	}
+
+	return opts, nil
+}
+
 // newDeleteMemberHandler returns a handler func for a DELETE to /members or /owners endpoints
 func newDeleteMemberHandler(b MemberBackend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -8,10 +8,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/httprouter"
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	pcontext "github.com/influxdata/influxdb/context"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/mock"
 	"go.uber.org/zap/zaptest"
 )
@@ -223,6 +227,93 @@ func TestUserResourceMappingService_GetMembersHandler(t *testing.T) {
 	}
 }
 
+func TestUserResourceMappingService_GetMembersHandlerPaging(t *testing.T) {
+	const count = 2000
+	resourceID := platform.ID(0x99)
+	urms := make([]*platform.UserResourceMapping, 0, count)
+	for i := 1; i <= count; i++ {
+		urms = append(urms, &platform.UserResourceMapping{
+			ResourceID:   resourceID,
+			ResourceType: platform.BucketsResourceType,
+			UserType:     platform.Member,
+			UserID:       platform.ID(i),
+		})
+	}
+	memberBackend := MemberBackend{
+		HTTPErrorHandler: kithttp.ErrorHandler(0),
+		log:              zaptest.NewLogger(t),
+		ResourceType:     platform.BucketsResourceType,
+		UserType:         platform.Member,
+		UserResourceMappingService: authorizer.NewURMService(
+			&mock.OrganizationService{
+				FindResourceOrganizationIDF: func(ctx context.Context, rt platform.ResourceType, id platform.ID) (platform.ID, error) {
+					return 10, nil
+				},
+			},
+			&mock.UserResourceMappingService{
+				FindMappingsFn: func(ctx context.Context, filter platform.UserResourceMappingFilter) ([]*platform.UserResourceMapping, int, error) {
+					return append([]*platform.UserResourceMapping(nil), urms...), len(urms), nil
+				},
+			},
+		),
+		UserService: &mock.UserService{
+			FindUserByIDFn: func(ctx context.Context, id platform.ID) (*platform.User, error) {
+				return &platform.User{ID: id, Name: fmt.Sprintf("user%s", id), Status: platform.Active}, nil
+			},
+		},
+	}
+	h := newGetMembersHandler(memberBackend)
+	auth := &platform.Authorization{Status: platform.Active, Permissions: platform.OwnerPermissions(10)}
+
+	seen := make(map[string]bool)
+	target := "/api/v2/buckets/0000000000000099/members?limit=100"
+	for pages := 0; target != ""; pages++ {
+		if pages > count/100 {
+			t.Fatalf("got more than %d pages", count/100)
+		}
+
+		r := httptest.NewRequest("GET", target, nil)
+		ctx := pcontext.SetAuthorizer(r.Context(), auth)
+		ctx = context.WithValue(ctx, httprouter.ParamsKey, httprouter.Params{{Key: "id", Value: resourceID.String()}})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(ctx))
+
+		res := w.Result()
+		if res.StatusCode != http.StatusOK {
+			body, _ := ioutil.ReadAll(res.Body)
+			t.Fatalf("got status %d for %s: %s", res.StatusCode, target, body)
+		}
+		var resp struct {
+			Users []struct {
+				ID string `json:"id"`
+			} `json:"users"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Users) != 100 {
+			t.Fatalf("got %d users for %s, exp 100", len(resp.Users), target)
+		}
+		for _, u := range resp.Users {
+			if seen[u.ID] {
+				t.Fatalf("got user %s twice", u.ID)
+			}
+			seen[u.ID] = true
+		}
+
+		target = ""
+		if link := res.Header.Get("Link"); link != "" {
+			if !strings.HasPrefix(link, "<") || !strings.HasSuffix(link, `>; rel="next"`) {
+				t.Fatalf("got invalid link header %q", link)
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	if len(seen) != count {
+		t.Fatalf("got %d users, exp %d", len(seen), count)
+	}
+}
+
 func TestUserResourceMappingService_PostMembersHandler(t *testing.T) {
 	type fields struct {
 		userService                platform.UserService
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
@@ -66,11 +68,14 @@ type WriteHandler struct {
 
 	EventRecorder metric.EventRecorder
 
-	maxBatchSizeBytes int64
-	parserOptions     []models.ParserOption
-	parserMaxBytes    int
-	parserMaxLines    int
-	parserMaxValues   int
+	quota *WriteQuota
+
+	maxBatchSizeBytes       int64
+	prometheusDefaultBucket string
+	parserOptions           []models.ParserOption
+	parserMaxBytes          int
+	parserMaxLines          int
+	parserMaxValues         int
 }
 
 // WriteHandlerOption is a functional option for a *WriteHandler
@@ -84,6 +89,14 @@ func WithMaxBatchSizeBytes(n int64) WriteHandlerOption {
 	}
 }
 
+// WithWriteQuota configures the quota used to enforce the MaxBytesPerDay
+// limit of the authorization making the write.
+func WithWriteQuota(q *WriteQuota) WriteHandlerOption {
+	return func(w *WriteHandler) {
+		w.quota = q
+	}
+}
+
 // WithParserMaxBytes specifies the maximum number of bytes that may be allocated when processing a single
 // write request. When n is zero, there is no limit.
 func WithParserMaxBytes(n int) WriteHandlerOption {
@@ -115,6 +128,7 @@ func (*WriteHandler) Prefix() string {
 
 const (
 	prefixWrite          = "/api/v2/write"
+	writePrecisionPath   = "/api/v2/write/:precision"
 	errInvalidGzipHeader = "gzipped HTTP body contains an invalid header"
 	errInvalidPrecision  = "invalid precision; valid precision units are ns, us, ms, and s"
 )
@@ -136,6 +150,10 @@ func NewWriteHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOptio
 		opt(h)
 	}
 
+	if h.quota == nil {
+		h.quota = NewWriteQuota(nil)
+	}
+
 	// cache configured options
 	if h.parserMaxBytes > 0 {
 		h.parserOptions = append(h.parserOptions, models.WithParserMaxBytes(h.parserMaxBytes))
@@ -148,6 +166,9 @@ func NewWriteHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOptio
 	}
 
 	h.HandlerFunc("POST", prefixWrite, h.handleWrite)
+	h.HandlerFunc("POST", writePrecisionPath, h.handleWrite)
+	h.HandlerFunc("POST", prefixPrometheusWrite, h.handlePrometheusWrite)
+	h.HandlerFunc("POST", prefixOTLPMetrics, h.handleOTLPMetrics)
 	return h
 }
 
@@ -209,52 +230,25 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 	orgID = org.ID
 	span.LogKV("org_id", orgID)
 
-	var bucket *influxdb.Bucket
-	if id, err := influxdb.IDFromString(req.Bucket); err == nil {
-		// Decoded ID successfully. Make sure it's a real bucket.
-		b, err := h.BucketService.FindBucket(ctx, influxdb.BucketFilter{
-			OrganizationID: &org.ID,
-			ID:             id,
-		})
+	bucket, err := h.findWriteBucket(ctx, a, org, req.Bucket, "http/handleWrite")
+	if req.CreateBucket && influxdb.ErrorCode(err) == influxdb.ENotFound {
+		bucket, err = h.createWriteBucket(ctx, a, org, req.Bucket, "http/handleWrite")
 		if err == nil {
-			bucket = b
-		} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
-			h.HandleHTTPError(ctx, err, w)
-			return
+			log.Info("Created bucket on write", zap.Stringer("bucket_id", bucket.ID))
 		}
 	}
-
-	if bucket == nil {
-		b, err := h.BucketService.FindBucket(ctx, influxdb.BucketFilter{
-			OrganizationID: &org.ID,
-			Name:           &req.Bucket,
-		})
-		if err != nil {
-			h.HandleHTTPError(ctx, err, w)
-			return
-		}
-
-		bucket = b
-	}
-	span.LogKV("bucket_id", bucket.ID)
-
-	p, err := influxdb.NewPermissionAtID(bucket.ID, influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
 	if err != nil {
-		handleError(err, influxdb.EInternal, fmt.Sprintf("unable to create permission for bucket: %v", err))
-		return
-	}
-
-	if !a.Allowed(*p) {
-		handleError(err, influxdb.EForbidden, "insufficient permissions for write")
+		h.HandleHTTPError(ctx, err, w)
 		return
 	}
+	span.LogKV("bucket_id", bucket.ID)
 
 	data, err := readWriteRequest(ctx, r.Body, r.Header.Get("Content-Encoding"), h.maxBatchSizeBytes)
 	if err != nil {
 		log.Error("Error reading body", zap.Error(err))
 
 		code := influxdb.EInternal
-		if errors.Is(err, ErrMaxBatchSizeExceeded) {
+		if errors.Is(err, ErrMaxBatchSizeExceeded) || influxdb.ErrorCode(err) == influxdb.ETooLarge {
 			code = influxdb.ETooLarge
 		} else if errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) {
 			code = influxdb.EInvalid
@@ -301,8 +295,22 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only writes that parse are charged to the quota, and the charge is
+	// refunded if the points are not written.
+	if !h.allowWrite(ctx, w, a, requestBytes, "http/handleWrite") {
+		return
+	}
+
 	if err := h.PointsWriter.WritePoints(ctx, points); err != nil {
 		log.Error("Error writing points", zap.Error(err))
+		h.refundWrite(ctx, a, requestBytes)
+		switch code := influxdb.ErrorCode(err); code {
+		case influxdb.EInvalid, influxdb.EConflict:
+			// The points were rejected, such as by a write validator or
+			// because a field has a different type than already written.
+			handleError(err, code, "")
+			return
+		}
 		handleError(err, influxdb.EInternal, "unexpected error writing points to database")
 		return
 	}
@@ -310,9 +318,187 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// allowWrite charges n bytes to the write quota of a. If the quota is
+// exceeded, it responds with 429 Too Many Requests and returns false.
+func (h *WriteHandler) allowWrite(ctx context.Context, w http.ResponseWriter, a influxdb.Authorizer, n int, op string) bool {
+	auth, ok := a.(*influxdb.Authorization)
+	if !ok {
+		return true
+	}
+
+	retry, ok := h.quota.Allow(ctx, auth, int64(n))
+	if !ok {
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retry.Seconds())), 10))
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.ETooManyRequests,
+			Op:   op,
+			Msg:  fmt.Sprintf("write quota of %d bytes per day exceeded", auth.MaxBytesPerDay),
+		}, w)
+	}
+	return ok
+}
+
+// refundWrite refunds n bytes charged by allowWrite to the write quota of a,
+// once writing the points failed.
+func (h *WriteHandler) refundWrite(ctx context.Context, a influxdb.Authorizer, n int) {
+	if auth, ok := a.(*influxdb.Authorization); ok {
+		h.quota.Refund(ctx, auth, int64(n))
+	}
+}
+
+// recordWrite wraps w to record the usage metrics of the write request r. The
+// handler sets the OrgID and RequestBytes of the returned event, which is
+// recorded when the returned function is called once the request is handled.
+func (h *WriteHandler) recordWrite(ctx context.Context, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *metric.Event, func()) {
+	sw := kithttp.NewStatusResponseWriter(w)
+	event := &metric.Event{Endpoint: r.URL.Path}
+	return sw, event, func() {
+		event.ResponseBytes = sw.ResponseBytes()
+		event.Status = sw.Code()
+		h.EventRecorder.Record(ctx, *event)
+	}
+}
+
+// writeLineProtocol parses lp, the line protocol converted from a request of
+// n bytes, and writes its points to a bucket, charging n bytes to the write
+// quota of a unless writing them fails. It returns false if an error response
+// was sent.
+func (h *WriteHandler) writeLineProtocol(ctx context.Context, w http.ResponseWriter, log *zap.Logger, a influxdb.Authorizer, orgID, bucketID influxdb.ID, lp []byte, n int, op string) bool {
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	mm := models.EscapeMeasurement(encoded[:])
+	points, err := models.ParsePointsWithOptions(lp, mm, h.parserOptions...)
+	if err != nil {
+		log.Error("Error parsing points", zap.Error(err))
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   op,
+			Err:  err,
+		}, w)
+		return false
+	}
+
+	if !h.allowWrite(ctx, w, a, n, op) {
+		return false
+	}
+
+	if len(points) > 0 {
+		if err := h.PointsWriter.WritePoints(ctx, points); err != nil {
+			log.Error("Error writing points", zap.Error(err))
+			h.refundWrite(ctx, a, n)
+			h.HandleHTTPError(ctx, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Op:   op,
+				Msg:  "unexpected error writing points to database",
+				Err:  err,
+			}, w)
+			return false
+		}
+	}
+	return true
+}
+
+// findWriteBucket returns the bucket of the organization with the ID or name,
+// checking that a is allowed to write to it.
+func (h *WriteHandler) findWriteBucket(ctx context.Context, a influxdb.Authorizer, org *influxdb.Organization, bucketIDOrName, op string) (*influxdb.Bucket, error) {
+	var bucket *influxdb.Bucket
+	if id, err := influxdb.IDFromString(bucketIDOrName); err == nil {
+		// Decoded ID successfully. Make sure it's a real bucket.
+		b, err := h.BucketService.FindBucket(ctx, influxdb.BucketFilter{
+			OrganizationID: &org.ID,
+			ID:             id,
+		})
+		if err == nil {
+			bucket = b
+		} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			return nil, err
+		}
+	}
+
+	if bucket == nil {
+		b, err := h.BucketService.FindBucket(ctx, influxdb.BucketFilter{
+			OrganizationID: &org.ID,
+			Name:           &bucketIDOrName,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		bucket = b
+	}
+
+	p, err := influxdb.NewPermissionAtID(bucket.ID, influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   op,
+			Msg:  fmt.Sprintf("unable to create permission for bucket: %v", err),
+			Err:  err,
+		}
+	}
+
+	if !a.Allowed(*p) {
+		return nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Op:   op,
+			Msg:  "insufficient permissions for write",
+		}
+	}
+	return bucket, nil
+}
+
+// createWriteBucket creates the bucket of the organization with the name,
+// checking that a is allowed to create buckets in the organization. The bucket
+// retains its data forever, as a bucket created without retention rules does.
+// A bucket with the name created concurrently is returned instead.
+func (h *WriteHandler) createWriteBucket(ctx context.Context, a influxdb.Authorizer, org *influxdb.Organization, name, op string) (*influxdb.Bucket, error) {
+	p, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   op,
+			Msg:  fmt.Sprintf("unable to create permission for buckets: %v", err),
+			Err:  err,
+		}
+	}
+
+	if !a.Allowed(*p) {
+		return nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Op:   op,
+			Msg:  "insufficient permissions to create bucket",
+		}
+	}
+
+	bucket := &influxdb.Bucket{
+		OrgID:           org.ID,
+		Name:            name,
+		RetentionPeriod: influxdb.InfiniteRetention,
+	}
+	if err := h.BucketService.CreateBucket(ctx, bucket); err != nil {
+		if influxdb.ErrorCode(err) == influxdb.EConflict {
+			return h.findWriteBucket(ctx, a, org, name, op)
+		}
+		return nil, err
+	}
+	return bucket, nil
+}
+
 func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest, error) {
 	qp := r.URL.Query()
 	p := qp.Get("precision")
+
+	// The precision can also be given as the last segment of the path, in
+	// which case it must agree with the query parameter.
+	if pp := httprouter.ParamsFromContext(ctx).ByName("precision"); pp != "" {
+		if p != "" && p != pp {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Op:   "http/decodeWriteRequest",
+				Msg:  fmt.Sprintf("precision %q in path does not match precision %q in query", pp, p),
+			}
+		}
+		p = pp
+	}
 	if p == "" {
 		p = "ns"
 	}
@@ -330,10 +516,24 @@ func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest
 		precision = models.WithParserPrecision(p)
 	}
 
+	var createBucket bool
+	if v := qp.Get("create-bucket"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Op:   "http/decodeWriteRequest",
+				Msg:  fmt.Sprintf("invalid create-bucket value %q", v),
+			}
+		}
+		createBucket = b
+	}
+
 	return &postWriteRequest{
-		Bucket:    qp.Get("bucket"),
-		Org:       qp.Get("org"),
-		Precision: precision,
+		Bucket:       qp.Get("bucket"),
+		Org:          qp.Get("org"),
+		Precision:    precision,
+		CreateBucket: createBucket,
 	}, nil
 }
 
@@ -371,9 +571,10 @@ func readWriteRequest(ctx context.Context, rc io.ReadCloser, encoding string, ma
 }
 
 type postWriteRequest struct {
-	Org       string
-	Bucket    string
-	Precision models.ParserOption
+	Org          string
+	Bucket       string
+	Precision    models.ParserOption
+	CreateBucket bool
 }
 
 // WriteService sends data over HTTP to influxdb via line protocol.
@@ -3,6 +3,7 @@ package http
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,7 +11,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http/metric"
 	httpmock "github.com/influxdata/influxdb/http/mock"
@@ -142,6 +145,24 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 				body: `{"code":"internal error","message":"unexpected error writing points to database: error"}`,
 			},
 		},
+		{
+			name: "points rejected by the points writer are invalid",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:      testOrg("043e0780ee2b1000"),
+				bucket:   testBucket("043e0780ee2b1000", "04504b356e23b000"),
+				writeErr: &influxdb.Error{Code: influxdb.EInvalid, Msg: "missing host tag"},
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"missing host tag"}`,
+			},
+		},
 		{
 			name: "empty request body returns 400 error",
 			request: request{
@@ -376,6 +397,183 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 	}
 }
 
+func TestWriteHandler_handleWrite_quota(t *testing.T) {
+	const body = "m1,t1=v1 f1=1"
+
+	orgs := mock.NewOrganizationService()
+	orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+		return testOrg("043e0780ee2b1000"), nil
+	}
+	buckets := mock.NewBucketService()
+	buckets.FindBucketFn = func(context.Context, influxdb.BucketFilter) (*influxdb.Bucket, error) {
+		return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+	}
+
+	pw := &mock.PointsWriter{}
+	b := &APIBackend{
+		HTTPErrorHandler:    DefaultErrorHandler,
+		Logger:              zaptest.NewLogger(t),
+		OrganizationService: orgs,
+		BucketService:       buckets,
+		PointsWriter:        pw,
+		WriteEventRecorder:  &metric.NopEventRecorder{},
+	}
+
+	clk := clock.NewMock()
+	clk.Set(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	auth := bucketWritePermission("043e0780ee2b1000", "04504b356e23b000")
+	auth.ID = influxtesting.MustIDBase16("020f755c3c082000")
+	auth.MaxBytesPerDay = int64(2 * len(body))
+
+	quota := NewWriteQuota(clk)
+	writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b), WithWriteQuota(quota))
+	handler := httpmock.NewAuthMiddlewareHandler(writeHandler, auth)
+
+	writeBody := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://localhost:9999/api/v2/write", strings.NewReader(body))
+		params := r.URL.Query()
+		params.Set("org", "043e0780ee2b1000")
+		params.Set("bucket", "04504b356e23b000")
+		r.URL.RawQuery = params.Encode()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	write := func() *httptest.ResponseRecorder { return writeBody(body) }
+
+	// Writes that fail to parse are not charged to the quota.
+	if got, want := writeBody("m1,t1=v1 f1=").Code, http.StatusBadRequest; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+
+	// Writes that the engine rejects are not charged to the quota either.
+	pw.ForceError(&influxdb.Error{Code: influxdb.EInvalid, Msg: "field type conflict"})
+	if got, want := write().Code, http.StatusBadRequest; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+	pw.ForceError(errors.New("disk full"))
+	if got, want := write().Code, http.StatusInternalServerError; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+	pw.ForceError(nil)
+	if got, want := quota.Usage(context.Background(), auth.ID), int64(0); got != want {
+		t.Fatalf("unexpected usage after failed writes: got %d want %d", got, want)
+	}
+
+	// Writing exactly the quota is allowed.
+	for i := 0; i < 2; i++ {
+		if got, want := write().Code, http.StatusNoContent; got != want {
+			t.Fatalf("write %d: unexpected status code: got %d want %d", i, got, want)
+		}
+	}
+
+	clk.Add(time.Hour)
+	w := write()
+	if got, want := w.Code, http.StatusTooManyRequests; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+	if got, want := w.Header().Get("Retry-After"), "82800"; got != want {
+		t.Errorf("unexpected Retry-After: got %s want %s", got, want)
+	}
+	if got, want := w.Body.String(), `{"code":"too many requests","message":"write quota of 26 bytes per day exceeded"}`; got != want {
+		t.Errorf("unexpected body: got %s want %s", got, want)
+	}
+
+	// Once the window has elapsed writes are accepted again.
+	clk.Add(23 * time.Hour)
+	if got, want := write().Code, http.StatusNoContent; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+}
+
+func TestWriteHandler_handleWrite_precisionPath(t *testing.T) {
+	orgs := mock.NewOrganizationService()
+	orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+		return testOrg("043e0780ee2b1000"), nil
+	}
+	buckets := mock.NewBucketService()
+	buckets.FindBucketFn = func(context.Context, influxdb.BucketFilter) (*influxdb.Bucket, error) {
+		return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		precision string
+		code      int
+		body      string
+	}{
+		{
+			name: "precision in path",
+			path: "/api/v2/write/s",
+			code: http.StatusNoContent,
+		},
+		{
+			name:      "same precision in path and query",
+			path:      "/api/v2/write/s",
+			precision: "s",
+			code:      http.StatusNoContent,
+		},
+		{
+			name:      "different precision in path and query",
+			path:      "/api/v2/write/s",
+			precision: "ms",
+			code:      http.StatusBadRequest,
+			body:      `{"code":"invalid","message":"precision \"s\" in path does not match precision \"ms\" in query"}`,
+		},
+		{
+			name: "invalid precision in path",
+			path: "/api/v2/write/m",
+			code: http.StatusBadRequest,
+			body: `{"code":"invalid","message":"invalid precision; valid precision units are ns, us, ms, and s"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw := &mock.PointsWriter{}
+			b := &APIBackend{
+				HTTPErrorHandler:    DefaultErrorHandler,
+				Logger:              zaptest.NewLogger(t),
+				OrganizationService: orgs,
+				BucketService:       buckets,
+				PointsWriter:        pw,
+				WriteEventRecorder:  &metric.NopEventRecorder{},
+			}
+			writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b))
+			handler := httpmock.NewAuthMiddlewareHandler(writeHandler, bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"))
+
+			r := httptest.NewRequest("POST", "http://localhost:9999"+tt.path, strings.NewReader("m1,t1=v1 f1=1 1577836800"))
+			params := r.URL.Query()
+			params.Set("org", "043e0780ee2b1000")
+			params.Set("bucket", "04504b356e23b000")
+			if tt.precision != "" {
+				params.Set("precision", tt.precision)
+			}
+			r.URL.RawQuery = params.Encode()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got, want := w.Code, tt.code; got != want {
+				t.Fatalf("unexpected status code: got %d want %d", got, want)
+			}
+			if got, want := w.Body.String(), tt.body; got != want {
+				t.Errorf("unexpected body: got %s want %s", got, want)
+			}
+
+			if tt.code == http.StatusNoContent {
+				if got, want := len(pw.Points), 1; got != want {
+					t.Fatalf("unexpected number of points: got %d want %d", got, want)
+				}
+				if got, want := pw.Points[0].Time().UnixNano(), int64(1577836800000000000); got != want {
+					t.Errorf("unexpected point time: got %d want %d", got, want)
+				}
+			}
+		})
+	}
+}
+
 var DefaultErrorHandler = kithttp.ErrorHandler(0)
 
 func bucketWritePermission(org, bucket string) *influxdb.Authorization {
@@ -0,0 +1,278 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/influxdata/influxdb"
+	"go.uber.org/zap"
+)
+
+const (
+	// writeQuotaWindow is the length of the rolling window over which an
+	// authorization's MaxBytesPerDay is enforced.
+	writeQuotaWindow = 24 * time.Hour
+
+	// writeQuotaBucket is the granularity at which usage leaves the window.
+	writeQuotaBucket = time.Hour
+
+	// DefaultWriteQuotaFlushInterval is how often Run persists usage.
+	DefaultWriteQuotaFlushInterval = time.Minute
+)
+
+// WriteQuota tracks the number of bytes written by each authorization and
+// enforces the authorization's MaxBytesPerDay limit.
+//
+// Usage is counted in memory per hour, and the bytes of an hour leave the
+// rolling 24 hour window once it is a day old. If the quota has a
+// WriteUsageService, the usage of an authorization is loaded from it on its
+// first write, and Run flushes changed usage to it.
+type WriteQuota struct {
+	clock clock.Clock
+	store influxdb.WriteUsageService
+	log   *zap.Logger
+
+	mu    sync.Mutex
+	usage map[influxdb.ID]*writeUsage
+}
+
+type writeUsage struct {
+	hours []influxdb.WriteUsageHour // oldest first
+	dirty bool                      // changed since the last flush
+}
+
+// expire removes the hours that have left the window ending at now.
+func (u *writeUsage) expire(now time.Time) {
+	i := 0
+	for i < len(u.hours) && now.Sub(u.hours[i].Start) >= writeQuotaWindow {
+		i++
+	}
+	if i > 0 {
+		u.hours = append(u.hours[:0], u.hours[i:]...)
+		u.dirty = true
+	}
+}
+
+// bytes returns the number of bytes in the window.
+func (u *writeUsage) bytes() int64 {
+	var n int64
+	for _, h := range u.hours {
+		n += h.Bytes
+	}
+	return n
+}
+
+// WriteQuotaOption is a functional option for a WriteQuota.
+type WriteQuotaOption func(*WriteQuota)
+
+// WithWriteUsageService persists usage to s, so that it survives restarts.
+func WithWriteUsageService(s influxdb.WriteUsageService) WriteQuotaOption {
+	return func(q *WriteQuota) {
+		q.store = s
+	}
+}
+
+// WithWriteQuotaLogger sets the logger used to report failures to load or
+// flush usage.
+func WithWriteQuotaLogger(log *zap.Logger) WriteQuotaOption {
+	return func(q *WriteQuota) {
+		q.log = log
+	}
+}
+
+// NewWriteQuota returns a new WriteQuota that uses c to determine the current
+// time. If c is nil the wall clock is used.
+func NewWriteQuota(c clock.Clock, opts ...WriteQuotaOption) *WriteQuota {
+	if c == nil {
+		c = clock.New()
+	}
+	q := &WriteQuota{
+		clock: c,
+		log:   zap.NewNop(),
+		usage: make(map[influxdb.ID]*writeUsage),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Allow records n bytes written by the authorization a if doing so would not
+// exceed the authorization's MaxBytesPerDay. If the quota would be exceeded the
+// write is not recorded, and the duration until enough of the window has
+// elapsed to allow the write is returned along with false.
+//
+// Authorizations with a MaxBytesPerDay of zero are unlimited.
+func (q *WriteQuota) Allow(ctx context.Context, a *influxdb.Authorization, n int64) (time.Duration, bool) {
+	if a.MaxBytesPerDay <= 0 {
+		return 0, true
+	}
+
+	u := q.load(ctx, a.ID)
+	now := q.clock.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Another write may have loaded the usage, or a flush evicted it, since
+	// it was loaded.
+	if cur, ok := q.usage[a.ID]; ok {
+		u = cur
+	} else {
+		q.usage[a.ID] = u
+	}
+
+	u.expire(now)
+	if total := u.bytes(); total+n > a.MaxBytesPerDay {
+		// Find the hour after which enough bytes have left the window.
+		for _, h := range u.hours {
+			total -= h.Bytes
+			if total+n <= a.MaxBytesPerDay {
+				return h.Start.Add(writeQuotaWindow).Sub(now), false
+			}
+		}
+		return writeQuotaWindow, false
+	}
+
+	start := now.Truncate(writeQuotaBucket)
+	if l := len(u.hours); l > 0 && u.hours[l-1].Start.Equal(start) {
+		u.hours[l-1].Bytes += n
+	} else {
+		u.hours = append(u.hours, influxdb.WriteUsageHour{Start: start, Bytes: n})
+	}
+	u.dirty = true
+	return 0, true
+}
+
+// Refund removes n bytes recorded by Allow for the authorization a, such as
+// when the write it allowed then failed. The bytes are removed from the most
+// recent hours first.
+func (q *WriteQuota) Refund(ctx context.Context, a *influxdb.Authorization, n int64) {
+	if a.MaxBytesPerDay <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u, ok := q.usage[a.ID]
+	if !ok {
+		return
+	}
+	for i := len(u.hours) - 1; i >= 0 && n > 0; i-- {
+		d := u.hours[i].Bytes
+		if d > n {
+			d = n
+		}
+		u.hours[i].Bytes -= d
+		n -= d
+		u.dirty = true
+	}
+}
+
+// Usage returns the number of bytes written by the authorization with the
+// provided id in the last 24 hours.
+func (q *WriteQuota) Usage(ctx context.Context, id influxdb.ID) int64 {
+	u := q.load(ctx, id)
+	now := q.clock.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u.expire(now)
+	return u.bytes()
+}
+
+// load returns the usage of the authorization with the provided id, reading
+// it from the store if it is not in memory. The usage read is not added to
+// the quota.
+func (q *WriteQuota) load(ctx context.Context, id influxdb.ID) *writeUsage {
+	q.mu.Lock()
+	u, ok := q.usage[id]
+	q.mu.Unlock()
+	if ok {
+		return u
+	}
+
+	u = &writeUsage{}
+	if q.store != nil {
+		stored, err := q.store.FindWriteUsage(ctx, id)
+		if err == nil {
+			u.hours = stored.Hours
+		} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			q.log.Error("Failed to load write usage", zap.Stringer("authorization_id", id), zap.Error(err))
+		}
+	}
+	return u
+}
+
+// Flush writes the usage changed since the last flush to the store, and
+// evicts the authorizations with no usage left in the window.
+func (q *WriteQuota) Flush(ctx context.Context) error {
+	now := q.clock.Now()
+
+	var changed []*influxdb.WriteUsage
+	q.mu.Lock()
+	for id, u := range q.usage {
+		u.expire(now)
+		if u.dirty {
+			changed = append(changed, &influxdb.WriteUsage{
+				AuthorizationID: id,
+				Hours:           append([]influxdb.WriteUsageHour(nil), u.hours...),
+			})
+			u.dirty = false
+		}
+		if len(u.hours) == 0 {
+			delete(q.usage, id)
+		}
+	}
+	q.mu.Unlock()
+
+	if q.store == nil {
+		return nil
+	}
+
+	var firstErr error
+	for _, u := range changed {
+		if err := q.store.PutWriteUsage(ctx, u); err != nil {
+			q.markDirty(u.AuthorizationID)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// markDirty marks the usage of an authorization to be flushed again.
+func (q *WriteQuota) markDirty(id influxdb.ID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if u, ok := q.usage[id]; ok {
+		u.dirty = true
+	}
+}
+
+// Run flushes usage every interval until ctx is done, and once more before
+// returning.
+func (q *WriteQuota) Run(ctx context.Context, interval time.Duration) {
+	ticker := q.clock.Ticker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush with a fresh context, as ctx is already done.
+			if err := q.Flush(context.Background()); err != nil {
+				q.log.Error("Failed to flush write usage", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+			if err := q.Flush(ctx); err != nil {
+				q.log.Error("Failed to flush write usage", zap.Error(err))
+			}
+		}
+	}
+}
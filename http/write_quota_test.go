@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	influxtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWriteQuota_RollingWindow(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	clk.Set(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	q := NewWriteQuota(clk)
+	auth := &influxdb.Authorization{ID: influxtesting.MustIDBase16("020f755c3c082000"), MaxBytesPerDay: 100}
+
+	if _, ok := q.Allow(ctx, auth, 60); !ok {
+		t.Fatal("expected first write to be allowed")
+	}
+	clk.Add(12 * time.Hour)
+	if _, ok := q.Allow(ctx, auth, 40); !ok {
+		t.Fatal("expected second write to be allowed")
+	}
+
+	// The window is full until the first write is a day old.
+	clk.Add(6 * time.Hour)
+	retry, ok := q.Allow(ctx, auth, 10)
+	if ok {
+		t.Fatal("expected write over the quota to be rejected")
+	}
+	if got, want := retry, 6*time.Hour; got != want {
+		t.Errorf("unexpected retry: got %s want %s", got, want)
+	}
+
+	// Once it is, only the bytes of the second write remain in the window.
+	clk.Add(6 * time.Hour)
+	if got, want := q.Usage(ctx, auth.ID), int64(40); got != want {
+		t.Errorf("unexpected usage: got %d want %d", got, want)
+	}
+	if _, ok := q.Allow(ctx, auth, 60); !ok {
+		t.Fatal("expected write to be allowed once the first write left the window")
+	}
+	if _, ok := q.Allow(ctx, auth, 1); ok {
+		t.Fatal("expected write over the quota to be rejected")
+	}
+}
+
+func TestWriteQuota_Flush(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock()
+	clk.Set(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &influxdb.Authorization{ID: influxtesting.MustIDBase16("020f755c3c082000"), MaxBytesPerDay: 100}
+
+	q := NewWriteQuota(clk, WithWriteUsageService(svc))
+	if _, ok := q.Allow(ctx, auth, 80); !ok {
+		t.Fatal("expected write to be allowed")
+	}
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new quota, as after a restart, loads the usage flushed.
+	q = NewWriteQuota(clk, WithWriteUsageService(svc))
+	if got, want := q.Usage(ctx, auth.ID), int64(80); got != want {
+		t.Errorf("unexpected usage: got %d want %d", got, want)
+	}
+	if _, ok := q.Allow(ctx, auth, 30); ok {
+		t.Fatal("expected write over the loaded quota to be rejected")
+	}
+
+	// Once the usage leaves the window, it is evicted and removed from the
+	// store.
+	clk.Add(24 * time.Hour)
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(q.usage), 0; got != want {
+		t.Errorf("unexpected number of authorizations in memory: got %d want %d", got, want)
+	}
+	if _, err := svc.FindWriteUsage(ctx, auth.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Errorf("expected write usage to be removed, got error %v", err)
+	}
+}
@@ -74,6 +74,40 @@ func (t *TokenParser) Parse(v string) (*Token, error) {
 	return token, nil
 }
 
+// UnverifiedToken is a jwt decoded without validating its signature
+// or claims
+type UnverifiedToken struct {
+	// Header is the decoded header of the token
+	Header map[string]interface{}
+	// Claims is the decoded set of all claims of the token
+	Claims map[string]interface{}
+	// Token is the token described by the claims
+	Token *Token
+}
+
+// ParseUnverified decodes v as a jwt without validating its signature
+// or claims, such as to inspect a token without the key it was signed
+// with. The returned token must not be used to authorize requests.
+func ParseUnverified(v string) (*UnverifiedToken, error) {
+	var parser jwt.Parser
+	claims := jwt.MapClaims{}
+	jwtToken, _, err := parser.ParseUnverified(v, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if _, _, err := parser.ParseUnverified(v, &token); err != nil {
+		return nil, err
+	}
+
+	return &UnverifiedToken{
+		Header: jwtToken.Header,
+		Claims: claims,
+		Token:  &token,
+	}, nil
+}
+
 // IsMalformedError returns true if the error returned represents
 // a jwt malformed token error
 func IsMalformedError(err error) bool {
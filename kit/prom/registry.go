@@ -34,6 +34,19 @@ func NewRegistry(log *zap.Logger) *Registry {
 	}
 }
 
+// RegisterIfNotExists registers c, unless a collector of the same metrics
+// is already registered, which is not an error. Other errors of the
+// registration are returned.
+func (r *Registry) RegisterIfNotExists(c prometheus.Collector) error {
+	if err := r.Register(c); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // HTTPHandler returns an http.Handler for the registry,
 // so that the /metrics HTTP handler is uniformly configured across all apps in the platform.
 func (r *Registry) HTTPHandler() http.Handler {
@@ -42,6 +42,27 @@ func TestRegistry_Logger(t *testing.T) {
 	}
 }
 
+func TestRegistry_RegisterIfNotExists(t *testing.T) {
+	reg := prom.NewRegistry(zap.NewNop())
+
+	newCounter := func() prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{Name: "test_counter", Help: "A counter."})
+	}
+	if err := reg.RegisterIfNotExists(newCounter()); err != nil {
+		t.Fatal(err)
+	}
+	// Collectors of the same metrics are only registered once.
+	if err := reg.RegisterIfNotExists(newCounter()); err != nil {
+		t.Fatalf("unexpected error registering the same metric twice: %v", err)
+	}
+
+	// Collectors of inconsistent metrics are still rejected.
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_counter", Help: "Another help."})
+	if err := reg.RegisterIfNotExists(other); err == nil {
+		t.Fatal("expected error registering an inconsistent metric")
+	}
+}
+
 type errorCollector struct{}
 
 var _ prometheus.Collector = errorCollector{}
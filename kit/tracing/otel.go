@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/api/kv"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OpenTelemetryServiceName is the service name of the spans exported with
+// OpenTelemetry.
+const OpenTelemetryServiceName = "influxd"
+
+// NewOpenTelemetryTracer returns an OpenTracing tracer recording its spans with
+// the OpenTelemetry SDK provider p, so that the spans of the existing
+// instrumentation are exported by the span processors of p.
+func NewOpenTelemetryTracer(p *sdktrace.Provider) opentracing.Tracer {
+	tracer, _ := otbridge.NewTracerPair(p.Tracer("github.com/influxdata/influxdb"))
+	return tracer
+}
+
+// NewOTLPTracer returns an OpenTracing tracer exporting all its spans to the
+// OpenTelemetry collector at endpoint, such as http://localhost:55680, and a
+// Closer exporting the spans not yet exported and closing the connection to
+// the collector. The connection is insecure unless the scheme is https.
+func NewOTLPTracer(endpoint string) (opentracing.Tracer, io.Closer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid OpenTelemetry exporter endpoint %q: %v", endpoint, err)
+	} else if u.Host == "" {
+		return nil, nil, fmt.Errorf("invalid OpenTelemetry exporter endpoint %q: expected a URL such as http://localhost:55680", endpoint)
+	}
+
+	opts := []otlp.ExporterOption{otlp.WithAddress(u.Host)}
+	if u.Scheme != "https" {
+		opts = append(opts, otlp.WithInsecure())
+	}
+	exporter, err := otlp.NewExporter(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bsp, err := sdktrace.NewBatchSpanProcessor(exporter)
+	if err != nil {
+		_ = exporter.Stop()
+		return nil, nil, err
+	}
+	p, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithResource(resource.New(kv.String("service.name", OpenTelemetryServiceName))),
+	)
+	if err != nil {
+		_ = exporter.Stop()
+		return nil, nil, err
+	}
+	p.RegisterSpanProcessor(bsp)
+
+	return NewOpenTelemetryTracer(p), &otlpCloser{provider: p, processor: bsp, exporter: exporter}, nil
+}
+
+type otlpCloser struct {
+	provider  *sdktrace.Provider
+	processor sdktrace.SpanProcessor
+	exporter  *otlp.Exporter
+}
+
+// Close exports the spans not yet exported and stops the exporter.
+func (c *otlpCloser) Close() error {
+	c.provider.UnregisterSpanProcessor(c.processor)
+	return c.exporter.Stop()
+}
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanRecorder is an in-memory exporter of the spans of a provider.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*export.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(_ context.Context, sd *export.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, sd)
+}
+
+func (r *spanRecorder) span(t *testing.T, name string) *export.SpanData {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sd := range r.spans {
+		if sd.Name == name {
+			return sd
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+// setupOpenTelemetryTracing sets the global tracer to an OpenTelemetry tracer
+// exporting its spans to the returned recorder.
+func setupOpenTelemetryTracing(t *testing.T) (*spanRecorder, func()) {
+	t.Helper()
+
+	r := &spanRecorder{}
+	p, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(r),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(NewOpenTelemetryTracer(p))
+	return r, func() { opentracing.SetGlobalTracer(old) }
+}
+
+func TestOpenTelemetryTracer(t *testing.T) {
+	const traceparentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const traceparentSpanID = "00f067aa0ba902b7"
+
+	for _, test := range []struct {
+		name     string
+		header   map[string]string
+		rootName string
+		traceID  string
+		parentID string
+	}{
+		{
+			name:     "without headers",
+			rootName: "request",
+		},
+		{
+			name:     "request ID",
+			header:   map[string]string{RequestIDHeader: "abc-123"},
+			rootName: "abc-123",
+		},
+		{
+			name: "traceparent",
+			header: map[string]string{
+				RequestIDHeader: "abc-123",
+				"traceparent":   "00-" + traceparentTraceID + "-" + traceparentSpanID + "-01",
+			},
+			rootName: "request",
+			traceID:  traceparentTraceID,
+			parentID: traceparentSpanID,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, teardown := setupOpenTelemetryTracing(t)
+			defer teardown()
+
+			req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v2/query", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+
+			root, req := ExtractFromHTTPRequest(req, "FluxHandler")
+			child, ctx := StartSpanFromContextWithOperationName(req.Context(), "query")
+			traceID, sampled, found := InfoFromContext(ctx)
+			child.Finish()
+			root.Finish()
+
+			rootData, childData := recorder.span(t, test.rootName), recorder.span(t, "query")
+			if got, exp := childData.ParentSpanID, rootData.SpanContext.SpanID; got != exp {
+				t.Errorf("unexpected parent of query span: got %s, exp %s", got, exp)
+			}
+			if got, exp := childData.SpanContext.TraceID, rootData.SpanContext.TraceID; got != exp {
+				t.Errorf("unexpected trace of query span: got %s, exp %s", got, exp)
+			}
+
+			if test.parentID == "" {
+				if rootData.ParentSpanID.IsValid() {
+					t.Errorf("expected root span, got parent %s", rootData.ParentSpanID)
+				}
+			} else {
+				if got := rootData.ParentSpanID.String(); got != test.parentID {
+					t.Errorf("unexpected parent of request span: got %s, exp %s", got, test.parentID)
+				}
+				if got := rootData.SpanContext.TraceID.String(); got != test.traceID {
+					t.Errorf("unexpected trace of request span: got %s, exp %s", got, test.traceID)
+				}
+			}
+
+			if !found || !sampled {
+				t.Fatalf("expected sampled trace in context, got found %t sampled %t", found, sampled)
+			}
+			if exp := rootData.SpanContext.TraceID.String(); traceID != exp {
+				t.Errorf("unexpected trace ID in context: got %s, exp %s", traceID, exp)
+			}
+		})
+	}
+}
@@ -13,8 +13,28 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
+	oteltrace "go.opentelemetry.io/otel/api/trace"
 )
 
+// RequestIDHeader is the header of the ID of a request, which names the span
+// of a request that does not continue a trace.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a new context with the ID of the request
+// handled within it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID of the request handled within ctx, and
+// whether there is one.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
 // LogError adds a span log for an error.
 // Returns unchanged error, so useful to wrap as in:
 //  return 0, tracing.LogError(err)
@@ -49,11 +69,16 @@ func InjectToHTTPRequest(span opentracing.Span, req *http.Request) {
 
 // ExtractFromHTTPRequest gets a child span of the parent referenced in HTTP request headers.
 // Returns the request with updated tracing context.
+// A request not referencing a parent starts a root span named by its X-Request-ID header, if any.
 // Easier than adding this boilerplate everywhere.
 func ExtractFromHTTPRequest(req *http.Request, handlerName string) (opentracing.Span, *http.Request) {
 	spanContext, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
 	if err != nil {
-		span, ctx := opentracing.StartSpanFromContext(req.Context(), "request")
+		operationName := "request"
+		if id := req.Header.Get(RequestIDHeader); id != "" {
+			operationName = id
+		}
+		span, ctx := opentracing.StartSpanFromContext(req.Context(), operationName)
 		annotateSpan(span, handlerName, req)
 
 		_ = LogError(span, err)
@@ -179,11 +204,17 @@ func InfoFromSpan(span opentracing.Span) (traceID string, sampled bool, found bo
 	return "", false, false
 }
 
-// InfoFromContext returns the traceID and if it was sampled from the Jaeger span
-// found in the given context. It returns whether a span associated to the context has been found.
+// InfoFromContext returns the traceID and if it was sampled from the Jaeger or
+// OpenTelemetry span found in the given context. It returns whether a span associated
+// to the context has been found.
 func InfoFromContext(ctx context.Context) (traceID string, sampled bool, found bool) {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
-		return InfoFromSpan(span)
+		if traceID, sampled, found = InfoFromSpan(span); found {
+			return traceID, sampled, found
+		}
+	}
+	if sc := oteltrace.SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		return sc.TraceID.String(), sc.IsSampled(), true
 	}
 	return "", false, false
 }
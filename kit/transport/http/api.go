@@ -242,4 +242,6 @@ var statusCodePlatformError = map[string]int{
 	influxdb.EUnauthorized:        http.StatusUnauthorized,
 	influxdb.EMethodNotAllowed:    http.StatusMethodNotAllowed,
 	influxdb.ETooLarge:            http.StatusRequestEntityTooLarge,
+	influxdb.ETimeout:             http.StatusGatewayTimeout,
+	influxdb.EPreconditionFailed:  http.StatusPreconditionFailed,
 }
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+// ETag returns the entity tag of a resource last updated at updatedAt, which
+// changes whenever the resource is updated.
+func ETag(updatedAt time.Time) string {
+	sum := sha256.Sum256([]byte(updatedAt.UTC().Format(time.RFC3339Nano)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// SetETag sets the ETag header of the response to the entity tag of a resource
+// last updated at updatedAt.
+func SetETag(w http.ResponseWriter, updatedAt time.Time) {
+	w.Header().Set("ETag", ETag(updatedAt))
+}
+
+// CheckIfMatch returns a precondition failed error if the request has an
+// If-Match header that does not list the entity tag of a resource last
+// updated at updatedAt. A request without an If-Match header always passes.
+func CheckIfMatch(r *http.Request, updatedAt time.Time) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+
+	etag := ETag(updatedAt)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return &influxdb.Error{
+		Code: influxdb.EPreconditionFailed,
+		Msg:  "resource has been updated since it was read: If-Match does not match its ETag " + etag,
+	}
+}
+
+// HasIfMatch reports whether the request is conditional on an If-Match header.
+func HasIfMatch(r *http.Request) bool {
+	return r.Header.Get("If-Match") != ""
+}
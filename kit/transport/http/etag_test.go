@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	updatedAt := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	etag := ETag(updatedAt)
+	if etag == ETag(updatedAt.Add(time.Nanosecond)) {
+		t.Fatalf("ETag does not change with the update time: %s", etag)
+	}
+
+	for _, test := range []struct {
+		name    string
+		ifMatch string
+		failed  bool
+	}{
+		{name: "no If-Match"},
+		{name: "matching", ifMatch: etag},
+		{name: "any", ifMatch: "*"},
+		{name: "list", ifMatch: `"abc", ` + etag},
+		{name: "stale", ifMatch: ETag(updatedAt.Add(-time.Second)), failed: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/", nil)
+			if test.ifMatch != "" {
+				r.Header.Set("If-Match", test.ifMatch)
+			}
+
+			err := CheckIfMatch(r, updatedAt)
+			if !test.failed {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if code := influxdb.ErrorCode(err); code != influxdb.EPreconditionFailed {
+				t.Fatalf("unexpected error code: got %q, exp %q", code, influxdb.EPreconditionFailed)
+			}
+		})
+	}
+}
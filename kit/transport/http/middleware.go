@@ -1,6 +1,8 @@
 package http
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"strings"
@@ -10,17 +12,23 @@ import (
 	"github.com/influxdata/influxdb/kit/tracing"
 	ua "github.com/mileusna/useragent"
 	"github.com/prometheus/client_golang/prometheus"
+	uuid "github.com/satori/go.uuid"
 )
 
 // Middleware constructor.
 type Middleware func(http.Handler) http.Handler
 
+const (
+	corsAllowMethods = "POST, GET, OPTIONS, PUT, DELETE"
+	corsAllowHeaders = "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization"
+)
+
 func SetCORS(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if origin := r.Header.Get("Origin"); origin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
 		}
 		next.ServeHTTP(w, r)
 	}
@@ -28,6 +36,48 @@ func SetCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// CORS sets the CORS headers of the responses to requests from the origins
+// in allowOrigins, which allows any origin if it contains "*". allowHeaders
+// are the request headers allowed, defaulting to the headers allowed by
+// SetCORS. Preflight requests from allowed origins are answered without
+// calling next.
+func CORS(allowOrigins, allowHeaders []string) Middleware {
+	allowed := make(map[string]bool, len(allowOrigins))
+	for _, origin := range allowOrigins {
+		allowed[origin] = true
+	}
+	headers := corsAllowHeaders
+	if len(allowHeaders) > 0 {
+		headers = strings.Join(allowHeaders, ", ")
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(allowed["*"] || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowed["*"] {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
 func Metrics(name string, reqMetric *prometheus.CounterVec, durMetric *prometheus.HistogramVec) Middleware {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
@@ -91,6 +141,78 @@ func Trace(name string) Middleware {
 	}
 }
 
+// MaxBodySize limits the body of each request to n bytes; 0 disables the
+// limit. A request with a larger Content-Length is rejected by h with a 413
+// Request Entity Too Large error, and reading past n bytes of any other body
+// fails with the same error.
+func MaxBodySize(n int64, h influxdb.HTTPErrorHandler) Middleware {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				h.HandleHTTPError(r.Context(), errBodyTooLarge(n), w)
+				return
+			}
+
+			r.Body = &maxBytesReader{ReadCloser: http.MaxBytesReader(w, r.Body, n), limit: n}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func errBodyTooLarge(n int64) error {
+	return &influxdb.Error{
+		Code: influxdb.ETooLarge,
+		Msg:  fmt.Sprintf("request body exceeds the limit of %d bytes", n),
+	}
+}
+
+// maxBytesReader replaces the error of an http.MaxBytesReader reading past
+// its limit with a 413 Request Entity Too Large error.
+type maxBytesReader struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	if err != nil && err != io.EOF && r.read >= r.limit {
+		err = errBodyTooLarge(r.limit)
+	}
+	return n, err
+}
+
+// TraceIDHeader is the response header of the ID correlating a request with
+// the log entries emitted while handling it.
+const TraceIDHeader = "X-Influx-Trace-ID"
+
+// RequestID stores the ID of each request in its context and returns it in
+// the X-Influx-Trace-ID response header. The ID is the X-Request-ID header of
+// the request when set, or a generated UUID v4 otherwise.
+func RequestID(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(tracing.RequestIDHeader)
+		if id == "" {
+			if u, err := uuid.NewV4(); err == nil {
+				id = u.String()
+			}
+		}
+
+		if id != "" {
+			w.Header().Set(TraceIDHeader, id)
+			r = r.WithContext(tracing.ContextWithRequestID(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
 func UserAgent(r *http.Request) string {
 	header := r.Header.Get("User-Agent")
 	if header == "" {
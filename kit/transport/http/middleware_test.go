@@ -1,12 +1,18 @@
 package http
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/pkg/testttp"
+	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,3 +99,221 @@ func TestCors(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestID(t *testing.T) {
+	var ctxID string
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID, _ = tracing.RequestIDFromContext(r.Context())
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		rec := testttp.
+			HTTP(t, http.MethodGet, "/", nil).
+			Do(RequestID(nextHandler)).
+			ExpectStatus(http.StatusOK).
+			Rec
+
+		id := rec.Header().Get(TraceIDHeader)
+		if _, err := uuid.FromString(id); err != nil {
+			t.Fatalf("expected a UUID trace ID, got %q: %v", id, err)
+		}
+		if ctxID != id {
+			t.Errorf("unexpected request ID in context: got %q, exp %q", ctxID, id)
+		}
+	})
+
+	t.Run("from X-Request-ID", func(t *testing.T) {
+		rec := testttp.
+			HTTP(t, http.MethodGet, "/", nil).
+			Headers(tracing.RequestIDHeader, "abc-123").
+			Do(RequestID(nextHandler)).
+			ExpectStatus(http.StatusOK).
+			Rec
+
+		if got, exp := rec.Header().Get(TraceIDHeader), "abc-123"; got != exp {
+			t.Errorf("unexpected trace ID header: got %q, exp %q", got, exp)
+		}
+		if exp := "abc-123"; ctxID != exp {
+			t.Errorf("unexpected request ID in context: got %q, exp %q", ctxID, exp)
+		}
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			ErrorHandler(0).HandleHTTPError(r.Context(), err, w)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		limit         int64
+		body          string
+		chunked       bool
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "at the limit",
+			limit:        4,
+			body:         "abcd",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:          "over the limit",
+			limit:         4,
+			body:          "abcde",
+			expectedCode:  http.StatusRequestEntityTooLarge,
+			expectedError: "request body exceeds the limit of 4 bytes",
+		},
+		{
+			name:          "chunked over the limit",
+			limit:         4,
+			body:          "abcde",
+			chunked:       true,
+			expectedCode:  http.StatusRequestEntityTooLarge,
+			expectedError: "request body exceeds the limit of 4 bytes",
+		},
+		{
+			name:         "unlimited",
+			body:         "abcde",
+			expectedCode: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/write", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+
+			MaxBodySize(tt.limit, ErrorHandler(0))(nextHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("unexpected status code: got %d, exp %d, body: %s", rec.Code, tt.expectedCode, rec.Body.String())
+			}
+			if tt.expectedError == "" {
+				return
+			}
+			var body struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != influxdb.ETooLarge || body.Message != tt.expectedError {
+				t.Errorf("unexpected error: got %q %q, exp %q %q", body.Code, body.Message, influxdb.ETooLarge, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("nextHandler"))
+	})
+
+	tests := []struct {
+		name            string
+		allowOrigins    []string
+		allowHeaders    []string
+		method          string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			name:         "preflight from allowed origin",
+			allowOrigins: []string{"http://myapp.com", "http://anotherapp.com"},
+			allowHeaders: []string{"Authorization", "X-Request-ID"},
+			method:       http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "http://myapp.com",
+				"Access-Control-Request-Method": "POST",
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "http://myapp.com",
+				"Access-Control-Allow-Methods": "POST, GET, OPTIONS, PUT, DELETE",
+				"Access-Control-Allow-Headers": "Authorization, X-Request-ID",
+				"Vary":                         "Origin",
+			},
+		},
+		{
+			name:           "GET from allowed origin",
+			allowOrigins:   []string{"http://myapp.com"},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "http://myapp.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "http://myapp.com",
+				"Access-Control-Allow-Headers": "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization",
+			},
+		},
+		{
+			name:           "GET from any origin",
+			allowOrigins:   []string{"*"},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "http://myapp.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+				"Vary":                        "",
+			},
+		},
+		{
+			name:           "GET from other origin",
+			allowOrigins:   []string{"http://myapp.com"},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "http://evil.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:           "preflight from other origin",
+			allowOrigins:   []string{"http://myapp.com"},
+			method:         http.MethodOptions,
+			headers:        map[string]string{"Origin": "http://evil.com", "Access-Control-Request-Method": "POST"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(CORS(tt.allowOrigins, tt.allowHeaders)(nextHandler))
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL+"/api/v2/query", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("unexpected status code: got %d, exp %d", resp.StatusCode, tt.expectedStatus)
+			}
+			for k, exp := range tt.expectedHeaders {
+				if got := resp.Header.Get(k); got != exp {
+					t.Errorf("unexpected %s header: got %q, exp %q", k, got, exp)
+				}
+			}
+		})
+	}
+}
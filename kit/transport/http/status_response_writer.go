@@ -1,6 +1,11 @@
 package http
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
 
 type StatusResponseWriter struct {
 	statusCode    int
@@ -26,6 +31,18 @@ func (w *StatusResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Hijack lets the caller take over the connection, such as to upgrade it to a
+// WebSocket, if the wrapped ResponseWriter supports it.
+func (w *StatusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	// The status of a hijacked connection is that of the upgrade.
+	w.statusCode = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
 func (w *StatusResponseWriter) Code() int {
 	code := w.statusCode
 	if code == 0 {
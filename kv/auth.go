@@ -126,6 +126,17 @@ func (s *Service) findAuthorizationByToken(ctx context.Context, tx Tx, n string)
 			Err:  err,
 		}
 	}
+
+	// The authorization of an expired OpenID Connect session can no longer
+	// be used.
+	if expired, err := s.oidcSessionExpired(ctx, tx, id); err != nil {
+		return nil, err
+	} else if expired {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  "authorization not found",
+		}
+	}
 	return s.findAuthorizationByID(ctx, tx, id)
 }
 
@@ -503,7 +514,11 @@ func (s *Service) deleteAuthorization(ctx context.Context, tx Tx, id influxdb.ID
 			Err: err,
 		}
 	}
-	return nil
+
+	if err := s.deleteWriteUsage(ctx, tx, id); err != nil {
+		return err
+	}
+	return s.deleteOIDCSession(ctx, tx, id)
 }
 
 // UpdateAuthorization updates the status and description if available.
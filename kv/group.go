@@ -0,0 +1,382 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	groupBucket = []byte("groupsv1")
+)
+
+var _ influxdb.GroupService = (*Service)(nil)
+
+func (s *Service) initializeGroups(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(groupBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addGroupsPermissions grants the permissions on groups to the authorizations
+// created before groups were added, which would have been given them. For
+// each action, an authorization allowed it on every other resource type is
+// allowed it on all groups, as operators are, and one allowed it on every other
+// resource type of an organization is allowed it on the organization's groups.
+func (s *Service) addGroupsPermissions(ctx context.Context, tx Tx) error {
+	var updated []*influxdb.Authorization
+	err := s.forEachAuthorization(ctx, tx, nil, func(a *influxdb.Authorization) bool {
+		if ps := missingGroupsPermissions(a.Permissions); len(ps) > 0 {
+			a.Permissions = append(a.Permissions, ps...)
+			updated = append(updated, a)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range updated {
+		if err := s.putAuthorization(ctx, tx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingGroupsPermissions returns the permissions on groups that are implied
+// by ps but not in it. See addGroupsPermissions.
+func missingGroupsPermissions(ps []influxdb.Permission) []influxdb.Permission {
+	allowedAll := func(types []influxdb.ResourceType, action influxdb.Action, orgID *influxdb.ID) bool {
+		for _, t := range types {
+			if t == influxdb.GroupsResourceType {
+				continue
+			}
+			p := influxdb.Permission{Action: action, Resource: influxdb.Resource{Type: t, OrgID: orgID}}
+			if !influxdb.PermissionAllowed(p, ps) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var orgIDs []influxdb.ID
+	seen := make(map[influxdb.ID]bool)
+	for _, p := range ps {
+		if id := p.Resource.OrgID; id != nil && !seen[*id] {
+			seen[*id] = true
+			orgIDs = append(orgIDs, *id)
+		}
+	}
+
+	var missing []influxdb.Permission
+	for _, action := range []influxdb.Action{influxdb.ReadAction, influxdb.WriteAction} {
+		all := influxdb.Permission{Action: action, Resource: influxdb.Resource{Type: influxdb.GroupsResourceType}}
+		if allowedAll(influxdb.AllResourceTypes, action, nil) {
+			if !influxdb.PermissionAllowed(all, ps) {
+				missing = append(missing, all)
+			}
+			continue
+		}
+
+		for i := range orgIDs {
+			orgID := &orgIDs[i]
+			p := influxdb.Permission{Action: action, Resource: influxdb.Resource{Type: influxdb.GroupsResourceType, OrgID: orgID}}
+			if allowedAll(influxdb.OrgResourceTypes, action, orgID) && !influxdb.PermissionAllowed(p, ps) {
+				missing = append(missing, p)
+			}
+		}
+	}
+	return missing
+}
+
+// FindGroupByID retrieves a group by id.
+func (s *Service) FindGroupByID(ctx context.Context, id influxdb.ID) (*influxdb.Group, error) {
+	var g *influxdb.Group
+	err := s.kv.View(ctx, func(tx Tx) error {
+		grp, err := s.findGroupByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		g = grp
+		return nil
+	})
+
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindGroupByID,
+			Err: err,
+		}
+	}
+
+	return g, nil
+}
+
+func (s *Service) findGroupByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Group, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(groupBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrGroupNotFound,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var g influxdb.Group
+	if err := json.Unmarshal(v, &g); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	return &g, nil
+}
+
+func filterGroupsFn(filter influxdb.GroupFilter) func(g *influxdb.Group) bool {
+	return func(g *influxdb.Group) bool {
+		return (filter.ID == nil || *filter.ID == g.ID) &&
+			(filter.OrgID == nil || *filter.OrgID == g.OrgID) &&
+			(filter.UserID == nil || g.HasMember(*filter.UserID))
+	}
+}
+
+// FindGroups returns a list of groups that match filter and the total count of matching groups.
+func (s *Service) FindGroups(ctx context.Context, filter influxdb.GroupFilter, opt ...influxdb.FindOptions) ([]*influxdb.Group, int, error) {
+	var gs []*influxdb.Group
+	err := s.kv.View(ctx, func(tx Tx) error {
+		grps, err := s.findGroups(ctx, tx, filter)
+		if err != nil {
+			return err
+		}
+		gs = grps
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindGroups,
+			Err: err,
+		}
+	}
+
+	return gs, len(gs), nil
+}
+
+func (s *Service) findGroups(ctx context.Context, tx Tx, filter influxdb.GroupFilter) ([]*influxdb.Group, error) {
+	if filter.ID != nil {
+		g, err := s.findGroupByID(ctx, tx, *filter.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !filterGroupsFn(filter)(g) {
+			return []*influxdb.Group{}, nil
+		}
+		return []*influxdb.Group{g}, nil
+	}
+
+	b, err := tx.Bucket(groupBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := b.ForwardCursor(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	filterFn := filterGroupsFn(filter)
+	gs := []*influxdb.Group{}
+	for k, v := cur.Next(); k != nil; k, v = cur.Next() {
+		var g influxdb.Group
+		if err := json.Unmarshal(v, &g); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		if filterFn(&g) {
+			gs = append(gs, &g)
+		}
+	}
+
+	return gs, cur.Err()
+}
+
+// CreateGroup creates a new group and sets g.ID with the new identifier.
+func (s *Service) CreateGroup(ctx context.Context, g *influxdb.Group) error {
+	if g.Name == "" {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   influxdb.OpCreateGroup,
+			Msg:  "group name is required",
+		}
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findOrganizationByID(ctx, tx, g.OrgID); err != nil {
+			return err
+		}
+
+		g.ID = s.IDGenerator.ID()
+		return s.putGroup(ctx, tx, g)
+	})
+
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateGroup,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) putGroup(ctx context.Context, tx Tx, g *influxdb.Group) error {
+	v, err := json.Marshal(g)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := g.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(groupBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(encodedID, v)
+}
+
+// DeleteGroup removes a group by ID along with any resource mappings granted to it.
+func (s *Service) DeleteGroup(ctx context.Context, id influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findGroupByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := s.deleteUserResourceMappings(ctx, tx, influxdb.UserResourceMappingFilter{
+			GroupID: &id,
+		}); err != nil {
+			return err
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(groupBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Delete(encodedID)
+	})
+
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteGroup,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// AddGroupMember adds the user to the group.
+func (s *Service) AddGroupMember(ctx context.Context, groupID, userID influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		g, err := s.findGroupByID(ctx, tx, groupID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.findUserByID(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		if g.HasMember(userID) {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("user %s is already a member of group %s", userID, groupID),
+			}
+		}
+
+		g.Members = append(g.Members, userID)
+		return s.putGroup(ctx, tx, g)
+	})
+
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpAddGroupMember,
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// RemoveGroupMember removes the user from the group.
+func (s *Service) RemoveGroupMember(ctx context.Context, groupID, userID influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		g, err := s.findGroupByID(ctx, tx, groupID)
+		if err != nil {
+			return err
+		}
+
+		members := g.Members[:0]
+		for _, id := range g.Members {
+			if id != userID {
+				members = append(members, id)
+			}
+		}
+
+		if len(members) == len(g.Members) {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  fmt.Sprintf("user %s is not a member of group %s", userID, groupID),
+			}
+		}
+
+		g.Members = members
+		return s.putGroup(ctx, tx, g)
+	})
+
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpRemoveGroupMember,
+			Err: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestService_GroupResourceMapping(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "jane"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	bucket := &influxdb.Bucket{OrgID: org.ID, Name: "metrics"}
+	if err := svc.CreateBucket(ctx, bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &influxdb.Group{OrgID: org.ID, Name: "readers"}
+	if err := svc.CreateGroup(ctx, group); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AddGroupMember(ctx, group.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+		GroupID:      &group.ID,
+		UserType:     influxdb.Member,
+		ResourceType: influxdb.BucketsResourceType,
+		ResourceID:   bucket.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	readBucket := influxdb.Permission{
+		Action: influxdb.ReadAction,
+		Resource: influxdb.Resource{
+			Type:  influxdb.BucketsResourceType,
+			ID:    &bucket.ID,
+			OrgID: &org.ID,
+		},
+	}
+
+	sess, err := svc.CreateSession(ctx, user.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess, err = svc.FindSession(ctx, sess.Key); err != nil {
+		t.Fatal(err)
+	}
+	if !sess.Allowed(readBucket) {
+		t.Fatal("expected group member to be allowed to read bucket")
+	}
+
+	ms, _, err := svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{GroupID: &group.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].ResourceID != bucket.ID {
+		t.Fatalf("unexpected group mappings: %+v", ms)
+	}
+
+	if err := svc.RemoveGroupMember(ctx, group.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess, err = svc.FindSession(ctx, sess.Key); err != nil {
+		t.Fatal(err)
+	}
+	if sess.Allowed(readBucket) {
+		t.Fatal("expected removed member to no longer be allowed to read bucket")
+	}
+
+	if err := svc.DeleteGroup(ctx, group.ID); err != nil {
+		t.Fatal(err)
+	}
+	ms, _, err = svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{GroupID: &group.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("expected group mappings to be deleted, got %+v", ms)
+	}
+}
+
+func TestService_GroupsPermissionsMigration(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.NewKVStore()
+	svc := kv.NewService(zaptest.NewLogger(t), store)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "jane"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// withoutGroups returns ps as it was before groups were added.
+	withoutGroups := func(ps []influxdb.Permission) []influxdb.Permission {
+		var out []influxdb.Permission
+		for _, p := range ps {
+			if p.Resource.Type != influxdb.GroupsResourceType {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	operator := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, Permissions: withoutGroups(influxdb.OperPermissions())}
+	owner := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, Permissions: withoutGroups(influxdb.OwnerPermissions(org.ID))}
+	member := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, Permissions: withoutGroups(influxdb.MemberPermissions(org.ID))}
+	bucketReader := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, Permissions: []influxdb.Permission{{
+		Action:   influxdb.ReadAction,
+		Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &org.ID},
+	}}}
+	for _, a := range []*influxdb.Authorization{operator, owner, member, bucketReader} {
+		if err := svc.CreateAuthorization(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Rerun the migrations, as when upgrading from a version without groups.
+	if err := svc.Migrator.Down(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Migrator.Up(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+
+	groups := func(action influxdb.Action, orgID *influxdb.ID) influxdb.Permission {
+		return influxdb.Permission{Action: action, Resource: influxdb.Resource{Type: influxdb.GroupsResourceType, OrgID: orgID}}
+	}
+	otherOrgID := influxdb.ID(1)
+
+	for _, tt := range []struct {
+		name    string
+		auth    *influxdb.Authorization
+		allowed []influxdb.Permission
+		denied  []influxdb.Permission
+	}{
+		{
+			name:    "operator",
+			auth:    operator,
+			allowed: []influxdb.Permission{groups(influxdb.ReadAction, nil), groups(influxdb.WriteAction, nil)},
+		},
+		{
+			name:    "owner",
+			auth:    owner,
+			allowed: []influxdb.Permission{groups(influxdb.ReadAction, &org.ID), groups(influxdb.WriteAction, &org.ID)},
+			denied:  []influxdb.Permission{groups(influxdb.ReadAction, &otherOrgID)},
+		},
+		{
+			name:    "member",
+			auth:    member,
+			allowed: []influxdb.Permission{groups(influxdb.ReadAction, &org.ID)},
+			denied:  []influxdb.Permission{groups(influxdb.WriteAction, &org.ID)},
+		},
+		{
+			name:   "bucket reader",
+			auth:   bucketReader,
+			denied: []influxdb.Permission{groups(influxdb.ReadAction, &org.ID)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := svc.FindAuthorizationByID(ctx, tt.auth.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tt.allowed {
+				if !a.Allowed(p) {
+					t.Errorf("expected %s to be allowed", p)
+				}
+			}
+			for _, p := range tt.denied {
+				if a.Allowed(p) {
+					t.Errorf("expected %s to be denied", p)
+				}
+			}
+		})
+	}
+}
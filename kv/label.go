@@ -319,6 +319,7 @@ func (s *Service) createUserResourceMappingForOrg(ctx context.Context, tx Tx, or
 			ResourceType: resType,
 			ResourceID:   resID,
 			UserID:       m.UserID,
+			GroupID:      m.GroupID,
 			UserType:     m.UserType,
 		}); err != nil {
 			return &influxdb.Error{
@@ -0,0 +1,258 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	oidcIdentityBucket = []byte("oidcidentitiesv1")
+	oidcSessionBucket  = []byte("oidcsessionsv1")
+)
+
+var _ influxdb.OIDCIdentityService = (*Service)(nil)
+
+func (s *Service) initializeOIDCIdentities(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(oidcIdentityBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) initializeOIDCSessions(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(oidcSessionBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// oidcIdentityKey returns the key of the identity of the subject of the
+// issuer. The issuer is prefixed with its length, so that no pair of issuer
+// and subject shares a key with another.
+func oidcIdentityKey(issuer, subject string) []byte {
+	return []byte(strconv.Itoa(len(issuer)) + ":" + issuer + subject)
+}
+
+// FindOIDCIdentity returns the identity of the subject of the issuer.
+func (s *Service) FindOIDCIdentity(ctx context.Context, issuer, subject string) (*influxdb.OIDCIdentity, error) {
+	var i *influxdb.OIDCIdentity
+	err := s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(oidcIdentityBucket)
+		if err != nil {
+			return err
+		}
+
+		v, err := b.Get(oidcIdentityKey(issuer, subject))
+		if IsNotFound(err) {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrOIDCIdentityNotFound,
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		i = &influxdb.OIDCIdentity{}
+		if err := json.Unmarshal(v, i); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// CreateOIDCIdentity creates a new identity. It returns an EConflict error if
+// the subject of the issuer already has an identity.
+func (s *Service) CreateOIDCIdentity(ctx context.Context, i *influxdb.OIDCIdentity) error {
+	if i.Issuer == "" || i.Subject == "" || !i.UserID.Valid() {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "oidc identity requires an issuer, subject and user",
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(oidcIdentityBucket)
+		if err != nil {
+			return err
+		}
+
+		key := oidcIdentityKey(i.Issuer, i.Subject)
+		if _, err := b.Get(key); err == nil {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  "oidc identity already exists",
+			}
+		} else if !IsNotFound(err) {
+			return err
+		}
+
+		v, err := json.Marshal(i)
+		if err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return b.Put(key, v)
+	})
+}
+
+// FindOIDCSession returns the session of the authorization with the provided
+// id. It returns an ENotFound error if the authorization was not minted by an
+// OpenID Connect login.
+func (s *Service) FindOIDCSession(ctx context.Context, authorizationID influxdb.ID) (*influxdb.OIDCSession, error) {
+	var sess *influxdb.OIDCSession
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		sess, err = s.findOIDCSession(ctx, tx, authorizationID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *Service) findOIDCSession(ctx context.Context, tx Tx, authorizationID influxdb.ID) (*influxdb.OIDCSession, error) {
+	key, err := authorizationID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(oidcSessionBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(key)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  "oidc session not found",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &influxdb.OIDCSession{}
+	if err := json.Unmarshal(v, sess); err != nil {
+		return nil, &influxdb.Error{
+			Err: err,
+		}
+	}
+	return sess, nil
+}
+
+// CreateOIDCSession records the session of an authorization. Expired sessions
+// are removed along with their authorizations.
+func (s *Service) CreateOIDCSession(ctx context.Context, sess *influxdb.OIDCSession) error {
+	key, err := sess.AuthorizationID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	v, err := json.Marshal(sess)
+	if err != nil {
+		return &influxdb.Error{
+			Err: err,
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if err := s.deleteExpiredOIDCSessions(ctx, tx); err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(oidcSessionBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, v)
+	})
+}
+
+// deleteExpiredOIDCSessions removes the expired sessions and their
+// authorizations.
+func (s *Service) deleteExpiredOIDCSessions(ctx context.Context, tx Tx) error {
+	b, err := tx.Bucket(oidcSessionBucket)
+	if err != nil {
+		return err
+	}
+	cur, err := b.ForwardCursor(nil)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	var expired []influxdb.ID
+	for k, v := cur.Next(); k != nil; k, v = cur.Next() {
+		var sess influxdb.OIDCSession
+		if err := json.Unmarshal(v, &sess); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		if sess.Expired(now) {
+			expired = append(expired, sess.AuthorizationID)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if err := cur.Close(); err != nil {
+		return err
+	}
+
+	for _, id := range expired {
+		if err := s.deleteAuthorization(ctx, tx, id); err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+			return err
+		}
+		// The session of an authorization deleted before is left.
+		if err := s.deleteOIDCSession(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oidcSessionExpired returns whether the authorization with the provided id
+// was minted by an OpenID Connect login whose session has expired.
+func (s *Service) oidcSessionExpired(ctx context.Context, tx Tx, authorizationID influxdb.ID) (bool, error) {
+	sess, err := s.findOIDCSession(ctx, tx, authorizationID)
+	if influxdb.ErrorCode(err) == influxdb.ENotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return sess.Expired(s.clock.Now()), nil
+}
+
+// deleteOIDCSession removes the session of a deleted authorization.
+func (s *Service) deleteOIDCSession(ctx context.Context, tx Tx, authorizationID influxdb.ID) error {
+	key, err := authorizationID.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(oidcSessionBucket)
+	if err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
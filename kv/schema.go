@@ -0,0 +1,152 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+)
+
+var (
+	measurementSchemaBucket = []byte("measurementschemasv1")
+)
+
+var _ influxdb.SchemaService = (*Service)(nil)
+
+func (s *Service) initializeMeasurementSchemas(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(measurementSchemaBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateMeasurementSchema registers the schema of a measurement in a bucket.
+func (s *Service) CreateMeasurementSchema(ctx context.Context, orgID, bucketID influxdb.ID, schema influxdb.MeasurementSchema) error {
+	if err := schema.Valid(); err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		key, err := encodeMeasurementSchemaKey(orgID, bucketID, schema.Measurement)
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(measurementSchemaBucket)
+		if err != nil {
+			return err
+		}
+
+		if _, err := b.Get(key); err == nil {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  "measurement schema already exists",
+			}
+		} else if !IsNotFound(err) {
+			return err
+		}
+
+		v, err := json.Marshal(schema)
+		if err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return b.Put(key, v)
+	})
+}
+
+// FindMeasurementSchema returns the schema of a measurement in a bucket.
+func (s *Service) FindMeasurementSchema(ctx context.Context, orgID, bucketID influxdb.ID, measurement string) (*influxdb.MeasurementSchema, error) {
+	var schema *influxdb.MeasurementSchema
+	err := s.kv.View(ctx, func(tx Tx) error {
+		sch, err := s.findMeasurementSchema(ctx, tx, orgID, bucketID, measurement)
+		if err != nil {
+			return err
+		}
+		schema = sch
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (s *Service) findMeasurementSchema(ctx context.Context, tx Tx, orgID, bucketID influxdb.ID, measurement string) (*influxdb.MeasurementSchema, error) {
+	key, err := encodeMeasurementSchemaKey(orgID, bucketID, measurement)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := tx.Bucket(measurementSchemaBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(key)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrMeasurementSchemaNotFound,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var schema influxdb.MeasurementSchema
+	if err := json.Unmarshal(v, &schema); err != nil {
+		return nil, &influxdb.Error{
+			Err: err,
+		}
+	}
+	return &schema, nil
+}
+
+// ValidatePoint returns an error if the fields of p do not match the schema
+// of its measurement.
+func (s *Service) ValidatePoint(ctx context.Context, orgID, bucketID influxdb.ID, p models.Point) error {
+	measurement := p.Tags().Get(models.MeasurementTagKeyBytes)
+	if measurement == nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "point has no measurement",
+		}
+	}
+
+	schema, err := s.FindMeasurementSchema(ctx, orgID, bucketID, string(measurement))
+	if err != nil {
+		return err
+	}
+
+	iter := p.FieldIterator()
+	for iter.Next() {
+		if err := schema.ValidateField(string(iter.FieldKey()), iter.Type()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMeasurementSchemaKey returns the key of the schema of a measurement,
+// which is the organization and bucket IDs followed by the measurement.
+func encodeMeasurementSchemaKey(orgID, bucketID influxdb.ID, measurement string) ([]byte, error) {
+	org, err := orgID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	bucket, err := bucketID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	return bytes.Join([][]byte{org, bucket, []byte(measurement)}, nil), nil
+}
@@ -0,0 +1,57 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestService_MeasurementSchema(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const orgID, bucketID = influxdb.ID(1), influxdb.ID(2)
+	schema := influxdb.MeasurementSchema{
+		Measurement: "cpu",
+		Fields: []influxdb.MeasurementSchemaField{
+			{Name: "usage", Type: influxdb.SchemaFieldTypeFloat},
+			{Name: "cores", Type: influxdb.SchemaFieldTypeInteger},
+		},
+	}
+	if err := svc.CreateMeasurementSchema(ctx, orgID, bucketID, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.FindMeasurementSchema(ctx, orgID, bucketID, "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Fields) != 2 || got.Fields[1] != schema.Fields[1] {
+		t.Fatalf("unexpected schema: %+v", got)
+	}
+
+	if err := svc.CreateMeasurementSchema(ctx, orgID, bucketID, schema); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("expected conflict creating schema twice, got %v", err)
+	}
+
+	// Schemas are per bucket.
+	if _, err := svc.FindMeasurementSchema(ctx, orgID, bucketID+1, "cpu"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected schema not to be found in another bucket, got %v", err)
+	}
+
+	invalid := influxdb.MeasurementSchema{
+		Measurement: "mem",
+		Fields:      []influxdb.MeasurementSchemaField{{Name: "free", Type: "long"}},
+	}
+	if err := svc.CreateMeasurementSchema(ctx, orgID, bucketID, invalid); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid schema to be rejected, got %v", err)
+	}
+}
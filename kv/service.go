@@ -75,7 +75,7 @@ func NewService(log *zap.Logger, kv Store, configs ...ServiceConfig) *Service {
 					return nil, err
 				}
 
-				id, _ := urm.UserID.Encode()
+				id, _ := urm.PrincipalID().Encode()
 				return id, nil
 			},
 		)),
@@ -95,6 +95,84 @@ func NewService(log *zap.Logger, kv Store, configs ...ServiceConfig) *Service {
 		),
 		// add index user resource mappings by user id
 		s.urmByUserIndex.Migration(),
+		// add groups bucket
+		NewAnonymousMigration(
+			"create groups bucket",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.initializeGroups(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
+		// add measurement schemas bucket
+		NewAnonymousMigration(
+			"create measurement schemas bucket",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.initializeMeasurementSchemas(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
+		// add authorization write usage bucket
+		NewAnonymousMigration(
+			"create authorization write usage bucket",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.initializeWriteUsage(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
+		// add oidc identities bucket
+		NewAnonymousMigration(
+			"create oidc identities bucket",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.initializeOIDCIdentities(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
+		// add the permissions on groups to existing authorizations
+		NewAnonymousMigration(
+			"add groups permissions to authorizations",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.addGroupsPermissions(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
+		// add oidc sessions bucket
+		NewAnonymousMigration(
+			"create oidc sessions bucket",
+			func(ctx context.Context, store Store) error {
+				return store.Update(ctx, func(tx Tx) error {
+					return s.initializeOIDCSessions(ctx, tx)
+				})
+			},
+			// down is a noop
+			func(context.Context, Store) error {
+				return nil
+			},
+		),
 		// and new migrations below here (and move this comment down):
 	)
 
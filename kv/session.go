@@ -111,6 +111,21 @@ func (s *Service) maxPermissions(ctx context.Context, tx Tx, userID influxdb.ID)
 		}
 	}
 
+	// include the mappings granted to any group the user is a member of
+	groups, err := s.findGroups(ctx, tx, influxdb.GroupFilter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		ms, err := s.findUserResourceMappings(ctx, tx, influxdb.UserResourceMappingFilter{GroupID: &g.ID})
+		if err != nil {
+			return nil, &influxdb.Error{
+				Err: err,
+			}
+		}
+		mappings = append(mappings, ms...)
+	}
+
 	ps := make([]influxdb.Permission, 0, len(mappings))
 	for _, m := range mappings {
 		p, err := m.ToPermissions()
@@ -73,9 +73,18 @@ func (s *Service) initializeURMs(ctx context.Context, tx Tx) error {
 	return nil
 }
 
+// filterPrincipalID returns the user or group id the filter restricts mappings to.
+func filterPrincipalID(filter influxdb.UserResourceMappingFilter) influxdb.ID {
+	if filter.GroupID != nil {
+		return *filter.GroupID
+	}
+	return filter.UserID
+}
+
 func filterMappingsFn(filter influxdb.UserResourceMappingFilter) func(m *influxdb.UserResourceMapping) bool {
 	return func(mapping *influxdb.UserResourceMapping) bool {
-		return (!filter.UserID.Valid() || (filter.UserID == mapping.UserID)) &&
+		return (!filter.UserID.Valid() || (filter.UserID == mapping.PrincipalID())) &&
+			(filter.GroupID == nil || (mapping.GroupID != nil && *filter.GroupID == *mapping.GroupID)) &&
 			(!filter.ResourceID.Valid() || (filter.ResourceID == mapping.ResourceID)) &&
 			(filter.UserType == "" || (filter.UserType == mapping.UserType)) &&
 			(filter.ResourceType == "" || (filter.ResourceType == mapping.ResourceType))
@@ -99,20 +108,21 @@ func (s *Service) FindUserResourceMappings(ctx context.Context, filter influxdb.
 }
 
 func userResourceMappingPredicate(filter influxdb.UserResourceMappingFilter) CursorPredicateFunc {
+	principalID := filterPrincipalID(filter)
 	switch {
-	case filter.ResourceID.Valid() && filter.UserID.Valid():
-		keyPredicate := filter.ResourceID.String() + filter.UserID.String()
+	case filter.ResourceID.Valid() && principalID.Valid():
+		keyPredicate := filter.ResourceID.String() + principalID.String()
 		return func(key, _ []byte) bool {
 			return len(key) >= 32 && string(key[:32]) == keyPredicate
 		}
 
-	case !filter.ResourceID.Valid() && filter.UserID.Valid():
-		keyPredicate := filter.UserID.String()
+	case !filter.ResourceID.Valid() && principalID.Valid():
+		keyPredicate := principalID.String()
 		return func(key, _ []byte) bool {
 			return len(key) >= 32 && string(key[16:32]) == keyPredicate
 		}
 
-	case filter.ResourceID.Valid() && !filter.UserID.Valid():
+	case filter.ResourceID.Valid() && !principalID.Valid():
 		keyPredicate := filter.ResourceID.String()
 		return func(key, _ []byte) bool {
 			return len(key) >= 16 && string(key[:16]) == keyPredicate
@@ -125,9 +135,9 @@ func userResourceMappingPredicate(filter influxdb.UserResourceMappingFilter) Cur
 
 func (s *Service) findUserResourceMappings(ctx context.Context, tx Tx, filter influxdb.UserResourceMappingFilter) (ms []*influxdb.UserResourceMapping, _ error) {
 	filterFn := filterMappingsFn(filter)
-	if filter.UserID.Valid() {
+	if principalID := filterPrincipalID(filter); principalID.Valid() {
 		// urm by user index lookup
-		userID, _ := filter.UserID.Encode()
+		userID, _ := principalID.Encode()
 		if err := s.urmByUserIndex.Walk(tx, userID, func(k, v []byte) error {
 			m := &influxdb.UserResourceMapping{}
 			if err := json.Unmarshal(v, m); err != nil {
@@ -212,7 +222,7 @@ func (s *Service) createUserResourceMapping(ctx context.Context, tx Tx, m *influ
 		return UnavailableURMServiceError(err)
 	}
 
-	userID, err := m.UserID.Encode()
+	userID, err := m.PrincipalID().Encode()
 	if err != nil {
 		return err
 	}
@@ -245,6 +255,7 @@ func (s *Service) createOrgDependentMappings(ctx context.Context, tx Tx, m *infl
 			ResourceID:   b.ID,
 			UserType:     m.UserType,
 			UserID:       m.UserID,
+			GroupID:      m.GroupID,
 		}
 		if err := s.createUserResourceMapping(ctx, tx, m); err != nil {
 			return err
@@ -261,7 +272,7 @@ func userResourceKey(m *influxdb.UserResourceMapping) ([]byte, error) {
 		return nil, ErrInvalidURMID
 	}
 
-	encodedUserID, err := m.UserID.Encode()
+	encodedUserID, err := m.PrincipalID().Encode()
 	if err != nil {
 		return nil, ErrInvalidURMID
 	}
@@ -315,7 +326,7 @@ func (s *Service) uniqueUserResourceMapping(ctx context.Context, tx Tx, m *influ
 
 	_, err = b.Get(key)
 	if !IsNotFound(err) {
-		return NonUniqueMappingError(m.UserID)
+		return NonUniqueMappingError(m.PrincipalID())
 	}
 
 	return nil
@@ -382,7 +393,7 @@ func (s *Service) deleteUserResourceMapping(ctx context.Context, tx Tx, filter i
 		return UnavailableURMServiceError(err)
 	}
 
-	userID, err := ms[0].UserID.Encode()
+	userID, err := ms[0].PrincipalID().Encode()
 	if err != nil {
 		return err
 	}
@@ -423,7 +434,7 @@ func (s *Service) deleteUserResourceMappings(ctx context.Context, tx Tx, filter
 			return UnavailableURMServiceError(err)
 		}
 
-		userID, err := m.UserID.Encode()
+		userID, err := m.PrincipalID().Encode()
 		if err != nil {
 			return err
 		}
@@ -447,7 +458,7 @@ func (s *Service) deleteOrgDependentMappings(ctx context.Context, tx Tx, m *infl
 		if err := s.deleteUserResourceMapping(ctx, tx, influxdb.UserResourceMappingFilter{
 			ResourceType: influxdb.BucketsResourceType,
 			ResourceID:   b.ID,
-			UserID:       m.UserID,
+			UserID:       m.PrincipalID(),
 		}); err != nil {
 			if influxdb.ErrorCode(err) == influxdb.ENotFound {
 				s.log.Info("URM bucket is missing", zap.Stringer("orgID", m.ResourceID))
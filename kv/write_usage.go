@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	writeUsageBucket = []byte("authorizationwriteusagev1")
+)
+
+var _ influxdb.WriteUsageService = (*Service)(nil)
+
+func (s *Service) initializeWriteUsage(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(writeUsageBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindWriteUsage returns the write usage of an authorization.
+func (s *Service) FindWriteUsage(ctx context.Context, authID influxdb.ID) (*influxdb.WriteUsage, error) {
+	var u *influxdb.WriteUsage
+	err := s.kv.View(ctx, func(tx Tx) error {
+		key, err := authID.Encode()
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+
+		b, err := tx.Bucket(writeUsageBucket)
+		if err != nil {
+			return err
+		}
+
+		v, err := b.Get(key)
+		if IsNotFound(err) {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrWriteUsageNotFound,
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		u = &influxdb.WriteUsage{}
+		if err := json.Unmarshal(v, u); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// PutWriteUsage replaces the write usage of an authorization. A usage without
+// hours is removed.
+func (s *Service) PutWriteUsage(ctx context.Context, u *influxdb.WriteUsage) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		key, err := u.AuthorizationID.Encode()
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+
+		b, err := tx.Bucket(writeUsageBucket)
+		if err != nil {
+			return err
+		}
+
+		if len(u.Hours) == 0 {
+			return b.Delete(key)
+		}
+
+		v, err := json.Marshal(u)
+		if err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return b.Put(key, v)
+	})
+}
+
+// deleteWriteUsage removes the write usage of a deleted authorization.
+func (s *Service) deleteWriteUsage(ctx context.Context, tx Tx, authID influxdb.ID) error {
+	key, err := authID.Encode()
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(writeUsageBucket)
+	if err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
@@ -0,0 +1,65 @@
+package kv_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestService_WriteUsage(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "jane"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	auth := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, MaxBytesPerDay: 100}
+	if err := svc.CreateAuthorization(ctx, auth); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.FindWriteUsage(ctx, auth.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	usage := &influxdb.WriteUsage{
+		AuthorizationID: auth.ID,
+		Hours: []influxdb.WriteUsageHour{
+			{Start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Bytes: 10},
+			{Start: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), Bytes: 20},
+		},
+	}
+	if err := svc.PutWriteUsage(ctx, usage); err != nil {
+		t.Fatal(err)
+	}
+	got, err := svc.FindWriteUsage(ctx, auth.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, usage) {
+		t.Errorf("unexpected write usage: got %+v want %+v", got, usage)
+	}
+
+	// The usage of a deleted authorization is removed.
+	if err := svc.DeleteAuthorization(ctx, auth.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FindWriteUsage(ctx, auth.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
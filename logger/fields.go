@@ -40,6 +40,10 @@ const (
 
 	// TraceSampledKey is the logging context key used for determining whether the current trace will be sampled.
 	TraceSampledKey = "ot_trace_sampled"
+
+	// RequestTraceIDKey is the logging context key used for correlating the entries logged while
+	// handling a request.
+	RequestTraceIDKey = "trace_id"
 )
 const (
 	eventStart = "start"
@@ -95,23 +99,31 @@ func Shard(id uint64) zapcore.Field {
 }
 
 // TraceFields returns a fields "ot_trace_id" and "ot_trace_sampled", values pulled from the (Jaeger) trace ID
-// found in the given context. Returns nil if the context doesn't have a trace ID.
+// found in the given context, and a field "trace_id" of the ID of the request handled within the context.
+// Returns nil if the context has neither a trace ID nor a request ID.
 func TraceFields(ctx context.Context) []zap.Field {
-	id, sampled, found := tracing.InfoFromContext(ctx)
-	if !found {
-		return nil
+	var fields []zap.Field
+	if id, sampled, found := tracing.InfoFromContext(ctx); found {
+		fields = append(fields, zap.String(TraceIDKey, id), zap.Bool(TraceSampledKey, sampled))
+	}
+	if id, found := tracing.RequestIDFromContext(ctx); found {
+		fields = append(fields, zap.String(RequestTraceIDKey, id))
 	}
-	return []zap.Field{zap.String(TraceIDKey, id), zap.Bool(TraceSampledKey, sampled)}
+	return fields
 }
 
-// TraceID returns a field "trace_id", value pulled from the (Jaeger) trace ID found in the given context.
-// Returns zap.Skip() if the context doesn't have a trace ID.
+// TraceID returns a field "trace_id", value pulled from the (Jaeger) trace ID found in the given context,
+// or else from the ID of the request handled within the context.
+// Returns zap.Skip() if the context has neither a trace ID nor a request ID.
 func TraceID(ctx context.Context) zap.Field {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		if spanContext, ok := span.Context().(jaeger.SpanContext); ok {
-			return zap.String("trace_id", spanContext.TraceID().String())
+			return zap.String(RequestTraceIDKey, spanContext.TraceID().String())
 		}
 	}
+	if id, found := tracing.RequestIDFromContext(ctx); found {
+		return zap.String(RequestTraceIDKey, id)
+	}
 	return zap.Skip()
 }
 
@@ -104,3 +104,45 @@ func (s *BucketService) DeleteBucket(ctx context.Context, id platform.ID) error
 	defer s.DeleteBucketCalls.IncrFn()()
 	return s.DeleteBucketFn(ctx, id)
 }
+
+var _ platform.BucketCardinalityService = (*BucketCardinalityService)(nil)
+
+// BucketCardinalityService is a mock implementation of platform.BucketCardinalityService.
+type BucketCardinalityService struct {
+	SeriesCountFn func(ctx context.Context, orgID, bucketID platform.ID) (int64, error)
+}
+
+// SeriesCount returns the number of distinct series in the bucket.
+func (s *BucketCardinalityService) SeriesCount(ctx context.Context, orgID, bucketID platform.ID) (int64, error) {
+	return s.SeriesCountFn(ctx, orgID, bucketID)
+}
+
+var _ platform.BucketSizeService = (*BucketSizeService)(nil)
+
+// BucketSizeService is a mock implementation of platform.BucketSizeService.
+type BucketSizeService struct {
+	EstimatedCompressedSizeFn func(ctx context.Context, orgID, bucketID platform.ID) (int64, error)
+}
+
+// EstimatedCompressedSize returns an estimate of the disk space used by the bucket.
+func (s *BucketSizeService) EstimatedCompressedSize(ctx context.Context, orgID, bucketID platform.ID) (int64, error) {
+	return s.EstimatedCompressedSizeFn(ctx, orgID, bucketID)
+}
+
+var _ platform.BucketMetadataService = (*BucketMetadataService)(nil)
+
+// BucketMetadataService is a mock implementation of platform.BucketMetadataService.
+type BucketMetadataService struct {
+	WriteMetadataFn func(ctx context.Context, orgID, bucketID platform.ID, metadata map[string]string) error
+	ReadMetadataFn  func(ctx context.Context, orgID, bucketID platform.ID) (map[string]string, error)
+}
+
+// WriteMetadata replaces the metadata of the bucket.
+func (s *BucketMetadataService) WriteMetadata(ctx context.Context, orgID, bucketID platform.ID, metadata map[string]string) error {
+	return s.WriteMetadataFn(ctx, orgID, bucketID, metadata)
+}
+
+// ReadMetadata returns the metadata of the bucket.
+func (s *BucketMetadataService) ReadMetadata(ctx context.Context, orgID, bucketID platform.ID) (map[string]string, error) {
+	return s.ReadMetadataFn(ctx, orgID, bucketID)
+}
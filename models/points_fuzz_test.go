@@ -0,0 +1,142 @@
+//go:build go1.18
+// +build go1.18
+
+package models_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// FuzzWriteLineProtocol checks that ParsePoints does not panic on any input,
+// and that the line protocol of each parsed point parses back to the same
+// point. Run it with go test -fuzz=FuzzWriteLineProtocol.
+func FuzzWriteLineProtocol(f *testing.F) {
+	for _, seed := range []string{
+		// Valid line protocol.
+		"cpu value=1",
+		"cpu,host=serverA,region=us-west value=1.5 1000000000",
+		"cpu,host=serverA value=1i,count=2u,ok=true,msg=\"hello\" 1000000000",
+		"cpu,region=us-west,host=serverA value=-1e-10,f=F,t=T -1000000000",
+		`cpu\,main,tag\ key=tag\=value field\ key="a \"quoted\" string"`,
+		"cpu value=1\n\n# comment\nmem free=2i 1000\n",
+		"cpu value=1\r\nmem free=2i\r\n",
+		"cpu value=\"\" 0",
+		"\xe2\x98\x83,tag=\xf0\x9f\x90\x8d value=1",
+
+		// Invalid line protocol.
+		"",
+		"cpu",
+		"cpu value=",
+		"cpu,host= value=1",
+		"cpu,=a value=1",
+		"cpu,host=a,host=b value=1",
+		"cpu value=1,value=2",
+		"cpu value=1 1000 extra",
+		"cpu value=1 99999999999999999999",
+		"cpu value=9223372036854775808i",
+		"cpu value=-1u",
+		"cpu value=\"unterminated",
+		"cpu value=1e",
+		",host=a value=1",
+		"cpu,_field=a value=1",
+		"cpu _measurement=1",
+		"cpu\\ value=1",
+		"\x00\xff\x01 \x00=\x01",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	mm := []byte(models.EscapeMeasurement([]byte("mm")))
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		now := time.Unix(0, 0).UTC()
+		points, _ := models.ParsePointsWithPrecision(buf, mm, now, "n")
+		for _, p := range points {
+			if !escapable(p) {
+				continue
+			}
+
+			lp := userLineProtocol(t, p)
+			got, err := models.ParsePointsWithPrecision([]byte(lp), mm, now, "n")
+			if err != nil {
+				t.Fatalf("parse %q of point %q of %q failed: %v", lp, p.String(), buf, err)
+			} else if len(got) != 1 {
+				t.Fatalf("parse %q of point %q of %q returned %d points, exp 1", lp, p.String(), buf, len(got))
+			}
+
+			// The series key keeps the escaping of the tag values and field key
+			// as written, so compare their decoded values instead.
+			if !bytes.Equal(measurement(got[0]), measurement(p)) || !userTags(got[0]).Equal(userTags(p)) {
+				t.Fatalf("parse %q of %q key mismatch:\ngot %q\nexp %q", lp, buf, got[0].Key(), p.Key())
+			} else if !got[0].Time().Equal(p.Time()) {
+				t.Fatalf("parse %q of %q time mismatch:\ngot %v\nexp %v", lp, buf, got[0].Time(), p.Time())
+			}
+			gotFields, err := got[0].Fields()
+			if err != nil {
+				t.Fatalf("fields of point %q of %q: %v", got[0].String(), lp, err)
+			}
+			if expFields, _ := p.Fields(); !reflect.DeepEqual(gotFields, expFields) {
+				t.Fatalf("parse %q of %q fields mismatch:\ngot %#v\nexp %#v", lp, buf, gotFields, expFields)
+			}
+		}
+	})
+}
+
+// userLineProtocol returns the line protocol of a parsed point as it was
+// written, moving its measurement and field key out of the reserved tags.
+func userLineProtocol(t *testing.T, p models.Point) string {
+	t.Helper()
+
+	fields, err := p.Fields()
+	if err != nil {
+		t.Fatalf("fields of point %q: %v", p.String(), err)
+	}
+	up, err := models.NewPoint(string(measurement(p)), userTags(p), fields, p.Time())
+	if err != nil {
+		t.Fatalf("new point of %q: %v", p.String(), err)
+	}
+	return up.String()
+}
+
+// escapable reports whether the measurement, tags and field key of a parsed
+// point can be written as line protocol. Backslashes and control characters
+// cannot be escaped, so keys containing them do not round-trip, and a NUL
+// field key is stored as an empty one.
+func escapable(p models.Point) bool {
+	if fields, err := p.Fields(); err != nil || len(fields) == 0 {
+		return false
+	}
+
+	unescapable := func(r rune) bool { return r == '\\' || r < ' ' }
+	for _, tag := range p.Tags() {
+		if bytes.Equal(tag.Key, models.MeasurementTagKeyBytes) || bytes.Equal(tag.Key, models.FieldKeyTagKeyBytes) {
+			if bytes.IndexFunc(tag.Value, unescapable) >= 0 {
+				return false
+			}
+		} else if bytes.IndexFunc(tag.Key, unescapable) >= 0 || bytes.IndexFunc(tag.Value, unescapable) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// measurement returns the measurement of a parsed point.
+func measurement(p models.Point) []byte {
+	return p.Tags().Get(models.MeasurementTagKeyBytes)
+}
+
+// userTags returns the tags of a parsed point other than its measurement and
+// field key.
+func userTags(p models.Point) models.Tags {
+	var tags models.Tags
+	for _, tag := range p.Tags() {
+		if !bytes.Equal(tag.Key, models.MeasurementTagKeyBytes) && !bytes.Equal(tag.Key, models.FieldKeyTagKeyBytes) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,51 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// ErrOIDCIdentityNotFound is the error msg for a missing OpenID Connect identity.
+const ErrOIDCIdentityNotFound = "oidc identity not found"
+
+// OIDCIdentity links the subject of an OpenID Connect provider to the user it
+// logs in as. Identities are keyed by issuer and subject, and are never
+// matched to users by name.
+type OIDCIdentity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+	UserID  ID     `json:"userID"`
+}
+
+// OIDCSession records an authorization minted by an OpenID Connect login. The
+// authorization is no longer found by its token once the session expires, and
+// only authorizations with a session can be revoked by logging out.
+type OIDCSession struct {
+	AuthorizationID ID        `json:"authorizationID"`
+	UserID          ID        `json:"userID"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+// Expired returns whether the session has expired at now.
+func (s *OIDCSession) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// OIDCIdentityService represents a service for managing OpenID Connect identities.
+type OIDCIdentityService interface {
+	// FindOIDCIdentity returns the identity of the subject of the issuer.
+	FindOIDCIdentity(ctx context.Context, issuer, subject string) (*OIDCIdentity, error)
+
+	// CreateOIDCIdentity creates a new identity. It returns an EConflict error
+	// if the subject of the issuer already has an identity.
+	CreateOIDCIdentity(ctx context.Context, i *OIDCIdentity) error
+
+	// FindOIDCSession returns the session of the authorization with the
+	// provided id. It returns an ENotFound error if the authorization was not
+	// minted by an OpenID Connect login.
+	FindOIDCSession(ctx context.Context, authorizationID ID) (*OIDCSession, error)
+
+	// CreateOIDCSession records the session of an authorization. Expired
+	// sessions are removed along with their authorizations.
+	CreateOIDCSession(ctx context.Context, s *OIDCSession) error
+}
@@ -0,0 +1,615 @@
+// Package otlp converts metrics sent with the OpenTelemetry protocol (OTLP)
+// to line protocol.
+package otlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/pkg/protowire"
+)
+
+// ValueField is the field the values of gauge and sum data points are written
+// to.
+const ValueField = "_value"
+
+// MonotonicTag is the tag added to the data points of sum metrics recording
+// whether the sum is monotonic.
+const MonotonicTag = "monotonic"
+
+// Fields the data points of histogram metrics are written to. Each bucket is
+// written to a field named after its upper bound, such as bucket_le_0.5, which
+// holds the cumulative count of values less than or equal to the bound.
+const (
+	HistogramSumField          = "sum"
+	HistogramCountField        = "count"
+	HistogramBucketFieldPrefix = "bucket_le_"
+	HistogramInfBucketField    = HistogramBucketFieldPrefix + "inf"
+)
+
+// MetricType is the type of an OTLP metric.
+type MetricType int
+
+// Metric types supported by the receiver. Metrics of other types, such as
+// exponential histograms and summaries, are ignored.
+const (
+	MetricTypeUnknown MetricType = iota
+	MetricTypeGauge
+	MetricTypeSum
+	MetricTypeHistogram
+)
+
+// ExportMetricsRequest is an OTLP metrics export request. It holds the parts
+// of the ExportMetricsServiceRequest protobuf message needed to write its data
+// points; descriptions, units, exemplars and instrumentation scopes are
+// ignored.
+type ExportMetricsRequest struct {
+	ResourceMetrics []ResourceMetrics
+}
+
+// ResourceMetrics are the metrics reported by a resource, such as a service
+// or host, with the attributes of the resource.
+type ResourceMetrics struct {
+	Attributes []Attribute
+	Metrics    []Metric
+}
+
+// Attribute is a key and value pair describing a resource or data point. All
+// values are converted to strings.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Metric is a named series of data points. Gauges and sums hold
+// NumberDataPoints and histograms hold HistogramDataPoints.
+type Metric struct {
+	Name            string
+	Type            MetricType
+	Monotonic       bool
+	NumberPoints    []NumberDataPoint
+	HistogramPoints []HistogramDataPoint
+}
+
+// NumberDataPoint is a value of a gauge or sum. Integer values are converted
+// to floats. Time is in nanoseconds.
+type NumberDataPoint struct {
+	Attributes []Attribute
+	Time       int64
+	Value      float64
+}
+
+// HistogramDataPoint is the distribution of the values recorded by a
+// histogram. BucketCounts holds the count of values in each bucket, which has
+// an upper bound given by ExplicitBounds; the last bucket is unbounded.
+type HistogramDataPoint struct {
+	Attributes     []Attribute
+	Time           int64
+	Count          uint64
+	Sum            float64
+	BucketCounts   []uint64
+	ExplicitBounds []float64
+}
+
+// Protobuf field numbers of the OTLP metrics messages.
+const (
+	requestResourceMetricsField = 1
+
+	resourceMetricsResourceField     = 1
+	resourceMetricsScopeMetricsField = 2
+	resourceAttributesField          = 1
+	scopeMetricsMetricsField         = 2
+
+	metricNameField      = 1
+	metricGaugeField     = 5
+	metricSumField       = 7
+	metricHistogramField = 9
+
+	dataPointsField   = 1
+	sumMonotonicField = 3
+
+	numberPointTimeField       = 3
+	numberPointDoubleField     = 4
+	numberPointIntField        = 6
+	numberPointAttributesField = 7
+
+	histogramPointTimeField           = 3
+	histogramPointCountField          = 4
+	histogramPointSumField            = 5
+	histogramPointBucketCountsField   = 6
+	histogramPointExplicitBoundsField = 7
+	histogramPointAttributesField     = 9
+
+	keyValueKeyField   = 1
+	keyValueValueField = 2
+
+	anyValueStringField = 1
+	anyValueBoolField   = 2
+	anyValueIntField    = 3
+	anyValueDoubleField = 4
+)
+
+// fieldFunc decodes a field of a message. It returns false if the field was
+// not read, in which case it is skipped.
+type fieldFunc func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error)
+
+// decodeMessage calls fn for each field of the message in data.
+func decodeMessage(data []byte, fn fieldFunc) error {
+	d := protowire.NewDecoder(data)
+	for !d.Done() {
+		field, wire, err := d.Key()
+		if err != nil {
+			return err
+		}
+		if ok, err := fn(d, field, wire); err != nil {
+			return err
+		} else if !ok {
+			if err := d.Skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// embedded reads an embedded message field and decodes it with fn.
+func embedded(d *protowire.Decoder, fn func([]byte) error) (bool, error) {
+	b, err := d.Bytes()
+	if err != nil {
+		return true, err
+	}
+	return true, fn(b)
+}
+
+// Unmarshal decodes the protobuf encoded export request in data.
+func (r *ExportMetricsRequest) Unmarshal(data []byte) error {
+	r.ResourceMetrics = r.ResourceMetrics[:0]
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		if field != requestResourceMetricsField || wire != protowire.BytesType {
+			return false, nil
+		}
+		return embedded(d, func(b []byte) error {
+			var rm ResourceMetrics
+			if err := rm.unmarshal(b); err != nil {
+				return err
+			}
+			r.ResourceMetrics = append(r.ResourceMetrics, rm)
+			return nil
+		})
+	})
+}
+
+func (rm *ResourceMetrics) unmarshal(data []byte) error {
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		if wire != protowire.BytesType {
+			return false, nil
+		}
+		switch field {
+		case resourceMetricsResourceField:
+			return embedded(d, func(b []byte) error {
+				return decodeMessage(b, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+					if field != resourceAttributesField || wire != protowire.BytesType {
+						return false, nil
+					}
+					return embedded(d, func(b []byte) error {
+						return appendAttribute(&rm.Attributes, b)
+					})
+				})
+			})
+		case resourceMetricsScopeMetricsField:
+			return embedded(d, func(b []byte) error {
+				return decodeMessage(b, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+					if field != scopeMetricsMetricsField || wire != protowire.BytesType {
+						return false, nil
+					}
+					return embedded(d, func(b []byte) error {
+						var m Metric
+						if err := m.unmarshal(b); err != nil {
+							return err
+						}
+						rm.Metrics = append(rm.Metrics, m)
+						return nil
+					})
+				})
+			})
+		}
+		return false, nil
+	})
+}
+
+func (m *Metric) unmarshal(data []byte) error {
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		if wire != protowire.BytesType {
+			return false, nil
+		}
+		switch field {
+		case metricNameField:
+			b, err := d.Bytes()
+			m.Name = string(b)
+			return true, err
+		case metricGaugeField:
+			m.Type = MetricTypeGauge
+			return embedded(d, m.unmarshalData)
+		case metricSumField:
+			m.Type = MetricTypeSum
+			return embedded(d, m.unmarshalData)
+		case metricHistogramField:
+			m.Type = MetricTypeHistogram
+			return embedded(d, m.unmarshalData)
+		}
+		return false, nil
+	})
+}
+
+// unmarshalData decodes the Gauge, Sum or Histogram message of a metric.
+func (m *Metric) unmarshalData(data []byte) error {
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		switch {
+		case field == dataPointsField && wire == protowire.BytesType:
+			if m.Type == MetricTypeHistogram {
+				return embedded(d, func(b []byte) error {
+					var p HistogramDataPoint
+					if err := p.unmarshal(b); err != nil {
+						return err
+					}
+					m.HistogramPoints = append(m.HistogramPoints, p)
+					return nil
+				})
+			}
+			return embedded(d, func(b []byte) error {
+				var p NumberDataPoint
+				if err := p.unmarshal(b); err != nil {
+					return err
+				}
+				m.NumberPoints = append(m.NumberPoints, p)
+				return nil
+			})
+		case field == sumMonotonicField && wire == protowire.VarintType && m.Type == MetricTypeSum:
+			v, err := d.Varint()
+			m.Monotonic = v != 0
+			return true, err
+		}
+		return false, nil
+	})
+}
+
+func (p *NumberDataPoint) unmarshal(data []byte) error {
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		switch {
+		case field == numberPointAttributesField && wire == protowire.BytesType:
+			return embedded(d, func(b []byte) error {
+				return appendAttribute(&p.Attributes, b)
+			})
+		case field == numberPointTimeField && wire == protowire.Fixed64Type:
+			v, err := d.Fixed64()
+			p.Time = int64(v)
+			return true, err
+		case field == numberPointDoubleField && wire == protowire.Fixed64Type:
+			v, err := d.Double()
+			p.Value = v
+			return true, err
+		case field == numberPointIntField && wire == protowire.Fixed64Type:
+			v, err := d.Fixed64()
+			p.Value = float64(int64(v))
+			return true, err
+		}
+		return false, nil
+	})
+}
+
+func (p *HistogramDataPoint) unmarshal(data []byte) error {
+	return decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		switch {
+		case field == histogramPointAttributesField && wire == protowire.BytesType:
+			return embedded(d, func(b []byte) error {
+				return appendAttribute(&p.Attributes, b)
+			})
+		case field == histogramPointTimeField && wire == protowire.Fixed64Type:
+			v, err := d.Fixed64()
+			p.Time = int64(v)
+			return true, err
+		case field == histogramPointCountField && wire == protowire.Fixed64Type:
+			v, err := d.Fixed64()
+			p.Count = v
+			return true, err
+		case field == histogramPointSumField && wire == protowire.Fixed64Type:
+			v, err := d.Double()
+			p.Sum = v
+			return true, err
+		case field == histogramPointBucketCountsField:
+			return true, readFixed64s(d, wire, func(v uint64) {
+				p.BucketCounts = append(p.BucketCounts, v)
+			})
+		case field == histogramPointExplicitBoundsField:
+			return true, readFixed64s(d, wire, func(v uint64) {
+				p.ExplicitBounds = append(p.ExplicitBounds, math.Float64frombits(v))
+			})
+		}
+		return false, nil
+	})
+}
+
+// readFixed64s reads the values of a repeated 64-bit field, which may be
+// packed or sent as one field per value.
+func readFixed64s(d *protowire.Decoder, wire protowire.Type, fn func(uint64)) error {
+	switch wire {
+	case protowire.Fixed64Type:
+		v, err := d.Fixed64()
+		if err != nil {
+			return err
+		}
+		fn(v)
+		return nil
+	case protowire.BytesType:
+		b, err := d.Bytes()
+		if err != nil {
+			return err
+		}
+		packed := protowire.NewDecoder(b)
+		for !packed.Done() {
+			v, err := packed.Fixed64()
+			if err != nil {
+				return err
+			}
+			fn(v)
+		}
+		return nil
+	}
+	return d.Skip(wire)
+}
+
+// appendAttribute decodes the KeyValue message in data and appends it to
+// attrs. Attributes with array, key-value list or bytes values are ignored.
+func appendAttribute(attrs *[]Attribute, data []byte) error {
+	var (
+		a  Attribute
+		ok bool
+	)
+	err := decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		if wire != protowire.BytesType {
+			return false, nil
+		}
+		switch field {
+		case keyValueKeyField:
+			b, err := d.Bytes()
+			a.Key = string(b)
+			return true, err
+		case keyValueValueField:
+			return embedded(d, func(b []byte) error {
+				var err error
+				a.Value, ok, err = decodeAnyValue(b)
+				return err
+			})
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if ok {
+		*attrs = append(*attrs, a)
+	}
+	return nil
+}
+
+// decodeAnyValue returns the AnyValue message in data as a string. It returns
+// false if the value is not a scalar.
+func decodeAnyValue(data []byte) (value string, ok bool, err error) {
+	err = decodeMessage(data, func(d *protowire.Decoder, field int, wire protowire.Type) (bool, error) {
+		switch {
+		case field == anyValueStringField && wire == protowire.BytesType:
+			b, err := d.Bytes()
+			value, ok = string(b), true
+			return true, err
+		case field == anyValueBoolField && wire == protowire.VarintType:
+			v, err := d.Varint()
+			value, ok = strconv.FormatBool(v != 0), true
+			return true, err
+		case field == anyValueIntField && wire == protowire.VarintType:
+			v, err := d.Varint()
+			value, ok = strconv.FormatInt(int64(v), 10), true
+			return true, err
+		case field == anyValueDoubleField && wire == protowire.Fixed64Type:
+			v, err := d.Double()
+			value, ok = strconv.FormatFloat(v, 'f', -1, 64), true
+			return true, err
+		}
+		return false, nil
+	})
+	return value, ok, err
+}
+
+// Marshal returns the protobuf encoding of the export request.
+func (r *ExportMetricsRequest) Marshal() []byte {
+	var e protowire.Encoder
+	for _, rm := range r.ResourceMetrics {
+		var resource, scope, rme protowire.Encoder
+		for _, a := range rm.Attributes {
+			resource.BytesField(resourceAttributesField, marshalAttribute(a))
+		}
+		for _, m := range rm.Metrics {
+			scope.BytesField(scopeMetricsMetricsField, m.marshal())
+		}
+		rme.BytesField(resourceMetricsResourceField, resource.Bytes())
+		rme.BytesField(resourceMetricsScopeMetricsField, scope.Bytes())
+		e.BytesField(requestResourceMetricsField, rme.Bytes())
+	}
+	return e.Bytes()
+}
+
+func (m *Metric) marshal() []byte {
+	var data protowire.Encoder
+	for _, p := range m.NumberPoints {
+		var pe protowire.Encoder
+		pe.Fixed64(numberPointTimeField, uint64(p.Time))
+		pe.Double(numberPointDoubleField, p.Value)
+		for _, a := range p.Attributes {
+			pe.BytesField(numberPointAttributesField, marshalAttribute(a))
+		}
+		data.BytesField(dataPointsField, pe.Bytes())
+	}
+	for _, p := range m.HistogramPoints {
+		var pe protowire.Encoder
+		pe.Fixed64(histogramPointTimeField, uint64(p.Time))
+		pe.Fixed64(histogramPointCountField, p.Count)
+		pe.Double(histogramPointSumField, p.Sum)
+		bounds := make([]uint64, len(p.ExplicitBounds))
+		for i, b := range p.ExplicitBounds {
+			bounds[i] = math.Float64bits(b)
+		}
+		pe.BytesField(histogramPointBucketCountsField, packFixed64s(p.BucketCounts))
+		pe.BytesField(histogramPointExplicitBoundsField, packFixed64s(bounds))
+		for _, a := range p.Attributes {
+			pe.BytesField(histogramPointAttributesField, marshalAttribute(a))
+		}
+		data.BytesField(dataPointsField, pe.Bytes())
+	}
+	if m.Type == MetricTypeSum && m.Monotonic {
+		data.Varint(sumMonotonicField, 1)
+	}
+
+	var e protowire.Encoder
+	e.BytesField(metricNameField, []byte(m.Name))
+	switch m.Type {
+	case MetricTypeGauge:
+		e.BytesField(metricGaugeField, data.Bytes())
+	case MetricTypeSum:
+		e.BytesField(metricSumField, data.Bytes())
+	case MetricTypeHistogram:
+		e.BytesField(metricHistogramField, data.Bytes())
+	}
+	return e.Bytes()
+}
+
+// packFixed64s returns the packed encoding of the values of a repeated 64-bit
+// field.
+func packFixed64s(vs []uint64) []byte {
+	b := make([]byte, 8*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint64(b[8*i:], v)
+	}
+	return b
+}
+
+func marshalAttribute(a Attribute) []byte {
+	var value, kv protowire.Encoder
+	value.BytesField(anyValueStringField, []byte(a.Value))
+	kv.BytesField(keyValueKeyField, []byte(a.Key))
+	kv.BytesField(keyValueValueField, value.Bytes())
+	return kv.Bytes()
+}
+
+// LineProtocol appends the data points of the request to buf as line
+// protocol. The name of each metric is its measurement, and the attributes of
+// its resource and data points are its tags; data point attributes override
+// resource attributes with the same key.
+//
+// Gauge and sum values are written to the _value field, with sums tagged with
+// whether they are monotonic. Histograms are written to the sum, count and
+// cumulative bucket_le_* fields. Data points with a NaN or infinite value are
+// dropped, as line protocol cannot represent them. An error is returned for a
+// metric without a name.
+func (r *ExportMetricsRequest) LineProtocol(buf []byte) ([]byte, error) {
+	var tags []Attribute
+	for _, rm := range r.ResourceMetrics {
+		for _, m := range rm.Metrics {
+			if m.Name == "" {
+				return nil, errors.New("metric without a name")
+			}
+
+			switch m.Type {
+			case MetricTypeGauge, MetricTypeSum:
+				for _, p := range m.NumberPoints {
+					if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+						continue
+					}
+					tags = mergeAttributes(tags[:0], rm.Attributes, p.Attributes)
+					if m.Type == MetricTypeSum {
+						tags = mergeAttributes(tags, []Attribute{{Key: MonotonicTag, Value: strconv.FormatBool(m.Monotonic)}})
+					}
+					buf = appendSeriesKey(buf, m.Name, tags)
+					buf = appendField(buf, ValueField, p.Value)
+					buf = appendTime(buf, p.Time)
+				}
+			case MetricTypeHistogram:
+				for _, p := range m.HistogramPoints {
+					if math.IsNaN(p.Sum) || math.IsInf(p.Sum, 0) {
+						continue
+					}
+					tags = mergeAttributes(tags[:0], rm.Attributes, p.Attributes)
+					buf = appendSeriesKey(buf, m.Name, tags)
+					buf = appendField(buf, HistogramSumField, p.Sum)
+					buf = append(buf, ',')
+					buf = appendField(buf, HistogramCountField, float64(p.Count))
+					var cumulative uint64
+					for i, c := range p.BucketCounts {
+						cumulative += c
+						buf = append(buf, ',')
+						if i < len(p.ExplicitBounds) {
+							name := HistogramBucketFieldPrefix + strconv.FormatFloat(p.ExplicitBounds[i], 'f', -1, 64)
+							buf = appendField(buf, name, float64(cumulative))
+						} else {
+							buf = appendField(buf, HistogramInfBucketField, float64(cumulative))
+							break
+						}
+					}
+					buf = appendTime(buf, p.Time)
+				}
+			}
+		}
+	}
+	return buf, nil
+}
+
+// mergeAttributes appends the non-empty attributes of each set to dst, sorted
+// by key. Attributes of later sets override those of earlier sets with the
+// same key.
+func mergeAttributes(dst []Attribute, sets ...[]Attribute) []Attribute {
+	for _, set := range sets {
+		dst = append(dst, set...)
+	}
+	sort.SliceStable(dst, func(i, j int) bool { return dst[i].Key < dst[j].Key })
+
+	merged := dst[:0]
+	for i, a := range dst {
+		if i+1 < len(dst) && dst[i+1].Key == a.Key {
+			continue
+		}
+		if a.Key != "" && a.Value != "" {
+			merged = append(merged, a)
+		}
+	}
+	return merged
+}
+
+func appendSeriesKey(buf []byte, name string, tags []Attribute) []byte {
+	buf = append(buf, models.EscapeMeasurement([]byte(name))...)
+	for _, t := range tags {
+		buf = append(buf, ',')
+		buf = append(buf, escape.String(t.Key)...)
+		buf = append(buf, '=')
+		buf = append(buf, escape.String(t.Value)...)
+	}
+	return append(buf, ' ')
+}
+
+func appendField(buf []byte, name string, v float64) []byte {
+	buf = append(buf, escape.String(name)...)
+	buf = append(buf, '=')
+	return strconv.AppendFloat(buf, v, 'f', -1, 64)
+}
+
+// appendTime ends the line with the time of a data point. Data points without
+// a time are written at the time they are received.
+func appendTime(buf []byte, t int64) []byte {
+	if t != 0 {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, t, 10)
+	}
+	return append(buf, '\n')
+}
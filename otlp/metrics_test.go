@@ -0,0 +1,139 @@
+package otlp_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb/otlp"
+)
+
+func TestExportMetricsRequest_Unmarshal(t *testing.T) {
+	// ExportMetricsServiceRequest{resource_metrics: [{scope_metrics: [{metrics: [
+	//   {name: up, gauge: {data_points: [{time_unix_nano: 1000, as_int: 1, attributes: [{host: {string_value: a}}]}]}}
+	// ]}]}]}
+	data := []byte{
+		0x0a, 0x2d, // resource_metrics
+		0x12, 0x2b, // scope_metrics
+		0x12, 0x29, // metrics
+		0x0a, 0x02, 'u', 'p',
+		0x2a, 0x23, // gauge
+		0x0a, 0x21, // data_points
+		0x19, 0xe8, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // time_unix_nano
+		0x31, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // as_int
+		0x3a, 0x0d, // attributes
+		0x0a, 0x04, 'h', 'o', 's', 't',
+		0x12, 0x05, 0x0a, 0x03, 'a', 'b', 'c',
+	}
+
+	var req otlp.ExportMetricsRequest
+	if err := req.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := otlp.ExportMetricsRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Metrics: []otlp.Metric{{
+			Name: "up",
+			Type: otlp.MetricTypeGauge,
+			NumberPoints: []otlp.NumberDataPoint{{
+				Attributes: []otlp.Attribute{{Key: "host", Value: "abc"}},
+				Time:       1000,
+				Value:      1,
+			}},
+		}},
+	}}}
+	if !reflect.DeepEqual(req, exp) {
+		t.Fatalf("got %+v, exp %+v", req, exp)
+	}
+
+	if err := req.Unmarshal(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error decoding truncated request")
+	}
+}
+
+func TestExportMetricsRequest_Marshal(t *testing.T) {
+	exp := otlp.ExportMetricsRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Attributes: []otlp.Attribute{{Key: "service.name", Value: "api"}},
+		Metrics: []otlp.Metric{
+			{
+				Name:         "requests",
+				Type:         otlp.MetricTypeSum,
+				Monotonic:    true,
+				NumberPoints: []otlp.NumberDataPoint{{Time: 2000, Value: 10}},
+			},
+			{
+				Name: "latency",
+				Type: otlp.MetricTypeHistogram,
+				HistogramPoints: []otlp.HistogramDataPoint{{
+					Time:           3000,
+					Count:          6,
+					Sum:            2.5,
+					BucketCounts:   []uint64{1, 2, 3},
+					ExplicitBounds: []float64{0.1, 0.5},
+				}},
+			},
+		},
+	}}}
+
+	var got otlp.ExportMetricsRequest
+	if err := got.Unmarshal(exp.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %+v, exp %+v", got, exp)
+	}
+}
+
+func TestExportMetricsRequest_LineProtocol(t *testing.T) {
+	req := otlp.ExportMetricsRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Attributes: []otlp.Attribute{
+			{Key: "service.name", Value: "api"},
+			{Key: "host", Value: "a"},
+		},
+		Metrics: []otlp.Metric{
+			{
+				Name: "cpu usage",
+				Type: otlp.MetricTypeGauge,
+				NumberPoints: []otlp.NumberDataPoint{
+					{Attributes: []otlp.Attribute{{Key: "host", Value: "b"}}, Time: 1000, Value: 0.5},
+					{Time: 2000, Value: math.NaN()},
+				},
+			},
+			{
+				Name:         "requests",
+				Type:         otlp.MetricTypeSum,
+				Monotonic:    true,
+				NumberPoints: []otlp.NumberDataPoint{{Time: 1000, Value: 10}},
+			},
+			{
+				Name: "latency",
+				Type: otlp.MetricTypeHistogram,
+				HistogramPoints: []otlp.HistogramDataPoint{{
+					Time:           1000,
+					Count:          6,
+					Sum:            2.5,
+					BucketCounts:   []uint64{1, 2, 3},
+					ExplicitBounds: []float64{0.1, 0.5},
+				}},
+			},
+		},
+	}}}
+
+	got, err := req.LineProtocol(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `cpu\ usage,host=b,service.name=api _value=0.5 1000
+requests,host=a,monotonic=true,service.name=api _value=10 1000
+latency,host=a,service.name=api sum=2.5,count=6,bucket_le_0.1=1,bucket_le_0.5=3,bucket_le_inf=6 1000
+`
+	if string(got) != exp {
+		t.Fatalf("got\n%s\nexp\n%s", got, exp)
+	}
+
+	req.ResourceMetrics[0].Metrics[0].Name = ""
+	if _, err := req.LineProtocol(nil); err == nil {
+		t.Fatal("expected error for metric without a name")
+	}
+}
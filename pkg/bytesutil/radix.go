@@ -0,0 +1,104 @@
+package bytesutil
+
+import "bytes"
+
+// radixInsertionThreshold is the size below which RadixSort uses insertion
+// sort.
+const radixInsertionThreshold = 12
+
+// RadixSort sorts a slice of byte slices in the same order as Sort.
+//
+// It is a three-way radix quicksort, which partitions the slices on one byte
+// at a time rather than comparing them from the start. It is faster than Sort
+// for keys sharing long prefixes, such as the series keys of a measurement.
+func RadixSort(a [][]byte) {
+	radixSort(a, 0)
+}
+
+// radixSort sorts a, all of whose elements share their first depth bytes.
+func radixSort(a [][]byte, depth int) {
+	for len(a) > radixInsertionThreshold {
+		p := medianOfThree(byteAt(a[0], depth), byteAt(a[len(a)/2], depth), byteAt(a[len(a)-1], depth))
+
+		// Partition a into the elements whose byte at depth is less than,
+		// equal to and greater than the pivot.
+		lt, gt := 0, len(a)
+		for i := 0; i < gt; {
+			switch c := byteAt(a[i], depth); {
+			case c < p:
+				a[lt], a[i] = a[i], a[lt]
+				lt++
+				i++
+			case c > p:
+				gt--
+				a[gt], a[i] = a[i], a[gt]
+			default:
+				i++
+			}
+		}
+
+		radixSort(a[:lt], depth)
+		radixSort(a[gt:], depth)
+
+		// Elements that end at depth are all equal.
+		if p < 0 {
+			return
+		}
+		// Skip the bytes shared by all elements, such as the measurement of
+		// series keys, rather than partitioning on each of them.
+		if lt == 0 && gt == len(a) {
+			depth = commonPrefix(a, depth+1)
+		} else {
+			a, depth = a[lt:gt], depth+1
+		}
+	}
+	insertionSort(a, depth)
+}
+
+// commonPrefix returns the length of the prefix shared by all elements of a,
+// which are known to share their first depth bytes.
+func commonPrefix(a [][]byte, depth int) int {
+	prefix := a[0]
+	for _, b := range a[1:] {
+		if bytes.HasPrefix(b, prefix) {
+			continue
+		}
+		n := depth
+		for n < len(prefix) && n < len(b) && prefix[n] == b[n] {
+			n++
+		}
+		if prefix = prefix[:n]; n == depth {
+			break
+		}
+	}
+	return len(prefix)
+}
+
+func insertionSort(a [][]byte, depth int) {
+	for i := 1; i < len(a); i++ {
+		for j := i; j > 0 && bytes.Compare(a[j][depth:], a[j-1][depth:]) < 0; j-- {
+			a[j], a[j-1] = a[j-1], a[j]
+		}
+	}
+}
+
+// byteAt returns the byte of b at i, or -1 if b is shorter than i+1 bytes.
+func byteAt(b []byte, i int) int {
+	if i < len(b) {
+		return int(b[i])
+	}
+	return -1
+}
+
+func medianOfThree(a, b, c int) int {
+	if a > b {
+		a, b = b, a
+	}
+	if b > c {
+		b = c
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,82 @@
+package bytesutil_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+)
+
+func TestRadixSort(t *testing.T) {
+	in := toByteSlices("cpu,host=b", "cpu", "", "cpu,host=a", "cpu,host=a", "mem", "cpu,host=a,region=west", "cp")
+	bytesutil.RadixSort(in)
+
+	exp := toByteSlices("", "cp", "cpu", "cpu,host=a", "cpu,host=a", "cpu,host=a,region=west", "cpu,host=b", "mem")
+	if !cmp.Equal(in, exp) {
+		t.Fatalf("unexpected order -got/+exp\n%s", cmp.Diff(in, exp))
+	}
+}
+
+// randomKeys is a set of keys drawn from a small alphabet, so that many of
+// them share prefixes or are equal.
+type randomKeys [][]byte
+
+func (randomKeys) Generate(rand *rand.Rand, size int) reflect.Value {
+	keys := make(randomKeys, rand.Intn(size*10+1))
+	for i := range keys {
+		key := make([]byte, rand.Intn(size+1))
+		for j := range key {
+			key[j] = "ab,=\x00\xff"[rand.Intn(6)]
+		}
+		keys[i] = key
+	}
+	return reflect.ValueOf(keys)
+}
+
+func TestRadixSort_Quick(t *testing.T) {
+	if err := quick.Check(func(keys randomKeys) bool {
+		got := append([][]byte(nil), keys...)
+		bytesutil.RadixSort(got)
+
+		exp := append([][]byte(nil), keys...)
+		bytesutil.Sort(exp)
+		return reflect.DeepEqual(got, exp)
+	}, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seriesKeys returns n series keys of about 100 bytes in random order.
+func seriesKeys(n int) [][]byte {
+	rnd := rand.New(rand.NewSource(0))
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x02,\x00=cpu,host=server-%06d,region=us-west-%d,service=api-%04d,\xff=usage_user",
+			rnd.Intn(n), rnd.Intn(4), rnd.Intn(1000)))
+	}
+	return keys
+}
+
+func BenchmarkRadixSort(b *testing.B) {
+	for _, n := range []int{1000, 1000000} {
+		data := seriesKeys(n)
+		keys := make([][]byte, n)
+
+		b.Run(fmt.Sprintf("sort/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(keys, data)
+				bytesutil.Sort(keys)
+			}
+		})
+		b.Run(fmt.Sprintf("radix/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(keys, data)
+				bytesutil.RadixSort(keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+// Package parquet implements a minimal writer and reader of Apache Parquet
+// files.
+//
+// Files hold a single row group of uncompressed, optional (nullable) columns.
+// Values are PLAIN encoded, except for dictionary columns whose values are
+// stored once in a dictionary page and referenced by index.
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Magic is written at the start and end of every Parquet file.
+const Magic = "PAR1"
+
+// CreatedBy identifies the writer of the files.
+const CreatedBy = "influxdb"
+
+// Type is the type of the values of a column.
+type Type int
+
+// Column types.
+const (
+	Boolean Type = iota
+	Int64
+	Uint64
+	Double
+	String
+	// Timestamp columns hold UTC times with microsecond precision.
+	Timestamp
+)
+
+func (t Type) String() string {
+	switch t {
+	case Boolean:
+		return "boolean"
+	case Int64:
+		return "int64"
+	case Uint64:
+		return "uint64"
+	case Double:
+		return "double"
+	case String:
+		return "string"
+	case Timestamp:
+		return "timestamp"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Column describes a column of a file.
+type Column struct {
+	Name string
+	Type Type
+
+	// Dictionary encodes the values of a String column with a dictionary. It
+	// reduces the size of columns with few distinct values, such as tags.
+	Dictionary bool
+}
+
+// Physical types, converted types, encodings and page types defined by the
+// Parquet format.
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+	convertedUint64          = 14
+
+	repetitionOptional = 1
+
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingRLEDictionary   = 8
+
+	codecUncompressed = 0
+
+	pageData       = 0
+	pageDictionary = 2
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Boolean:
+		return physicalBoolean
+	case Double:
+		return physicalDouble
+	case String:
+		return physicalByteArray
+	}
+	return physicalInt64
+}
+
+// Writer buffers rows and writes them as a Parquet file.
+type Writer struct {
+	columns []*columnBuffer
+	rows    int
+}
+
+// columnBuffer holds the values of a column. Null values are only recorded
+// in defined.
+type columnBuffer struct {
+	Column
+	defined []bool
+	bools   []bool
+	ints    []int64
+	floats  []float64
+	strings []string
+}
+
+// NewWriter returns a Writer of files with the given columns.
+func NewWriter(columns []Column) *Writer {
+	w := &Writer{columns: make([]*columnBuffer, len(columns))}
+	for i, c := range columns {
+		w.columns[i] = &columnBuffer{Column: c}
+	}
+	return w
+}
+
+// Rows returns the number of rows written.
+func (w *Writer) Rows() int { return w.rows }
+
+// WriteRow buffers a row. It has one value per column, which is either nil
+// or of the type of the column: bool, int64, uint64, float64, string or
+// time.Time.
+func (w *Writer) WriteRow(values []interface{}) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("parquet: got %d values for %d columns", len(values), len(w.columns))
+	}
+	for i, v := range values {
+		if err := w.columns[i].check(v); err != nil {
+			return err
+		}
+	}
+	for i, v := range values {
+		w.columns[i].append(v)
+	}
+	w.rows++
+	return nil
+}
+
+func (c *columnBuffer) check(v interface{}) error {
+	var ok bool
+	switch v.(type) {
+	case nil:
+		ok = true
+	case bool:
+		ok = c.Type == Boolean
+	case int64:
+		ok = c.Type == Int64
+	case uint64:
+		ok = c.Type == Uint64
+	case float64:
+		ok = c.Type == Double
+	case string:
+		ok = c.Type == String
+	case time.Time:
+		ok = c.Type == Timestamp
+	}
+	if !ok {
+		return fmt.Errorf("parquet: invalid value %v for %s column %q", v, c.Type, c.Name)
+	}
+	return nil
+}
+
+func (c *columnBuffer) append(v interface{}) {
+	c.defined = append(c.defined, v != nil)
+	switch v := v.(type) {
+	case bool:
+		c.bools = append(c.bools, v)
+	case int64:
+		c.ints = append(c.ints, v)
+	case uint64:
+		c.ints = append(c.ints, int64(v))
+	case float64:
+		c.floats = append(c.floats, v)
+	case string:
+		c.strings = append(c.strings, v)
+	case time.Time:
+		c.ints = append(c.ints, v.UnixNano()/int64(time.Microsecond))
+	}
+}
+
+// chunkMeta is the location of a column chunk within the file.
+type chunkMeta struct {
+	dictionaryOffset int64
+	dataOffset       int64
+	size             int64
+	encodings        []int32
+}
+
+// WriteTo writes the buffered rows to w as a Parquet file.
+func (w *Writer) WriteTo(dst io.Writer) (int64, error) {
+	buf := []byte(Magic)
+	chunks := make([]chunkMeta, len(w.columns))
+	for i, c := range w.columns {
+		start := int64(len(buf))
+		meta := chunkMeta{dataOffset: start, encodings: []int32{encodingRLE}}
+
+		if c.Type == String && c.Dictionary {
+			dict, indices := c.dictionary()
+			meta.dictionaryOffset = start
+			buf = appendPage(buf, pageDictionary, len(dict), encodingPlainDictionary, plainStrings(nil, dict))
+			meta.dataOffset = int64(len(buf))
+			meta.encodings = append(meta.encodings, encodingPlainDictionary)
+			data := appendIndices(c.definitionLevels(), indices, len(dict))
+			buf = appendPage(buf, pageData, len(c.defined), encodingPlainDictionary, data)
+		} else {
+			meta.encodings = append(meta.encodings, encodingPlain)
+			buf = appendPage(buf, pageData, len(c.defined), encodingPlain, c.plain(c.definitionLevels()))
+		}
+		meta.size = int64(len(buf)) - start
+		chunks[i] = meta
+	}
+
+	footer := w.fileMetadata(chunks)
+	buf = append(buf, footer...)
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(footer)))
+	buf = append(buf, n[:]...)
+	buf = append(buf, Magic...)
+
+	written, err := dst.Write(buf)
+	return int64(written), err
+}
+
+// definitionLevels returns the RLE encoded definition levels of the column,
+// prefixed by their length.
+func (c *columnBuffer) definitionLevels() []byte {
+	levels := make([]int, len(c.defined))
+	for i, d := range c.defined {
+		if d {
+			levels[i] = 1
+		}
+	}
+	enc := appendRLE(nil, levels, 1)
+	buf := make([]byte, 4, 4+len(enc))
+	binary.LittleEndian.PutUint32(buf, uint32(len(enc)))
+	return append(buf, enc...)
+}
+
+// plain appends the PLAIN encoded non-null values of the column to buf.
+func (c *columnBuffer) plain(buf []byte) []byte {
+	switch c.Type {
+	case Boolean:
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		return append(buf, packed...)
+	case Double:
+		var b [8]byte
+		for _, v := range c.floats {
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			buf = append(buf, b[:]...)
+		}
+		return buf
+	case String:
+		return plainStrings(buf, c.strings)
+	default:
+		var b [8]byte
+		for _, v := range c.ints {
+			binary.LittleEndian.PutUint64(b[:], uint64(v))
+			buf = append(buf, b[:]...)
+		}
+		return buf
+	}
+}
+
+// dictionary returns the distinct values of a string column in order of
+// first appearance and the index of each value in the dictionary.
+func (c *columnBuffer) dictionary() ([]string, []int) {
+	var dict []string
+	index := make(map[string]int)
+	indices := make([]int, len(c.strings))
+	for i, v := range c.strings {
+		j, ok := index[v]
+		if !ok {
+			j = len(dict)
+			index[v] = j
+			dict = append(dict, v)
+		}
+		indices[i] = j
+	}
+	return dict, indices
+}
+
+func plainStrings(buf []byte, values []string) []byte {
+	var n [4]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(n[:], uint32(len(v)))
+		buf = append(buf, n[:]...)
+		buf = append(buf, v...)
+	}
+	return buf
+}
+
+// appendIndices appends the dictionary indices, prefixed by their bit width,
+// to buf.
+func appendIndices(buf []byte, indices []int, dictSize int) []byte {
+	width := bitWidth(dictSize - 1)
+	buf = append(buf, byte(width))
+	return appendRLE(buf, indices, width)
+}
+
+func bitWidth(max int) int {
+	w := 0
+	for ; max > 0; max >>= 1 {
+		w++
+	}
+	return w
+}
+
+// appendRLE appends values to buf with the RLE/bit-packing hybrid encoding,
+// using only RLE runs.
+func appendRLE(buf []byte, values []int, width int) []byte {
+	size := (width + 7) / 8
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		buf = appendUvarint(buf, uint64(j-i)<<1)
+		for b := 0; b < size; b++ {
+			buf = append(buf, byte(values[i]>>(8*uint(b))))
+		}
+		i = j
+	}
+	return buf
+}
+
+// appendPage appends a page with the given header fields and data to buf.
+func appendPage(buf []byte, typ int32, numValues int, encoding int32, data []byte) []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32Field(1, typ)
+	t.i32Field(2, int32(len(data)))
+	t.i32Field(3, int32(len(data)))
+	if typ == pageDictionary {
+		t.structField(7)
+		t.i32Field(1, int32(numValues))
+		t.i32Field(2, encoding)
+		t.endStruct()
+	} else {
+		t.structField(5)
+		t.i32Field(1, int32(numValues))
+		t.i32Field(2, encoding)
+		t.i32Field(3, encodingRLE)
+		t.i32Field(4, encodingRLE)
+		t.endStruct()
+	}
+	t.endStruct()
+	buf = append(buf, t.buf...)
+	return append(buf, data...)
+}
+
+// fileMetadata returns the encoded FileMetaData of the file.
+func (w *Writer) fileMetadata(chunks []chunkMeta) []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32Field(1, 1)
+
+	t.listField(2, thriftStruct, len(w.columns)+1)
+	t.beginStruct()
+	t.stringField(4, "schema")
+	t.i32Field(5, int32(len(w.columns)))
+	t.endStruct()
+	for _, c := range w.columns {
+		t.beginStruct()
+		t.i32Field(1, c.Type.physical())
+		t.i32Field(3, repetitionOptional)
+		t.stringField(4, c.Name)
+		switch c.Type {
+		case String:
+			t.i32Field(6, convertedUTF8)
+			t.structField(10)
+			t.structField(1) // STRING
+			t.endStruct()
+			t.endStruct()
+		case Uint64:
+			t.i32Field(6, convertedUint64)
+			t.structField(10)
+			t.structField(10) // INTEGER
+			t.byteField(1, 64)
+			t.boolField(2, false)
+			t.endStruct()
+			t.endStruct()
+		case Timestamp:
+			t.i32Field(6, convertedTimestampMicros)
+			t.structField(10)
+			t.structField(8) // TIMESTAMP
+			t.boolField(1, true)
+			t.structField(2)
+			t.structField(2) // MICROS
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+		}
+		t.endStruct()
+	}
+
+	t.i64Field(3, int64(w.rows))
+
+	var total int64
+	for _, m := range chunks {
+		total += m.size
+	}
+	t.listField(4, thriftStruct, 1)
+	t.beginStruct()
+	t.listField(1, thriftStruct, len(w.columns))
+	for i, c := range w.columns {
+		m := chunks[i]
+		offset := m.dataOffset
+		if m.dictionaryOffset > 0 {
+			offset = m.dictionaryOffset
+		}
+
+		t.beginStruct()
+		t.i64Field(2, offset)
+		t.structField(3)
+		t.i32Field(1, c.Type.physical())
+		t.listField(2, thriftI32, len(m.encodings))
+		for _, e := range m.encodings {
+			t.varint(int64(e))
+		}
+		t.listField(3, thriftBinary, 1)
+		t.str(c.Name)
+		t.i32Field(4, codecUncompressed)
+		t.i64Field(5, int64(len(c.defined)))
+		t.i64Field(6, m.size)
+		t.i64Field(7, m.size)
+		t.i64Field(9, m.dataOffset)
+		if m.dictionaryOffset > 0 {
+			t.i64Field(11, m.dictionaryOffset)
+		}
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64Field(2, total)
+	t.i64Field(3, int64(w.rows))
+	t.endStruct()
+
+	t.stringField(6, CreatedBy)
+	t.endStruct()
+	return t.buf
+}
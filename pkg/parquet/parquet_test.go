@@ -0,0 +1,202 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	columns := []Column{
+		{Name: "_time", Type: Timestamp},
+		{Name: "host", Type: String, Dictionary: true},
+		{Name: "_value", Type: Double},
+		{Name: "count", Type: Int64},
+		{Name: "total", Type: Uint64},
+		{Name: "ok", Type: Boolean},
+		{Name: "msg", Type: String},
+	}
+	t0 := time.Date(2019, 11, 1, 12, 0, 0, 123456000, time.UTC)
+	rows := [][]interface{}{
+		{t0, "server01", 1.5, int64(-1), uint64(1 << 63), true, "a"},
+		{t0.Add(time.Second), "server02", nil, int64(2), uint64(2), false, nil},
+		{t0.Add(2 * time.Second), "server01", 3.25, nil, nil, nil, "c"},
+		{nil, nil, 4.0, int64(4), uint64(4), true, ""},
+	}
+	for i := 0; i < 20; i++ {
+		rows = append(rows, []interface{}{t0, "server03", float64(i), int64(i), uint64(i), i%3 == 0, "x"})
+	}
+
+	w := NewWriter(columns)
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	gotColumns, gotRows, err := readFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotColumns, columns) {
+		t.Errorf("unexpected columns:\ngot  %v\nexp  %v", gotColumns, columns)
+	}
+	if !reflect.DeepEqual(gotRows, rows) {
+		t.Errorf("unexpected rows:\ngot  %v\nexp  %v", gotRows, rows)
+	}
+}
+
+// update rewrites testdata/influxdb.parquet with the output of Writer. Run
+// testdata/generate afterwards to check that parquet-go still reads it.
+var update = flag.Bool("update", false, "update testdata/influxdb.parquet")
+
+// testColumns and testRows are the contents of the files in testdata.
+var (
+	testColumns = []Column{
+		{Name: "_time", Type: Timestamp},
+		{Name: "host", Type: String, Dictionary: true},
+		{Name: "_value", Type: Double},
+		{Name: "count", Type: Int64},
+		{Name: "total", Type: Uint64},
+		{Name: "ok", Type: Boolean},
+		{Name: "msg", Type: String},
+	}
+	testT0   = time.Date(2019, 11, 1, 12, 0, 0, 123456000, time.UTC)
+	testRows = [][]interface{}{
+		{testT0, "server01", 1.5, int64(-1), uint64(1 << 62), true, "a"},
+		{testT0.Add(time.Second), "server02", nil, int64(2), uint64(2), false, nil},
+		{testT0.Add(2 * time.Second), "server01", 3.25, nil, nil, nil, "c"},
+		{nil, nil, 4.0, int64(4), uint64(4), true, ""},
+	}
+)
+
+// TestWriter_Golden checks that files are written as testdata/influxdb.parquet,
+// which testdata/generate reads back as testRows with
+// github.com/xitongsys/parquet-go.
+func TestWriter_Golden(t *testing.T) {
+	w := NewWriter(testColumns)
+	for _, row := range testRows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile("testdata/influxdb.parquet", buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp, err := ioutil.ReadFile("testdata/influxdb.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Fatalf("file differs from testdata/influxdb.parquet:\ngot  %x\nexp  %x", buf.Bytes(), exp)
+	}
+}
+
+// TestRead_External reads testdata/xitongsys.parquet, which holds testRows
+// written by github.com/xitongsys/parquet-go without compression by
+// testdata/generate.
+func TestRead_External(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/xitongsys.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, rows, err := readFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(columns, testColumns) {
+		t.Errorf("unexpected columns:\ngot  %v\nexp  %v", columns, testColumns)
+	}
+	if !reflect.DeepEqual(rows, testRows) {
+		t.Errorf("unexpected rows:\ngot  %v\nexp  %v", rows, testRows)
+	}
+}
+
+func TestWriter_Empty(t *testing.T) {
+	columns := []Column{{Name: "tag", Type: String, Dictionary: true}, {Name: "_value", Type: Double}}
+	var buf bytes.Buffer
+	if _, err := NewWriter(columns).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	gotColumns, gotRows, err := readFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotColumns, columns) || len(gotRows) != 0 {
+		t.Fatalf("unexpected file: %v %v", gotColumns, gotRows)
+	}
+}
+
+func TestWriter_WriteRow_Invalid(t *testing.T) {
+	w := NewWriter([]Column{{Name: "_value", Type: Double}})
+	if err := w.WriteRow([]interface{}{int64(1)}); err == nil {
+		t.Error("expected error for value of wrong type")
+	}
+	if err := w.WriteRow([]interface{}{1.0, 2.0}); err == nil {
+		t.Error("expected error for wrong number of values")
+	}
+	if got := w.Rows(); got != 0 {
+		t.Errorf("got %d rows, exp 0", got)
+	}
+}
+
+func TestWriter_TimestampLogicalType(t *testing.T) {
+	w := NewWriter([]Column{{Name: "_time", Type: Timestamp}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	end := len(data) - len(Magic) - 4
+	n := int(binary.LittleEndian.Uint32(data[end:]))
+	r := thriftReader{buf: data[end-n : end]}
+	meta, err := r.readStruct()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := meta.list(2)[1].(thriftStructValue)
+	if got := e.int(1); got != physicalInt64 {
+		t.Errorf("got physical type %d, exp INT64", got)
+	}
+	ts := e.strct(10).strct(8)
+	if ts == nil {
+		t.Fatal("missing TIMESTAMP logical type")
+	}
+	if got := ts[1]; got != true {
+		t.Errorf("timestamp is not adjusted to UTC")
+	}
+	if _, ok := ts.strct(2)[2]; !ok {
+		t.Errorf("timestamp unit is not MICROS: %v", ts.strct(2))
+	}
+}
+
+func TestDecodeHybrid_BitPacked(t *testing.T) {
+	// One group of 8 values 0..7 bit-packed with a width of 3, as in the
+	// example of the Parquet format specification.
+	buf := []byte{0x03, 0x88, 0xc6, 0xfa}
+	got, err := decodeHybrid(buf, 3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []int{0, 1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, exp %v", got, exp)
+	}
+}
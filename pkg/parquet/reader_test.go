@@ -0,0 +1,447 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The reader in this file decodes the files written by Writer, so that tests
+// can check them. Only the features used by Writer are supported.
+
+var (
+	errInvalidFile   = errors.New("parquet: invalid file")
+	errInvalidThrift = errors.New("parquet: invalid metadata")
+)
+
+// readFile decodes a file written by a Writer. It returns the columns of the file
+// and its rows, holding the values accepted by Writer.WriteRow. Compressed
+// files and nested columns are not supported.
+func readFile(data []byte) ([]Column, [][]interface{}, error) {
+	if len(data) < 2*len(Magic)+4 || string(data[:len(Magic)]) != Magic || string(data[len(data)-len(Magic):]) != Magic {
+		return nil, nil, errInvalidFile
+	}
+	end := len(data) - len(Magic) - 4
+	n := int(binary.LittleEndian.Uint32(data[end:]))
+	if n > end-len(Magic) {
+		return nil, nil, errInvalidFile
+	}
+	r := thriftReader{buf: data[end-n : end]}
+	meta, err := r.readStruct()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema := meta.list(2)
+	if len(schema) == 0 {
+		return nil, nil, errInvalidFile
+	}
+	columns := make([]Column, 0, len(schema)-1)
+	for _, v := range schema[1:] {
+		e, _ := v.(thriftStructValue)
+		if _, ok := e[5]; ok {
+			return nil, nil, errors.New("parquet: nested columns are not supported")
+		}
+		c := Column{Name: e.str(4)}
+		switch e.int(1) {
+		case physicalBoolean:
+			c.Type = Boolean
+		case physicalDouble:
+			c.Type = Double
+		case physicalByteArray:
+			c.Type = String
+		case physicalInt64:
+			switch e.int(6) {
+			case convertedTimestampMicros:
+				c.Type = Timestamp
+			case convertedUint64:
+				c.Type = Uint64
+			default:
+				if _, ok := e[6]; ok {
+					return nil, nil, fmt.Errorf("parquet: unsupported converted type %d of column %q", e.int(6), c.Name)
+				}
+				c.Type = Int64
+			}
+		default:
+			return nil, nil, fmt.Errorf("parquet: unsupported type %d of column %q", e.int(1), c.Name)
+		}
+		columns = append(columns, c)
+	}
+
+	rows := make([][]interface{}, meta.int(3))
+	for i := range rows {
+		rows[i] = make([]interface{}, len(columns))
+	}
+	var offset int
+	for _, v := range meta.list(4) {
+		rg, _ := v.(thriftStructValue)
+		chunks := rg.list(1)
+		if len(chunks) != len(columns) {
+			return nil, nil, errInvalidFile
+		}
+		for j, v := range chunks {
+			chunk, _ := v.(thriftStructValue)
+			values, dict, err := readChunk(data, chunk.strct(3), columns[j].Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parquet: column %q: %v", columns[j].Name, err)
+			} else if offset+len(values) > len(rows) {
+				return nil, nil, errInvalidFile
+			}
+			columns[j].Dictionary = columns[j].Dictionary || dict
+			for i, v := range values {
+				rows[offset+i][j] = v
+			}
+		}
+		offset += int(rg.int(3))
+	}
+	return columns, rows, nil
+}
+
+// readChunk reads the values of a column chunk and reports if they are
+// dictionary encoded.
+func readChunk(data []byte, meta thriftStructValue, typ Type) ([]interface{}, bool, error) {
+	if meta.int(4) != codecUncompressed {
+		return nil, false, errors.New("compression is not supported")
+	}
+
+	pos := meta.int(9)
+	off, hasDict := meta[11].(int64)
+	if hasDict {
+		pos = off
+	}
+	n := int(meta.int(5))
+
+	var dict []interface{}
+	values := make([]interface{}, 0, n)
+	for len(values) < n {
+		if pos < 0 || pos >= int64(len(data)) {
+			return nil, false, errInvalidFile
+		}
+		r := thriftReader{buf: data[pos:]}
+		header, err := r.readStruct()
+		if err != nil {
+			return nil, false, err
+		}
+		start := pos + int64(r.pos)
+		end := start + header.int(3)
+		if end > int64(len(data)) {
+			return nil, false, errInvalidFile
+		}
+		page := data[start:end]
+		pos = end
+
+		switch header.int(1) {
+		case pageDictionary:
+			h := header.strct(7)
+			if dict, _, err = decodePlain(page, typ, int(h.int(1))); err != nil {
+				return nil, false, err
+			}
+		case pageData:
+			h := header.strct(5)
+			if values, err = readDataPage(values, page, typ, int(h.int(1)), h.int(2), dict); err != nil {
+				return nil, false, err
+			}
+		default:
+			return nil, false, fmt.Errorf("unsupported page type %d", header.int(1))
+		}
+	}
+	return values, hasDict, nil
+}
+
+func readDataPage(values []interface{}, page []byte, typ Type, n int, encoding int64, dict []interface{}) ([]interface{}, error) {
+	if len(page) < 4 {
+		return nil, errInvalidFile
+	}
+	size := int(binary.LittleEndian.Uint32(page))
+	if size > len(page)-4 {
+		return nil, errInvalidFile
+	}
+	levels, err := decodeHybrid(page[4:4+size], 1, n)
+	if err != nil {
+		return nil, err
+	}
+	page = page[4+size:]
+
+	defined := 0
+	for _, l := range levels {
+		defined += l
+	}
+
+	var decoded []interface{}
+	switch encoding {
+	case encodingPlain:
+		decoded, _, err = decodePlain(page, typ, defined)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if len(page) == 0 {
+			if defined > 0 {
+				return nil, errInvalidFile
+			}
+			break
+		}
+		var indices []int
+		if indices, err = decodeHybrid(page[1:], int(page[0]), defined); err != nil {
+			return nil, err
+		}
+		decoded = make([]interface{}, len(indices))
+		for i, j := range indices {
+			if j >= len(dict) {
+				return nil, errInvalidFile
+			}
+			decoded[i] = dict[j]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range levels {
+		if l == 0 {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, decoded[0])
+		decoded = decoded[1:]
+	}
+	return values, nil
+}
+
+// decodePlain decodes n PLAIN encoded values and returns the number of bytes
+// read.
+func decodePlain(buf []byte, typ Type, n int) ([]interface{}, int, error) {
+	values := make([]interface{}, n)
+	pos := 0
+	for i := range values {
+		switch typ {
+		case Boolean:
+			if i/8 >= len(buf) {
+				return nil, 0, errInvalidFile
+			}
+			values[i] = buf[i/8]&(1<<uint(i%8)) != 0
+			pos = i/8 + 1
+		case String:
+			if len(buf)-pos < 4 {
+				return nil, 0, errInvalidFile
+			}
+			size := int(binary.LittleEndian.Uint32(buf[pos:]))
+			pos += 4
+			if size > len(buf)-pos {
+				return nil, 0, errInvalidFile
+			}
+			values[i] = string(buf[pos : pos+size])
+			pos += size
+		default:
+			if len(buf)-pos < 8 {
+				return nil, 0, errInvalidFile
+			}
+			v := binary.LittleEndian.Uint64(buf[pos:])
+			pos += 8
+			switch typ {
+			case Double:
+				values[i] = math.Float64frombits(v)
+			case Uint64:
+				values[i] = v
+			case Timestamp:
+				values[i] = time.Unix(0, int64(v)*int64(time.Microsecond)).UTC()
+			default:
+				values[i] = int64(v)
+			}
+		}
+	}
+	return values, pos, nil
+}
+
+// decodeHybrid decodes n values encoded with the RLE/bit-packing hybrid
+// encoding.
+func decodeHybrid(buf []byte, width, n int) ([]int, error) {
+	values := make([]int, 0, n)
+	size := (width + 7) / 8
+	for len(values) < n {
+		header, k := binary.Uvarint(buf)
+		if k <= 0 {
+			return nil, errInvalidFile
+		}
+		buf = buf[k:]
+
+		if header&1 == 0 {
+			// RLE run of a single value.
+			if len(buf) < size {
+				return nil, errInvalidFile
+			}
+			v := 0
+			for b := 0; b < size; b++ {
+				v |= int(buf[b]) << (8 * uint(b))
+			}
+			buf = buf[size:]
+			for count := header >> 1; count > 0 && len(values) < n; count-- {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		// Bit-packed groups of 8 values.
+		count := int(header>>1) * 8
+		bytes := int(header>>1) * width
+		if len(buf) < bytes {
+			return nil, errInvalidFile
+		}
+		for i := 0; i < count && len(values) < n; i++ {
+			v := 0
+			for b := 0; b < width; b++ {
+				bit := i*width + b
+				if buf[bit/8]&(1<<uint(bit%8)) != 0 {
+					v |= 1 << uint(b)
+				}
+			}
+			values = append(values, v)
+		}
+		buf = buf[bytes:]
+	}
+	return values, nil
+}
+
+// thriftStructValue is a decoded Thrift struct, mapping field IDs to values.
+// Values are bool, int64, float64, []byte, []interface{} or thriftStructValue.
+type thriftStructValue map[int16]interface{}
+
+func (s thriftStructValue) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStructValue) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStructValue) strct(id int16) thriftStructValue {
+	v, _ := s[id].(thriftStructValue)
+	return v
+}
+
+func (s thriftStructValue) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftReader decodes Thrift structs encoded with the compact protocol.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errInvalidThrift
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errInvalidThrift
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) readStruct() (thriftStructValue, error) {
+	s := make(thriftStructValue)
+	var id int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		} else if b == 0 {
+			return s, nil
+		}
+
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+
+		switch typ {
+		case thriftTrue:
+			s[id] = true
+		case thriftFalse:
+			s[id] = false
+		default:
+			if s[id], err = r.readValue(typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// Booleans in lists are encoded as a byte.
+		b, err := r.byte()
+		return b == thriftTrue, err
+	case thriftByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.varint()
+	case thriftDouble:
+		if len(r.buf)-r.pos < 8 {
+			return nil, errInvalidThrift
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		} else if uint64(len(r.buf)-r.pos) < n {
+			return nil, errInvalidThrift
+		}
+		v := r.buf[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case thriftList, thriftSet:
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n, elem := uint64(b>>4), b&0x0f
+		if n == 15 {
+			if n, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(len(r.buf)-r.pos) {
+			return nil, errInvalidThrift
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = r.readValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		return r.readStruct()
+	}
+	return nil, errInvalidThrift
+}
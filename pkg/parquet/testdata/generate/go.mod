@@ -0,0 +1,8 @@
+module generate
+
+go 1.13
+
+require (
+	github.com/xitongsys/parquet-go v1.5.5-0.20201110004701-b09c49d6d457
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+)
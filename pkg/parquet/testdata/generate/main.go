@@ -0,0 +1,119 @@
+// Command generate writes testdata/xitongsys.parquet with
+// github.com/xitongsys/parquet-go, and checks that parquet-go reads
+// testdata/influxdb.parquet, which is written by the parquet package, as the
+// same rows. Both files hold testRows of parquet_test.go.
+//
+// It is a separate module so that parquet-go is not a dependency of influxdb.
+// Run it from this directory:
+//
+//	go run .
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type row struct {
+	Time  *int64   `parquet:"name=_time, type=TIMESTAMP_MICROS, basetype=INT64, repetitiontype=OPTIONAL"`
+	Host  *string  `parquet:"name=host, type=UTF8, basetype=BYTE_ARRAY, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL"`
+	Value *float64 `parquet:"name=_value, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Count *int64   `parquet:"name=count, type=INT64, repetitiontype=OPTIONAL"`
+	Total *uint64  `parquet:"name=total, type=UINT_64, basetype=INT64, repetitiontype=OPTIONAL"`
+	OK    *bool    `parquet:"name=ok, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	Msg   *string  `parquet:"name=msg, type=UTF8, basetype=BYTE_ARRAY, repetitiontype=OPTIONAL"`
+}
+
+func main() {
+	dir := flag.String("dir", "..", "testdata directory")
+	flag.Parse()
+
+	if err := run(*dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	t0 := time.Date(2019, 11, 1, 12, 0, 0, 123456000, time.UTC)
+	us := func(t time.Time) *int64 { v := t.UnixNano() / int64(time.Microsecond); return &v }
+	i64 := func(v int64) *int64 { return &v }
+	u64 := func(v uint64) *uint64 { return &v }
+	f64 := func(v float64) *float64 { return &v }
+	str := func(v string) *string { return &v }
+	boolean := func(v bool) *bool { return &v }
+	rows := []row{
+		{us(t0), str("server01"), f64(1.5), i64(-1), u64(1 << 62), boolean(true), str("a")},
+		{us(t0.Add(time.Second)), str("server02"), nil, i64(2), u64(2), boolean(false), nil},
+		{us(t0.Add(2 * time.Second)), str("server01"), f64(3.25), nil, nil, nil, str("c")},
+		{nil, nil, f64(4.0), i64(4), u64(4), boolean(true), str("")},
+	}
+
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, new(row), 1)
+	if err != nil {
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_UNCOMPRESSED
+	for _, r := range rows {
+		if err := pw.Write(r); err != nil {
+			return err
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "xitongsys.parquet"), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "influxdb.parquet"))
+	if err != nil {
+		return err
+	}
+	f, err := buffer.NewBufferFile(data)
+	if err != nil {
+		return err
+	}
+	pr, err := reader.NewParquetReader(f, new(row), 1)
+	if err != nil {
+		return err
+	}
+	defer pr.ReadStop()
+
+	got := make([]row, pr.GetNumRows())
+	if err := pr.Read(&got); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(got, rows) {
+		return fmt.Errorf("influxdb.parquet does not hold the expected rows:\ngot  %s\nexp  %s", format(got), format(rows))
+	}
+	return nil
+}
+
+func format(rows []row) string {
+	var b bytes.Buffer
+	for _, r := range rows {
+		fmt.Fprintf(&b, "{%s %s %s %s %s %s %s} ", deref(r.Time), deref(r.Host), deref(r.Value), deref(r.Count), deref(r.Total), deref(r.OK), deref(r.Msg))
+	}
+	return b.String()
+}
+
+func deref(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	return fmt.Sprint(rv.Elem().Interface())
+}
@@ -0,0 +1,104 @@
+package parquet
+
+import "encoding/binary"
+
+// Parquet metadata is encoded with the Thrift compact protocol. Only the
+// subset of the protocol used by the file metadata is implemented.
+
+// Thrift compact protocol types.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol.
+type thriftWriter struct {
+	buf    []byte
+	lastID []int16
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &w.lastID[len(w.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.buf = appendUvarint(w.buf, uint64((v<<1)^(v>>63)))
+}
+
+func (w *thriftWriter) beginStruct() { w.lastID = append(w.lastID, 0) }
+
+func (w *thriftWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+func (w *thriftWriter) structField(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.beginStruct()
+}
+
+func (w *thriftWriter) boolField(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, thriftTrue)
+	} else {
+		w.fieldHeader(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) byteField(id int16, v int8) {
+	w.fieldHeader(id, thriftByte)
+	w.buf = append(w.buf, byte(v))
+}
+
+func (w *thriftWriter) i32Field(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64Field(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) stringField(id int16, v string) {
+	w.fieldHeader(id, thriftBinary)
+	w.str(v)
+}
+
+func (w *thriftWriter) str(v string) {
+	w.buf = appendUvarint(w.buf, uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *thriftWriter) listField(id int16, elem byte, n int) {
+	w.fieldHeader(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elem)
+	} else {
+		w.buf = append(w.buf, 0xf0|elem)
+		w.buf = appendUvarint(w.buf, uint64(n))
+	}
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
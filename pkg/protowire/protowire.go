@@ -0,0 +1,155 @@
+// Package protowire reads and writes the fields of protobuf encoded messages.
+//
+// It allows the handful of fields needed from third-party protobuf messages,
+// such as those sent by Prometheus remote write or OpenTelemetry exporters, to
+// be decoded without generating code for their full schema.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type is a protobuf wire type.
+type Type int
+
+// Protobuf wire types.
+const (
+	VarintType  Type = 0
+	Fixed64Type Type = 1
+	BytesType   Type = 2
+	Fixed32Type Type = 5
+)
+
+// ErrTruncated is returned when a message ends in the middle of a field.
+var ErrTruncated = errors.New("truncated protobuf message")
+
+// Decoder reads the fields of a protobuf message.
+type Decoder struct {
+	buf []byte
+}
+
+// NewDecoder returns a Decoder reading the message in buf.
+func NewDecoder(buf []byte) *Decoder {
+	return &Decoder{buf: buf}
+}
+
+// Done returns true once all fields of the message have been read.
+func (d *Decoder) Done() bool { return len(d.buf) == 0 }
+
+// Key reads the field number and wire type of the next field.
+func (d *Decoder) Key() (field int, typ Type, err error) {
+	v, err := d.Varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(v >> 3), Type(v & 0x7), nil
+}
+
+// Varint reads a varint value.
+func (d *Decoder) Varint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		return 0, ErrTruncated
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+// Fixed64 reads a 64-bit value.
+func (d *Decoder) Fixed64() (uint64, error) {
+	if len(d.buf) < 8 {
+		return 0, ErrTruncated
+	}
+	v := binary.LittleEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v, nil
+}
+
+// Double reads a double value.
+func (d *Decoder) Double() (float64, error) {
+	v, err := d.Fixed64()
+	return math.Float64frombits(v), err
+}
+
+// Bytes reads a length-delimited value. The returned slice refers to the
+// message being decoded.
+func (d *Decoder) Bytes() ([]byte, error) {
+	n, err := d.Varint()
+	if err != nil {
+		return nil, err
+	} else if n > uint64(len(d.buf)) {
+		return nil, ErrTruncated
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+// Skip reads and discards a value of type typ.
+func (d *Decoder) Skip(typ Type) error {
+	var err error
+	switch typ {
+	case VarintType:
+		_, err = d.Varint()
+	case Fixed64Type:
+		_, err = d.Fixed64()
+	case BytesType:
+		_, err = d.Bytes()
+	case Fixed32Type:
+		if len(d.buf) < 4 {
+			return ErrTruncated
+		}
+		d.buf = d.buf[4:]
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", typ)
+	}
+	return err
+}
+
+// Encoder writes the fields of a protobuf message.
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the encoded message.
+func (e *Encoder) Bytes() []byte { return e.buf }
+
+func (e *Encoder) key(field int, typ Type) {
+	e.buf = appendUvarint(e.buf, uint64(field)<<3|uint64(typ))
+}
+
+// Varint writes a varint field.
+func (e *Encoder) Varint(field int, v uint64) {
+	e.key(field, VarintType)
+	e.buf = appendUvarint(e.buf, v)
+}
+
+// Fixed64 writes a 64-bit field.
+func (e *Encoder) Fixed64(field int, v uint64) {
+	e.key(field, Fixed64Type)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+// Double writes a double field.
+func (e *Encoder) Double(field int, v float64) {
+	e.Fixed64(field, math.Float64bits(v))
+}
+
+// BytesField writes a length-delimited field, such as a string or an embedded
+// message.
+func (e *Encoder) BytesField(field int, b []byte) {
+	e.key(field, BytesType)
+	e.buf = appendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
@@ -0,0 +1,51 @@
+package protowire_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/protowire"
+)
+
+func TestDecoder(t *testing.T) {
+	var e protowire.Encoder
+	e.Varint(1, 300)
+	e.Double(2, 1.5)
+	e.BytesField(3, []byte("abc"))
+	e.Fixed64(4, 7)
+
+	d := protowire.NewDecoder(e.Bytes())
+	if field, typ, err := d.Key(); err != nil || field != 1 || typ != protowire.VarintType {
+		t.Fatalf("unexpected key: %d %d %v", field, typ, err)
+	}
+	if v, err := d.Varint(); err != nil || v != 300 {
+		t.Fatalf("unexpected varint: %d %v", v, err)
+	}
+	if field, typ, err := d.Key(); err != nil || field != 2 || typ != protowire.Fixed64Type {
+		t.Fatalf("unexpected key: %d %d %v", field, typ, err)
+	}
+	if v, err := d.Double(); err != nil || v != 1.5 {
+		t.Fatalf("unexpected double: %v %v", v, err)
+	}
+	if field, typ, err := d.Key(); err != nil || field != 3 || typ != protowire.BytesType {
+		t.Fatalf("unexpected key: %d %d %v", field, typ, err)
+	}
+	if b, err := d.Bytes(); err != nil || string(b) != "abc" {
+		t.Fatalf("unexpected bytes: %q %v", b, err)
+	}
+	if _, typ, err := d.Key(); err != nil {
+		t.Fatal(err)
+	} else if err := d.Skip(typ); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Done() {
+		t.Fatal("expected all fields to be read")
+	}
+
+	d = protowire.NewDecoder([]byte{0x12, 0x05, 'a'})
+	if _, _, err := d.Key(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Bytes(); err != protowire.ErrTruncated {
+		t.Fatalf("got error %v, want %v", err, protowire.ErrTruncated)
+	}
+}
@@ -0,0 +1,416 @@
+// Package s3 implements the subset of the Amazon S3 API used to store backups
+// in object storage: uploading objects in parts, so they can be streamed
+// without knowing their size in advance, and downloading them again.
+//
+// Requests are signed with AWS Signature Version 4 and use path-style
+// addressing, so any S3 compatible service, such as MinIO, may be used by
+// setting the endpoint of the Client.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPartSize is the size of the parts objects are uploaded in. It is the
+// minimum size S3 allows for all but the last part of an upload.
+const DefaultPartSize = 5 * 1024 * 1024
+
+// DefaultRegion is the region requests are signed for when none is given.
+const DefaultRegion = "us-east-1"
+
+// Credentials are the AWS credentials requests are signed with.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// CredentialsFromEnv returns the credentials set by the standard
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// RegionFromEnv returns the region set by the AWS_REGION or AWS_DEFAULT_REGION
+// environment variables, or DefaultRegion if neither is set.
+func RegionFromEnv() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	} else if region := os.Getenv("AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return DefaultRegion
+}
+
+// ParseURL returns the bucket and key prefix of an s3://bucket/prefix/ URL. A
+// non-empty prefix always ends with a slash.
+func ParseURL(rawurl string) (bucket, prefix string, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	} else if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 URL %q: expected s3://bucket/prefix", rawurl)
+	}
+
+	prefix = strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return u.Host, prefix, nil
+}
+
+// Error is an error returned by the S3 API.
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// Client makes requests to the S3 API.
+type Client struct {
+	// Endpoint is the URL of the S3 API. It defaults to the AWS endpoint of
+	// Region.
+	Endpoint    string
+	Region      string
+	Credentials Credentials
+
+	// PartSize is the size of the parts objects are uploaded in.
+	PartSize int
+
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+// NewClient returns a client for the S3 API at endpoint, or the AWS endpoint
+// of region if endpoint is empty.
+func NewClient(endpoint, region string, creds Credentials) *Client {
+	if region == "" {
+		region = DefaultRegion
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	return &Client{
+		Endpoint:    strings.TrimSuffix(endpoint, "/"),
+		Region:      region,
+		Credentials: creds,
+		PartSize:    DefaultPartSize,
+		HTTPClient:  http.DefaultClient,
+		now:         time.Now,
+	}
+}
+
+// PutObject uploads the contents of r to key in bucket. Objects larger than a
+// single part are uploaded with a multipart upload, so at most one part is
+// held in memory at a time.
+func (c *Client) PutObject(ctx context.Context, bucket, key string, r io.Reader) error {
+	buf := make([]byte, c.PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err := c.do(ctx, "PUT", bucket, key, nil, buf[:n])
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	uploadID, err := c.createMultipartUpload(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := c.uploadParts(ctx, bucket, key, uploadID, buf, r); err != nil {
+		if _, abortErr := c.do(ctx, "DELETE", bucket, key, url.Values{"uploadId": {uploadID}}, nil); abortErr != nil {
+			return fmt.Errorf("%v; aborting upload: %v", err, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// uploadParts uploads buf, which holds the first part of an object, and the
+// rest of the object read from r, then completes the upload.
+func (c *Client) uploadParts(ctx context.Context, bucket, key, uploadID string, buf []byte, r io.Reader) error {
+	var complete completeMultipartUpload
+	for part := 1; len(buf) > 0; part++ {
+		params := url.Values{
+			"partNumber": {strconv.Itoa(part)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := c.do(ctx, "PUT", bucket, key, params, buf)
+		if err != nil {
+			return err
+		}
+		complete.Parts = append(complete.Parts, completedPart{
+			PartNumber: part,
+			ETag:       resp.Header.Get("ETag"),
+		})
+
+		n, err := io.ReadFull(r, buf[:cap(buf)])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		buf = buf[:n]
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, "POST", bucket, key, url.Values{"uploadId": {uploadID}}, body)
+	return err
+}
+
+func (c *Client) createMultipartUpload(ctx context.Context, bucket, key string) (string, error) {
+	resp, err := c.do(ctx, "POST", bucket, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp.body, &result); err != nil {
+		return "", err
+	} else if result.UploadID == "" {
+		return "", errors.New("s3: no upload ID in response")
+	}
+	return result.UploadID, nil
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// GetObject writes the contents of key in bucket to w.
+func (c *Client) GetObject(ctx context.Context, bucket, key string, w io.Writer) error {
+	req, err := c.newRequest(ctx, "GET", bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// response is a response to a request whose body has been read.
+type response struct {
+	*http.Response
+	body []byte
+}
+
+// do makes a request with the given body and reads its response.
+func (c *Client) do(ctx context.Context, method, bucket, key string, params url.Values, body []byte) (*response, error) {
+	req, err := c.newRequest(ctx, method, bucket, key, params, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, decodeError(resp)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{Response: resp, body: b}, nil
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	if b, err := ioutil.ReadAll(resp.Body); err == nil {
+		_ = xml.Unmarshal(b, e)
+	}
+	return e
+}
+
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, params url.Values, body []byte) (*http.Request, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = encodePath(u.Path)
+	u.RawQuery = encodeQuery(params)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body == nil {
+		req.Body = nil
+	}
+	c.sign(req, body)
+	return req, nil
+}
+
+// sign signs req with AWS Signature Version 4.
+func (c *Client) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if c.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.Credentials.SessionToken)
+	}
+
+	scope := strings.Join([]string{date, c.Region, "s3", "aws4_request"}, "/")
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.Credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.Credentials.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalHeaders returns the names and canonical form of the headers of req
+// that are signed: the host, x-amz-* headers and content headers.
+func canonicalHeaders(req *http.Request) (signed, canonical string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-md5" || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(headers[name])
+		b.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// encodeQuery returns the canonical encoding of params, sorted by key.
+func encodeQuery(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range params[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func encodePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes all but the unreserved characters of s, as
+// required by Signature Version 4. Slashes are only encoded if encodeSlash is
+// true.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		}
+	}
+	return b.String()
+}
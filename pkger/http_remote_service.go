@@ -58,6 +58,40 @@ func (s *HTTPRemoteService) InitStack(ctx context.Context, userID influxdb.ID, s
 	return newStack, nil
 }
 
+// ReadStack returns the stack matching the given id.
+func (s *HTTPRemoteService) ReadStack(ctx context.Context, id influxdb.ID) (Stack, error) {
+	var respBody RespStack
+	err := s.Client.
+		Get(RoutePrefix, "/stacks", id.String()).
+		DecodeJSON(&respBody).
+		Do(ctx)
+	if err != nil {
+		return Stack{}, err
+	}
+
+	stack := Stack{
+		Name:        respBody.Name,
+		Description: respBody.Description,
+		URLs:        respBody.URLs,
+		Resources:   respBody.Resources,
+		CRUDLog:     respBody.CRUDLog,
+	}
+
+	stackID, err := influxdb.IDFromString(respBody.ID)
+	if err != nil {
+		return Stack{}, err
+	}
+	stack.ID = *stackID
+
+	orgID, err := influxdb.IDFromString(respBody.OrgID)
+	if err != nil {
+		return Stack{}, err
+	}
+	stack.OrgID = *orgID
+
+	return stack, nil
+}
+
 // CreatePkg will produce a pkg from the parameters provided.
 func (s *HTTPRemoteService) CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (*Pkg, error) {
 	var opt CreateOpt
@@ -140,6 +174,9 @@ func (s *HTTPRemoteService) apply(ctx context.Context, orgID influxdb.ID, pkg *P
 		Secrets: opt.MissingSecrets,
 		RawPkg:  b,
 	}
+	if opt.StackID != 0 {
+		reqBody.StackID = opt.StackID.String()
+	}
 
 	var resp RespApplyPkg
 	err = s.Client.
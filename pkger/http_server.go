@@ -50,7 +50,10 @@ func NewHTTPServer(log *zap.Logger, svc SVC) *HTTPServer {
 			Post("/", svr.createPkg)
 		r.With(middleware.SetHeader("Content-Type", "application/json; charset=utf-8")).
 			Post("/apply", svr.applyPkg)
-		r.Post("/stacks", svr.createStack)
+		r.Route("/stacks", func(r chi.Router) {
+			r.Post("/", svr.createStack)
+			r.Get("/{stack_id}", svr.readStack)
+		})
 	}
 
 	svr.Router = r
@@ -141,6 +144,50 @@ func (s *HTTPServer) createStack(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RespStack is the response body for the read stack call.
+type RespStack struct {
+	ID          string          `json:"id"`
+	OrgID       string          `json:"orgID"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	URLs        []string        `json:"urls"`
+	Resources   []StackResource `json:"resources"`
+	influxdb.CRUDLog
+}
+
+func (s *HTTPServer) readStack(w http.ResponseWriter, r *http.Request) {
+	stackID, err := influxdb.IDFromString(chi.URLParam(r, "stack_id"))
+	if err != nil {
+		s.api.Err(w, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "the stack id provided in the path was invalid",
+			Err:  err,
+		})
+		return
+	}
+
+	stack, err := s.svc.ReadStack(r.Context(), *stackID)
+	if err != nil {
+		s.api.Err(w, err)
+		return
+	}
+
+	resources := stack.Resources
+	if resources == nil {
+		resources = []StackResource{}
+	}
+
+	s.api.Respond(w, http.StatusOK, RespStack{
+		ID:          stack.ID.String(),
+		OrgID:       stack.OrgID.String(),
+		Name:        stack.Name,
+		Description: stack.Description,
+		URLs:        stack.URLs,
+		Resources:   resources,
+		CRUDLog:     stack.CRUDLog,
+	})
+}
+
 // ReqCreateOrgIDOpt provides options to export resources by organization id.
 type ReqCreateOrgIDOpt struct {
 	OrgID   string `json:"orgID"`
@@ -256,6 +303,7 @@ func (p PkgRemote) Encoding() Encoding {
 type ReqApplyPkg struct {
 	DryRun  bool              `json:"dryRun" yaml:"dryRun"`
 	OrgID   string            `json:"orgID" yaml:"orgID"`
+	StackID string            `json:"stackID" yaml:"stackID"`
 	Remotes []PkgRemote       `json:"remotes" yaml:"remotes"`
 	RawPkgs []json.RawMessage `json:"packages" yaml:"packages"`
 	RawPkg  json.RawMessage   `json:"package" yaml:"package"`
@@ -322,6 +370,19 @@ func (s *HTTPServer) applyPkg(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var stackID influxdb.ID
+	if reqBody.StackID != "" {
+		id, err := influxdb.IDFromString(reqBody.StackID)
+		if err != nil {
+			s.api.Err(w, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid stack ID provided: %q", reqBody.StackID),
+			})
+			return
+		}
+		stackID = *id
+	}
+
 	auth, err := pctx.GetAuthorizer(r.Context())
 	if err != nil {
 		s.api.Err(w, err)
@@ -361,7 +422,7 @@ func (s *HTTPServer) applyPkg(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sum, err = s.svc.Apply(r.Context(), *orgID, userID, parsedPkg, ApplyWithEnvRefs(reqBody.EnvRefs), ApplyWithSecrets(reqBody.Secrets))
+	sum, err = s.svc.Apply(r.Context(), *orgID, userID, parsedPkg, ApplyWithEnvRefs(reqBody.EnvRefs), ApplyWithSecrets(reqBody.Secrets), ApplyWithStackID(stackID))
 	if err != nil && !IsParseErr(err) {
 		s.api.Err(w, err)
 		return
@@ -363,6 +363,7 @@ func TestPkgerHTTPServer(t *testing.T) {
 				for _, o := range opts {
 					require.NoError(t, o(&opt))
 				}
+				assert.Equal(t, influxdb.ID(3), opt.StackID)
 				sum := pkg.Summary()
 				for key := range opt.MissingSecrets {
 					sum.MissingSecrets = append(sum.MissingSecrets, key)
@@ -377,6 +378,7 @@ func TestPkgerHTTPServer(t *testing.T) {
 		testttp.
 			PostJSON(t, "/api/v2/packages/apply", pkger.ReqApplyPkg{
 				OrgID:   influxdb.ID(9000).String(),
+				StackID: influxdb.ID(3).String(),
 				Secrets: map[string]string{"secret1": "val1"},
 				RawPkg:  bucketPkgKinds(t, pkger.EncodingJSON),
 			}).
@@ -501,6 +503,85 @@ func TestPkgerHTTPServer(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("read a stack", func(t *testing.T) {
+		t.Run("should return the stack with its resources", func(t *testing.T) {
+			svc := &fakeSVC{
+				readStack: func(ctx context.Context, id influxdb.ID) (pkger.Stack, error) {
+					return pkger.Stack{
+						ID:    id,
+						OrgID: 3,
+						Name:  "threeve",
+						Resources: []pkger.StackResource{
+							{
+								APIVersion: pkger.APIVersion,
+								ID:         4,
+								Kind:       pkger.KindBucket,
+								Name:       "rucket_11",
+							},
+						},
+					}, nil
+				},
+			}
+			pkgHandler := pkger.NewHTTPServer(zap.NewNop(), svc)
+			svr := newMountedHandler(pkgHandler, 1)
+
+			testttp.
+				Get(t, "/api/v2/packages/stacks/"+influxdb.ID(1).String()).
+				Do(svr).
+				ExpectStatus(http.StatusOK).
+				ExpectBody(func(buf *bytes.Buffer) {
+					var resp pkger.RespStack
+					decodeBody(t, buf, &resp)
+
+					assert.Equal(t, influxdb.ID(1).String(), resp.ID)
+					assert.Equal(t, influxdb.ID(3).String(), resp.OrgID)
+					assert.Equal(t, "threeve", resp.Name)
+					require.Len(t, resp.Resources, 1)
+					assert.Equal(t, pkger.KindBucket, resp.Resources[0].Kind)
+					assert.Equal(t, influxdb.ID(4), resp.Resources[0].ID)
+					assert.Equal(t, "rucket_11", resp.Resources[0].Name)
+				})
+		})
+
+		t.Run("error cases", func(t *testing.T) {
+			tests := []struct {
+				name           string
+				stackID        string
+				expectedStatus int
+			}{
+				{
+					name:           "bad stack id",
+					stackID:        "invalid",
+					expectedStatus: http.StatusBadRequest,
+				},
+				{
+					name:           "translates svc not found error",
+					stackID:        influxdb.ID(1).String(),
+					expectedStatus: http.StatusNotFound,
+				},
+			}
+
+			for _, tt := range tests {
+				fn := func(t *testing.T) {
+					svc := &fakeSVC{
+						readStack: func(ctx context.Context, id influxdb.ID) (pkger.Stack, error) {
+							return pkger.Stack{}, &influxdb.Error{Code: influxdb.ENotFound}
+						},
+					}
+					pkgHandler := pkger.NewHTTPServer(zap.NewNop(), svc)
+					svr := newMountedHandler(pkgHandler, 1)
+
+					testttp.
+						Get(t, "/api/v2/packages/stacks/"+tt.stackID).
+						Do(svr).
+						ExpectStatus(tt.expectedStatus)
+				}
+
+				t.Run(tt.name, fn)
+			}
+		})
+	})
 }
 
 func bucketPkgKinds(t *testing.T, encoding pkger.Encoding) []byte {
@@ -582,6 +663,7 @@ func decodeBody(t *testing.T, r io.Reader, v interface{}) {
 
 type fakeSVC struct {
 	initStack func(ctx context.Context, userID influxdb.ID, stack pkger.Stack) (pkger.Stack, error)
+	readStack func(ctx context.Context, id influxdb.ID) (pkger.Stack, error)
 	dryRunFn  func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg, opts ...pkger.ApplyOptFn) (pkger.Summary, pkger.Diff, error)
 	applyFn   func(ctx context.Context, orgID, userID influxdb.ID, pkg *pkger.Pkg, opts ...pkger.ApplyOptFn) (pkger.Summary, error)
 }
@@ -595,6 +677,13 @@ func (f *fakeSVC) InitStack(ctx context.Context, userID influxdb.ID, stack pkger
 	return f.initStack(ctx, userID, stack)
 }
 
+func (f *fakeSVC) ReadStack(ctx context.Context, id influxdb.ID) (pkger.Stack, error) {
+	if f.readStack == nil {
+		panic("not implemented")
+	}
+	return f.readStack(ctx, id)
+}
+
 func (f *fakeSVC) CreatePkg(ctx context.Context, setters ...pkger.CreatePkgSetFn) (*pkger.Pkg, error) {
 	panic("not implemented")
 }
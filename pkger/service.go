@@ -50,6 +50,7 @@ const ResourceTypeStack influxdb.ResourceType = "stack"
 // SVC is the packages service interface.
 type SVC interface {
 	InitStack(ctx context.Context, userID influxdb.ID, stack Stack) (Stack, error)
+	ReadStack(ctx context.Context, id influxdb.ID) (Stack, error)
 	CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (*Pkg, error)
 	DryRun(ctx context.Context, orgID, userID influxdb.ID, pkg *Pkg, opts ...ApplyOptFn) (Summary, Diff, error)
 	Apply(ctx context.Context, orgID, userID influxdb.ID, pkg *Pkg, opts ...ApplyOptFn) (Summary, error)
@@ -286,6 +287,19 @@ func (s *Service) InitStack(ctx context.Context, userID influxdb.ID, stack Stack
 	return stack, nil
 }
 
+// ReadStack returns the stack matching the given id, including the resources
+// recorded from applying pkgs to it.
+func (s *Service) ReadStack(ctx context.Context, id influxdb.ID) (Stack, error) {
+	stack, err := s.store.ReadStackByID(ctx, id)
+	if err != nil {
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			return Stack{}, err
+		}
+		return Stack{}, internalErr(err)
+	}
+	return stack, nil
+}
+
 type (
 	// CreatePkgSetFn is a functional input for setting the pkg fields.
 	CreatePkgSetFn func(opt *CreateOpt) error
@@ -1068,6 +1082,7 @@ func (s *Service) dryRunResourceLabelMapping(ctx context.Context, la labelAssoci
 type ApplyOpt struct {
 	EnvRefs        map[string]string
 	MissingSecrets map[string]string
+	StackID        influxdb.ID
 }
 
 // ApplyOptFn updates the ApplyOpt per the functional option.
@@ -1089,6 +1104,15 @@ func ApplyWithSecrets(secrets map[string]string) ApplyOptFn {
 	}
 }
 
+// ApplyWithStackID associates the application of the pkg with a stack. The
+// resources the pkg applies are recorded as the resources of the stack.
+func ApplyWithStackID(stackID influxdb.ID) ApplyOptFn {
+	return func(o *ApplyOpt) error {
+		o.StackID = stackID
+		return nil
+	}
+}
+
 // Apply will apply all the resources identified in the provided pkg. The entire pkg will be applied
 // in its entirety. If a failure happens midway then the entire pkg will be rolled back to the state
 // from before the pkg were applied.
@@ -1110,6 +1134,19 @@ func (s *Service) Apply(ctx context.Context, orgID, userID influxdb.ID, pkg *Pkg
 		return Summary{}, failedValidationErr(err)
 	}
 
+	var stack Stack
+	if opt.StackID != 0 {
+		var err error
+		stack, err = s.ReadStack(ctx, opt.StackID)
+		if err != nil {
+			return Summary{}, err
+		}
+		if stack.OrgID != orgID {
+			msg := fmt.Sprintf("stack[%q] does not belong to organization[%q]", stack.ID.String(), orgID.String())
+			return Summary{}, toInfluxError(influxdb.EConflict, msg)
+		}
+	}
+
 	if !pkg.isVerified {
 		if _, _, err := s.DryRun(ctx, orgID, userID, pkg); err != nil {
 			return Summary{}, err
@@ -1175,9 +1212,68 @@ func (s *Service) Apply(ctx context.Context, orgID, userID influxdb.ID, pkg *Pkg
 
 	pkg.applySecrets(opt.MissingSecrets)
 
+	if opt.StackID != 0 {
+		if err := s.updateStackResources(ctx, stack, pkg); err != nil {
+			return Summary{}, err
+		}
+	}
+
 	return pkg.Summary(), nil
 }
 
+// updateStackResources records the resources of an applied pkg as the
+// resources of the stack.
+func (s *Service) updateStackResources(ctx context.Context, stack Stack, pkg *Pkg) error {
+	type stackIdentity interface {
+		ID() influxdb.ID
+		PkgName() string
+	}
+
+	var resources []StackResource
+	addResource := func(kind Kind, r stackIdentity) {
+		resources = append(resources, StackResource{
+			APIVersion: APIVersion,
+			ID:         r.ID(),
+			Kind:       kind,
+			Name:       r.PkgName(),
+		})
+	}
+	for _, l := range pkg.labels() {
+		addResource(KindLabel, l)
+	}
+	for _, v := range pkg.variables() {
+		addResource(KindVariable, v)
+	}
+	for _, b := range pkg.buckets() {
+		addResource(KindBucket, b)
+	}
+	for _, c := range pkg.checks() {
+		addResource(KindCheck, c)
+	}
+	for _, d := range pkg.dashboards() {
+		addResource(KindDashboard, d)
+	}
+	for _, e := range pkg.notificationEndpoints() {
+		addResource(KindNotificationEndpoint, e)
+	}
+	for _, r := range pkg.notificationRules() {
+		addResource(KindNotificationRule, r)
+	}
+	for _, t := range pkg.tasks() {
+		addResource(KindTask, t)
+	}
+	for _, t := range pkg.telegrafs() {
+		addResource(KindTelegraf, t)
+	}
+
+	stack.Resources = resources
+	stack.UpdatedAt = s.timeGen.Now()
+	if err := s.store.UpdateStack(ctx, stack); err != nil {
+		return internalErr(err)
+	}
+	return nil
+}
+
 func (s *Service) applyBuckets(buckets []*bucket) applier {
 	const resource = "bucket"
 
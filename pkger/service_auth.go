@@ -36,6 +36,17 @@ func (s *authMW) InitStack(ctx context.Context, userID influxdb.ID, newStack Sta
 	return s.next.InitStack(ctx, userID, newStack)
 }
 
+func (s *authMW) ReadStack(ctx context.Context, id influxdb.ID) (Stack, error) {
+	stack, err := s.next.ReadStack(ctx, id)
+	if err != nil {
+		return Stack{}, err
+	}
+	if err := s.authAgent.OrgPermissions(ctx, stack.OrgID, influxdb.ReadAction); err != nil {
+		return Stack{}, err
+	}
+	return stack, nil
+}
+
 func (s *authMW) CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (*Pkg, error) {
 	return s.next.CreatePkg(ctx, setters...)
 }
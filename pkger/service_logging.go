@@ -43,6 +43,22 @@ func (s *loggingMW) InitStack(ctx context.Context, userID influxdb.ID, newStack
 	return s.next.InitStack(ctx, userID, newStack)
 }
 
+func (s *loggingMW) ReadStack(ctx context.Context, id influxdb.ID) (stack Stack, err error) {
+	defer func(start time.Time) {
+		if err == nil {
+			return
+		}
+
+		s.logger.Error(
+			"failed to read stack",
+			zap.Error(err),
+			zap.Duration("took", time.Since(start)),
+			zap.Stringer("stackID", id),
+		)
+	}(time.Now())
+	return s.next.ReadStack(ctx, id)
+}
+
 func (s *loggingMW) CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (pkg *Pkg, err error) {
 	defer func(start time.Time) {
 		dur := zap.Duration("took", time.Since(start))
@@ -33,6 +33,12 @@ func (s *mwMetrics) InitStack(ctx context.Context, userID influxdb.ID, newStack
 	return stack, rec(err)
 }
 
+func (s *mwMetrics) ReadStack(ctx context.Context, id influxdb.ID) (Stack, error) {
+	rec := s.rec.Record("read_stack")
+	stack, err := s.next.ReadStack(ctx, id)
+	return stack, rec(err)
+}
+
 func (s *mwMetrics) CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (*Pkg, error) {
 	rec := s.rec.Record("create_pkg")
 	pkg, err := s.next.CreatePkg(ctx, setters...)
@@ -1428,6 +1428,78 @@ func TestService(t *testing.T) {
 				})
 			})
 		})
+
+		t.Run("stacks", func(t *testing.T) {
+			t.Run("records the applied resources on the stack", func(t *testing.T) {
+				testfileRunner(t, "testdata/bucket.yml", func(t *testing.T, pkg *Pkg) {
+					fakeBktSVC := mock.NewBucketService()
+					fakeBktSVC.CreateBucketFn = func(_ context.Context, b *influxdb.Bucket) error {
+						b.ID = influxdb.ID(b.RetentionPeriod)
+						return nil
+					}
+					fakeBktSVC.FindBucketByNameFn = func(_ context.Context, id influxdb.ID, s string) (*influxdb.Bucket, error) {
+						// forces the bucket to be created a new
+						return nil, errors.New("an error")
+					}
+					fakeBktSVC.UpdateBucketFn = func(_ context.Context, id influxdb.ID, upd influxdb.BucketUpdate) (*influxdb.Bucket, error) {
+						return &influxdb.Bucket{ID: id}, nil
+					}
+
+					orgID := influxdb.ID(9000)
+					now := time.Time{}.Add(10 * 24 * time.Hour)
+
+					var updated Stack
+					store := &fakeStore{
+						readFn: func(ctx context.Context, id influxdb.ID) (Stack, error) {
+							return Stack{ID: id, OrgID: orgID, Name: "threeve"}, nil
+						},
+						updateFn: func(ctx context.Context, stack Stack) error {
+							updated = stack
+							return nil
+						},
+					}
+
+					svc := newTestService(
+						WithBucketSVC(fakeBktSVC),
+						WithStore(store),
+						WithTimeGenerator(newTimeGen(now)),
+					)
+
+					_, err := svc.Apply(context.TODO(), orgID, 0, pkg, ApplyWithStackID(3))
+					require.NoError(t, err)
+
+					assert.Equal(t, influxdb.ID(3), updated.ID)
+					assert.Equal(t, "threeve", updated.Name)
+					assert.Equal(t, now, updated.UpdatedAt)
+					require.Len(t, updated.Resources, 2)
+					assert.Contains(t, updated.Resources, StackResource{
+						APIVersion: APIVersion,
+						ID:         influxdb.ID(time.Hour),
+						Kind:       KindBucket,
+						Name:       "rucket_11",
+					})
+				})
+			})
+
+			t.Run("fails when the stack belongs to another org", func(t *testing.T) {
+				testfileRunner(t, "testdata/bucket.yml", func(t *testing.T, pkg *Pkg) {
+					fakeBktSVC := mock.NewBucketService()
+
+					store := &fakeStore{
+						readFn: func(ctx context.Context, id influxdb.ID) (Stack, error) {
+							return Stack{ID: id, OrgID: 1}, nil
+						},
+					}
+
+					svc := newTestService(WithBucketSVC(fakeBktSVC), WithStore(store))
+
+					_, err := svc.Apply(context.TODO(), 9000, 0, pkg, ApplyWithStackID(3))
+					require.Error(t, err)
+					assert.Equal(t, influxdb.EConflict, influxdb.ErrorCode(err))
+					assert.Zero(t, fakeBktSVC.CreateBucketCalls.Count())
+				})
+			})
+		})
 	})
 
 	t.Run("CreatePkg", func(t *testing.T) {
@@ -3141,6 +3213,8 @@ func levelPtr(l notification.CheckLevel) *notification.CheckLevel {
 
 type fakeStore struct {
 	createFn func(ctx context.Context, stack Stack) error
+	readFn   func(ctx context.Context, id influxdb.ID) (Stack, error)
+	updateFn func(ctx context.Context, stack Stack) error
 }
 
 var _ Store = (*fakeStore)(nil)
@@ -3153,10 +3227,16 @@ func (s *fakeStore) CreateStack(ctx context.Context, stack Stack) error {
 }
 
 func (s *fakeStore) ReadStackByID(ctx context.Context, id influxdb.ID) (Stack, error) {
+	if s.readFn != nil {
+		return s.readFn(ctx, id)
+	}
 	panic("not implemented")
 }
 
 func (s *fakeStore) UpdateStack(ctx context.Context, stack Stack) error {
+	if s.updateFn != nil {
+		return s.updateFn(ctx, stack)
+	}
 	panic("not implemented")
 }
 
@@ -26,6 +26,13 @@ func (s *traceMW) InitStack(ctx context.Context, userID influxdb.ID, newStack St
 	return s.next.InitStack(ctx, userID, newStack)
 }
 
+func (s *traceMW) ReadStack(ctx context.Context, id influxdb.ID) (Stack, error) {
+	span, ctx := tracing.StartSpanFromContextWithOperationName(ctx, "ReadStack")
+	span.LogKV("stackID", id.String())
+	defer span.Finish()
+	return s.next.ReadStack(ctx, id)
+}
+
 func (s *traceMW) CreatePkg(ctx context.Context, setters ...CreatePkgSetFn) (pkg *Pkg, err error) {
 	span, ctx := tracing.StartSpanFromContextWithOperationName(ctx, "CreatePkg")
 	defer span.Finish()
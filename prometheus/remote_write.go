@@ -0,0 +1,248 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/pkg/protowire"
+)
+
+// MetricNameLabel is the label holding the name of a Prometheus time series.
+const MetricNameLabel = "__name__"
+
+// RemoteWriteValueField is the field the samples of a remote write request are
+// written to.
+const RemoteWriteValueField = "_value"
+
+// WriteRequest is a Prometheus remote write request. It holds the parts of the
+// prompb.WriteRequest protobuf message needed to write its samples; metadata
+// sent with the request is ignored.
+type WriteRequest struct {
+	TimeSeries []TimeSeries
+}
+
+// TimeSeries is a series of samples identified by its labels.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a name and value pair identifying a time series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a time series. Timestamp is in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// Protobuf field numbers of the remote write messages.
+const (
+	writeRequestTimeSeriesField = 1
+	timeSeriesLabelsField       = 1
+	timeSeriesSamplesField      = 2
+	labelNameField              = 1
+	labelValueField             = 2
+	sampleValueField            = 1
+	sampleTimestampField        = 2
+)
+
+// Unmarshal decodes the protobuf encoded remote write request in data, which
+// must already have been decompressed.
+func (r *WriteRequest) Unmarshal(data []byte) error {
+	r.TimeSeries = r.TimeSeries[:0]
+	d := protowire.NewDecoder(data)
+	for !d.Done() {
+		field, wire, err := d.Key()
+		if err != nil {
+			return err
+		}
+		if field != writeRequestTimeSeriesField || wire != protowire.BytesType {
+			if err := d.Skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := d.Bytes()
+		if err != nil {
+			return err
+		}
+		var ts TimeSeries
+		if err := ts.unmarshal(b); err != nil {
+			return err
+		}
+		r.TimeSeries = append(r.TimeSeries, ts)
+	}
+	return nil
+}
+
+func (ts *TimeSeries) unmarshal(data []byte) error {
+	d := protowire.NewDecoder(data)
+	for !d.Done() {
+		field, wire, err := d.Key()
+		if err != nil {
+			return err
+		}
+		if wire != protowire.BytesType || (field != timeSeriesLabelsField && field != timeSeriesSamplesField) {
+			if err := d.Skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := d.Bytes()
+		if err != nil {
+			return err
+		}
+		if field == timeSeriesLabelsField {
+			var l Label
+			if err := l.unmarshal(b); err != nil {
+				return err
+			}
+			ts.Labels = append(ts.Labels, l)
+		} else {
+			var s Sample
+			if err := s.unmarshal(b); err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+	}
+	return nil
+}
+
+func (l *Label) unmarshal(data []byte) error {
+	d := protowire.NewDecoder(data)
+	for !d.Done() {
+		field, wire, err := d.Key()
+		if err != nil {
+			return err
+		}
+		if wire != protowire.BytesType || (field != labelNameField && field != labelValueField) {
+			if err := d.Skip(wire); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := d.Bytes()
+		if err != nil {
+			return err
+		}
+		if field == labelNameField {
+			l.Name = string(b)
+		} else {
+			l.Value = string(b)
+		}
+	}
+	return nil
+}
+
+func (s *Sample) unmarshal(data []byte) error {
+	d := protowire.NewDecoder(data)
+	for !d.Done() {
+		field, wire, err := d.Key()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case field == sampleValueField && wire == protowire.Fixed64Type:
+			v, err := d.Double()
+			if err != nil {
+				return err
+			}
+			s.Value = v
+		case field == sampleTimestampField && wire == protowire.VarintType:
+			v, err := d.Varint()
+			if err != nil {
+				return err
+			}
+			s.Timestamp = int64(v)
+		default:
+			if err := d.Skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal returns the protobuf encoding of the remote write request.
+func (r *WriteRequest) Marshal() []byte {
+	var e protowire.Encoder
+	for _, ts := range r.TimeSeries {
+		var tse protowire.Encoder
+		for _, l := range ts.Labels {
+			var le protowire.Encoder
+			le.BytesField(labelNameField, []byte(l.Name))
+			le.BytesField(labelValueField, []byte(l.Value))
+			tse.BytesField(timeSeriesLabelsField, le.Bytes())
+		}
+		for _, s := range ts.Samples {
+			var se protowire.Encoder
+			se.Double(sampleValueField, s.Value)
+			se.Varint(sampleTimestampField, uint64(s.Timestamp))
+			tse.BytesField(timeSeriesSamplesField, se.Bytes())
+		}
+		e.BytesField(writeRequestTimeSeriesField, tse.Bytes())
+	}
+	return e.Bytes()
+}
+
+// LineProtocol appends the samples of the request to buf as line protocol.
+// The __name__ label of each series is its measurement and the other labels
+// are its tags. Each sample is written to the _value field at its timestamp,
+// in nanoseconds.
+//
+// Labels with an empty value are dropped, as are samples with a NaN or
+// infinite value, such as Prometheus staleness markers, as line protocol
+// cannot represent them. An error is returned for a series without a name.
+func (r *WriteRequest) LineProtocol(buf []byte) ([]byte, error) {
+	var tags []Label
+	for _, ts := range r.TimeSeries {
+		var name string
+		tags = tags[:0]
+		for _, l := range ts.Labels {
+			if l.Name == MetricNameLabel {
+				name = l.Value
+			} else if l.Value != "" {
+				tags = append(tags, l)
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("time series without a %s label", MetricNameLabel)
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+		for _, s := range ts.Samples {
+			if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+				continue
+			}
+
+			buf = append(buf, models.EscapeMeasurement([]byte(name))...)
+			for _, t := range tags {
+				buf = append(buf, ',')
+				buf = append(buf, escape.String(t.Name)...)
+				buf = append(buf, '=')
+				buf = append(buf, escape.String(t.Value)...)
+			}
+			buf = append(buf, ' ')
+			buf = append(buf, RemoteWriteValueField...)
+			buf = append(buf, '=')
+			buf = strconv.AppendFloat(buf, s.Value, 'f', -1, 64)
+			buf = append(buf, ' ')
+			buf = strconv.AppendInt(buf, s.Timestamp*1e6, 10)
+			buf = append(buf, '\n')
+		}
+	}
+	return buf, nil
+}
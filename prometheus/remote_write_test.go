@@ -0,0 +1,83 @@
+package prometheus_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	pr "github.com/influxdata/influxdb/prometheus"
+)
+
+func TestWriteRequest_Unmarshal(t *testing.T) {
+	// WriteRequest{timeseries: [{labels: [{__name__, up}], samples: [{1.5, 1000}]}]}
+	data := []byte{
+		0x0a, 0x1e, // timeseries
+		0x0a, 0x0e, // labels
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+		0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, // samples
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f,
+		0x10, 0xe8, 0x07,
+	}
+
+	var req pr.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := pr.WriteRequest{TimeSeries: []pr.TimeSeries{{
+		Labels:  []pr.Label{{Name: "__name__", Value: "up"}},
+		Samples: []pr.Sample{{Value: 1.5, Timestamp: 1000}},
+	}}}
+	if !reflect.DeepEqual(req, exp) {
+		t.Fatalf("got %+v, exp %+v", req, exp)
+	}
+
+	if got := exp.Marshal(); !reflect.DeepEqual(got, data) {
+		t.Fatalf("got encoding %x, exp %x", got, data)
+	}
+
+	if err := req.Unmarshal(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error decoding truncated request")
+	}
+}
+
+func TestWriteRequest_LineProtocol(t *testing.T) {
+	req := pr.WriteRequest{TimeSeries: []pr.TimeSeries{
+		{
+			Labels: []pr.Label{
+				{Name: "job", Value: "node"},
+				{Name: "__name__", Value: "node_load1"},
+				{Name: "instance", Value: "host a"},
+				{Name: "empty", Value: ""},
+			},
+			Samples: []pr.Sample{
+				{Value: 0.5, Timestamp: 1000},
+				{Value: math.NaN(), Timestamp: 2000},
+				{Value: 1e21, Timestamp: 3000},
+			},
+		},
+		{
+			Labels:  []pr.Label{{Name: "__name__", Value: "up"}},
+			Samples: []pr.Sample{{Value: 1, Timestamp: 1000}},
+		},
+	}}
+
+	got, err := req.LineProtocol(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `node_load1,instance=host\ a,job=node _value=0.5 1000000000
+node_load1,instance=host\ a,job=node _value=1000000000000000000000 3000000000
+up _value=1 1000000000
+`
+	if string(got) != exp {
+		t.Fatalf("got\n%s\nexp\n%s", got, exp)
+	}
+
+	req.TimeSeries[1].Labels = nil
+	if _, err := req.LineProtocol(nil); err == nil {
+		t.Fatal("expected error for series without a name")
+	}
+}
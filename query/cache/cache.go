@@ -0,0 +1,243 @@
+// Package cache implements a cache of encoded query results.
+//
+// Results are cached for a fixed time to live, keyed by the organization,
+// authorization, query and dialect of the request. The buckets a query reads
+// are recorded as it executes, so that its results can be invalidated when
+// one of those buckets is written to or deleted from. All the results of an
+// organization are invalidated when its secrets change.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultMaxBytes is the default size of the cache.
+const DefaultMaxBytes = 256 << 20
+
+// Config configures a Cache.
+type Config struct {
+	// TTL is how long results are cached. A TTL of zero disables the cache.
+	TTL time.Duration
+
+	// MaxBytes is the total size of the cached results, beyond which the
+	// least recently used results are evicted.
+	MaxBytes int
+}
+
+// NewConfig returns a Config with the default size and caching disabled.
+func NewConfig() Config {
+	return Config{MaxBytes: DefaultMaxBytes}
+}
+
+// Enabled reports whether the configuration enables the cache.
+func (c Config) Enabled() bool {
+	return c.TTL > 0 && c.MaxBytes > 0
+}
+
+type key [sha256.Size]byte
+
+type orgBucket struct {
+	org, bucket platform.ID
+}
+
+type entry struct {
+	key     key
+	org     platform.ID
+	buckets []orgBucket
+	result  []byte
+	expires time.Time
+}
+
+// Cache holds the encoded results of queries.
+type Cache struct {
+	config Config
+
+	mu       sync.Mutex
+	entries  map[key]*list.Element
+	lru      *list.List // of *entry, most recently used first
+	size     int
+	buckets  map[orgBucket]map[key]struct{} // the entries that read each bucket
+	orgs     map[platform.ID]map[key]struct{}   // the entries of each organization
+	epoch    uint64                         // incremented on every invalidation
+	hits     prometheus.Counter
+	misses   prometheus.Counter
+	sizeDesc *prometheus.Desc
+
+	now func() time.Time
+}
+
+// New returns a new Cache.
+func New(config Config) *Cache {
+	const namespace, subsystem = "query", "result_cache"
+	return &Cache{
+		config:  config,
+		entries: make(map[key]*list.Element),
+		lru:     list.New(),
+		buckets: make(map[orgBucket]map[key]struct{}),
+		orgs:    make(map[platform.ID]map[key]struct{}),
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "hits_total",
+			Help:      "Number of queries answered from the result cache.",
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "misses_total",
+			Help:      "Number of cacheable queries not found in the result cache.",
+		}),
+		sizeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "bytes"),
+			"Size of the results held by the result cache.",
+			nil, nil,
+		),
+		now: time.Now,
+	}
+}
+
+// PrometheusCollectors returns the metrics of the cache.
+func (c *Cache) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{c.hits, c.misses, (*sizeCollector)(c)}
+}
+
+type sizeCollector Cache
+
+func (c *sizeCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.sizeDesc }
+
+func (c *sizeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	size := c.size
+	c.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(c.sizeDesc, prometheus.GaugeValue, float64(size))
+}
+
+// get returns the result cached for k.
+func (c *Cache) get(k key) (result []byte, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el := c.entries[k]
+	if el == nil {
+		c.misses.Inc()
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		c.misses.Inc()
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	c.hits.Inc()
+	return e.result, true
+}
+
+// currentEpoch returns a token to pass to put for a query that is about to
+// be executed.
+func (c *Cache) currentEpoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// put caches the result of a query of org that read buckets. The result is dropped
+// if any part of the cache was invalidated since epoch was obtained, as the
+// query may have read data that has since changed.
+func (c *Cache) put(k key, org platform.ID, epoch uint64, buckets []orgBucket, result []byte) {
+	size := entrySize(buckets, result)
+	if size > c.config.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch != epoch {
+		return
+	}
+	if el := c.entries[k]; el != nil {
+		c.remove(el)
+	}
+
+	e := &entry{
+		key:     k,
+		org:     org,
+		buckets: buckets,
+		result:  result,
+		expires: c.now().Add(c.config.TTL),
+	}
+	c.entries[k] = c.lru.PushFront(e)
+	c.size += size
+	for _, b := range buckets {
+		keys := c.buckets[b]
+		if keys == nil {
+			keys = make(map[key]struct{})
+			c.buckets[b] = keys
+		}
+		keys[k] = struct{}{}
+	}
+	keys := c.orgs[org]
+	if keys == nil {
+		keys = make(map[key]struct{})
+		c.orgs[org] = keys
+	}
+	keys[k] = struct{}{}
+
+	for c.size > c.config.MaxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes el from the cache. The caller must hold c.mu.
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	c.size -= entrySize(e.buckets, e.result)
+	for _, b := range e.buckets {
+		if keys := c.buckets[b]; len(keys) > 1 {
+			delete(keys, e.key)
+		} else {
+			delete(c.buckets, b)
+		}
+	}
+	if keys := c.orgs[e.org]; len(keys) > 1 {
+		delete(keys, e.key)
+	} else {
+		delete(c.orgs, e.org)
+	}
+}
+
+// InvalidateBucket removes the results of the queries that read bucket.
+func (c *Cache) InvalidateBucket(org, bucket platform.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for k := range c.buckets[orgBucket{org: org, bucket: bucket}] {
+		c.remove(c.entries[k])
+	}
+}
+
+// InvalidateOrg removes the results of the queries of org.
+func (c *Cache) InvalidateOrg(org platform.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for k := range c.orgs[org] {
+		c.remove(c.entries[k])
+	}
+}
+
+// entrySize estimates the memory held by an entry.
+func entrySize(buckets []orgBucket, result []byte) int {
+	const overhead = 256 // the entry, its list element and map entries
+	return overhead + len(buckets)*16 + len(result)
+}
@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/memory"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+const (
+	orgID    = platform.ID(0x10)
+	bucketID = platform.ID(0x20)
+)
+
+// countingReader counts the reads made of the storage engine.
+type countingReader struct {
+	influxdb.Reader
+	reads int
+}
+
+func (r *countingReader) ReadFilter(ctx context.Context, spec influxdb.ReadFilterSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	r.reads++
+	return nil, nil
+}
+
+// fakeQueryService reads bucketID through reader and returns the number of
+// reads made so far as the result.
+type fakeQueryService struct {
+	reader  influxdb.Reader
+	engine  *countingReader
+	writer  func(ctx context.Context) error
+	failing bool
+}
+
+func newFakeQueryService() *fakeQueryService {
+	engine := &countingReader{}
+	return &fakeQueryService{reader: NewReader(engine), engine: engine}
+}
+
+func (s *fakeQueryService) Query(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+	spec := influxdb.ReadFilterSpec{OrganizationID: orgID, BucketID: bucketID}
+	if _, err := s.reader.ReadFilter(ctx, spec, nil); err != nil {
+		return flux.Statistics{}, err
+	}
+	if s.writer != nil {
+		if err := s.writer(ctx); err != nil {
+			return flux.Statistics{}, err
+		}
+	}
+	fmt.Fprintf(w, "reads=%d", s.engine.reads)
+	if s.failing {
+		return flux.Statistics{}, fmt.Errorf("query failed")
+	}
+	return flux.Statistics{TotalDuration: time.Second}, nil
+}
+
+func (s *fakeQueryService) Check(ctx context.Context) check.Response {
+	return check.Response{Status: check.StatusPass}
+}
+
+func newRequest(org platform.ID, q string) *query.ProxyRequest {
+	return &query.ProxyRequest{
+		Request: query.Request{
+			Authorization:  &platform.Authorization{ID: 1},
+			OrganizationID: org,
+			Compiler:       lang.FluxCompiler{Query: q, Now: time.Now()},
+		},
+		Dialect: &csv.Dialect{},
+	}
+}
+
+func mustQuery(t *testing.T, s query.ProxyQueryService, req *query.ProxyRequest) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := s.Query(context.Background(), &buf, req); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func mustWrite(t *testing.T, ctx context.Context, w storage.PointsWriter, org, bucket platform.ID) {
+	t.Helper()
+	pt := models.MustNewPoint(tsdb.EncodeNameString(org, bucket), models.NewTags(map[string]string{
+		models.MeasurementTagKey: "cpu",
+		models.FieldKeyTagKey:    "value",
+	}), models.Fields{"value": 1.0}, time.Unix(0, 0))
+	if err := w.WritePoints(ctx, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type nopPointsWriter struct{}
+
+func (nopPointsWriter) WritePoints(context.Context, []models.Point) error { return nil }
+
+func TestCache_ProxyQueryService(t *testing.T) {
+	c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+	fs := newFakeQueryService()
+	s := c.ProxyQueryService(fs)
+	w := c.PointsWriter(nopPointsWriter{})
+
+	req := newRequest(orgID, `from(bucket: "b") |> range(start: -1m)`)
+	if got, exp := mustQuery(t, s, req), "reads=1"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+
+	// The same query made later is answered from the cache.
+	req = newRequest(orgID, `from(bucket: "b") |> range(start: -1m)`)
+	if got, exp := mustQuery(t, s, req), "reads=1"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+	if got := fs.engine.reads; got != 1 {
+		t.Fatalf("expected the second query not to read the storage engine, got %d reads", got)
+	}
+
+	// Other queries, organizations and authorizations are not.
+	if got, exp := mustQuery(t, s, newRequest(orgID, `from(bucket: "c")`)), "reads=2"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+	if got, exp := mustQuery(t, s, newRequest(orgID+1, `from(bucket: "b") |> range(start: -1m)`)), "reads=3"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+	req = newRequest(orgID, `from(bucket: "b") |> range(start: -1m)`)
+	req.Request.Authorization.ID = 2
+	if got, exp := mustQuery(t, s, req), "reads=4"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+
+	// Writing to another bucket leaves the result cached.
+	mustWrite(t, context.Background(), w, orgID, bucketID+1)
+	if got, exp := mustQuery(t, s, newRequest(orgID, `from(bucket: "b") |> range(start: -1m)`)), "reads=1"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+
+	// Writing to the bucket read by the query invalidates its result.
+	mustWrite(t, context.Background(), w, orgID, bucketID)
+	if got, exp := mustQuery(t, s, newRequest(orgID, `from(bucket: "b") |> range(start: -1m)`)), "reads=5"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	s := c.ProxyQueryService(newFakeQueryService())
+
+	mustQuery(t, s, newRequest(orgID, "q"))
+	now = now.Add(59 * time.Second)
+	if got, exp := mustQuery(t, s, newRequest(orgID, "q")), "reads=1"; got != exp {
+		t.Fatalf("unexpected result within TTL: got %q, exp %q", got, exp)
+	}
+	now = now.Add(time.Second)
+	if got, exp := mustQuery(t, s, newRequest(orgID, "q")), "reads=2"; got != exp {
+		t.Fatalf("unexpected result after TTL: got %q, exp %q", got, exp)
+	}
+}
+
+func TestCache_MaxBytes(t *testing.T) {
+	// Room for two results.
+	c := New(Config{TTL: time.Minute, MaxBytes: 2 * entrySize([]orgBucket{{}}, []byte("reads=1"))})
+	s := c.ProxyQueryService(newFakeQueryService())
+
+	mustQuery(t, s, newRequest(orgID, "a"))
+	mustQuery(t, s, newRequest(orgID, "b"))
+	mustQuery(t, s, newRequest(orgID, "a")) // a is now the most recently used
+	mustQuery(t, s, newRequest(orgID, "c")) // evicts b
+
+	if got, exp := mustQuery(t, s, newRequest(orgID, "a")), "reads=1"; got != exp {
+		t.Errorf("unexpected result of a: got %q, exp %q", got, exp)
+	}
+	if got, exp := mustQuery(t, s, newRequest(orgID, "b")), "reads=4"; got != exp {
+		t.Errorf("unexpected result of b: got %q, exp %q", got, exp)
+	}
+}
+
+func TestCache_NotCached(t *testing.T) {
+	t.Run("failed", func(t *testing.T) {
+		c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+		fs := newFakeQueryService()
+		fs.failing = true
+		s := c.ProxyQueryService(fs)
+
+		for i := 0; i < 2; i++ {
+			if _, err := s.Query(context.Background(), ioutil.Discard, newRequest(orgID, "q")); err == nil {
+				t.Fatal("expected error")
+			}
+		}
+		if got := fs.engine.reads; got != 2 {
+			t.Fatalf("expected failed queries not to be cached, got %d reads", got)
+		}
+	})
+
+	t.Run("wrote points", func(t *testing.T) {
+		c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+		fs := newFakeQueryService()
+		w := c.PointsWriter(nopPointsWriter{})
+		fs.writer = func(ctx context.Context) error {
+			mustWrite(t, ctx, w, orgID, bucketID+1)
+			return nil
+		}
+		s := c.ProxyQueryService(fs)
+
+		mustQuery(t, s, newRequest(orgID, "q"))
+		mustQuery(t, s, newRequest(orgID, "q"))
+		if got := fs.engine.reads; got != 2 {
+			t.Fatalf("expected queries that write not to be cached, got %d reads", got)
+		}
+	})
+
+	t.Run("invalidated during query", func(t *testing.T) {
+		c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+		fs := newFakeQueryService()
+		fs.writer = func(ctx context.Context) error {
+			// A write made outside of the query while it executes.
+			mustWrite(t, context.Background(), c.PointsWriter(nopPointsWriter{}), orgID, bucketID)
+			return nil
+		}
+		s := c.ProxyQueryService(fs)
+
+		mustQuery(t, s, newRequest(orgID, "q"))
+		fs.writer = nil
+		mustQuery(t, s, newRequest(orgID, "q"))
+		if got := fs.engine.reads; got != 2 {
+			t.Fatalf("expected the result not to be cached, got %d reads", got)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		c := New(Config{TTL: time.Minute, MaxBytes: entrySize([]orgBucket{{}}, nil) + 1})
+		fs := newFakeQueryService()
+		s := c.ProxyQueryService(fs)
+
+		mustQuery(t, s, newRequest(orgID, "q"))
+		if got, exp := mustQuery(t, s, newRequest(orgID, "q")), "reads=2"; got != exp {
+			t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+		}
+	})
+}
+
+func TestCache_DeleteService(t *testing.T) {
+	c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+	s := c.ProxyQueryService(newFakeQueryService())
+	d := c.DeleteService(nopDeleteService{})
+
+	mustQuery(t, s, newRequest(orgID, "q"))
+	if err := d.DeleteBucketRangePredicate(context.Background(), orgID, bucketID, 0, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := mustQuery(t, s, newRequest(orgID, "q")), "reads=2"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+}
+
+type nopDeleteService struct{}
+
+func (nopDeleteService) DeleteBucketRangePredicate(context.Context, platform.ID, platform.ID, int64, int64, platform.Predicate) error {
+	return nil
+}
+
+func TestCache_SecretService(t *testing.T) {
+	const otherOrgID = platform.ID(0x11)
+
+	c := New(Config{TTL: time.Minute, MaxBytes: DefaultMaxBytes})
+	s := c.ProxyQueryService(newFakeQueryService())
+	secrets := c.SecretService(nopSecretService{})
+
+	mustQuery(t, s, newRequest(orgID, "q"))
+	mustQuery(t, s, newRequest(otherOrgID, "q"))
+	if err := secrets.PutSecret(context.Background(), orgID, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := mustQuery(t, s, newRequest(orgID, "q")), "reads=3"; got != exp {
+		t.Fatalf("unexpected result: got %q, exp %q", got, exp)
+	}
+	if got, exp := mustQuery(t, s, newRequest(otherOrgID, "q")), "reads=2"; got != exp {
+		t.Fatalf("unexpected result of other org: got %q, exp %q", got, exp)
+	}
+}
+
+type nopSecretService struct {
+	platform.SecretService
+}
+
+func (nopSecretService) PutSecret(context.Context, platform.ID, string, string) error {
+	return nil
+}
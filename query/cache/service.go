@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/memory"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/influxql"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// ProxyQueryService returns a query.ProxyQueryService that answers queries
+// from the cache, executing them with s when their result is not cached.
+//
+// Only the storage reads of queries executed by s using a reader returned
+// by NewReader are recorded, so s must use one for the results of queries
+// to be invalidated when their buckets are written to.
+func (c *Cache) ProxyQueryService(s query.ProxyQueryService) query.ProxyQueryService {
+	return &proxyQueryService{cache: c, s: s}
+}
+
+type proxyQueryService struct {
+	cache *Cache
+	s     query.ProxyQueryService
+}
+
+func (s *proxyQueryService) Query(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+	k, err := requestKey(req)
+	if err != nil {
+		return s.s.Query(ctx, w, req)
+	}
+
+	if result, ok := s.cache.get(k); ok {
+		// The storage engine was not read, so there are no statistics.
+		_, err := w.Write(result)
+		return flux.Statistics{}, err
+	}
+
+	epoch := s.cache.currentEpoch()
+	rec := &recorder{}
+	buf := &limitedBuffer{max: s.cache.config.MaxBytes}
+	stats, err := s.s.Query(context.WithValue(ctx, recorderKey, rec), io.MultiWriter(w, buf), req)
+	if err != nil {
+		return stats, err
+	}
+
+	// The result of a query that wrote points, such as with to(), is not
+	// cached, as doing so would skip the writes when it is next made. As
+	// writes invalidate the cache, put drops it.
+	if !buf.overflow {
+		s.cache.put(k, req.Request.OrganizationID, epoch, rec.result(), buf.Bytes())
+	}
+	return stats, nil
+}
+
+func (s *proxyQueryService) Check(ctx context.Context) check.Response {
+	return s.s.Check(ctx)
+}
+
+// requestKey returns the key of the result of req, which is a hash of its
+// organization, authorization, query and dialect.
+func requestKey(req *query.ProxyRequest) (key, error) {
+	// The time a query is made is part of its compiler, so that relative
+	// time ranges are fixed. It is left out of the key, so the results of
+	// such queries are cached for the TTL.
+	compiler := req.Request.Compiler
+	switch c := compiler.(type) {
+	case lang.FluxCompiler:
+		c.Now = time.Time{}
+		compiler = c
+	case lang.ASTCompiler:
+		c.Now = time.Time{}
+		compiler = c
+	case *influxql.Compiler:
+		cc := *c
+		cc.Now = nil
+		compiler = &cc
+	}
+
+	h := sha256.New()
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(req.Request.OrganizationID))
+	h.Write(b[:])
+
+	// Authorizations in the same organization may read different buckets,
+	// so results are not shared between them.
+	var authID platform.ID
+	if a := req.Request.Authorization; a != nil {
+		authID = a.ID
+	}
+	binary.BigEndian.PutUint64(b[:], uint64(authID))
+	h.Write(b[:])
+
+	enc := json.NewEncoder(h)
+	for _, v := range []interface{}{
+		compiler.CompilerType(),
+		compiler,
+		req.Dialect.DialectType(),
+		req.Dialect,
+	} {
+		if err := enc.Encode(v); err != nil {
+			return key{}, err
+		}
+	}
+
+	var k key
+	h.Sum(k[:0])
+	return k, nil
+}
+
+type contextKey int
+
+const recorderKey contextKey = 0
+
+// recorder records the buckets read by a query.
+type recorder struct {
+	mu      sync.Mutex
+	buckets []orgBucket
+}
+
+func recorderFromContext(ctx context.Context) *recorder {
+	rec, _ := ctx.Value(recorderKey).(*recorder)
+	return rec
+}
+
+func (r *recorder) read(org, bucket platform.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := orgBucket{org: org, bucket: bucket}
+	for _, v := range r.buckets {
+		if v == b {
+			return
+		}
+	}
+	r.buckets = append(r.buckets, b)
+}
+
+func (r *recorder) result() []orgBucket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buckets
+}
+
+// limitedBuffer buffers up to max bytes, recording whether more were
+// written.
+type limitedBuffer struct {
+	bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if !b.overflow {
+		if b.Len()+len(p) > b.max {
+			b.overflow = true
+			b.Reset()
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// NewReader returns a reader that records the buckets read by queries made
+// through a Cache.
+func NewReader(r influxdb.Reader) influxdb.Reader {
+	return &reader{Reader: r}
+}
+
+type reader struct {
+	influxdb.Reader
+}
+
+func (r *reader) ReadFilter(ctx context.Context, spec influxdb.ReadFilterSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	recordRead(ctx, spec)
+	return r.Reader.ReadFilter(ctx, spec, alloc)
+}
+
+func (r *reader) ReadGroup(ctx context.Context, spec influxdb.ReadGroupSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	recordRead(ctx, spec.ReadFilterSpec)
+	return r.Reader.ReadGroup(ctx, spec, alloc)
+}
+
+func (r *reader) ReadTagKeys(ctx context.Context, spec influxdb.ReadTagKeysSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	recordRead(ctx, spec.ReadFilterSpec)
+	return r.Reader.ReadTagKeys(ctx, spec, alloc)
+}
+
+func (r *reader) ReadTagValues(ctx context.Context, spec influxdb.ReadTagValuesSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	recordRead(ctx, spec.ReadFilterSpec)
+	return r.Reader.ReadTagValues(ctx, spec, alloc)
+}
+
+func recordRead(ctx context.Context, spec influxdb.ReadFilterSpec) {
+	if rec := recorderFromContext(ctx); rec != nil {
+		rec.read(spec.OrganizationID, spec.BucketID)
+	}
+}
+
+// PointsWriter returns a storage.PointsWriter that invalidates the results
+// of the queries that read the buckets written to by w.
+func (c *Cache) PointsWriter(w storage.PointsWriter) storage.PointsWriter {
+	return &pointsWriter{cache: c, w: w}
+}
+
+type pointsWriter struct {
+	cache *Cache
+	w     storage.PointsWriter
+}
+
+func (w *pointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	var buckets []orgBucket
+	for _, p := range points {
+		org, bucket := tsdb.DecodeNameSlice(p.Name())
+		if n := len(buckets); n == 0 || buckets[n-1] != (orgBucket{org: org, bucket: bucket}) {
+			buckets = append(buckets, orgBucket{org: org, bucket: bucket})
+		}
+	}
+
+	// Invalidate once the points are written, so that a query made in the
+	// meantime cannot cache a result without them. Some points may have
+	// been written even if the write fails.
+	err := w.w.WritePoints(ctx, points)
+	for _, b := range buckets {
+		w.cache.InvalidateBucket(b.org, b.bucket)
+	}
+	return err
+}
+
+// DeleteService returns a platform.DeleteService that invalidates the
+// results of the queries that read the buckets deleted from by s.
+func (c *Cache) DeleteService(s platform.DeleteService) platform.DeleteService {
+	return &deleteService{cache: c, s: s}
+}
+
+type deleteService struct {
+	cache *Cache
+	s     platform.DeleteService
+}
+
+func (s *deleteService) DeleteBucketRangePredicate(ctx context.Context, orgID, bucketID platform.ID, min, max int64, pred platform.Predicate) error {
+	err := s.s.DeleteBucketRangePredicate(ctx, orgID, bucketID, min, max, pred)
+	s.cache.InvalidateBucket(orgID, bucketID)
+	return err
+}
+
+// SecretService returns a platform.SecretService that invalidates the
+// results of the queries of an organization when s changes its secrets, as
+// queries may read them.
+func (c *Cache) SecretService(s platform.SecretService) platform.SecretService {
+	return &secretService{SecretService: s, cache: c}
+}
+
+type secretService struct {
+	platform.SecretService
+	cache *Cache
+}
+
+func (s *secretService) PutSecret(ctx context.Context, orgID platform.ID, k string, v string) error {
+	err := s.SecretService.PutSecret(ctx, orgID, k, v)
+	s.cache.InvalidateOrg(orgID)
+	return err
+}
+
+func (s *secretService) PutSecrets(ctx context.Context, orgID platform.ID, m map[string]string) error {
+	err := s.SecretService.PutSecrets(ctx, orgID, m)
+	s.cache.InvalidateOrg(orgID)
+	return err
+}
+
+func (s *secretService) PatchSecrets(ctx context.Context, orgID platform.ID, m map[string]string) error {
+	err := s.SecretService.PatchSecrets(ctx, orgID, m)
+	s.cache.InvalidateOrg(orgID)
+	return err
+}
+
+func (s *secretService) DeleteSecret(ctx context.Context, orgID platform.ID, ks ...string) error {
+	err := s.SecretService.DeleteSecret(ctx, orgID, ks...)
+	s.cache.InvalidateOrg(orgID)
+	return err
+}
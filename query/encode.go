@@ -13,15 +13,25 @@ const (
 	NoContentWErrDialectType = "no-content-with-error"
 )
 
-// AddDialectMappings adds the mappings for the no-content dialects.
+// AddDialectMappings adds the mappings for the no-content, Parquet and table JSON dialects.
 func AddDialectMappings(mappings flux.DialectMappings) error {
 	if err := mappings.Add(NoContentDialectType, func() flux.Dialect {
 		return NewNoContentDialect()
 	}); err != nil {
 		return err
 	}
-	return mappings.Add(NoContentWErrDialectType, func() flux.Dialect {
+	if err := mappings.Add(NoContentWErrDialectType, func() flux.Dialect {
 		return NewNoContentWithErrorDialect()
+	}); err != nil {
+		return err
+	}
+	if err := mappings.Add(ParquetDialectType, func() flux.Dialect {
+		return NewParquetDialect()
+	}); err != nil {
+		return err
+	}
+	return mappings.Add(TableJSONDialectType, func() flux.Dialect {
+		return NewTableJSONDialect()
 	})
 }
 
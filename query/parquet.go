@@ -0,0 +1,175 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/parquet"
+)
+
+const (
+	ParquetDialectType = "parquet"
+
+	// DefaultParquetMaxRows is the largest number of rows a Parquet file
+	// written for a query may have.
+	DefaultParquetMaxRows = 1000000
+)
+
+// ParquetDialect is a dialect that encodes query results as a Parquet file.
+// It is an HTTPDialect that sends the file as an attachment.
+type ParquetDialect struct {
+	// MaxRows is the largest number of rows the file may have. Queries with
+	// more rows fail, as the results are held in memory until all have been
+	// read. Zero means no limit.
+	MaxRows int
+}
+
+func NewParquetDialect() *ParquetDialect {
+	return &ParquetDialect{MaxRows: DefaultParquetMaxRows}
+}
+
+func (d *ParquetDialect) Encoder() flux.MultiResultEncoder {
+	return &ParquetEncoder{MaxRows: d.MaxRows}
+}
+
+func (d *ParquetDialect) DialectType() flux.DialectType {
+	return ParquetDialectType
+}
+
+func (d *ParquetDialect) SetHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="result.parquet"`)
+}
+
+// ParquetEncoder writes the tables of all results to a single Parquet file,
+// with one column per distinct table column. Rows of tables that lack a
+// column are null in that column. String columns that are part of a group
+// key, such as tags, are dictionary encoded.
+//
+// As the schema of a Parquet file is only known once all tables have been
+// read, the results are buffered in memory and nothing is written to w if
+// the query fails. Results with more than MaxRows rows fail with an
+// ETooLarge error, unless MaxRows is zero.
+type ParquetEncoder struct {
+	MaxRows int
+}
+
+func (e *ParquetEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	defer results.Release()
+
+	var (
+		columns []parquet.Column
+		indexes = make(map[string]int)
+		rows    [][]interface{}
+	)
+	for results.More() {
+		if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			cols := tbl.Cols()
+			idx := make([]int, len(cols))
+			for j, c := range cols {
+				typ, err := parquetType(c.Type)
+				if err != nil {
+					return fmt.Errorf("column %q: %v", c.Label, err)
+				}
+				i, ok := indexes[c.Label]
+				if !ok {
+					i = len(columns)
+					indexes[c.Label] = i
+					columns = append(columns, parquet.Column{Name: c.Label, Type: typ})
+				} else if columns[i].Type != typ {
+					return fmt.Errorf("column %q has conflicting types %s and %s", c.Label, columns[i].Type, typ)
+				}
+				if typ == parquet.String && tbl.Key().HasCol(c.Label) {
+					columns[i].Dictionary = true
+				}
+				idx[j] = i
+			}
+
+			return tbl.Do(func(cr flux.ColReader) error {
+				if e.MaxRows > 0 && len(rows)+cr.Len() > e.MaxRows {
+					return &influxdb.Error{
+						Code: influxdb.ETooLarge,
+						Msg:  fmt.Sprintf("query results exceed the limit of %d rows of the parquet format; narrow the query or use the csv format", e.MaxRows),
+					}
+				}
+				for i := 0; i < cr.Len(); i++ {
+					row := make([]interface{}, len(columns))
+					for j := range cols {
+						row[idx[j]] = parquetValue(cr, i, j)
+					}
+					rows = append(rows, row)
+				}
+				return nil
+			})
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if err := results.Err(); err != nil {
+		return 0, err
+	}
+
+	pw := parquet.NewWriter(columns)
+	for _, row := range rows {
+		// Rows of earlier tables may lack columns added by later ones.
+		if len(row) < len(columns) {
+			row = append(row, make([]interface{}, len(columns)-len(row))...)
+		}
+		if err := pw.WriteRow(row); err != nil {
+			return 0, err
+		}
+	}
+	return pw.WriteTo(w)
+}
+
+func parquetType(typ flux.ColType) (parquet.Type, error) {
+	switch typ {
+	case flux.TBool:
+		return parquet.Boolean, nil
+	case flux.TInt:
+		return parquet.Int64, nil
+	case flux.TUInt:
+		return parquet.Uint64, nil
+	case flux.TFloat:
+		return parquet.Double, nil
+	case flux.TString:
+		return parquet.String, nil
+	case flux.TTime:
+		return parquet.Timestamp, nil
+	}
+	return 0, fmt.Errorf("unsupported column type %s", typ)
+}
+
+func parquetValue(cr flux.ColReader, i, j int) interface{} {
+	switch cr.Cols()[j].Type {
+	case flux.TBool:
+		if vs := cr.Bools(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TInt:
+		if vs := cr.Ints(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TUInt:
+		if vs := cr.UInts(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TFloat:
+		if vs := cr.Floats(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TString:
+		if vs := cr.Strings(j); vs.IsValid(i) {
+			return vs.ValueString(i)
+		}
+	case flux.TTime:
+		if vs := cr.Times(j); vs.IsValid(i) {
+			return time.Unix(0, vs.Value(i)).UTC()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package query_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/parquet"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestParquetEncoder_Encode(t *testing.T) {
+	results := []flux.Result{
+		executetest.NewResult([]*executetest.Table{
+			{
+				KeyCols: []string{"_measurement", "host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_measurement", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1000), "cpu", "a", 1.5},
+					{execute.Time(2000), "cpu", "a", nil},
+				},
+			},
+			{
+				KeyCols: []string{"_measurement", "host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_measurement", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "region", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(3000), "cpu", "b", 2.5, "east"},
+				},
+			},
+		}),
+	}
+
+	var buf bytes.Buffer
+	if _, err := query.NewParquetDialect().Encoder().Encode(&buf, flux.NewSliceResultIterator(results)); err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []parquet.Column{
+		{Name: "_time", Type: parquet.Timestamp},
+		{Name: "_measurement", Type: parquet.String, Dictionary: true},
+		{Name: "host", Type: parquet.String, Dictionary: true},
+		{Name: "_value", Type: parquet.Double},
+		{Name: "region", Type: parquet.String},
+	}
+	ts := func(ns int64) time.Time { return time.Unix(0, ns).UTC() }
+	rows := [][]interface{}{
+		{ts(1000), "cpu", "a", 1.5, nil},
+		{ts(2000), "cpu", "a", nil, nil},
+		{ts(3000), "cpu", "b", 2.5, "east"},
+	}
+	w := parquet.NewWriter(columns)
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var exp bytes.Buffer
+	if _, err := w.WriteTo(&exp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), exp.Bytes()) {
+		t.Errorf("unexpected file:\ngot  %x\nexp  %x", buf.Bytes(), exp.Bytes())
+	}
+}
+
+func TestParquetEncoder_Encode_MaxRows(t *testing.T) {
+	results := []flux.Result{
+		executetest.NewResult([]*executetest.Table{
+			{
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+				},
+				Data: [][]interface{}{
+					{execute.Time(1000), 1.0},
+					{execute.Time(2000), 2.0},
+					{execute.Time(3000), 3.0},
+				},
+			},
+		}),
+	}
+
+	var buf bytes.Buffer
+	enc := &query.ParquetEncoder{MaxRows: 2}
+	_, err := enc.Encode(&buf, flux.NewSliceResultIterator(results))
+	if got, exp := influxdb.ErrorCode(err), influxdb.ETooLarge; got != exp {
+		t.Fatalf("unexpected error code: got %q, exp %q: %v", got, exp, err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing to be written, got %d bytes", buf.Len())
+	}
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/iocounter"
+)
+
+const TableJSONDialectType = "table-json"
+
+// TableJSONDialect is a dialect that encodes each table of the query results
+// as a line of JSON, so that a client can process the tables as they are
+// produced.
+type TableJSONDialect struct{}
+
+func NewTableJSONDialect() *TableJSONDialect {
+	return &TableJSONDialect{}
+}
+
+func (d *TableJSONDialect) Encoder() flux.MultiResultEncoder {
+	return &TableJSONEncoder{}
+}
+
+func (d *TableJSONDialect) DialectType() flux.DialectType {
+	return TableJSONDialectType
+}
+
+func (d *TableJSONDialect) SetHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+}
+
+// TableJSON is a table of the query results encoded by a TableJSONEncoder.
+type TableJSON struct {
+	Type    string            `json:"type"`
+	Result  string            `json:"result"`
+	Table   int               `json:"table"`
+	Columns []TableJSONColumn `json:"columns"`
+	Rows    [][]interface{}   `json:"rows"`
+}
+
+// TableJSONColumn is a column of a TableJSON. Group is whether the column is
+// part of the group key of the table.
+type TableJSONColumn struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Group bool   `json:"group"`
+}
+
+// TableJSONEncoder writes each table of the results to w as a TableJSON of
+// type "table" followed by a newline, in a single write. Times are encoded
+// as RFC3339 strings.
+type TableJSONEncoder struct{}
+
+func (e *TableJSONEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	defer results.Release()
+
+	cw := &iocounter.Writer{Writer: w}
+	table := 0
+	for results.More() {
+		res := results.Next()
+		if err := res.Tables().Do(func(tbl flux.Table) error {
+			t := TableJSON{
+				Type:   "table",
+				Result: res.Name(),
+				Table:  table,
+				Rows:   [][]interface{}{},
+			}
+			table++
+
+			cols := tbl.Cols()
+			for _, c := range cols {
+				t.Columns = append(t.Columns, TableJSONColumn{
+					Name:  c.Label,
+					Type:  c.Type.String(),
+					Group: tbl.Key().HasCol(c.Label),
+				})
+			}
+			if err := tbl.Do(func(cr flux.ColReader) error {
+				for i := 0; i < cr.Len(); i++ {
+					row := make([]interface{}, len(cols))
+					for j := range cols {
+						row[j] = tableJSONValue(cr, i, j)
+					}
+					t.Rows = append(t.Rows, row)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			b, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			_, err = cw.Write(append(b, '\n'))
+			return err
+		}); err != nil {
+			return cw.Count(), err
+		}
+	}
+	return cw.Count(), results.Err()
+}
+
+func tableJSONValue(cr flux.ColReader, i, j int) interface{} {
+	if cr.Cols()[j].Type == flux.TTime {
+		if vs := cr.Times(j); vs.IsValid(i) {
+			return time.Unix(0, vs.Value(i)).UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+	}
+	return parquetValue(cr, i, j)
+}
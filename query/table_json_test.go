@@ -0,0 +1,66 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/influxdb/query"
+)
+
+// writeRecorder records the writes made to it.
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestTableJSONEncoder_Encode(t *testing.T) {
+	result := executetest.NewResult([]*executetest.Table{
+		{
+			KeyCols: []string{"host"},
+			ColMeta: []flux.ColMeta{
+				{Label: "_time", Type: flux.TTime},
+				{Label: "host", Type: flux.TString},
+				{Label: "_value", Type: flux.TFloat},
+			},
+			Data: [][]interface{}{
+				{execute.Time(1000), "a", 1.5},
+				{execute.Time(2000), "a", nil},
+			},
+		},
+		{
+			KeyCols: []string{"host"},
+			ColMeta: []flux.ColMeta{
+				{Label: "host", Type: flux.TString},
+				{Label: "count", Type: flux.TInt},
+			},
+			Data: [][]interface{}{
+				{"b", int64(3)},
+			},
+		},
+	})
+	result.Nm = "_result"
+
+	var w writeRecorder
+	n, err := query.NewTableJSONDialect().Encoder().Encode(&w, flux.NewSliceResultIterator([]flux.Result{result}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		`{"type":"table","result":"_result","table":0,"columns":[{"name":"_time","type":"time","group":false},{"name":"host","type":"string","group":true},{"name":"_value","type":"float","group":false}],"rows":[["1970-01-01T00:00:00.000001Z","a",1.5],["1970-01-01T00:00:00.000002Z","a",null]]}` + "\n",
+		`{"type":"table","result":"_result","table":1,"columns":[{"name":"host","type":"string","group":true},{"name":"count","type":"int","group":false}],"rows":[["b",3]]}` + "\n",
+	}
+	if !cmp.Equal(w.writes, exp) {
+		t.Errorf("unexpected writes -got/+exp\n%s", cmp.Diff(w.writes, exp))
+	}
+	if exp := int64(len(exp[0]) + len(exp[1])); n != exp {
+		t.Errorf("unexpected number of bytes written: got %d, exp %d", n, exp)
+	}
+}
@@ -0,0 +1,11 @@
+package influxdb
+
+import "context"
+
+// ReadinessService reports whether the storage engine is ready to accept
+// writes, for readiness probes.
+type ReadinessService interface {
+	// Ready returns the reason the storage engine is not ready to accept
+	// writes, or nil if it is ready.
+	Ready(ctx context.Context) error
+}
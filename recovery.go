@@ -0,0 +1,30 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// RecoveryService reports the data that the storage engine skipped because it
+// was corrupt when it started in recovery mode.
+type RecoveryService interface {
+	// RecoveryReport returns the files skipped when the engine was opened.
+	RecoveryReport(ctx context.Context) (*RecoveryReport, error)
+}
+
+// RecoveryReport lists the corrupt files skipped by the storage engine, so
+// that operators know which data to restore.
+type RecoveryReport struct {
+	RecoveryMode bool          `json:"recoveryMode"`
+	SkippedFiles []SkippedFile `json:"skippedFiles"`
+}
+
+// SkippedFile is a corrupt file skipped by the storage engine. The time range
+// of its data is unknown if the file is too corrupt to read it.
+type SkippedFile struct {
+	Path    string     `json:"path"`
+	Size    int64      `json:"size"`
+	MinTime *time.Time `json:"minTime,omitempty"`
+	MaxTime *time.Time `json:"maxTime,omitempty"`
+	Error   string     `json:"error"`
+}
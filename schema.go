@@ -0,0 +1,198 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// ErrMeasurementSchemaNotFound is the error msg for a measurement without a
+// registered schema.
+const ErrMeasurementSchemaNotFound = "measurement schema not found"
+
+// SchemaService registers the schemas of measurements and validates the
+// points written to them.
+type SchemaService interface {
+	// CreateMeasurementSchema registers the schema of a measurement in a
+	// bucket. A measurement has at most one schema.
+	CreateMeasurementSchema(ctx context.Context, orgID, bucketID ID, schema MeasurementSchema) error
+
+	// FindMeasurementSchema returns the schema of a measurement in a bucket.
+	FindMeasurementSchema(ctx context.Context, orgID, bucketID ID, measurement string) (*MeasurementSchema, error)
+
+	// ValidatePoint returns an EInvalid error if the fields of p do not match
+	// the schema of its measurement, or an ENotFound error if the measurement
+	// has no schema.
+	//
+	// p is a point as written to the storage engine, whose measurement is
+	// the value of its models.MeasurementTagKey tag.
+	ValidatePoint(ctx context.Context, orgID, bucketID ID, p models.Point) error
+}
+
+// SchemaFieldType is the type of a field of a measurement schema.
+type SchemaFieldType string
+
+// The types of the fields of a measurement schema.
+const (
+	SchemaFieldTypeFloat    SchemaFieldType = "float"
+	SchemaFieldTypeInteger  SchemaFieldType = "integer"
+	SchemaFieldTypeUnsigned SchemaFieldType = "unsigned"
+	SchemaFieldTypeString   SchemaFieldType = "string"
+	SchemaFieldTypeBoolean  SchemaFieldType = "boolean"
+)
+
+// schemaFieldTypes maps the field types of points to those of schemas.
+var schemaFieldTypes = map[models.FieldType]SchemaFieldType{
+	models.Float:    SchemaFieldTypeFloat,
+	models.Integer:  SchemaFieldTypeInteger,
+	models.Unsigned: SchemaFieldTypeUnsigned,
+	models.String:   SchemaFieldTypeString,
+	models.Boolean:  SchemaFieldTypeBoolean,
+}
+
+// SchemaFieldTypeOf returns the schema field type of the field type of points.
+func SchemaFieldTypeOf(typ models.FieldType) SchemaFieldType {
+	return schemaFieldTypes[typ]
+}
+
+// Valid returns an error if t is not a known field type.
+func (t SchemaFieldType) Valid() error {
+	switch t {
+	case SchemaFieldTypeFloat, SchemaFieldTypeInteger, SchemaFieldTypeUnsigned, SchemaFieldTypeString, SchemaFieldTypeBoolean:
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("invalid field type %q", string(t)),
+	}
+}
+
+// MeasurementSchemaField is a field of a measurement schema.
+type MeasurementSchemaField struct {
+	Name string          `json:"name"`
+	Type SchemaFieldType `json:"type"`
+}
+
+// MeasurementSchema is the schema of a measurement, which lists the fields
+// that may be written to it.
+type MeasurementSchema struct {
+	Measurement string                   `json:"measurement"`
+	Fields      []MeasurementSchemaField `json:"fields"`
+
+	// AllowAdditionalFields permits writing fields that are not listed.
+	AllowAdditionalFields bool `json:"allowAdditionalFields"`
+}
+
+// Valid returns an error if the schema is missing its measurement, or has
+// fields that are unnamed, duplicated or of an unknown type.
+func (s *MeasurementSchema) Valid() error {
+	if s.Measurement == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "measurement schema must have a measurement",
+		}
+	}
+
+	names := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.Name == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "measurement schema fields must have a name",
+			}
+		}
+		if names[f.Name] {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("measurement schema has duplicate field %q", f.Name),
+			}
+		}
+		names[f.Name] = true
+		if err := f.Type.Valid(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateField returns an EInvalid error if a field named name of type typ
+// may not be written to the measurement.
+func (s *MeasurementSchema) ValidateField(name string, typ models.FieldType) error {
+	for _, f := range s.Fields {
+		if f.Name != name {
+			continue
+		}
+		if got := schemaFieldTypes[typ]; got != f.Type {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("field '%s' of measurement '%s' has type %s but received %s", name, s.Measurement, f.Type, got),
+			}
+		}
+		return nil
+	}
+
+	if s.AllowAdditionalFields {
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("field '%s' is not in the schema of measurement '%s'", name, s.Measurement),
+	}
+}
+
+// BucketSchemaService reads the schema of the data stored in a bucket.
+type BucketSchemaService interface {
+	// BucketSchema returns the measurements stored in a bucket, with the
+	// types of their fields and their tag keys, sorted by name.
+	BucketSchema(ctx context.Context, orgID, bucketID ID) ([]BucketSchemaMeasurement, error)
+
+	// TagValueHistogram returns the topN values of the tag key of a
+	// measurement stored in a bucket, with the number of series with data
+	// within the time range (start, end] having each, sorted by descending
+	// count. All the values are returned if topN is not positive.
+	TagValueHistogram(ctx context.Context, orgID, bucketID ID, measurement, tagKey string, start, end int64, topN int) ([]TagValueCount, error)
+}
+
+// TagValueCount is the number of series with a value of a tag key.
+type TagValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// BucketSchemaMeasurement is the schema of a measurement stored in a bucket.
+// Its fields are those stored, which are not checked against the schema
+// registered for the measurement, if any.
+type BucketSchemaMeasurement struct {
+	MeasurementSchema
+	TagKeys []string `json:"tagKeys"`
+}
+
+// BucketSchema is a document of the schema of the data of a bucket, exported
+// from one instance to be imported as measurement schemas into another.
+type BucketSchema struct {
+	GeneratedAt   time.Time                 `json:"generatedAt"`
+	SourceVersion string                    `json:"sourceVersion"`
+	Measurements  []BucketSchemaMeasurement `json:"measurements"`
+}
+
+// Valid returns an error if a measurement of the schema is invalid or
+// duplicated.
+func (s *BucketSchema) Valid() error {
+	names := make(map[string]bool, len(s.Measurements))
+	for i := range s.Measurements {
+		m := &s.Measurements[i].MeasurementSchema
+		if err := m.Valid(); err != nil {
+			return err
+		}
+		if names[m.Measurement] {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("bucket schema has duplicate measurement %q", m.Measurement),
+			}
+		}
+		names[m.Measurement] = true
+	}
+	return nil
+}
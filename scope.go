@@ -0,0 +1,49 @@
+package influxdb
+
+import (
+	"fmt"
+)
+
+// ScopeMapping maps OAuth 2.0 scope strings, such as "read:buckets", to the
+// permissions they grant. Permissions without an OrgID are granted within the
+// organization of the authorization that holds the scope.
+type ScopeMapping map[string][]Permission
+
+// DefaultScopeMapping returns a ScopeMapping with a "read:<type>" and a
+// "write:<type>" scope for every resource type that belongs to an organization.
+func DefaultScopeMapping() ScopeMapping {
+	m := make(ScopeMapping, 2*len(OrgResourceTypes))
+	for _, t := range OrgResourceTypes {
+		for _, a := range []Action{ReadAction, WriteAction} {
+			m[fmt.Sprintf("%s:%s", a, t)] = []Permission{{
+				Action:   a,
+				Resource: Resource{Type: t},
+			}}
+		}
+	}
+	return m
+}
+
+// Permissions returns the permissions granted by scopes within the
+// organization orgID. An error is returned if a scope is not in the mapping.
+func (m ScopeMapping) Permissions(orgID ID, scopes []string) ([]Permission, error) {
+	var ps []Permission
+	for _, scope := range scopes {
+		sps, ok := m[scope]
+		if !ok {
+			return nil, &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("unknown scope %q", scope),
+			}
+		}
+
+		for _, p := range sps {
+			if p.Resource.OrgID == nil {
+				id := orgID
+				p.Resource.OrgID = &id
+			}
+			ps = append(ps, p)
+		}
+	}
+	return ps, nil
+}
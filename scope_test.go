@@ -0,0 +1,73 @@
+package influxdb_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestScopeMapping_Permissions(t *testing.T) {
+	orgID := influxdb.ID(1)
+	otherOrgID := influxdb.ID(2)
+
+	m := influxdb.DefaultScopeMapping()
+	m["read:everything-in-2"] = []influxdb.Permission{{
+		Action:   influxdb.ReadAction,
+		Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &otherOrgID},
+	}}
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []influxdb.Permission
+		err    bool
+	}{
+		{
+			name:   "default scopes are granted in the org",
+			scopes: []string{"read:buckets", "write:dashboards"},
+			want: []influxdb.Permission{
+				{
+					Action:   influxdb.ReadAction,
+					Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: influxdbtesting.IDPtr(1)},
+				},
+				{
+					Action:   influxdb.WriteAction,
+					Resource: influxdb.Resource{Type: influxdb.DashboardsResourceType, OrgID: influxdbtesting.IDPtr(1)},
+				},
+			},
+		},
+		{
+			name:   "configured org is kept",
+			scopes: []string{"read:everything-in-2"},
+			want: []influxdb.Permission{
+				{
+					Action:   influxdb.ReadAction,
+					Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: influxdbtesting.IDPtr(2)},
+				},
+			},
+		},
+		{
+			name:   "unknown scope",
+			scopes: []string{"read:buckets", "admin"},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Permissions(orgID, tt.scopes)
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("permissions are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+
+	if m["read:buckets"][0].Resource.OrgID != nil {
+		t.Error("mapping should not be modified")
+	}
+}
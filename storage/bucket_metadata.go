@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// BucketMetadataFileName is the name of the file in the engine's directory
+// holding the metadata of buckets written by WriteMetadata.
+const BucketMetadataFileName = "bucket_metadata.json"
+
+var _ influxdb.BucketMetadataService = (*Engine)(nil)
+
+// WriteMetadata replaces the metadata of the bucket, such as its owner, with
+// metadata. An empty metadata removes that of the bucket.
+//
+// The metadata is stored in the engine's directory apart from the data of
+// the bucket, so it is not part of its schema.
+func (e *Engine) WriteMetadata(ctx context.Context, orgID, bucketID influxdb.ID, metadata map[string]string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.metadataMu.Lock()
+	defer e.metadataMu.Unlock()
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	prev, ok := e.metadata.buckets[name]
+	if !ok && len(metadata) == 0 {
+		return nil
+	}
+
+	e.metadata.set(name, metadata)
+	if err := e.metadata.save(); err != nil {
+		e.metadata.set(name, prev)
+		return err
+	}
+	return nil
+}
+
+// ReadMetadata returns the metadata of the bucket written by WriteMetadata,
+// which is empty if it has none.
+func (e *Engine) ReadMetadata(ctx context.Context, orgID, bucketID influxdb.ID) (map[string]string, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	e.metadataMu.Lock()
+	defer e.metadataMu.Unlock()
+
+	metadata := make(map[string]string)
+	for k, v := range e.metadata.buckets[tsdb.EncodeName(orgID, bucketID)] {
+		metadata[k] = v
+	}
+	return metadata, nil
+}
+
+// bucketMetadataRecord is the metadata of a bucket in the file of the
+// metadata of buckets.
+type bucketMetadataRecord struct {
+	OrgID    influxdb.ID       `json:"orgID"`
+	BucketID influxdb.ID       `json:"bucketID"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// bucketMetadata is the metadata of buckets written by WriteMetadata.
+//
+// bucketMetadata is not safe for concurrent use.
+type bucketMetadata struct {
+	path    string
+	buckets map[[16]byte]map[string]string
+}
+
+// loadBucketMetadata loads the metadata in the file at path, which need not
+// exist.
+func loadBucketMetadata(path string) (*bucketMetadata, error) {
+	m := &bucketMetadata{path: path, buckets: make(map[[16]byte]map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []bucketMetadataRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		m.set(tsdb.EncodeName(r.OrgID, r.BucketID), r.Metadata)
+	}
+	return m, nil
+}
+
+// save writes the metadata to its file.
+func (m *bucketMetadata) save() error {
+	records := m.list()
+	if len(records) == 0 {
+		if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// list returns the metadata of each bucket, ordered by bucket.
+func (m *bucketMetadata) list() []bucketMetadataRecord {
+	var records []bucketMetadataRecord
+	for name, metadata := range m.buckets {
+		org, bucket := tsdb.DecodeName(name)
+		records = append(records, bucketMetadataRecord{OrgID: org, BucketID: bucket, Metadata: metadata})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		x, y := records[i], records[j]
+		if x.OrgID != y.OrgID {
+			return x.OrgID < y.OrgID
+		}
+		return x.BucketID < y.BucketID
+	})
+	return records
+}
+
+// set replaces the metadata of the bucket with a copy of metadata, or
+// removes it if metadata is empty.
+func (m *bucketMetadata) set(name [16]byte, metadata map[string]string) {
+	if len(metadata) == 0 {
+		delete(m.buckets, name)
+		return
+	}
+
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	m.buckets[name] = copied
+}
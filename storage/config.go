@@ -12,7 +12,7 @@ import (
 
 // Default configuration values.
 const (
-	DefaultRetentionInterval       = time.Hour
+	DefaultRetentionInterval       = 30 * time.Minute
 	DefaultSeriesFileDirectoryName = "_series"
 	DefaultIndexDirectoryName      = "index"
 	DefaultWALDirectoryName        = "wal"
@@ -41,6 +41,10 @@ type Config struct {
 	// Index config.
 	Index     tsi1.Config `toml:"index"`
 	IndexPath string      `toml:"index-path"` // Overrides the default path.
+
+	// Measurements whose writes and reads are counted by their own metrics.
+	// The activity of other measurements is counted together.
+	MetricsMeasurementAllowlist []string `toml:"metrics-measurement-allowlist"`
 }
 
 // NewConfig initialises a new config for an Engine.
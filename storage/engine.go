@@ -62,6 +62,37 @@ type Engine struct {
 	retentionEnforcer        runner
 	retentionEnforcerLimiter runnable
 
+	// validators are called in order on the points of each write.
+	validators []WriteValidator
+
+	// sideEffects are called asynchronously with the points of each write.
+	sideEffects []*writeSideEffect
+
+	// writeSubs are the channels of the subscribers of write events.
+	writeSubsMu sync.RWMutex
+	writeSubs   map[<-chan WriteEvent]chan WriteEvent
+
+	// eventLoggers are called synchronously with each write and delete.
+	eventLoggers []EventLogger
+
+	// writeLatency holds the durations of the most recent writes.
+	writeLatency *writeLatencyWindow
+
+	// measurements tracks the writes and reads of each measurement.
+	measurements *measurementTracker
+
+	// renameMu is held for reading by writes, and for writing while
+	// measurements are renamed or rewritten.
+	renameMu sync.RWMutex
+	aliasMu  sync.RWMutex
+	aliases  *measurementAliases // measurements renamed by MeasurementRename
+	rewriteC chan struct{}       // signals the data of renamed measurements to be rewritten
+
+	walPruneC chan struct{} // signals the WAL exceeds its maximum disk size
+
+	metadataMu sync.Mutex
+	metadata   *bucketMetadata // metadata of buckets written by WriteMetadata
+
 	defaultMetricLabels prometheus.Labels
 
 	// Tracks all goroutines started by the Engine.
@@ -125,6 +156,14 @@ func WithRetentionEnforcerLimiter(f runnable) Option {
 	}
 }
 
+// WithWriteValidator adds v to the validators called on the points of each
+// write, as RegisterWriteValidator does.
+func WithWriteValidator(v WriteValidator) Option {
+	return func(e *Engine) {
+		e.validators = append(e.validators, v)
+	}
+}
+
 // WithFileStoreObserver makes the engine have the provided file store observer.
 func WithFileStoreObserver(obs tsm1.FileStoreObserver) Option {
 	return func(e *Engine) {
@@ -163,6 +202,10 @@ func NewEngine(path string, c Config, options ...Option) *Engine {
 		config:              c,
 		path:                path,
 		defaultMetricLabels: prometheus.Labels{},
+		validators:          []WriteValidator{NoopWriteValidator{}},
+		rewriteC:            make(chan struct{}, 1),
+		walPruneC:           make(chan struct{}, 1),
+		writeLatency:        &writeLatencyWindow{},
 		logger:              zap.NewNop(),
 	}
 
@@ -180,15 +223,23 @@ func NewEngine(path string, c Config, options ...Option) *Engine {
 	e.wal.SetEnabled(c.WAL.Enabled)
 
 	// Initialise Engine
-	e.engine = tsm1.NewEngine(c.GetEnginePath(path), e.index, c.Engine, tsm1.WithSnapshotter(e))
+	e.engine = tsm1.NewEngine(c.GetEnginePath(path), e.index, c.Engine, tsm1.WithSnapshotter(e),
+		tsm1.WithFullCompactionObserver(e.scheduleMeasurementRewrite))
 
 	// Apply options.
 	for _, option := range options {
 		option(e)
 	}
 
+	// Enforce policies registered with SetRetentionPolicy even without a
+	// bucket service.
+	if e.retentionEnforcer == nil {
+		e.retentionEnforcer = newRetentionEnforcer(e, e.engine, nil)
+	}
+
 	// Set default metrics labels.
 	e.engine.SetDefaultMetricLabels(e.defaultMetricLabels)
+	e.measurements = newMeasurementTracker(e.defaultMetricLabels, c.MetricsMeasurementAllowlist)
 	e.sfile.SetDefaultMetricLabels(e.defaultMetricLabels)
 	e.index.SetDefaultMetricLabels(e.defaultMetricLabels)
 	e.wal.SetDefaultMetricLabels(e.defaultMetricLabels)
@@ -230,6 +281,8 @@ func (e *Engine) PrometheusCollectors() []prometheus.Collector {
 	metrics = append(metrics, tsm1.PrometheusCollectors()...)
 	metrics = append(metrics, wal.PrometheusCollectors()...)
 	metrics = append(metrics, RetentionPrometheusCollectors()...)
+	metrics = append(metrics, MeasurementPrometheusCollectors()...)
+	metrics = append(metrics, newWriteLatencyCollector(e))
 	return metrics
 }
 
@@ -256,6 +309,20 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 		return err
 	}
 
+	if e.aliases, err = loadMeasurementAliases(filepath.Join(e.path, MeasurementAliasesFileName)); err != nil {
+		return err
+	}
+
+	if e.metadata, err = loadBucketMetadata(filepath.Join(e.path, BucketMetadataFileName)); err != nil {
+		return err
+	}
+
+	if r, ok := e.retentionEnforcer.(*retentionEnforcer); ok {
+		if r.Policies, err = loadRetentionPolicies(filepath.Join(e.path, RetentionPoliciesFileName)); err != nil {
+			return err
+		}
+	}
+
 	if err := e.replayWAL(); err != nil {
 		return err
 	}
@@ -269,9 +336,64 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 		e.runRetentionEnforcer()
 	}
 
+	if d := time.Duration(e.config.Engine.Cache.WarmupDuration); d > 0 {
+		e.runCacheWarmup(d)
+	}
+	e.runMeasurementRewriter()
+
+	if e.config.WAL.Enabled && e.config.WAL.MaxDiskBytes > 0 {
+		e.runWALPruner()
+	}
+
+	for _, s := range e.sideEffects {
+		e.runWriteSideEffect(s)
+	}
+
 	return nil
 }
 
+// runCacheWarmup loads the data written within d from TSM files into the cache
+// in the background, so that opening the engine is not blocked. The warmup is
+// stopped when the engine is closed.
+func (e *Engine) runCacheWarmup(d time.Duration) {
+	// Stop below the snapshot threshold so the warmup does not cause the values
+	// it loads to be written straight back to disk.
+	cache := e.config.Engine.Cache
+	limit := uint64(cache.MaxMemorySize)
+	if sz := uint64(cache.SnapshotMemorySize); sz > 0 && (limit == 0 || sz < limit) {
+		limit = sz
+	}
+
+	l := e.logger.With(zap.String("component", "cache_warmup"), logger.DurationLiteral("duration", d))
+	l.Info("Starting")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.wg.Add(2)
+	go func() {
+		defer e.wg.Done()
+		// It's safe to read closing without a lock because it's never
+		// modified if this goroutine is active.
+		select {
+		case <-e.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		defer e.wg.Done()
+		defer cancel()
+
+		start := time.Now()
+		n, err := e.engine.WarmCache(ctx, start.Add(-d).UnixNano(), limit)
+		if err != nil && ctx.Err() == nil {
+			l.Error("Failed to warm cache", zap.Error(err))
+			return
+		}
+		l.Info("Finished", zap.Uint64("bytes_loaded", n), logger.DurationLiteral("elapsed", time.Since(start)))
+	}()
+}
+
 // replayWAL reads the WAL segment files and replays them.
 func (e *Engine) replayWAL() error {
 	if !e.config.WAL.Enabled {
@@ -431,7 +553,22 @@ func (e *Engine) CreateSeriesCursor(ctx context.Context, orgID, bucketID influxd
 		return nil, ErrEngineClosed
 	}
 
-	return newSeriesCursor(orgID, bucketID, e.index, e.sfile, cond)
+	e.measurements.AddRead(cond)
+
+	renamed := e.renamedMeasurements(orgID, bucketID)
+	if renamed == nil {
+		return newSeriesCursor(orgID, bucketID, e.index, e.sfile, cond)
+	}
+
+	cond = rewriteMeasurementExpr(renamed, cond)
+	if b, ok := cond.(*influxql.BooleanLiteral); ok && b.Val {
+		cond = nil
+	}
+	cur, err := newSeriesCursor(orgID, bucketID, e.index, e.sfile, cond)
+	if err != nil {
+		return nil, err
+	}
+	return &renamedSeriesCursor{SeriesCursor: cur, renamed: renamed}, nil
 }
 
 // CreateCursorIterator creates a CursorIterator for usage with the read service.
@@ -441,7 +578,14 @@ func (e *Engine) CreateCursorIterator(ctx context.Context) (cursors.CursorIterat
 	if e.closing == nil {
 		return nil, ErrEngineClosed
 	}
-	return e.engine.CreateCursorIterator(ctx)
+	itr, err := e.engine.CreateCursorIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stored := e.storedMeasurements(); itr != nil && stored != nil {
+		return &renamedCursorIterator{CursorIterator: itr, stored: stored}, nil
+	}
+	return itr, nil
 }
 
 // WritePoints writes the provided points to the engine.
@@ -450,11 +594,20 @@ func (e *Engine) CreateCursorIterator(ctx context.Context) (cursors.CursorIterat
 // However, WritePoints will determine if any tag key-pairs are missing, or if
 // there are any field type conflicts.
 //
-// Appropriate errors are returned in those cases.
+// Appropriate errors are returned in those cases. The points that are valid are
+// written even if others are not, as with the zero WriteOptions of
+// WritePointsWithOptions.
 func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
+	return e.writePoints(ctx, points, WriteOptions{})
+}
+
+// writePoints writes the provided points, in the form the engine stores them,
+// with the given options.
+func (e *Engine) writePoints(ctx context.Context, points []models.Point, opts WriteOptions) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
+	start := time.Now()
 	collection, j := tsdb.NewSeriesCollection(points), 0
 
 	// dropPoint should be called whenever there is reason to drop a point from
@@ -514,6 +667,16 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 	}
 	collection.Truncate(j)
 
+	if opts.RejectPartial && collection.Dropped > 0 {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("write rejected: %d invalid points: %s", collection.Dropped, collection.Reason),
+		}
+	}
+
+	e.renameMu.RLock()
+	defer e.renameMu.RUnlock()
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -521,18 +684,59 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 		return ErrEngineClosed
 	}
 
+	validators := e.validators
+	if opts.Validator != nil {
+		validators = append(validators[:len(validators):len(validators)], opts.Validator)
+	}
+	for _, v := range validators {
+		if err := v.Validate(collection.Points); err != nil {
+			if _, ok := err.(*influxdb.Error); !ok {
+				err = &influxdb.Error{Code: influxdb.EInvalid, Err: err}
+			}
+			return err
+		}
+	}
+
+	e.storeRenamedMeasurements(collection)
+
 	// Convert the collection to values for adding to the WAL/Cache.
 	values, err := tsm1.CollectionToValues(collection)
 	if err != nil {
 		return err
 	}
 
+	if err := e.engine.CheckFieldTypes(values); err != nil {
+		return err
+	}
+
 	// Add the write to the WAL to be replayed if there is a crash or shutdown.
 	if _, err := e.wal.WriteMulti(ctx, values); err != nil {
 		return err
 	}
+	e.signalWALPrune()
 
-	return e.writePointsLocked(ctx, collection, values)
+	// The points that were not dropped are written even if some were.
+	err = e.writePointsLocked(ctx, collection, values)
+	if _, ok := err.(tsdb.PartialWriteError); err != nil && !ok {
+		return err
+	}
+
+	e.queueWriteSideEffects(collection.Points)
+	e.publishWriteEvents(collection.Points)
+	e.logWriteEvents(collection.Points)
+	e.measurements.AddWrite(collection.Points)
+	e.writeLatency.record(time.Since(start))
+	return err
+}
+
+// RegisterWriteValidator adds v to the validators called on the points of
+// each write before they are written to the WAL. Validators are called in
+// the order they were registered, and the first error rejects the write.
+// Validators that do not return an *influxdb.Error reject it as invalid.
+func (e *Engine) RegisterWriteValidator(v WriteValidator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.validators = append(e.validators, v)
 }
 
 // writePointsLocked does the work of writing points and must be called under some sort of lock.
@@ -605,7 +809,30 @@ func (e *Engine) CommitSegments(ctx context.Context, segs []string, fn func() er
 func (e *Engine) DeleteBucket(ctx context.Context, orgID, bucketID influxdb.ID) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
-	return e.DeleteBucketRange(ctx, orgID, bucketID, math.MinInt64, math.MaxInt64)
+	if err := e.DeleteBucketRange(ctx, orgID, bucketID, math.MinInt64, math.MaxInt64); err != nil {
+		return err
+	}
+	// Values written to the bucket while it was deleted must not linger.
+	if err := e.ClearCache(ctx, orgID, bucketID); err != nil {
+		return err
+	}
+	return e.WriteMetadata(ctx, orgID, bucketID, nil)
+}
+
+// ClearCache removes all the values of a bucket from the write cache. It is
+// safe to call while points are written.
+func (e *Engine) ClearCache(ctx context.Context, orgID, bucketID influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.engine.ClearCache(ctx, orgID, bucketID)
+	return nil
 }
 
 // DeleteBucketRange deletes an entire bucket from the storage engine.
@@ -624,7 +851,11 @@ func (e *Engine) DeleteBucketRange(ctx context.Context, orgID, bucketID influxdb
 		return err
 	}
 
-	return e.deleteBucketRangeLocked(ctx, orgID, bucketID, min, max, nil)
+	if err := e.deleteBucketRangeLocked(ctx, orgID, bucketID, min, max, nil); err != nil {
+		return err
+	}
+	e.logDeleteEvent(orgID, bucketID, nil, min, max)
+	return nil
 }
 
 // DeleteBucketRangePredicate deletes data within a bucket from the storage engine. Any data
@@ -654,16 +885,82 @@ func (e *Engine) DeleteBucketRangePredicate(ctx context.Context, orgID, bucketID
 		return err
 	}
 
-	return e.deleteBucketRangeLocked(ctx, orgID, bucketID, min, max, pred)
+	if err := e.deleteBucketRangeLocked(ctx, orgID, bucketID, min, max, pred); err != nil {
+		return err
+	}
+	e.logDeleteEvent(orgID, bucketID, pred, min, max)
+	return nil
+}
+
+// BatchDeleteSeries deletes all the data of the series of a bucket with the
+// provided keys, which are series keys as written to the engine. The series
+// are deleted together by a single tombstone in each TSM file, rather than one
+// delete for each series.
+func (e *Engine) BatchDeleteSeries(ctx context.Context, orgID, bucketID influxdb.ID, seriesKeys []string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if len(seriesKeys) == 0 {
+		return nil
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := models.EscapeMeasurement(encoded[:])
+	keys := make([][]byte, 0, len(seriesKeys))
+	for _, key := range seriesKeys {
+		if len(key) <= len(name) || !bytes.HasPrefix([]byte(key), name) || key[len(name)] != ',' {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("series key %q is not in bucket %s", key, bucketID),
+			}
+		}
+		keys = append(keys, []byte(key))
+	}
+	pred := tsm1.NewSeriesKeysPredicate(keys)
+
+	predData, err := pred.Marshal()
+	if err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	// Add the delete to the WAL to be replayed if there is a crash or shutdown.
+	if _, err := e.wal.DeleteBucketRange(orgID, bucketID, math.MinInt64, math.MaxInt64, predData); err != nil {
+		return err
+	}
+
+	// Only the keys sharing the prefix of all the series need to be checked
+	// against the predicate.
+	if err := e.engine.DeletePrefixRange(ctx, seriesKeysPrefix(keys), math.MinInt64, math.MaxInt64, pred); err != nil {
+		return err
+	}
+	e.logDeleteEvent(orgID, bucketID, pred, math.MinInt64, math.MaxInt64)
+	return nil
+}
+
+// seriesKeysPrefix returns the longest common prefix of the series keys.
+func seriesKeysPrefix(keys [][]byte) []byte {
+	prefix := keys[0]
+	for _, key := range keys[1:] {
+		n := 0
+		for n < len(prefix) && n < len(key) && prefix[n] == key[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
 }
 
 // deleteBucketRangeLocked does the work of deleting a bucket range and must be called under
 // some sort of lock.
 func (e *Engine) deleteBucketRangeLocked(ctx context.Context, orgID, bucketID influxdb.ID, min, max int64, pred tsm1.Predicate) error {
-	// TODO(edd): we need to clean up how we're encoding the prefix so that we
-	// don't have to remember to get it right everywhere we need to touch TSM data.
 	encoded := tsdb.EncodeName(orgID, bucketID)
-	name := models.EscapeMeasurement(encoded[:])
+	name := tsm1.BucketPrefix(encoded[:])
 
 	return e.engine.DeletePrefixRange(ctx, name, min, max, pred)
 }
@@ -771,6 +1068,181 @@ func (e *Engine) InternalBackupPath(backupID int) string {
 	return e.engine.FileStore.InternalBackupPath(backupID)
 }
 
+// FlushCache synchronously writes the contents of the cache to TSM files. It is
+// intended for tests and administration.
+func (e *Engine) FlushCache(ctx context.Context) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	// The lock must not be held while flushing, as the snapshot acquires the
+	// WAL segments under it.
+	e.mu.RLock()
+	closing := e.closing
+	e.mu.RUnlock()
+	if closing == nil {
+		return ErrEngineClosed
+	}
+	return e.engine.FlushCache(ctx)
+}
+
+// Ready returns the reason the engine is not ready to accept writes, or nil if
+// it is ready: it must be open with an initialized cache, its WAL directory
+// must be writable, and one of its TSM files must be readable.
+func (e *Engine) Ready(ctx context.Context) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+	if e.engine.Cache == nil {
+		return errors.New("cache is not initialized")
+	}
+
+	if e.config.WAL.Enabled {
+		f, err := ioutil.TempFile(e.wal.Path(), ".ready")
+		if err != nil {
+			return fmt.Errorf("WAL directory is not writable: %v", err)
+		}
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			return fmt.Errorf("WAL directory is not writable: %v", err)
+		}
+	}
+
+	if stats := e.engine.FileStore.Stats(); len(stats) > 0 {
+		f, err := os.Open(stats[0].Path)
+		if err != nil {
+			return fmt.Errorf("TSM file is not readable: %v", err)
+		}
+		defer f.Close()
+		var header [5]byte
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			return fmt.Errorf("TSM file %s is not readable: %v", stats[0].Path, err)
+		}
+	}
+	return nil
+}
+
+// Defragment rewrites the TSM files holding the data of the bucket into fully
+// compacted files, reclaiming the space of deleted data.
+func (e *Engine) Defragment(ctx context.Context, orgID, bucketID influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	// The lock must not be held while defragmenting, which may take a long
+	// time and must not block snapshots.
+	e.mu.RLock()
+	closing := e.closing
+	e.mu.RUnlock()
+	if closing == nil {
+		return ErrEngineClosed
+	}
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	return e.engine.Defragment(ctx, name[:])
+}
+
+// RecoveryReport returns the corrupt TSM files that were skipped when the
+// engine was opened in recovery mode.
+func (e *Engine) RecoveryReport(ctx context.Context) (*influxdb.RecoveryReport, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	report := &influxdb.RecoveryReport{
+		RecoveryMode: e.config.Engine.RecoveryMode,
+		SkippedFiles: []influxdb.SkippedFile{},
+	}
+	for _, f := range e.engine.FileStore.SkippedFiles() {
+		file := influxdb.SkippedFile{
+			Path:  f.Path,
+			Size:  f.Size,
+			Error: f.Err.Error(),
+		}
+		if f.HasTimeRange {
+			min, max := time.Unix(0, f.MinTime).UTC(), time.Unix(0, f.MaxTime).UTC()
+			file.MinTime, file.MaxTime = &min, &max
+		}
+		report.SkippedFiles = append(report.SkippedFiles, file)
+	}
+	return report, nil
+}
+
+// EngineStats returns the current statistics of the cache, TSM files,
+// compactions, WAL and write latency of the engine.
+func (e *Engine) EngineStats(ctx context.Context) (*influxdb.EngineStats, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	stats := e.snapshotStatsLocked()
+	return &stats, nil
+}
+
+// SnapshotStats returns the statistics of the engine at a moment in time. They
+// are read from counters kept up to date by the engine, without visiting the
+// cache entries or the files on disk. The statistics of a closed engine are
+// zero.
+func (e *Engine) SnapshotStats() influxdb.EngineStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return influxdb.EngineStats{}
+	}
+	return e.snapshotStatsLocked()
+}
+
+// snapshotStatsLocked returns the statistics of the engine. e.mu must be held.
+func (e *Engine) snapshotStatsLocked() influxdb.EngineStats {
+	stats := influxdb.EngineStats{
+		CacheSize:        int64(e.engine.Cache.Size()),
+		CacheCount:       e.engine.Cache.EntryCount(),
+		OpenTSMFiles:     e.engine.FileStore.Count(),
+		TSMFileSizeBytes: e.engine.FileStore.DiskSizeBytes(),
+		WALSegments:      e.wal.SegmentCount(),
+		WALSizeBytes:     e.wal.DiskSizeBytes(),
+	}
+	stats.CompactionLevel1Running, stats.CompactionLevel2Running, stats.CompactionFullRunning = e.engine.ActiveCompactions()
+	stats.ActiveCompactions = e.engine.AllActiveCompactions()
+
+	latency := e.EstimatedWriteLatency()
+	stats.WriteLatencyP50, stats.WriteLatencyP95, stats.WriteLatencyP99 = latency.P50, latency.P95, latency.P99
+	return stats
+}
+
+// SetRetentionPolicy registers a retention policy for the bucket, which takes
+// precedence over the retention period of the bucket itself. Data older than
+// duration is deleted each time retention is enforced. A duration of 0 removes
+// the policy.
+//
+// The policies are stored in the engine's directory, so they survive
+// restarts.
+func (e *Engine) SetRetentionPolicy(orgID, bucketID influxdb.ID, duration time.Duration) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	if r, ok := e.retentionEnforcer.(*retentionEnforcer); ok {
+		return r.Policies.put(orgID, bucketID, duration)
+	}
+	return nil
+}
+
 // SeriesCardinality returns the number of series in the engine.
 func (e *Engine) SeriesCardinality() int64 {
 	e.mu.RLock()
@@ -781,6 +1253,58 @@ func (e *Engine) SeriesCardinality() int64 {
 	return e.index.SeriesN()
 }
 
+// SeriesCount returns the number of distinct series keys in the bucket.
+func (e *Engine) SeriesCount(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	cur, err := e.CreateSeriesCursor(ctx, orgID, bucketID, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close()
+
+	var n int64
+	for {
+		row, err := cur.Next()
+		if err != nil {
+			return 0, err
+		} else if row == nil {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// EstimatedCompressedSize returns the sizes of the TSM files with data of the
+// bucket, plus the size of its data not yet written to TSM files.
+func (e *Engine) EstimatedCompressedSize(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return 0, ErrEngineClosed
+	}
+	return e.engine.EstimatedCompressedSize(orgID, bucketID), nil
+}
+
+// ApproxMeasurementCount returns an estimate of the number of distinct
+// measurements in the bucket, without scanning the bucket's data. The estimate
+// has a relative standard error of tsm1.ApproxMeasurementCountError.
+func (e *Engine) ApproxMeasurementCount(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return 0, ErrEngineClosed
+	}
+	return e.engine.ApproxMeasurementCount(ctx, orgID, bucketID)
+}
+
 // Path returns the path of the engine's base directory.
 func (e *Engine) Path() string {
 	return e.path
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxql"
+)
+
+// ReadArrowRequest describes the data returned by Engine.ReadArrow.
+type ReadArrowRequest struct {
+	OrgID       influxdb.ID
+	BucketID    influxdb.ID
+	Measurement string
+
+	// Start and Stop are the time range in nanoseconds of the values to
+	// read. Start is inclusive and Stop is exclusive.
+	Start int64
+	Stop  int64
+
+	// Predicate optionally restricts the series read using their tags.
+	Predicate influxql.Expr
+}
+
+// Column names of the records returned by Engine.ReadArrow. The remaining
+// columns are the tag keys of the series read.
+const (
+	ArrowTimeColumn  = "_time"
+	ArrowValueColumn = "_value"
+	ArrowFieldColumn = "_field"
+)
+
+// ReadArrow returns the float values of the measurement in the bucket as a
+// stream of Arrow records. Each record holds a block of values from a single
+// series and every record shares the same schema: the time and value of each
+// row, the field and a column for each tag key of the series read. Rows of
+// series without a tag hold a null in that tag's column.
+//
+// Values are copied from the cursors straight into Arrow arrays. Only float
+// fields are returned; fields of other types are skipped.
+//
+// The records are sent on the channel returned by the stream's Records method,
+// which is closed once all records have been sent, the context is canceled or
+// an error occurs reading a series. Err reports why the stream ended early.
+// Callers must Release each record received.
+func (e *Engine) ReadArrow(ctx context.Context, req ReadArrowRequest) (*ArrowRecords, error) {
+	cond := influxql.Expr(&influxql.BinaryExpr{
+		Op:  influxql.EQ,
+		LHS: &influxql.VarRef{Val: models.MeasurementTagKey},
+		RHS: &influxql.StringLiteral{Val: req.Measurement},
+	})
+	if req.Predicate != nil {
+		cond = &influxql.BinaryExpr{Op: influxql.AND, LHS: cond, RHS: req.Predicate}
+	}
+
+	sc, err := e.CreateSeriesCursor(ctx, req.OrgID, req.BucketID, cond)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	// Read every series up front so the schema can include all of their tag keys.
+	var series []SeriesCursorRow
+	tagKeys := make(map[string]struct{})
+	for {
+		row, err := sc.Next()
+		if err != nil {
+			return nil, err
+		} else if row == nil {
+			break
+		}
+
+		series = append(series, SeriesCursorRow{Name: row.Name, Tags: row.Tags.Clone()})
+		for _, t := range row.Tags {
+			tagKeys[string(t.Key)] = struct{}{}
+		}
+	}
+	delete(tagKeys, models.MeasurementTagKey)
+	delete(tagKeys, models.FieldKeyTagKey)
+
+	itr, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newArrowReader(tagKeys)
+	recs := &ArrowRecords{ch: make(chan array.Record)}
+	go func() {
+		defer close(recs.ch)
+
+		creq := cursors.CursorRequest{
+			Ascending: true,
+			StartTime: req.Start,
+			EndTime:   req.Stop - 1,
+		}
+		for _, s := range series {
+			if err := ctx.Err(); err != nil {
+				recs.err = err
+				return
+			}
+
+			creq.Name = s.Name
+			creq.Tags = s.Tags
+			creq.Field = string(s.Tags.Get(models.FieldKeyTagKeyBytes))
+			if err := r.readSeries(ctx, itr, &creq, recs.ch); err != nil {
+				recs.err = err
+				return
+			}
+		}
+	}()
+	return recs, nil
+}
+
+// ArrowRecords is a stream of Arrow records read by Engine.ReadArrow.
+type ArrowRecords struct {
+	ch  chan array.Record
+	err error // set before ch is closed
+}
+
+// Records returns the channel the records are sent on. The channel is closed
+// once the stream ends.
+func (r *ArrowRecords) Records() <-chan array.Record { return r.ch }
+
+// Err returns the error that ended the stream early, or nil if every record
+// was sent. It must only be called once the channel returned by Records has
+// been closed.
+func (r *ArrowRecords) Err() error { return r.err }
+
+// arrowReader builds Arrow records from the float cursors of a set of series.
+type arrowReader struct {
+	mem     memory.Allocator
+	schema  *arrow.Schema
+	tagKeys []string
+}
+
+func newArrowReader(tagKeys map[string]struct{}) *arrowReader {
+	r := &arrowReader{mem: memory.NewGoAllocator()}
+	for k := range tagKeys {
+		r.tagKeys = append(r.tagKeys, k)
+	}
+	sort.Strings(r.tagKeys)
+
+	fields := []arrow.Field{
+		{Name: ArrowTimeColumn, Type: arrow.FixedWidthTypes.Timestamp_ns},
+		{Name: ArrowValueColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: ArrowFieldColumn, Type: arrow.BinaryTypes.String},
+	}
+	for _, k := range r.tagKeys {
+		fields = append(fields, arrow.Field{Name: k, Type: arrow.BinaryTypes.String, Nullable: true})
+	}
+	r.schema = arrow.NewSchema(fields, nil)
+	return r
+}
+
+// readSeries sends a record for each block of values read from the series.
+func (r *arrowReader) readSeries(ctx context.Context, itr cursors.CursorIterator, req *cursors.CursorRequest, ch chan<- array.Record) error {
+	cur, err := itr.Next(ctx, req)
+	if err != nil {
+		return err
+	} else if cur == nil {
+		return nil
+	}
+	defer cur.Close()
+
+	fcur, ok := cur.(cursors.FloatArrayCursor)
+	if !ok {
+		return nil
+	}
+
+	for {
+		a := fcur.Next()
+		if a.Len() == 0 {
+			break
+		}
+
+		rec := r.newRecord(req, a)
+		select {
+		case ch <- rec:
+		case <-ctx.Done():
+			rec.Release()
+			return ctx.Err()
+		}
+	}
+	return fcur.Err()
+}
+
+func (r *arrowReader) newRecord(req *cursors.CursorRequest, a *cursors.FloatArray) array.Record {
+	n := a.Len()
+	cols := make([]array.Interface, 0, len(r.schema.Fields()))
+
+	tb := array.NewTimestampBuilder(r.mem, arrow.FixedWidthTypes.Timestamp_ns.(*arrow.TimestampType))
+	tb.Reserve(n)
+	for _, ts := range a.Timestamps {
+		tb.UnsafeAppend(arrow.Timestamp(ts))
+	}
+	cols = append(cols, tb.NewArray())
+	tb.Release()
+
+	vb := array.NewFloat64Builder(r.mem)
+	vb.AppendValues(a.Values, nil)
+	cols = append(cols, vb.NewArray())
+	vb.Release()
+
+	cols = append(cols, r.repeat(req.Field, true, n))
+	for _, k := range r.tagKeys {
+		v := req.Tags.Get([]byte(k))
+		cols = append(cols, r.repeat(string(v), v != nil, n))
+	}
+
+	rec := array.NewRecord(r.schema, cols, int64(n))
+	for _, c := range cols {
+		c.Release()
+	}
+	return rec
+}
+
+// repeat returns a string array of n copies of v, or n nulls if valid is false.
+func (r *arrowReader) repeat(v string, valid bool, n int) array.Interface {
+	b := array.NewStringBuilder(r.mem)
+	defer b.Release()
+
+	b.Reserve(n)
+	for i := 0; i < n; i++ {
+		if valid {
+			b.Append(v)
+		} else {
+			b.AppendNull()
+		}
+	}
+	return b.NewArray()
+}
@@ -0,0 +1,273 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/values"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	storageflux "github.com/influxdata/influxdb/storage/flux"
+	"github.com/influxdata/influxdb/storage/readservice"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+func TestEngine_ReadArrow(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	p := func(m, f string, v interface{}, ts int64, kvs ...string) models.Point {
+		tags := map[string]string{models.FieldKeyTagKey: f, models.MeasurementTagKey: m}
+		for i := 0; i < len(kvs)-1; i += 2 {
+			tags[kvs[i]] = kvs[i+1]
+		}
+		return models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(tags),
+			map[string]interface{}{f: v},
+			time.Unix(0, ts),
+		)
+	}
+
+	if err := engine.Engine.WritePoints(context.Background(), []models.Point{
+		p("cpu", "usage", 1.0, 10, "host", "a"),
+		p("cpu", "usage", 2.0, 20, "host", "a"),
+		p("cpu", "usage", 3.0, 30, "host", "a"),
+		p("cpu", "usage", 4.0, 10, "host", "b", "region", "west"),
+		p("cpu", "count", int64(1), 10, "host", "a"),
+		p("mem", "usage", 5.0, 10, "host", "a"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		Time   int64
+		Value  float64
+		Field  string
+		Host   string
+		Region string
+	}
+
+	read := func(t *testing.T, req func(storage.ReadArrowRequest) storage.ReadArrowRequest) []row {
+		t.Helper()
+
+		recs, err := engine.ReadArrow(context.Background(), req(storage.ReadArrowRequest{
+			OrgID:       engine.org,
+			BucketID:    engine.bucket,
+			Measurement: "cpu",
+			Start:       0,
+			Stop:        100,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rows []row
+		for rec := range recs.Records() {
+			tag := func(i int, k string) string {
+				idx := rec.Schema().FieldIndex(k)
+				if idx < 0 {
+					return ""
+				}
+				col := rec.Column(idx).(*array.String)
+				if col.IsNull(i) {
+					return ""
+				}
+				return col.Value(i)
+			}
+
+			ts := rec.Column(0).(*array.Timestamp)
+			vs := rec.Column(1).(*array.Float64)
+			fs := rec.Column(2).(*array.String)
+			for i := 0; i < int(rec.NumRows()); i++ {
+				r := row{
+					Time:   int64(ts.Value(i)),
+					Value:  vs.Value(i),
+					Field:  fs.Value(i),
+					Host:   tag(i, "host"),
+					Region: tag(i, "region"),
+				}
+				rows = append(rows, r)
+			}
+			rec.Release()
+		}
+		if err := recs.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	t.Run("all float series of the measurement", func(t *testing.T) {
+		got := read(t, func(req storage.ReadArrowRequest) storage.ReadArrowRequest { return req })
+		want := []row{
+			{Time: 10, Value: 1, Field: "usage", Host: "a"},
+			{Time: 20, Value: 2, Field: "usage", Host: "a"},
+			{Time: 30, Value: 3, Field: "usage", Host: "a"},
+			{Time: 10, Value: 4, Field: "usage", Host: "b", Region: "west"},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected rows -got/+want\n%s", diff)
+		}
+	})
+
+	t.Run("time range and tag predicate", func(t *testing.T) {
+		got := read(t, func(req storage.ReadArrowRequest) storage.ReadArrowRequest {
+			req.Start, req.Stop = 15, 30
+			req.Predicate = &influxql.BinaryExpr{
+				Op:  influxql.EQ,
+				LHS: &influxql.VarRef{Val: "host"},
+				RHS: &influxql.StringLiteral{Val: "a"},
+			}
+			return req
+		})
+		want := []row{
+			{Time: 20, Value: 2, Field: "usage", Host: "a"},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected rows -got/+want\n%s", diff)
+		}
+	})
+
+	t.Run("canceled read reports error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		recs, err := engine.ReadArrow(ctx, storage.ReadArrowRequest{
+			OrgID:       engine.org,
+			BucketID:    engine.bucket,
+			Measurement: "cpu",
+			Start:       0,
+			Stop:        100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+
+		var n int
+		for rec := range recs.Records() {
+			n++
+			rec.Release()
+		}
+		if got, want := recs.Err(), context.Canceled; got != want {
+			t.Errorf("unexpected error: got %v want %v", got, want)
+		}
+		if n > 1 {
+			t.Errorf("expected at most one record after cancel, got %d", n)
+		}
+	})
+}
+
+// BenchmarkEngine_ReadArrow compares reading 1M rows through the Flux storage
+// reader and encoding them as an annotated CSV query response with reading
+// them with ReadArrow and encoding them as an Arrow IPC stream.
+func BenchmarkEngine_ReadArrow(b *testing.B) {
+	const (
+		numSeries = 100
+		numPoints = 10000 // per series
+	)
+
+	cfg := storage.NewConfig()
+	cfg.WAL.Enabled = false
+	engine := NewEngine(cfg, 0, 0)
+	defer engine.Close()
+	engine.MustOpen()
+
+	points := make([]models.Point, 0, numPoints)
+	for s := 0; s < numSeries; s++ {
+		points = points[:0]
+		for i := 0; i < numPoints; i++ {
+			points = append(points, models.MustNewPoint(
+				tsdb.EncodeNameString(engine.org, engine.bucket),
+				models.NewTags(map[string]string{
+					models.MeasurementTagKey: "cpu",
+					models.FieldKeyTagKey:    "usage",
+					"host":                   fmt.Sprintf("server-%d", s),
+				}),
+				map[string]interface{}{"usage": float64(i)},
+				time.Unix(0, int64(i)),
+			))
+		}
+		if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	req := storage.ReadArrowRequest{
+		OrgID:       engine.org,
+		BucketID:    engine.bucket,
+		Measurement: "cpu",
+		Start:       0,
+		Stop:        numPoints,
+	}
+
+	b.Run("flux_csv", func(b *testing.B) {
+		reader := storageflux.NewReader(readservice.NewStore(engine.Engine))
+		spec := influxdb.ReadFilterSpec{
+			OrganizationID: engine.org,
+			BucketID:       engine.bucket,
+			Bounds: execute.Bounds{
+				Start: values.Time(req.Start),
+				Stop:  values.Time(req.Stop),
+			},
+		}
+		enc := csv.NewResultEncoder(csv.DefaultEncoderConfig())
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tables, err := reader.ReadFilter(context.Background(), spec, &memory.Allocator{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := enc.Encode(ioutil.Discard, tableResult{tables}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("arrow_ipc", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			recs, err := engine.ReadArrow(context.Background(), req)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			var w *ipc.Writer
+			for rec := range recs.Records() {
+				if w == nil {
+					w = ipc.NewWriter(ioutil.Discard, ipc.WithSchema(rec.Schema()))
+				}
+				if err := w.Write(rec); err != nil {
+					b.Fatal(err)
+				}
+				rec.Release()
+			}
+			if err := recs.Err(); err != nil {
+				b.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// tableResult is a flux.Result of the tables read from the storage reader.
+type tableResult struct {
+	tables flux.TableIterator
+}
+
+func (r tableResult) Name() string               { return "_result" }
+func (r tableResult) Tables() flux.TableIterator { return r.tables }
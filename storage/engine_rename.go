@@ -0,0 +1,504 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxdb/tsdb/seriesfile"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+// MeasurementRename renames a measurement in a bucket without rewriting its
+// data.
+//
+// The data of from is read as to from then on, and from can no longer be
+// read. Points written to either name are read as to. The data is rewritten
+// under the new name after the next full compaction.
+//
+// It is an error to rename a measurement that does not exist, or to a name
+// that is already in use.
+func (e *Engine) MeasurementRename(ctx context.Context, orgID, bucketID influxdb.ID, from, to string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if from == "" || to == "" {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "measurement names must not be empty",
+		}
+	} else if from == to {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "measurement must be renamed to a different name",
+		}
+	}
+
+	// Writes are blocked while renaming, so that none are made under the
+	// new name in the meantime.
+	e.renameMu.Lock()
+	defer e.renameMu.Unlock()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.aliasMu.Lock()
+	defer e.aliasMu.Unlock()
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	exists, err := e.measurementExists(name, from)
+	if err != nil {
+		return err
+	} else if !exists {
+		return &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  fmt.Sprintf("measurement '%s' not found", from),
+		}
+	}
+
+	if exists, err := e.measurementExists(name, to); err != nil {
+		return err
+	} else if exists {
+		return &influxdb.Error{
+			Code: influxdb.EConflict,
+			Msg:  fmt.Sprintf("measurement '%s' already exists", to),
+		}
+	}
+
+	// The data of a measurement renamed away from to is still stored as to
+	// until it is rewritten, unless from is that measurement being renamed
+	// back.
+	if stored, ok := e.aliases.stored(name, from); e.aliases.hidden(name, to) && (!ok || stored != to) {
+		return &influxdb.Error{
+			Code: influxdb.EConflict,
+			Msg:  fmt.Sprintf("measurement '%s' was renamed and has not yet been rewritten", to),
+		}
+	}
+
+	e.aliases.rename(name, from, to)
+	if err := e.aliases.save(); err != nil {
+		e.aliases.rename(name, to, from)
+		return err
+	}
+	return nil
+}
+
+// measurementExists reports whether the bucket has series of measurement, as
+// they are read. The caller must hold e.mu and e.aliasMu.
+func (e *Engine) measurementExists(name [16]byte, measurement string) (bool, error) {
+	stored, ok := e.aliases.stored(name, measurement)
+	if !ok {
+		if e.aliases.hidden(name, measurement) {
+			return false, nil
+		}
+		stored = measurement
+	}
+
+	sitr, err := e.index.MeasurementSeriesByExprIterator(name[:], measurementComparison(influxql.EQ, stored))
+	if err != nil || sitr == nil {
+		return false, err
+	}
+	defer sitr.Close()
+
+	elem, err := sitr.Next()
+	if err != nil {
+		return false, err
+	}
+	return !elem.SeriesID.IsZero(), nil
+}
+
+// renamedMeasurements returns the measurements of a bucket that are stored
+// under another name, mapped to the names they are read as.
+func (e *Engine) renamedMeasurements(orgID, bucketID influxdb.ID) map[string]string {
+	e.aliasMu.RLock()
+	defer e.aliasMu.RUnlock()
+
+	renamed := e.aliases.bucket(tsdb.EncodeName(orgID, bucketID))
+	if len(renamed) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(renamed))
+	for from, to := range renamed {
+		m[from] = to
+	}
+	return m
+}
+
+// storedMeasurements returns a copy of the measurements of each bucket that
+// are stored under another name, mapped by the names they are read as to the
+// names they are stored as.
+func (e *Engine) storedMeasurements() map[[16]byte]map[string]string {
+	e.aliasMu.RLock()
+	defer e.aliasMu.RUnlock()
+
+	if len(e.aliases.renamed) == 0 {
+		return nil
+	}
+	stored := make(map[[16]byte]map[string]string, len(e.aliases.renamed))
+	for name, renamed := range e.aliases.renamed {
+		m := make(map[string]string, len(renamed))
+		for from, to := range renamed {
+			m[to] = from
+		}
+		stored[name] = m
+	}
+	return stored
+}
+
+// storeRenamedMeasurements stores the points of the collection written to
+// the new name of a renamed measurement under its old name, where the rest
+// of its data is until it is rewritten. The caller must hold e.renameMu.
+func (e *Engine) storeRenamedMeasurements(collection *tsdb.SeriesCollection) {
+	stored := e.storedMeasurements()
+	if stored == nil {
+		return
+	}
+
+	for i, name := range collection.Names {
+		var n [16]byte
+		copy(n[:], name)
+		m := stored[n]
+		if m == nil {
+			continue
+		}
+
+		from, ok := m[string(collection.Tags[i].Get(models.MeasurementTagKeyBytes))]
+		if !ok {
+			continue
+		}
+		tags := collection.Tags[i].Clone()
+		tags.Set(models.MeasurementTagKeyBytes, []byte(from))
+		collection.Tags[i] = tags
+		collection.Keys[i] = models.MakeKey(name, tags)
+		collection.Points[i].SetTags(tags)
+	}
+}
+
+// renamedSeriesCursor reads the series of renamed measurements by their new
+// name.
+type renamedSeriesCursor struct {
+	SeriesCursor
+	renamed map[string]string
+}
+
+func (cur *renamedSeriesCursor) Next() (*SeriesCursorRow, error) {
+	row, err := cur.SeriesCursor.Next()
+	if row == nil || err != nil {
+		return row, err
+	}
+	if to, ok := cur.renamed[string(row.Tags.Get(models.MeasurementTagKeyBytes))]; ok {
+		row.Tags.Set(models.MeasurementTagKeyBytes, []byte(to))
+	}
+	return row, nil
+}
+
+// renamedCursorIterator reads the series of renamed measurements requested
+// by their new name from where they are stored.
+type renamedCursorIterator struct {
+	cursors.CursorIterator
+	stored map[[16]byte]map[string]string
+	req    cursors.CursorRequest
+}
+
+func (itr *renamedCursorIterator) Next(ctx context.Context, r *cursors.CursorRequest) (cursors.Cursor, error) {
+	var name [16]byte
+	copy(name[:], r.Name)
+	if from, ok := itr.stored[name][string(r.Tags.Get(models.MeasurementTagKeyBytes))]; ok {
+		itr.req = *r
+		itr.req.Tags = r.Tags.Clone()
+		itr.req.Tags.Set(models.MeasurementTagKeyBytes, []byte(from))
+		r = &itr.req
+	}
+	return itr.CursorIterator.Next(ctx, r)
+}
+
+// renameTagValues returns the measurements of itr by the names they are read
+// as.
+func renameTagValues(itr cursors.StringIterator, renamed map[string]string) cursors.StringIterator {
+	var values []string
+	for itr.Next() {
+		v := itr.Value()
+		if to, ok := renamed[v]; ok {
+			v = to
+		}
+		values = append(values, v)
+	}
+	return cursors.NewStringSliceIteratorWithStats(sortedUnique(values), itr.Stats())
+}
+
+func sortedUnique(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	sort.Strings(values)
+	j := 1
+	for i := 1; i < len(values); i++ {
+		if values[i] != values[j-1] {
+			values[j] = values[i]
+			j++
+		}
+	}
+	return values[:j]
+}
+
+// scheduleMeasurementRewrite signals the engine to rewrite the data of the
+// renamed measurements. It does not block.
+func (e *Engine) scheduleMeasurementRewrite() {
+	select {
+	case e.rewriteC <- struct{}{}:
+	default:
+	}
+}
+
+// runMeasurementRewriter rewrites the data of renamed measurements when
+// scheduled, which is after each full compaction.
+func (e *Engine) runMeasurementRewriter() {
+	l := e.logger.With(zap.String("component", "measurement_rewriter"))
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			// It's safe to read closing without a lock because it's never
+			// modified if this goroutine is active.
+			select {
+			case <-e.closing:
+				return
+			case <-e.rewriteC:
+				if err := e.rewriteRenamedMeasurements(context.Background()); err != nil {
+					l.Error("Failed to rewrite renamed measurements", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// rewriteRenamedMeasurements rewrites the data of each renamed measurement
+// under its new name, removing the data stored under the old one.
+//
+// Writes are blocked while a measurement is rewritten.
+func (e *Engine) rewriteRenamedMeasurements(ctx context.Context) error {
+	e.aliasMu.RLock()
+	aliases := e.aliases.list()
+	e.aliasMu.RUnlock()
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	e.renameMu.Lock()
+	defer e.renameMu.Unlock()
+
+	// Measurements may have been renamed in the meantime.
+	e.aliasMu.RLock()
+	aliases = e.aliases.list()
+	e.aliasMu.RUnlock()
+
+	for _, alias := range aliases {
+		if err := e.rewriteMeasurement(ctx, alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteMeasurement copies the series stored under the old name of a
+// renamed measurement to its new name and deletes them. The caller must hold
+// e.renameMu.
+func (e *Engine) rewriteMeasurement(ctx context.Context, alias measurementAlias) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	name := tsdb.EncodeName(alias.OrgID, alias.BucketID)
+	keys, err := e.measurementSeriesKeys(name, alias.From)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := e.rewriteSeries(ctx, key, alias.To); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	// The alias is removed first, so that the copies are not hidden should
+	// the delete fail.
+	e.aliasMu.Lock()
+	e.aliases.remove(name, alias.From)
+	err = e.aliases.save()
+	e.aliasMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	pred, err := tsm1.NewProtobufPredicate(&datatypes.Predicate{
+		Root: &datatypes.Node{
+			NodeType: datatypes.NodeTypeComparisonExpression,
+			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
+			Children: []*datatypes.Node{
+				{NodeType: datatypes.NodeTypeTagRef, Value: &datatypes.Node_TagRefValue{TagRefValue: models.MeasurementTagKey}},
+				{NodeType: datatypes.NodeTypeLiteral, Value: &datatypes.Node_StringValue{StringValue: alias.From}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	predData, err := pred.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.wal.DeleteBucketRange(alias.OrgID, alias.BucketID, math.MinInt64, math.MaxInt64, predData); err != nil {
+		return err
+	}
+	return e.deleteBucketRangeLocked(ctx, alias.OrgID, alias.BucketID, math.MinInt64, math.MaxInt64, pred)
+}
+
+// measurementSeriesKeys returns the keys of the series stored under
+// measurement in a bucket.
+func (e *Engine) measurementSeriesKeys(name [16]byte, measurement string) ([][]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	sitr, err := e.index.MeasurementSeriesByExprIterator(name[:], measurementComparison(influxql.EQ, measurement))
+	if err != nil || sitr == nil {
+		return nil, err
+	}
+	defer sitr.Close()
+
+	var keys [][]byte
+	for {
+		elem, err := sitr.Next()
+		if err != nil {
+			return nil, err
+		} else if elem.SeriesID.IsZero() {
+			return keys, nil
+		}
+		if key := e.sfile.SeriesKey(elem.SeriesID); len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+}
+
+// rewriteSeries writes the values of the series with the key to the same
+// series of the measurement to.
+func (e *Engine) rewriteSeries(ctx context.Context, key []byte, to string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	name, tags := seriesfile.ParseSeriesKey(key)
+	field := string(tags.Get(models.FieldKeyTagKeyBytes))
+	renamed := tags.Clone()
+	renamed.Set(models.MeasurementTagKeyBytes, []byte(to))
+
+	itr, err := e.engine.CreateCursorIterator(ctx)
+	if err != nil {
+		return err
+	}
+	cur, err := itr.Next(ctx, &cursors.CursorRequest{
+		Name:      name,
+		Tags:      tags,
+		Field:     field,
+		Ascending: true,
+		StartTime: math.MinInt64,
+		EndTime:   math.MaxInt64,
+	})
+	if err != nil || cur == nil {
+		return err
+	}
+	defer cur.Close()
+
+	var points []models.Point
+	add := func(ts int64, v interface{}) error {
+		pt, err := models.NewPoint(string(name), renamed, models.Fields{field: v}, time.Unix(0, ts))
+		if err != nil {
+			return err
+		}
+		points = append(points, pt)
+		return nil
+	}
+
+	switch cur := cur.(type) {
+	case cursors.FloatArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if err := add(ts, a.Values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case cursors.IntegerArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if err := add(ts, a.Values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case cursors.UnsignedArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if err := add(ts, a.Values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case cursors.StringArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if err := add(ts, a.Values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case cursors.BooleanArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if err := add(ts, a.Values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected cursor type %T", cur)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	} else if len(points) == 0 {
+		return nil
+	}
+
+	collection := tsdb.NewSeriesCollection(points)
+	values, err := tsm1.CollectionToValues(collection)
+	if err != nil {
+		return err
+	}
+	if _, err := e.wal.WriteMulti(ctx, values); err != nil {
+		return err
+	}
+	return e.writePointsLocked(ctx, collection, values)
+}
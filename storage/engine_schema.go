@@ -1,10 +1,17 @@
 package storage
 
 import (
+	"bytes"
+	"container/heap"
 	"context"
+	"fmt"
+	"sort"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/influxdata/influxql"
 )
 
@@ -19,6 +26,9 @@ func (e *Engine) TagKeys(ctx context.Context, orgID, bucketID influxdb.ID, start
 		return cursors.EmptyStringIterator, nil
 	}
 
+	if renamed := e.renamedMeasurements(orgID, bucketID); renamed != nil {
+		predicate = rewriteMeasurementExpr(renamed, predicate)
+	}
 	return e.engine.TagKeys(ctx, orgID, bucketID, start, end, predicate)
 }
 
@@ -34,5 +44,184 @@ func (e *Engine) TagValues(ctx context.Context, orgID, bucketID influxdb.ID, tag
 		return cursors.EmptyStringIterator, nil
 	}
 
-	return e.engine.TagValues(ctx, orgID, bucketID, tagKey, start, end, predicate)
+	renamed := e.renamedMeasurements(orgID, bucketID)
+	if renamed == nil {
+		return e.engine.TagValues(ctx, orgID, bucketID, tagKey, start, end, predicate)
+	}
+
+	itr, err := e.engine.TagValues(ctx, orgID, bucketID, tagKey, start, end, rewriteMeasurementExpr(renamed, predicate))
+	if err != nil || tagKey != models.MeasurementTagKey {
+		return itr, err
+	}
+	return renameTagValues(itr, renamed), nil
+}
+
+// BucketSchema returns the measurements stored in the bucket, with the types
+// of their fields and their tag keys, sorted by name. Renamed measurements
+// are returned by their new name. A field whose values are deleted is not
+// returned.
+func (e *Engine) BucketSchema(ctx context.Context, orgID, bucketID influxdb.ID) ([]influxdb.BucketSchemaMeasurement, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	// The series are read by their stored measurement, which is part of the
+	// key of their values.
+	cur, err := newSeriesCursor(orgID, bucketID, e.index, e.sfile, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	type measurement struct {
+		fields  map[string]influxdb.SchemaFieldType
+		tagKeys map[string]bool
+	}
+	measurements := make(map[string]*measurement)
+	renamed := e.renamedMeasurements(orgID, bucketID)
+	var key []byte
+	for {
+		row, err := cur.Next()
+		if err != nil {
+			return nil, err
+		} else if row == nil {
+			break
+		}
+
+		field := row.Tags.Get(models.FieldKeyTagKeyBytes)
+		key = models.AppendMakeKey(key[:0], row.Name, row.Tags)
+		key = append(key, tsm1.KeyFieldSeparatorBytes...)
+		key = append(key, field...)
+		typ, ok := e.engine.FieldType(key)
+		if !ok {
+			continue
+		}
+
+		name := string(row.Tags.Get(models.MeasurementTagKeyBytes))
+		if to, ok := renamed[name]; ok {
+			name = to
+		}
+		m := measurements[name]
+		if m == nil {
+			m = &measurement{
+				fields:  make(map[string]influxdb.SchemaFieldType),
+				tagKeys: make(map[string]bool),
+			}
+			measurements[name] = m
+		}
+
+		schemaType := influxdb.SchemaFieldTypeOf(typ)
+		if existing, ok := m.fields[string(field)]; ok && existing != schemaType {
+			return nil, &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("field '%s' of measurement '%s' has type %s in some series and %s in others", field, name, existing, schemaType),
+			}
+		}
+		m.fields[string(field)] = schemaType
+		for _, t := range row.Tags {
+			if !bytes.Equal(t.Key, models.MeasurementTagKeyBytes) && !bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+				m.tagKeys[string(t.Key)] = true
+			}
+		}
+	}
+
+	schemas := make([]influxdb.BucketSchemaMeasurement, 0, len(measurements))
+	for name, m := range measurements {
+		schema := influxdb.BucketSchemaMeasurement{
+			MeasurementSchema: influxdb.MeasurementSchema{Measurement: name},
+			TagKeys:           make([]string, 0, len(m.tagKeys)),
+		}
+		for field, typ := range m.fields {
+			schema.Fields = append(schema.Fields, influxdb.MeasurementSchemaField{Name: field, Type: typ})
+		}
+		sort.Slice(schema.Fields, func(i, j int) bool {
+			return schema.Fields[i].Name < schema.Fields[j].Name
+		})
+		for k := range m.tagKeys {
+			schema.TagKeys = append(schema.TagKeys, k)
+		}
+		sort.Strings(schema.TagKeys)
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Measurement < schemas[j].Measurement
+	})
+	return schemas, nil
+}
+
+// TagValueHistogram returns the topN values of the tag key of a measurement
+// stored in the bucket, with the number of series with data within the time
+// range (start, end] having each, sorted by descending count and then by
+// value. All the values are returned if topN is not positive.
+func (e *Engine) TagValueHistogram(ctx context.Context, orgID, bucketID influxdb.ID, measurement, tagKey string, start, end int64, topN int) ([]influxdb.TagValueCount, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	// A renamed measurement is stored, and its series counted, by its old
+	// name, which is no longer read.
+	stored := measurement
+	for from, to := range e.renamedMeasurements(orgID, bucketID) {
+		if from == measurement {
+			return []influxdb.TagValueCount{}, nil
+		}
+		if to == measurement {
+			stored = from
+		}
+	}
+
+	counts, err := e.engine.TagValueCounts(ctx, orgID, bucketID, stored, tagKey, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	h := make(tagValueCountHeap, 0, len(counts))
+	for value, count := range counts {
+		heap.Push(&h, influxdb.TagValueCount{Value: value, Count: count})
+		if topN > 0 && h.Len() > topN {
+			heap.Pop(&h)
+		}
+	}
+
+	// Popping the heap returns the least counts first.
+	hist := make([]influxdb.TagValueCount, h.Len())
+	for i := len(hist) - 1; i >= 0; i-- {
+		hist[i] = heap.Pop(&h).(influxdb.TagValueCount)
+	}
+	return hist, nil
+}
+
+// tagValueCountHeap is a min-heap of tag value counts, whose least count is
+// the one with the lowest count, or the greatest value of equal counts.
+type tagValueCountHeap []influxdb.TagValueCount
+
+func (h tagValueCountHeap) Len() int { return len(h) }
+func (h tagValueCountHeap) Less(i, j int) bool {
+	if h[i].Count != h[j].Count {
+		return h[i].Count < h[j].Count
+	}
+	return h[i].Value > h[j].Value
+}
+func (h tagValueCountHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *tagValueCountHeap) Push(x interface{}) {
+	*h = append(*h, x.(influxdb.TagValueCount))
+}
+
+func (h *tagValueCountHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
 }
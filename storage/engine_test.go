@@ -1,23 +1,36 @@
 package storage_test
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/prom/promtest"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxql"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestEngine_WriteAndIndex(t *testing.T) {
@@ -255,6 +268,301 @@ func TestEngine_DeleteBucket(t *testing.T) {
 	}
 }
 
+func TestEngine_ClearCache(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	otherBucket := influxdb.ID(0x8888888888888888)
+	point := func(bucket influxdb.ID, host string) models.Point {
+		return models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		)
+	}
+	if err := engine.Engine.WritePoints(context.Background(), []models.Point{point(engine.bucket, "a")}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := engine.EngineStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Engine.WritePoints(context.Background(), []models.Point{point(otherBucket, "a"), point(otherBucket, "b")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucket(context.Background(), engine.org, otherBucket); err != nil {
+		t.Fatal(err)
+	}
+
+	// Points written to the other bucket while it is cleared are removed,
+	// and those of the bucket are kept.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := engine.Engine.WritePoints(context.Background(), []models.Point{point(otherBucket, "c")}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := engine.ClearCache(context.Background(), engine.org, otherBucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if err := engine.ClearCache(context.Background(), engine.org, otherBucket); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := engine.EngineStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.CacheCount != before.CacheCount || after.CacheSize != before.CacheSize {
+		t.Fatalf("got cache of %d entries of %d bytes, exp %d entries of %d bytes",
+			after.CacheCount, after.CacheSize, before.CacheCount, before.CacheSize)
+	}
+}
+
+func TestEngine_SnapshotStats(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	if err := engine.Engine.WritePoints(ctx, engine.hostPoints(100, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := engine.SnapshotStats()
+	if stats.CacheSize <= 0 {
+		t.Errorf("expected data in cache, got cache size %d", stats.CacheSize)
+	}
+	if got, exp := stats.CacheCount, 100; got != exp {
+		t.Errorf("got %d cache entries, exp %d", got, exp)
+	}
+	if stats.OpenTSMFiles != 0 || stats.TSMFileSizeBytes != 0 {
+		t.Errorf("got %d TSM files of %d bytes before flushing the cache", stats.OpenTSMFiles, stats.TSMFileSizeBytes)
+	}
+	if stats.WALSegments != 1 || stats.WALSizeBytes <= 0 {
+		t.Errorf("expected a written WAL segment, got %d segments of %d bytes", stats.WALSegments, stats.WALSizeBytes)
+	}
+	walSize := stats.WALSizeBytes
+
+	if err := engine.FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats = engine.SnapshotStats()
+	if stats.CacheSize != 0 || stats.CacheCount != 0 {
+		t.Errorf("got cache of %d entries of %d bytes after flushing it", stats.CacheCount, stats.CacheSize)
+	}
+	if stats.OpenTSMFiles != 1 || stats.TSMFileSizeBytes <= 0 {
+		t.Errorf("expected a TSM file, got %d files of %d bytes", stats.OpenTSMFiles, stats.TSMFileSizeBytes)
+	}
+	if stats.WALSizeBytes >= walSize {
+		t.Errorf("got WAL of %d bytes after flushing the cache, exp less than %d", stats.WALSizeBytes, walSize)
+	}
+	if stats.ActiveCompactions != 0 {
+		t.Errorf("got %d active compactions, exp 0", stats.ActiveCompactions)
+	}
+
+	// The statistics served by the API are the same.
+	apiStats, err := engine.EngineStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(*apiStats, stats) {
+		t.Errorf("unexpected engine stats -got/+exp\n%s", cmp.Diff(*apiStats, stats))
+	}
+
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SnapshotStats(), (influxdb.EngineStats{}); got != exp {
+		t.Errorf("got stats %+v of closed engine, exp %+v", got, exp)
+	}
+}
+
+func TestEngine_WriteMetadata(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	metadata := map[string]string{"owner": "team-infra", "sla": "99.9%"}
+	if err := engine.WriteMetadata(ctx, engine.org, engine.bucket, metadata); err != nil {
+		t.Fatal(err)
+	}
+	// The metadata written is copied.
+	metadata["owner"] = "someone-else"
+
+	// The metadata persists after reopening the engine.
+	if err := engine.Engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := engine.ReadMetadata(ctx, engine.org, engine.bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := map[string]string{"owner": "team-infra", "sla": "99.9%"}; !cmp.Equal(got, exp) {
+		t.Errorf("unexpected metadata -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+
+	// Other buckets have no metadata.
+	if got, err := engine.ReadMetadata(ctx, engine.org, engine.bucket+1); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 0 {
+		t.Errorf("got metadata %v of other bucket, exp none", got)
+	}
+
+	// The metadata of a deleted bucket is removed.
+	if err := engine.DeleteBucket(ctx, engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := engine.ReadMetadata(ctx, engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 0 {
+		t.Errorf("got metadata %v of deleted bucket, exp none", got)
+	}
+}
+
+func TestEngine_ImportPoints(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	// 100 values of each of 1000 hosts are streamed through a pipe, so they
+	// are never all in memory.
+	const hosts, values = 1000, 100
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		fmt.Fprintln(w, "# the values of each host")
+		for v := 0; v < values; v++ {
+			for h := 0; h < hosts; h++ {
+				fmt.Fprintf(w, "cpu,host=%04d value=%d %d\n", h, v, time.Unix(int64(v), 0).UnixNano())
+			}
+		}
+		if err := w.Flush(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+
+	ctx := context.Background()
+	opts := storage.WriteOptions{ImportBatchSize: 10000}
+	n, err := engine.ImportPoints(ctx, engine.org, engine.bucket, pr, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := n, int64(hosts*values); got != exp {
+		t.Fatalf("got %d points imported, exp %d", got, exp)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(hosts); got != exp {
+		t.Errorf("got %d series, exp %d", got, exp)
+	}
+	got := engine.readMeasurement(t, "cpu")
+	if len(got) != hosts {
+		t.Fatalf("got values of %d hosts, exp %d", len(got), hosts)
+	}
+	for host, v := range got {
+		if v != values-1 {
+			t.Errorf("got last value %v of host %s, exp %v", v, host, values-1)
+		}
+	}
+	if got, exp := engine.countValues(t, "cpu"), hosts*values; got != exp {
+		t.Errorf("got %d values, exp %d", got, exp)
+	}
+
+	// Line protocol that cannot be parsed stops the import.
+	lp := "cpu,host=0000 value=1 1\ncpu,host=0000 value= 2\n"
+	if _, err := engine.ImportPoints(ctx, engine.org, engine.bucket, strings.NewReader(lp), opts); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Errorf("got error %v importing invalid line protocol, exp %q", err, influxdb.EInvalid)
+	}
+}
+
+// hostPoints returns a point of the value field for each of n hosts of the
+// cpu measurement of the engine's bucket, with the value v.
+func (e *Engine) hostPoints(n int, v float64) []models.Point {
+	points := make([]models.Point, n)
+	for i := range points {
+		points[i] = models.MustNewPoint(
+			tsdb.EncodeNameString(e.org, e.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": fmt.Sprintf("%04d", i)}),
+			map[string]interface{}{"value": v},
+			time.Unix(int64(v), 0),
+		)
+	}
+	return points
+}
+
+func TestEngine_BatchDeleteSeries(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	// Write the series to a TSM file, and then more values to the cache.
+	points := engine.hostPoints(1000, 1)
+	if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := engine.CreateBackup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Engine.WritePoints(context.Background(), engine.hostPoints(1000, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for i := 0; i < len(points); i += 2 {
+		keys = append(keys, string(points[i].Key()))
+	}
+	if err := engine.BatchDeleteSeries(context.Background(), engine.org, engine.bucket, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(500); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+	values := engine.readMeasurement(t, "cpu")
+	if got, exp := len(values), 500; got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+	for i := 0; i < len(points); i++ {
+		host := fmt.Sprintf("%04d", i)
+		if v, ok := values[host]; i%2 == 0 && ok {
+			t.Fatalf("series of host %s was not deleted", host)
+		} else if i%2 == 1 && v != 2 {
+			t.Fatalf("got %v for host %s, exp 2", v, host)
+		}
+	}
+
+	// The keys must be of series in the bucket.
+	otherPoint := models.MustNewPoint(
+		tsdb.EncodeNameString(engine.org, influxdb.ID(0x8888888888888888)),
+		models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "a"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 0),
+	)
+	err := engine.BatchDeleteSeries(context.Background(), engine.org, engine.bucket, []string{string(otherPoint.Key())})
+	if influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("got error %v, exp invalid", err)
+	}
+}
+
 func TestEngine_DeleteBucket_Predicate(t *testing.T) {
 	engine := NewDefaultEngine()
 	defer engine.Close()
@@ -308,186 +616,1328 @@ func TestEngine_DeleteBucket_Predicate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Remove the matching series.
-	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
-		math.MinInt64, math.MaxInt64, pred); err != nil {
+
+	// Remove the matching series.
+	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check only matching series were removed.
+	if got, exp := engine.SeriesCardinality(), int64(4); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	// Delete based on field key.
+	pred, err = tsm1.NewProtobufPredicate(&datatypes.Predicate{
+		Root: &datatypes.Node{
+			NodeType: datatypes.NodeTypeComparisonExpression,
+			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
+			Children: []*datatypes.Node{
+				{NodeType: datatypes.NodeTypeTagRef,
+					Value: &datatypes.Node_TagRefValue{TagRefValue: models.FieldKeyTagKey},
+				},
+				{NodeType: datatypes.NodeTypeLiteral,
+					Value: &datatypes.Node_StringValue{StringValue: "value"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the matching series.
+	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check only matching series were removed.
+	if got, exp := engine.SeriesCardinality(), int64(0); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+}
+
+func TestEngine_Defragment(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	rnd := rand.New(rand.NewSource(0))
+
+	// Write 1000 series, keeping 1 in 10 of them.
+	var points []models.Point
+	for i := 0; i < 1000; i++ {
+		tags := models.NewTags(map[string]string{
+			models.MeasurementTagKey: "cpu",
+			models.FieldKeyTagKey:    "value",
+			"host":                   fmt.Sprintf("server%04d", i),
+			"keep":                   fmt.Sprint(i%10 == 0),
+		})
+		for j := 0; j < 100; j++ {
+			points = append(points, models.MustNewPoint(name, tags, models.Fields{"value": rnd.Float64()}, time.Unix(int64(j), 0)))
+		}
+	}
+	if err := engine.Engine.WritePoints(ctx, points); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	before := engine.MustTSMSize()
+
+	pred, err := tsm1.NewProtobufPredicate(&datatypes.Predicate{
+		Root: &datatypes.Node{
+			NodeType: datatypes.NodeTypeComparisonExpression,
+			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
+			Children: []*datatypes.Node{
+				{NodeType: datatypes.NodeTypeTagRef,
+					Value: &datatypes.Node_TagRefValue{TagRefValue: "keep"},
+				},
+				{NodeType: datatypes.NodeTypeLiteral,
+					Value: &datatypes.Node_StringValue{StringValue: "false"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucketRangePredicate(ctx, engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting only tombstones the data.
+	if got := engine.MustTSMSize(); got < before {
+		t.Fatalf("expected deleting to reclaim no space, got %d bytes, had %d bytes", got, before)
+	}
+
+	if err := engine.Defragment(ctx, engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	}
+	if got, max := engine.MustTSMSize(), before/5; got > max {
+		t.Fatalf("got %d bytes after defragmenting, exp at most %d bytes of %d", got, max, before)
+	}
+
+	if got, exp := len(engine.readMeasurement(t, "cpu")), 100; got != exp {
+		t.Fatalf("got %d series after defragmenting, exp %d", got, exp)
+	}
+
+	// Defragmenting a bucket with no data does nothing.
+	if err := engine.Defragment(ctx, engine.org, engine.bucket+1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_SeriesCount(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	var points []models.Point
+	for i := 0; i < 100; i++ {
+		m := "cpu"
+		if i%2 == 1 {
+			m = "mem"
+		}
+		points = append(points, models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(map[string]string{
+				models.FieldKeyTagKey:    "value",
+				models.MeasurementTagKey: m,
+				"host":                   fmt.Sprintf("server-%d", i),
+			}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		))
+	}
+	if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
+		t.Fatal(err)
+	}
+
+	// Series of another bucket are not counted.
+	other := tsdb.EncodeNameString(engine.org, engine.bucket+1)
+	if err := engine.Engine.WritePoints(context.Background(), []models.Point{models.MustNewPoint(
+		other,
+		models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := engine.SeriesCount(context.Background(), engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	} else if exp := int64(100); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+
+	// Delete the mem measurement.
+	pred, err := tsm1.NewProtobufPredicate(&datatypes.Predicate{
+		Root: &datatypes.Node{
+			NodeType: datatypes.NodeTypeComparisonExpression,
+			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
+			Children: []*datatypes.Node{
+				{NodeType: datatypes.NodeTypeTagRef,
+					Value: &datatypes.Node_TagRefValue{TagRefValue: models.MeasurementTagKey},
+				},
+				{NodeType: datatypes.NodeTypeLiteral,
+					Value: &datatypes.Node_StringValue{StringValue: "mem"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := engine.SeriesCount(context.Background(), engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	} else if exp := int64(50); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+}
+
+func TestEngine_OpenClose(t *testing.T) {
+	engine := NewDefaultEngine()
+	engine.MustOpen()
+
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_InitializeMetrics(t *testing.T) {
+	engine := NewDefaultEngine()
+
+	engine.MustOpen()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(engine.PrometheusCollectors()...)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := promtest.MustFindMetric(t, mfs, "storage_tsm_files_total", prometheus.Labels{
+		"node_id":   fmt.Sprint(engine.nodeID),
+		"engine_id": fmt.Sprint(engine.engineID),
+		"level":     "1",
+	})
+	if m, got, exp := files, files.GetGauge().GetValue(), 0.0; got != exp {
+		t.Errorf("[%s] got %v, expected %v", m, got, exp)
+	}
+
+	bytes := promtest.MustFindMetric(t, mfs, "storage_tsm_files_disk_bytes", prometheus.Labels{
+		"node_id":   fmt.Sprint(engine.nodeID),
+		"engine_id": fmt.Sprint(engine.engineID),
+		"level":     "1",
+	})
+	if m, got, exp := bytes, bytes.GetGauge().GetValue(), 0.0; got != exp {
+		t.Errorf("[%s] got %v, expected %v", m, got, exp)
+	}
+
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_MeasurementMetrics(t *testing.T) {
+	c := storage.NewConfig()
+	c.MetricsMeasurementAllowlist = []string{"cpu", "mem"}
+	engine := NewEngine(c, rand.Int(), rand.Int())
+	defer engine.Close()
+	engine.MustOpen()
+
+	point := func(measurement string) models.Point {
+		return models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: measurement, "host": "a"}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		)
+	}
+	ctx := context.Background()
+	writes := [][]models.Point{
+		{point("cpu"), point("mem")},
+		{point("cpu")},
+		{point("disk"), point("net")},
+	}
+	for _, points := range writes {
+		if err := engine.Engine.WritePoints(ctx, points); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cond := &influxql.BinaryExpr{
+		Op:  influxql.OR,
+		LHS: &influxql.BinaryExpr{Op: influxql.EQ, LHS: &influxql.VarRef{Val: models.MeasurementTagKey}, RHS: &influxql.StringLiteral{Val: "cpu"}},
+		RHS: &influxql.BinaryExpr{Op: influxql.EQ, LHS: &influxql.VarRef{Val: models.MeasurementTagKey}, RHS: &influxql.StringLiteral{Val: "disk"}},
+	}
+	sc, err := engine.CreateSeriesCursor(ctx, engine.org, engine.bucket, cond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Close()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(engine.PrometheusCollectors()...)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := func(measurement string) prometheus.Labels {
+		return prometheus.Labels{
+			"node_id":     fmt.Sprint(engine.nodeID),
+			"engine_id":   fmt.Sprint(engine.engineID),
+			"measurement": measurement,
+		}
+	}
+	for _, tt := range []struct {
+		name        string
+		measurement string
+		exp         float64
+	}{
+		{name: "storage_writes_total", measurement: "cpu", exp: 2},
+		{name: "storage_writes_total", measurement: "mem", exp: 1},
+		{name: "storage_writes_total", measurement: "_other", exp: 1},
+		{name: "storage_bytes_written_total", measurement: "mem", exp: float64(point("mem").StringSize())},
+		{name: "query_reads_total", measurement: "cpu", exp: 1},
+		{name: "query_reads_total", measurement: "_other", exp: 1},
+	} {
+		m := promtest.MustFindMetric(t, mfs, tt.name, labels(tt.measurement))
+		if got := m.GetCounter().GetValue(); got != tt.exp {
+			t.Errorf("got %v for %s of %s, exp %v", got, tt.name, tt.measurement, tt.exp)
+		}
+	}
+	if m := promtest.FindMetric(mfs, "storage_writes_total", labels("disk")); m != nil {
+		t.Errorf("got metric of measurement disk outside of the allowlist: %v", m)
+	}
+}
+
+// Ensures that when a shard is closed, it removes any series meta-data
+// from the index.
+func TestEngineClose_RemoveIndex(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	pt := models.MustNewPoint(
+		"cpu",
+		models.Tags{
+			{Key: models.MeasurementTagKeyBytes, Value: []byte("cpu")},
+			{Key: []byte("host"), Value: []byte("server")},
+			{Key: models.FieldKeyTagKeyBytes, Value: []byte("value")},
+		},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)
+
+	err := engine.Engine.WritePoints(context.TODO(), []models.Point{pt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	// ensure the index gets loaded after closing and opening the shard
+	engine.Engine.Close() // Don't destroy temporary data.
+	engine.Open(context.Background())
+
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+func TestEngine_WALDisabled(t *testing.T) {
+	config := storage.NewConfig()
+	config.WAL.Enabled = false
+
+	engine := NewEngine(config, rand.Int(), rand.Int())
+	defer engine.Close()
+	engine.MustOpen()
+
+	pt := models.MustNewPoint(
+		"cpu",
+		models.Tags{
+			{Key: models.MeasurementTagKeyBytes, Value: []byte("cpu")},
+			{Key: []byte("host"), Value: []byte("server")},
+			{Key: models.FieldKeyTagKeyBytes, Value: []byte("value")},
+		},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)
+
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_Ready(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+
+	ctx := context.Background()
+	if err := engine.Ready(ctx); err != storage.ErrEngineClosed {
+		t.Fatalf("unexpected readiness of closed engine: got %v, exp %v", err, storage.ErrEngineClosed)
+	}
+
+	engine.MustOpen()
+	if err := engine.Ready(ctx); err != nil {
+		t.Fatalf("empty engine not ready: %v", err)
+	}
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	tags := models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", models.FieldKeyTagKey: "value"})
+	if err := engine.Engine.WritePoints(ctx, []models.Point{models.MustNewPoint(name, tags, models.Fields{"value": 1.0}, time.Unix(1, 0))}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Ready(ctx); err != nil {
+		t.Fatalf("engine with TSM file not ready: %v", err)
+	}
+
+	if err := os.RemoveAll(storage.NewConfig().GetWALPath(engine.path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Ready(ctx); err == nil || !strings.Contains(err.Error(), "WAL directory is not writable") {
+		t.Fatalf("unexpected readiness of engine without WAL directory: %v", err)
+	}
+}
+
+func TestEngine_FlushCache(t *testing.T) {
+	// The engine is closed at the end of the test rather than deferred, as
+	// closing a deadlocked engine would hang the test instead of failing it.
+	engine := NewDefaultEngine()
+	defer os.RemoveAll(engine.path)
+
+	ctx := context.Background()
+	if err := engine.FlushCache(ctx); err != storage.ErrEngineClosed {
+		t.Fatalf("unexpected error flushing closed engine: got %v, exp %v", err, storage.ErrEngineClosed)
+	}
+
+	engine.MustOpen()
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(host string, v float64) models.Point {
+		tags := models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", models.FieldKeyTagKey: "value", "host": host})
+		return models.MustNewPoint(name, tags, models.Fields{"value": v}, time.Unix(1, 0))
+	}
+	if err := engine.Engine.WritePoints(ctx, []models.Point{point("a", 1), point("b", 2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flush acquires the WAL segments under the engine's lock, which
+	// FlushCache must not hold.
+	done := make(chan error, 1)
+	go func() { done <- engine.FlushCache(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out flushing the cache")
+	}
+
+	// The flushed values were removed from the WAL and are read from the TSM
+	// file after reopening the engine.
+	walPath := storage.NewConfig().GetWALPath(engine.path)
+	segments, err := wal.SegmentFileNames(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range segments {
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if fi.Size() > 0 {
+			t.Fatalf("unexpected WAL segment with %d bytes after flush: %s", fi.Size(), path)
+		}
+	}
+
+	if err := engine.Engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(walPath); err != nil {
+		t.Fatal(err)
+	}
+	engine.MustOpen()
+
+	exp := map[string]float64{"a": 1, "b": 2}
+	if diff := cmp.Diff(engine.readMeasurement(t, "cpu"), exp); diff != "" {
+		t.Errorf("unexpected values -got/+exp\n%s", diff)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_WriteConflictingBatch(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+
+	err := engine.Engine.WritePoints(context.TODO(), []models.Point{
+		models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		),
+		models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
+			map[string]interface{}{"value": 2},
+			time.Unix(1, 2),
+		),
+	})
+	if _, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatal("expected partial write error. got:", err)
+	}
+}
+
+func TestEngine_WriteFieldTypeConflict(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(v interface{}) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
+			map[string]interface{}{"value": v},
+			time.Unix(1, 2),
+		)
+	}
+
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{point(1.0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := &influxdb.Error{
+		Code: influxdb.EConflict,
+		Msg:  "field type conflict: measurement 'cpu' field 'value' has type float64 but received int64",
+	}
+	check := func() {
+		t.Helper()
+		err := engine.Engine.WritePoints(context.TODO(), []models.Point{point(int64(1))})
+		if diff := cmp.Diff(err, error(exp)); diff != "" {
+			t.Fatalf("unexpected error -got/+exp\n%s", diff)
+		}
+	}
+
+	// The existing type is read from the cache,
+	check()
+
+	// and once the cache is written to a TSM file, from its index.
+	if err := engine.FlushCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	check()
+
+	// Values of the existing type are still accepted.
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{point(2.0)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_BucketSchema(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(measurement, field string, tags map[string]string, v interface{}) models.Point {
+		t := map[string]string{models.FieldKeyTagKey: field, models.MeasurementTagKey: measurement}
+		for k, v := range tags {
+			t[k] = v
+		}
+		return models.MustNewPoint(name, models.NewTags(t), map[string]interface{}{field: v}, time.Unix(1, 0))
+	}
+
+	if err := engine.Engine.WritePoints(ctx, []models.Point{
+		point("cpu", "usage", map[string]string{"host": "a"}, 1.0),
+		point("cpu", "cores", map[string]string{"host": "a", "region": "west"}, int64(4)),
+		point("mem", "free", nil, uint64(10)),
+		point("mem", "ok", map[string]string{"host": "b"}, true),
+		point("log", "msg", nil, "hello"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The data of other buckets is not part of the schema.
+	other := tsdb.EncodeNameString(engine.org, engine.bucket+1)
+	if err := engine.Engine.WritePoints(ctx, []models.Point{
+		models.MustNewPoint(other, models.NewTags(map[string]string{models.FieldKeyTagKey: "x", models.MeasurementTagKey: "other"}), map[string]interface{}{"x": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.MeasurementRename(ctx, engine.org, engine.bucket, "log", "events"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []influxdb.BucketSchemaMeasurement{
+		{
+			MeasurementSchema: influxdb.MeasurementSchema{
+				Measurement: "cpu",
+				Fields: []influxdb.MeasurementSchemaField{
+					{Name: "cores", Type: influxdb.SchemaFieldTypeInteger},
+					{Name: "usage", Type: influxdb.SchemaFieldTypeFloat},
+				},
+			},
+			TagKeys: []string{"host", "region"},
+		},
+		{
+			MeasurementSchema: influxdb.MeasurementSchema{
+				Measurement: "events",
+				Fields: []influxdb.MeasurementSchemaField{
+					{Name: "msg", Type: influxdb.SchemaFieldTypeString},
+				},
+			},
+			TagKeys: []string{},
+		},
+		{
+			MeasurementSchema: influxdb.MeasurementSchema{
+				Measurement: "mem",
+				Fields: []influxdb.MeasurementSchemaField{
+					{Name: "free", Type: influxdb.SchemaFieldTypeUnsigned},
+					{Name: "ok", Type: influxdb.SchemaFieldTypeBoolean},
+				},
+			},
+			TagKeys: []string{"host"},
+		},
+	}
+	check := func() {
+		t.Helper()
+		got, err := engine.BucketSchema(ctx, engine.org, engine.bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(exp, got); diff != "" {
+			t.Fatalf("unexpected schema -exp/+got\n%s", diff)
+		}
+	}
+
+	// The field types are read from the cache,
+	check()
+
+	// and once the cache is written to a TSM file, from its index.
+	if err := engine.FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+func TestEngine_TagValueHistogram(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(measurement, field, host, region string, sec int64) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: field, models.MeasurementTagKey: measurement, "host": host, "region": region}),
+			map[string]interface{}{field: 1.0},
+			time.Unix(sec, 0),
+		)
+	}
+
+	if err := engine.Engine.WritePoints(ctx, []models.Point{
+		point("cpu", "usage", "a", "west", 1),
+		point("cpu", "usage", "b", "west", 1),
+		point("cpu", "usage", "d", "east", 1),
+		// Each field of the data of a host is a series of its own.
+		point("cpu", "usage", "f", "north", 1),
+		point("cpu", "idle", "f", "north", 1),
+		point("mem", "free", "a", "east", 1),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.FlushCache(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The series in the cache are counted with those in TSM files, and those
+	// in both only once.
+	if err := engine.Engine.WritePoints(ctx, []models.Point{
+		point("cpu", "usage", "a", "west", 2),
+		point("cpu", "usage", "c", "west", 2),
+		point("cpu", "usage", "e", "east", 2),
+		point("cpu", "usage", "g", "south", 100),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	histogram := func(measurement string, end int64, topN int) []influxdb.TagValueCount {
+		t.Helper()
+		got, err := engine.TagValueHistogram(ctx, engine.org, engine.bucket, measurement, "region", models.MinNanoTime, end, topN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name        string
+		measurement string
+		end         int64
+		topN        int
+		exp         []influxdb.TagValueCount
+	}{
+		{
+			name:        "top values",
+			measurement: "cpu",
+			end:         models.MaxNanoTime,
+			topN:        2,
+			exp:         []influxdb.TagValueCount{{Value: "west", Count: 3}, {Value: "east", Count: 2}},
+		},
+		{
+			name:        "all values",
+			measurement: "cpu",
+			end:         models.MaxNanoTime,
+			exp: []influxdb.TagValueCount{
+				{Value: "west", Count: 3},
+				{Value: "east", Count: 2},
+				{Value: "north", Count: 2},
+				{Value: "south", Count: 1},
+			},
+		},
+		{
+			name:        "time range",
+			measurement: "cpu",
+			end:         time.Unix(1, 0).UnixNano(),
+			topN:        10,
+			exp: []influxdb.TagValueCount{
+				{Value: "north", Count: 2},
+				{Value: "west", Count: 2},
+				{Value: "east", Count: 1},
+			},
+		},
+		{
+			name:        "other measurement",
+			measurement: "mem",
+			end:         models.MaxNanoTime,
+			topN:        10,
+			exp:         []influxdb.TagValueCount{{Value: "east", Count: 1}},
+		},
+		{
+			name:        "missing measurement",
+			measurement: "disk",
+			end:         models.MaxNanoTime,
+			topN:        10,
+			exp:         []influxdb.TagValueCount{},
+		},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.exp, histogram(tc.measurement, tc.end, tc.topN)); diff != "" {
+			t.Errorf("%s: unexpected histogram -exp/+got\n%s", tc.name, diff)
+		}
+	}
+
+	// A renamed measurement is counted by its new name only.
+	if err := engine.MeasurementRename(ctx, engine.org, engine.bucket, "mem", "memory"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]influxdb.TagValueCount{{Value: "east", Count: 1}}, histogram("memory", models.MaxNanoTime, 10)); diff != "" {
+		t.Errorf("unexpected histogram of renamed measurement -exp/+got\n%s", diff)
+	}
+	if got := histogram("mem", models.MaxNanoTime, 10); len(got) != 0 {
+		t.Errorf("unexpected histogram of old name of renamed measurement: %v", got)
+	}
+}
+
+func TestEngine_MeasurementRename(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(measurement, host string, v float64) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: measurement, "host": host}),
+			map[string]interface{}{"value": v},
+			time.Unix(1, 0),
+		)
+	}
+
+	if err := engine.Engine.WritePoints(ctx, []models.Point{
+		point("cpu_old", "a", 1),
+		point("cpu_old", "b", 2),
+		point("mem", "a", 3),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.MeasurementRename(ctx, engine.org, engine.bucket, "cpu_old", "cpu"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.readMeasurement(t, "cpu"), map[string]float64{"a": 1, "b": 2}; !cmp.Equal(got, exp) {
+		t.Fatalf("unexpected values of cpu -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+	if got := engine.readMeasurement(t, "cpu_old"); len(got) != 0 {
+		t.Fatalf("expected no values of cpu_old, got %v", got)
+	}
+
+	itr, err := engine.TagValues(ctx, engine.org, engine.bucket, models.MeasurementTagKey, math.MinInt64, math.MaxInt64, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := cursors.StringIteratorToSlice(itr), []string{"cpu", "mem"}; !cmp.Equal(got, exp) {
+		t.Fatalf("unexpected measurements -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+
+	// Points written to the new name are read with the rest of its data.
+	if err := engine.Engine.WritePoints(ctx, []models.Point{point("cpu", "c", 4)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.readMeasurement(t, "cpu"), map[string]float64{"a": 1, "b": 2, "c": 4}; !cmp.Equal(got, exp) {
+		t.Fatalf("unexpected values of cpu -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+
+	err = engine.MeasurementRename(ctx, engine.org, engine.bucket, "cpu_old", "cpu_new")
+	if got, exp := influxdb.ErrorCode(err), influxdb.ENotFound; got != exp {
+		t.Fatalf("unexpected error code renaming a missing measurement: got %q, exp %q", got, exp)
+	}
+	err = engine.MeasurementRename(ctx, engine.org, engine.bucket, "cpu", "mem")
+	if got, exp := influxdb.ErrorCode(err), influxdb.EConflict; got != exp {
+		t.Fatalf("unexpected error code renaming to an existing measurement: got %q, exp %q", got, exp)
+	}
+}
+
+// hostValidator rejects points without a host tag.
+type hostValidator struct{}
+
+func (hostValidator) Validate(points []models.Point) error {
+	for _, p := range points {
+		if p.Tags().Get([]byte("host")) == nil {
+			return fmt.Errorf("point %q has no host tag", p.Key())
+		}
+	}
+	return nil
+}
+
+// countingValidator counts the writes it validates.
+type countingValidator struct{ n int }
+
+func (v *countingValidator) Validate(points []models.Point) error {
+	v.n++
+	return nil
+}
+
+func TestEngine_RegisterWriteValidator(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	first, last := &countingValidator{}, &countingValidator{}
+	engine.RegisterWriteValidator(first)
+	engine.RegisterWriteValidator(hostValidator{})
+	engine.RegisterWriteValidator(last)
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(tags map[string]string) models.Point {
+		tags[models.MeasurementTagKey] = "cpu"
+		tags[models.FieldKeyTagKey] = "value"
+		return models.MustNewPoint(name, models.NewTags(tags), map[string]interface{}{"value": 1.0}, time.Unix(1, 2))
+	}
+
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{point(map[string]string{"host": "a"})}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %v series, exp %v series in index", got, exp)
+	}
+
+	// A point without a host tag rejects the whole write.
+	err := engine.Engine.WritePoints(context.TODO(), []models.Point{
+		point(map[string]string{"host": "b"}),
+		point(map[string]string{"region": "west"}),
+	})
+	if err == nil {
+		t.Fatal("expected error writing point without host tag")
+	}
+	if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+		t.Fatalf("unexpected error code: got %q, exp %q", got, exp)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %v series, exp %v series in index", got, exp)
+	}
+
+	// Validators after the one that rejected the write are not called.
+	if got, exp := [2]int{first.n, last.n}, [2]int{2, 1}; got != exp {
+		t.Fatalf("unexpected validator calls: got %v, exp %v", got, exp)
+	}
+}
+
+func TestEngine_WritePointsWithOptions(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	point := func(tags map[string]string, ts time.Time) models.Point {
+		return models.MustNewPoint("cpu", models.NewTags(tags), map[string]interface{}{"value": 1.0}, ts)
+	}
+	valid := point(map[string]string{"host": "a"}, time.Unix(1, 500))
+	invalid := point(map[string]string{"\xf2": "b"}, time.Unix(1, 500))
+
+	// Without partial writes, an invalid point rejects the whole write.
+	err := engine.Engine.WritePointsWithOptions(ctx, engine.org, engine.bucket, []models.Point{valid, invalid}, storage.WriteOptions{RejectPartial: true})
+	if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+		t.Fatalf("unexpected error code: got %q, exp %q", got, exp)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(0); got != exp {
+		t.Fatalf("got %v series, exp %v series in index", got, exp)
+	}
+
+	// With partial writes, the valid points are written, and the validator
+	// of the write sees them with their truncated times.
+	var times []time.Time
+	opts := storage.WriteOptions{
+		Precision: time.Second,
+		Validator: writeValidatorFunc(func(points []models.Point) error {
+			for _, p := range points {
+				times = append(times, p.Time())
+			}
+			return nil
+		}),
+	}
+	err = engine.Engine.WritePointsWithOptions(ctx, engine.org, engine.bucket, []models.Point{valid, invalid}, opts)
+	if _, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatalf("expected partial write error, got %v", err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %v series, exp %v series in index", got, exp)
+	}
+	if got, exp := times, []time.Time{time.Unix(1, 0)}; !cmp.Equal(got, exp) {
+		t.Fatalf("unexpected validated times -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+	if got, exp := valid.Time(), time.Unix(1, 500); !got.Equal(exp) {
+		t.Fatalf("the time of the written point changed: got %v, exp %v", got, exp)
+	}
+
+	// The validator of the write rejects it after those of the engine.
+	opts.Validator = hostValidator{}
+	err = engine.Engine.WritePointsWithOptions(ctx, engine.org, engine.bucket, []models.Point{point(map[string]string{"region": "west"}, time.Unix(2, 0))}, opts)
+	if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+		t.Fatalf("unexpected error code: got %q, exp %q", got, exp)
+	}
+
+	err = engine.Engine.WritePointsWithOptions(ctx, 0, engine.bucket, []models.Point{valid}, opts)
+	if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+		t.Fatalf("unexpected error code writing without an org: got %q, exp %q", got, exp)
+	}
+}
+
+// writeValidatorFunc adapts a function to a storage.WriteValidator.
+type writeValidatorFunc func(points []models.Point) error
+
+func (f writeValidatorFunc) Validate(points []models.Point) error { return f(points) }
+
+func TestEngine_RegisterEventLogger(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	logger := &recordingEventLogger{}
+	engine.RegisterEventLogger(logger)
+
+	ctx := context.Background()
+	points := engine.hostPoints(3, 1)
+	if err := engine.Engine.WritePoints(ctx, points); err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, p := range points {
+		size += int64(p.StringSize())
+	}
+	if err := engine.DeleteBucketRange(ctx, engine.org, engine.bucket, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.BatchDeleteSeries(ctx, engine.org, engine.bucket, []string{string(points[0].Key())}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucket(ctx, engine.org, engine.bucket); err != nil {
 		t.Fatal(err)
 	}
 
-	// Check only matching series were removed.
-	if got, exp := engine.SeriesCardinality(), int64(4); got != exp {
-		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	// A rejected write is not logged.
+	engine.RegisterWriteValidator(hostValidator{})
+	bad := models.MustNewPoint(
+		tsdb.EncodeNameString(engine.org, engine.bucket),
+		models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 0),
+	)
+	if err := engine.Engine.WritePoints(ctx, []models.Point{bad}); err == nil {
+		t.Fatal("expected the write to be rejected")
 	}
 
-	// Delete based on field key.
-	pred, err = tsm1.NewProtobufPredicate(&datatypes.Predicate{
-		Root: &datatypes.Node{
-			NodeType: datatypes.NodeTypeComparisonExpression,
-			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
-			Children: []*datatypes.Node{
-				{NodeType: datatypes.NodeTypeTagRef,
-					Value: &datatypes.Node_TagRefValue{TagRefValue: models.FieldKeyTagKey},
-				},
-				{NodeType: datatypes.NodeTypeLiteral,
-					Value: &datatypes.Node_StringValue{StringValue: "value"},
-				},
-			},
-		},
-	})
+	exp := []eventLogEntry{
+		{op: "write", orgID: engine.org, bucketID: engine.bucket, points: 3, bytes: size},
+		{op: "delete", orgID: engine.org, bucketID: engine.bucket, start: 0, end: 10},
+		{op: "delete", orgID: engine.org, bucketID: engine.bucket, predicate: "<predicate>", start: math.MinInt64, end: math.MaxInt64},
+		{op: "delete", orgID: engine.org, bucketID: engine.bucket, start: math.MinInt64, end: math.MaxInt64},
+	}
+	got := logger.entries
+	for i := range got {
+		if got[i].ts.IsZero() {
+			t.Fatalf("event %d has no time", i)
+		}
+		got[i].ts = time.Time{}
+		if got[i].predicate != "" {
+			got[i].predicate = "<predicate>"
+		}
+	}
+	if !cmp.Equal(got, exp, cmp.AllowUnexported(eventLogEntry{})) {
+		t.Fatalf("unexpected events -got/+exp\n%s", cmp.Diff(got, exp, cmp.AllowUnexported(eventLogEntry{})))
+	}
+}
+
+func TestFileEventLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage-event-log-")
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	ts := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		// The logger appends to the events of an existing file.
+		logger, err := storage.NewFileEventLogger(path, zap.NewNop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.LogWrite(1, 2, 10, 200, ts)
+		logger.LogDelete(1, 2, "host = 'a'", 0, 100, ts)
+		if err := logger.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	// Remove the matching series.
-	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
-		math.MinInt64, math.MaxInt64, pred); err != nil {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
 		t.Fatal(err)
 	}
+	write := `{"time":"2020-03-01T12:00:00Z","op":"write","orgID":"0000000000000001","bucketID":"0000000000000002","points":10,"bytes":200}`
+	del := `{"time":"2020-03-01T12:00:00Z","op":"delete","orgID":"0000000000000001","bucketID":"0000000000000002","predicate":"host = 'a'","start":0,"end":100}`
+	exp := strings.Join([]string{write, del, write, del}, "\n") + "\n"
+	if got := string(data); got != exp {
+		t.Fatalf("unexpected event log -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+}
 
-	// Check only matching series were removed.
-	if got, exp := engine.SeriesCardinality(), int64(0); got != exp {
-		t.Fatalf("got %d series, exp %d series in index", got, exp)
+func TestEngine_WALMaxDiskBytes(t *testing.T) {
+	// The cache is not snapshotted on its own while the test writes, so the
+	// WAL only shrinks when it exceeds its maximum size.
+	const maxWALBytes = 64 << 10
+	c := storage.NewConfig()
+	c.WAL.MaxDiskBytes = maxWALBytes
+	c.Engine.Cache.SnapshotMemorySize = 1 << 30
+	c.Engine.Cache.SnapshotWriteColdDuration = toml.Duration(time.Hour)
+	engine := NewEngine(c, rand.Int(), rand.Int())
+	defer engine.Close()
+
+	core, logs := observer.New(zap.WarnLevel)
+	engine.WithLogger(zap.New(core))
+	engine.MustOpen()
+
+	const msg = "WAL exceeds maximum disk size, flushing cache to TSM files"
+	ctx := context.Background()
+	var writes int
+	for ; logs.FilterMessage(msg).Len() == 0; writes++ {
+		if writes == 10000 {
+			t.Fatal("the WAL exceeding its maximum disk size was not logged")
+		}
+		if err := engine.Engine.WritePoints(ctx, engine.hostPoints(100, float64(writes))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The cache is flushed to a TSM file, and the segments of the WAL it
+	// flushed are removed.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		stats, err := engine.EngineStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.OpenTSMFiles > 0 && stats.WALSizeBytes < maxWALBytes {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache was not flushed: got %d TSM files and %d bytes of WAL", stats.OpenTSMFiles, stats.WALSizeBytes)
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 
+	values := engine.readMeasurement(t, "cpu")
+	if got, exp := len(values), 100; got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+	for host, v := range values {
+		if exp := float64(writes - 1); v != exp {
+			t.Fatalf("got %v for host %s, exp %v", v, host, exp)
+		}
+	}
 }
 
-func TestEngine_OpenClose(t *testing.T) {
+func TestEngine_EstimatedWriteLatency(t *testing.T) {
 	engine := NewDefaultEngine()
+	defer engine.Close()
 	engine.MustOpen()
 
-	if err := engine.Close(); err != nil {
-		t.Fatal(err)
+	if got, exp := engine.EstimatedWriteLatency(), (storage.WriteLatencyStats{}); got != exp {
+		t.Fatalf("got latency %+v before any write, exp %+v", got, exp)
 	}
 
-	if err := engine.Open(context.Background()); err != nil {
-		t.Fatal(err)
+	ctx := context.Background()
+	var max time.Duration
+	for i := 0; i < 100; i++ {
+		start := time.Now()
+		if err := engine.Engine.WritePoints(ctx, engine.hostPoints(10, float64(i))); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d > max {
+			max = d
+		}
 	}
 
-	if err := engine.Close(); err != nil {
-		t.Fatal(err)
+	// The durations are measured within the timed writes.
+	latency := engine.EstimatedWriteLatency()
+	if latency.P50 <= 0 || latency.P50 > max {
+		t.Fatalf("got p50 latency %v, exp within (0, %v]", latency.P50, max)
+	}
+	if latency.P95 < latency.P50 || latency.P99 < latency.P95 || latency.P99 > max {
+		t.Fatalf("got latency %+v, exp increasing quantiles of at most %v", latency, max)
 	}
-}
 
-func TestEngine_InitializeMetrics(t *testing.T) {
-	engine := NewDefaultEngine()
+	stats, err := engine.EngineStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (storage.WriteLatencyStats{P50: stats.WriteLatencyP50, P95: stats.WriteLatencyP95, P99: stats.WriteLatencyP99}); got != latency {
+		t.Fatalf("got engine stats latency %+v, exp %+v", got, latency)
+	}
 
-	engine.MustOpen()
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(engine.PrometheusCollectors()...)
-
 	mfs, err := reg.Gather()
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	files := promtest.MustFindMetric(t, mfs, "storage_tsm_files_total", prometheus.Labels{
+	p50 := promtest.MustFindMetric(t, mfs, "storage_write_duration_seconds", prometheus.Labels{
 		"node_id":   fmt.Sprint(engine.nodeID),
 		"engine_id": fmt.Sprint(engine.engineID),
-		"level":     "1",
+		"quantile":  "0.5",
 	})
-	if m, got, exp := files, files.GetGauge().GetValue(), 0.0; got != exp {
-		t.Errorf("[%s] got %v, expected %v", m, got, exp)
+	if got, exp := p50.GetGauge().GetValue(), latency.P50.Seconds(); got != exp {
+		t.Fatalf("got p50 metric %v, exp %v", got, exp)
 	}
+}
 
-	bytes := promtest.MustFindMetric(t, mfs, "storage_tsm_files_disk_bytes", prometheus.Labels{
-		"node_id":   fmt.Sprint(engine.nodeID),
-		"engine_id": fmt.Sprint(engine.engineID),
-		"level":     "1",
+// eventLogEntry is an event recorded by a recordingEventLogger.
+type eventLogEntry struct {
+	op              string
+	orgID, bucketID influxdb.ID
+	points          int
+	bytes           int64
+	predicate       string
+	start, end      int64
+	ts              time.Time
+}
+
+// recordingEventLogger is a storage.EventLogger recording its events.
+type recordingEventLogger struct {
+	mu      sync.Mutex
+	entries []eventLogEntry
+}
+
+func (l *recordingEventLogger) LogWrite(orgID, bucketID influxdb.ID, pointCount int, byteCount int64, ts time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, eventLogEntry{op: "write", orgID: orgID, bucketID: bucketID, points: pointCount, bytes: byteCount, ts: ts})
+}
+
+func (l *recordingEventLogger) LogDelete(orgID, bucketID influxdb.ID, predicate string, start, end int64, ts time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, eventLogEntry{op: "delete", orgID: orgID, bucketID: bucketID, predicate: predicate, start: start, end: end, ts: ts})
+}
+
+func TestEngine_RegisterWriteSideEffect(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+
+	// Hooks registered before the engine opens run once it is open, and
+	// failing hooks do not fail writes.
+	failed := make(chan struct{}, 10)
+	engine.RegisterWriteSideEffect("failing", func([]models.Point) error {
+		failed <- struct{}{}
+		return errors.New("side effect failed")
 	})
-	if m, got, exp := bytes, bytes.GetGauge().GetValue(), 0.0; got != exp {
-		t.Errorf("[%s] got %v, expected %v", m, got, exp)
-	}
+	engine.MustOpen()
 
-	if err := engine.Close(); err != nil {
-		t.Fatal(err)
+	written := make(chan []models.Point, 10)
+	engine.RegisterWriteSideEffect("collect", func(points []models.Point) error {
+		written <- points
+		return nil
+	})
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	for i := 0; i < 3; i++ {
+		point := models.MustNewPoint(name, models.NewTags(map[string]string{
+			models.MeasurementTagKey: "cpu",
+			"host":                   fmt.Sprintf("server%d", i),
+			models.FieldKeyTagKey:    "value",
+		}), map[string]interface{}{"value": float64(i)}, time.Unix(int64(i), 0))
+
+		if err := engine.Engine.WritePoints(context.Background(), []models.Point{point}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case points := <-written:
+			if len(points) != 1 || string(points[0].Key()) != string(point.Key()) {
+				t.Fatalf("unexpected points for write %d: %v", i, points)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("side effect not called within 100ms of write %d", i)
+		}
+
+		select {
+		case <-failed:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("failing side effect not called within 100ms of write %d", i)
+		}
 	}
 }
 
-// Ensures that when a shard is closed, it removes any series meta-data
-// from the index.
-func TestEngineClose_RemoveIndex(t *testing.T) {
+func TestEngine_WriteEvents(t *testing.T) {
 	engine := NewDefaultEngine()
 	defer engine.Close()
 	engine.MustOpen()
 
-	pt := models.MustNewPoint(
-		"cpu",
-		models.Tags{
-			{Key: models.MeasurementTagKeyBytes, Value: []byte("cpu")},
-			{Key: []byte("host"), Value: []byte("server")},
-			{Key: models.FieldKeyTagKeyBytes, Value: []byte("value")},
-		},
-		map[string]interface{}{"value": 1.0},
-		time.Unix(1, 2),
-	)
+	events := engine.WriteEvents()
 
-	err := engine.Engine.WritePoints(context.TODO(), []models.Point{pt})
-	if err != nil {
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	var points []models.Point
+	for i := 0; i < 25; i++ {
+		points = append(points, models.MustNewPoint(name, models.NewTags(map[string]string{
+			models.MeasurementTagKey: "cpu",
+			"host":                   fmt.Sprintf("server%02d", i),
+			models.FieldKeyTagKey:    "value",
+		}), map[string]interface{}{"value": float64(i)}, time.Unix(int64(i), 0)))
+	}
+	if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
 		t.Fatal(err)
 	}
 
-	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
-		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	select {
+	case ev := <-events:
+		if ev.OrgID != engine.org || ev.BucketID != engine.bucket {
+			t.Fatalf("got event of org %s bucket %s, exp org %s bucket %s", ev.OrgID, ev.BucketID, engine.org, engine.bucket)
+		}
+		if len(ev.Points) != 10 {
+			t.Fatalf("got %d sampled points, exp 10", len(ev.Points))
+		}
+		for i, p := range ev.Points {
+			if got, exp := string(p.Key()), string(points[i].Key()); got != exp {
+				t.Fatalf("got sampled point %q, exp %q", got, exp)
+			}
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("write event not received within 100ms of write")
 	}
 
-	// ensure the index gets loaded after closing and opening the shard
-	engine.Engine.Close() // Don't destroy temporary data.
-	engine.Open(context.Background())
-
-	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
-		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	// Once stopped, the subscription is closed and no longer receives events.
+	engine.StopWriteEvents(events)
+	if err := engine.Engine.WritePoints(context.Background(), points[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := <-events; ok {
+		t.Fatalf("unexpected event after the subscription stopped: %v", ev)
 	}
 }
 
-func TestEngine_WALDisabled(t *testing.T) {
-	config := storage.NewConfig()
-	config.WAL.Enabled = false
+func BenchmarkEngine_BatchDeleteSeries(b *testing.B) {
+	const n, deleted = 1000, 100
 
-	engine := NewEngine(config, rand.Int(), rand.Int())
-	defer engine.Close()
-	engine.MustOpen()
+	run := func(b *testing.B, del func(engine *Engine, keys []string)) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			engine := NewDefaultEngine()
+			engine.MustOpen()
+			points := engine.hostPoints(n, 1)
+			if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
+				b.Fatal(err)
+			}
+			if _, _, err := engine.CreateBackup(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+			keys := make([]string, deleted)
+			for j := range keys {
+				keys[j] = string(points[j*n/deleted].Key())
+			}
+			b.StartTimer()
 
-	pt := models.MustNewPoint(
-		"cpu",
-		models.Tags{
-			{Key: models.MeasurementTagKeyBytes, Value: []byte("cpu")},
-			{Key: []byte("host"), Value: []byte("server")},
-			{Key: models.FieldKeyTagKeyBytes, Value: []byte("value")},
-		},
-		map[string]interface{}{"value": 1.0},
-		time.Unix(1, 2),
-	)
+			del(engine, keys)
 
-	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{pt}); err != nil {
-		t.Fatal(err)
+			b.StopTimer()
+			if err := engine.Close(); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+		}
 	}
-}
-
-func TestEngine_WriteConflictingBatch(t *testing.T) {
-	engine := NewDefaultEngine()
-	defer engine.Close()
-	engine.MustOpen()
 
-	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	b.Run("batch", func(b *testing.B) {
+		run(b, func(engine *Engine, keys []string) {
+			if err := engine.BatchDeleteSeries(context.Background(), engine.org, engine.bucket, keys); err != nil {
+				b.Fatal(err)
+			}
+		})
+	})
 
-	err := engine.Engine.WritePoints(context.TODO(), []models.Point{
-		models.MustNewPoint(
-			name,
-			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
-			map[string]interface{}{"value": 1.0},
-			time.Unix(1, 2),
-		),
-		models.MustNewPoint(
-			name,
-			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
-			map[string]interface{}{"value": 2},
-			time.Unix(1, 2),
-		),
+	b.Run("individual", func(b *testing.B) {
+		run(b, func(engine *Engine, keys []string) {
+			for _, key := range keys {
+				if err := engine.BatchDeleteSeries(context.Background(), engine.org, engine.bucket, []string{key}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	})
-	if _, ok := err.(tsdb.PartialWriteError); !ok {
-		t.Fatal("expected partial write error. got:", err)
-	}
 }
 
 // BenchmarkWritePoints_100K demonstrates the impact that batch size has on
@@ -669,6 +2119,130 @@ func NewDefaultEngine() *Engine {
 	return NewEngine(storage.NewConfig(), rand.Int(), rand.Int())
 }
 
+// readMeasurement returns the float values of the value field of a
+// measurement, by the host of their series.
+func (e *Engine) readMeasurement(tb testing.TB, measurement string) map[string]float64 {
+	tb.Helper()
+
+	ctx := context.Background()
+	cond := &influxql.BinaryExpr{
+		Op:  influxql.EQ,
+		LHS: &influxql.VarRef{Val: models.MeasurementTagKey},
+		RHS: &influxql.StringLiteral{Val: measurement},
+	}
+	sc, err := e.CreateSeriesCursor(ctx, e.org, e.bucket, cond)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer sc.Close()
+
+	itr, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for {
+		row, err := sc.Next()
+		if err != nil {
+			tb.Fatal(err)
+		} else if row == nil {
+			return values
+		}
+		if got := string(row.Tags.Get(models.MeasurementTagKeyBytes)); got != measurement {
+			tb.Fatalf("unexpected measurement: got %q, exp %q", got, measurement)
+		}
+
+		cur, err := itr.Next(ctx, &cursors.CursorRequest{
+			Name:      row.Name,
+			Tags:      row.Tags,
+			Field:     "value",
+			Ascending: true,
+			StartTime: models.MinNanoTime,
+			EndTime:   models.MaxNanoTime,
+		})
+		if err != nil {
+			tb.Fatal(err)
+		} else if cur == nil {
+			continue
+		}
+		for a := cur.(cursors.FloatArrayCursor).Next(); a.Len() > 0; a = cur.(cursors.FloatArrayCursor).Next() {
+			values[string(row.Tags.Get([]byte("host")))] = a.Values[len(a.Values)-1]
+		}
+		cur.Close()
+	}
+}
+
+// MustTSMSize returns the size of the engine's TSM and tombstone files.
+// countValues returns the number of values of the value field of the
+// measurement.
+func (e *Engine) countValues(tb testing.TB, measurement string) int {
+	tb.Helper()
+
+	ctx := context.Background()
+	cond := &influxql.BinaryExpr{
+		Op:  influxql.EQ,
+		LHS: &influxql.VarRef{Val: models.MeasurementTagKey},
+		RHS: &influxql.StringLiteral{Val: measurement},
+	}
+	sc, err := e.CreateSeriesCursor(ctx, e.org, e.bucket, cond)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer sc.Close()
+
+	itr, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var n int
+	for {
+		row, err := sc.Next()
+		if err != nil {
+			tb.Fatal(err)
+		} else if row == nil {
+			return n
+		}
+
+		cur, err := itr.Next(ctx, &cursors.CursorRequest{
+			Name:      row.Name,
+			Tags:      row.Tags,
+			Field:     "value",
+			Ascending: true,
+			StartTime: models.MinNanoTime,
+			EndTime:   models.MaxNanoTime,
+		})
+		if err != nil {
+			tb.Fatal(err)
+		} else if cur == nil {
+			continue
+		}
+		for a := cur.(cursors.FloatArrayCursor).Next(); a.Len() > 0; a = cur.(cursors.FloatArrayCursor).Next() {
+			n += a.Len()
+		}
+		cur.Close()
+	}
+}
+
+func (e *Engine) MustTSMSize() int64 {
+	var size int64
+	err := filepath.Walk(e.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case "." + tsm1.TSMFileExtension, ".tombstone":
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return size
+}
+
 // MustOpen opens the engine or panicks.
 func (e *Engine) MustOpen() {
 	if err := e.Engine.Open(context.Background()); err != nil {
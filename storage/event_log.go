@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// EventLogger records the writes and deletes accepted by the engine, such as
+// for an audit log. Its methods are called synchronously by the operations
+// they record, so they must be fast and safe for concurrent use.
+type EventLogger interface {
+	// LogWrite records a write of pointCount points to a bucket. The points
+	// are those the engine stores, one for each field value, and byteCount
+	// is the size of their line protocol.
+	LogWrite(orgID, bucketID influxdb.ID, pointCount int, byteCount int64, ts time.Time)
+
+	// LogDelete records a delete of the data of a bucket within [start, end]
+	// whose series match predicate, or all its series if predicate is "".
+	LogDelete(orgID, bucketID influxdb.ID, predicate string, start, end int64, ts time.Time)
+}
+
+// WithEventLogger adds l to the event loggers of the engine, as
+// RegisterEventLogger does.
+func WithEventLogger(l EventLogger) Option {
+	return func(e *Engine) {
+		e.eventLoggers = append(e.eventLoggers, l)
+	}
+}
+
+// RegisterEventLogger adds l to the loggers of the writes and deletes accepted
+// by the engine.
+func (e *Engine) RegisterEventLogger(l EventLogger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.eventLoggers = append(e.eventLoggers, l)
+}
+
+// logWriteEvents records a write of points with each event logger, once for
+// each bucket written to. e.mu must be held.
+func (e *Engine) logWriteEvents(points []models.Point) {
+	if len(e.eventLoggers) == 0 || len(points) == 0 {
+		return
+	}
+
+	type bucketWrite struct {
+		name   [influxdb.IDLength]byte
+		points int
+		bytes  int64
+	}
+	var writes []bucketWrite
+	index := make(map[string]int)
+	for _, p := range points {
+		name := p.Name()
+		i, ok := index[string(name)]
+		if !ok {
+			i = len(writes)
+			index[string(name)] = i
+			writes = append(writes, bucketWrite{})
+			copy(writes[i].name[:], name)
+		}
+		writes[i].points++
+		writes[i].bytes += int64(p.StringSize())
+	}
+
+	now := time.Now().UTC()
+	for _, w := range writes {
+		org, bucket := tsdb.DecodeName(w.name)
+		for _, l := range e.eventLoggers {
+			l.LogWrite(org, bucket, w.points, w.bytes, now)
+		}
+	}
+}
+
+// logDeleteEvent records a delete with each event logger. e.mu must be held.
+func (e *Engine) logDeleteEvent(orgID, bucketID influxdb.ID, pred influxdb.Predicate, min, max int64) {
+	if len(e.eventLoggers) == 0 {
+		return
+	}
+
+	predicate := predicateString(pred)
+	now := time.Now().UTC()
+	for _, l := range e.eventLoggers {
+		l.LogDelete(orgID, bucketID, predicate, min, max, now)
+	}
+}
+
+// predicateString returns the predicate as formatted by its String method, or
+// else its marshaled form in base64, as it is added to the WAL.
+func predicateString(pred influxdb.Predicate) string {
+	if pred == nil {
+		return ""
+	}
+	if s, ok := pred.(fmt.Stringer); ok {
+		return s.String()
+	}
+	data, err := pred.Marshal()
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// FileEventLogger is an EventLogger that appends the events to a file, as a
+// JSON record on each line.
+type FileEventLogger struct {
+	log *zap.Logger
+
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+var _ EventLogger = (*FileEventLogger)(nil)
+
+// eventRecord is the JSON record of an event in the file of a FileEventLogger.
+type eventRecord struct {
+	Time      time.Time   `json:"time"`
+	Op        string      `json:"op"`
+	OrgID     influxdb.ID `json:"orgID"`
+	BucketID  influxdb.ID `json:"bucketID"`
+	Points    int         `json:"points,omitempty"`
+	Bytes     int64       `json:"bytes,omitempty"`
+	Predicate string      `json:"predicate,omitempty"`
+	Start     *int64      `json:"start,omitempty"`
+	End       *int64      `json:"end,omitempty"`
+}
+
+// NewFileEventLogger returns a FileEventLogger appending to the file at path,
+// which is created if it does not exist. Errors writing events are logged to
+// log.
+func NewFileEventLogger(path string, log *zap.Logger) (*FileEventLogger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileEventLogger{
+		log: log,
+		f:   f,
+		enc: json.NewEncoder(f),
+	}, nil
+}
+
+// LogWrite appends a record of the write.
+func (l *FileEventLogger) LogWrite(orgID, bucketID influxdb.ID, pointCount int, byteCount int64, ts time.Time) {
+	l.append(eventRecord{
+		Time:     ts,
+		Op:       "write",
+		OrgID:    orgID,
+		BucketID: bucketID,
+		Points:   pointCount,
+		Bytes:    byteCount,
+	})
+}
+
+// LogDelete appends a record of the delete.
+func (l *FileEventLogger) LogDelete(orgID, bucketID influxdb.ID, predicate string, start, end int64, ts time.Time) {
+	l.append(eventRecord{
+		Time:      ts,
+		Op:        "delete",
+		OrgID:     orgID,
+		BucketID:  bucketID,
+		Predicate: predicate,
+		Start:     &start,
+		End:       &end,
+	})
+}
+
+func (l *FileEventLogger) append(r eventRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(r); err != nil {
+		l.log.Error("Failed to write event to event log", zap.String("op", r.Op), zap.Error(err))
+	}
+}
+
+// Close closes the file of the logger.
+func (l *FileEventLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
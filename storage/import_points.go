@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// DefaultImportBatchSize is the number of lines ImportPoints writes at a
+// time by default.
+const DefaultImportBatchSize = 5000
+
+// ImportPoints writes the points of the line protocol read from r to the
+// bucket, with nanosecond timestamps. The line protocol is parsed and written
+// in batches of opts.ImportBatchSize lines as it is read, so that it is not
+// held in memory. It returns the number of points written, in the form the
+// engine stores them, one for each field value.
+//
+// A batch with line protocol that cannot be parsed stops the import. Unless
+// opts.RejectPartial is set, the import continues past the points dropped by
+// a batch, and a tsdb.PartialWriteError for all dropped points is returned
+// after the line protocol is written.
+func (e *Engine) ImportPoints(ctx context.Context, orgID, bucketID influxdb.ID, r io.Reader, opts WriteOptions) (int64, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if !orgID.Valid() || !bucketID.Valid() {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "a valid organization and bucket ID are required to write",
+		}
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	mm := models.EscapeMeasurement(encoded[:])
+
+	batchSize := opts.ImportBatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	var (
+		n       int64
+		partial *tsdb.PartialWriteError
+		buf     []byte
+		lines   int
+	)
+
+	// write parses and writes the batch in buf.
+	write := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		points, err := models.ParsePointsWithPrecision(buf, mm, time.Now().UTC(), "n")
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "failed to parse line protocol",
+				Err:  err,
+			}
+		}
+
+		if opts.Precision > 0 {
+			for _, p := range points {
+				p.SetTime(p.Time().Truncate(opts.Precision))
+			}
+		}
+
+		written := int64(len(points))
+		err = e.writePoints(ctx, points, opts)
+		if pwe, ok := err.(tsdb.PartialWriteError); ok && !opts.RejectPartial {
+			if partial == nil {
+				partial = &tsdb.PartialWriteError{Reason: pwe.Reason}
+			}
+			partial.Dropped += pwe.Dropped
+			partial.DroppedKeys = append(partial.DroppedKeys, pwe.DroppedKeys...)
+			written -= int64(pwe.Dropped)
+		} else if err != nil {
+			return err
+		}
+
+		n += written
+		buf, lines = buf[:0], 0
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		// A line longer than the buffer of the reader is read in parts.
+		start := len(buf)
+		line, err := br.ReadSlice('\n')
+		buf = append(buf, line...)
+		for err == bufio.ErrBufferFull {
+			line, err = br.ReadSlice('\n')
+			buf = append(buf, line...)
+		}
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+
+		if isPointLine(buf[start:]) {
+			lines++
+		}
+		if lines >= batchSize || (err == io.EOF && lines > 0) {
+			if err := write(); err != nil {
+				return n, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if partial != nil {
+		return n, *partial
+	}
+	return n, nil
+}
+
+// isPointLine returns whether the line of line protocol is a point, rather
+// than a blank line or a comment.
+func isPointLine(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return len(line) > 0 && line[0] != '#'
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+// MeasurementAliasesFileName is the name of the file in the engine's
+// directory holding the measurements renamed by MeasurementRename.
+const MeasurementAliasesFileName = "measurement_aliases.json"
+
+// measurementAlias records that the data stored under a measurement is read
+// as another until it is rewritten.
+type measurementAlias struct {
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+}
+
+// measurementAliases is the index of the measurements renamed by
+// MeasurementRename whose data has not yet been rewritten.
+//
+// The data of a renamed measurement stays stored under its old name, which
+// is read as the new one. Points written to the new name are stored under
+// the old one too, so that all of the measurement's data is in one place
+// until it is rewritten. The old name itself can no longer be read, acting
+// as a tombstone.
+//
+// measurementAliases is not safe for concurrent use.
+type measurementAliases struct {
+	path string
+
+	// renamed maps the stored measurements of each bucket to the names they
+	// are read as.
+	renamed map[[16]byte]map[string]string
+}
+
+// loadMeasurementAliases loads the aliases in the file at path, which need
+// not exist.
+func loadMeasurementAliases(path string) (*measurementAliases, error) {
+	a := &measurementAliases{path: path, renamed: make(map[[16]byte]map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var aliases []measurementAlias
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		a.set(tsdb.EncodeName(alias.OrgID, alias.BucketID), alias.From, alias.To)
+	}
+	return a, nil
+}
+
+// save writes the aliases to their file.
+func (a *measurementAliases) save() error {
+	aliases := a.list()
+	if len(aliases) == 0 {
+		if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+	tmp := a.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.path)
+}
+
+// list returns the aliases, ordered by bucket and stored measurement.
+func (a *measurementAliases) list() []measurementAlias {
+	var aliases []measurementAlias
+	for name, renamed := range a.renamed {
+		org, bucket := tsdb.DecodeName(name)
+		for from, to := range renamed {
+			aliases = append(aliases, measurementAlias{OrgID: org, BucketID: bucket, From: from, To: to})
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		x, y := aliases[i], aliases[j]
+		if x.OrgID != y.OrgID {
+			return x.OrgID < y.OrgID
+		}
+		if x.BucketID != y.BucketID {
+			return x.BucketID < y.BucketID
+		}
+		return x.From < y.From
+	})
+	return aliases
+}
+
+func (a *measurementAliases) set(name [16]byte, from, to string) {
+	renamed := a.renamed[name]
+	if renamed == nil {
+		renamed = make(map[string]string)
+		a.renamed[name] = renamed
+	}
+	renamed[from] = to
+}
+
+// remove removes the alias of the measurement stored as from.
+func (a *measurementAliases) remove(name [16]byte, from string) {
+	renamed := a.renamed[name]
+	delete(renamed, from)
+	if len(renamed) == 0 {
+		delete(a.renamed, name)
+	}
+}
+
+// rename reads the measurement read as from as to. A measurement renamed
+// back to the name it is stored as no longer has an alias.
+func (a *measurementAliases) rename(name [16]byte, from, to string) {
+	stored := from
+	if s, ok := a.stored(name, from); ok {
+		stored = s
+	}
+	if stored == to {
+		a.remove(name, stored)
+		return
+	}
+	a.set(name, stored, to)
+}
+
+// bucket returns the measurements of the bucket stored under another name,
+// mapped to the names they are read as.
+func (a *measurementAliases) bucket(name [16]byte) map[string]string {
+	return a.renamed[name]
+}
+
+// stored returns the name a measurement read as measurement is stored as,
+// if it was renamed.
+func (a *measurementAliases) stored(name [16]byte, measurement string) (string, bool) {
+	for from, to := range a.renamed[name] {
+		if to == measurement {
+			return from, true
+		}
+	}
+	return "", false
+}
+
+// hidden reports whether measurement is stored under its name but read as
+// another.
+func (a *measurementAliases) hidden(name [16]byte, measurement string) bool {
+	_, ok := a.renamed[name][measurement]
+	return ok
+}
+
+// rewriteMeasurementExpr rewrites cond, a condition on the tags of series as
+// they are read, to a condition on the series as they are stored in a bucket
+// with the renamed measurements. Series stored under the new name of a
+// measurement, such as those being rewritten, are excluded.
+func rewriteMeasurementExpr(renamed map[string]string, cond influxql.Expr) influxql.Expr {
+	if len(renamed) == 0 {
+		return cond
+	}
+
+	stored := make(map[string]string, len(renamed))
+	for from, to := range renamed {
+		stored[to] = from
+	}
+
+	expr := influxql.Expr(&influxql.BooleanLiteral{Val: true})
+	if cond != nil {
+		expr = influxql.RewriteExpr(influxql.CloneExpr(cond), func(e influxql.Expr) influxql.Expr {
+			be, ok := e.(*influxql.BinaryExpr)
+			if !ok {
+				return e
+			}
+			if ref, ok := be.LHS.(*influxql.VarRef); !ok || ref.Val != models.MeasurementTagKey {
+				return e
+			}
+			return rewriteMeasurementComparison(be, renamed, stored)
+		})
+	}
+
+	for to := range stored {
+		expr = andExpr(expr, measurementComparison(influxql.NEQ, to))
+	}
+	return influxql.Reduce(expr, nil)
+}
+
+// rewriteMeasurementComparison rewrites a comparison of the measurement.
+func rewriteMeasurementComparison(be *influxql.BinaryExpr, renamed, stored map[string]string) influxql.Expr {
+	switch lit := be.RHS.(type) {
+	case *influxql.StringLiteral:
+		from, isRenamed := stored[lit.Val]
+		_, isHidden := renamed[lit.Val]
+		switch {
+		case isRenamed:
+			return measurementComparison(be.Op, from)
+		case isHidden && be.Op == influxql.EQ:
+			return &influxql.BooleanLiteral{Val: false}
+		case isHidden && be.Op == influxql.NEQ:
+			return &influxql.BooleanLiteral{Val: true}
+		}
+
+	case *influxql.RegexLiteral:
+		if be.Op != influxql.EQREGEX && be.Op != influxql.NEQREGEX {
+			return be
+		}
+
+		// The regex applies to the series stored under their own name, and
+		// to the renamed measurements by their new name.
+		expr := influxql.Expr(be)
+		for from := range renamed {
+			expr = andExpr(expr, measurementComparison(influxql.NEQ, from))
+		}
+		for from, to := range renamed {
+			if lit.Val.MatchString(to) == (be.Op == influxql.EQREGEX) {
+				expr = &influxql.BinaryExpr{Op: influxql.OR, LHS: expr, RHS: measurementComparison(influxql.EQ, from)}
+			}
+		}
+		return expr
+	}
+	return be
+}
+
+func measurementComparison(op influxql.Token, measurement string) influxql.Expr {
+	return &influxql.BinaryExpr{
+		Op:  op,
+		LHS: &influxql.VarRef{Val: models.MeasurementTagKey},
+		RHS: &influxql.StringLiteral{Val: measurement},
+	}
+}
+
+func andExpr(lhs, rhs influxql.Expr) influxql.Expr {
+	return &influxql.BinaryExpr{Op: influxql.AND, LHS: lhs, RHS: rhs}
+}
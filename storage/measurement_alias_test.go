@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+func TestRewriteMeasurementExpr(t *testing.T) {
+	// cpu_old is read as cpu, and the series stored as cpu are being
+	// rewritten from it.
+	renamed := map[string]string{"cpu_old": "cpu"}
+	stored := []string{"cpu_old", "cpu", "mem"}
+
+	for _, tt := range []struct {
+		cond string
+		exp  []string // the stored measurements matched
+	}{
+		{cond: "", exp: []string{"cpu_old", "mem"}},
+		{cond: `_measurement = 'cpu'`, exp: []string{"cpu_old"}},
+		{cond: `_measurement = 'cpu_old'`, exp: nil},
+		{cond: `_measurement != 'cpu'`, exp: []string{"mem"}},
+		{cond: `_measurement != 'cpu_old' AND host = 'a'`, exp: []string{"cpu_old", "mem"}},
+		{cond: `_measurement = 'mem' OR host = 'b'`, exp: []string{"mem"}},
+		{cond: `_measurement =~ /^cpu/`, exp: []string{"cpu_old"}},
+		{cond: `_measurement =~ /^(cpu_old|mem)$/`, exp: []string{"mem"}},
+		{cond: `_measurement !~ /^cpu$/`, exp: []string{"mem"}},
+		{cond: `_measurement !~ /^mem$/`, exp: []string{"cpu_old"}},
+	} {
+		t.Run(tt.cond, func(t *testing.T) {
+			// The conditions refer to the measurement as _measurement.
+			var cond influxql.Expr
+			if tt.cond != "" {
+				cond = influxql.RewriteExpr(influxql.MustParseExpr(tt.cond), func(e influxql.Expr) influxql.Expr {
+					if ref, ok := e.(*influxql.VarRef); ok && ref.Val == "_measurement" {
+						ref.Val = models.MeasurementTagKey
+					}
+					return e
+				})
+			}
+
+			expr := rewriteMeasurementExpr(renamed, cond)
+			var got []string
+			for _, m := range stored {
+				if influxql.EvalBool(expr, influxql.MapValuer{models.MeasurementTagKey: m, "host": "a"}) {
+					got = append(got, m)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.exp) {
+				t.Fatalf("unexpected measurements matched by %s: got %v, exp %v", expr, got, tt.exp)
+			}
+		})
+	}
+}
+
+func TestEngine_RewriteRenamedMeasurements(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	e := NewEngine(dir, NewConfig(), WithNodeID(102), WithEngineID(34))
+	if err := e.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(1), influxdb.ID(2)
+	pt := models.MustNewPoint(tsdb.EncodeNameString(org, bucket), models.NewTags(map[string]string{
+		models.MeasurementTagKey: "cpu_old",
+		models.FieldKeyTagKey:    "value",
+		"host":                   "a",
+	}), models.Fields{"value": 1.0}, time.Unix(1, 0))
+	if err := e.WritePoints(ctx, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.MeasurementRename(ctx, org, bucket, "cpu_old", "cpu"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The alias survives reopening the engine.
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := e.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.renamedMeasurements(org, bucket); got["cpu_old"] != "cpu" {
+		t.Fatalf("expected cpu_old to be renamed to cpu, got %v", got)
+	}
+
+	if err := e.rewriteRenamedMeasurements(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.renamedMeasurements(org, bucket); got != nil {
+		t.Fatalf("expected no renamed measurements after rewriting, got %v", got)
+	}
+
+	name := tsdb.EncodeName(org, bucket)
+	for _, tt := range []struct {
+		measurement string
+		n           int
+	}{
+		{measurement: "cpu", n: 1},
+		{measurement: "cpu_old", n: 0},
+	} {
+		keys, err := e.measurementSeriesKeys(name, tt.measurement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(keys); got != tt.n {
+			t.Fatalf("got %d series of %s, exp %d", got, tt.measurement, tt.n)
+		}
+	}
+	if _, err := os.Stat(e.aliases.path); !os.IsNotExist(err) {
+		t.Fatalf("expected the aliases file to be removed, got %v", err)
+	}
+}
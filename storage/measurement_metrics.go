@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherMeasurementLabel is the measurement label of the metrics of the
+// measurements that are not in the allowlist of the engine.
+const otherMeasurementLabel = "_other"
+
+// mems are the measurement metrics shared by all engines, guarded by mmu.
+var mems *measurementMetrics
+
+// MeasurementPrometheusCollectors returns all prometheus metrics for the
+// activity of measurements.
+func MeasurementPrometheusCollectors() []prometheus.Collector {
+	mmu.RLock()
+	defer mmu.RUnlock()
+
+	var collectors []prometheus.Collector
+	if mems != nil {
+		collectors = append(collectors, mems.PrometheusCollectors()...)
+	}
+	return collectors
+}
+
+// measurementMetrics is a set of metrics of the writes and reads of each
+// measurement.
+type measurementMetrics struct {
+	labels       prometheus.Labels
+	Writes       *prometheus.CounterVec
+	BytesWritten *prometheus.CounterVec
+	Reads        *prometheus.CounterVec
+}
+
+func newMeasurementMetrics(labels prometheus.Labels) *measurementMetrics {
+	var names []string
+	for k := range labels {
+		names = append(names, k)
+	}
+	names = append(names, "measurement")
+	sort.Strings(names)
+
+	return &measurementMetrics{
+		labels: labels,
+		Writes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "writes_total",
+			Help:      "Number of writes that wrote points of the measurement.",
+		}, names),
+		BytesWritten: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_written_total",
+			Help:      "Number of bytes of line protocol of the points written to the measurement.",
+		}, names),
+		Reads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "query",
+			Name:      "reads_total",
+			Help:      "Number of reads whose filter compares the measurement for equality.",
+		}, names),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *measurementMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.Writes,
+		m.BytesWritten,
+		m.Reads,
+	}
+}
+
+// measurementTracker tracks the writes and reads of the measurements of an
+// engine. Measurements not in its allowlist are tracked together, so that
+// the cardinality of the metrics is bounded.
+type measurementTracker struct {
+	metrics *measurementMetrics
+	labels  prometheus.Labels
+	allowed map[string]bool
+}
+
+func newMeasurementTracker(defaultLabels prometheus.Labels, allowlist []string) *measurementTracker {
+	mmu.Lock()
+	if mems == nil {
+		mems = newMeasurementMetrics(defaultLabels)
+	}
+	mmu.Unlock()
+
+	// The metrics are labeled by the default labels of the first engine.
+	labels := make(prometheus.Labels, len(mems.labels))
+	for k := range mems.labels {
+		labels[k] = defaultLabels[k]
+	}
+
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+	return &measurementTracker{metrics: mems, labels: labels, allowed: allowed}
+}
+
+// Labels returns a copy of the labels of the metrics of measurement.
+func (t *measurementTracker) Labels(measurement string) prometheus.Labels {
+	l := make(map[string]string, len(t.labels)+1)
+	for k, v := range t.labels {
+		l[k] = v
+	}
+	if !t.allowed[measurement] {
+		measurement = otherMeasurementLabel
+	}
+	l["measurement"] = measurement
+	return l
+}
+
+// AddWrite records a write of points, in the form the engine stores them.
+func (t *measurementTracker) AddWrite(points []models.Point) {
+	bytes := make(map[string]int)
+	for _, p := range points {
+		m := string(p.Tags().Get(models.MeasurementTagKeyBytes))
+		if !t.allowed[m] {
+			m = otherMeasurementLabel
+		}
+		bytes[m] += p.StringSize()
+	}
+
+	for m, n := range bytes {
+		labels := t.Labels(m)
+		t.metrics.Writes.With(labels).Inc()
+		t.metrics.BytesWritten.With(labels).Add(float64(n))
+	}
+}
+
+// AddRead records a read with the condition cond, once for each measurement
+// it compares for equality.
+func (t *measurementTracker) AddRead(cond influxql.Expr) {
+	if cond == nil {
+		return
+	}
+
+	read := make(map[string]bool)
+	influxql.WalkFunc(cond, func(n influxql.Node) {
+		be, ok := n.(*influxql.BinaryExpr)
+		if !ok || be.Op != influxql.EQ {
+			return
+		}
+		if ref, ok := be.LHS.(*influxql.VarRef); !ok || ref.Val != models.MeasurementTagKey {
+			return
+		}
+		if lit, ok := be.RHS.(*influxql.StringLiteral); ok {
+			m := lit.Val
+			if !t.allowed[m] {
+				m = otherMeasurementLabel
+			}
+			read[m] = true
+		}
+	})
+
+	for m := range read {
+		t.metrics.Reads.With(t.Labels(m)).Inc()
+	}
+}
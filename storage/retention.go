@@ -2,8 +2,13 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"math"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb"
@@ -46,9 +51,13 @@ type retentionEnforcer struct {
 	Snapshotter Snapshotter
 
 	// BucketService provides an API for retrieving buckets associated with
-	// organisations.
+	// organisations. It may be nil if only registered policies are enforced.
 	BucketService BucketFinder
 
+	// Policies holds the retention periods registered with the engine, which
+	// take precedence over those of the buckets.
+	Policies *retentionPolicies
+
 	logger *zap.Logger
 
 	tracker *retentionTracker
@@ -62,6 +71,7 @@ func newRetentionEnforcer(engine Deleter, snapshotter Snapshotter, bucketService
 		Engine:        engine,
 		Snapshotter:   snapshotter,
 		BucketService: bucketService,
+		Policies:      newRetentionPolicies(),
 		logger:        zap.NewNop(),
 		tracker:       newRetentionTracker(newRetentionMetrics(nil), nil),
 	}
@@ -107,7 +117,7 @@ func (s *retentionEnforcer) run() {
 	buckets, err := s.getBucketInformation(ctx)
 	if err != nil {
 		log.Error("Unable to determine bucket information", zap.Error(err))
-	} else {
+	} else if len(buckets) > 0 {
 		s.expireData(ctx, buckets, now)
 	}
 	s.tracker.CheckDuration(time.Since(now), err == nil)
@@ -177,11 +187,168 @@ func (s *retentionEnforcer) expireData(ctx context.Context, buckets []*influxdb.
 
 // getBucketInformation returns a slice of buckets to run retention on.
 func (s *retentionEnforcer) getBucketInformation(ctx context.Context) ([]*influxdb.Bucket, error) {
-	ctx, cancel := context.WithTimeout(ctx, bucketAPITimeout)
-	defer cancel()
+	var buckets []*influxdb.Bucket
+	if s.BucketService != nil {
+		ctx, cancel := context.WithTimeout(ctx, bucketAPITimeout)
+		defer cancel()
+
+		var err error
+		if buckets, _, err = s.BucketService.FindBuckets(ctx, influxdb.BucketFilter{}); err != nil {
+			return nil, err
+		}
+	}
+	return s.Policies.apply(buckets), nil
+}
+
+// RetentionPoliciesFileName is the name of the file in the engine's
+// directory holding the retention policies registered with
+// SetRetentionPolicy.
+const RetentionPoliciesFileName = "retention_policies.json"
+
+// retentionPolicyRecord is the retention policy of a bucket in the file of
+// the retention policies.
+type retentionPolicyRecord struct {
+	OrgID    influxdb.ID   `json:"orgID"`
+	BucketID influxdb.ID   `json:"bucketID"`
+	Duration time.Duration `json:"duration"`
+}
+
+// retentionPolicies holds retention periods registered for buckets of
+// organisations.
+type retentionPolicies struct {
+	mu       sync.RWMutex
+	path     string // file the policies are saved to by put, if any
+	policies map[[2]influxdb.ID]time.Duration
+}
+
+func newRetentionPolicies() *retentionPolicies {
+	return &retentionPolicies{policies: make(map[[2]influxdb.ID]time.Duration)}
+}
+
+// loadRetentionPolicies loads the policies in the file at path, which need
+// not exist.
+func loadRetentionPolicies(path string) (*retentionPolicies, error) {
+	p := newRetentionPolicies()
+	p.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []retentionPolicyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		p.set(r.OrgID, r.BucketID, r.Duration)
+	}
+	return p, nil
+}
+
+// set registers the retention period d for the bucket. A period of 0 removes
+// the bucket's policy.
+func (p *retentionPolicies) set(orgID, bucketID influxdb.ID, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setLocked([2]influxdb.ID{orgID, bucketID}, d)
+}
+
+func (p *retentionPolicies) setLocked(key [2]influxdb.ID, d time.Duration) {
+	if d == 0 {
+		delete(p.policies, key)
+		return
+	}
+	p.policies[key] = d
+}
 
-	buckets, _, err := s.BucketService.FindBuckets(ctx, influxdb.BucketFilter{})
-	return buckets, err
+// put registers the retention period d for the bucket like set, and saves
+// the policies to their file. The policies are left unchanged if they cannot
+// be saved.
+func (p *retentionPolicies) put(orgID, bucketID influxdb.ID, d time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := [2]influxdb.ID{orgID, bucketID}
+	prev := p.policies[key]
+	p.setLocked(key, d)
+	if err := p.save(); err != nil {
+		p.setLocked(key, prev)
+		return err
+	}
+	return nil
+}
+
+// save writes the policies to their file. p.mu must be held.
+func (p *retentionPolicies) save() error {
+	if p.path == "" {
+		return nil
+	}
+
+	records := make([]retentionPolicyRecord, 0, len(p.policies))
+	for key, d := range p.policies {
+		records = append(records, retentionPolicyRecord{OrgID: key[0], BucketID: key[1], Duration: d})
+	}
+	if len(records) == 0 {
+		if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sort.Slice(records, func(i, j int) bool {
+		x, y := records[i], records[j]
+		if x.OrgID != y.OrgID {
+			return x.OrgID < y.OrgID
+		}
+		return x.BucketID < y.BucketID
+	})
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// apply returns buckets with the registered retention periods in place of
+// their own, followed by any buckets with a registered policy that are not in
+// buckets. The buckets passed in are not modified.
+func (p *retentionPolicies) apply(buckets []*influxdb.Bucket) []*influxdb.Bucket {
+	if p == nil {
+		return buckets
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.policies) == 0 {
+		return buckets
+	}
+
+	seen := make(map[[2]influxdb.ID]struct{}, len(p.policies))
+	a := make([]*influxdb.Bucket, 0, len(buckets)+len(p.policies))
+	for _, b := range buckets {
+		key := [2]influxdb.ID{b.OrgID, b.ID}
+		if d, ok := p.policies[key]; ok {
+			other := *b
+			other.RetentionPeriod = d
+			b = &other
+			seen[key] = struct{}{}
+		}
+		a = append(a, b)
+	}
+
+	for key, d := range p.policies {
+		if _, ok := seen[key]; !ok {
+			a = append(a, &influxdb.Bucket{OrgID: key[0], ID: key[1], RetentionPeriod: d})
+		}
+	}
+	return a
 }
 
 //
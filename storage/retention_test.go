@@ -17,8 +17,10 @@ import (
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/prom/promtest"
 	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -240,6 +242,128 @@ func TestRetentionService(t *testing.T) {
 	})
 }
 
+func TestEngine_SetRetentionPolicy(t *testing.T) {
+	path := MustTempDir()
+	defer os.RemoveAll(path)
+
+	engine := NewEngine(path, NewConfig(), WithNodeID(102), WithEngineID(33))
+	if err := engine.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { engine.Close() }()
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	now := time.Now()
+	point := func(host string, ts time.Time) models.Point {
+		return models.MustNewPoint(
+			tsdb.EncodeNameString(org, bucket),
+			models.NewTags(map[string]string{
+				models.MeasurementTagKey: "cpu",
+				models.FieldKeyTagKey:    "value",
+				"host":                   host,
+			}),
+			map[string]interface{}{"value": 1.0},
+			ts,
+		)
+	}
+	if err := engine.WritePoints(context.Background(), []models.Point{
+		point("old", now.Add(-2*time.Hour)),
+		point("new", now),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// count returns the number of values of the series.
+	count := func(host string) int {
+		t.Helper()
+		itr, err := engine.CreateCursorIterator(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cur, err := itr.Next(context.Background(), &cursors.CursorRequest{
+			Name:      tsdb.EncodeNameSlice(org, bucket),
+			Tags:      point(host, now).Tags(),
+			Field:     "value",
+			Ascending: true,
+			StartTime: models.MinNanoTime,
+			EndTime:   models.MaxNanoTime,
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if cur == nil {
+			return 0
+		}
+		defer cur.Close()
+
+		var n int
+		fcur := cur.(cursors.FloatArrayCursor)
+		for a := fcur.Next(); a.Len() > 0; a = fcur.Next() {
+			n += a.Len()
+		}
+		return n
+	}
+
+	if got := count("old"); got != 1 {
+		t.Fatalf("got %d old values, exp 1", got)
+	}
+	if got := count("new"); got != 1 {
+		t.Fatalf("got %d new values, exp 1", got)
+	}
+
+	if err := engine.SetRetentionPolicy(org, bucket, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy is enforced after the engine is reopened.
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	engine = NewEngine(path, NewConfig(), WithNodeID(102), WithEngineID(33))
+	if err := engine.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	engine.retentionEnforcer.run()
+
+	if got := count("old"); got != 0 {
+		t.Fatalf("got %d old values after retention, exp 0", got)
+	}
+	if got := count("new"); got != 1 {
+		t.Fatalf("got %d new values after retention, exp 1", got)
+	}
+}
+
+func TestRetentionPolicies_apply(t *testing.T) {
+	p := newRetentionPolicies()
+	buckets := []*influxdb.Bucket{
+		{OrgID: 1, ID: 10, RetentionPeriod: time.Hour},
+		{OrgID: 1, ID: 11, RetentionPeriod: time.Hour},
+	}
+
+	if got := p.apply(buckets); !reflect.DeepEqual(got, buckets) {
+		t.Fatalf("got %v, exp buckets unchanged", got)
+	}
+
+	p.set(1, 11, time.Minute)
+	p.set(2, 20, time.Second)
+	exp := []*influxdb.Bucket{
+		{OrgID: 1, ID: 10, RetentionPeriod: time.Hour},
+		{OrgID: 1, ID: 11, RetentionPeriod: time.Minute},
+		{OrgID: 2, ID: 20, RetentionPeriod: time.Second},
+	}
+	if got := p.apply(buckets); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, exp %v", got, exp)
+	}
+	if buckets[1].RetentionPeriod != time.Hour {
+		t.Fatal("expected buckets not to be modified")
+	}
+
+	p.set(1, 11, 0)
+	p.set(2, 20, 0)
+	if got := p.apply(buckets); !reflect.DeepEqual(got, buckets) {
+		t.Fatalf("got %v, exp policies removed", got)
+	}
+}
+
 func TestMetrics_Retention(t *testing.T) {
 	t.Parallel()
 	// metrics to be shared by multiple file stores.
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// SchemaValidator is a WriteValidator that rejects points whose fields do
+// not match the registered schema of their measurement.
+type SchemaValidator struct {
+	svc influxdb.SchemaService
+
+	// strict rejects points of measurements without a schema.
+	strict bool
+}
+
+// NewSchemaValidator returns a SchemaValidator validating points with svc.
+// If strict is true, points of measurements without a registered schema are
+// rejected too.
+func NewSchemaValidator(svc influxdb.SchemaService, strict bool) *SchemaValidator {
+	return &SchemaValidator{svc: svc, strict: strict}
+}
+
+type schemaMeasurement struct {
+	org, bucket influxdb.ID
+	measurement string
+}
+
+// Validate returns an EInvalid error for the first point that does not match
+// the schema of its measurement.
+func (v *SchemaValidator) Validate(points []models.Point) error {
+	ctx := context.Background()
+
+	// The measurements of the write without a schema, which are only looked
+	// up once.
+	var unregistered map[schemaMeasurement]bool
+
+	for _, p := range points {
+		org, bucket := tsdb.DecodeNameSlice(p.Name())
+		m := schemaMeasurement{org: org, bucket: bucket, measurement: string(p.Tags().Get(models.MeasurementTagKeyBytes))}
+		if unregistered[m] {
+			continue
+		}
+
+		err := v.svc.ValidatePoint(ctx, org, bucket, p)
+		if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if v.strict {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("measurement '%s' has no schema", m.measurement),
+			}
+		}
+		if unregistered == nil {
+			unregistered = make(map[schemaMeasurement]bool)
+		}
+		unregistered[m] = true
+	}
+	return nil
+}
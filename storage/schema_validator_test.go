@@ -0,0 +1,96 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSchemaValidator(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	if err := svc.CreateMeasurementSchema(ctx, engine.org, engine.bucket, influxdb.MeasurementSchema{
+		Measurement: "cpu",
+		Fields: []influxdb.MeasurementSchemaField{
+			{Name: "usage", Type: influxdb.SchemaFieldTypeFloat},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(measurement, field string, v interface{}) models.Point {
+		tags := models.NewTags(map[string]string{
+			models.MeasurementTagKey: measurement,
+			models.FieldKeyTagKey:    field,
+		})
+		return models.MustNewPoint(name, tags, models.Fields{field: v}, time.Unix(1, 0))
+	}
+
+	t.Run("registered schema", func(t *testing.T) {
+		v := storage.NewSchemaValidator(svc, false)
+		for _, tt := range []struct {
+			name  string
+			point models.Point
+			code  string
+		}{
+			{name: "valid", point: point("cpu", "usage", 1.0)},
+			{name: "unknown field", point: point("cpu", "idle", 1.0), code: influxdb.EInvalid},
+			{name: "wrong type", point: point("cpu", "usage", int64(1)), code: influxdb.EInvalid},
+			{name: "no schema", point: point("mem", "free", int64(1))},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				err := v.Validate([]models.Point{tt.point})
+				if got := influxdb.ErrorCode(err); got != tt.code {
+					t.Fatalf("unexpected error code: got %q, exp %q (%v)", got, tt.code, err)
+				}
+			})
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		v := storage.NewSchemaValidator(svc, true)
+		if err := v.Validate([]models.Point{point("cpu", "usage", 1.0)}); err != nil {
+			t.Fatal(err)
+		}
+		err := v.Validate([]models.Point{point("mem", "free", int64(1))})
+		if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+			t.Fatalf("unexpected error code: got %q, exp %q", got, exp)
+		}
+	})
+
+	t.Run("engine", func(t *testing.T) {
+		engine.RegisterWriteValidator(storage.NewSchemaValidator(svc, false))
+
+		err := engine.Engine.WritePoints(ctx, []models.Point{
+			point("cpu", "usage", 1.0),
+			point("cpu", "idle", 1.0),
+		})
+		if got, exp := influxdb.ErrorCode(err), influxdb.EInvalid; got != exp {
+			t.Fatalf("unexpected error code: got %q, exp %q", got, exp)
+		}
+		if got, exp := engine.SeriesCardinality(), int64(0); got != exp {
+			t.Fatalf("got %v series, exp %v series in index", got, exp)
+		}
+
+		if err := engine.Engine.WritePoints(ctx, []models.Point{point("cpu", "usage", 1.0)}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
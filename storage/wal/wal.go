@@ -394,6 +394,11 @@ func (l *WAL) Remove(ctx context.Context, files []string) error {
 
 	var totalOldDiskSize int64
 	for _, seg := range segments {
+		// The current segment is tracked separately.
+		if l.currentSegmentWriter != nil && seg == l.currentSegmentWriter.path() {
+			continue
+		}
+
 		stat, err := os.Stat(seg)
 		if err != nil {
 			return err
@@ -417,6 +422,11 @@ func (l *WAL) DiskSizeBytes() int64 {
 	return int64(l.tracker.OldSegmentSize() + l.tracker.CurrentSegmentSize())
 }
 
+// SegmentCount returns the number of segment files of the WAL on disk.
+func (l *WAL) SegmentCount() int {
+	return int(l.tracker.Segments())
+}
+
 func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 	// limit how many concurrent encodings can be in flight.  Since we can only
 	// write one at a time to disk, a slow disk can cause the allocations below
@@ -586,7 +596,7 @@ func (l *WAL) newSegmentFile() error {
 		if err := l.currentSegmentWriter.close(); err != nil {
 			return err
 		}
-		l.tracker.SetOldSegmentSize(uint64(l.currentSegmentWriter.size))
+		l.tracker.SetOldSegmentSize(l.tracker.OldSegmentSize() + uint64(l.currentSegmentWriter.size))
 	}
 
 	fileName := filepath.Join(l.path, fmt.Sprintf("%s%05d.%s", WALFilePrefix, l.currentSegmentID, WALFileExtension))
@@ -611,9 +621,11 @@ func (l *WAL) newSegmentFile() error {
 // *NOTE* - walTracker fields should not be directory modified. Doing so
 // could result in the Engine exposing inaccurate metrics.
 type walTracker struct {
-	metrics         *walMetrics
-	labels          prometheus.Labels
-	oldSegmentBytes uint64
+	metrics             *walMetrics
+	labels              prometheus.Labels
+	oldSegmentBytes     uint64
+	currentSegmentBytes uint64
+	segments            uint64
 }
 
 func newWALTracker(metrics *walMetrics, defaultLabels prometheus.Labels) *walTracker {
@@ -654,35 +666,44 @@ func (t *walTracker) SetOldSegmentSize(sz uint64) {
 // OldSegmentSize returns the on-disk size of all old segments.
 func (t *walTracker) OldSegmentSize() uint64 { return atomic.LoadUint64(&t.oldSegmentBytes) }
 
-// SetCurrentSegmentSize sets the size of all old segments on disk.
+// SetCurrentSegmentSize sets the size of the current segment on disk.
 func (t *walTracker) SetCurrentSegmentSize(sz uint64) {
-	atomic.StoreUint64(&t.oldSegmentBytes, sz)
+	atomic.StoreUint64(&t.currentSegmentBytes, sz)
 
 	labels := t.labels
 	t.metrics.CurrentSegmentBytes.With(labels).Set(float64(sz))
 }
 
-// CurrentSegmentSize returns the on-disk size of all old segments.
-func (t *walTracker) CurrentSegmentSize() uint64 { return atomic.LoadUint64(&t.oldSegmentBytes) }
+// CurrentSegmentSize returns the on-disk size of the current segment.
+func (t *walTracker) CurrentSegmentSize() uint64 { return atomic.LoadUint64(&t.currentSegmentBytes) }
 
 // SetSegments sets the number of segments files on disk.
 func (t *walTracker) SetSegments(sz uint64) {
+	atomic.StoreUint64(&t.segments, sz)
+
 	labels := t.labels
 	t.metrics.Segments.With(labels).Set(float64(sz))
 }
 
 // IncSegments increases the number of segments files by one.
 func (t *walTracker) IncSegments() {
+	atomic.AddUint64(&t.segments, 1)
+
 	labels := t.labels
 	t.metrics.Segments.With(labels).Inc()
 }
 
 // DecSegments decreases the number of segments files by one.
 func (t *walTracker) DecSegments() {
+	atomic.AddUint64(&t.segments, ^uint64(0))
+
 	labels := t.labels
 	t.metrics.Segments.With(labels).Dec()
 }
 
+// Segments returns the number of segment files on disk.
+func (t *walTracker) Segments() uint64 { return atomic.LoadUint64(&t.segments) }
+
 // WALEntry is record stored in each WAL segment.  Each entry has a type
 // and an opaque, type dependent byte slice data attribute.
 type WALEntry interface {
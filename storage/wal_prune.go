@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+// signalWALPrune signals the WAL pruner if the WAL exceeds its maximum disk
+// size. It does not block, since a signal already pending prunes the WAL.
+func (e *Engine) signalWALPrune() {
+	max := int64(e.config.WAL.MaxDiskBytes)
+	if max <= 0 || e.wal.DiskSizeBytes() <= max {
+		return
+	}
+
+	select {
+	case e.walPruneC <- struct{}{}:
+	default:
+	}
+}
+
+// runWALPruner flushes the cache to TSM files when the WAL exceeds its
+// maximum disk size, so that the segments of the flushed values are removed
+// before the WAL fills the disk.
+func (e *Engine) runWALPruner() {
+	l := e.logger.With(zap.String("component", "wal_pruner"))
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			// It's safe to read closing without a lock because it's never
+			// modified if this goroutine is active.
+			select {
+			case <-e.closing:
+				return
+			case <-e.walPruneC:
+				e.pruneWAL(context.Background(), l)
+			}
+		}
+	}()
+}
+
+// pruneWAL flushes the cache if the WAL still exceeds its maximum disk size.
+func (e *Engine) pruneWAL(ctx context.Context, l *zap.Logger) {
+	max := int64(e.config.WAL.MaxDiskBytes)
+	size := e.wal.DiskSizeBytes()
+	if size <= max {
+		return
+	}
+
+	l.Warn("WAL exceeds maximum disk size, flushing cache to TSM files",
+		zap.Int64("wal_bytes", size),
+		zap.Int64("max_wal_bytes", max))
+
+	err := e.engine.WriteSnapshot(ctx, tsm1.CacheStatusWALSizeExceeded)
+	if err != nil && err != tsm1.ErrSnapshotInProgress {
+		l.Error("Failed to flush cache", zap.Error(err))
+		return
+	}
+	l.Info("Flushed cache", zap.Int64("wal_bytes", e.wal.DiskSizeBytes()))
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+const (
+	// writeEventSampleSize is the maximum number of points of a write that
+	// are sampled in its event.
+	writeEventSampleSize = 10
+
+	// writeEventBufferSize is the number of events buffered for each
+	// subscriber. Events are dropped for a subscriber whose buffer is full,
+	// so that a slow subscriber does not slow down writes.
+	writeEventBufferSize = 64
+)
+
+// WriteEvent is sent to the subscribers of write events for each bucket that
+// a write accepted by the engine wrote to.
+type WriteEvent struct {
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Points are the first points written to the bucket, at most
+	// writeEventSampleSize of them. They are the points the engine stores,
+	// as with RegisterWriteSideEffect.
+	Points []models.Point
+}
+
+// WriteEvents subscribes to the writes accepted by the engine, and returns the
+// channel their events are sent to. Events are dropped while the channel is
+// full, rather than blocking writes. The subscription lasts until it is
+// stopped by StopWriteEvents.
+func (e *Engine) WriteEvents() <-chan WriteEvent {
+	c := make(chan WriteEvent, writeEventBufferSize)
+
+	e.writeSubsMu.Lock()
+	defer e.writeSubsMu.Unlock()
+	if e.writeSubs == nil {
+		e.writeSubs = make(map[<-chan WriteEvent]chan WriteEvent)
+	}
+	e.writeSubs[c] = c
+	return c
+}
+
+// StopWriteEvents ends the subscription of c, returned by WriteEvents, and
+// closes it.
+func (e *Engine) StopWriteEvents(c <-chan WriteEvent) {
+	e.writeSubsMu.Lock()
+	defer e.writeSubsMu.Unlock()
+	if sub, ok := e.writeSubs[c]; ok {
+		delete(e.writeSubs, c)
+		close(sub)
+	}
+}
+
+// publishWriteEvents sends the events of a write to each subscriber whose
+// channel is not full.
+func (e *Engine) publishWriteEvents(points []models.Point) {
+	e.writeSubsMu.RLock()
+	defer e.writeSubsMu.RUnlock()
+	if len(e.writeSubs) == 0 || len(points) == 0 {
+		return
+	}
+
+	events := writeEvents(points)
+	for _, c := range e.writeSubs {
+		for _, ev := range events {
+			select {
+			case c <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// writeEvents returns the events of the buckets written to by points, in the
+// order of their first points.
+func writeEvents(points []models.Point) []WriteEvent {
+	var events []WriteEvent
+	index := make(map[string]int)
+	for _, p := range points {
+		name := p.Name()
+		i, ok := index[string(name)]
+		if !ok {
+			var orgBucket [16]byte
+			copy(orgBucket[:], name)
+			org, bucket := tsdb.DecodeName(orgBucket)
+
+			i = len(events)
+			index[string(name)] = i
+			events = append(events, WriteEvent{OrgID: org, BucketID: bucket})
+		}
+		if len(events[i].Points) < writeEventSampleSize {
+			events[i].Points = append(events[i].Points, p)
+		}
+	}
+	return events
+}
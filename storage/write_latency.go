@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// writeLatencySamples is the number of the most recent writes whose durations
+// the write latency is estimated from.
+const writeLatencySamples = 1000
+
+// WriteLatencyStats are the quantiles of the durations of the most recent
+// writes accepted by the engine.
+type WriteLatencyStats struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// EstimatedWriteLatency returns the quantiles of the durations of the last
+// 1000 writes accepted by the engine, or zero durations if there were none.
+func (e *Engine) EstimatedWriteLatency() WriteLatencyStats {
+	return e.writeLatency.stats()
+}
+
+// writeLatencyWindow is a sliding window of the durations of the most recent
+// writes. Durations are recorded without locking, so that concurrent writes
+// do not wait on each other.
+type writeLatencyWindow struct {
+	count   uint64                     // atomic; number of durations recorded
+	samples [writeLatencySamples]int64 // atomic; ring buffer of durations
+}
+
+// record adds the duration of a write to the window, replacing the oldest
+// duration once the window is full.
+func (w *writeLatencyWindow) record(d time.Duration) {
+	i := atomic.AddUint64(&w.count, 1) - 1
+	atomic.StoreInt64(&w.samples[i%writeLatencySamples], int64(d))
+}
+
+// stats returns the quantiles of the durations in the window.
+func (w *writeLatencyWindow) stats() WriteLatencyStats {
+	n := atomic.LoadUint64(&w.count)
+	if n > writeLatencySamples {
+		n = writeLatencySamples
+	}
+	if n == 0 {
+		return WriteLatencyStats{}
+	}
+
+	samples := make([]int64, n)
+	for i := range samples {
+		samples[i] = atomic.LoadInt64(&w.samples[i])
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	quantile := func(q float64) time.Duration {
+		return time.Duration(samples[int(q*float64(n-1))])
+	}
+	return WriteLatencyStats{
+		P50: quantile(0.5),
+		P95: quantile(0.95),
+		P99: quantile(0.99),
+	}
+}
+
+// writeLatencyCollector reports the estimated write latency of an engine as
+// the quantiles of the write_duration_seconds metric.
+type writeLatencyCollector struct {
+	desc *prometheus.Desc
+	e    *Engine
+}
+
+func newWriteLatencyCollector(e *Engine) *writeLatencyCollector {
+	return &writeLatencyCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "write_duration_seconds"),
+			"Estimated quantiles of the duration of the last 1000 writes.",
+			[]string{"quantile"},
+			e.defaultMetricLabels,
+		),
+		e: e,
+	}
+}
+
+// Describe returns the description of the write latency metric.
+func (c *writeLatencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect returns the current quantiles of the write latency.
+func (c *writeLatencyCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.e.EstimatedWriteLatency()
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, stats.P50.Seconds(), "0.5")
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, stats.P95.Seconds(), "0.95")
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, stats.P99.Seconds(), "0.99")
+}
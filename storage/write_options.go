@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// WriteOptions are the options of a write to the engine.
+type WriteOptions struct {
+	// Precision is the duration the times of the points are truncated to.
+	// Zero leaves them as they are.
+	Precision time.Duration
+
+	// RejectPartial rejects a write that has invalid points, so that none of
+	// its points are written. Otherwise, the valid points are written and a
+	// tsdb.PartialWriteError is returned for the dropped points.
+	RejectPartial bool
+
+	// Validator validates the points of the write after the validators
+	// registered with the engine. It may be nil.
+	Validator WriteValidator
+
+	// ImportBatchSize is the number of lines of line protocol ImportPoints
+	// writes at a time. Zero uses DefaultImportBatchSize.
+	ImportBatchSize int
+}
+
+// WritePointsWithOptions writes points to the bucket with the given options.
+// The points are those of a write to the bucket, before they are exploded
+// into the points the engine stores, one for each field.
+func (e *Engine) WritePointsWithOptions(ctx context.Context, orgID, bucketID influxdb.ID, points []models.Point, opts WriteOptions) error {
+	if !orgID.Valid() || !bucketID.Valid() {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "a valid organization and bucket ID are required to write",
+		}
+	}
+
+	points, err := tsdb.ExplodePoints(orgID, bucketID, points)
+	if err != nil {
+		return err
+	}
+
+	// The exploded points are copies, so truncating them leaves the points
+	// of the caller unchanged.
+	if opts.Precision > 0 {
+		for _, p := range points {
+			p.SetTime(p.Time().Truncate(opts.Precision))
+		}
+	}
+
+	return e.writePoints(ctx, points, opts)
+}
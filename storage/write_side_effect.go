@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap"
+)
+
+// writeSideEffectQueueSize is the number of writes queued for a side effect.
+// Writes are dropped for a side effect whose queue is full, so that a slow
+// side effect does not slow down writes.
+const writeSideEffectQueueSize = 1024
+
+// writeSideEffect is a hook called with the points of each write accepted by
+// the engine.
+type writeSideEffect struct {
+	name string
+	hook func([]models.Point) error
+	c    chan []models.Point
+	done chan struct{} // closed when the side effect is replaced
+}
+
+func newWriteSideEffect(name string, hook func([]models.Point) error) *writeSideEffect {
+	return &writeSideEffect{
+		name: name,
+		hook: hook,
+		c:    make(chan []models.Point, writeSideEffectQueueSize),
+		done: make(chan struct{}),
+	}
+}
+
+// WithWriteSideEffect registers hook as a side effect of each write, as
+// RegisterWriteSideEffect does.
+func WithWriteSideEffect(name string, hook func([]models.Point) error) Option {
+	return func(e *Engine) {
+		e.sideEffects = append(e.sideEffects, newWriteSideEffect(name, hook))
+	}
+}
+
+// RegisterWriteSideEffect registers hook to be called with the points of each
+// write once they are written to the WAL, replacing any hook registered with
+// the same name. Hooks are called asynchronously, in the order of the writes,
+// and do not affect them: errors are logged, and writes are dropped for a
+// hook that falls too far behind.
+//
+// The points are those the engine stores: their name is the encoded
+// organization and bucket, and their measurement and field key are the
+// values of the models.MeasurementTagKey and models.FieldKeyTagKey tags.
+func (e *Engine) RegisterWriteSideEffect(name string, hook func([]models.Point) error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := newWriteSideEffect(name, hook)
+	replaced := false
+	for i, other := range e.sideEffects {
+		if other.name == name {
+			close(other.done)
+			e.sideEffects[i], replaced = s, true
+			break
+		}
+	}
+	if !replaced {
+		e.sideEffects = append(e.sideEffects, s)
+	}
+
+	if e.closing != nil {
+		e.runWriteSideEffect(s)
+	}
+}
+
+// runWriteSideEffect calls the hook of s with the queued writes until the
+// engine is closed or s is replaced. e.mu must be held.
+func (e *Engine) runWriteSideEffect(s *writeSideEffect) {
+	closing := e.closing
+	log := e.logger.With(zap.String("write_side_effect", s.name))
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-closing:
+				return
+			case <-s.done:
+				return
+			case points := <-s.c:
+				if err := s.hook(points); err != nil {
+					log.Error("Write side effect failed", zap.Int("points", len(points)), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// queueWriteSideEffects queues the points of a write for each side effect.
+// e.mu must be held.
+func (e *Engine) queueWriteSideEffects(points []models.Point) {
+	if len(e.sideEffects) == 0 || len(points) == 0 {
+		return
+	}
+
+	// The hooks run after the write returns, when its points may be reused.
+	points = append([]models.Point(nil), points...)
+	for _, s := range e.sideEffects {
+		select {
+		case s.c <- points:
+		default:
+			e.logger.Warn("Dropping write for write side effect with full queue",
+				zap.String("write_side_effect", s.name), zap.Int("points", len(points)))
+		}
+	}
+}
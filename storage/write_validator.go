@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"github.com/influxdata/influxdb/models"
+)
+
+// WriteValidator validates the points of a write before the engine stores
+// them, such as to enforce a schema.
+//
+// The points are those the engine would store: their name is the encoded
+// organization and bucket, and their measurement and field key are the
+// values of the models.MeasurementTagKey and models.FieldKeyTagKey tags.
+type WriteValidator interface {
+	// Validate returns an error if the points must not be written.
+	Validate(points []models.Point) error
+}
+
+// NoopWriteValidator is a WriteValidator that accepts all points.
+type NoopWriteValidator struct{}
+
+// Validate returns nil.
+func (NoopWriteValidator) Validate([]models.Point) error { return nil }
@@ -846,6 +846,7 @@ func FindAuthorizations(
 	type args struct {
 		ID     platform.ID
 		UserID platform.ID
+		user   string
 		OrgID  platform.ID
 		token  string
 	}
@@ -986,6 +987,76 @@ func FindAuthorizations(
 				},
 			},
 		},
+		{
+			name: "find authorization by user name",
+			fields: AuthorizationFields{
+				Users: []*platform.User{
+					{
+						Name: "cooluser",
+						ID:   MustIDBase16(userOneID),
+					},
+					{
+						Name: "regularuser",
+						ID:   MustIDBase16(userTwoID),
+					},
+				},
+				Orgs: []*platform.Organization{
+					{
+						Name: "o1",
+						ID:   MustIDBase16(orgOneID),
+					},
+				},
+				Authorizations: []*platform.Authorization{
+					{
+						ID:          MustIDBase16(authOneID),
+						UserID:      MustIDBase16(userOneID),
+						OrgID:       MustIDBase16(orgOneID),
+						Token:       "rand1",
+						Status:      platform.Active,
+						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+					},
+					{
+						ID:          MustIDBase16(authTwoID),
+						UserID:      MustIDBase16(userTwoID),
+						OrgID:       MustIDBase16(orgOneID),
+						Token:       "rand2",
+						Status:      platform.Active,
+						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
+					},
+					{
+						ID:          MustIDBase16(authThreeID),
+						UserID:      MustIDBase16(userOneID),
+						OrgID:       MustIDBase16(orgOneID),
+						Token:       "rand3",
+						Status:      platform.Active,
+						Permissions: deleteUsersPermission(MustIDBase16(orgOneID)),
+					},
+				},
+			},
+			args: args{
+				user: "cooluser",
+			},
+			wants: wants{
+				authorizations: []*platform.Authorization{
+					{
+						ID:          MustIDBase16(authOneID),
+						UserID:      MustIDBase16(userOneID),
+						OrgID:       MustIDBase16(orgOneID),
+						Status:      platform.Active,
+						Token:       "rand1",
+						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+					},
+					{
+						ID:          MustIDBase16(authThreeID),
+						UserID:      MustIDBase16(userOneID),
+						OrgID:       MustIDBase16(orgOneID),
+						Status:      platform.Active,
+						Token:       "rand3",
+						Permissions: deleteUsersPermission(MustIDBase16(orgOneID)),
+					},
+				},
+			},
+		},
 		{
 			name: "find authorization by org id",
 			fields: AuthorizationFields{
@@ -1212,6 +1283,9 @@ func FindAuthorizations(
 			if tt.args.UserID.Valid() {
 				filter.UserID = &tt.args.UserID
 			}
+			if tt.args.user != "" {
+				filter.User = &tt.args.user
+			}
 			if tt.args.OrgID.Valid() {
 				filter.OrgID = &tt.args.OrgID
 			}
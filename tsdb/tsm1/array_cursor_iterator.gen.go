@@ -9,6 +9,7 @@ package tsm1
 import (
 	"context"
 
+	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/tsdb/cursors"
@@ -17,8 +18,16 @@ import (
 // buildFloatArrayCursor creates an array cursor for a float field.
 func (q *arrayCursorIterator) buildFloatArrayCursor(ctx context.Context, name []byte, tags models.Tags, field string, opt query.IteratorOptions) cursors.FloatArrayCursor {
 	key := q.seriesFieldKeyBytes(name, tags, field)
+
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "cache values")
 	cacheValues := q.e.Cache.Values(key)
+	span.LogKV("values", len(cacheValues))
+	span.Finish()
+
+	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "TSM key cursor")
 	keyCursor := q.e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
+	span.LogKV("seeks", keyCursor.seekN())
+	span.Finish()
 
 	q.e.readTracker.AddSeeks(uint64(keyCursor.seekN()))
 
@@ -40,8 +49,16 @@ func (q *arrayCursorIterator) buildFloatArrayCursor(ctx context.Context, name []
 // buildIntegerArrayCursor creates an array cursor for a integer field.
 func (q *arrayCursorIterator) buildIntegerArrayCursor(ctx context.Context, name []byte, tags models.Tags, field string, opt query.IteratorOptions) cursors.IntegerArrayCursor {
 	key := q.seriesFieldKeyBytes(name, tags, field)
+
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "cache values")
 	cacheValues := q.e.Cache.Values(key)
+	span.LogKV("values", len(cacheValues))
+	span.Finish()
+
+	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "TSM key cursor")
 	keyCursor := q.e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
+	span.LogKV("seeks", keyCursor.seekN())
+	span.Finish()
 
 	q.e.readTracker.AddSeeks(uint64(keyCursor.seekN()))
 
@@ -63,8 +80,16 @@ func (q *arrayCursorIterator) buildIntegerArrayCursor(ctx context.Context, name
 // buildUnsignedArrayCursor creates an array cursor for a unsigned field.
 func (q *arrayCursorIterator) buildUnsignedArrayCursor(ctx context.Context, name []byte, tags models.Tags, field string, opt query.IteratorOptions) cursors.UnsignedArrayCursor {
 	key := q.seriesFieldKeyBytes(name, tags, field)
+
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "cache values")
 	cacheValues := q.e.Cache.Values(key)
+	span.LogKV("values", len(cacheValues))
+	span.Finish()
+
+	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "TSM key cursor")
 	keyCursor := q.e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
+	span.LogKV("seeks", keyCursor.seekN())
+	span.Finish()
 
 	q.e.readTracker.AddSeeks(uint64(keyCursor.seekN()))
 
@@ -86,8 +111,16 @@ func (q *arrayCursorIterator) buildUnsignedArrayCursor(ctx context.Context, name
 // buildStringArrayCursor creates an array cursor for a string field.
 func (q *arrayCursorIterator) buildStringArrayCursor(ctx context.Context, name []byte, tags models.Tags, field string, opt query.IteratorOptions) cursors.StringArrayCursor {
 	key := q.seriesFieldKeyBytes(name, tags, field)
+
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "cache values")
 	cacheValues := q.e.Cache.Values(key)
+	span.LogKV("values", len(cacheValues))
+	span.Finish()
+
+	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "TSM key cursor")
 	keyCursor := q.e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
+	span.LogKV("seeks", keyCursor.seekN())
+	span.Finish()
 
 	q.e.readTracker.AddSeeks(uint64(keyCursor.seekN()))
 
@@ -109,8 +142,16 @@ func (q *arrayCursorIterator) buildStringArrayCursor(ctx context.Context, name [
 // buildBooleanArrayCursor creates an array cursor for a boolean field.
 func (q *arrayCursorIterator) buildBooleanArrayCursor(ctx context.Context, name []byte, tags models.Tags, field string, opt query.IteratorOptions) cursors.BooleanArrayCursor {
 	key := q.seriesFieldKeyBytes(name, tags, field)
+
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "cache values")
 	cacheValues := q.e.Cache.Values(key)
+	span.LogKV("values", len(cacheValues))
+	span.Finish()
+
+	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "TSM key cursor")
 	keyCursor := q.e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
+	span.LogKV("seeks", keyCursor.seekN())
+	span.Finish()
 
 	q.e.readTracker.AddSeeks(uint64(keyCursor.seekN()))
 
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage/wal"
@@ -55,6 +57,19 @@ type Cache struct {
 	tracker       *cacheTracker
 	lastSnapshot  time.Time
 	lastWriteTime time.Time
+
+	// maxBucketSize, when non-zero, is the maximum number of bytes the values of a
+	// single bucket may use. bucketSizes holds the number of bytes used by each
+	// bucket in the live cache, keyed by its encoded organization and bucket
+	// name, and is only kept when maxBucketSize is set. Values loaded from TSM
+	// files do not count toward their bucket's size.
+	bucketMu      sync.Mutex
+	maxBucketSize uint64
+	bucketSizes   map[[16]byte]uint64
+
+	// warmSize is the number of bytes in the live cache that were loaded from
+	// TSM files by warm or kept by a snapshot. Those values are already on disk.
+	warmSize uint64
 }
 
 // NewCache returns an instance of a cache which will use a maximum of maxSize bytes of memory.
@@ -93,6 +108,10 @@ func (c *Cache) Write(key []byte, values []Value) error {
 	if newKey {
 		addedSize += uint64(len(key))
 	}
+	if c.maxBucketSize > 0 {
+		c.addBucketSizes(map[string]uint64{string(key): addedSize})
+	}
+
 	// Update the cache size and the memory size stat.
 	c.tracker.IncCacheSize(addedSize)
 	c.tracker.AddMemBytes(addedSize)
@@ -129,6 +148,11 @@ func (c *Cache) WriteMulti(values map[string][]Value) error {
 
 	var bytesWrittenErr uint64
 
+	var keySizes map[string]uint64
+	if c.maxBucketSize > 0 {
+		keySizes = make(map[string]uint64, len(values))
+	}
+
 	// We'll optimistically set size here, and then decrement it for write errors.
 	for k, v := range values {
 		newKey, err := store.write([]byte(k), v)
@@ -137,12 +161,18 @@ func (c *Cache) WriteMulti(values map[string][]Value) error {
 			werr = err
 			addedSize -= uint64(Values(v).Size())
 			bytesWrittenErr += uint64(Values(v).Size())
+		} else if keySizes != nil {
+			keySizes[k] = uint64(Values(v).Size())
 		}
 
 		if newKey {
 			addedSize += uint64(len(k))
+			if keySizes != nil {
+				keySizes[k] += uint64(len(k))
+			}
 		}
 	}
+	c.addBucketSizes(keySizes)
 
 	// Some points in the batch were dropped.  An error is returned so
 	// error stat is incremented as well.
@@ -193,23 +223,241 @@ func (c *Cache) Snapshot() (*Cache, error) {
 	}
 
 	c.snapshot.store, c.store = c.store, c.snapshot.store
-	snapshotSize := c.Size()
+
+	// Reset the cache's store, keeping the entries that are already on disk.
+	c.store.reset()
+	kept, dropped := c.keepPersistedEntries(time.Now())
+	snapshotSize := c.Size() - kept - dropped
 
 	c.snapshot.tracker.SetSnapshotSize(snapshotSize) // Save the size of the snapshot on the snapshot cache
 	c.tracker.SetSnapshotSize(snapshotSize)          // Save the size of the snapshot on the live cache
 
-	// Reset the cache's store.
-	c.store.reset()
-	c.tracker.SetCacheSize(0)
+	c.tracker.SetCacheSize(kept)
+	c.tracker.SubMemBytes(dropped)
+	c.resetBucketSizes()
+	atomic.StoreUint64(&c.warmSize, kept)
 	c.lastSnapshot = time.Now()
 
 	c.tracker.AddSnapshottedBytes(snapshotSize) // increment the number of bytes added to the snapshot
 	c.tracker.SetDiskBytes(0)
 	c.tracker.SetSnapshotsActive(0)
 
+	c.retainReadEntries(snapshotSize / cacheReadRetainRatio)
+
 	return c.snapshot, nil
 }
 
+// cacheReadRetainRatio limits the entries retained in the cache by a snapshot
+// to this fraction of the bytes snapshotted.
+const cacheReadRetainRatio = 4
+
+// cacheColdReads is the decayed number of reads below which a snapshot drops
+// an entry whose values are already on disk. An entry read once goes cold
+// after entryReadHalfLife.
+const cacheColdReads = 0.5
+
+// keepPersistedEntries moves the entries of the snapshot whose values are all
+// in TSM files back into the cache, as they do not need to be written again.
+// Entries that have gone cold are dropped instead. It returns the number of
+// bytes kept and dropped. c.mu must be held, and the cache must be empty, as
+// after the snapshot is swapped out.
+func (c *Cache) keepPersistedEntries(now time.Time) (kept, dropped uint64) {
+	var keys []string
+	// applySerial only errors if the closure returns an error.
+	_ = c.snapshot.store.applySerial(func(k string, e *entry) error {
+		if e.isPersisted() {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+
+	for _, k := range keys {
+		key := []byte(k)
+		e := c.snapshot.store.entry(key)
+		c.snapshot.store.remove(key)
+
+		n := uint64(e.size()) + uint64(len(key))
+		if e.readCount(now) < cacheColdReads {
+			dropped += n
+			continue
+		}
+		c.store.add(key, e)
+		kept += n
+	}
+	return kept, dropped
+}
+
+// retainReadEntries copies the entries of the snapshot that were read most
+// often back into the cache, as persisted entries, so that
+// queries of recently written data that is read frequently are served from
+// memory after it is flushed. Entries are retained in order of their decayed
+// read counts, up to limit bytes. c.mu must be held, and the cache must only
+// hold the entries kept by keepPersistedEntries.
+func (c *Cache) retainReadEntries(limit uint64) {
+	type readEntry struct {
+		key   string
+		e     *entry
+		reads float64
+	}
+
+	now := time.Now()
+	var read []readEntry
+	// applySerial only errors if the closure returns an error.
+	_ = c.snapshot.store.applySerial(func(k string, e *entry) error {
+		if reads := e.readCount(now); reads >= 1 {
+			read = append(read, readEntry{key: k, e: e, reads: reads})
+		}
+		return nil
+	})
+	sort.Slice(read, func(i, j int) bool { return read[i].reads > read[j].reads })
+
+	var retained uint64
+	keySizes := make(map[string]uint64)
+	for _, r := range read {
+		r.e.mu.RLock()
+		e, err := newEntryValues(r.e.values)
+		r.e.mu.RUnlock()
+		if err != nil {
+			continue
+		}
+
+		n := uint64(e.size()) + uint64(len(r.key))
+		if retained+n > limit {
+			continue
+		}
+		e.persisted = true
+		e.setReads(r.reads, now)
+		c.store.add([]byte(r.key), e)
+		retained += n
+		keySizes[r.key] = n
+	}
+	if retained == 0 {
+		return
+	}
+
+	if c.maxBucketSize > 0 {
+		c.addBucketSizes(keySizes)
+	}
+	c.tracker.IncCacheSize(retained)
+	c.tracker.AddMemBytes(retained)
+	atomic.AddUint64(&c.warmSize, retained)
+}
+
+// SnapshotBucket takes a snapshot of the values of a single bucket, identified
+// by its encoded organization and bucket name, moving them from the current
+// cache to the snapshot that is being flushed. Values of other buckets remain
+// in the cache.
+func (c *Cache) SnapshotBucket(name [16]byte) (*Cache, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.snapshotting {
+		return nil, ErrSnapshotInProgress
+	}
+
+	c.snapshotting = true
+	c.tracker.IncSnapshotsActive()
+
+	if c.snapshot == nil {
+		c.snapshot = &Cache{
+			store:   newRing(),
+			tracker: newCacheTracker(c.tracker.metrics, c.tracker.labels),
+		}
+	}
+
+	// Did a prior snapshot exist that failed?  If so, return the existing
+	// snapshot to retry.
+	if c.snapshot.Size() > 0 {
+		return c.snapshot, nil
+	}
+
+	prefix := bucketKeyPrefix(name)
+	var keys []string
+	// applySerial only errors if the closure returns an error.
+	_ = c.store.applySerial(func(k string, _ *entry) error {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+
+	var snapshotSize uint64
+	for _, k := range keys {
+		key := []byte(k)
+		e := c.store.entry(key)
+		if e == nil || e.isPersisted() {
+			continue
+		}
+		c.snapshot.store.add(key, e)
+		c.store.remove(key)
+		snapshotSize += uint64(e.size()) + uint64(len(key))
+	}
+
+	c.snapshot.tracker.SetSnapshotSize(snapshotSize)
+	c.tracker.SetSnapshotSize(snapshotSize)
+	c.tracker.DecCacheSize(snapshotSize)
+
+	c.bucketMu.Lock()
+	delete(c.bucketSizes, name)
+	c.bucketMu.Unlock()
+
+	c.tracker.AddSnapshottedBytes(snapshotSize)
+	c.tracker.SetDiskBytes(0)
+	c.tracker.SetSnapshotsActive(0)
+
+	return c.snapshot, nil
+}
+
+// warm adds values read from TSM files for the key to the cache, unless the
+// cache or its snapshot already hold values for the key. Those values may be
+// newer than the ones on disk and must not be overwritten. The entry is marked
+// as persisted, so it is not written by a snapshot unless values are added to
+// it. It returns the number of bytes added to the cache.
+func (c *Cache) warm(key []byte, values []Value) (uint64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	e, err := newEntryValues(values)
+	if err != nil {
+		return 0, err
+	}
+	e.persisted = true
+	// Count the load as a read, so the entry outlives the next snapshot.
+	e.markRead(time.Now())
+
+	n := uint64(e.size()) + uint64(len(key))
+	if limit, sz := c.maxSize, c.Size()+n; limit > 0 && sz > limit {
+		return 0, ErrCacheMemorySizeLimitExceeded(sz, limit)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.snapshot != nil && c.snapshot.store.entry(key) != nil {
+		return 0, nil
+	}
+	if !c.store.addIfAbsent(key, e) {
+		return 0, nil
+	}
+
+	c.tracker.IncCacheSize(n)
+	c.tracker.AddMemBytes(n)
+	atomic.AddUint64(&c.warmSize, n)
+	c.tracker.AddWarmupBytes(n)
+	return n, nil
+}
+
+// WarmSize returns the number of bytes in the cache that were loaded from TSM
+// files while warming up the cache, and so do not need to be snapshotted.
+func (c *Cache) WarmSize() uint64 {
+	warm, live := atomic.LoadUint64(&c.warmSize), c.tracker.CacheSize()
+	if warm > live {
+		// Values removed by deletes or bucket snapshots may have been warm.
+		return live
+	}
+	return warm
+}
+
 // Deduplicate sorts the snapshot before returning it. The compactor and any queries
 // coming in while it writes will need the values sorted.
 func (c *Cache) Deduplicate() {
@@ -273,6 +521,16 @@ func (c *Cache) Count() int {
 	return n
 }
 
+// EntryCount returns the number of keys in the cache, without the snapshot.
+// Unlike Count, it includes keys whose values were all deleted, and it does
+// not visit the entries.
+func (c *Cache) EntryCount() int {
+	c.mu.RLock()
+	n := c.store.len()
+	c.mu.RUnlock()
+	return n
+}
+
 // Keys returns a sorted slice of all keys under management by the cache.
 func (c *Cache) Keys() [][]byte {
 	c.mu.RLock()
@@ -344,7 +602,9 @@ func (c *Cache) Values(key []byte) Values {
 			// No values in hot cache or snapshots.
 			return nil
 		}
+		snapshotEntries.markRead(time.Now())
 	} else {
+		e.markRead(time.Now())
 		e.deduplicate()
 	}
 
@@ -438,6 +698,7 @@ func (c *Cache) DeleteBucketRange(ctx context.Context, name string, min, max int
 
 	c.tracker.DecCacheSize(total)
 	c.tracker.SetMemBytes(uint64(c.Size()))
+	c.subBucketSize(name, total)
 }
 
 // SetMaxSize updates the memory limit of the cache.
@@ -447,6 +708,146 @@ func (c *Cache) SetMaxSize(size uint64) {
 	c.mu.Unlock()
 }
 
+// SetMaxBucketSize updates the memory limit of each bucket in the cache.
+// A size of zero disables the limit.
+func (c *Cache) SetMaxBucketSize(size uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+
+	c.maxBucketSize = size
+	c.bucketSizes = nil
+	if size == 0 {
+		return
+	}
+
+	c.bucketSizes = make(map[[16]byte]uint64)
+	_ = c.store.applySerial(func(k string, e *entry) error {
+		if e.isPersisted() {
+			return nil
+		}
+		if name, ok := keyBucketName(k); ok {
+			c.bucketSizes[name] += uint64(e.size()) + uint64(len(k))
+		}
+		return nil
+	})
+}
+
+// BucketSize returns the number of bytes the values of the bucket use in the
+// cache, excluding any snapshot being flushed. It is zero if no bucket limit
+// is set.
+func (c *Cache) BucketSize(orgID, bucketID influxdb.ID) uint64 {
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+	return c.bucketSizes[tsdb.EncodeName(orgID, bucketID)]
+}
+
+// BucketsOverLimit returns the encoded names of the buckets whose values would
+// exceed the bucket limit once values are written to the cache. Buckets
+// without values in the cache are never returned, as flushing them frees
+// nothing.
+func (c *Cache) BucketsOverLimit(values map[string][]Value) [][16]byte {
+	if c.maxBucketSize == 0 {
+		return nil
+	}
+
+	added := make(map[[16]byte]uint64)
+	for k, v := range values {
+		if name, ok := keyBucketName(k); ok {
+			added[name] += uint64(Values(v).Size())
+		}
+	}
+
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+
+	var names [][16]byte
+	for name, n := range added {
+		if sz := c.bucketSizes[name]; sz > 0 && sz+n > c.maxBucketSize {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addBucketSizes adds the number of bytes written for each key to the size of
+// the key's bucket.
+func (c *Cache) addBucketSizes(keySizes map[string]uint64) {
+	if c.maxBucketSize == 0 || len(keySizes) == 0 {
+		return
+	}
+
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+	if c.bucketSizes == nil {
+		return
+	}
+	for k, n := range keySizes {
+		if name, ok := keyBucketName(k); ok {
+			c.bucketSizes[name] += n
+		}
+	}
+}
+
+// subBucketSize subtracts n bytes from the size of the bucket with the escaped
+// bucket name.
+func (c *Cache) subBucketSize(escaped string, n uint64) {
+	name, ok := keyBucketName(escaped)
+	if !ok {
+		return
+	}
+
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+	if sz, ok := c.bucketSizes[name]; ok {
+		if n >= sz {
+			delete(c.bucketSizes, name)
+		} else {
+			c.bucketSizes[name] = sz - n
+		}
+	}
+}
+
+func (c *Cache) resetBucketSizes() {
+	c.bucketMu.Lock()
+	defer c.bucketMu.Unlock()
+	if c.bucketSizes != nil {
+		c.bucketSizes = make(map[[16]byte]uint64)
+	}
+}
+
+// keyBucketName returns the encoded organization and bucket name of a cache
+// key, which begins with the escaped name.
+func keyBucketName(key string) ([16]byte, bool) {
+	var name [16]byte
+	n := 0
+	for i := 0; i < len(key) && n < len(name); i++ {
+		if key[i] == '\\' && i+1 < len(key) && (key[i+1] == ',' || key[i+1] == ' ') {
+			i++
+		}
+		name[n] = key[i]
+		n++
+	}
+	if n < len(name) {
+		return name, false
+	}
+
+	return name, true
+}
+
+// bucketKeyPrefix returns the prefix shared by the cache keys of the bucket.
+func bucketKeyPrefix(name [16]byte) string {
+	return string(BucketPrefix(name[:]))
+}
+
+// BucketPrefix returns the prefix of the series keys of the bucket with the
+// encoded org and bucket ID in the cache and TSM files.
+func BucketPrefix(orgBucket []byte) []byte {
+	return models.EscapeMeasurement(orgBucket)
+}
+
 // values returns the values for the key. It assumes the data is already sorted.
 // It doesn't lock the cache but it does read-lock the entry if there is one for the key.
 // values should only be used in compact.go in the CacheKeyIterator.
@@ -501,10 +902,8 @@ func (cl *CacheLoader) Load(cache *Cache) error {
 				}
 			}
 
-			// TODO(edd): we need to clean up how we're encoding the prefix so that we
-			// don't have to remember to get it right everywhere we need to touch TSM data.
 			encoded := tsdb.EncodeName(en.OrgID, en.BucketID)
-			name := models.EscapeMeasurement(encoded[:])
+			name := BucketPrefix(encoded[:])
 
 			cache.DeleteBucketRange(context.Background(), string(name), en.Min, en.Max, pred)
 			return nil
@@ -556,6 +955,9 @@ type cacheTracker struct {
 	snapshotSize    uint64
 	cacheSize       uint64
 
+	// warmupBytes is the number of bytes loaded while warming up the cache.
+	warmupBytes uint64
+
 	// Used in testing.
 	memSizeBytes     uint64
 	snapshottedBytes uint64
@@ -615,6 +1017,20 @@ func (t *cacheTracker) AddSnapshottedBytes(bytes uint64) {
 	t.metrics.SnapshottedBytes.With(labels).Add(float64(bytes))
 }
 
+// AddWarmupBytes increases the number of bytes loaded from TSM files while
+// warming up the cache.
+func (t *cacheTracker) AddWarmupBytes(bytes uint64) {
+	atomic.AddUint64(&t.warmupBytes, bytes)
+
+	labels := t.labels
+	t.metrics.WarmupBytes.With(labels).Add(float64(bytes))
+}
+
+// WarmupBytes returns the number of bytes loaded while warming up the cache.
+func (t *cacheTracker) WarmupBytes() uint64 {
+	return atomic.LoadUint64(&t.warmupBytes)
+}
+
 // SetDiskBytes sets the number of bytes on disk used by snapshot data.
 func (t *cacheTracker) SetDiskBytes(bytes uint64) {
 	labels := t.labels
@@ -1,12 +1,18 @@
 package tsm1
 
 import (
+	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/influxdata/influxql"
 )
 
+// entryReadHalfLife is the time after which the reads of a cache entry count
+// for half as much, so that entries that are no longer read become cold.
+const entryReadHalfLife = 5 * time.Minute
+
 // entry is a set of values and some metadata.
 type entry struct {
 	// Tracks the number of values in the entry. Must always be accessed via
@@ -18,6 +24,47 @@ type entry struct {
 
 	// The type of values stored. Read only so doesn't need to be protected by mu.
 	vtype byte
+
+	// persisted is set for entries whose values were all read from TSM files,
+	// and cleared once values are added. Protected by mu.
+	persisted bool
+
+	// reads is the number of times the values were read by queries, decayed
+	// by entryReadHalfLife since readTime.
+	readMu   sync.Mutex
+	reads    float64
+	readTime time.Time
+}
+
+// markRead counts a read of the entry by a query at now.
+func (e *entry) markRead(now time.Time) {
+	e.readMu.Lock()
+	e.reads = decayReads(e.reads, e.readTime, now) + 1
+	e.readTime = now
+	e.readMu.Unlock()
+}
+
+// readCount returns the number of reads of the entry, decayed to now.
+func (e *entry) readCount(now time.Time) float64 {
+	e.readMu.Lock()
+	defer e.readMu.Unlock()
+	return decayReads(e.reads, e.readTime, now)
+}
+
+// setReads sets the decayed number of reads of the entry at now.
+func (e *entry) setReads(reads float64, now time.Time) {
+	e.readMu.Lock()
+	e.reads, e.readTime = reads, now
+	e.readMu.Unlock()
+}
+
+// decayReads returns reads counted at t decayed to now, halving them every
+// entryReadHalfLife.
+func decayReads(reads float64, t, now time.Time) float64 {
+	if reads == 0 || !now.After(t) {
+		return reads
+	}
+	return reads * math.Exp2(-float64(now.Sub(t))/float64(entryReadHalfLife))
 }
 
 // newEntryValues returns a new instance of entry with the given values.  If the
@@ -65,6 +112,7 @@ func (e *entry) add(values []Value) error {
 
 	// entry currently has no values, so add the new ones and we're done.
 	e.mu.Lock()
+	e.persisted = false
 	if len(e.values) == 0 {
 		e.values = values
 		atomic.StoreInt64(&e.n, int64(len(e.values)))
@@ -93,6 +141,13 @@ func (e *entry) deduplicate() {
 	atomic.StoreInt64(&e.n, int64(len(e.values)))
 }
 
+// isPersisted returns true if the values of the entry are all in TSM files.
+func (e *entry) isPersisted() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.persisted
+}
+
 // count returns the number of values in this entry.
 func (e *entry) count() int {
 	return int(atomic.LoadInt64(&e.n))
@@ -109,6 +164,15 @@ func (e *entry) filter(min, max int64) {
 	e.mu.Unlock()
 }
 
+// contains returns true if the entry has values for the time interval
+// [min, max] inclusive.
+func (e *entry) contains(min, max int64) bool {
+	e.mu.RLock()
+	ok := e.values.ContainsUnsorted(min, max)
+	e.mu.RUnlock()
+	return ok
+}
+
 // size returns the size of this entry in bytes.
 func (e *entry) size() int {
 	e.mu.RLock()
@@ -14,6 +14,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/storage/wal"
@@ -500,6 +501,109 @@ func TestCache_Snapshot_Stats(t *testing.T) {
 	}
 }
 
+func TestCache_Snapshot_RetainsReadEntries(t *testing.T) {
+	c := NewCache(0)
+
+	// The cold series holds most of the bytes, so the hot one fits within
+	// the bytes retained by the snapshot.
+	var hot, cold Values
+	for i := 0; i < 100; i++ {
+		if i < 10 {
+			hot = append(hot, NewValue(int64(i), float64(i)))
+		}
+		cold = append(cold, NewValue(int64(i), float64(i)))
+	}
+	if err := c.WriteMulti(map[string][]Value{"hot": hot, "cold": cold}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if got := c.Values([]byte("hot")); !reflect.DeepEqual(got, hot) {
+			t.Fatalf("unexpected values for hot: got %v, exp %v", got, hot)
+		}
+	}
+
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot.Deduplicate()
+	c.ClearSnapshot(true)
+
+	// The series that was read remains in the cache as values already on
+	// disk, and the other was flushed.
+	if got := c.Values([]byte("hot")); !reflect.DeepEqual(got, hot) {
+		t.Fatalf("unexpected values for hot after snapshot: got %v, exp %v", got, hot)
+	}
+	if got := c.Values([]byte("cold")); got != nil {
+		t.Fatalf("unexpected values for cold after snapshot: %v", got)
+	}
+	if got, exp := c.WarmSize(), uint64(hot.Size()+len("hot")); got != exp {
+		t.Fatalf("got warm size %d, exp %d", got, exp)
+	}
+	if got, exp := c.Size(), c.WarmSize(); got != exp {
+		t.Fatalf("got cache size %d, exp %d", got, exp)
+	}
+
+	// While it is read, the series is not written again by the next
+	// snapshot and stays in the cache.
+	if err := c.Write([]byte("cold"), cold); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err = c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshot.Values([]byte("hot")); got != nil {
+		t.Fatalf("unexpected snapshot values for hot: %v", got)
+	}
+	if got := snapshot.Values([]byte("cold")); !reflect.DeepEqual(got, cold) {
+		t.Fatalf("unexpected snapshot values for cold: got %v, exp %v", got, cold)
+	}
+	c.ClearSnapshot(true)
+	if got := c.Values([]byte("hot")); !reflect.DeepEqual(got, hot) {
+		t.Fatalf("unexpected values for hot after second snapshot: got %v, exp %v", got, hot)
+	}
+
+	// Once it is no longer read, the series is dropped by the next snapshot
+	// without being written again.
+	c.store.entry([]byte("hot")).setReads(0, time.Now())
+	snapshot, err = c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshot.Values([]byte("hot")); got != nil {
+		t.Fatalf("unexpected snapshot values for hot: %v", got)
+	}
+	c.ClearSnapshot(true)
+	if got := c.Values([]byte("hot")); got != nil {
+		t.Fatalf("unexpected values for hot after third snapshot: %v", got)
+	}
+	if got := c.Size(); got != 0 {
+		t.Fatalf("got cache size %d, exp 0", got)
+	}
+}
+
+func TestCacheEntry_ReadCountDecays(t *testing.T) {
+	e, err := newEntryValues([]Value{NewValue(1, 1.0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		e.markRead(now)
+	}
+	if got, exp := e.readCount(now), 8.0; got != exp {
+		t.Fatalf("got %v reads, exp %v", got, exp)
+	}
+	if got, exp := e.readCount(now.Add(entryReadHalfLife)), 4.0; math.Abs(got-exp) > 1e-9 {
+		t.Fatalf("got %v reads after one half-life, exp %v", got, exp)
+	}
+	if got, exp := e.readCount(now.Add(3*entryReadHalfLife)), 1.0; math.Abs(got-exp) > 1e-9 {
+		t.Fatalf("got %v reads after three half-lives, exp %v", got, exp)
+	}
+}
+
 func TestCache_CacheEmptySnapshot(t *testing.T) {
 	c := NewCache(512)
 
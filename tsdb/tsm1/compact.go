@@ -42,6 +42,9 @@ const (
 
 	// TSSFileExtension is the extension used for TSM stats files.
 	TSSFileExtension = "tss"
+
+	// TKSFileExtension is the extension used for TSM key stats files.
+	TKSFileExtension = "tks"
 )
 
 var (
@@ -179,6 +182,17 @@ func (t *tsmGeneration) level() int {
 	return 4
 }
 
+// tier returns the storage tier of the files in this generation.
+func (t *tsmGeneration) tier() Tier {
+	return t.files[0].Tier
+}
+
+// partition returns the directory of the measurement partition of the files
+// in this generation, or "" if they are not partitioned.
+func (t *tsmGeneration) partition() string {
+	return tsmFilePartition(t.files[0].Path)
+}
+
 // count returns the number of files in the generation.
 func (t *tsmGeneration) count() int {
 	return len(t.files)
@@ -230,9 +244,22 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 
 	// Determine the generations from all files on disk.  We need to treat
 	// a generation conceptually as a single file even though it may be
-	// split across several files in sequence.
-	generations := c.findGenerations(true)
+	// split across several files in sequence.  The files of each measurement
+	// partition are planned separately.
+	var cGroups []CompactionGroup
+	for _, generations := range c.findGenerations(true).partitions() {
+		cGroups = append(cGroups, c.planLevel(generations, level)...)
+	}
+
+	if !c.acquire(cGroups) {
+		return nil
+	}
+
+	return cGroups
+}
 
+// planLevel returns the groups of generations to rewrite for a specific level.
+func (c *DefaultPlanner) planLevel(generations tsmGenerations, level int) []CompactionGroup {
 	// If there is only one generation and no tombstones, then there's nothing to
 	// do.
 	if len(generations) <= 1 && !generations.hasTombstones() {
@@ -255,7 +282,7 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 			}
 		}
 
-		if len(currentGen) == 0 || currentGen.level() == cur.level() {
+		if len(currentGen) == 0 || (currentGen.level() == cur.level() && currentGen.tier() == cur.tier()) {
 			currentGen = append(currentGen, cur)
 			continue
 		}
@@ -303,11 +330,6 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 			cGroups = append(cGroups, cGroup)
 		}
 	}
-
-	if !c.acquire(cGroups) {
-		return nil
-	}
-
 	return cGroups
 }
 
@@ -326,9 +348,22 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 
 	// Determine the generations from all files on disk.  We need to treat
 	// a generation conceptually as a single file even though it may be
-	// split across several files in sequence.
-	generations := c.findGenerations(true)
+	// split across several files in sequence.  The files of each measurement
+	// partition are planned separately.
+	var cGroups []CompactionGroup
+	for _, generations := range c.findGenerations(true).partitions() {
+		cGroups = append(cGroups, c.planOptimize(generations)...)
+	}
 
+	if !c.acquire(cGroups) {
+		return nil
+	}
+
+	return cGroups
+}
+
+// planOptimize returns the groups of level 4 generations to optimize.
+func (c *DefaultPlanner) planOptimize(generations tsmGenerations) []CompactionGroup {
 	// If there is only one generation and no tombstones, then there's nothing to
 	// do.
 	if len(generations) <= 1 && !generations.hasTombstones() {
@@ -356,7 +391,7 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 			}
 		}
 
-		if len(currentGen) == 0 || currentGen.level() == cur.level() {
+		if len(currentGen) == 0 || (currentGen.level() == cur.level() && currentGen.tier() == cur.tier()) {
 			currentGen = append(currentGen, cur)
 			continue
 		}
@@ -395,11 +430,6 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 
 		cGroups = append(cGroups, cGroup)
 	}
-
-	if !c.acquire(cGroups) {
-		return nil
-	}
-
 	return cGroups
 }
 
@@ -422,56 +452,81 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 			c.mu.Unlock()
 		}
 
-		var tsmFiles []string
-		var genCount int
-		for i, group := range generations {
-			var skip bool
-
-			// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
-			if len(generations) > 2 && group.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
-				skip = true
+		var groups []CompactionGroup
+		for _, generations := range generations.partitions() {
+			if group := c.planFull(generations); group != nil {
+				groups = append(groups, group)
 			}
+		}
 
-			// We need to look at the level of the next file because it may need to be combined with this generation
-			// but won't get picked up on it's own if this generation is skipped.  This allows the most recently
-			// created files to get picked up by the full compaction planner and avoids having a few less optimally
-			// compressed files.
-			if i < len(generations)-1 {
-				if generations[i+1].level() <= 3 {
-					skip = false
-				}
-			}
+		if len(groups) == 0 || !c.acquire(groups) {
+			return nil
+		}
+		return groups
+	}
 
-			if skip {
-				continue
-			}
+	// don't plan if nothing has changed in the filestore
+	if c.lastPlanCheck.After(c.FileStore.LastModified()) && !generations.hasTombstones() {
+		return nil
+	}
 
-			for _, f := range group.files {
-				tsmFiles = append(tsmFiles, f.Path)
+	c.lastPlanCheck = time.Now()
+
+	var tsmFiles []CompactionGroup
+	for _, generations := range generations.partitions() {
+		tsmFiles = append(tsmFiles, c.plan(generations)...)
+	}
+
+	if !c.acquire(tsmFiles) {
+		return nil
+	}
+	return tsmFiles
+}
+
+// planFull returns all the files of the generations to compact in full, or
+// nil if there are not enough of them.
+func (c *DefaultPlanner) planFull(generations tsmGenerations) CompactionGroup {
+	var tsmFiles []string
+	var genCount int
+	for i, group := range generations {
+		var skip bool
+
+		// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
+		if len(generations) > 2 && group.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
+			skip = true
+		}
+
+		// We need to look at the level of the next file because it may need to be combined with this generation
+		// but won't get picked up on it's own if this generation is skipped.  This allows the most recently
+		// created files to get picked up by the full compaction planner and avoids having a few less optimally
+		// compressed files.
+		if i < len(generations)-1 {
+			if generations[i+1].level() <= 3 {
+				skip = false
 			}
-			genCount += 1
 		}
-		sort.Strings(tsmFiles)
 
-		// Make sure we have more than 1 file and more than 1 generation
-		if len(tsmFiles) <= 1 || genCount <= 1 {
-			return nil
+		if skip {
+			continue
 		}
 
-		group := []CompactionGroup{tsmFiles}
-		if !c.acquire(group) {
-			return nil
+		for _, f := range group.files {
+			tsmFiles = append(tsmFiles, f.Path)
 		}
-		return group
+		genCount += 1
 	}
+	sortTSMPaths(tsmFiles)
 
-	// don't plan if nothing has changed in the filestore
-	if c.lastPlanCheck.After(c.FileStore.LastModified()) && !generations.hasTombstones() {
+	// Make sure we have more than 1 file and more than 1 generation
+	if len(tsmFiles) <= 1 || genCount <= 1 {
 		return nil
 	}
 
-	c.lastPlanCheck = time.Now()
+	return tsmFiles
+}
 
+// plan returns the groups of level 4 generations to compact.
+func (c *DefaultPlanner) plan(generations tsmGenerations) []CompactionGroup {
 	// If there is only one generation, return early to avoid re-compacting the same file
 	// over and over again.
 	if len(generations) <= 1 && !generations.hasTombstones() {
@@ -591,13 +646,9 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 				cGroup = append(cGroup, f.Path)
 			}
 		}
-		sort.Strings(cGroup)
+		sortTSMPaths(cGroup)
 		tsmFiles = append(tsmFiles, cGroup)
 	}
-
-	if !c.acquire(tsmFiles) {
-		return nil
-	}
 	return tsmFiles
 }
 
@@ -698,6 +749,14 @@ type Compactor struct {
 	// RateLimit is the limit for disk writes for all concurrent compactions.
 	RateLimit limiter.Rate
 
+	// EncryptionKey is the master key used to encrypt the TSM files written.
+	// The files are not encrypted if it is nil.
+	EncryptionKey []byte
+
+	// PartitionMeasurements makes snapshots write the data of each measurement
+	// of a bucket to the files of its own partition.
+	PartitionMeasurements bool
+
 	formatFileName FormatFileNameFunc
 	parseFileName  ParseFileNameFunc
 
@@ -842,26 +901,50 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 		throttle = false
 	}
 
-	splits := cache.Split(concurrency)
+	// Each split of the cache is written to the files of its directory, which
+	// is the directory of its measurement partition if they are enabled.
+	type split struct {
+		cache       *Cache
+		dir, prefix string
+	}
+	var splits []split
+	if c.PartitionMeasurements {
+		for dir, sp := range cache.SplitMeasurements() {
+			splits = append(splits, split{cache: sp, dir: filepath.Join(c.Dir, dir), prefix: filepath.Base(dir)})
+		}
+	} else {
+		for _, sp := range cache.Split(concurrency) {
+			splits = append(splits, split{cache: sp, dir: c.Dir})
+		}
+	}
 
 	type res struct {
 		files []string
 		err   error
 	}
 
-	resC := make(chan res, concurrency)
-	for i := 0; i < concurrency; i++ {
-		go func(sp *Cache) {
-			iter := NewCacheKeyIterator(sp, MaxPointsPerBlock, intC)
-			files, err := c.writeNewFiles(c.FileStore.NextGeneration(), 0, nil, iter, throttle)
-			resC <- res{files: files, err: err}
+	resC := make(chan res, len(splits))
+	sem := make(chan struct{}, concurrency)
+	for _, sp := range splits {
+		go func(sp split) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
 
-		}(splits[i])
+			if sp.prefix != "" {
+				if err := os.MkdirAll(sp.dir, 0777); err != nil {
+					resC <- res{err: err}
+					return
+				}
+			}
+			iter := NewCacheKeyIterator(sp.cache, MaxPointsPerBlock, intC)
+			files, err := c.writeNewFilesTo(sp.dir, sp.prefix, c.FileStore.NextGeneration(), 0, iter, throttle)
+			resC <- res{files: files, err: err}
+		}(sp)
 	}
 
 	var err error
-	files := make([]string, 0, concurrency)
-	for i := 0; i < concurrency; i++ {
+	files := make([]string, 0, len(splits))
+	for range splits {
 		result := <-resC
 		if result.err != nil {
 			err = result.err
@@ -1020,6 +1103,10 @@ func (c *Compactor) removeTmpFiles(files []string) error {
 	for _, f := range files {
 		if err := os.Remove(f); err != nil {
 			return fmt.Errorf("error removing temp compaction file: %v", err)
+		} else if err := os.Remove(StatsFilename(f)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing temp compaction stats file: %v", err)
+		} else if err := os.Remove(KeyStatsFilename(f)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing temp compaction key stats file: %v", err)
 		}
 	}
 	return nil
@@ -1028,14 +1115,31 @@ func (c *Compactor) removeTmpFiles(files []string) error {
 // writeNewFiles writes from the iterator into new TSM files, rotating
 // to a new file once it has reached the max TSM file size.
 func (c *Compactor) writeNewFiles(generation, sequence int, src []string, iter KeyIterator, throttle bool) ([]string, error) {
+	// New files are written to the directory of the newest source file, which
+	// keeps compacted data in the storage tier and partition it was in.
+	dir, prefix := c.Dir, ""
+	if len(src) > 0 {
+		dir = filepath.Dir(src[len(src)-1])
+		prefix, _ = splitPartitionPrefix(filepath.Base(src[len(src)-1]))
+	}
+	return c.writeNewFilesTo(dir, prefix, generation, sequence, iter, throttle)
+}
+
+// writeNewFilesTo writes the keys of iter to new TSM files in dir, whose names
+// start with the measurement prefix of its partition, if it is not empty.
+func (c *Compactor) writeNewFilesTo(dir, prefix string, generation, sequence int, iter KeyIterator, throttle bool) ([]string, error) {
 	// These are the new TSM files written
 	var files []string
 
+	if prefix != "" {
+		prefix += string(partitionPrefixSep)
+	}
+
 	for {
 		sequence++
 
 		// New TSM files are written to a temp file and renamed when fully completed.
-		fileName := filepath.Join(c.Dir, c.formatFileName(generation, sequence)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+		fileName := filepath.Join(dir, prefix+c.formatFileName(generation, sequence)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
 		statsFileName := StatsFilename(fileName)
 
 		// Write as much as possible to this file
@@ -1053,6 +1157,8 @@ func (c *Compactor) writeNewFiles(generation, sequence int, src []string, iter K
 				return nil, err
 			} else if err := os.RemoveAll(statsFileName); err != nil && !os.IsNotExist(err) {
 				return nil, err
+			} else if err := os.RemoveAll(KeyStatsFilename(fileName)); err != nil && !os.IsNotExist(err) {
+				return nil, err
 			}
 			break
 		} else if _, ok := err.(errCompactionInProgress); ok {
@@ -1066,6 +1172,8 @@ func (c *Compactor) writeNewFiles(generation, sequence int, src []string, iter K
 					return nil, err
 				} else if err := os.RemoveAll(StatsFilename(f)); err != nil && !os.IsNotExist(err) {
 					return nil, err
+				} else if err := os.RemoveAll(KeyStatsFilename(f)); err != nil && !os.IsNotExist(err) {
+					return nil, err
 				}
 			}
 			// We hit an error and didn't finish the compaction.  Remove the temp file and abort.
@@ -1073,6 +1181,8 @@ func (c *Compactor) writeNewFiles(generation, sequence int, src []string, iter K
 				return nil, err
 			} else if err := os.RemoveAll(statsFileName); err != nil && !os.IsNotExist(err) {
 				return nil, err
+			} else if err := os.RemoveAll(KeyStatsFilename(fileName)); err != nil && !os.IsNotExist(err) {
+				return nil, err
 			}
 			return nil, err
 		}
@@ -1084,6 +1194,14 @@ func (c *Compactor) writeNewFiles(generation, sequence int, src []string, iter K
 	return files, nil
 }
 
+// writerOptions returns the options of the TSM writers of the compactor.
+func (c *Compactor) writerOptions() []tsmWriterOption {
+	if c.EncryptionKey == nil {
+		return nil
+	}
+	return []tsmWriterOption{WithTSMWriterEncryptionKey(c.EncryptionKey)}
+}
+
 func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err error) {
 	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
 	if err != nil {
@@ -1111,12 +1229,12 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 	// Use a disk based TSM buffer if it looks like we might create a big index
 	// in memory.
 	if iter.EstimatedIndexSize() > 64*1024*1024 {
-		w, err = NewTSMWriterWithDiskBuffer(limitWriter)
+		w, err = NewTSMWriterWithDiskBuffer(limitWriter, c.writerOptions()...)
 		if err != nil {
 			return err
 		}
 	} else {
-		w, err = NewTSMWriter(limitWriter)
+		w, err = NewTSMWriter(limitWriter, c.writerOptions()...)
 		if err != nil {
 			return err
 		}
@@ -2055,6 +2173,30 @@ func (a tsmGenerations) level() int {
 	return level
 }
 
+// tier returns the storage tier of the newest generation.
+func (a tsmGenerations) tier() Tier {
+	return a[len(a)-1].tier()
+}
+
+// partitions groups the generations by the measurement partition of their
+// files, keeping their order within each partition. Files that are not
+// partitioned are in a group of their own.
+func (a tsmGenerations) partitions() []tsmGenerations {
+	var partitions []tsmGenerations
+	index := make(map[string]int)
+	for _, g := range a {
+		p := g.partition()
+		i, ok := index[p]
+		if !ok {
+			i = len(partitions)
+			index[p] = i
+			partitions = append(partitions, nil)
+		}
+		partitions[i] = append(partitions[i], g)
+	}
+	return partitions
+}
+
 func (a tsmGenerations) chunk(size int) []tsmGenerations {
 	var chunks []tsmGenerations
 	for len(a) > 0 {
@@ -0,0 +1,259 @@
+package tsm1
+
+import (
+	"fmt"
+	"time"
+)
+
+// Names of the built-in compaction strategies.
+const (
+	DefaultCompactionStrategyName = "default"
+	LeveledCompactionStrategyName = "leveled"
+)
+
+// CompactionPlan is a group of TSM files to compact together.
+type CompactionPlan struct {
+	// Level decides how the compaction is scheduled and run. Levels 1 and 2
+	// are high priority level compactions, level 3 is a low priority level
+	// compaction and level 4 is a full compaction.
+	Level int
+
+	// Group holds the paths of the files to compact, ordered by generation.
+	Group CompactionGroup
+}
+
+// CompactionStrategy decides which TSM files the engine compacts together.
+type CompactionStrategy interface {
+	// PlanCompactions returns the compactions to run for files, which are all
+	// the TSM files of the engine ordered by generation. The groups of the
+	// plans must not overlap. Plans with files that are already being
+	// compacted are not run.
+	PlanCompactions(files []TSMFile) []CompactionPlan
+}
+
+// NewCompactionStrategy returns the built-in compaction strategy called name.
+// The default strategy is nil, which makes the engine plan compactions with
+// its CompactionPlanner.
+func NewCompactionStrategy(name string) (CompactionStrategy, error) {
+	switch name {
+	case "", DefaultCompactionStrategyName:
+		return nil, nil
+	case LeveledCompactionStrategyName:
+		return NewLeveledCompactionStrategy(), nil
+	}
+	return nil, fmt.Errorf("unknown compaction strategy %q", name)
+}
+
+// DefaultCompactionStrategy is the CompactionStrategy that plans compactions
+// with a CompactionPlanner, which rolls up generations of TSM files into
+// larger ones in stages.
+type DefaultCompactionStrategy struct {
+	Planner CompactionPlanner
+
+	// LastWrite returns the time of the last write to the engine, after
+	// which a full compaction is planned once writes have gone cold.
+	LastWrite func() time.Time
+}
+
+// PlanCompactions returns the level, full and optimize compactions planned by
+// the planner. The planner reads the files from its own FileStore.
+func (s *DefaultCompactionStrategy) PlanCompactions(files []TSMFile) []CompactionPlan {
+	var plans []CompactionPlan
+	var groups []CompactionGroup
+	add := func(level int, g []CompactionGroup) {
+		for _, group := range g {
+			plans = append(plans, CompactionPlan{Level: level, Group: group})
+		}
+		groups = append(groups, g...)
+	}
+
+	add(1, s.Planner.PlanLevel(1))
+	add(2, s.Planner.PlanLevel(2))
+	add(3, s.Planner.PlanLevel(3))
+
+	// If no full compactions are need, see if an optimize is needed
+	full := s.Planner.Plan(s.LastWrite())
+	if len(full) == 0 {
+		full = s.Planner.PlanOptimize()
+	}
+	add(4, full)
+
+	// The planner holds the files of its plans so that the plans of each level
+	// do not overlap. The engine holds them from here on.
+	s.Planner.Release(groups)
+	return plans
+}
+
+// Default values of the LeveledCompactionStrategy.
+const (
+	DefaultLeveledL0Trigger = 4
+	DefaultLeveledBaseSize  = 64 * 1024 * 1024
+	DefaultLeveledSizeRatio = 10
+	DefaultLeveledMaxLevel  = 4
+)
+
+// LeveledCompactionStrategy is a CompactionStrategy that keeps the generations
+// of TSM files in levels of increasing size, like the leveled compaction of
+// LSM trees. Cache snapshots are in level 0, and are merged into the newest
+// generation of level 1 once there are enough of them. A generation that grows
+// into the level of the generation before it is merged into it, so that each
+// generation is much larger than the next. This keeps the number of files that
+// reads touch low, at the cost of rewriting more data.
+type LeveledCompactionStrategy struct {
+	// L0Trigger is the number of level 0 generations that are merged into
+	// level 1.
+	L0Trigger int
+
+	// BaseSize is the maximum size of a level 1 generation. Each level above
+	// it holds generations SizeRatio times larger.
+	BaseSize  uint64
+	SizeRatio uint64
+
+	// MaxLevel is the highest level. Its generations are never merged.
+	MaxLevel int
+
+	// ParseFileName returns the generation and sequence of a TSM file.
+	ParseFileName ParseFileNameFunc
+}
+
+// NewLeveledCompactionStrategy returns a LeveledCompactionStrategy with the
+// default settings.
+func NewLeveledCompactionStrategy() *LeveledCompactionStrategy {
+	return &LeveledCompactionStrategy{
+		L0Trigger:     DefaultLeveledL0Trigger,
+		BaseSize:      DefaultLeveledBaseSize,
+		SizeRatio:     DefaultLeveledSizeRatio,
+		MaxLevel:      DefaultLeveledMaxLevel,
+		ParseFileName: DefaultParseFileName,
+	}
+}
+
+// leveledGeneration is a generation of TSM files in a leveled compaction.
+type leveledGeneration struct {
+	paths         []string
+	size          uint64
+	level         int
+	hasTombstones bool
+}
+
+// PlanCompactions returns the merges of level 0 into level 1, and of each
+// generation that has grown into the level of the generation before it.
+// Generations with tombstones are rewritten by themselves if they are not
+// merged. The files of each measurement partition are leveled separately.
+func (s *LeveledCompactionStrategy) PlanCompactions(files []TSMFile) []CompactionPlan {
+	var partitions [][]TSMFile
+	index := make(map[string]int)
+	for _, f := range files {
+		p := tsmFilePartition(f.Path())
+		i, ok := index[p]
+		if !ok {
+			i = len(partitions)
+			index[p] = i
+			partitions = append(partitions, nil)
+		}
+		partitions[i] = append(partitions[i], f)
+	}
+
+	var plans []CompactionPlan
+	for _, files := range partitions {
+		plans = append(plans, s.planPartition(files)...)
+	}
+	return plans
+}
+
+// planPartition returns the compactions of the files of a partition.
+func (s *LeveledCompactionStrategy) planPartition(files []TSMFile) []CompactionPlan {
+	gens := s.generations(files)
+	used := make([]bool, len(gens))
+
+	var plans []CompactionPlan
+	plan := func(level int, from, to int) {
+		var group CompactionGroup
+		for i := from; i < to; i++ {
+			group = append(group, gens[i].paths...)
+			used[i] = true
+		}
+		plans = append(plans, CompactionPlan{Level: level, Group: group})
+	}
+
+	// Level 0 generations are always the newest.
+	l0 := len(gens)
+	for l0 > 0 && gens[l0-1].level == 0 {
+		l0--
+	}
+	if len(gens)-l0 >= s.L0Trigger {
+		from := l0
+		if l0 > 0 && gens[l0-1].level == 1 {
+			from--
+		}
+		plan(1, from, len(gens))
+	}
+
+	for i := l0 - 1; i > 0; i-- {
+		older, newer := gens[i-1], gens[i]
+		if used[i] || older.level >= s.MaxLevel || newer.level < older.level {
+			continue
+		}
+
+		level := 2
+		if older.level > 1 {
+			level = 3
+		}
+		plan(level, i-1, i+1)
+		i--
+	}
+
+	for i, g := range gens {
+		if !used[i] && g.hasTombstones {
+			plan(3, i, i+1)
+		}
+	}
+	return plans
+}
+
+// generations groups files by generation, and assigns each generation its level.
+func (s *LeveledCompactionStrategy) generations(files []TSMFile) []*leveledGeneration {
+	parseFileName := s.ParseFileName
+	if parseFileName == nil {
+		parseFileName = DefaultParseFileName
+	}
+
+	var gens []*leveledGeneration
+	lastGen := -1
+	snapshot := false
+	for _, f := range files {
+		gen, seq, err := parseFileName(f.Path())
+		if err != nil {
+			continue
+		}
+
+		if len(gens) == 0 || gen != lastGen {
+			// Cache snapshots start at sequence 1, and compactions write
+			// sequences after those of the files they compact.
+			snapshot = seq == 1
+			gens = append(gens, &leveledGeneration{})
+			lastGen = gen
+		}
+
+		g := gens[len(gens)-1]
+		g.paths = append(g.paths, f.Path())
+		g.size += uint64(f.Size())
+		g.hasTombstones = g.hasTombstones || f.HasTombstones()
+		if snapshot {
+			g.level = 0
+		} else {
+			g.level = s.level(g.size)
+		}
+	}
+	return gens
+}
+
+// level returns the level of a generation that is not a cache snapshot.
+func (s *LeveledCompactionStrategy) level(size uint64) int {
+	level, max := 1, s.BaseSize
+	for level < s.MaxLevel && size > max {
+		level++
+		max *= s.SizeRatio
+	}
+	return level
+}
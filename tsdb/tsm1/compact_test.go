@@ -1767,6 +1767,84 @@ func TestDefaultPlanner_PlanLevel_SmallestCompactionStep(t *testing.T) {
 	}
 }
 
+// Ensure that generations in different storage tiers are compacted separately.
+// Ensure that generations in different storage tiers are not compacted together.
+func TestDefaultPlanner_PlanLevel_Tiers(t *testing.T) {
+	var data []tsm1.FileStat
+	for i := 1; i <= 12; i++ {
+		tier := tsm1.TierWarm
+		if i > 4 {
+			tier = tsm1.TierHot
+		}
+		data = append(data, tsm1.FileStat{
+			Path: fmt.Sprintf("%02d-01.tsm1", i),
+			Tier: tier,
+			Size: 1 * 1024 * 1024,
+		})
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+
+	tsm := cp.PlanLevel(1)
+	if exp, got := 1, len(tsm); got != exp {
+		t.Fatalf("compaction group length mismatch: got %v, exp %v", got, exp)
+	}
+
+	expFiles := data[4:]
+	if exp, got := len(expFiles), len(tsm[0]); got != exp {
+		t.Fatalf("tsm file length mismatch: got %v, exp %v", got, exp)
+	}
+	for i, p := range expFiles {
+		if got, exp := tsm[0][i], p.Path; got != exp {
+			t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+		}
+	}
+}
+
+func TestDefaultPlanner_PlanLevel_Partitions(t *testing.T) {
+	var data []tsm1.FileStat
+	for i := 1; i <= 16; i++ {
+		m := "cpu"
+		if i%2 == 0 {
+			m = "mem"
+		}
+		data = append(data, tsm1.FileStat{
+			Path: filepath.Join("data", "bucket", m, fmt.Sprintf("%s.%02d-01.tsm1", m, i)),
+			Size: 1 * 1024 * 1024,
+		})
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+
+	tsm := cp.PlanLevel(1)
+	if exp, got := 2, len(tsm); got != exp {
+		t.Fatalf("compaction group length mismatch: got %v, exp %v", got, exp)
+	}
+
+	for i, group := range tsm {
+		if exp, got := 8, len(group); got != exp {
+			t.Fatalf("tsm file length mismatch: got %v, exp %v", got, exp)
+		}
+		for j, path := range group {
+			if got, exp := path, data[2*j+i].Path; got != exp {
+				t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+			}
+		}
+	}
+}
+
 func TestDefaultPlanner_PlanLevel_SplitFile(t *testing.T) {
 	data := []tsm1.FileStat{
 		{
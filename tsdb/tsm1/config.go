@@ -33,8 +33,23 @@ type Config struct {
 	// preallocation to improve throughput. Currently used in the series file.
 	LargeSeriesWriteThreshold int `toml:"large-series-write-threshold"`
 
+	// RecoveryMode makes the engine skip corrupt TSM files when it is opened,
+	// starting with the data of the other files rather than failing to open.
+	RecoveryMode bool `toml:"recovery-mode"`
+
+	// EncryptionKeyHex is the hex encoded 256 bit master key used to encrypt
+	// TSM files at rest. TSM files are not encrypted if it is empty.
+	EncryptionKeyHex string `toml:"encryption-key-hex"`
+
+	// MeasurementPartition makes cache snapshots write the data of each
+	// measurement of a bucket to TSM files in a directory of its own, which
+	// are only compacted with each other. It cannot be combined with an
+	// encryption key.
+	MeasurementPartition bool `toml:"measurement-partition"`
+
 	Compaction CompactionConfig `toml:"compaction"`
 	Cache      CacheConfig      `toml:"cache"`
+	Tiers      TierConfig       `toml:"tiers"`
 }
 
 // NewConfig constructs a Config with the default values.
@@ -50,6 +65,7 @@ func NewConfig() Config {
 			Throughput:            toml.Size(DefaultCompactThroughput),
 			ThroughputBurst:       toml.Size(DefaultCompactThroughputBurst),
 			MaxConcurrent:         DefaultCompactMaxConcurrent,
+			Strategy:              DefaultCompactionStrategyName,
 		},
 	}
 }
@@ -82,6 +98,10 @@ type CompactionConfig struct {
 	// MaxConcurrent is the maximum number of concurrent full and level compactions that can
 	// run at one time.  A value of 0 results in 50% of runtime.GOMAXPROCS(0) used at runtime.
 	MaxConcurrent int `toml:"max-concurrent"`
+
+	// Strategy is the name of the compaction strategy that plans which TSM
+	// files are compacted together: "default" or "leveled".
+	Strategy string `toml:"strategy"`
 }
 
 // Default Cache configuration values.
@@ -99,6 +119,13 @@ type CacheConfig struct {
 	// rejecting writes.
 	MaxMemorySize toml.Size `toml:"max-memory-size"`
 
+	// MaxMemorySizePerOrg, when non-zero, is the maximum size the values of a
+	// single bucket of an organization should reach in the engine's cache. A
+	// write that takes a bucket over this size schedules that bucket's cached
+	// values to be written to a TSM file in the background, leaving the values
+	// of other buckets, of the same or other organizations, in the cache.
+	MaxMemorySizePerOrg toml.Size `toml:"max-memory-size-per-org"`
+
 	// SnapshotMemorySize is the size at which the engine will snapshot the cache and
 	// write it to a TSM file, freeing up memory
 	SnapshotMemorySize toml.Size `toml:"snapshot-memory-size"`
@@ -113,6 +140,11 @@ type CacheConfig struct {
 	//
 	// SnapshotWriteColdDuration should not be larger than SnapshotAgeDuration
 	SnapshotWriteColdDuration toml.Duration `toml:"snapshot-write-cold-duration"`
+
+	// WarmupDuration, when set, causes the data written within this duration of
+	// the engine opening to be loaded from TSM files into the cache in the
+	// background, up to the smaller of MaxMemorySize and SnapshotMemorySize.
+	WarmupDuration toml.Duration `toml:"warmup-duration"`
 }
 
 // NewCacheConfig initialises a new CacheConfig with default values.
@@ -141,6 +173,11 @@ type WALConfig struct {
 	// useful for slower disks or when WAL write contention is seen.  A value of 0 fsyncs
 	// every write to the WAL.
 	FsyncDelay toml.Duration `toml:"fsync-delay"`
+
+	// MaxDiskBytes is the size of the WAL segments on disk above which the
+	// cache is flushed to TSM files, even if it is not due to be snapshotted,
+	// so that the flushed segments are removed. A value of 0 is unlimited.
+	MaxDiskBytes toml.Size `toml:"max-disk-bytes"`
 }
 
 func NewWALConfig() WALConfig {
@@ -137,6 +137,50 @@ func (a Values) Contains(min, max int64) bool {
 	return rmax-rmin > 0
 }
 
+// ContainsUnsorted returns true if values exist for the time interval
+// [min, max] inclusive. Unlike Contains, the values need not be sorted, so
+// it may be used on the values of a cache entry that has not been
+// deduplicated.
+//
+// Timestamps are compared eight at a time: t is within [min, max] if t-min
+// is at most max-min as unsigned integers, which is computed without a
+// branch, and the results for all eight are combined with a bitwise or
+// before testing them.
+func (a Values) ContainsUnsorted(min, max int64) bool {
+	if min > max {
+		return false
+	}
+	lo, span := uint64(min), uint64(max)-uint64(min)
+
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		b := a[i : i+8 : i+8]
+		in := lessEqual(uint64(b[0].UnixNano())-lo, span) |
+			lessEqual(uint64(b[1].UnixNano())-lo, span) |
+			lessEqual(uint64(b[2].UnixNano())-lo, span) |
+			lessEqual(uint64(b[3].UnixNano())-lo, span) |
+			lessEqual(uint64(b[4].UnixNano())-lo, span) |
+			lessEqual(uint64(b[5].UnixNano())-lo, span) |
+			lessEqual(uint64(b[6].UnixNano())-lo, span) |
+			lessEqual(uint64(b[7].UnixNano())-lo, span)
+		if in != 0 {
+			return true
+		}
+	}
+	for ; i < len(a); i++ {
+		if t := a[i].UnixNano(); t >= min && t <= max {
+			return true
+		}
+	}
+	return false
+}
+
+// lessEqual returns 1 if a ≤ b and 0 otherwise, by computing the borrow of
+// b-a.
+func lessEqual(a, b uint64) uint64 {
+	return ((^b&a | ^(b^a)&(b-a)) >> 63) ^ 1
+}
+
 // InfluxQLType returns the influxql.DataType the values map to.
 func (a Values) InfluxQLType() (influxql.DataType, error) {
 	if len(a) == 0 {
@@ -2,6 +2,7 @@ package tsm1_test
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -614,6 +615,56 @@ func TestValues_Contains(t *testing.T) {
 	}
 }
 
+func TestValues_ContainsUnsorted(t *testing.T) {
+	// Compare against Contains for sorted values, including every boundary
+	// around them and lengths either side of a multiple of eight.
+	for n := 0; n <= 17; n++ {
+		vals := make(tsm1.Values, n)
+		for i := range vals {
+			vals[i] = tsm1.NewRawIntegerValue(int64(10+2*i), 0)
+		}
+		for min := int64(8); min <= int64(12+2*n); min++ {
+			for max := min - 1; max <= int64(12+2*n); max++ {
+				if got, exp := vals.ContainsUnsorted(min, max), vals.Contains(min, max); got != exp {
+					t.Fatalf("n=%d [%d,%d]: got %v, expected %v", n, min, max, got, exp)
+				}
+			}
+		}
+	}
+
+	// Extreme timestamps must not overflow the comparison.
+	vals := tsm1.Values{
+		tsm1.NewRawIntegerValue(math.MinInt64, 0),
+		tsm1.NewRawIntegerValue(math.MaxInt64, 0),
+	}
+	for _, tc := range []struct {
+		min, max int64
+		exp      bool
+	}{
+		{math.MinInt64, math.MaxInt64, true},
+		{math.MinInt64, math.MinInt64, true},
+		{math.MaxInt64, math.MaxInt64, true},
+		{math.MinInt64 + 1, math.MaxInt64 - 1, false},
+	} {
+		if got := vals.ContainsUnsorted(tc.min, tc.max); got != tc.exp {
+			t.Errorf("[%d,%d]: got %v, expected %v", tc.min, tc.max, got, tc.exp)
+		}
+	}
+
+	// Unsorted values, as held by a cache entry, are all compared.
+	vals = make(tsm1.Values, 20)
+	for i := range vals {
+		vals[i] = tsm1.NewRawIntegerValue(int64(100-i), 0)
+	}
+	vals[13] = tsm1.NewRawIntegerValue(5, 0)
+	if !vals.ContainsUnsorted(5, 5) {
+		t.Error("expected unsorted values to contain 5")
+	}
+	if vals.ContainsUnsorted(6, 80) {
+		t.Error("expected unsorted values not to contain [6,80]")
+	}
+}
+
 func TestIntegerValues_Merge(t *testing.T) {
 	integerValue := func(t int64, f int64) tsm1.IntegerValue {
 		return tsm1.NewValue(t, f).(tsm1.IntegerValue)
@@ -1939,3 +1990,39 @@ func BenchmarkValues_EncodeBool(b *testing.B) {
 		tsm1.Values(a).Encode(buf)
 	}
 }
+
+func BenchmarkValues_ContainsUnsorted(b *testing.B) {
+	vals := make(tsm1.Values, 10000)
+	for i := range vals {
+		vals[i] = tsm1.NewRawIntegerValue(int64(i), 0)
+	}
+
+	// containsScalar compares each timestamp in turn.
+	containsScalar := func(a tsm1.Values, min, max int64) bool {
+		for _, v := range a {
+			if t := v.UnixNano(); t >= min && t <= max {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, bm := range []struct {
+		name     string
+		min, max int64
+	}{
+		{"hit", int64(len(vals) - 1), int64(len(vals) - 1)},
+		{"miss", int64(len(vals)), math.MaxInt64},
+	} {
+		b.Run(bm.name+"/scalar", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				containsScalar(vals, bm.min, bm.max)
+			}
+		})
+		b.Run(bm.name+"/unsorted", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				vals.ContainsUnsorted(bm.min, bm.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package tsm1
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+/*
+Encrypted TSM files have the EncryptedVersion and differ from other TSM files
+in three places. Each block has a flag byte after its CRC, which is always set,
+followed by a nonce and the AES-256-GCM ciphertext of the data. The flag and
+the offset of the block are authenticated with the data. The index is a nonce
+followed by the ciphertext of the index. The footer stores a random salt, from
+which the key of the file is derived with HKDF, and a key check, also derived
+from the master key and salt, which tells a file encrypted with another key
+apart from a corrupt one. The master key is never stored in the file.
+
+┌────────────────────────────────────┐ ┌──────────────────┐ ┌─────────────────────────────┐
+│               Block                │ │      Index       │ │           Footer            │
+├─────────┬────────┬─────────┬───────┤ ├─────────┬────────┤ ├─────────┬─────────┬─────────┤
+│  CRC    │  Flag  │  Nonce  │ Data  │ │  Nonce  │ Index  │ │  Salt   │Key Check│Index Ofs│
+│ 4 bytes │ 1 byte │12 bytes │N bytes│ │12 bytes │N bytes │ │32 bytes │16 bytes │ 8 bytes │
+└─────────┴────────┴─────────┴───────┘ └─────────┴────────┘ └─────────┴─────────┴─────────┘
+
+The stats and key stats files of a TSM file, which hold measurement names and
+tag keys, are encrypted as a whole when an encryption key is set. They start
+with a magic number, followed by their own salt and key check, and the nonce
+and ciphertext of the plaintext file. Tombstone files are encrypted as well;
+see tombstone.go.
+
+┌─────────┬─────────┬─────────┬─────────┬──────────┐
+│  Magic  │  Salt   │Key Check│  Nonce  │   Data   │
+│ 4 bytes │32 bytes │16 bytes │12 bytes │ N bytes  │
+└─────────┴─────────┴─────────┴─────────┴──────────┘
+*/
+
+const (
+	// EncryptedVersion is the version of TSM files with encrypted blocks and index.
+	EncryptedVersion byte = 2
+
+	// EncryptionKeySize is the size in bytes of the master encryption key.
+	EncryptionKeySize = 32
+
+	// Size in bytes of the salt in the footer of an encrypted TSM file
+	encryptionSaltSize = 32
+
+	// Size in bytes of the key check in the footer of an encrypted TSM file
+	encryptionKeyCheckSize = 16
+
+	// Flag of the blocks of an encrypted TSM file
+	blockFlagEncrypted byte = 1
+
+	// Magic number of encrypted stats and key stats files
+	encryptedFileMagic = "TSE1"
+)
+
+var (
+	// ErrEncryptionKeyRequired is returned when opening an encrypted TSM file
+	// without an encryption key.
+	ErrEncryptionKeyRequired = errors.New("tsm file is encrypted and no encryption key is set")
+
+	// ErrEncryptionKeyMismatch is returned when opening an encrypted TSM file
+	// with another key than the one it was encrypted with.
+	ErrEncryptionKeyMismatch = errors.New("tsm file is encrypted with a different encryption key")
+)
+
+// ParseEncryptionKey decodes a master encryption key from 64 hex characters.
+// An empty string returns a nil key, which disables encryption.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	} else if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key: got %d bytes, exp %d", len(key), EncryptionKeySize)
+	}
+	return key, nil
+}
+
+// tsmCipher encrypts and decrypts the blocks and index of a TSM file.
+type tsmCipher struct {
+	aead     cipher.AEAD
+	salt     []byte
+	keyCheck []byte
+}
+
+// newTSMCipher returns a tsmCipher with the key derived from masterKey and
+// salt. A nil salt is replaced by a random one.
+func newTSMCipher(masterKey, salt []byte) (*tsmCipher, error) {
+	if len(masterKey) != EncryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key: got %d bytes, exp %d", len(masterKey), EncryptionKeySize)
+	}
+	if salt == nil {
+		salt = make([]byte, encryptionSaltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+	}
+
+	key := make([]byte, EncryptionKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, salt, []byte("influxdb tsm1 file key")), key); err != nil {
+		return nil, err
+	}
+	keyCheck := make([]byte, encryptionKeyCheckSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, salt, []byte("influxdb tsm1 key check")), keyCheck); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &tsmCipher{aead: aead, salt: salt, keyCheck: keyCheck}, nil
+}
+
+// checkKey returns ErrEncryptionKeyMismatch if keyCheck, read from the footer
+// of a file, was not derived from the same master key and salt.
+func (c *tsmCipher) checkKey(keyCheck []byte) error {
+	if subtle.ConstantTimeCompare(c.keyCheck, keyCheck) != 1 {
+		return ErrEncryptionKeyMismatch
+	}
+	return nil
+}
+
+// sealBlock returns the flag, nonce and ciphertext of a block written at
+// offset. The flag and offset are authenticated so that blocks cannot be
+// moved or passed off as plaintext.
+func (c *tsmCipher) sealBlock(block []byte, offset int64) ([]byte, error) {
+	n := c.aead.NonceSize()
+	buf := make([]byte, 1+n, 1+n+len(block)+c.aead.Overhead())
+	buf[0] = blockFlagEncrypted
+	if _, err := io.ReadFull(rand.Reader, buf[1:]); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(buf, buf[1:], block, blockAdditionalData(buf[0], offset)), nil
+}
+
+// openBlock returns the plaintext of the block data read at offset. Every
+// block of an encrypted file must be encrypted.
+func (c *tsmCipher) openBlock(data []byte, offset int64) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("encrypted block too short")
+	} else if data[0] != blockFlagEncrypted {
+		return nil, fmt.Errorf("unknown block flag %d", data[0])
+	}
+
+	n := c.aead.NonceSize()
+	if len(data) < 1+n {
+		return nil, fmt.Errorf("encrypted block too short")
+	}
+	return c.aead.Open(nil, data[1:1+n], data[1+n:], blockAdditionalData(data[0], offset))
+}
+
+// blockAdditionalData returns the data authenticated with a block: its flag
+// followed by its offset.
+func blockAdditionalData(flag byte, offset int64) []byte {
+	var ad [9]byte
+	ad[0] = flag
+	binary.BigEndian.PutUint64(ad[1:], uint64(offset))
+	return ad[:]
+}
+
+// sealIndex returns the nonce and ciphertext of the index.
+func (c *tsmCipher) sealIndex(index []byte) ([]byte, error) {
+	return c.seal(index, nil)
+}
+
+// openIndex returns the plaintext of the index.
+func (c *tsmCipher) openIndex(data []byte) ([]byte, error) {
+	return c.open(data, nil)
+}
+
+// seal returns the nonce and ciphertext of data, authenticated with ad.
+func (c *tsmCipher) seal(data, ad []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	buf := make([]byte, n, n+len(data)+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(buf, buf, data, ad), nil
+}
+
+// open returns the plaintext of the nonce and ciphertext returned by seal.
+func (c *tsmCipher) open(data, ad []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("encrypted data too short")
+	}
+	return c.aead.Open(nil, data[:n], data[n:], ad)
+}
+
+// writeSealed writes src to w. It is encrypted as a whole if masterKey is set.
+func writeSealed(w io.Writer, masterKey []byte, src io.WriterTo) error {
+	if masterKey == nil {
+		_, err := src.WriteTo(w)
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		return err
+	}
+	c, err := newTSMCipher(masterKey, nil)
+	if err != nil {
+		return err
+	}
+	data, err := c.seal(buf.Bytes(), nil)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(encryptedFileMagic)
+	bw.Write(c.salt)
+	bw.Write(c.keyCheck)
+	bw.Write(data)
+	return bw.Flush()
+}
+
+// readSealed reads dst from r, decrypting it if it was written encrypted by
+// writeSealed. Files written without encryption are read with or without a key.
+func readSealed(r io.Reader, masterKey []byte, dst io.ReaderFrom) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(encryptedFileMagic)); err != nil || string(magic) != encryptedFileMagic {
+		_, err := dst.ReadFrom(br)
+		return err
+	} else if masterKey == nil {
+		return ErrEncryptionKeyRequired
+	}
+
+	b, err := ioutil.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	b = b[len(encryptedFileMagic):]
+	if len(b) < encryptionSaltSize+encryptionKeyCheckSize {
+		return fmt.Errorf("encrypted file too short")
+	}
+	c, err := newTSMCipher(masterKey, b[:encryptionSaltSize])
+	if err != nil {
+		return err
+	} else if err := c.checkKey(b[encryptionSaltSize : encryptionSaltSize+encryptionKeyCheckSize]); err != nil {
+		return err
+	}
+	data, err := c.open(b[encryptionSaltSize+encryptionKeyCheckSize:], nil)
+	if err != nil {
+		return err
+	}
+	_, err = dst.ReadFrom(bytes.NewReader(data))
+	return err
+}
@@ -103,10 +103,23 @@ func WithSnapshotter(snapshotter Snapshotter) EngineOption {
 	}
 }
 
+// WithFullCompactionObserver sets a function called after each full
+// compaction of the engine's TSM files.
+func WithFullCompactionObserver(fn func()) EngineOption {
+	return func(e *Engine) {
+		e.fullCompactionObserver = fn
+	}
+}
+
 // Engine represents a storage engine with compressed blocks.
 type Engine struct {
 	mu sync.RWMutex
 
+	// warmMu is held for writing while a key is loaded into the cache by
+	// WarmCache and for reading while data is deleted, so that values read from
+	// TSM files before a delete are not added to the cache after it.
+	warmMu sync.RWMutex
+
 	index    *tsi1.Index
 	indexref *lifecycle.Reference
 
@@ -125,6 +138,17 @@ type Engine struct {
 	snapDone chan struct{}   // channel to signal snapshot compactions to stop
 	snapWG   *sync.WaitGroup // waitgroup for running snapshot compactions
 
+	// bucketSnapshotC wakes the snapshot goroutine to flush the buckets in
+	// bucketSnapshots, which went over their share of the cache.
+	bucketSnapshotMu sync.Mutex
+	bucketSnapshots  map[[16]byte]struct{}
+	bucketSnapshotC  chan struct{}
+
+	// bucketSnapshotBytes is the number of bytes written by bucket
+	// snapshots since the last full snapshot. Their WAL segments are kept
+	// until a full snapshot. Should be accessed atomically.
+	bucketSnapshotBytes uint64
+
 	path     string
 	sfile    *seriesfile.SeriesFile
 	sfileref *lifecycle.Reference
@@ -135,6 +159,14 @@ type Engine struct {
 	CompactionPlan CompactionPlanner
 	FileStore      *FileStore
 
+	// compactionStrategy plans the compactions of the engine. If nil, they
+	// are planned by CompactionPlan.
+	compactionStrategy CompactionStrategy
+
+	// compacting holds the paths of the TSM files in planned and running compactions.
+	compactingMu sync.Mutex
+	compacting   map[string]struct{}
+
 	MaxPointsPerBlock int
 
 	// CacheFlushMemorySizeThreshold specifies the minimum size threshold for
@@ -169,19 +201,36 @@ type Engine struct {
 
 	scheduler   *scheduler
 	snapshotter Snapshotter
+
+	// fullCompactionObserver, if set, is called after each full compaction.
+	fullCompactionObserver func()
+
+	// configErr is the error of an invalid encryption key or combination of
+	// options in the config, which is returned when the engine is opened.
+	configErr error
 }
 
 // NewEngine returns a new instance of Engine.
 func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOption) *Engine {
 	fs := NewFileStore(path)
+	fs.WithTiers(config.Tiers)
+	fs.WithRecoveryMode(config.RecoveryMode)
+	encryptionKey, configErr := ParseEncryptionKey(config.EncryptionKeyHex)
+	if encryptionKey != nil && config.MeasurementPartition {
+		configErr = ErrEncryptedMeasurementPartition
+	}
+	fs.WithEncryptionKey(encryptionKey)
 	fs.openLimiter = limiter.NewFixed(config.MaxConcurrentOpens)
 	fs.tsmMMAPWillNeed = config.MADVWillNeed
 
 	cache := NewCache(uint64(config.Cache.MaxMemorySize))
+	cache.SetMaxBucketSize(uint64(config.Cache.MaxMemorySizePerOrg))
 
 	c := NewCompactor()
 	c.Dir = path
 	c.FileStore = fs
+	c.EncryptionKey = encryptionKey
+	c.PartitionMeasurements = config.MeasurementPartition
 	c.RateLimit = limiter.NewRate(
 		int(config.Compaction.Throughput),
 		int(config.Compaction.ThroughputBurst))
@@ -219,6 +268,7 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 		Compactor: c,
 		CompactionPlan: NewDefaultPlanner(fs,
 			time.Duration(config.Compaction.FullWriteColdDuration)),
+		compacting: make(map[string]struct{}),
 
 		CacheFlushMemorySizeThreshold:  uint64(config.Cache.SnapshotMemorySize),
 		CacheFlushWriteColdDuration:    time.Duration(config.Cache.SnapshotWriteColdDuration),
@@ -229,8 +279,15 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 		fullCompactionSemaphore:        influxdb.NopSemaphore,
 		scheduler:                      newScheduler(maxCompactions),
 		snapshotter:                    new(noSnapshotter),
+		configErr:                      configErr,
+		bucketSnapshots:                make(map[[16]byte]struct{}),
+		bucketSnapshotC:                make(chan struct{}, 1),
 	}
 
+	// An unknown strategy is rejected when the configuration is loaded, and
+	// falls back to the default strategy here.
+	e.compactionStrategy, _ = NewCompactionStrategy(config.Compaction.Strategy)
+
 	for _, option := range options {
 		option(e)
 	}
@@ -273,6 +330,15 @@ func (e *Engine) WithCompactionPlanner(planner CompactionPlanner) {
 	e.CompactionPlan = planner
 }
 
+// SetCompactionStrategy sets the strategy that plans the compactions of the
+// engine, starting with the next plan. Running compactions are not affected.
+// A nil strategy plans compactions with the engine's CompactionPlanner.
+func (e *Engine) SetCompactionStrategy(s CompactionStrategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.compactionStrategy = s
+}
+
 // SetDefaultMetricLabels sets the default labels for metrics on the engine.
 // It must be called before the Engine is opened.
 func (e *Engine) SetDefaultMetricLabels(labels prometheus.Labels) {
@@ -328,9 +394,15 @@ func (e *Engine) enableLevelCompactions(wait bool) {
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
 	e.wg = wg
+	done := e.done
 	e.mu.Unlock()
 
 	go func() { defer wg.Done(); e.compact(wg) }()
+
+	if e.FileStore.tiers.enabled() {
+		wg.Add(1)
+		go e.moveTiers(done, wg)
+	}
 }
 
 // disableLevelCompactions will stop level compactions before returning.
@@ -524,6 +596,10 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
+	if e.configErr != nil {
+		return e.configErr
+	}
+
 	defer func() {
 		if err != nil {
 			e.Close()
@@ -542,8 +618,10 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 
 	e.initTrackers()
 
-	if err := os.MkdirAll(e.path, 0777); err != nil {
-		return err
+	for _, dir := range e.FileStore.tiers.unique() {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
 	}
 
 	if err := e.cleanup(); err != nil {
@@ -606,6 +684,23 @@ func (e *Engine) IsIdle() bool {
 	return cacheEmpty && e.compactionTracker.AllActive() == 0 && e.CompactionPlan.FullyCompacted()
 }
 
+// ActiveCompactions returns the number of running level 1, level 2 and full
+// compactions.
+func (e *Engine) ActiveCompactions() (level1, level2, full int) {
+	t := e.compactionTracker
+	return int(t.Active(1)), int(t.Active(2)), int(t.ActiveFull())
+}
+
+// AllActiveCompactions returns the number of running compactions of every
+// level, not counting cache snapshots.
+func (e *Engine) AllActiveCompactions() int {
+	var n uint64
+	for level := 1; level < len(e.compactionTracker.active); level++ {
+		n += e.compactionTracker.Active(level)
+	}
+	return int(n)
+}
+
 // WritePoints saves the set of points in the engine.
 func (e *Engine) WritePoints(points []models.Point) error {
 	collection := tsdb.NewSeriesCollection(points)
@@ -624,6 +719,11 @@ func (e *Engine) WritePoints(points []models.Point) error {
 
 // WriteValues saves the set of values in the engine.
 func (e *Engine) WriteValues(values map[string][]Value) error {
+	// Flush buckets that exceed their share of the cache in the background.
+	if names := e.Cache.BucketsOverLimit(values); len(names) > 0 {
+		e.scheduleBucketSnapshots(names)
+	}
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -634,6 +734,128 @@ func (e *Engine) WriteValues(values map[string][]Value) error {
 	return nil
 }
 
+// CheckFieldTypes returns an EConflict error if the values of a key have a
+// different type than the values already stored for it, in the cache or in
+// a TSM file. Values of different types for the same key within values are
+// left to the cache, which rejects all but the first type.
+func (e *Engine) CheckFieldTypes(values map[string][]Value) error {
+	for k, vs := range values {
+		if len(vs) == 0 {
+			continue
+		}
+		key := []byte(k)
+		existing, ok := e.fieldType(key)
+		if !ok {
+			continue
+		}
+		if typ := valueBlockType(vs[0]); typ != existing {
+			seriesKey, field := SeriesAndFieldFromCompositeKey(key)
+			_, tags := models.ParseKeyBytes(seriesKey)
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg: fmt.Sprintf("field type conflict: measurement '%s' field '%s' has type %s but received %s",
+					tags.Get(models.MeasurementTagKeyBytes), field, blockTypeName(existing), blockTypeName(typ)),
+			}
+		}
+	}
+	return nil
+}
+
+// EstimatedCompressedSize returns the sizes on disk of the TSM files with data
+// of the bucket, plus the size of its data in the cache, which is that of its
+// WAL entries not yet snapshotted. TSM files are shared by buckets, so the
+// estimate is an upper bound of their space used by the bucket.
+func (e *Engine) EstimatedCompressedSize(orgID, bucketID influxdb.ID) int64 {
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	prefix := models.EscapeMeasurement(encoded[:])
+
+	var size int64
+	for _, sz := range e.FileStore.FileSizes(prefix) {
+		size += int64(sz)
+	}
+
+	prefixStr := string(prefix)
+	_ = e.Cache.ApplyEntryFn(func(sfkey string, entry *entry) error {
+		if strings.HasPrefix(sfkey, prefixStr) {
+			size += int64(len(sfkey) + entry.size())
+		}
+		return nil
+	})
+	return size
+}
+
+// FieldType returns the type of the values stored for the series field key,
+// in the cache or in a TSM file, and whether there are any.
+func (e *Engine) FieldType(key []byte) (models.FieldType, bool) {
+	typ, ok := e.fieldType(key)
+	if !ok {
+		return models.Empty, false
+	}
+	switch typ {
+	case BlockInteger:
+		return models.Integer, true
+	case BlockUnsigned:
+		return models.Unsigned, true
+	case BlockBoolean:
+		return models.Boolean, true
+	case BlockString:
+		return models.String, true
+	default:
+		return models.Float, true
+	}
+}
+
+// fieldType returns the block type of the values stored for key.
+func (e *Engine) fieldType(key []byte) (byte, bool) {
+	if typ, err := e.Cache.Type(key); err == nil {
+		switch typ {
+		case models.Float:
+			return BlockFloat64, true
+		case models.Integer:
+			return BlockInteger, true
+		case models.Unsigned:
+			return BlockUnsigned, true
+		case models.Boolean:
+			return BlockBoolean, true
+		case models.String:
+			return BlockString, true
+		}
+	}
+	typ, err := e.FileStore.Type(key)
+	return typ, err == nil
+}
+
+func valueBlockType(v Value) byte {
+	switch v.(type) {
+	case IntegerValue:
+		return BlockInteger
+	case UnsignedValue:
+		return BlockUnsigned
+	case BooleanValue:
+		return BlockBoolean
+	case StringValue:
+		return BlockString
+	default:
+		return BlockFloat64
+	}
+}
+
+func blockTypeName(typ byte) string {
+	switch typ {
+	case BlockFloat64:
+		return "float64"
+	case BlockInteger:
+		return "int64"
+	case BlockUnsigned:
+		return "uint64"
+	case BlockBoolean:
+		return "bool"
+	case BlockString:
+		return "string"
+	}
+	return "unknown"
+}
+
 // ForEachMeasurementName iterates over each measurement name in the engine.
 func (e *Engine) ForEachMeasurementName(fn func(name []byte) error) error {
 	return e.index.ForEachMeasurementName(fn)
@@ -794,6 +1016,12 @@ func (t *compactionTracker) SetOptimiseQueue(length uint64) { t.SetQueue(4, leng
 // SetFullQueue sets the queue depth for Full compactions.
 func (t *compactionTracker) SetFullQueue(length uint64) { t.SetQueue(5, length) }
 
+// SetDefragmentProgress sets the fraction of the TSM files being defragmented
+// that have been rewritten.
+func (t *compactionTracker) SetDefragmentProgress(progress float64) {
+	t.metrics.DefragmentProgress.With(t.labels).Set(progress)
+}
+
 func (e *Engine) WriteSnapshot(ctx context.Context, status CacheStatus) error {
 	start := time.Now()
 	err := e.writeSnapshot(ctx)
@@ -810,6 +1038,30 @@ func (e *Engine) WriteSnapshot(ctx context.Context, status CacheStatus) error {
 	return nil
 }
 
+// FlushCache synchronously writes the contents of the cache to a new TSM file,
+// returning once the file has been added to the file store. If a snapshot is
+// already in progress, FlushCache waits for it to finish and flushes again.
+//
+// FlushCache is intended for tests and administration; the cache is otherwise
+// flushed in the background as it fills up or ages.
+func (e *Engine) FlushCache(ctx context.Context) error {
+	e.logger.Warn("Flushing cache on request, this is intended for testing and administration only",
+		zap.String("path", e.path))
+
+	for {
+		err := e.WriteSnapshot(ctx, CacheStatusFlush)
+		if err != ErrSnapshotInProgress {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
 // WriteSnapshot will snapshot the cache and write a new TSM file with its contents, releasing the snapshot when done.
 func (e *Engine) writeSnapshot(ctx context.Context) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
@@ -884,8 +1136,223 @@ func (e *Engine) writeSnapshotAndCommit(ctx context.Context, log *zap.Logger, sn
 
 		// clear the snapshot from the in-memory cache
 		e.Cache.ClearSnapshot(true)
+
+		// The segments kept by bucket snapshots are removed with these.
+		atomic.StoreUint64(&e.bucketSnapshotBytes, 0)
+		return nil
+	})
+}
+
+// scheduleBucketSnapshots queues the buckets to be flushed by the snapshot
+// goroutine. It does not block, since a signal already pending flushes them.
+func (e *Engine) scheduleBucketSnapshots(names [][16]byte) {
+	e.bucketSnapshotMu.Lock()
+	for _, name := range names {
+		e.bucketSnapshots[name] = struct{}{}
+	}
+	e.bucketSnapshotMu.Unlock()
+
+	select {
+	case e.bucketSnapshotC <- struct{}{}:
+	default:
+	}
+}
+
+// writeBucketSnapshots flushes the buckets queued by scheduleBucketSnapshots.
+func (e *Engine) writeBucketSnapshots(ctx context.Context) {
+	e.bucketSnapshotMu.Lock()
+	names := e.bucketSnapshots
+	e.bucketSnapshots = make(map[[16]byte]struct{})
+	e.bucketSnapshotMu.Unlock()
+
+	for name := range names {
+		err := e.writeBucketSnapshot(ctx, name)
+		if err != nil && err != errCompactionsDisabled && err != ErrSnapshotInProgress {
+			orgID, bucketID := tsdb.DecodeName(name)
+			e.logger.Info("Error writing bucket snapshot",
+				zap.Stringer("org_id", orgID), zap.Stringer("bucket_id", bucketID), zap.Error(err))
+		}
+	}
+}
+
+// writeBucketSnapshot writes the cached values of the bucket to a new TSM file,
+// leaving the values of other buckets in the cache. The closed WAL segments
+// are not removed as they may still hold values of other buckets. Once the
+// bucket snapshots hold as many bytes as a full snapshot would, the
+// snapshot goroutine writes a full snapshot, which removes them.
+func (e *Engine) writeBucketSnapshot(ctx context.Context, name [16]byte) (err error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	orgID, bucketID := tsdb.DecodeName(name)
+	log, logEnd := logger.NewOperation(ctx, e.logger, "Bucket cache snapshot", "tsm1_cache_snapshot_bucket",
+		zap.String("org_id", orgID.String()), zap.String("bucket_id", bucketID.String()))
+	defer logEnd()
+
+	e.mu.Lock()
+	snapshot, err := e.Cache.SnapshotBucket(name)
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			e.Cache.ClearSnapshot(false)
+		}
+	}()
+
+	if snapshot.Size() == 0 {
+		e.Cache.ClearSnapshot(true)
 		return nil
+	}
+	snapshot.Deduplicate()
+
+	newFiles, err := e.Compactor.WriteSnapshot(ctx, snapshot)
+	if err != nil {
+		log.Info("Error writing snapshot from compactor", zap.Error(err))
+		return err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if err := e.FileStore.Replace(nil, newFiles); err != nil {
+		log.Info("Error adding new TSM files from snapshot", zap.Error(err))
+		return err
+	}
+
+	atomic.AddUint64(&e.bucketSnapshotBytes, snapshot.Size())
+	e.Cache.ClearSnapshot(true)
+	return nil
+}
+
+// errCacheWarmupLimit stops WarmCache once the cache has reached its limit.
+var errCacheWarmupLimit = fmt.Errorf("cache warmup limit reached")
+
+// WarmCache loads the values written at or after min from the TSM files into
+// the cache, so that recently written data is held in memory after a restart.
+// Loading stops once the cache would exceed limit bytes or ctx is canceled.
+// Keys that already have values in the cache are skipped, as the cached values
+// may be newer than those on disk. WarmCache returns the number of bytes loaded.
+//
+// Writes are blocked while each key is loaded, so WarmCache is intended to be
+// run in the background.
+func (e *Engine) WarmCache(ctx context.Context, min int64, limit uint64) (uint64, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		loaded uint64
+		last   []byte
+	)
+	err := e.FileStore.WalkKeys(nil, func(key []byte, typ byte) error {
+		// Keys found in more than one file are walked once for each file.
+		if bytes.Equal(key, last) {
+			return nil
+		}
+		last = append(last[:0], key...)
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := e.warmKey(ctx, key, typ, min, limit)
+		loaded += n
+		return err
 	})
+	if err == errCacheWarmupLimit {
+		err = nil
+	}
+	return loaded, err
+}
+
+// warmKey loads the values of the key written at or after min into the cache.
+func (e *Engine) warmKey(ctx context.Context, key []byte, typ byte, min int64, limit uint64) (uint64, error) {
+	e.warmMu.Lock()
+	defer e.warmMu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := e.FileStore.KeyCursor(ctx, key, min, true)
+	defer c.Close()
+
+	var values []Value
+	for {
+		n := len(values)
+		switch typ {
+		case BlockFloat64:
+			var buf []FloatValue
+			vs, err := c.ReadFloatBlock(&buf)
+			if err != nil {
+				return 0, err
+			}
+			for _, v := range vs {
+				values = append(values, v)
+			}
+		case BlockInteger:
+			var buf []IntegerValue
+			vs, err := c.ReadIntegerBlock(&buf)
+			if err != nil {
+				return 0, err
+			}
+			for _, v := range vs {
+				values = append(values, v)
+			}
+		case BlockUnsigned:
+			var buf []UnsignedValue
+			vs, err := c.ReadUnsignedBlock(&buf)
+			if err != nil {
+				return 0, err
+			}
+			for _, v := range vs {
+				values = append(values, v)
+			}
+		case BlockBoolean:
+			var buf []BooleanValue
+			vs, err := c.ReadBooleanBlock(&buf)
+			if err != nil {
+				return 0, err
+			}
+			for _, v := range vs {
+				values = append(values, v)
+			}
+		case BlockString:
+			var buf []StringValue
+			vs, err := c.ReadStringBlock(&buf)
+			if err != nil {
+				return 0, err
+			}
+			for _, v := range vs {
+				values = append(values, v)
+			}
+		default:
+			return 0, fmt.Errorf("unknown block type: %v", typ)
+		}
+
+		if len(values) == n {
+			break
+		}
+		c.Next()
+	}
+
+	// The first block read may hold values written before min.
+	recent := values[:0]
+	for _, v := range values {
+		if v.UnixNano() >= min {
+			recent = append(recent, v)
+		}
+	}
+	values = recent
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	if limit > 0 && e.Cache.Size()+uint64(Values(values).Size())+uint64(len(key)) > limit {
+		return 0, errCacheWarmupLimit
+	}
+	return e.Cache.warm(key, values)
 }
 
 // compactCache checks once per second if the in-memory cache should be
@@ -902,6 +1369,12 @@ func (e *Engine) compactCache() {
 		case <-quit:
 			return
 
+		case <-e.bucketSnapshotC:
+			span, ctx := tracing.StartSpanFromContextWithOperationName(context.Background(), "compact cache bucket")
+			span.LogKV("path", e.path)
+			e.writeBucketSnapshots(ctx)
+			span.Finish()
+
 		case <-t.C:
 			e.Cache.UpdateAge()
 			status := e.ShouldCompactCache(time.Now())
@@ -928,19 +1401,22 @@ type CacheStatus int
 
 // Possible types of Cache status
 const (
-	CacheStatusOkay           CacheStatus = iota // Cache is Okay - do not snapshot.
-	CacheStatusSizeExceeded                      // The cache is large enough to be snapshotted.
-	CacheStatusAgeExceeded                       // The cache is past the age threshold to be snapshotted.
-	CacheStatusColdNoWrites                      // The cache has not been written to for long enough that it should be snapshotted.
-	CacheStatusRetention                         // The cache was snapshotted before running retention.
-	CacheStatusFullCompaction                    // The cache was snapshotted as part of a full compaction.
-	CacheStatusBackup                            // The cache was snapshotted before running backup.
+	CacheStatusOkay            CacheStatus = iota // Cache is Okay - do not snapshot.
+	CacheStatusSizeExceeded                       // The cache is large enough to be snapshotted.
+	CacheStatusAgeExceeded                        // The cache is past the age threshold to be snapshotted.
+	CacheStatusColdNoWrites                       // The cache has not been written to for long enough that it should be snapshotted.
+	CacheStatusRetention                          // The cache was snapshotted before running retention.
+	CacheStatusFullCompaction                     // The cache was snapshotted as part of a full compaction.
+	CacheStatusBackup                             // The cache was snapshotted before running backup.
+	CacheStatusFlush                              // The cache was flushed on request.
+	CacheStatusWALSizeExceeded                    // The WAL exceeded its maximum disk size.
 )
 
 // ShouldCompactCache returns a status indicating if the Cache should be
 // snapshotted. There are three situations when the cache should be snapshotted:
 //
-// - the Cache size is over its flush size threshold;
+// - the Cache size, or the size of the bucket snapshots whose WAL
+//   segments are kept, is over its flush size threshold;
 // - the Cache has not been snapshotted for longer than its flush time threshold; or
 // - the Cache has not been written since the write cold threshold.
 //
@@ -950,8 +1426,19 @@ func (e *Engine) ShouldCompactCache(t time.Time) CacheStatus {
 		return 0
 	}
 
+	// Bucket snapshots have left enough flushed values in the WAL.
+	if atomic.LoadUint64(&e.bucketSnapshotBytes) > e.CacheFlushMemorySizeThreshold {
+		return CacheStatusSizeExceeded
+	}
+
+	// Values loaded by WarmCache or kept by a snapshot are already on disk.
+	warm := e.Cache.WarmSize()
+	if sz == warm {
+		return CacheStatusOkay
+	}
+
 	// Cache is now big enough to snapshot.
-	if sz > e.CacheFlushMemorySizeThreshold {
+	if sz-warm > e.CacheFlushMemorySizeThreshold {
 		return CacheStatusSizeExceeded
 	}
 
@@ -996,17 +1483,12 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 			span, ctx := tracing.StartSpanFromContext(context.Background())
 
 			// Find our compaction plans
-			level1Groups := e.CompactionPlan.PlanLevel(1)
-			level2Groups := e.CompactionPlan.PlanLevel(2)
-			level3Groups := e.CompactionPlan.PlanLevel(3)
-			level4Groups := e.CompactionPlan.Plan(e.lastModified())
-			e.compactionTracker.SetOptimiseQueue(uint64(len(level4Groups)))
-
-			// If no full compactions are need, see if an optimize is needed
-			if len(level4Groups) == 0 {
-				level4Groups = e.CompactionPlan.PlanOptimize()
-				e.compactionTracker.SetOptimiseQueue(uint64(len(level4Groups)))
+			var groups [5][]CompactionGroup
+			for _, plan := range e.planCompactions() {
+				groups[plan.Level] = append(groups[plan.Level], plan.Group)
 			}
+			level1Groups, level2Groups, level3Groups, level4Groups := groups[1], groups[2], groups[3], groups[4]
+			e.compactionTracker.SetOptimiseQueue(uint64(len(level4Groups)))
 
 			// Update the level plan queue stats
 			e.compactionTracker.SetQueue(1, uint64(len(level1Groups)))
@@ -1044,10 +1526,10 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 			}
 
 			// Release all the plans we didn't start.
-			e.CompactionPlan.Release(level1Groups)
-			e.CompactionPlan.Release(level2Groups)
-			e.CompactionPlan.Release(level3Groups)
-			e.CompactionPlan.Release(level4Groups)
+			e.releaseCompactions(level1Groups)
+			e.releaseCompactions(level2Groups)
+			e.releaseCompactions(level3Groups)
+			e.releaseCompactions(level4Groups)
 
 			if runnable {
 				span.Finish()
@@ -1056,6 +1538,52 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 	}
 }
 
+// planCompactions returns the compactions planned by the compaction strategy
+// whose files are not in other compactions. Their files are held until they are
+// released.
+func (e *Engine) planCompactions() []CompactionPlan {
+	e.mu.RLock()
+	strategy := e.compactionStrategy
+	e.mu.RUnlock()
+	if strategy == nil {
+		strategy = &DefaultCompactionStrategy{Planner: e.CompactionPlan, LastWrite: e.lastModified}
+	}
+
+	files := e.FileStore.Files()
+
+	e.compactingMu.Lock()
+	defer e.compactingMu.Unlock()
+
+	var plans []CompactionPlan
+PLANS:
+	for _, plan := range strategy.PlanCompactions(files) {
+		if plan.Level < 1 || plan.Level > 4 || len(plan.Group) == 0 {
+			continue
+		}
+		for _, f := range plan.Group {
+			if _, ok := e.compacting[f]; ok {
+				continue PLANS
+			}
+		}
+		for _, f := range plan.Group {
+			e.compacting[f] = struct{}{}
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// releaseCompactions releases the files of compactions returned by planCompactions.
+func (e *Engine) releaseCompactions(groups []CompactionGroup) {
+	e.compactingMu.Lock()
+	defer e.compactingMu.Unlock()
+	for _, g := range groups {
+		for _, f := range g {
+			delete(e.compacting, f)
+		}
+	}
+}
+
 // compactHiPriorityLevel kicks off compactions using the high priority policy. It returns
 // true if the compaction was started
 func (e *Engine) compactHiPriorityLevel(ctx context.Context, grp CompactionGroup, level compactionLevel, fast bool, wg *sync.WaitGroup) bool {
@@ -1075,7 +1603,7 @@ func (e *Engine) compactHiPriorityLevel(ctx context.Context, grp CompactionGroup
 			defer e.compactionLimiter.Release()
 			s.Apply(ctx)
 			// Release the files in the compaction plan
-			e.CompactionPlan.Release([]CompactionGroup{s.group})
+			e.releaseCompactions([]CompactionGroup{s.group})
 		}()
 		return true
 	}
@@ -1102,7 +1630,7 @@ func (e *Engine) compactLoPriorityLevel(ctx context.Context, grp CompactionGroup
 			defer e.compactionLimiter.Release()
 			s.Apply(ctx)
 			// Release the files in the compaction plan
-			e.CompactionPlan.Release([]CompactionGroup{s.group})
+			e.releaseCompactions([]CompactionGroup{s.group})
 		}()
 		return true
 	}
@@ -1155,8 +1683,12 @@ func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.
 			atomic.StoreInt64(&e.lastFullCompactionDuration, int64(time.Since(now)))
 
 			// Release the files in the compaction plan
-			e.CompactionPlan.Release([]CompactionGroup{s.group})
+			e.releaseCompactions([]CompactionGroup{s.group})
 			cancel()
+
+			if e.fullCompactionObserver != nil {
+				e.fullCompactionObserver()
+			}
 		}()
 		return true
 	}
@@ -1341,14 +1873,21 @@ func (e *Engine) cleanup() error {
 }
 
 func (e *Engine) cleanupTempTSMFiles() error {
-	files, err := filepath.Glob(filepath.Join(e.path, fmt.Sprintf("*.%s", CompactionTempExtension)))
-	if err != nil {
-		return fmt.Errorf("error getting compaction temp files: %s", err.Error())
-	}
+	for _, dir := range e.FileStore.tiers.unique() {
+		for _, pattern := range []string{
+			filepath.Join(dir, fmt.Sprintf("*.%s", CompactionTempExtension)),
+			filepath.Join(dir, "*", "*", fmt.Sprintf("*.%s", CompactionTempExtension)),
+		} {
+			files, err := filepath.Glob(pattern)
+			if err != nil {
+				return fmt.Errorf("error getting compaction temp files: %s", err.Error())
+			}
 
-	for _, f := range files {
-		if err := os.Remove(f); err != nil {
-			return fmt.Errorf("error removing temp compaction files: %v", err)
+			for _, f := range files {
+				if err := os.Remove(f); err != nil {
+					return fmt.Errorf("error removing temp compaction files: %v", err)
+				}
+			}
 		}
 	}
 	return nil
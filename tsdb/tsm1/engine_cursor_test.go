@@ -2,12 +2,17 @@ package tsm1_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/opentracing/opentracing-go"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestEngine_CursorIterator_Stats(t *testing.T) {
@@ -115,3 +120,88 @@ func TestEngine_CursorIterator_Stats(t *testing.T) {
 		t.Fatalf("expected %v, got %v", exp, got)
 	}
 }
+
+// spanRecorder is an in-memory exporter of the spans of a provider.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*export.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(_ context.Context, sd *export.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, sd)
+}
+
+func TestEngine_CursorIterator_Spans(t *testing.T) {
+	recorder := &spanRecorder{}
+	p, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(recorder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracing.NewOpenTelemetryTracer(p))
+	defer opentracing.SetGlobalTracer(old)
+
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	points := []models.Point{
+		models.MustNewPoint("cpu",
+			models.Tags{
+				{Key: []byte("a"), Value: []byte("b")},
+			},
+			models.Fields{"value": 1.5},
+			time.Now().UTC(),
+		),
+	}
+	if err := e.index.CreateSeriesListIfNotExists(tsdb.NewSeriesCollection(points)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	e.MustWriteSnapshot()
+
+	span, ctx := tracing.StartSpanFromContextWithOperationName(context.Background(), "query")
+	cursorIterator, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := cursorIterator.Next(ctx, &cursors.CursorRequest{
+		Name:      []byte("cpu"),
+		Tags:      []models.Tag{{Key: []byte("a"), Value: []byte("b")}},
+		Field:     "value",
+		EndTime:   time.Now().UTC().UnixNano(),
+		Ascending: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if cur == nil {
+		t.Fatal("expected cursor to be present")
+	}
+	cur.Close()
+	span.Finish()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	spans := make(map[string]*export.SpanData)
+	for _, sd := range recorder.spans {
+		spans[sd.Name] = sd
+	}
+	query := spans["query"]
+	if query == nil {
+		t.Fatal("no query span")
+	}
+	for _, name := range []string{"cache values", "TSM key cursor"} {
+		sd := spans[name]
+		if sd == nil {
+			t.Errorf("no %q span", name)
+		} else if sd.ParentSpanID != query.SpanContext.SpanID || sd.SpanContext.TraceID != query.SpanContext.TraceID {
+			t.Errorf("%q span is not a child of the query span", name)
+		}
+	}
+}
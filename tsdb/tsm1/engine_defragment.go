@@ -0,0 +1,155 @@
+package tsm1
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/logger"
+	"go.uber.org/zap"
+)
+
+// defragmentFile is a TSM file to be rewritten by Defragment.
+type defragmentFile struct {
+	path       string
+	generation int
+	size       uint64
+}
+
+// Defragment rewrites the TSM files holding keys with the prefix into fully
+// compacted files, dropping any tombstoned data. Unlike the planned
+// compactions, which leave fully compacted generations alone, every file from
+// the first to the last holding keys with the prefix is rewritten, across all
+// levels.
+//
+// The files are rewritten a group of generations at a time, so that the data
+// of each group is replaced as it is written. Defragment waits for any
+// compaction of the files to finish before rewriting them.
+func (e *Engine) Defragment(ctx context.Context, prefix []byte) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	log, logEnd := logger.NewOperation(ctx, e.logger, "TSM defragmentation", "tsm1_defragment")
+	defer logEnd()
+
+	files, err := e.defragmentFiles(prefix, 0)
+	if err != nil {
+		return err
+	}
+	var total, done uint64
+	for _, f := range files {
+		total += f.size
+	}
+	log.Info("Beginning defragmentation", zap.Int("tsm1_files_n", len(files)), zap.Uint64("bytes", total))
+
+	e.compactionTracker.SetDefragmentProgress(0)
+	defer e.compactionTracker.SetDefragmentProgress(1)
+
+	// Generations up to generation have been rewritten.
+	var generation int
+	for {
+		files, err := e.defragmentFiles(prefix, generation)
+		if err != nil {
+			return err
+		} else if len(files) == 0 {
+			break
+		}
+
+		group := defragmentGroup(files)
+		paths := make([]string, 0, len(group))
+		for _, f := range group {
+			paths = append(paths, f.path)
+		}
+
+		newFiles, err := e.Compactor.CompactFull(paths)
+		switch err.(type) {
+		case nil:
+		case errCompactionInProgress, errCompactionAborted:
+			// The files are being compacted, or were replaced by a compaction
+			// since they were planned. Try again once it is done.
+			log.Info("Waiting for compaction of TSM files", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		default:
+			return err
+		}
+
+		if err := e.FileStore.ReplaceWithCallback(paths, newFiles, nil); err != nil {
+			for _, file := range newFiles {
+				if err := os.Remove(file); err != nil {
+					log.Error("Unable to remove file", zap.String("path", file), zap.Error(err))
+				}
+			}
+			return err
+		}
+
+		for _, f := range group {
+			done += f.size
+		}
+		if done > total {
+			// Compactions since Defragment began added to the files.
+			total = done
+		}
+		e.compactionTracker.SetDefragmentProgress(float64(done) / float64(total))
+		log.Info("Defragmented TSM files", zap.Int("tsm1_files_n", len(group)), zap.Strings("tsm1_files", newFiles))
+
+		generation = group[len(group)-1].generation
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Finished defragmentation", zap.Uint64("bytes", done))
+	return nil
+}
+
+// defragmentFiles returns the TSM files of the generations after generation,
+// from the first to the last file holding keys with the prefix, in order of
+// generation.
+func (e *Engine) defragmentFiles(prefix []byte, generation int) ([]defragmentFile, error) {
+	var (
+		files       []defragmentFile
+		first, last = -1, -1
+		err         error
+	)
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		var gen int
+		if gen, _, err = e.FileStore.ParseFileName(f.Path()); err != nil {
+			return false
+		} else if gen <= generation {
+			return true
+		}
+
+		if f.OverlapsKeyPrefixRange(prefix, prefix) {
+			if first < 0 {
+				first = len(files)
+			}
+			last = len(files)
+		}
+		files = append(files, defragmentFile{path: f.Path(), generation: gen, size: uint64(f.Size())})
+		return true
+	})
+	if err != nil || first < 0 {
+		return nil, err
+	}
+	return files[first : last+1], nil
+}
+
+// defragmentGroup returns the files of the first generations of files to be
+// rewritten together, of about maxTSMFileSize bytes. Generations are never
+// split, so the rewritten files take the place of the generations in order.
+func defragmentGroup(files []defragmentFile) []defragmentFile {
+	var size uint64
+	for i, f := range files {
+		if i > 0 && f.generation != files[i-1].generation && size >= uint64(maxTSMFileSize) {
+			return files[:i]
+		}
+		size += f.size
+	}
+	return files
+}
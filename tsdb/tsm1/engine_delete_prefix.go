@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/tsdb"
@@ -35,6 +36,11 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 	// now we know that the series file or index won't be closed out from underneath
 	// of us.
 
+	// Ensure that values read from TSM files by a concurrent cache warmup are not
+	// added to the cache once they have been deleted.
+	e.warmMu.RLock()
+	defer e.warmMu.RUnlock()
+
 	// Ensure that the index does not compact away the measurement or series we're
 	// going to delete before we're done with them.
 	span, _ = tracing.StartSpanFromContextWithOperationName(rootCtx, "disable index compactions")
@@ -272,11 +278,11 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 			isLastBatch := i+batchSize > len(possiblyDeadKeysSlice)
 			batch, ids = batch[:0], ids[:0]
 
-			for j := 0; (i*batchSize)+j < len(possiblyDeadKeysSlice) && j < batchSize; j++ {
+			for j := 0; i+j < len(possiblyDeadKeysSlice) && j < batchSize; j++ {
 				var item tsi1.DropSeriesItem
 
 				// TODO(jeff): ugh reduce copies here
-				key := possiblyDeadKeysSlice[(i*batchSize)+j]
+				key := possiblyDeadKeysSlice[i+j]
 				item.Key = []byte(key)
 				item.Key, _ = SeriesAndFieldFromCompositeKey(item.Key)
 
@@ -301,3 +307,20 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 
 	return nil
 }
+
+// ClearCache removes all the values of the bucket from the cache, including
+// those written while its data was being deleted from the TSM files. It is
+// safe to call while values are written to the cache.
+func (e *Engine) ClearCache(ctx context.Context, orgID, bucketID influxdb.ID) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	// Values read from TSM files by a concurrent cache warmup must not be
+	// added back to the cache.
+	e.warmMu.RLock()
+	defer e.warmMu.RUnlock()
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := models.EscapeMeasurement(encoded[:])
+	e.Cache.DeleteBucketRange(ctx, string(name), math.MinInt64, math.MaxInt64, nil)
+}
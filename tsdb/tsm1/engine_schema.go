@@ -5,11 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/hll"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxdb/tsdb/seriesfile"
@@ -61,7 +63,7 @@ func (e *Engine) tagValuesNoPredicate(ctx context.Context, orgBucket, tagKeyByte
 			return false
 		default:
 		}
-		if f.OverlapsTimeRange(start, end) && f.OverlapsKeyPrefixRange(prefix, prefix) {
+		if f.OverlapsTimeRange(start, end) && f.OverlapsTagKeyRange(orgBucket, nil, tagKeyBytes) {
 			// TODO(sgc): create f.TimeRangeIterator(minKey, maxKey, start, end)
 			iter := f.TimeRangeIterator(prefix, start, end)
 			for i := 0; iter.Next(); i++ {
@@ -118,7 +120,7 @@ func (e *Engine) tagValuesNoPredicate(ctx context.Context, orgBucket, tagKeyByte
 		stats.ScannedValues += entry.values.Len()
 		stats.ScannedBytes += entry.values.Len() * 8 // sizeof timestamp
 
-		if entry.values.Contains(start, end) {
+		if entry.contains(start, end) {
 			tsmValues[string(curVal)] = struct{}{}
 		}
 		return nil
@@ -168,7 +170,7 @@ func (e *Engine) tagValuesPredicate(ctx context.Context, orgBucket, tagKeyBytes
 			return false
 		default:
 		}
-		if f.OverlapsTimeRange(start, end) && f.OverlapsKeyPrefixRange(prefix, prefix) {
+		if f.OverlapsTimeRange(start, end) && f.OverlapsTagKeyRange(orgBucket, nil, tagKeyBytes) {
 			f.Ref()
 			files = append(files, f)
 			iters = append(iters, f.TimeRangeIterator(prefix, start, end))
@@ -248,6 +250,94 @@ func (e *Engine) tagValuesPredicate(ctx context.Context, orgBucket, tagKeyBytes
 	return cursors.NewStringSliceIteratorWithStats(vals, stats), err
 }
 
+// TagValueCounts returns the number of series of the measurement in the given
+// bucket with each value of tagKey, counting only the series with data within
+// the time range (start, end]. The measurement is the value of the
+// models.MeasurementTagKey tag of the series.
+//
+// The TSM files and the cache are each scanned once. If the context is
+// canceled before TagValueCounts has finished processing, a non-nil error
+// will be returned along with a partial result of the already scanned series.
+func (e *Engine) TagValueCounts(ctx context.Context, orgID, bucketID influxdb.ID, measurement, tagKey string, start, end int64) (map[string]int64, error) {
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	orgBucket := encoded[:]
+	measurementBytes, tagKeyBytes := []byte(measurement), []byte(tagKey)
+
+	// A series has a composite key per field, possibly in several files, and
+	// is counted once.
+	counted := make(map[string]struct{})
+	counts := make(map[string]int64)
+	var tags models.Tags
+
+	// TODO(edd): we need to clean up how we're encoding the prefix so that we
+	// don't have to remember to get it right everywhere we need to touch TSM data.
+	prefix := models.EscapeMeasurement(orgBucket)
+
+	// count reports whether the series key has yet to be counted, and if so,
+	// returns the value of tagKey it should be counted for.
+	count := func(key []byte) ([]byte, bool) {
+		if _, ok := counted[string(key)]; ok {
+			return nil, false
+		}
+		tags = models.ParseTagsWithTags(key, tags[:0])
+		if !bytes.Equal(tags.Get(models.MeasurementTagKeyBytes), measurementBytes) {
+			return nil, false
+		}
+		val := tags.Get(tagKeyBytes)
+		return val, len(val) > 0
+	}
+
+	var canceled bool
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		// Check the context before accessing each tsm file
+		select {
+		case <-ctx.Done():
+			canceled = true
+			return false
+		default:
+		}
+		if f.OverlapsTimeRange(start, end) && f.OverlapsTagKeyRange(orgBucket, measurementBytes, tagKeyBytes) {
+			iter := f.TimeRangeIterator(prefix, start, end)
+			for iter.Next() {
+				sfkey := iter.Key()
+				if !bytes.HasPrefix(sfkey, prefix) {
+					// end of org+bucket
+					break
+				}
+
+				key, _ := SeriesAndFieldFromCompositeKey(sfkey)
+				if val, ok := count(key); ok && iter.HasData() {
+					counted[string(key)] = struct{}{}
+					counts[string(val)]++
+				}
+			}
+		}
+		return true
+	})
+
+	if canceled {
+		return counts, ctx.Err()
+	}
+
+	// With performance in mind, we explicitly do not check the context
+	// while scanning the entries in the cache.
+	prefixStr := string(prefix)
+	_ = e.Cache.ApplyEntryFn(func(sfkey string, entry *entry) error {
+		if !strings.HasPrefix(sfkey, prefixStr) {
+			return nil
+		}
+
+		key, _ := SeriesAndFieldFromCompositeKey([]byte(sfkey))
+		if val, ok := count(key); ok && entry.contains(start, end) {
+			counted[string(key)] = struct{}{}
+			counts[string(val)]++
+		}
+		return nil
+	})
+
+	return counts, nil
+}
+
 func (e *Engine) findCandidateKeys(ctx context.Context, orgBucket []byte, predicate influxql.Expr) ([][]byte, error) {
 	// determine candidate series keys
 	sitr, err := e.index.MeasurementSeriesByExprIterator(orgBucket, predicate)
@@ -372,7 +462,7 @@ func (e *Engine) tagKeysNoPredicate(ctx context.Context, orgBucket []byte, start
 		stats.ScannedValues += entry.values.Len()
 		stats.ScannedBytes += entry.values.Len() * 8 // sizeof timestamp
 
-		if entry.values.Contains(start, end) {
+		if entry.contains(start, end) {
 			keyset.UnionKeys(tags)
 		}
 		return nil
@@ -403,9 +493,7 @@ func (e *Engine) tagKeysPredicate(ctx context.Context, orgBucket []byte, start,
 	}()
 	var iters []*TimeRangeIterator
 
-	// TODO(edd): we need to clean up how we're encoding the prefix so that we
-	// don't have to remember to get it right everywhere we need to touch TSM data.
-	prefix := models.EscapeMeasurement(orgBucket)
+	prefix := BucketPrefix(orgBucket)
 	var canceled bool
 
 	e.FileStore.ForEachFile(func(f TSMFile) bool {
@@ -485,6 +573,75 @@ func (e *Engine) tagKeysPredicate(ctx context.Context, orgBucket []byte, start,
 	return cursors.NewStringSliceIteratorWithStats(keyset.Keys(), stats), err
 }
 
+// ApproxMeasurementCountError is the relative standard error of the estimates
+// returned by ApproxMeasurementCount.
+var ApproxMeasurementCountError = 1.04 / math.Sqrt(float64(uint64(1)<<hll.DefaultPrecision))
+
+// ApproxMeasurementCount returns an estimate of the number of distinct
+// measurements in the given bucket. The estimate has a relative standard
+// error of ApproxMeasurementCountError.
+//
+// The measurement sketches held in the key stats of each TSM file are merged
+// rather than reading the files' indexes. Only the index of files written
+// without key stats is scanned. Measurements whose data has been deleted but
+// not yet compacted away are still counted.
+func (e *Engine) ApproxMeasurementCount(ctx context.Context, orgID, bucketID influxdb.ID) (int64, error) {
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	orgBucket := encoded[:]
+
+	prefix := BucketPrefix(orgBucket)
+
+	sketch := hll.NewDefaultPlus()
+	var tags models.Tags
+	var err error
+
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		if !f.OverlapsKeyPrefixRange(prefix, prefix) {
+			return true
+		}
+
+		if stats := f.KeyStats(); stats != nil {
+			if stat := stats[string(orgBucket)]; stat != nil {
+				err = sketch.Merge(stat.MeasurementSketch)
+			}
+			return err == nil
+		}
+
+		iter := f.TimeRangeIterator(prefix, math.MinInt64, math.MaxInt64)
+		for iter.Next() {
+			sfkey := iter.Key()
+			if !bytes.HasPrefix(sfkey, prefix) {
+				// end of org+bucket
+				break
+			}
+
+			key, _ := SeriesAndFieldFromCompositeKey(sfkey)
+			tags = models.ParseTagsWithTags(key, tags[:0])
+			sketch.Add(tags.Get(models.MeasurementTagKeyBytes))
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_ = e.Cache.ApplyEntryFn(func(sfkey string, entry *entry) error {
+		if !strings.HasPrefix(sfkey, string(prefix)) {
+			return nil
+		}
+
+		key, _ := SeriesAndFieldFromCompositeKey([]byte(sfkey))
+		tags = models.ParseTagsWithTags(key, tags[:0])
+		sketch.Add(tags.Get(models.MeasurementTagKeyBytes))
+		return nil
+	})
+
+	return int64(sketch.Count()), nil
+}
+
 func statsFromIters(stats cursors.CursorStats, iters []*TimeRangeIterator) cursors.CursorStats {
 	for _, iter := range iters {
 		stats.Add(iter.Stats())
@@ -1,14 +1,20 @@
 package tsm1_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/influxdata/influxql"
@@ -389,6 +395,76 @@ memB,host=EB,os=macOS value=1.3 201`)
 	}
 }
 
+// TestEngine_TagValues_CacheOnly verifies that the tag values of a measurement
+// that has only been written to the cache are returned, and are the same once
+// the cache is written to TSM files.
+func TestEngine_TagValues_CacheOnly(t *testing.T) {
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x5020), influxdb.ID(0x5100)
+	e.MustWritePointsString(org, bucket, `
+cpu,host=A,os=linux value=1.1 101
+cpu,host=B,os=linux value=1.2 102
+cpu,host=C,os=macOS value=1.3 103
+mem,host=D,os=macOS value=1.4 104`)
+
+	if got := e.FileStore.Count(); got != 0 {
+		t.Fatalf("got %d TSM files, exp 0", got)
+	}
+
+	tagValues := func(key, expr string) []string {
+		t.Helper()
+		// _m refers to the measurement, which is stored in the models.MeasurementTagKey tag.
+		pred := influxql.RewriteExpr(influxql.MustParseExpr(expr), func(expr influxql.Expr) influxql.Expr {
+			if r, ok := expr.(*influxql.VarRef); ok && r.Val == "_m" {
+				r.Val = models.MeasurementTagKey
+			}
+			return expr
+		})
+		iter, err := e.TagValues(context.Background(), org, bucket, key, 0, 1000, pred)
+		if err != nil {
+			t.Fatalf("TagValues: error %v", err)
+		}
+		return cursors.StringIteratorToSlice(iter)
+	}
+
+	tests := []struct {
+		key, expr string
+		exp       []string
+	}{
+		{key: "host", expr: "_m = 'cpu'", exp: []string{"A", "B", "C"}},
+		{key: "os", expr: "_m = 'cpu'", exp: []string{"linux", "macOS"}},
+		{key: "host", expr: "_m = 'mem'", exp: []string{"D"}},
+		{key: "host", expr: "_m = 'cpu' AND os = 'macOS'", exp: []string{"C"}},
+	}
+
+	cached := make([][]string, len(tests))
+	for i, tc := range tests {
+		cached[i] = tagValues(tc.key, tc.expr)
+		if !cmp.Equal(cached[i], tc.exp) {
+			t.Errorf("unexpected TagValues for %s in cache: -got/+exp\n%v", tc.expr, cmp.Diff(cached[i], tc.exp))
+		}
+	}
+
+	e.MustWriteSnapshot()
+	if got := e.FileStore.Count(); got != 1 {
+		t.Fatalf("got %d TSM files, exp 1", got)
+	}
+
+	for i, tc := range tests {
+		if got := tagValues(tc.key, tc.expr); !cmp.Equal(got, cached[i]) {
+			t.Errorf("unexpected TagValues for %s in TSM: -got/+exp\n%v", tc.expr, cmp.Diff(got, cached[i]))
+		}
+	}
+}
+
 func TestEngine_TagKeys(t *testing.T) {
 	e, err := NewEngine(tsm1.NewConfig(), t)
 	if err != nil {
@@ -648,6 +724,60 @@ mem,mem1=v,mem2=v        f=1 201`)
 	}
 }
 
+func TestEngine_ApproxMeasurementCount(t *testing.T) {
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var (
+		org    influxdb.ID = 0x6000
+		bucket influxdb.ID = 0x6100
+		other  influxdb.ID = 0x6200
+	)
+
+	write := func(bucket influxdb.ID, min, max int) {
+		t.Helper()
+		var buf bytes.Buffer
+		for i := min; i < max; i++ {
+			fmt.Fprintf(&buf, "m%d,host=a value=1 %d\n", i, i)
+		}
+		e.MustWritePointsString(org, bucket, buf.String())
+	}
+
+	// Overlapping measurements in two TSM files and the cache.
+	write(bucket, 0, 600)
+	write(other, 0, 500)
+	e.MustWriteSnapshot()
+	write(bucket, 300, 900)
+	e.MustWriteSnapshot()
+	write(bucket, 800, 1000)
+
+	iter, err := e.TagValues(context.Background(), org, bucket, models.MeasurementTagKey, math.MinInt64, math.MaxInt64, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exact int64
+	for iter.Next() {
+		exact++
+	}
+	if exact != 1000 {
+		t.Fatalf("got %d measurements, exp 1000", exact)
+	}
+
+	got, err := e.ApproxMeasurementCount(context.Background(), org, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := math.Abs(float64(got-exact)) / float64(exact); diff > 0.1 {
+		t.Fatalf("got estimate %d, exp within 10%% of %d", got, exact)
+	}
+}
+
 func TestValidateTagPredicate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -683,3 +813,121 @@ func TestValidateTagPredicate(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkMeasurementNames_1000Files benchmarks listing the measurement
+// names of a bucket, the values of models.MeasurementTagKey, from 1000 TSM
+// files with 100 measurements of 10 series each and an empty cache.
+func BenchmarkMeasurementNames_1000Files(b *testing.B) {
+	const (
+		files        = 1000
+		measurements = 100
+		series       = 10
+	)
+
+	e, err := NewEngine(tsm1.NewConfig(), b)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x5020), influxdb.ID(0x5100)
+	name := tsdb.EncodeName(org, bucket)
+	dir := filepath.Join(e.root, "data")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		b.Fatal(err)
+	}
+
+	keys := make([][]byte, 0, measurements*series)
+	for i := 0; i < files; i++ {
+		keys = keys[:0]
+		for m := 0; m < measurements; m++ {
+			for s := 0; s < series; s++ {
+				tags := models.NewTags(map[string]string{
+					models.MeasurementTagKey: fmt.Sprintf("m%d", m),
+					models.FieldKeyTagKey:    "value",
+					"host":                   fmt.Sprintf("host%d", i*series+s),
+				})
+				keys = append(keys, tsm1.SeriesFieldKeyBytes(string(models.MakeKey(name[:], tags)), "value"))
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+		f, err := os.Create(filepath.Join(dir, tsm1.DefaultFormatFileName(i+1, 1)+".tsm"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		w, err := tsm1.NewTSMWriter(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, key := range keys {
+			if err := w.Write(key, []tsm1.Value{tsm1.NewValue(int64(i), 1.0)}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.WriteIndex(); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := e.Open(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	if got := e.FileStore.Count(); got != files {
+		b.Fatalf("got %d TSM files, exp %d", got, files)
+	}
+
+	benchmarkMeasurementNames(b, e, org, bucket, measurements)
+}
+
+// BenchmarkMeasurementNames_CacheOnly benchmarks listing the measurement
+// names of a bucket with 100 measurements of 1000 series each, all in the
+// cache.
+func BenchmarkMeasurementNames_CacheOnly(b *testing.B) {
+	const (
+		measurements = 100
+		series       = 1000
+	)
+
+	config := tsm1.NewConfig()
+	config.Cache.SnapshotMemorySize = toml.Size(256 * 1024 * 1024)
+	e, err := NewEngine(config, b)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x5020), influxdb.ID(0x5100)
+	var buf bytes.Buffer
+	for m := 0; m < measurements; m++ {
+		for s := 0; s < series; s++ {
+			fmt.Fprintf(&buf, "m%d,host=host%d value=1 %d\n", m, s, s)
+		}
+	}
+	e.MustWritePointsString(org, bucket, buf.String())
+	if got := e.FileStore.Count(); got != 0 {
+		b.Fatalf("got %d TSM files, exp 0", got)
+	}
+
+	benchmarkMeasurementNames(b, e, org, bucket, measurements)
+}
+
+func benchmarkMeasurementNames(b *testing.B, e *Engine, org, bucket influxdb.ID, exp int) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter, err := e.TagValues(context.Background(), org, bucket, models.MeasurementTagKey, math.MinInt64, math.MaxInt64, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if got := len(cursors.StringIteratorToSlice(iter)); got != exp {
+			b.Fatalf("got %d measurements, exp %d", got, exp)
+		}
+	}
+}
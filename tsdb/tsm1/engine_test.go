@@ -1,21 +1,26 @@
 package tsm1_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/seriesfile"
@@ -175,6 +180,844 @@ func TestEngine_ShouldCompactCache(t *testing.T) {
 	}
 }
 
+func TestEngine_MaxMemorySizePerOrg(t *testing.T) {
+	const limit = 512
+
+	config := tsm1.NewConfig()
+	config.Cache.MaxMemorySizePerOrg = limit
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mock the planner so compactions don't run during the test
+	e.CompactionPlan = &mockPlanner{}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatalf("failed to open tsm1 engine: %s", err.Error())
+	}
+	defer e.Close()
+
+	orgA, orgB := influxdb.ID(0x1100000000000001), influxdb.ID(0x1200000000000002)
+	bucket1, bucket2 := influxdb.ID(0x1300000000000003), influxdb.ID(0x1400000000000004)
+
+	// Other buckets, of org B and of org A itself, keep their values.
+	others := []struct {
+		org, bucket influxdb.ID
+		size        uint64
+	}{
+		{org: orgB, bucket: bucket1},
+		{org: orgA, bucket: bucket2},
+	}
+	for i := range others {
+		o := &others[i]
+		e.MustWritePointsString(o.org, o.bucket, "cpu,host=b value=1 1")
+		if o.size = e.Cache.BucketSize(o.org, o.bucket); o.size == 0 {
+			t.Fatalf("expected bucket %s of org %s to have values in the cache", o.bucket, o.org)
+		}
+	}
+
+	// Fill bucket 1 of org A past the limit. Its values are written to a TSM
+	// file in the background.
+	for i := 0; i < 100; i++ {
+		e.MustWritePointsString(orgA, bucket1, fmt.Sprintf("cpu,host=a value=%d %d", i, i))
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for e.FileStore.Count() == 0 || e.Cache.BucketSize(orgA, bucket1) > limit {
+		if time.Now().After(deadline) {
+			t.Fatalf("bucket uses %d bytes of the cache in %d TSM files, exp at most %d in a TSM file",
+				e.Cache.BucketSize(orgA, bucket1), e.FileStore.Count(), limit)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	keys := make(map[[16]byte]int)
+	for _, k := range e.Cache.Keys() {
+		org, bucket := tsdb.DecodeNameSlice(k)
+		keys[tsdb.EncodeName(org, bucket)]++
+	}
+	for _, o := range others {
+		if got := e.Cache.BucketSize(o.org, o.bucket); got != o.size {
+			t.Fatalf("bucket %s of org %s uses %d bytes of the cache, exp %d", o.bucket, o.org, got, o.size)
+		}
+		if got := keys[tsdb.EncodeName(o.org, o.bucket)]; got != 1 {
+			t.Fatalf("got %d cache keys for bucket %s of org %s, exp 1", got, o.bucket, o.org)
+		}
+	}
+
+	// The WAL segments of the flushed values are kept until a full snapshot,
+	// which is due once bucket snapshots hold more than the flush threshold.
+	e.CacheFlushMemorySizeThreshold = limit
+	if got, exp := e.ShouldCompactCache(time.Now()), tsm1.CacheStatusSizeExceeded; got != exp {
+		t.Fatalf("got status %v, exp status %v", got, exp)
+	}
+	// FlushCache waits for any bucket snapshot still in progress.
+	if err := e.FlushCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e.MustWritePointsString(orgB, bucket1, "cpu,host=b value=2 2")
+	if got, exp := e.ShouldCompactCache(time.Now()), tsm1.CacheStatusOkay; got != exp {
+		t.Fatalf("got status %v, exp status %v", got, exp)
+	}
+}
+
+func TestEngine_FlushCache(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	e.MustWritePointsString(org, bucket, `
+cpu,host=a value=1 10
+cpu,host=b value=2 20
+mem,host=a value=3 30`)
+
+	if e.Cache.Size() == 0 {
+		t.Fatal("expected values in the cache")
+	}
+
+	if err := e.FlushCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.Cache.Size(); got != 0 {
+		t.Fatalf("got cache size %d, exp 0", got)
+	}
+	if got := e.FileStore.Count(); got != 1 {
+		t.Fatalf("got %d TSM files, exp 1", got)
+	}
+
+	// Reads are served from the TSM file.
+	iter, err := e.TagValues(context.Background(), org, bucket, "host", math.MinInt64, math.MaxInt64, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for iter.Next() {
+		got = append(got, iter.Value())
+	}
+	if exp := []string{"a", "b"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got tag values %v, exp %v", got, exp)
+	}
+}
+
+func TestEngine_WarmCache(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	now := time.Now().UnixNano()
+	old := now - int64(2*time.Hour)
+
+	e.MustWritePointsString(org, bucket, fmt.Sprintf(`
+cpu,host=a value=1 %[1]d
+cpu,host=a value=2 %[2]d
+cpu,host=b value=3 %[1]d
+mem,host=a free=4i %[2]d
+`, old, now))
+	e.MustWriteSnapshot()
+
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Cache.Size(); got != 0 {
+		t.Fatalf("got cache size %d after reopening, exp 0", got)
+	}
+
+	// A value written after the restart is newer than the data on disk.
+	e.MustWritePointsString(org, bucket, fmt.Sprintf("mem,host=a free=5i %d", now))
+
+	n, err := e.WarmCache(context.Background(), now-int64(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if n == 0 {
+		t.Fatal("expected values to be loaded into the cache")
+	}
+
+	key := func(m, tags, field string) []byte {
+		p := MustParseExplodePoints(org, bucket, fmt.Sprintf("%s,%s %s=0", m, tags, field))[0]
+		return tsm1.SeriesFieldKeyBytes(string(p.Key()), field)
+	}
+
+	// Only the recent values of cpu,host=a are loaded.
+	if got := e.Cache.Values(key("cpu", "host=a", "value")); len(got) != 1 || got[0].UnixNano() != now {
+		t.Fatalf("unexpected cached values for cpu,host=a: %v", got)
+	}
+	// cpu,host=b has no recent values.
+	if got := e.Cache.Values(key("cpu", "host=b", "value")); len(got) != 0 {
+		t.Fatalf("unexpected cached values for cpu,host=b: %v", got)
+	}
+	// The value written after the restart is not replaced by the one on disk.
+	if got := e.Cache.Values(key("mem", "host=a", "free")); len(got) != 1 || got[0].Value() != int64(5) {
+		t.Fatalf("unexpected cached values for mem,host=a: %v", got)
+	}
+
+	// Warmed values are already on disk and do not need to be snapshotted.
+	if got, exp := e.Cache.WarmSize(), n; got != exp {
+		t.Fatalf("got warm size %d, exp %d", got, exp)
+	}
+
+	// A snapshot only holds the value written after the restart, and the
+	// warmed values stay in the cache.
+	snapshot, err := e.Cache.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshot.Values(key("cpu", "host=a", "value")); len(got) != 0 {
+		t.Fatalf("unexpected snapshot values for cpu,host=a: %v", got)
+	}
+	if got := snapshot.Values(key("mem", "host=a", "free")); len(got) != 1 || got[0].Value() != int64(5) {
+		t.Fatalf("unexpected snapshot values for mem,host=a: %v", got)
+	}
+	e.Cache.ClearSnapshot(true)
+	if got := e.Cache.Values(key("cpu", "host=a", "value")); len(got) != 1 || got[0].UnixNano() != now {
+		t.Fatalf("unexpected cached values for cpu,host=a after snapshot: %v", got)
+	}
+	if got, exp := e.Cache.Size(), e.Cache.WarmSize(); got != exp {
+		t.Fatalf("got cache size %d, exp %d", got, exp)
+	}
+}
+
+func TestEngine_MoveTiers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsm1-tiers-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := tsm1.NewConfig()
+	config.Tiers = tsm1.TierConfig{
+		WarmPath: filepath.Join(dir, "warm"),
+		ColdPath: filepath.Join(dir, "cold"),
+	}
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	now := time.Now()
+	times := map[string]int64{
+		"cold": now.Add(-48 * time.Hour).UnixNano(),
+		"warm": now.Add(-2 * time.Hour).UnixNano(),
+		"hot":  now.UnixNano(),
+	}
+	for _, host := range []string{"cold", "warm", "hot"} {
+		e.MustWritePointsString(org, bucket, fmt.Sprintf("cpu,host=%s value=1 %d", host, times[host]))
+		e.MustWriteSnapshot()
+	}
+
+	if err := e.MoveTiers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() {
+		t.Helper()
+		dirs := map[tsm1.Tier]string{
+			tsm1.TierHot:  filepath.Join(e.root, "data"),
+			tsm1.TierWarm: config.Tiers.WarmPath,
+			tsm1.TierCold: config.Tiers.ColdPath,
+		}
+		for tier, dir := range dirs {
+			files, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+			if err != nil {
+				t.Fatal(err)
+			} else if len(files) != 1 {
+				t.Fatalf("got %d TSM files in %s tier, exp 1", len(files), tier)
+			}
+		}
+		for _, stat := range e.FileStore.Stats() {
+			if got, exp := filepath.Dir(stat.Path), dirs[stat.Tier]; got != exp {
+				t.Fatalf("got %s tier file %s, exp it in %s", stat.Tier, stat.Path, exp)
+			}
+		}
+
+		for host, ts := range times {
+			p := MustParseExplodePoints(org, bucket, fmt.Sprintf("cpu,host=%s value=0", host))[0]
+			values, err := e.FileStore.Read(tsm1.SeriesFieldKeyBytes(string(p.Key()), "value"), ts)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(values) != 1 || values[0].UnixNano() != ts {
+				t.Fatalf("unexpected values for host %s: %v", host, values)
+			}
+		}
+	}
+	check()
+
+	// The files are found in the tier directories after a restart.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+func TestEngine_CompactionStrategy(t *testing.T) {
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+
+	// write writes snapshots of overlapping points, later ones overwriting
+	// earlier ones, and returns the values that should be read back.
+	write := func(e *Engine, snapshots int) map[string]map[int64]float64 {
+		exp := make(map[string]map[int64]float64)
+		for i := 0; i < snapshots; i++ {
+			var lines []string
+			for host := 0; host < 10; host++ {
+				for ts := i * 50; ts < i*50+100; ts++ {
+					v := float64(i*1000 + ts)
+					lines = append(lines, fmt.Sprintf("cpu,host=%d value=%v %d", host, v, ts))
+
+					p := MustParseExplodePoints(org, bucket, fmt.Sprintf("cpu,host=%d value=0", host))[0]
+					key := string(tsm1.SeriesFieldKeyBytes(string(p.Key()), "value"))
+					if exp[key] == nil {
+						exp[key] = make(map[int64]float64)
+					}
+					exp[key][int64(ts)] = v
+				}
+			}
+			e.MustWritePointsString(org, bucket, strings.Join(lines, "\n"))
+			e.MustWriteSnapshot()
+		}
+		return exp
+	}
+
+	// waitFiles waits for compactions to reduce the number of TSM files to n.
+	waitFiles := func(e *Engine, n int) {
+		t.Helper()
+		deadline := time.Now().Add(30 * time.Second)
+		for e.FileStore.Count() > n {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %d TSM files, exp at most %d", e.FileStore.Count(), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	check := func(e *Engine, exp map[string]map[int64]float64) {
+		t.Helper()
+		for key, values := range exp {
+			got := make(map[int64]float64)
+			buf := make([]tsm1.FloatValue, 1000)
+			c := e.FileStore.KeyCursor(context.Background(), []byte(key), 0, true)
+			for {
+				vals, err := c.ReadFloatBlock(&buf)
+				if err != nil {
+					t.Fatal(err)
+				} else if len(vals) == 0 {
+					break
+				}
+				for _, v := range vals {
+					got[v.UnixNano()] = v.Value().(float64)
+				}
+				c.Next()
+			}
+			c.Close()
+
+			if !reflect.DeepEqual(got, values) {
+				t.Fatalf("unexpected values for %q: got %v, exp %v", key, got, values)
+			}
+		}
+	}
+
+	for _, name := range []string{tsm1.DefaultCompactionStrategyName, tsm1.LeveledCompactionStrategyName} {
+		t.Run(name, func(t *testing.T) {
+			config := tsm1.NewConfig()
+			config.Compaction.Strategy = name
+			e, err := NewEngine(config, t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.WithCompactionPlanner(tsm1.NewDefaultPlanner(e.FileStore, tsm1.DefaultCompactFullWriteColdDuration))
+			if err := e.Open(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+
+			exp := write(e, 16)
+			waitFiles(e, 4)
+			check(e, exp)
+		})
+	}
+
+	t.Run("swap", func(t *testing.T) {
+		e, err := NewEngine(tsm1.NewConfig(), t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		planner := &countingPlanner{}
+		e.WithCompactionPlanner(planner)
+		if err := e.Open(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+
+		// The planner never plans compactions. Wait for a planning round that
+		// started after the writes, so that it saw all of their files.
+		exp := write(e, 4)
+		planner.waitRounds(t, planner.Rounds()+2)
+		if got := e.AllActiveCompactions(); got != 0 {
+			t.Fatalf("got %d active compactions, exp 0", got)
+		}
+		if got := e.FileStore.Count(); got != 4 {
+			t.Fatalf("got %d TSM files, exp 4", got)
+		}
+
+		e.SetCompactionStrategy(tsm1.NewLeveledCompactionStrategy())
+		waitFiles(e, 1)
+		check(e, exp)
+	})
+}
+
+func TestEngine_MeasurementPartition(t *testing.T) {
+	config := tsm1.NewConfig()
+	config.MeasurementPartition = true
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.WithCompactionPlanner(tsm1.NewDefaultPlanner(e.FileStore, tsm1.DefaultCompactFullWriteColdDuration))
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// Each snapshot writes a level 1 file of each measurement, which are
+	// compacted once each measurement has 8 of them.
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	for i := 0; i < 8; i++ {
+		e.MustWritePointsString(org, bucket, fmt.Sprintf("cpu,host=a value=%d %d\nmem,host=a free=%d %d", i, i, i, i))
+		e.MustWriteSnapshot()
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for e.FileStore.Count() > 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d TSM files, exp 2", e.FileStore.Count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	check := func() {
+		t.Helper()
+		bucketDir := filepath.Join(e.root, "data", fmt.Sprintf("%s%s", org, bucket))
+		for _, m := range []string{"cpu", "mem"} {
+			files, err := filepath.Glob(filepath.Join(bucketDir, m, m+".*."+tsm1.TSMFileExtension))
+			if err != nil {
+				t.Fatal(err)
+			} else if len(files) != 1 {
+				t.Fatalf("got %d TSM files of %s, exp 1: %v", len(files), m, files)
+			}
+
+			r := e.FileStore.TSMReader(files[0])
+			if r == nil {
+				t.Fatalf("TSM file %s is not open", files[0])
+			}
+			var keys int
+			iter := r.Iterator(nil)
+			for iter.Next() {
+				seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(iter.Key())
+				_, tags := models.ParseKeyBytes(seriesKey)
+				if got := string(tags.Get(models.MeasurementTagKeyBytes)); got != m {
+					t.Errorf("got key of measurement %s in TSM file of %s", got, m)
+				}
+				keys++
+			}
+			r.Unref()
+			if keys != 1 {
+				t.Errorf("got %d keys in TSM file of %s, exp 1", keys, m)
+			}
+		}
+
+		for _, s := range []struct{ line, field string }{
+			{line: "cpu,host=a value=0", field: "value"},
+			{line: "mem,host=a free=0", field: "free"},
+		} {
+			p := MustParseExplodePoints(org, bucket, s.line)[0]
+			values, err := e.FileStore.Read(tsm1.SeriesFieldKeyBytes(string(p.Key()), s.field), 7)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(values) != 8 {
+				t.Fatalf("got %d values of %s, exp 8", len(values), s.line)
+			}
+		}
+	}
+	check()
+
+	// The files are found in the partition directories after a restart.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+func TestEngine_MeasurementPartition_Encryption(t *testing.T) {
+	config := tsm1.NewConfig()
+	config.MeasurementPartition = true
+	config.EncryptionKeyHex = strings.Repeat("0f", tsm1.EncryptionKeySize)
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.Open(context.Background()); err != tsm1.ErrEncryptedMeasurementPartition {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptedMeasurementPartition)
+	}
+}
+
+func TestEngine_RecoveryMode(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	for _, ts := range []int{10, 20, 30} {
+		e.MustWritePointsString(org, bucket, fmt.Sprintf("cpu,host=a value=%d %d", ts, ts))
+		e.MustWriteSnapshot()
+	}
+
+	stats := e.FileStore.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("got %d TSM files, exp 3", len(stats))
+	}
+
+	// Corrupt a block of the second file, and add a file that is not a TSM file.
+	buf, err := ioutil.ReadFile(stats[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[12] ^= 0xff
+	if err := ioutil.WriteFile(stats[1].Path, buf, 0666); err != nil {
+		t.Fatal(err)
+	}
+	garbage := filepath.Join(filepath.Dir(stats[0].Path), "000000099-000000001.tsm")
+	if err := ioutil.WriteFile(garbage, []byte("not a TSM file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without recovery mode, the engine does not open with a corrupt file.
+	if err := e.Reopen(); err == nil {
+		t.Fatal("expected error opening engine with corrupt file")
+	}
+
+	e.config.RecoveryMode = true
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := e.FileStore.SkippedFiles()
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Path < skipped[j].Path })
+	if len(skipped) != 2 {
+		t.Fatalf("got %d skipped files, exp 2", len(skipped))
+	}
+	if got := skipped[0]; got.Path != stats[1].Path || !got.HasTimeRange || got.MinTime != 20 || got.MaxTime != 20 || got.Err == nil {
+		t.Fatalf("unexpected skipped file: %+v", got)
+	}
+	if got := skipped[1]; got.Path != garbage || got.HasTimeRange || got.Err == nil {
+		t.Fatalf("unexpected skipped file: %+v", got)
+	}
+	for _, f := range skipped {
+		if _, err := os.Stat(f.Path + "." + tsm1.BadTSMFileExtension); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The data of the other files is read.
+	p := MustParseExplodePoints(org, bucket, "cpu,host=a value=0")[0]
+	key := tsm1.SeriesFieldKeyBytes(string(p.Key()), "value")
+	var got []int64
+	values := make([]tsm1.FloatValue, 1000)
+	c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+	defer c.Close()
+	for {
+		vals, err := c.ReadFloatBlock(&values)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(vals) == 0 {
+			break
+		}
+		for _, v := range vals {
+			got = append(got, v.UnixNano())
+		}
+		c.Next()
+	}
+	if exp := []int64{10, 30}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got times %v, exp %v", got, exp)
+	}
+}
+
+func TestEngine_Encryption(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	e.config.EncryptionKeyHex = strings.Repeat("0f", tsm1.EncryptionKeySize)
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	for _, ts := range []int{10, 20} {
+		e.MustWritePointsString(org, bucket, fmt.Sprintf("secretcpu,secretkey=secrethost value=%d %d", ts, ts))
+		e.MustWriteSnapshot()
+	}
+
+	// Compacting the snapshots writes an encrypted file too.
+	var paths []string
+	for _, s := range e.FileStore.Stats() {
+		paths = append(paths, s.Path)
+	}
+	e.Compactor.Open()
+	files, err := e.Compactor.CompactFull(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.FileStore.Replace(paths, files); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting data writes an encrypted tombstone file.
+	p := MustParseExplodePoints(org, bucket, "secretcpu,secretkey=secrethost value=0")[0]
+	key := tsm1.SeriesFieldKeyBytes(string(p.Key()), "value")
+	if err := e.FileStore.DeleteRange([][]byte{key}, 10, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	var encrypted []string
+	for _, s := range e.FileStore.Stats() {
+		encrypted = append(encrypted, s.Path)
+		buf, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := buf[4], tsm1.EncryptedVersion; got != exp {
+			t.Fatalf("got version %d, exp %d", got, exp)
+		}
+	}
+
+	// No file of the engine holds the names, measurements or tags of the
+	// series: neither the TSM files, nor their stats or tombstones.
+	name := tsdb.EncodeName(org, bucket)
+	var n int
+	if err := filepath.Walk(e.Path(), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		n++
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(buf, []byte("secret")) || bytes.Contains(buf, name[:]) {
+			t.Errorf("file %s contains the series key", path)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if n < 4 {
+		t.Fatalf("got %d files, exp the TSM file, its stats and its tombstones", n)
+	}
+
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	values := make([]tsm1.FloatValue, 1000)
+	c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+	for {
+		vals, err := c.ReadFloatBlock(&values)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(vals) == 0 {
+			break
+		}
+		for _, v := range vals {
+			got = append(got, v.UnixNano())
+		}
+		c.Next()
+	}
+	c.Close()
+	if exp := []int64{20}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got times %v, exp %v", got, exp)
+	}
+
+	// The stats are read back.
+	if stats, err := e.MeasurementStats(); err != nil {
+		t.Fatal(err)
+	} else if len(stats) == 0 {
+		t.Fatal("exp measurement stats")
+	}
+	for _, f := range e.FileStore.Files() {
+		if !f.KeyStats().Contains(name[:], []byte("secretcpu"), []byte("secretkey")) {
+			t.Fatalf("exp key stats of %s to contain the series", f.Path())
+		}
+	}
+
+	// The encrypted files are not read without the key, even in recovery mode.
+	e.config.EncryptionKeyHex = ""
+	e.config.RecoveryMode = true
+	if err := e.Reopen(); err == nil || !strings.Contains(err.Error(), tsm1.ErrEncryptionKeyRequired.Error()) {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptionKeyRequired)
+	}
+
+	// Nor are they read, or renamed as corrupt, with a different key.
+	e.config.EncryptionKeyHex = strings.Repeat("f0", tsm1.EncryptionKeySize)
+	if err := e.Reopen(); err == nil || !strings.Contains(err.Error(), tsm1.ErrEncryptionKeyMismatch.Error()) {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptionKeyMismatch)
+	}
+	for _, path := range encrypted {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("encrypted file was moved: %v", err)
+		}
+	}
+}
+
+// Test that interrupting a compaction mid-flight leaves no partial files and
+// the data readable.
+func TestEngine_CompactionInterrupted(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	// The values do not compress well, so that the compacted file is larger
+	// than the buffer of its writer.
+	const series, snapshots, pointsPerSnapshot = 200, 3, 500
+	value := func(i, ts int) float64 { return float64(ts) + float64(i)/7 }
+	org, bucket := influxdb.ID(0x1100000000000001), influxdb.ID(0x1300000000000003)
+	for s := 0; s < snapshots; s++ {
+		var buf bytes.Buffer
+		for i := 0; i < series; i++ {
+			for j := 0; j < pointsPerSnapshot; j++ {
+				ts := s*pointsPerSnapshot + j
+				fmt.Fprintf(&buf, "cpu,host=server%03d value=%v %d\n", i, value(i, ts), ts)
+			}
+		}
+		e.MustWritePointsString(org, bucket, buf.String())
+		e.MustWriteSnapshot()
+	}
+
+	var paths []string
+	for _, s := range e.FileStore.Stats() {
+		paths = append(paths, s.Path)
+	}
+	dir := filepath.Dir(paths[0])
+	dirFiles := func() []string {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		return names
+	}
+	before := dirFiles()
+
+	// Throttle the compaction so that it is interrupted while writing.
+	e.Compactor.RateLimit = limiter.NewRate(1024*1024, 1024*1024)
+	e.Compactor.Open()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		e.Compactor.DisableCompactions()
+	}()
+
+	type result struct {
+		files []string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		files, err := e.Compactor.CompactFull(paths)
+		done <- result{files: files, err: err}
+	}()
+
+	// Cancel once the compaction has started writing its temporary file.
+	tmpFiles := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.CompactionTempExtension))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+	for deadline := time.Now().Add(10 * time.Second); len(tmpFiles()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the compaction to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case r := <-done:
+		if r.err == nil {
+			t.Fatalf("got files %v, exp compaction error", r.files)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for the compaction to abort")
+	}
+
+	// The files of the interrupted compaction are removed.
+	if got := dirFiles(); !reflect.DeepEqual(got, before) {
+		t.Fatalf("got files %v after interrupted compaction, exp %v", got, before)
+	}
+	var got []string
+	for _, s := range e.FileStore.Stats() {
+		got = append(got, s.Path)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Fatalf("got files %v, exp %v", got, paths)
+	}
+
+	checkSeries := func() {
+		t.Helper()
+		values := make([]tsm1.FloatValue, 1000)
+		for i := 0; i < series; i++ {
+			p := MustParseExplodePoints(org, bucket, fmt.Sprintf("cpu,host=server%03d value=0", i))[0]
+			key := tsm1.SeriesFieldKeyBytes(string(p.Key()), "value")
+			var n int
+			c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+			for {
+				vals, err := c.ReadFloatBlock(&values)
+				if err != nil {
+					t.Fatal(err)
+				} else if len(vals) == 0 {
+					break
+				}
+				for _, v := range vals {
+					if got, exp := v.Value(), value(i, int(v.UnixNano())); got != exp {
+						t.Fatalf("series %s: got value %v at %d, exp %v", key, got, v.UnixNano(), exp)
+					}
+				}
+				n += len(vals)
+				c.Next()
+			}
+			c.Close()
+			if exp := snapshots * pointsPerSnapshot; n != exp {
+				t.Fatalf("series %s: got %d values, exp %d", key, n, exp)
+			}
+		}
+	}
+	checkSeries()
+
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	checkSeries()
+
+	// A compaction after the restart completes.
+	e.Compactor.Open()
+	files, err := e.Compactor.CompactFull(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.FileStore.Replace(paths, files); err != nil {
+		t.Fatal(err)
+	}
+	checkSeries()
+}
+
 func makeBlockTypeSlice(n int) []byte {
 	r := make([]byte, n)
 	b := tsm1.BlockFloat64
@@ -358,6 +1201,7 @@ func BenchmarkEngine_DeletePrefixRange_Cache(b *testing.B) {
 type Engine struct {
 	*tsm1.Engine
 	root      string
+	config    tsm1.Config
 	indexPath string
 	index     *tsi1.Index
 	sfile     *seriesfile.SeriesFile
@@ -389,6 +1233,7 @@ func NewEngine(config tsm1.Config, tb testing.TB) (*Engine, error) {
 	return &Engine{
 		Engine:    tsm1Engine,
 		root:      root,
+		config:    config,
 		indexPath: idxPath,
 		index:     idx,
 		sfile:     sfile,
@@ -451,8 +1296,7 @@ func (e *Engine) Reopen() error {
 	e.index = MustOpenIndex(e.indexPath, tsdb.NewSeriesIDSet(), e.sfile)
 
 	// Re-initialize engine.
-	config := tsm1.NewConfig()
-	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, config,
+	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, e.config,
 		tsm1.WithCompactionPlanner(newMockPlanner()))
 
 	// Reopen engine
@@ -510,9 +1354,9 @@ func (e *Engine) MustAddSeries(name string, tags map[string]string) {
 	}
 }
 
-// MustWriteSnapshot forces a snapshot of the engine. Panic on error.
+// MustWriteSnapshot flushes the cache of the engine. Panic on error.
 func (e *Engine) MustWriteSnapshot() {
-	if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+	if err := e.FlushCache(context.Background()); err != nil {
 		panic(err)
 	}
 }
@@ -612,3 +1456,27 @@ func (m *mockPlanner) Release(groups []tsm1.CompactionGroup)           {}
 func (m *mockPlanner) FullyCompacted() bool                            { return false }
 func (m *mockPlanner) ForceFull()                                      {}
 func (m *mockPlanner) SetFileStore(fs *tsm1.FileStore)                 {}
+
+// countingPlanner is a mockPlanner that counts the compaction planning rounds
+// of the engine. The engine releases the planned groups at the end of each round.
+type countingPlanner struct {
+	mockPlanner
+	rounds uint64
+}
+
+func (p *countingPlanner) Release(groups []tsm1.CompactionGroup) { atomic.AddUint64(&p.rounds, 1) }
+
+// Rounds returns the number of completed planning rounds.
+func (p *countingPlanner) Rounds() uint64 { return atomic.LoadUint64(&p.rounds) }
+
+// waitRounds waits until at least n planning rounds completed.
+func (p *countingPlanner) waitRounds(tb testing.TB, n uint64) {
+	tb.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for p.Rounds() < n {
+		if time.Now().After(deadline) {
+			tb.Fatalf("got %d compaction planning rounds, exp at least %d", p.Rounds(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
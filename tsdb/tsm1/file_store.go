@@ -84,6 +84,16 @@ type TSMFile interface {
 	// of the key range.
 	OverlapsKeyPrefixRange(min, max []byte) bool
 
+	// OverlapsTagKeyRange returns true if the file may contain series of the
+	// name and measurement with the tag key. The name is the encoded
+	// organization and bucket. An empty measurement matches any series of the
+	// name, and an empty tag key matches any series of the measurement.
+	OverlapsTagKeyRange(name, measurement, tagKey []byte) bool
+
+	// KeyStats returns the key stats of the file, or nil if the file was
+	// written without them.
+	KeyStats() KeyStats
+
 	// TimeRange returns the min and max time across all keys in the file.
 	TimeRange() (int64, int64)
 
@@ -202,6 +212,14 @@ type FileStore struct {
 	currentGeneration     int        // internally maintained generation
 	currentGenerationFunc func() int // external generation
 	dir                   string
+	tiers                 tierDirs // directory of each storage tier
+
+	// In recovery mode, corrupt TSM files are skipped on open rather than
+	// failing it. The files skipped by the last open are kept in skipped.
+	recoveryMode bool
+	skipped      []SkippedFile
+
+	encryptionKey []byte // master key of encrypted TSM files
 
 	files           []TSMFile
 	tsmMMAPWillNeed bool          // If true then the kernel will be advised MMAP_WILLNEED for TSM files.
@@ -219,9 +237,25 @@ type FileStore struct {
 	obs FileStoreObserver
 }
 
+// SkippedFile is a corrupt TSM file that was skipped when the FileStore was
+// opened in recovery mode. The file is renamed with the BadTSMFileExtension.
+type SkippedFile struct {
+	Path string
+	Size int64
+
+	// HasTimeRange is true if the index of the file could be read, in which
+	// case MinTime and MaxTime are the time range of its data.
+	HasTimeRange     bool
+	MinTime, MaxTime int64
+
+	// Err is the reason the file is corrupt.
+	Err error
+}
+
 // FileStat holds information about a TSM file on disk.
 type FileStat struct {
 	Path             string
+	Tier             Tier
 	HasTombstone     bool
 	Size             uint32
 	LastModified     int64
@@ -249,6 +283,7 @@ func NewFileStore(dir string) *FileStore {
 	logger := zap.NewNop()
 	fs := &FileStore{
 		dir:          dir,
+		tiers:        newTierDirs(dir, TierConfig{}),
 		lastModified: time.Time{},
 		logger:       logger,
 		openLimiter:  limiter.NewFixed(runtime.GOMAXPROCS(0)),
@@ -276,6 +311,31 @@ func (f *FileStore) WithParseFileNameFunc(parseFileNameFunc ParseFileNameFunc) {
 	f.parseFileName = parseFileNameFunc
 }
 
+// WithRecoveryMode sets whether corrupt TSM files are skipped when the file
+// store is opened. In recovery mode, the blocks of each file are verified when
+// it is opened.
+func (f *FileStore) WithRecoveryMode(enabled bool) {
+	f.recoveryMode = enabled
+}
+
+// WithEncryptionKey sets the master key used to read encrypted TSM files.
+func (f *FileStore) WithEncryptionKey(key []byte) {
+	f.encryptionKey = key
+}
+
+// SkippedFiles returns the corrupt TSM files that were skipped when the file
+// store was opened in recovery mode.
+func (f *FileStore) SkippedFiles() []SkippedFile {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]SkippedFile(nil), f.skipped...)
+}
+
+// WithTiers sets the directories of the warm and cold storage tiers.
+func (f *FileStore) WithTiers(c TierConfig) {
+	f.tiers = newTierDirs(f.dir, c)
+}
+
 func (f *FileStore) ParseFileName(path string) (int, int, error) {
 	return f.parseFileName(path)
 }
@@ -518,6 +578,24 @@ func (f *FileStore) ForEachFile(fn func(f TSMFile) bool) {
 	f.mu.RUnlock()
 }
 
+// FileSizes returns the sizes on disk of the TSM files with keys beginning
+// with prefix, by path.
+func (f *FileStore) FileSizes(prefix []byte) map[string]uint32 {
+	sizes := make(map[string]uint32)
+	f.ForEachFile(func(r TSMFile) bool {
+		if !r.OverlapsKeyPrefixRange(prefix, prefix) {
+			return true
+		}
+		// The keys of the prefix may all be deleted.
+		itr := r.Iterator(prefix)
+		if itr.Next() && bytes.HasPrefix(itr.Key(), prefix) {
+			sizes[r.Path()] = r.Size()
+		}
+		return true
+	})
+	return sizes
+}
+
 // Apply calls fn on each TSMFile in the store concurrently. The level of
 // concurrency is set to GOMAXPROCS.
 func (f *FileStore) Apply(fn func(r TSMFile) error) error {
@@ -625,18 +703,30 @@ func (f *FileStore) Open(ctx context.Context) error {
 		}
 	}
 
-	files, err := filepath.Glob(filepath.Join(f.dir, fmt.Sprintf("*.%s", TSMFileExtension)))
-	if err != nil {
-		return err
+	var files []string
+	for _, dir := range f.tiers.unique() {
+		// The files of measurement partitions are two directories below.
+		for _, pattern := range []string{
+			filepath.Join(dir, fmt.Sprintf("*.%s", TSMFileExtension)),
+			filepath.Join(dir, "*", "*", fmt.Sprintf("*.%s", TSMFileExtension)),
+		} {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			files = append(files, matches...)
+		}
 	}
 
 	// struct to hold the result of opening each reader in a goroutine
 	type res struct {
-		r   *TSMReader
-		err error
+		r       *TSMReader
+		skipped *SkippedFile
+		err     error
 	}
+	f.skipped = nil
 
-	readerC := make(chan *res)
+	readerC := make(chan *res, len(files))
 	for i, fn := range files {
 		// Keep track of the latest ID
 		generation, _, err := f.parseFileName(fn)
@@ -663,21 +753,60 @@ func (f *FileStore) Open(ctx context.Context) error {
 			start := time.Now()
 			df, err := NewTSMReader(file,
 				WithMadviseWillNeed(f.tsmMMAPWillNeed),
+				WithTSMReaderEncryptionKey(f.encryptionKey),
 				WithTSMReaderLogger(f.logger))
 			f.logger.Info("Opened file",
 				zap.String("path", file.Name()),
 				zap.Int("id", idx),
 				zap.Duration("duration", time.Since(start)))
 
-			// If we are unable to read a TSM file then log the error, rename
-			// the file, and continue loading the shard without it.
+			if err == nil && f.recoveryMode {
+				err = verifyTSMBlocks(df)
+			}
+
+			// If we are unable to read a TSM file then it is corrupt. In recovery
+			// mode, log the error, rename the file, and continue loading the shard
+			// without it.
 			if err != nil {
+				skipped := &SkippedFile{Path: file.Name(), Err: err}
+				if stat, err := file.Stat(); err == nil {
+					skipped.Size = stat.Size()
+				}
+				if df != nil {
+					skipped.HasTimeRange = true
+					skipped.MinTime, skipped.MaxTime = df.TimeRange()
+					df.Close()
+				} else {
+					file.Close()
+				}
+
+				// An encrypted file is not corrupt if the key is missing or
+				// is not the one it was encrypted with.
+				if err == ErrEncryptionKeyRequired || err == ErrEncryptionKeyMismatch {
+					readerC <- &res{err: fmt.Errorf("cannot read file %s: %v", file.Name(), err)}
+					return
+				} else if !f.recoveryMode {
+					readerC <- &res{err: fmt.Errorf("cannot read corrupt file %s: %v", file.Name(), err)}
+					return
+				}
+
 				f.logger.Error("Cannot read corrupt tsm file, renaming", zap.String("path", file.Name()), zap.Int("id", idx), zap.Error(err))
+				if skipped.HasTimeRange {
+					f.logger.Warn("Skipped corrupt tsm file, its data is missing until the file is restored",
+						zap.String("path", file.Name()),
+						zap.Time("min_time", time.Unix(0, skipped.MinTime).UTC()),
+						zap.Time("max_time", time.Unix(0, skipped.MaxTime).UTC()))
+				} else {
+					f.logger.Warn("Skipped corrupt tsm file, data of an unknown time range is missing until the file is restored",
+						zap.String("path", file.Name()))
+				}
 				if e := fs.RenameFile(file.Name(), file.Name()+"."+BadTSMFileExtension); e != nil {
 					f.logger.Error("Cannot rename corrupt tsm file", zap.String("path", file.Name()), zap.Int("id", idx), zap.Error(e))
-					readerC <- &res{r: df, err: fmt.Errorf("cannot rename corrupt file %s: %v", file.Name(), e)}
+					readerC <- &res{err: fmt.Errorf("cannot rename corrupt file %s: %v", file.Name(), e)}
 					return
 				}
+				readerC <- &res{skipped: skipped}
+				return
 			}
 
 			df.WithObserver(f.obs)
@@ -696,6 +825,9 @@ func (f *FileStore) Open(ctx context.Context) error {
 		res := <-readerC
 		if res.err != nil {
 			return res.err
+		} else if res.skipped != nil {
+			f.skipped = append(f.skipped, *res.skipped)
+			continue
 		} else if res.r == nil {
 			continue
 		}
@@ -834,7 +966,9 @@ func (f *FileStore) Stats() []FileStat {
 	}
 
 	for _, fd := range f.files {
-		f.lastFileStats = append(f.lastFileStats, fd.Stats())
+		stat := fd.Stats()
+		stat.Tier = f.tiers.tier(stat.Path)
+		f.lastFileStats = append(f.lastFileStats, stat)
 	}
 	return f.lastFileStats
 }
@@ -873,11 +1007,12 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 			return err
 		}
 
-		// Observe the associated statistics file, if available.
-		statsFile := StatsFilename(file)
-		if _, err := os.Stat(statsFile); err == nil {
-			if err := f.obs.FileFinishing(statsFile); err != nil {
-				return err
+		// Observe the associated statistics files, if available.
+		for _, statsFile := range []string{StatsFilename(file), KeyStatsFilename(file)} {
+			if _, err := os.Stat(statsFile); err == nil {
+				if err := f.obs.FileFinishing(statsFile); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -904,6 +1039,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 
 		tsm, err := NewTSMReader(fd,
 			WithMadviseWillNeed(f.tsmMMAPWillNeed),
+			WithTSMReaderEncryptionKey(f.encryptionKey),
 			WithTSMReaderLogger(f.logger))
 		if err != nil {
 			return err
@@ -940,11 +1076,12 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 					return err
 				}
 
-				// Remove associated stats file.
-				statsFile := StatsFilename(file.Path())
-				if _, err := os.Stat(statsFile); err == nil {
-					if err := f.obs.FileUnlinking(statsFile); err != nil {
-						return err
+				// Remove associated stats files.
+				for _, statsFile := range []string{StatsFilename(file.Path()), KeyStatsFilename(file.Path())} {
+					if _, err := os.Stat(statsFile); err == nil {
+						if err := f.obs.FileUnlinking(statsFile); err != nil {
+							return err
+						}
 					}
 				}
 
@@ -1005,8 +1142,15 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 		}
 	}
 
-	if err := fs.SyncDir(f.dir); err != nil {
-		return err
+	for _, dir := range f.tiers.unique() {
+		if err := fs.SyncDir(dir); err != nil {
+			return err
+		}
+	}
+	for _, dir := range partitionDirs(newFiles) {
+		if err := fs.SyncDir(dir); err != nil {
+			return err
+		}
 	}
 
 	// Tell the purger about our in-use files we need to remove
@@ -1243,12 +1387,12 @@ func (f *FileStore) CreateSnapshot(ctx context.Context) (backupID int, backupDir
 	}
 	for _, tsmf := range files {
 		newpath := filepath.Join(backupDirFullPath, filepath.Base(tsmf.Path()))
-		if err := os.Link(tsmf.Path(), newpath); err != nil {
+		if err := f.linkFile(tsmf.Path(), newpath); err != nil {
 			return 0, "", fmt.Errorf("error creating tsm hard link: %q", err)
 		}
 		for _, tf := range tsmf.TombstoneFiles() {
 			newpath := filepath.Join(backupDirFullPath, filepath.Base(tf.Path))
-			if err := os.Link(tf.Path, newpath); err != nil {
+			if err := f.linkFile(tf.Path, newpath); err != nil {
 				return 0, "", fmt.Errorf("error creating tombstone hard link: %q", err)
 			}
 		}
@@ -1257,6 +1401,16 @@ func (f *FileStore) CreateSnapshot(ctx context.Context) (backupID int, backupDir
 	return backupID, backupDirFullPath, nil
 }
 
+// linkFile creates a hard link at newpath to the file at path. Files in the
+// directory of another storage tier may be on another device, so they are
+// copied instead.
+func (f *FileStore) linkFile(path, newpath string) error {
+	if tsmFileTierDir(path) != f.dir {
+		return copyFile(path, newpath)
+	}
+	return os.Link(path, newpath)
+}
+
 func (f *FileStore) InternalBackupPath(backupID int) string {
 	return filepath.Join(f.dir, fmt.Sprintf("%d.%s", backupID, TmpTSMFileExtension))
 }
@@ -1289,9 +1443,10 @@ func DefaultFormatFileName(generation, sequence int) string {
 // ParseFileNameFunc is executed when parsing a TSM filename into generation & sequence.
 type ParseFileNameFunc func(name string) (generation, sequence int, err error)
 
-// DefaultParseFileName is used to parse the filenames of TSM files.
+// DefaultParseFileName is used to parse the filenames of TSM files. The
+// measurement prefix of the files of a partition is ignored.
 func DefaultParseFileName(name string) (int, int, error) {
-	base := filepath.Base(name)
+	_, base := splitPartitionPrefix(filepath.Base(name))
 	idx := strings.Index(base, ".")
 	if idx == -1 {
 		return 0, 0, fmt.Errorf("file %s is named incorrectly", name)
@@ -1608,6 +1763,10 @@ func (p *purger) purge() {
 
 type tsmReaders []TSMFile
 
-func (a tsmReaders) Len() int           { return len(a) }
-func (a tsmReaders) Less(i, j int) bool { return a[i].Path() < a[j].Path() }
-func (a tsmReaders) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a tsmReaders) Len() int      { return len(a) }
+func (a tsmReaders) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// Less orders the files by generation and sequence, whichever tier they are in.
+func (a tsmReaders) Less(i, j int) bool {
+	return tsmFileOrder(a[i].Path()) < tsmFileOrder(a[j].Path())
+}
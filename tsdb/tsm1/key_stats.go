@@ -0,0 +1,368 @@
+package tsm1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/binaryutil"
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/pkg/hll"
+)
+
+const (
+	// KeyStatsMagicNumber is written as the first 4 bytes of a data file to
+	// identify the file as a tsm1 key stats file.
+	KeyStatsMagicNumber string = "TKS1"
+
+	// KeyStatsVersion indicates the version of the TKS1 file format.
+	KeyStatsVersion byte = 1
+)
+
+// KeyStats holds statistics about the series keys of each name in a TSM file.
+// In the storage engine the name of a series is its encoded organization and
+// bucket, and its measurement is the value of its models.MeasurementTagKey
+// tag. The stats allow files that do not contain a measurement and tag key
+// combination to be skipped, and the measurements of a file to be estimated,
+// without reading the file's index.
+type KeyStats map[string]*KeyStat
+
+// KeyStat holds the statistics of the series keys of a single name.
+type KeyStat struct {
+	// TagKeys is the set of tag keys of the series.
+	TagKeys map[string]struct{}
+
+	// Measurements holds the set of tag keys of the series of each
+	// measurement.
+	Measurements map[string]map[string]struct{}
+
+	// MeasurementSketch estimates the number of distinct values of the
+	// measurement tag of the series.
+	MeasurementSketch *hll.Plus
+}
+
+func newKeyStat() *KeyStat {
+	return &KeyStat{
+		TagKeys:           make(map[string]struct{}),
+		Measurements:      make(map[string]map[string]struct{}),
+		MeasurementSketch: hll.NewDefaultPlus(),
+	}
+}
+
+// NewKeyStats returns a new instance of KeyStats.
+func NewKeyStats() KeyStats {
+	return make(KeyStats)
+}
+
+// AddKey adds the name, measurement and tag keys of the series key, or series
+// and field composite key, to the stats.
+func (s KeyStats) AddKey(key []byte) {
+	seriesKey, _ := SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(seriesKey)
+
+	stat := s[string(name)]
+	if stat == nil {
+		stat = newKeyStat()
+		s[string(name)] = stat
+	}
+
+	var measurementTagKeys map[string]struct{}
+	if m := tags.Get(models.MeasurementTagKeyBytes); m != nil {
+		if measurementTagKeys = stat.Measurements[string(m)]; measurementTagKeys == nil {
+			measurementTagKeys = make(map[string]struct{})
+			stat.Measurements[string(m)] = measurementTagKeys
+			stat.MeasurementSketch.Add(m)
+		}
+	}
+
+	for _, t := range tags {
+		if _, ok := stat.TagKeys[string(t.Key)]; !ok {
+			stat.TagKeys[string(t.Key)] = struct{}{}
+		}
+		if measurementTagKeys != nil {
+			if _, ok := measurementTagKeys[string(t.Key)]; !ok {
+				measurementTagKeys[string(t.Key)] = struct{}{}
+			}
+		}
+	}
+}
+
+// Contains returns true if the stats hold series of the name and measurement
+// with the tag key. An empty measurement matches any series of the name, and
+// an empty tag key matches any series of the measurement.
+func (s KeyStats) Contains(name, measurement, tagKey []byte) bool {
+	stat, ok := s[string(name)]
+	if !ok {
+		return false
+	}
+
+	tagKeys := stat.TagKeys
+	if len(measurement) > 0 {
+		if tagKeys, ok = stat.Measurements[string(measurement)]; !ok {
+			return false
+		}
+	}
+
+	if len(tagKey) == 0 {
+		return true
+	}
+	_, ok = tagKeys[string(tagKey)]
+	return ok
+}
+
+// Names returns the sorted names of the stats, which are the encoded
+// organization and bucket names of the series keys.
+func (s KeyStats) Names() []string {
+	a := make([]string, 0, len(s))
+	for name := range s {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// ReadFrom reads stats from r in a binary format. Reader must also be an io.ByteReader.
+func (s KeyStats) ReadFrom(r io.Reader) (n int64, err error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		return 0, fmt.Errorf("tsm1.KeyStats.ReadFrom: ByteReader required")
+	}
+
+	// Read & verify magic.
+	magic := make([]byte, 4)
+	nn, err := io.ReadFull(r, magic)
+	if n += int64(nn); err != nil {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats magic: %s", err)
+	} else if string(magic) != KeyStatsMagicNumber {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: invalid tsm1 key stats file")
+	}
+
+	// Read & verify version.
+	version := make([]byte, 1)
+	nn, err = io.ReadFull(r, version)
+	if n += int64(nn); err != nil {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats version: %s", err)
+	} else if version[0] != KeyStatsVersion {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: incompatible tsm1 key stats version: %d", version[0])
+	}
+
+	// Read checksum.
+	checksum := make([]byte, 4)
+	nn, err = io.ReadFull(r, checksum)
+	if n += int64(nn); err != nil {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read checksum: %s", err)
+	}
+
+	// Read name count.
+	nameN, err := binary.ReadVarint(br)
+	if err != nil {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats name count: %s", err)
+	}
+	n += int64(binaryutil.VarintSize(nameN))
+
+	// Read names.
+	for i := int64(0); i < nameN; i++ {
+		name, nn64, err := readKeyStatsString(r, br)
+		if n += nn64; err != nil {
+			return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats name: %s", err)
+		}
+
+		stat := &KeyStat{Measurements: make(map[string]map[string]struct{})}
+		stat.TagKeys, nn64, err = readKeyStatsSet(r, br)
+		if n += nn64; err != nil {
+			return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats tag keys: %s", err)
+		}
+
+		sketch, nn64, err := readKeyStatsString(r, br)
+		if n += nn64; err != nil {
+			return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats measurement sketch: %s", err)
+		}
+		stat.MeasurementSketch = new(hll.Plus)
+		if err := stat.MeasurementSketch.UnmarshalBinary([]byte(sketch)); err != nil {
+			return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot decode stats measurement sketch: %s", err)
+		}
+
+		measurementN, err := binary.ReadVarint(br)
+		if err != nil {
+			return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats measurement count: %s", err)
+		}
+		n += int64(binaryutil.VarintSize(measurementN))
+
+		for j := int64(0); j < measurementN; j++ {
+			measurement, nn64, err := readKeyStatsString(r, br)
+			if n += nn64; err != nil {
+				return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats measurement: %s", err)
+			}
+			tagKeys, nn64, err := readKeyStatsSet(r, br)
+			if n += nn64; err != nil {
+				return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: cannot read stats measurement tag keys: %s", err)
+			}
+			stat.Measurements[measurement] = tagKeys
+		}
+		s[name] = stat
+	}
+
+	// Expect end-of-file.
+	buf := make([]byte, 1)
+	if _, err := r.Read(buf); err != io.EOF {
+		return n, fmt.Errorf("tsm1.KeyStats.ReadFrom: file too large, expected EOF")
+	}
+
+	return n, nil
+}
+
+func readKeyStatsString(r io.Reader, br io.ByteReader) (string, int64, error) {
+	sz, err := binary.ReadVarint(br)
+	if err != nil {
+		return "", 0, err
+	}
+	n := int64(binaryutil.VarintSize(sz))
+
+	buf := make([]byte, sz)
+	nn, err := io.ReadFull(r, buf)
+	return string(buf), n + int64(nn), err
+}
+
+func readKeyStatsSet(r io.Reader, br io.ByteReader) (map[string]struct{}, int64, error) {
+	sz, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := int64(binaryutil.VarintSize(sz))
+
+	set := make(map[string]struct{}, sz)
+	for i := int64(0); i < sz; i++ {
+		v, nn, err := readKeyStatsString(r, br)
+		if n += nn; err != nil {
+			return nil, n, err
+		}
+		set[v] = struct{}{}
+	}
+	return set, n, nil
+}
+
+// WriteTo writes stats to w in a binary format.
+func (s KeyStats) WriteTo(w io.Writer) (n int64, err error) {
+	// Write magic & version.
+	nn, err := io.WriteString(w, KeyStatsMagicNumber)
+	if n += int64(nn); err != nil {
+		return n, err
+	}
+	nn, err = w.Write([]byte{KeyStatsVersion})
+	if n += int64(nn); err != nil {
+		return n, err
+	}
+
+	// Write name count.
+	var buf bytes.Buffer
+	b := make([]byte, binary.MaxVarintLen64)
+	buf.Write(b[:binary.PutVarint(b, int64(len(s)))])
+
+	// Write all names, their tag keys in sorted order, their sketch and the
+	// tag keys of each of their measurements.
+	for _, name := range s.Names() {
+		stat := s[name]
+		buf.Write(b[:binary.PutVarint(b, int64(len(name)))])
+		buf.WriteString(name)
+
+		writeKeyStatsSet(&buf, b, stat.TagKeys)
+
+		sketch, err := stat.MeasurementSketch.MarshalBinary()
+		if err != nil {
+			return n, err
+		}
+		buf.Write(b[:binary.PutVarint(b, int64(len(sketch)))])
+		buf.Write(sketch)
+
+		measurements := make([]string, 0, len(stat.Measurements))
+		for m := range stat.Measurements {
+			measurements = append(measurements, m)
+		}
+		sort.Strings(measurements)
+
+		buf.Write(b[:binary.PutVarint(b, int64(len(measurements)))])
+		for _, m := range measurements {
+			buf.Write(b[:binary.PutVarint(b, int64(len(m)))])
+			buf.WriteString(m)
+			writeKeyStatsSet(&buf, b, stat.Measurements[m])
+		}
+	}
+	data := buf.Bytes()
+
+	// Compute & write checksum.
+	if err := binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(data)); err != nil {
+		return n, err
+	}
+	n += 4
+
+	// Write buffer.
+	nn, err = w.Write(data)
+	if n += int64(nn); err != nil {
+		return n, err
+	}
+
+	return n, err
+}
+
+// writeKeyStatsSet writes the values of set to buf in sorted order, using b
+// to encode varints.
+func writeKeyStatsSet(buf *bytes.Buffer, b []byte, set map[string]struct{}) {
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	buf.Write(b[:binary.PutVarint(b, int64(len(values)))])
+	for _, v := range values {
+		buf.Write(b[:binary.PutVarint(b, int64(len(v)))])
+		buf.WriteString(v)
+	}
+}
+
+// readKeyStatsFile reads the key stats file at path. Encrypted files are
+// decrypted with encryptionKey.
+func readKeyStatsFile(path string, encryptionKey []byte) (KeyStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := NewKeyStats()
+	if err := readSealed(f, encryptionKey, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// writeKeyStatsFile replaces the key stats file at path with stats. The file
+// is encrypted if encryptionKey is set.
+func writeKeyStatsFile(path string, stats KeyStats, encryptionKey []byte) error {
+	tmp := path + "." + TmpTSMFileExtension
+	f, err := fs.CreateFileWithReplacement(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := writeSealed(f, encryptionKey, stats); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return fs.RenameFileWithReplacement(tmp, path)
+}
+
+// KeyStatsFilename returns the path to the key stats file for a given TSM file path.
+func KeyStatsFilename(tsmPath string) string {
+	return strings.TrimSuffix(StatsFilename(tsmPath), "."+TSSFileExtension) + "." + TKSFileExtension
+}
@@ -73,6 +73,7 @@ type compactionMetrics struct {
 	CompactionsActive  *prometheus.GaugeVec
 	CompactionDuration *prometheus.HistogramVec
 	CompactionQueue    *prometheus.GaugeVec
+	DefragmentProgress *prometheus.GaugeVec
 
 	// The following metrics include a ``"status" = {ok, error}` label
 	Compactions *prometheus.CounterVec
@@ -89,6 +90,13 @@ func newCompactionMetrics(labels prometheus.Labels) *compactionMetrics {
 	totalCompactionsNames := append(append([]string(nil), names...), []string{"reason", "status"}...)
 	sort.Strings(totalCompactionsNames)
 
+	// Defragmentation is not levelled.
+	var defragmentNames []string
+	for k := range labels {
+		defragmentNames = append(defragmentNames, k)
+	}
+	sort.Strings(defragmentNames)
+
 	return &compactionMetrics{
 		Compactions: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: namespace,
@@ -116,6 +124,12 @@ func newCompactionMetrics(labels prometheus.Labels) *compactionMetrics {
 			Name:      "queued",
 			Help:      "Number of queued compactions.",
 		}, names),
+		DefragmentProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: compactionSubsystem,
+			Name:      "defragment_progress_ratio",
+			Help:      "Fraction of the TSM files being defragmented that have been rewritten.",
+		}, defragmentNames),
 	}
 }
 
@@ -126,6 +140,7 @@ func (m *compactionMetrics) PrometheusCollectors() []prometheus.Collector {
 		m.CompactionsActive,
 		m.CompactionDuration,
 		m.CompactionQueue,
+		m.DefragmentProgress,
 	}
 }
 
@@ -175,6 +190,7 @@ type cacheMetrics struct {
 	SnapshotsActive  *prometheus.GaugeVec
 	Age              *prometheus.GaugeVec
 	SnapshottedBytes *prometheus.CounterVec
+	WarmupBytes      *prometheus.CounterVec
 
 	// The following metrics include a ``"status" = {ok, error, dropped}` label
 	WrittenBytes *prometheus.CounterVec
@@ -223,6 +239,12 @@ func newCacheMetrics(labels prometheus.Labels) *cacheMetrics {
 			Name:      "snapshot_bytes",
 			Help:      "Number of bytes snapshotted.",
 		}, names),
+		WarmupBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: cacheSubsystem,
+			Name:      "warmup_bytes_loaded",
+			Help:      "Number of bytes loaded from TSM files into the Cache while warming it up.",
+		}, names),
 		WrittenBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: cacheSubsystem,
@@ -246,6 +268,7 @@ func (m *cacheMetrics) PrometheusCollectors() []prometheus.Collector {
 		m.SnapshotsActive,
 		m.Age,
 		m.SnapshottedBytes,
+		m.WarmupBytes,
 		m.WrittenBytes,
 		m.Writes,
 	}
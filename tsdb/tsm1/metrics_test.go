@@ -77,6 +77,7 @@ func TestMetrics_Cache(t *testing.T) {
 
 	counters := []string{
 		base + "snapshot_bytes",
+		base + "warmup_bytes_loaded",
 		base + "written_bytes",
 		base + "writes_total",
 	}
@@ -89,11 +90,12 @@ func TestMetrics_Cache(t *testing.T) {
 		tracker.SetSnapshotsActive(uint64(i + len(gauges[3])))
 
 		tracker.AddSnapshottedBytes(uint64(i + len(counters[0])))
-		tracker.AddWrittenBytesOK(uint64(i + len(counters[1])))
+		tracker.AddWarmupBytes(uint64(i + len(counters[1])))
+		tracker.AddWrittenBytesOK(uint64(i + len(counters[2])))
 
 		labels := tracker.Labels()
 		labels["status"] = "ok"
-		tracker.metrics.Writes.With(labels).Add(float64(i + len(counters[2])))
+		tracker.metrics.Writes.With(labels).Add(float64(i + len(counters[3])))
 	}
 
 	// Test that all the correct metrics are present.
@@ -120,7 +122,7 @@ func TestMetrics_Cache(t *testing.T) {
 		for _, name := range counters {
 			exp := float64(i + len(name))
 
-			if name == counters[1] || name == counters[2] {
+			if name == counters[2] || name == counters[3] {
 				labels["status"] = "ok"
 			}
 			metric := promtest.MustFindMetric(t, mfs, name, labels)
@@ -165,6 +167,8 @@ func TestMetrics_Compactions(t *testing.T) {
 
 		labels = tracker.Labels(2)
 		tracker.metrics.CompactionDuration.With(labels).Observe(float64(i + len(histograms[0])))
+
+		tracker.SetDefragmentProgress(float64(i+1) / 2)
 	}
 
 	// Test that all the correct metrics are present.
@@ -214,5 +218,13 @@ func TestMetrics_Compactions(t *testing.T) {
 				t.Errorf("[%s %d] got %v, expected %v", name, i, got, exp)
 			}
 		}
+
+		// Defragmentation progress has no level.
+		delete(labels, "level")
+		exp := float64(i+1) / 2
+		metric := promtest.MustFindMetric(t, mfs, base+"defragment_progress_ratio", labels)
+		if got := metric.GetGauge().GetValue(); got != exp {
+			t.Errorf("[defragment_progress_ratio %d] got %v, expected %v", i, got, exp)
+		}
 	}
 }
@@ -0,0 +1,150 @@
+package tsm1
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cespare/xxhash"
+	"github.com/influxdata/influxdb/models"
+)
+
+// With measurement partitioning enabled, cache snapshots write the data of
+// each measurement of a bucket to TSM files of its own, in the directory of
+// its partition below the directory of the storage tier:
+//
+//	<tier>/<org ID><bucket ID>/<measurement>/<measurement>.<generation>-<sequence>.tsm
+//
+// The files of a partition are only compacted with each other, so that each
+// TSM file, and the key statistics built for it, only holds the series of a
+// single measurement. Files written before partitioning was enabled remain in
+// the directory of the tier, and are compacted with each other as before.
+//
+// Partitioning cannot be combined with encryption at rest, as the directories of
+// the partitions are named after the IDs of the buckets and their measurements.
+
+// ErrEncryptedMeasurementPartition is returned when opening an engine with
+// both measurement partitioning and an encryption key.
+var ErrEncryptedMeasurementPartition = errors.New("measurement partitioning cannot be enabled with an encryption key, as partition directories are named after buckets and measurements")
+
+const (
+	// partitionPrefixSep separates the measurement prefix of the name of a
+	// TSM file in a partition from its generation and sequence. Neither the
+	// prefix nor the formatted generation of a file contain it.
+	partitionPrefixSep = '.'
+
+	// maxPartitionNameLen is the length of the escaped measurement name
+	// above which it is shortened and suffixed by its hash, so that the names
+	// of partition files stay within the limits of file systems.
+	maxPartitionNameLen = 128
+)
+
+// escapePartitionName returns the name of a measurement as it appears in the
+// names of the directories and files of its partition. Letters and digits are
+// kept and every other byte is percent-encoded, so that the name is safe in
+// paths and never contains partitionPrefixSep or the '-' of the generation.
+func escapePartitionName(name []byte) string {
+	if len(name) == 0 {
+		return "%"
+	}
+
+	var b strings.Builder
+	for _, c := range name {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	s := b.String()
+	if len(s) > maxPartitionNameLen {
+		s = fmt.Sprintf("%s~%016x", s[:maxPartitionNameLen-17], xxhash.Sum64(name))
+	}
+	return s
+}
+
+// measurementPartition returns the directory of the partition of a series
+// key, relative to the directory of a storage tier.
+func measurementPartition(seriesKey []byte) string {
+	name, tags := models.ParseKeyBytes(seriesKey)
+	return filepath.Join(hex.EncodeToString(name), escapePartitionName(tags.Get(models.MeasurementTagKeyBytes)))
+}
+
+// splitPartitionPrefix splits the base name of a TSM file into the measurement
+// prefix of its partition, which is empty for files that are not partitioned,
+// and the rest of the name.
+func splitPartitionPrefix(base string) (prefix, rest string) {
+	i := strings.IndexByte(base, partitionPrefixSep)
+	if i < 0 || strings.IndexByte(base[:i], '-') >= 0 {
+		return "", base
+	}
+	return base[:i], base[i+1:]
+}
+
+// tsmFileOrder returns the base name of the TSM file at path without its
+// partition prefix, by which files are ordered by generation and sequence
+// whichever tier and partition they are in.
+func tsmFileOrder(path string) string {
+	_, rest := splitPartitionPrefix(filepath.Base(path))
+	return rest
+}
+
+// tsmFilePartition returns the directory of the partition of the TSM file at
+// path relative to the directory of its tier, or "" if it is not partitioned.
+func tsmFilePartition(path string) string {
+	if prefix, _ := splitPartitionPrefix(filepath.Base(path)); prefix == "" {
+		return ""
+	}
+	dir := filepath.Dir(path)
+	return filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir))
+}
+
+// tsmFileTierDir returns the directory of the storage tier of the TSM file at
+// path, which is above the directory of its partition if it has one.
+func tsmFileTierDir(path string) string {
+	dir := filepath.Dir(path)
+	if tsmFilePartition(path) != "" {
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	return dir
+}
+
+// SplitMeasurements splits the cache into a cache for each measurement of
+// each bucket, keyed by the directory of its partition.
+func (c *Cache) SplitMeasurements() map[string]*Cache {
+	caches := make(map[string]*Cache)
+	// applySerial only errors if the closure returns an error.
+	_ = c.store.applySerial(func(k string, e *entry) error {
+		key := []byte(k)
+		seriesKey, _ := SeriesAndFieldFromCompositeKey(key)
+		dir := measurementPartition(seriesKey)
+		sp := caches[dir]
+		if sp == nil {
+			sp = &Cache{store: newRing()}
+			caches[dir] = sp
+		}
+		sp.store.add(key, e)
+		return nil
+	})
+	return caches
+}
+
+// partitionDirs returns the distinct directories of the partitions of the TSM
+// files at paths.
+func partitionDirs(paths []string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if tsmFilePartition(path) == "" {
+			continue
+		}
+		if dir := filepath.Dir(path); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -2,8 +2,10 @@ package tsm1
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"regexp"
+	"sort"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
@@ -17,13 +19,16 @@ type Predicate interface {
 }
 
 const ( // Enumeration of all predicate versions we support unmarshalling.
-	predicateVersionZero = '\x00'
+	predicateVersionZero       = '\x00'
+	predicateVersionSeriesKeys = '\x01'
 )
 
 // UnmarshalPredicate takes stored predicate bytes from a Marshal call and returns a Predicate.
 func UnmarshalPredicate(data []byte) (Predicate, error) {
 	if len(data) == 0 {
 		return nil, nil
+	} else if data[0] == predicateVersionSeriesKeys {
+		return unmarshalSeriesKeysPredicate(data[1:])
 	} else if data[0] != predicateVersionZero {
 		return nil, fmt.Errorf("unknown tag byte: %x", data[0])
 	}
@@ -596,6 +601,89 @@ func predicateEval(comp datatypes.Node_Comparison, left, right []byte, rightReg
 	return false
 }
 
+//
+// Series Keys Implementation
+//
+
+// NewSeriesKeysPredicate returns a Predicate that matches the series keys
+// provided, so that the series can be deleted together by a single tombstone
+// rather than one for each key.
+func NewSeriesKeysPredicate(keys [][]byte) Predicate {
+	sorted := make([][]byte, len(keys))
+	copy(sorted, keys)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+
+	// Remove duplicate keys.
+	n := 0
+	for i, key := range sorted {
+		if i > 0 && bytes.Equal(key, sorted[n-1]) {
+			continue
+		}
+		sorted[n] = key
+		n++
+	}
+	return &seriesKeysPredicate{keys: sorted[:n]}
+}
+
+// seriesKeysPredicate implements Predicate for a sorted set of series keys.
+type seriesKeysPredicate struct {
+	keys [][]byte
+}
+
+// Clone returns p, which is never modified.
+func (p *seriesKeysPredicate) Clone() influxdb.Predicate { return p }
+
+// Matches checks if the series of the key is in the set.
+func (p *seriesKeysPredicate) Matches(key []byte) bool {
+	key, _ = SeriesAndFieldFromCompositeKey(key)
+	i := sort.Search(len(p.keys), func(i int) bool { return bytes.Compare(p.keys[i], key) >= 0 })
+	return i < len(p.keys) && bytes.Equal(p.keys[i], key)
+}
+
+// Marshal returns a buffer of the length-prefixed series keys.
+func (p *seriesKeysPredicate) Marshal() ([]byte, error) {
+	size := 1 + binary.MaxVarintLen64
+	for _, key := range p.keys {
+		size += binary.MaxVarintLen64 + len(key)
+	}
+
+	buf := make([]byte, 1, size)
+	buf[0] = predicateVersionSeriesKeys
+	var tmp [binary.MaxVarintLen64]byte
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(p.keys)))]...)
+	for _, key := range p.keys {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(key)))]...)
+		buf = append(buf, key...)
+	}
+	return buf, nil
+}
+
+// unmarshalSeriesKeysPredicate decodes the series keys marshaled by a
+// seriesKeysPredicate, following the version byte.
+func unmarshalSeriesKeysPredicate(data []byte) (Predicate, error) {
+	n, sz := binary.Uvarint(data)
+	if sz <= 0 || n > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid series keys predicate")
+	}
+	data = data[sz:]
+
+	keys := make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		l, sz := binary.Uvarint(data)
+		if sz <= 0 || l > uint64(len(data)-sz) {
+			return nil, fmt.Errorf("invalid series keys predicate")
+		}
+		data = data[sz:]
+		// Copy the key, as data may be reused by the caller.
+		keys = append(keys, append([]byte(nil), data[:l]...))
+		data = data[l:]
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("invalid series keys predicate")
+	}
+	return NewSeriesKeysPredicate(keys), nil
+}
+
 //
 // Popping Tags
 //
@@ -285,6 +285,48 @@ func TestPredicate_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestSeriesKeysPredicate(t *testing.T) {
+	pred := NewSeriesKeysPredicate([][]byte{
+		[]byte("m,tag0=c"),
+		[]byte("m,tag0=a"),
+		[]byte("m,tag0=a"),
+		[]byte("m,tag0=b\\,c"),
+	})
+
+	for key, exp := range map[string]bool{
+		"m,tag0=a":          true,
+		"m,tag0=a#!~#f":     true,
+		"m,tag0=b\\,c#!~#f": true,
+		"m,tag0=c#!~#f":     true,
+		"m,tag0=ab#!~#f":    false,
+		"m,tag0=b#!~#f":     false,
+		"m,tag0=d#!~#f":     false,
+		"m#!~#f":            false,
+	} {
+		if got := pred.Matches([]byte(key)); got != exp {
+			t.Errorf("Matches(%q) = %v, exp %v", key, got, exp)
+		}
+	}
+
+	predData, err := pred.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred2, err := UnmarshalPredicate(predData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pred, pred2) {
+		t.Fatal("mismatch on unmarshal")
+	}
+
+	for _, data := range []string{"\x01", "\x01\x01", "\x01\x01\x05ab", "\x01\x01\x01ab"} {
+		if _, err := UnmarshalPredicate([]byte(data)); err == nil {
+			t.Fatalf("expected error unmarshaling %q", data)
+		}
+	}
+}
+
 func TestPredicate_Unmarshal_InvalidTag(t *testing.T) {
 	_, err := UnmarshalPredicate([]byte("\xff"))
 	if err == nil {
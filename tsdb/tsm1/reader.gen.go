@@ -108,6 +108,7 @@ type blockAccessor interface {
 	readBooleanBlock(entry *IndexEntry, values *[]BooleanValue) ([]BooleanValue, error)
 	readBooleanArrayBlock(entry *IndexEntry, values *cursors.BooleanArray) error
 	readBytes(entry *IndexEntry, buf []byte) (uint32, []byte, error)
+	willNeedIndex(start, end uint32) error
 	rename(path string) error
 	path() string
 	close() error
@@ -118,12 +119,13 @@ func (m *mmapAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) (
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeFloatBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeFloatBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -137,12 +139,13 @@ func (m *mmapAccessor) readFloatArrayBlock(entry *IndexEntry, values *cursors.Fl
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeFloatArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeFloatArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -152,12 +155,13 @@ func (m *mmapAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValu
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeIntegerBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeIntegerBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -171,12 +175,13 @@ func (m *mmapAccessor) readIntegerArrayBlock(entry *IndexEntry, values *cursors.
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeIntegerArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeIntegerArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -186,12 +191,13 @@ func (m *mmapAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedVa
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeUnsignedBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeUnsignedBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -205,12 +211,13 @@ func (m *mmapAccessor) readUnsignedArrayBlock(entry *IndexEntry, values *cursors
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeUnsignedArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeUnsignedArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -220,12 +227,13 @@ func (m *mmapAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue)
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeStringBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeStringBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -239,12 +247,13 @@ func (m *mmapAccessor) readStringArrayBlock(entry *IndexEntry, values *cursors.S
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeStringArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeStringArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -254,12 +263,13 @@ func (m *mmapAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValu
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeBooleanBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeBooleanBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -273,12 +283,13 @@ func (m *mmapAccessor) readBooleanArrayBlock(entry *IndexEntry, values *cursors.
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeBooleanArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeBooleanArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
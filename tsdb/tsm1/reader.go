@@ -1,12 +1,13 @@
 package tsm1
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"sync"
 	"sync/atomic"
 
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +22,8 @@ type TSMReader struct {
 
 	logger          *zap.Logger
 	madviseWillNeed bool // Hint to the kernel with MADV_WILLNEED.
+	readAheadSize   int  // Bytes of the index read ahead of a TimeRangeIterator.
+	encryptionKey   []byte
 	mu              sync.RWMutex
 
 	// accessor provides access and decoding of blocks for the reader.
@@ -40,6 +43,11 @@ type TSMReader struct {
 
 	// deleteMu limits concurrent deletes
 	deleteMu sync.Mutex
+
+	// keyStats are loaded from the key stats file on first use. They are nil
+	// if the file was written without key stats.
+	keyStatsOnce sync.Once
+	keyStats     KeyStats
 }
 
 type tsmReaderOption func(*TSMReader)
@@ -51,6 +59,23 @@ var WithMadviseWillNeed = func(willNeed bool) tsmReaderOption {
 	}
 }
 
+// WithReadAheadSize is an option for specifying how many bytes of the index a
+// TimeRangeIterator asks the kernel to read ahead of its current key. A size
+// of zero disables read-ahead.
+var WithReadAheadSize = func(size int) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.readAheadSize = size
+	}
+}
+
+// WithTSMReaderEncryptionKey is an option for specifying the master key of
+// encrypted TSM files.
+var WithTSMReaderEncryptionKey = func(key []byte) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.encryptionKey = key
+	}
+}
+
 var WithTSMReaderLogger = func(logger *zap.Logger) tsmReaderOption {
 	return func(r *TSMReader) {
 		r.logger = logger
@@ -60,7 +85,8 @@ var WithTSMReaderLogger = func(logger *zap.Logger) tsmReaderOption {
 // NewTSMReader returns a new TSMReader from the given file.
 func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	t := &TSMReader{
-		logger: zap.NewNop(),
+		logger:        zap.NewNop(),
+		readAheadSize: DefaultReadAheadSize,
 	}
 	for _, option := range options {
 		option(t)
@@ -73,9 +99,10 @@ func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	t.size = stat.Size()
 	t.lastModified = stat.ModTime().UnixNano()
 	t.accessor = &mmapAccessor{
-		logger:       t.logger,
-		f:            f,
-		mmapWillNeed: t.madviseWillNeed,
+		logger:        t.logger,
+		f:             f,
+		mmapWillNeed:  t.madviseWillNeed,
+		encryptionKey: t.encryptionKey,
 	}
 
 	index, err := t.accessor.init()
@@ -85,6 +112,7 @@ func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 
 	t.index = index
 	t.tombstoner = NewTombstoner(t.Path(), index.MaybeContainsKey)
+	t.tombstoner.WithEncryptionKey(t.encryptionKey)
 
 	if err := t.applyTombstones(); err != nil {
 		return nil, err
@@ -211,12 +239,55 @@ func (t *TSMReader) MeasurementStats() (MeasurementStats, error) {
 	defer f.Close()
 
 	stats := make(MeasurementStats)
-	if _, err := stats.ReadFrom(bufio.NewReader(f)); err != nil {
+	if err := readSealed(f, t.encryptionKey, stats); err != nil {
 		return nil, err
 	}
 	return stats, err
 }
 
+// KeyStats returns the on-disk key stats for this file, or nil if they are
+// not available. The returned stats must not be modified.
+func (t *TSMReader) KeyStats() KeyStats {
+	t.keyStatsOnce.Do(func() {
+		path := t.Path()
+		if path == "" {
+			return
+		}
+
+		stats, err := readKeyStatsFile(KeyStatsFilename(path), t.encryptionKey)
+		if os.IsNotExist(err) {
+			return
+		} else if err != nil {
+			// The file is damaged or has an incompatible version. Rebuild it
+			// from the index once, rather than scanning the index on every
+			// lookup.
+			t.logger.Warn("Rebuilding key stats file", zap.String("path", path), zap.Error(err))
+			if stats, err = t.buildKeyStats(); err != nil {
+				t.logger.Warn("Cannot rebuild key stats", zap.String("path", path), zap.Error(err))
+				return
+			}
+			if err := writeKeyStatsFile(KeyStatsFilename(path), stats, t.encryptionKey); err != nil {
+				t.logger.Warn("Cannot write key stats file", zap.String("path", path), zap.Error(err))
+			}
+		}
+		t.keyStats = stats
+	})
+	return t.keyStats
+}
+
+// buildKeyStats returns the key stats of the keys in the index.
+func (t *TSMReader) buildKeyStats() (KeyStats, error) {
+	stats := NewKeyStats()
+	iter := t.index.Iterator(nil)
+	for iter.Next() {
+		stats.AddKey(iter.Key())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // Close closes the TSMReader.
 func (t *TSMReader) Close() error {
 	t.refsWG.Wait()
@@ -280,6 +351,8 @@ func (t *TSMReader) remove() error {
 			return err
 		} else if err := os.RemoveAll(StatsFilename(path)); err != nil && !os.IsNotExist(err) {
 			return err
+		} else if err := os.RemoveAll(KeyStatsFilename(path)); err != nil && !os.IsNotExist(err) {
+			return err
 		}
 	}
 
@@ -380,6 +453,26 @@ func (t *TSMReader) OverlapsKeyPrefixRange(min, max []byte) bool {
 	return t.index.OverlapsKeyPrefixRange(min, max)
 }
 
+// OverlapsTagKeyRange returns true if the file may contain series of the
+// name and measurement with the tag key. Without key stats for the file, only
+// the key range of the file is checked against the name and measurement.
+func (t *TSMReader) OverlapsTagKeyRange(name, measurement, tagKey []byte) bool {
+	if stats := t.KeyStats(); stats != nil {
+		return stats.Contains(name, measurement, tagKey)
+	}
+
+	// The measurement tag key sorts first, so the series keys of a measurement
+	// share the prefix "<name>,\x00=<measurement>".
+	prefix := models.EscapeMeasurement(name)
+	if len(measurement) > 0 {
+		prefix = append(prefix, ',')
+		prefix = append(prefix, models.MeasurementTagKeyBytes...)
+		prefix = append(prefix, '=')
+		prefix = append(prefix, escape.Bytes(measurement)...)
+	}
+	return t.index.OverlapsKeyPrefixRange(prefix, prefix)
+}
+
 // TimeRange returns the min and max time across all keys in the file.
 func (t *TSMReader) TimeRange() (int64, int64) {
 	return t.index.TimeRange()
@@ -494,6 +587,7 @@ func (t *TSMReader) TimeRangeIterator(key []byte, min, max int64) *TimeRangeIter
 			Min: min,
 			Max: max,
 		},
+		ra: indexReadAhead{size: uint32(t.readAheadSize)},
 	}
 }
 
@@ -3,6 +3,7 @@ package tsm1
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"sync"
 	"sync/atomic"
@@ -25,6 +26,11 @@ type mmapAccessor struct {
 	f     *os.File
 	_path string // If the underlying file is renamed then this gets updated
 
+	indexStart uint64 // offset of the index in b
+
+	encryptionKey []byte     // Master key of encrypted files.
+	cipher        *tsmCipher // Decrypts the blocks of an encrypted file.
+
 	index *indirectIndex
 }
 
@@ -35,12 +41,13 @@ func (m *mmapAccessor) init() (*indirectIndex, error) {
 	// Set the path explicitly.
 	m._path = m.f.Name()
 
-	if err := verifyVersion(m.f); err != nil {
+	version, err := verifyVersion(m.f)
+	if err != nil {
 		return nil, err
+	} else if version == EncryptedVersion && m.encryptionKey == nil {
+		return nil, ErrEncryptionKeyRequired
 	}
 
-	var err error
-
 	if _, err := m.f.Seek(0, 0); err != nil {
 		return nil, err
 	}
@@ -72,9 +79,31 @@ func (m *mmapAccessor) init() (*indirectIndex, error) {
 	if indexStart >= uint64(indexOfsPos) {
 		return nil, fmt.Errorf("mmapAccessor: invalid indexStart")
 	}
+	index := m.b[indexStart:indexOfsPos]
+
+	// The index of an encrypted file is decrypted onto the heap, and the salt
+	// of the file key and the key check are stored before the index offset.
+	if version == EncryptedVersion {
+		keyCheckPos := indexOfsPos - encryptionKeyCheckSize
+		saltPos := keyCheckPos - encryptionSaltSize
+		if indexStart >= uint64(saltPos) {
+			return nil, fmt.Errorf("mmapAccessor: invalid indexStart")
+		}
+		m.cipher, err = newTSMCipher(m.encryptionKey, m.b[saltPos:keyCheckPos])
+		if err != nil {
+			return nil, err
+		}
+		if err := m.cipher.checkKey(m.b[keyCheckPos:indexOfsPos]); err != nil {
+			return nil, err
+		}
+		if index, err = m.cipher.openIndex(m.b[indexStart:saltPos]); err != nil {
+			return nil, fmt.Errorf("mmapAccessor: cannot decrypt index: %v", err)
+		}
+	}
 
+	m.indexStart = indexStart
 	m.index = NewIndirectIndex()
-	if err := m.index.UnmarshalBinary(m.b[indexStart:indexOfsPos]); err != nil {
+	if err := m.index.UnmarshalBinary(index); err != nil {
 		return nil, err
 	}
 	m.index.logger = m.logger
@@ -113,6 +142,29 @@ func (m *mmapAccessor) free() error {
 	return madviseDontNeed(m.b)
 }
 
+// willNeedIndex hints to the kernel that the index bytes in [start, end) will
+// be read soon. As madvise requires a page aligned address, the hint is
+// extended back to the start of the page containing start.
+func (m *mmapAccessor) willNeedIndex(start, end uint32) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The file has been closed, or its index is not in b.
+	if m.b == nil || m.cipher != nil {
+		return nil
+	}
+
+	from, to := m.indexStart+uint64(start), m.indexStart+uint64(end)
+	if max := uint64(len(m.b)); to > max {
+		to = max
+	}
+	from -= from % uint64(os.Getpagesize())
+	if from >= to {
+		return nil
+	}
+	return madviseWillNeed(m.b[from:to])
+}
+
 func (m *mmapAccessor) incAccess() {
 	atomic.AddUint64(&m.accessCount, 1)
 }
@@ -139,18 +191,32 @@ func (m *mmapAccessor) read(key []byte, timestamp int64) ([]Value, error) {
 	return m.readBlock(entry, nil)
 }
 
+// block returns the data of the block of entry, after the 4 byte checksum.
+// The blocks of encrypted files are decrypted. m.mu must be held.
+func (m *mmapAccessor) block(entry *IndexEntry) ([]byte, error) {
+	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+		return nil, ErrTSMClosed
+	}
+
+	b := m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)]
+	if m.cipher == nil {
+		return b, nil
+	}
+	return m.cipher.openBlock(b, entry.Offset)
+}
+
 func (m *mmapAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, error) {
 	m.incAccess()
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
-		return nil, ErrTSMClosed
-	}
 	//TODO: Validate checksum
-	var err error
-	values, err = DecodeBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	values, err = DecodeBlock(b, values)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +237,20 @@ func (m *mmapAccessor) readBytes(entry *IndexEntry, b []byte) (uint32, []byte, e
 	crc, block := binary.BigEndian.Uint32(m.b[entry.Offset:entry.Offset+4]), m.b[entry.Offset+4:entry.Offset+int64(entry.Size)]
 	m.mu.RUnlock()
 
+	// The checksum of an encrypted block covers the stored bytes. The block is
+	// returned decrypted, with the checksum of its plaintext, so that the
+	// stored bytes are verified before they are decrypted.
+	if m.cipher != nil {
+		if crc != crc32.ChecksumIEEE(block) {
+			return crc, block, nil
+		}
+		plain, err := m.cipher.openBlock(block, entry.Offset)
+		if err != nil {
+			return 0, nil, err
+		}
+		return crc32.ChecksumIEEE(plain), plain, nil
+	}
+
 	return crc, block, nil
 }
 
@@ -205,8 +285,11 @@ func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 		}
 		//TODO: Validate checksum
 		temp = temp[:0]
-		// The +4 is the 4 byte checksum length
-		temp, err = DecodeBlock(m.b[block.Offset+4:block.Offset+int64(block.Size)], temp)
+		b, err := m.block(&block)
+		if err != nil {
+			return nil, err
+		}
+		temp, err = DecodeBlock(b, temp)
 		if err != nil {
 			return nil, err
 		}
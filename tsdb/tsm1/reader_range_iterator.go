@@ -13,6 +13,7 @@ type TimeRangeIterator struct {
 	tr    TimeRange
 	err   error
 	stats cursors.CursorStats
+	ra    indexReadAhead
 
 	// temporary storage
 	trbuf []TimeRange
@@ -33,7 +34,11 @@ func (b *TimeRangeIterator) Next() bool {
 		return false
 	}
 
-	return b.iter.Next()
+	if !b.iter.Next() {
+		return false
+	}
+	b.readAhead()
+	return true
 }
 
 // Seek points the iterator at the smallest key greater than or equal to the
@@ -44,7 +49,20 @@ func (b *TimeRangeIterator) Seek(key []byte) (exact, ok bool) {
 		return false, false
 	}
 
-	return b.iter.Seek(key)
+	exact, ok = b.iter.Seek(key)
+	if ok {
+		b.readAhead()
+	}
+	return exact, ok
+}
+
+// readAhead asks the kernel to read the index ahead of the current key, so
+// that the keys the iterator reaches next are in memory by the time they are
+// accessed. Errors are ignored, as read-ahead is only a hint.
+func (b *TimeRangeIterator) readAhead() {
+	if start, end, ok := b.ra.advance(b.iter.offset); ok {
+		_ = b.r.accessor.willNeedIndex(start, end)
+	}
 }
 
 // Key reports the current key.
@@ -309,6 +309,127 @@ func TestTimeRangeIterator(t *testing.T) {
 	})
 }
 
+func TestIndexReadAhead_Advance(t *testing.T) {
+	type step struct {
+		offset     uint32
+		start, end uint32
+		ok         bool
+	}
+
+	tests := []struct {
+		name  string
+		size  uint32
+		steps []step
+	}{
+		{
+			name:  "disabled",
+			size:  0,
+			steps: []step{{offset: 0}, {offset: 100}},
+		},
+		{
+			name: "sequential",
+			size: 100,
+			steps: []step{
+				{offset: 0, start: 0, end: 100, ok: true},
+				{offset: 10},
+				{offset: 49},
+				{offset: 50, start: 100, end: 150, ok: true},
+				{offset: 99},
+				{offset: 100, start: 150, end: 200, ok: true},
+			},
+		},
+		{
+			name: "seek outside window",
+			size: 100,
+			steps: []step{
+				{offset: 0, start: 0, end: 100, ok: true},
+				{offset: 500, start: 500, end: 600, ok: true},
+				{offset: 20, start: 20, end: 120, ok: true},
+			},
+		},
+		{
+			name: "overflow",
+			size: 100,
+			steps: []step{
+				{offset: ^uint32(0) - 10, start: ^uint32(0) - 10, end: ^uint32(0), ok: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ra := indexReadAhead{size: tc.size}
+			for _, s := range tc.steps {
+				start, end, ok := ra.advance(s.offset)
+				if got, exp := [3]interface{}{start, end, ok}, [3]interface{}{s.start, s.end, s.ok}; got != exp {
+					t.Errorf("advance(%d): got %v, expected %v", s.offset, got, exp)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTimeRangeIterator_ReadAhead(b *testing.B) {
+	const keys = 200000
+
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		b.Fatalf("unexpected error creating writer: %v", err)
+	}
+	for i := 0; i < keys; i++ {
+		key := []byte(fmt.Sprintf("cpu,host=server-%08d#!~#usage_user", i))
+		if err := w.Write(key, []Value{NewValue(0, float64(i))}); err != nil {
+			b.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		b.Fatalf("unexpected error writing index: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatalf("unexpected error closing: %v", err)
+	}
+
+	for _, size := range []int{0, DefaultReadAheadSize} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			f, err := os.Open(f.Name())
+			if err != nil {
+				b.Fatalf("unexpected error opening: %v", err)
+			}
+			r, err := NewTSMReader(f, WithReadAheadSize(size))
+			if err != nil {
+				b.Fatalf("unexpected error creating reader: %v", err)
+			}
+			defer r.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Drop the mapped pages so the scan has to fault them in
+				// again, as it would for a file that is not in memory.
+				b.StopTimer()
+				if err := madviseDontNeed(r.accessor.(*mmapAccessor).b); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				n := 0
+				iter := r.TimeRangeIterator(nil, 0, 0)
+				for iter.Next() {
+					if iter.HasData() {
+						n++
+					}
+				}
+				if n != keys {
+					b.Fatalf("unexpected key count: got %d, expected %d", n, keys)
+				}
+			}
+		})
+	}
+}
+
 func TestExcludeEntries(t *testing.T) {
 	entries := func(ts ...int64) (e []IndexEntry) {
 		for i := 0; i+1 < len(ts); i += 2 {
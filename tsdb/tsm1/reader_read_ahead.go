@@ -0,0 +1,43 @@
+package tsm1
+
+// DefaultReadAheadSize is the default number of bytes of the index a
+// TimeRangeIterator asks the kernel to read ahead of its current key.
+const DefaultReadAheadSize = 64 * 1024
+
+// indexReadAhead tracks the window of the index that an iterator has asked
+// the kernel to read ahead of its position. Each iterator has its own window,
+// so concurrent iterators over the same file don't interfere.
+//
+// As the index is memory mapped, read-ahead is a MADV_WILLNEED hint rather
+// than a buffer: the kernel reads the pages in the background while the
+// iterator processes the current key.
+type indexReadAhead struct {
+	size       uint32 // size of the window; zero disables read-ahead
+	start, end uint32 // the window most recently read ahead
+}
+
+// advance reports the range of the index to read ahead of offset, the
+// position of the iterator. A new range is only returned once the iterator
+// has passed the middle of the current window, or has moved outside of it.
+func (r *indexReadAhead) advance(offset uint32) (start, end uint32, ok bool) {
+	if r.size == 0 {
+		return 0, 0, false
+	}
+
+	if offset >= r.start && offset < r.end {
+		if offset-r.start < r.size/2 {
+			return 0, 0, false
+		}
+		// Only the part of the window that has not been read ahead.
+		start = r.end
+	} else {
+		start = offset
+	}
+
+	end = offset + r.size
+	if end < offset { // overflow
+		end = ^uint32(0)
+	}
+	r.start, r.end = offset, end
+	return start, end, true
+}
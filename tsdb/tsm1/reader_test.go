@@ -1,10 +1,13 @@
 package tsm1
 
 import (
+	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"sort"
 	"testing"
 )
@@ -1073,6 +1076,149 @@ func TestTSMReader_MMAP_Stats(t *testing.T) {
 	}
 }
 
+func TestTSMReader_OverlapsTagKeyRange(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+	defer f.Close()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	for _, key := range []string{
+		"bucket,\x00=cpu,host=a#!~#value",
+		"bucket,\x00=cpu,host=b,region=west#!~#value",
+		"bucket,\x00=mem,host=a#!~#value",
+		"other,\x00=disk,path=a#!~#value",
+	} {
+		if err := w.Write([]byte(key), []Value{NewValue(0, 1.0)}); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	if _, err := os.Stat(KeyStatsFilename(f.Name())); err != nil {
+		t.Fatalf("expected key stats file: %v", err)
+	}
+
+	check := func(t *testing.T, r *TSMReader) {
+		t.Helper()
+		for _, tt := range []struct {
+			name, measurement, tagKey string
+			exp                       bool
+		}{
+			{"bucket", "cpu", "host", true},
+			{"bucket", "cpu", "region", true},
+			{"bucket", "cpu", "", true},
+			{"bucket", "mem", "host", true},
+			{"bucket", "mem", "region", false},
+			{"bucket", "", "region", true},
+			{"bucket", "", "path", false},
+			{"bucket", "disk", "", false},
+			{"other", "disk", "path", true},
+			{"none", "", "", false},
+		} {
+			if got := r.OverlapsTagKeyRange([]byte(tt.name), []byte(tt.measurement), []byte(tt.tagKey)); got != tt.exp {
+				t.Errorf("OverlapsTagKeyRange(%q, %q, %q) = %v, exp %v", tt.name, tt.measurement, tt.tagKey, got, tt.exp)
+			}
+		}
+	}
+
+	t.Run("KeyStats", func(t *testing.T) {
+		f, err := os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+
+		r, err := NewTSMReader(f)
+		if err != nil {
+			t.Fatalf("unexpected error created reader: %v", err)
+		}
+		defer r.Close()
+
+		check(t, r)
+	})
+
+	t.Run("IncompatibleKeyStats", func(t *testing.T) {
+		// Replace the version byte that follows the magic number.
+		kf, err := os.OpenFile(KeyStatsFilename(f.Name()), os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kf.WriteAt([]byte{KeyStatsVersion + 1}, int64(len(KeyStatsMagicNumber))); err != nil {
+			t.Fatal(err)
+		} else if err := kf.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readKeyStatsFile(KeyStatsFilename(f.Name()), nil); err == nil {
+			t.Fatal("expected error reading key stats file with an incompatible version")
+		}
+
+		f, err := os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+
+		r, err := NewTSMReader(f)
+		if err != nil {
+			t.Fatalf("unexpected error created reader: %v", err)
+		}
+		defer r.Close()
+
+		if r.KeyStats() == nil {
+			t.Fatal("expected key stats to be rebuilt from the index")
+		}
+		check(t, r)
+
+		// The rebuilt stats replace the file.
+		stats, err := readKeyStatsFile(KeyStatsFilename(f.Name()), nil)
+		if err != nil {
+			t.Fatalf("unexpected error reading rebuilt key stats file: %v", err)
+		}
+		if got, exp := stats.Names(), []string{"bucket", "other"}; !reflect.DeepEqual(got, exp) {
+			t.Fatalf("got names %v, exp %v", got, exp)
+		}
+	})
+
+	t.Run("NoKeyStats", func(t *testing.T) {
+		if err := os.Remove(KeyStatsFilename(f.Name())); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+
+		r, err := NewTSMReader(f)
+		if err != nil {
+			t.Fatalf("unexpected error created reader: %v", err)
+		}
+		defer r.Close()
+
+		// Without key stats only the key range is checked against the name
+		// and measurement.
+		if !r.OverlapsTagKeyRange([]byte("bucket"), []byte("mem"), []byte("region")) {
+			t.Fatal("expected file to overlap without key stats")
+		}
+		if r.OverlapsTagKeyRange([]byte("bucket"), []byte("aaa"), []byte("host")) {
+			t.Fatal("expected file not to overlap measurement before its key range")
+		}
+		if r.OverlapsTagKeyRange([]byte("zzz"), nil, []byte("host")) {
+			t.Fatal("expected file not to overlap name after its key range")
+		}
+	})
+}
+
 // Ensure that we return an error if we try to open a non-tsm file
 func TestTSMReader_VerifiesFileType(t *testing.T) {
 	dir := mustTempDir()
@@ -1618,3 +1764,69 @@ func TestTSMReader_DeletePrefix(t *testing.T) {
 		t.Fatalf("wrong value: %q but wanted %q", got, exp)
 	}
 }
+
+// BenchmarkTSMReader_Open reports the heap memory retained by opening a TSM
+// file. The file, including its index, is memory mapped; the heap only holds
+// the offset of each key and the prefix table used to search them.
+func BenchmarkTSMReader_Open(b *testing.B) {
+	const keys = 200000
+
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		b.Fatalf("unexpected error creating writer: %v", err)
+	}
+	for i := 0; i < keys; i++ {
+		key := []byte(fmt.Sprintf("cpu,host=server-%08d#!~#usage_user", i))
+		if err := w.Write(key, []Value{NewValue(0, float64(i))}); err != nil {
+			b.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		b.Fatalf("unexpected error writing index: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatalf("unexpected error closing: %v", err)
+	}
+
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var retained uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, err := os.Open(f.Name())
+		if err != nil {
+			b.Fatalf("unexpected error opening: %v", err)
+		}
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		r, err := NewTSMReader(f)
+		if err != nil {
+			b.Fatalf("unexpected error creating reader: %v", err)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc > before.HeapAlloc {
+			retained += after.HeapAlloc - before.HeapAlloc
+		}
+
+		if err := r.Close(); err != nil {
+			b.Fatalf("unexpected error closing reader: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(retained)/float64(b.N), "heap-B/op")
+	b.ReportMetric(float64(fi.Size()), "file-B")
+}
@@ -31,11 +31,9 @@ const numPartitions = 16
 // key is hashed and the least significant 4 bits are used as an index to the ring.
 //
 type ring struct {
-	// Number of keys within the ring. This is used to provide a hint for
-	// allocating the return values in keys(). It will not be perfectly accurate
-	// since it doesn't consider adding duplicate keys, or trying to remove non-
-	// existent keys.
-	keysHint int64
+	// Number of keys within the ring, including those of entries without
+	// values. Must always be accessed via atomic.
+	keyCount int64
 
 	// The unique set of partitions in the ring.
 	// len(partitions) <= len(continuum)
@@ -60,7 +58,7 @@ func (r *ring) reset() {
 	for _, partition := range r.partitions {
 		partition.reset()
 	}
-	r.keysHint = 0
+	atomic.StoreInt64(&r.keyCount, 0)
 }
 
 // getPartition retrieves the hash ring partition associated with the provided key.
@@ -78,34 +76,54 @@ func (r *ring) entry(key []byte) *entry {
 // If no entry exists for the key then one will be created.
 // write is safe for use by multiple goroutines.
 func (r *ring) write(key []byte, values Values) (bool, error) {
-	return r.getPartition(key).write(key, values)
+	newKey, err := r.getPartition(key).write(key, values)
+	if newKey {
+		atomic.AddInt64(&r.keyCount, 1)
+	}
+	return newKey, err
 }
 
 // add adds an entry to the ring.
 func (r *ring) add(key []byte, entry *entry) {
-	r.getPartition(key).add(key, entry)
-	atomic.AddInt64(&r.keysHint, 1)
+	if r.getPartition(key).add(key, entry) {
+		atomic.AddInt64(&r.keyCount, 1)
+	}
+}
+
+// addIfAbsent adds an entry to the ring unless the key is already in the ring.
+// It returns true if the entry was added.
+func (r *ring) addIfAbsent(key []byte, entry *entry) bool {
+	if !r.getPartition(key).addIfAbsent(key, entry) {
+		return false
+	}
+	atomic.AddInt64(&r.keyCount, 1)
+	return true
 }
 
 // remove deletes the entry for the given key.
 // remove is safe for use by multiple goroutines.
 func (r *ring) remove(key []byte) {
-	r.getPartition(key).remove(key)
-	if r.keysHint > 0 {
-		atomic.AddInt64(&r.keysHint, -1)
+	if r.getPartition(key).remove(key) {
+		atomic.AddInt64(&r.keyCount, -1)
 	}
 }
 
+// len returns the number of keys in the ring, including those of entries
+// without values. It is safe for use by multiple goroutines.
+func (r *ring) len() int {
+	return int(atomic.LoadInt64(&r.keyCount))
+}
+
 // keys returns all the keys from all partitions in the hash ring. The returned
 // keys will be in order if sorted is true.
 func (r *ring) keys(sorted bool) [][]byte {
-	keys := make([][]byte, 0, atomic.LoadInt64(&r.keysHint))
+	keys := make([][]byte, 0, r.len())
 	for _, p := range r.partitions {
 		keys = append(keys, p.keys()...)
 	}
 
 	if sorted {
-		bytesutil.Sort(keys)
+		bytesutil.RadixSort(keys)
 	}
 	return keys
 }
@@ -241,19 +259,37 @@ func (p *partition) write(key []byte, values Values) (bool, error) {
 	return true, nil
 }
 
-// add adds a new entry for key to the partition.
-func (p *partition) add(key []byte, entry *entry) {
+// add adds a new entry for key to the partition, replacing any existing
+// entry. It returns true if the key was not in the partition.
+func (p *partition) add(key []byte, entry *entry) bool {
 	p.mu.Lock()
+	_, exists := p.store[string(key)]
 	p.store[string(key)] = entry
 	p.mu.Unlock()
+	return !exists
+}
+
+// addIfAbsent adds a new entry for key to the partition, unless the key is
+// already in the partition. It returns true if the entry was added.
+func (p *partition) addIfAbsent(key []byte, entry *entry) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.store[string(key)]; exists {
+		return false
+	}
+	p.store[string(key)] = entry
+	return true
 }
 
-// remove deletes the entry associated with the provided key.
+// remove deletes the entry associated with the provided key. It returns true
+// if the key was in the partition.
 // remove is safe for use by multiple goroutines.
-func (p *partition) remove(key []byte) {
+func (p *partition) remove(key []byte) bool {
 	p.mu.Lock()
+	_, exists := p.store[string(key)]
 	delete(p.store, string(key))
 	p.mu.Unlock()
+	return exists
 }
 
 // keys returns an unsorted slice of the keys in the partition.
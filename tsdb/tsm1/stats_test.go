@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb/pkg/hll"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 )
 
@@ -40,3 +41,69 @@ func TestMeasurementStats_WriteTo(t *testing.T) {
 		}
 	})
 }
+
+func TestKeyStats_WriteTo(t *testing.T) {
+	sketchComparer := cmp.Comparer(func(a, b *hll.Plus) bool { return a.Count() == b.Count() })
+
+	t.Run("Empty", func(t *testing.T) {
+		stats, other := tsm1.NewKeyStats(), tsm1.NewKeyStats()
+		var buf bytes.Buffer
+		if wn, err := stats.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		} else if rn, err := other.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		} else if wn != rn {
+			t.Fatalf("byte count mismatch: w=%d r=%d", wn, rn)
+		} else if diff := cmp.Diff(stats, other, sketchComparer); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("WithData", func(t *testing.T) {
+		stats, other := tsm1.NewKeyStats(), tsm1.NewKeyStats()
+		stats.AddKey([]byte("cpu,host=a,region=west#!~#value"))
+		stats.AddKey([]byte("cpu,zone=b#!~#value"))
+		stats.AddKey([]byte("mem#!~#value"))
+		stats.AddKey([]byte("bucket,\x00=cpu,host=a#!~#value"))
+		stats.AddKey([]byte("bucket,\x00=cpu,host=b#!~#value"))
+		stats.AddKey([]byte("bucket,\x00=mem,host=a#!~#value"))
+
+		var buf bytes.Buffer
+		if wn, err := stats.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		} else if rn, err := other.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		} else if wn != rn {
+			t.Fatalf("byte count mismatch: w=%d r=%d", wn, rn)
+		} else if diff := cmp.Diff(stats, other, sketchComparer); diff != "" {
+			t.Fatal(diff)
+		}
+
+		for _, tt := range []struct {
+			name, measurement, tagKey string
+			exp                       bool
+		}{
+			{"cpu", "", "host", true},
+			{"cpu", "", "zone", true},
+			{"cpu", "", "", true},
+			{"cpu", "", "dc", false},
+			{"mem", "", "", true},
+			{"mem", "", "host", false},
+			{"disk", "", "", false},
+			{"bucket", "cpu", "host", true},
+			{"bucket", "cpu", "", true},
+			{"bucket", "mem", "host", true},
+			{"bucket", "mem", "zone", false},
+			{"bucket", "disk", "", false},
+			{"cpu", "cpu", "", false},
+		} {
+			if got := other.Contains([]byte(tt.name), []byte(tt.measurement), []byte(tt.tagKey)); got != tt.exp {
+				t.Errorf("Contains(%q, %q, %q) = %v, exp %v", tt.name, tt.measurement, tt.tagKey, got, tt.exp)
+			}
+		}
+
+		if got, exp := other["bucket"].MeasurementSketch.Count(), uint64(2); got != exp {
+			t.Fatalf("measurement count mismatch: got %d, exp %d", got, exp)
+		}
+	})
+}
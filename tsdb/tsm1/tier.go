@@ -0,0 +1,258 @@
+package tsm1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Tier is the storage tier of a TSM file. Recently written data is kept in
+// the hot tier, which is usually on faster storage, and moves to the warm and
+// cold tiers as it ages.
+type Tier int
+
+const (
+	TierHot Tier = iota
+	TierWarm
+	TierCold
+)
+
+const (
+	// TierWarmAge is the age of the newest data of a TSM file after which it
+	// belongs in the warm tier.
+	TierWarmAge = time.Hour
+
+	// TierColdAge is the age of the newest data of a TSM file after which it
+	// belongs in the cold tier.
+	TierColdAge = 24 * time.Hour
+
+	// tierCheckInterval is how often the engine looks for TSM files to move
+	// to another tier.
+	tierCheckInterval = time.Minute
+)
+
+// String returns the name of the tier.
+func (t Tier) String() string {
+	switch t {
+	case TierHot:
+		return "hot"
+	case TierWarm:
+		return "warm"
+	case TierCold:
+		return "cold"
+	}
+	return fmt.Sprintf("Tier(%d)", int(t))
+}
+
+// TierConfig holds the directories of the warm and cold storage tiers. The
+// hot tier is the engine's directory. A tier without a directory shares the
+// directory of the tier before it, so without either all TSM files are kept
+// in the engine's directory.
+type TierConfig struct {
+	WarmPath string `toml:"warm-path"`
+	ColdPath string `toml:"cold-path"`
+}
+
+// tierByAge returns the tier that a TSM file whose newest data is at maxTime
+// belongs in.
+func tierByAge(maxTime int64, now time.Time) Tier {
+	switch age := now.Sub(time.Unix(0, maxTime)); {
+	case age >= TierColdAge:
+		return TierCold
+	case age >= TierWarmAge:
+		return TierWarm
+	}
+	return TierHot
+}
+
+// tierDirs holds the directory of each tier.
+type tierDirs [TierCold + 1]string
+
+func newTierDirs(hot string, c TierConfig) tierDirs {
+	d := tierDirs{TierHot: hot, TierWarm: c.WarmPath, TierCold: c.ColdPath}
+	if d[TierWarm] == "" {
+		d[TierWarm] = d[TierHot]
+	}
+	if d[TierCold] == "" {
+		d[TierCold] = d[TierWarm]
+	}
+	return d
+}
+
+// enabled returns true if the tiers are not all in one directory.
+func (d tierDirs) enabled() bool {
+	return d[TierWarm] != d[TierHot] || d[TierCold] != d[TierHot]
+}
+
+// tier returns the tier the file at path is stored in, which is the hottest
+// tier with its directory.
+func (d tierDirs) tier(path string) Tier {
+	dir := tsmFileTierDir(path)
+	for t, tierDir := range d {
+		if tierDir == dir {
+			return Tier(t)
+		}
+	}
+	return TierHot
+}
+
+// unique returns the distinct directories of the tiers.
+func (d tierDirs) unique() []string {
+	var dirs []string
+	for t, dir := range d {
+		if t == 0 || dir != d[t-1] {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// sortTSMPaths sorts the paths of TSM files by generation and sequence,
+// which are ordered by their names whichever tier and partition they are in.
+func sortTSMPaths(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		return tsmFileOrder(paths[i]) < tsmFileOrder(paths[j])
+	})
+}
+
+// MoveTiers moves the TSM files whose data has aged out of the tier they are
+// stored in to the directory of the tier they belong in. Files with
+// tombstones are moved once they are compacted, and files being compacted
+// once they are replaced.
+func (e *Engine) MoveTiers(ctx context.Context) error {
+	now := time.Now()
+
+	type move struct {
+		path string
+		tier Tier
+	}
+	var moves []move
+	for _, stat := range e.FileStore.Stats() {
+		tier := tierByAge(stat.MaxTime, now)
+		if stat.HasTombstone || tsmFileTierDir(stat.Path) == e.FileStore.tiers[tier] {
+			continue
+		}
+		moves = append(moves, move{path: stat.Path, tier: tier})
+	}
+
+	for _, m := range moves {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := e.moveTier(m.path, m.tier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveTier copies the TSM file at path, and its statistics, to the directory
+// of tier, and replaces the file with the copy.
+func (e *Engine) moveTier(path string, tier Tier) error {
+	// Claim the file so that it is not compacted while it is moved.
+	if !e.Compactor.add([]string{path}) {
+		return nil
+	}
+	defer e.Compactor.remove([]string{path})
+
+	// The file may have been replaced since it was planned to move.
+	r := e.FileStore.TSMReader(path)
+	if r == nil {
+		return nil
+	}
+	defer r.Unref()
+
+	// Files of a partition are moved to its directory in the tier.
+	dir := filepath.Join(e.FileStore.tiers[tier], tsmFilePartition(path))
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	dst := filepath.Join(dir, filepath.Base(path)+"."+TmpTSMFileExtension)
+	copies := [][2]string{
+		{path, dst},
+		{StatsFilename(path), StatsFilename(dst)},
+		{KeyStatsFilename(path), KeyStatsFilename(dst)},
+	}
+	remove := func() {
+		for _, c := range copies {
+			os.Remove(c[1])
+		}
+	}
+	for _, c := range copies {
+		if err := copyFile(c[0], c[1]); os.IsNotExist(err) && c[0] != path {
+			// The file was written without statistics.
+			continue
+		} else if err != nil {
+			remove()
+			return err
+		}
+	}
+
+	if err := e.FileStore.Replace([]string{path}, []string{dst}); err != nil {
+		remove()
+		return err
+	}
+	e.logger.Info("Moved TSM file to tier",
+		zap.String("tsm1_file", path),
+		zap.String("tier", tier.String()))
+	return nil
+}
+
+// moveTiers moves TSM files to the tier they belong in each
+// tierCheckInterval, until done is closed.
+func (e *Engine) moveTiers(done <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	t := time.NewTicker(tierCheckInterval)
+	defer t.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			if err := e.MoveTiers(ctx); err != nil && ctx.Err() == nil {
+				e.logger.Info("Error moving TSM files to tiers", zap.Error(err))
+			}
+		}
+	}
+}
+
+// copyFile copies the file at src to dst, which is synced to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
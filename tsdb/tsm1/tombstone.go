@@ -41,10 +41,22 @@ Tombstone file format:
 
 NOTE: v1, v2 and v3 tombstone supports have been dropped from 2.x. Only v4 is now
 supported.
+
+Encrypted tombstone files have their own header, followed by the salt and key
+check of the file key, as in the footer of encrypted TSM files. Each flush of
+tombstones appends a record: its length, followed by the nonce and ciphertext of
+the gzipped tombstone entries. The offset of the record is authenticated with
+it. Plaintext tombstone files are rewritten encrypted on their next flush.
+
+┌─────────┬─────────┬─────────┐┌─────────┬─────────┬──────────┐
+│ Header  │  Salt   │Key Check││ Length  │  Nonce  │ Entries  │
+│ 4 bytes │32 bytes │16 bytes ││ 4 bytes │12 bytes │ N bytes  │
+└─────────┴─────────┴─────────┘└─────────┴─────────┴──────────┘
 */
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/binary"
 	"errors"
@@ -62,6 +74,12 @@ import (
 const (
 	headerSize = 4
 	v4header   = 0x1504
+
+	// Header of encrypted v4 tombstone files
+	v4EncryptedHeader = 0x1584
+
+	// Size of the header, salt and key check of encrypted tombstone files
+	encryptedHeaderSize = headerSize + encryptionSaltSize + encryptionKeyCheckSize
 )
 
 var errIncompatibleV4Version = errors.New("incompatible v4 version")
@@ -95,6 +113,16 @@ type Tombstoner struct {
 
 	// Optional observer for when tombstone files are written.
 	obs FileStoreObserver
+
+	// Master key of encrypted tombstone files. Tombstones are written
+	// encrypted if it is set.
+	encryptionKey []byte
+
+	// cipher encrypts the pending tombstones, which are gzipped into sealed
+	// and appended as a record at offset sealedOffset of the pending file.
+	cipher       *tsmCipher
+	sealed       bytes.Buffer
+	sealedOffset int64
 }
 
 // NewTombstoner constructs a Tombstoner for the given path. FilterFn can be nil.
@@ -138,6 +166,11 @@ func (t *Tombstoner) WithObserver(obs FileStoreObserver) {
 	t.obs = obs
 }
 
+// WithEncryptionKey sets the master key of encrypted tombstone files.
+func (t *Tombstoner) WithEncryptionKey(key []byte) {
+	t.encryptionKey = key
+}
+
 // AddPrefixRange adds a prefix-based tombstone key with an explicit range.
 func (t *Tombstoner) AddPrefixRange(key []byte, min, max int64, predicate []byte) error {
 	t.mu.Lock()
@@ -311,6 +344,8 @@ func (t *Tombstoner) Walk(fn func(t Tombstone) error) error {
 	header := binary.BigEndian.Uint32(b[:])
 	if header == v4header {
 		return t.readTombstoneV4(f, fn)
+	} else if header == v4EncryptedHeader {
+		return t.readEncryptedTombstoneV4(f, fn)
 	}
 	return errors.New("invalid tombstone file")
 }
@@ -331,6 +366,12 @@ func (t *Tombstoner) prepareLatest() error {
 		os.Remove(tmp.Name())
 	}
 
+	// cipher encrypts the tombstones if an encryption key is set. plaintext
+	// holds the tombstones of a plaintext file that is rewritten encrypted.
+	var cipher *tsmCipher
+	var plaintext []Tombstone
+	var size int64
+
 	// Copy the existing v4 file if it exists
 	f, err := os.Open(t.tombstonePath())
 	if err != nil && !os.IsNotExist(err) {
@@ -345,22 +386,46 @@ func (t *Tombstoner) prepareLatest() error {
 			header := binary.BigEndian.Uint32(b[:])
 			// There is an existing tombstone on disk and it's not a v4.
 			// We can't support it.
-			if header != v4header {
+			if header != v4header && header != v4EncryptedHeader {
 				removeTmp()
 				return errIncompatibleV4Version
 			}
 
-			// Seek back to the beginning we copy the header
-			if _, err := f.Seek(0, io.SeekStart); err != nil {
-				removeTmp()
-				return err
-			}
+			if header == v4header && t.encryptionKey != nil {
+				// The plaintext tombstones are rewritten encrypted. They are
+				// all applied again on the next walk.
+				t.lastAppliedOffset = 0
+				if err := t.readTombstoneV4(f, func(ts Tombstone) error {
+					ts.Key = append([]byte(nil), ts.Key...)
+					ts.Predicate = append([]byte(nil), ts.Predicate...)
+					plaintext = append(plaintext, ts)
+					return nil
+				}); err != nil {
+					removeTmp()
+					return err
+				}
+				t.lastAppliedOffset = 0
+			} else {
+				// Encrypted tombstones are appended with the key of the file.
+				if header == v4EncryptedHeader {
+					if cipher, err = t.readTombstoneCipher(f); err != nil {
+						removeTmp()
+						return err
+					}
+				}
 
-			// Copy the whole file
-			if _, err := io.Copy(tmp, f); err != nil {
-				f.Close()
-				removeTmp()
-				return err
+				// Seek back to the beginning we copy the header
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					removeTmp()
+					return err
+				}
+
+				// Copy the whole file
+				if size, err = io.Copy(tmp, f); err != nil {
+					f.Close()
+					removeTmp()
+					return err
+				}
 			}
 		}
 	}
@@ -369,8 +434,22 @@ func (t *Tombstoner) prepareLatest() error {
 	var b [8]byte
 	bw := bufio.NewWriterSize(tmp, 64*1024)
 
-	// Write the header only if the file is new
-	if os.IsNotExist(err) {
+	if t.encryptionKey != nil && cipher == nil {
+		// Write the header, salt and key check of a new encrypted file.
+		if cipher, err = newTSMCipher(t.encryptionKey, nil); err != nil {
+			removeTmp()
+			return err
+		}
+		binary.BigEndian.PutUint32(b[:4], v4EncryptedHeader)
+		bw.Write(b[:4])
+		bw.Write(cipher.salt)
+		if _, err := bw.Write(cipher.keyCheck); err != nil {
+			removeTmp()
+			return err
+		}
+		size = encryptedHeaderSize
+	} else if os.IsNotExist(err) {
+		// Write the header only if the file is new
 		binary.BigEndian.PutUint32(b[:4], v4header)
 		if _, err := bw.Write(b[:4]); err != nil {
 			removeTmp()
@@ -378,9 +457,24 @@ func (t *Tombstoner) prepareLatest() error {
 		}
 	}
 
-	// Write the tombstones
-	gz := gzip.NewWriter(bw)
+	// Write the tombstones. Encrypted tombstones are sealed on commit.
+	var gz *gzip.Writer
+	if cipher != nil {
+		t.sealed.Reset()
+		t.sealedOffset = size
+		gz = gzip.NewWriter(&t.sealed)
+	} else {
+		gz = gzip.NewWriter(bw)
+	}
+
+	for _, ts := range plaintext {
+		if err := t.writeTombstoneV4(gz, ts); err != nil {
+			removeTmp()
+			return err
+		}
+	}
 
+	t.cipher = cipher
 	t.pendingFile = tmp
 	t.gz = gz
 	t.bw = bw
@@ -398,6 +492,12 @@ func (t *Tombstoner) commit() error {
 		return err
 	}
 
+	if t.cipher != nil {
+		if err := t.writeSealedTombstones(); err != nil {
+			return err
+		}
+	}
+
 	if err := t.bw.Flush(); err != nil {
 		return err
 	}
@@ -425,10 +525,57 @@ func (t *Tombstoner) commit() error {
 	t.pendingFile = nil
 	t.bw = nil
 	t.gz = nil
+	t.cipher = nil
 
 	return nil
 }
 
+// writeSealedTombstones writes the pending tombstones of an encrypted file as a
+// record: their length followed by their nonce and ciphertext.
+func (t *Tombstoner) writeSealedTombstones() error {
+	data, err := t.cipher.seal(t.sealed.Bytes(), tombstoneAdditionalData(t.sealedOffset))
+	if err != nil {
+		return err
+	} else if len(data) > math.MaxUint32 {
+		return fmt.Errorf("encrypted tombstones have size %d, maximum allowed size %d", len(data), math.MaxUint32)
+	}
+
+	binary.BigEndian.PutUint32(t.tmp[:4], uint32(len(data)))
+	if _, err := t.bw.Write(t.tmp[:4]); err != nil {
+		return err
+	}
+	_, err = t.bw.Write(data)
+	return err
+}
+
+// readTombstoneCipher returns the cipher of an encrypted tombstone file from
+// the salt and key check after its header.
+func (t *Tombstoner) readTombstoneCipher(f *os.File) (*tsmCipher, error) {
+	if t.encryptionKey == nil {
+		return nil, ErrEncryptionKeyRequired
+	}
+
+	b := make([]byte, encryptionSaltSize+encryptionKeyCheckSize)
+	if _, err := f.ReadAt(b, headerSize); err != nil {
+		return nil, err
+	}
+	c, err := newTSMCipher(t.encryptionKey, b[:encryptionSaltSize])
+	if err != nil {
+		return nil, err
+	} else if err := c.checkKey(b[encryptionSaltSize:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// tombstoneAdditionalData returns the data authenticated with the record of
+// encrypted tombstones at offset.
+func tombstoneAdditionalData(offset int64) []byte {
+	var ad [8]byte
+	binary.BigEndian.PutUint64(ad[:], uint64(offset))
+	return ad[:]
+}
+
 func (t *Tombstoner) rollback() error {
 	if t.pendingFile == nil {
 		return nil
@@ -438,6 +585,7 @@ func (t *Tombstoner) rollback() error {
 	t.pendingFile.Close()
 	t.gz = nil
 	t.bw = nil
+	t.cipher = nil
 	t.pendingFile = nil
 	return os.Remove(tmpFilename)
 }
@@ -456,8 +604,6 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 		}
 	}
 
-	const kmask = int64(0xff000000) // Mask for non key-length bits
-
 	br := bufio.NewReaderSize(f, 64*1024)
 	gr, err := gzip.NewReader(br)
 	if err == io.EOF {
@@ -467,79 +613,10 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 	}
 	defer gr.Close()
 
-	var ( // save these buffers across loop iterations to avoid allocations
-		keyBuf  []byte
-		predBuf []byte
-	)
-
+	var r tombstoneReader
 	for {
 		gr.Multistream(false)
-		if err := func() error {
-			for {
-				var buf [8]byte
-
-				if _, err = io.ReadFull(gr, buf[:4]); err == io.EOF || err == io.ErrUnexpectedEOF {
-					return nil
-				} else if err != nil {
-					return err
-				}
-
-				keyLen := int64(binary.BigEndian.Uint32(buf[:4]))
-				prefix := keyLen>>31&1 == 1 // Prefix is set according to whether the highest bit is set.
-				hasPred := keyLen>>30&1 == 1
-
-				// Remove 8 MSB to get correct length.
-				keyLen &^= kmask
-
-				if int64(len(keyBuf)) < keyLen {
-					keyBuf = make([]byte, keyLen)
-				}
-				// cap slice protects against invalid usages of append in callback
-				key := keyBuf[:keyLen:keyLen]
-
-				if _, err := io.ReadFull(gr, key); err != nil {
-					return err
-				}
-
-				if _, err := io.ReadFull(gr, buf[:8]); err != nil {
-					return err
-				}
-				min := int64(binary.BigEndian.Uint64(buf[:8]))
-
-				if _, err := io.ReadFull(gr, buf[:8]); err != nil {
-					return err
-				}
-				max := int64(binary.BigEndian.Uint64(buf[:8]))
-
-				var predicate []byte
-				if hasPred {
-					if _, err := io.ReadFull(gr, buf[:8]); err != nil {
-						return err
-					}
-					predLen := binary.BigEndian.Uint64(buf[:8])
-
-					if uint64(len(predBuf)) < predLen {
-						predBuf = make([]byte, predLen)
-					}
-					// cap slice protects against invalid usages of append in callback
-					predicate = predBuf[:predLen:predLen]
-
-					if _, err := io.ReadFull(gr, predicate); err != nil {
-						return err
-					}
-				}
-
-				if err := fn(Tombstone{
-					Key:       key,
-					Min:       min,
-					Max:       max,
-					Prefix:    prefix,
-					Predicate: predicate,
-				}); err != nil {
-					return err
-				}
-			}
-		}(); err != nil {
+		if err := r.read(gr, fn); err != nil {
 			return err
 		}
 
@@ -565,6 +642,140 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 	return nil
 }
 
+// readEncryptedTombstoneV4 reads encrypted v4 tombstone files. Each record
+// holds the gzipped tombstones of one flush.
+func (t *Tombstoner) readEncryptedTombstoneV4(f *os.File, fn func(t Tombstone) error) error {
+	c, err := t.readTombstoneCipher(f)
+	if err != nil {
+		return err
+	}
+
+	offset := t.lastAppliedOffset
+	if offset == 0 {
+		offset = encryptedHeaderSize
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	var r tombstoneReader
+	br := bufio.NewReaderSize(f, 64*1024)
+	for {
+		var buf [4]byte
+		if _, err := io.ReadFull(br, buf[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		data := make([]byte, binary.BigEndian.Uint32(buf[:]))
+		if _, err := io.ReadFull(br, data); err != nil {
+			return err
+		}
+		b, err := c.open(data, tombstoneAdditionalData(offset))
+		if err != nil {
+			return fmt.Errorf("cannot decrypt tombstones: %v", err)
+		}
+
+		gr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		if err := r.read(gr, fn); err != nil {
+			return err
+		}
+		gr.Close()
+
+		for _, t := range t.tombstones {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		offset += int64(len(buf) + len(data))
+	}
+
+	// Save the position of tombstone file so we don't re-apply the same set again if there are
+	// more deletes.
+	t.lastAppliedOffset = offset
+	return nil
+}
+
+// tombstoneReader reads tombstone entries. Its buffers are reused across reads.
+type tombstoneReader struct {
+	keyBuf  []byte
+	predBuf []byte
+}
+
+// read calls fn for each tombstone entry read from r until r is at EOF.
+func (tr *tombstoneReader) read(r io.Reader, fn func(t Tombstone) error) error {
+	const kmask = int64(0xff000000) // Mask for non key-length bits
+
+	for {
+		var buf [8]byte
+
+		if _, err := io.ReadFull(r, buf[:4]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		keyLen := int64(binary.BigEndian.Uint32(buf[:4]))
+		prefix := keyLen>>31&1 == 1 // Prefix is set according to whether the highest bit is set.
+		hasPred := keyLen>>30&1 == 1
+
+		// Remove 8 MSB to get correct length.
+		keyLen &^= kmask
+
+		if int64(len(tr.keyBuf)) < keyLen {
+			tr.keyBuf = make([]byte, keyLen)
+		}
+		// cap slice protects against invalid usages of append in callback
+		key := tr.keyBuf[:keyLen:keyLen]
+
+		if _, err := io.ReadFull(r, key); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(r, buf[:8]); err != nil {
+			return err
+		}
+		min := int64(binary.BigEndian.Uint64(buf[:8]))
+
+		if _, err := io.ReadFull(r, buf[:8]); err != nil {
+			return err
+		}
+		max := int64(binary.BigEndian.Uint64(buf[:8]))
+
+		var predicate []byte
+		if hasPred {
+			if _, err := io.ReadFull(r, buf[:8]); err != nil {
+				return err
+			}
+			predLen := binary.BigEndian.Uint64(buf[:8])
+
+			if uint64(len(tr.predBuf)) < predLen {
+				tr.predBuf = make([]byte, predLen)
+			}
+			// cap slice protects against invalid usages of append in callback
+			predicate = tr.predBuf[:predLen:predLen]
+
+			if _, err := io.ReadFull(r, predicate); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(Tombstone{
+			Key:       key,
+			Min:       min,
+			Max:       max,
+			Prefix:    prefix,
+			Predicate: predicate,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
 func (t *Tombstoner) tombstonePath() string {
 	if strings.HasSuffix(t.Path, "tombstone") {
 		return t.Path
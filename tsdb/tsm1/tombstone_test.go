@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"os"
 	"reflect"
 	"testing"
@@ -304,6 +306,89 @@ func TestTombstoner_Add_Multiple(t *testing.T) {
 	}
 }
 
+func TestTombstoner_Encrypted(t *testing.T) {
+	dir := MustTempDir()
+	defer func() { os.RemoveAll(dir) }()
+
+	f := MustTempFile(dir)
+	key := bytes.Repeat([]byte{0x0f}, tsm1.EncryptionKeySize)
+
+	// A plaintext tombstone file is rewritten encrypted by the next flush.
+	ts := tsm1.NewTombstoner(f.Name(), nil)
+	ts.Add([][]byte{[]byte("secretfoo")})
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing tombstone: %v", err)
+	}
+
+	ts = tsm1.NewTombstoner(f.Name(), nil)
+	ts.WithEncryptionKey(key)
+	if got, exp := len(mustReadAll(ts)), 1; got != exp {
+		t.Fatalf("length mismatch: got %v, exp %v", got, exp)
+	}
+
+	ts.Add([][]byte{[]byte("secretbar")})
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing tombstone: %v", err)
+	}
+
+	// The rewritten tombstones are all applied again.
+	entries := mustReadAll(ts)
+	if got, exp := len(entries), 2; got != exp {
+		t.Fatalf("length mismatch: got %v, exp %v", got, exp)
+	}
+
+	if err := ts.AddPrefixRange([]byte("secretbaz"), 1, 2, []byte("predicate")); err != nil {
+		t.Fatalf("unexpected error adding tombstone: %v", err)
+	} else if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing tombstone: %v", err)
+	}
+
+	entries = mustReadAll(ts)
+	if got, exp := len(entries), 1; got != exp {
+		t.Fatalf("length mismatch: got %v, exp %v", got, exp)
+	}
+
+	stats := ts.TombstoneFiles()
+	if got, exp := len(stats), 1; got != exp {
+		t.Fatalf("stat length mismatch: got %v, exp %v", got, exp)
+	}
+	buf, err := ioutil.ReadFile(stats[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf, []byte("secret")) || bytes.Contains(buf, []byte("predicate")) {
+		t.Fatalf("tombstone file contains plaintext tombstones")
+	}
+
+	// Use a new Tombstoner to verify values are persisted
+	ts = tsm1.NewTombstoner(f.Name(), nil)
+	ts.WithEncryptionKey(key)
+	entries = mustReadAll(ts)
+	exp := []tsm1.Tombstone{
+		{Key: []byte("secretfoo"), Min: math.MinInt64, Max: math.MaxInt64},
+		{Key: []byte("secretbar"), Min: math.MinInt64, Max: math.MaxInt64},
+		{Key: []byte("secretbaz"), Min: 1, Max: 2, Prefix: true, Predicate: []byte("predicate")},
+	}
+	if !reflect.DeepEqual(entries, exp) {
+		t.Fatalf("unexpected tombstones: got %v, exp %v", entries, exp)
+	}
+
+	// The tombstones are not read or added to without the key.
+	ts = tsm1.NewTombstoner(f.Name(), nil)
+	if err := ts.Walk(func(tsm1.Tombstone) error { return nil }); err != tsm1.ErrEncryptionKeyRequired {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptionKeyRequired)
+	} else if err := ts.Add([][]byte{[]byte("foo")}); err != tsm1.ErrEncryptionKeyRequired {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptionKeyRequired)
+	}
+
+	// Nor with a different key.
+	ts = tsm1.NewTombstoner(f.Name(), nil)
+	ts.WithEncryptionKey(bytes.Repeat([]byte{0xf0}, tsm1.EncryptionKeySize))
+	if err := ts.Walk(func(tsm1.Tombstone) error { return nil }); err != tsm1.ErrEncryptionKeyMismatch {
+		t.Fatalf("got error %v, exp %v", err, tsm1.ErrEncryptionKeyMismatch)
+	}
+}
+
 func TestTombstoner_Add_Empty(t *testing.T) {
 	dir := MustTempDir()
 	defer func() { os.RemoveAll(dir) }()
@@ -101,3 +101,19 @@ func (v *VerifyTSM) processFile(path string) error {
 
 	return nil
 }
+
+// verifyTSMBlocks returns an error if the checksum of any block of r does not
+// match its data.
+func verifyTSMBlocks(r *TSMReader) error {
+	iter := r.BlockIterator()
+	for iter.Next() {
+		key, _, _, _, checksum, buf, err := iter.Read()
+		if err != nil {
+			return err
+		}
+		if expected := crc32.ChecksumIEEE(buf); checksum != expected {
+			return fmt.Errorf("unexpected checksum %d, expected %d for key %q", checksum, expected, key)
+		}
+	}
+	return iter.Err()
+}
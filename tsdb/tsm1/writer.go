@@ -592,23 +592,39 @@ type tsmWriter struct {
 	// The bytes written count of when we last fsync'd
 	lastSync int64
 
-	stats MeasurementStats
+	stats    MeasurementStats
+	keyStats KeyStats
+	lastKey  []byte // last key added to keyStats
+
+	encryptionKey []byte
+	cipher        *tsmCipher // Encrypts the blocks and index, if set.
+}
+
+type tsmWriterOption func(t *tsmWriter)
+
+// WithTSMWriterEncryptionKey is an option for encrypting the blocks and index
+// of the TSM file with a key derived from the master key.
+var WithTSMWriterEncryptionKey = func(key []byte) tsmWriterOption {
+	return func(t *tsmWriter) {
+		t.encryptionKey = key
+	}
 }
 
 // NewTSMWriter returns a new TSMWriter writing to w.
-func NewTSMWriter(w io.Writer) (TSMWriter, error) {
+func NewTSMWriter(w io.Writer, options ...tsmWriterOption) (TSMWriter, error) {
 	index := NewIndexWriter()
-	return &tsmWriter{
-		wrapped: w,
-		w:       bufio.NewWriterSize(w, 1024*1024),
-		index:   index,
-		stats:   NewMeasurementStats(),
-	}, nil
+	return newTSMWriter(&tsmWriter{
+		wrapped:  w,
+		w:        bufio.NewWriterSize(w, 1024*1024),
+		index:    index,
+		stats:    NewMeasurementStats(),
+		keyStats: NewKeyStats(),
+	}, options)
 }
 
 // NewTSMWriterWithDiskBuffer returns a new TSMWriter writing to w and will use a disk
 // based buffer for the TSM index if possible.
-func NewTSMWriterWithDiskBuffer(w io.Writer) (TSMWriter, error) {
+func NewTSMWriterWithDiskBuffer(w io.Writer, options ...tsmWriterOption) (TSMWriter, error) {
 	var index IndexWriter
 	// Make sure is a File so we can write the temp index alongside it.
 	if fw, ok := w.(syncer); ok {
@@ -622,21 +638,54 @@ func NewTSMWriterWithDiskBuffer(w io.Writer) (TSMWriter, error) {
 		index = NewIndexWriter()
 	}
 
-	return &tsmWriter{
-		wrapped: w,
-		w:       bufio.NewWriterSize(w, 1024*1024),
-		index:   index,
-		stats:   NewMeasurementStats(),
-	}, nil
+	return newTSMWriter(&tsmWriter{
+		wrapped:  w,
+		w:        bufio.NewWriterSize(w, 1024*1024),
+		index:    index,
+		stats:    NewMeasurementStats(),
+		keyStats: NewKeyStats(),
+	}, options)
+}
+
+// newTSMWriter applies options to t.
+func newTSMWriter(t *tsmWriter, options []tsmWriterOption) (TSMWriter, error) {
+	for _, option := range options {
+		option(t)
+	}
+
+	if t.encryptionKey != nil {
+		c, err := newTSMCipher(t.encryptionKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		t.cipher = c
+	}
+	return t, nil
 }
 
 // MeasurementStats returns the measurement statistics generated by the writer.
 func (t *tsmWriter) MeasurementStats() MeasurementStats { return t.stats }
 
+// KeyStats returns the key statistics generated by the writer.
+func (t *tsmWriter) KeyStats() KeyStats { return t.keyStats }
+
+// addKeyStats adds key to the key stats. Keys are written in order so each key
+// only needs to be parsed once.
+func (t *tsmWriter) addKeyStats(key []byte) {
+	if bytes.Equal(key, t.lastKey) {
+		return
+	}
+	t.keyStats.AddKey(key)
+	t.lastKey = append(t.lastKey[:0], key...)
+}
+
 func (t *tsmWriter) writeHeader() error {
 	var buf [5]byte
 	binary.BigEndian.PutUint32(buf[0:4], MagicNumber)
 	buf[4] = Version
+	if t.cipher != nil {
+		buf[4] = EncryptedVersion
+	}
 
 	n, err := t.w.Write(buf[:])
 	if err != nil {
@@ -674,19 +723,10 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 		return err
 	}
 
-	var checksum [crc32.Size]byte
-	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(block))
-
-	_, err = t.w.Write(checksum[:])
-	if err != nil {
-		return err
-	}
-
-	n, err := t.w.Write(block)
+	n, err := t.writeBlock(block)
 	if err != nil {
 		return err
 	}
-	n += len(checksum)
 
 	// Record this block in index
 	t.index.Add(key, blockType, values[0].UnixNano(), values[len(values)-1].UnixNano(), t.n, uint32(n))
@@ -694,6 +734,7 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 	// Add block size to measurement stats.
 	name := models.ParseName(key)
 	t.stats[string(name)] += n
+	t.addKeyStats(key)
 
 	// Increment file position pointer
 	t.n += int64(n)
@@ -705,6 +746,32 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 	return nil
 }
 
+// writeBlock writes the checksum and data of block at the current position,
+// and returns the number of bytes written. The block is encrypted if the
+// writer has a cipher.
+func (t *tsmWriter) writeBlock(block []byte) (int, error) {
+	if t.cipher != nil {
+		var err error
+		if block, err = t.cipher.sealBlock(block, t.n); err != nil {
+			return 0, err
+		}
+	}
+
+	var checksum [crc32.Size]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(block))
+
+	_, err := t.w.Write(checksum[:])
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := t.w.Write(block)
+	if err != nil {
+		return 0, err
+	}
+	return n + len(checksum), nil
+}
+
 // WriteBlock writes block for the given key and time range to the TSM file.  If the write
 // exceeds max entries for a given key, ErrMaxBlocksExceeded is returned.  This indicates
 // that the index is now full for this key and no future writes to this key will succeed.
@@ -730,26 +797,18 @@ func (t *tsmWriter) WriteBlock(key []byte, minTime, maxTime int64, block []byte)
 		}
 	}
 
-	var checksum [crc32.Size]byte
-	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(block))
-
-	_, err = t.w.Write(checksum[:])
+	n, err := t.writeBlock(block)
 	if err != nil {
 		return err
 	}
 
-	n, err := t.w.Write(block)
-	if err != nil {
-		return err
-	}
-	n += len(checksum)
-
 	// Record this block in index
 	t.index.Add(key, blockType, minTime, maxTime, t.n, uint32(n))
 
 	// Add block size to measurement stats.
 	name := models.ParseName(key)
 	t.stats[string(name)] += n
+	t.addKeyStats(key)
 
 	// Increment file position pointer (checksum + block len)
 	t.n += int64(n)
@@ -785,7 +844,11 @@ func (t *tsmWriter) WriteIndex() error {
 	}
 
 	// Write the index
-	if _, err := t.index.WriteTo(t.w); err != nil {
+	if t.cipher != nil {
+		if err := t.writeEncryptedIndex(); err != nil {
+			return err
+		}
+	} else if _, err := t.index.WriteTo(t.w); err != nil {
 		return err
 	}
 
@@ -797,6 +860,28 @@ func (t *tsmWriter) WriteIndex() error {
 	return err
 }
 
+// writeEncryptedIndex writes the encrypted index followed by the salt of the
+// file key and the key check.
+func (t *tsmWriter) writeEncryptedIndex() error {
+	var buf bytes.Buffer
+	if _, err := t.index.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	index, err := t.cipher.sealIndex(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if _, err := t.w.Write(index); err != nil {
+		return err
+	}
+	if _, err := t.w.Write(t.cipher.salt); err != nil {
+		return err
+	}
+	_, err = t.w.Write(t.cipher.keyCheck)
+	return err
+}
+
 func (t *tsmWriter) Flush() error {
 	if err := t.w.Flush(); err != nil {
 		return err
@@ -833,12 +918,28 @@ func (t *tsmWriter) writeStatsFile() error {
 	}
 	defer f.Close()
 
-	if _, err := t.stats.WriteTo(f); err != nil {
+	// The stats hold the names, measurements and tag keys of the series, so they
+	// are encrypted along with the TSM file.
+	if err := writeSealed(f, t.encryptionKey, t.stats); err != nil {
 		return err
 	} else if err := f.Sync(); err != nil {
 		return err
+	} else if err := f.Close(); err != nil {
+		return err
 	}
-	return f.Close()
+
+	kf, err := fs.CreateFile(KeyStatsFilename(fw.Name()))
+	if err != nil {
+		return err
+	}
+	defer kf.Close()
+
+	if err := writeSealed(kf, t.encryptionKey, t.keyStats); err != nil {
+		return err
+	} else if err := kf.Sync(); err != nil {
+		return err
+	}
+	return kf.Close()
 }
 
 func (t *tsmWriter) Close() error {
@@ -883,6 +984,8 @@ func (t *tsmWriter) Remove() error {
 			return err
 		} else if err := os.Remove(StatsFilename(f.Name())); err != nil && !os.IsNotExist(err) {
 			return err
+		} else if err := os.Remove(KeyStatsFilename(f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
 		}
 	}
 	return nil
@@ -893,27 +996,27 @@ func (t *tsmWriter) Size() uint32 {
 }
 
 // verifyVersion verifies that the reader's bytes are a TSM byte
-// stream of the correct version (1)
-func verifyVersion(r io.ReadSeeker) error {
+// stream of the correct version, and returns the version.
+func verifyVersion(r io.ReadSeeker) (byte, error) {
 	_, err := r.Seek(0, 0)
 	if err != nil {
-		return fmt.Errorf("init: failed to seek: %v", err)
+		return 0, fmt.Errorf("init: failed to seek: %v", err)
 	}
 	var b [4]byte
 	_, err = io.ReadFull(r, b[:])
 	if err != nil {
-		return fmt.Errorf("init: error reading magic number of file: %v", err)
+		return 0, fmt.Errorf("init: error reading magic number of file: %v", err)
 	}
 	if binary.BigEndian.Uint32(b[:]) != MagicNumber {
-		return fmt.Errorf("can only read from tsm file")
+		return 0, fmt.Errorf("can only read from tsm file")
 	}
 	_, err = io.ReadFull(r, b[:1])
 	if err != nil {
-		return fmt.Errorf("init: error reading version: %v", err)
+		return 0, fmt.Errorf("init: error reading version: %v", err)
 	}
-	if b[0] != Version {
-		return fmt.Errorf("init: file is version %b. expected %b", b[0], Version)
+	if b[0] != Version && b[0] != EncryptedVersion {
+		return 0, fmt.Errorf("init: file is version %b. expected %b or %b", b[0], Version, EncryptedVersion)
 	}
 
-	return nil
+	return b[0], nil
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"os"
@@ -645,3 +646,140 @@ func TestTSMWriter_Sync(t *testing.T) {
 		t.Fatal("failed to sync")
 	}
 }
+
+func TestTSMWriter_Write_Encrypted(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	f := MustTempFile(dir)
+
+	key := bytes.Repeat([]byte{0x42}, tsm1.EncryptionKeySize)
+	w, err := tsm1.NewTSMWriter(f, tsm1.WithTSMWriterEncryptionKey(key))
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	values := []tsm1.Value{tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)}
+	if err := w.Write([]byte("cpu,host=secrethost#!~#value"), values); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	if got, exp := b[4], tsm1.EncryptedVersion; got != exp {
+		t.Fatalf("version mismatch: got %v, exp %v", got, exp)
+	}
+	if bytes.Contains(b, []byte("secrethost")) {
+		t.Fatal("encrypted file contains the series key")
+	}
+
+	for _, tt := range []struct {
+		key []byte
+		exp error
+	}{
+		{key: nil, exp: tsm1.ErrEncryptionKeyRequired},
+		{key: bytes.Repeat([]byte{0x43}, tsm1.EncryptionKeySize), exp: tsm1.ErrEncryptionKeyMismatch},
+	} {
+		fd, err := os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+		if _, err := tsm1.NewTSMReader(fd, tsm1.WithTSMReaderEncryptionKey(tt.key)); err != tt.exp {
+			t.Fatalf("got error %v opening encrypted file without its key, exp %v", err, tt.exp)
+		}
+		fd.Close()
+	}
+
+	fd, err := os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error open file: %v", err)
+	}
+	r, err := tsm1.NewTSMReader(fd, tsm1.WithTSMReaderEncryptionKey(key))
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	readValues, err := r.ReadAll([]byte("cpu,host=secrethost#!~#value"))
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	if diff := cmp.Diff(values, readValues, cmp.AllowUnexported(tsm1.FloatValue{})); diff != "" {
+		t.Fatalf("unexpected values: %s", diff)
+	}
+
+	// Blocks are read decrypted, with the checksum of their plaintext.
+	iter := r.BlockIterator()
+	for iter.Next() {
+		_, _, _, _, checksum, buf, err := iter.Read()
+		if err != nil {
+			t.Fatalf("unexpected error reading block: %v", err)
+		}
+		if exp := crc32.ChecksumIEEE(buf); checksum != exp {
+			t.Fatalf("checksum mismatch: got %v, exp %v", checksum, exp)
+		}
+		if _, err := tsm1.BlockType(buf); err != nil {
+			t.Fatalf("unexpected error decoding block: %v", err)
+		}
+	}
+}
+
+func TestTSMWriter_Write_Encrypted_Tampered(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, tsm1.EncryptionKeySize)
+	var buf bytes.Buffer
+	w, err := tsm1.NewTSMWriter(&buf, tsm1.WithTSMWriterEncryptionKey(key))
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+	if err := w.Write([]byte("cpu"), []tsm1.Value{tsm1.NewValue(0, 1.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// open writes b to a file and opens it with the key.
+	open := func(b []byte) (*tsm1.TSMReader, error) {
+		dir := MustTempDir()
+		defer os.RemoveAll(dir)
+		f := MustTempFile(dir)
+		if _, err := f.Write(b); err != nil {
+			t.Fatalf("unexpected error writing file: %v", err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("unexpected error seeking file: %v", err)
+		}
+		return tsm1.NewTSMReader(f, tsm1.WithTSMReaderEncryptionKey(key))
+	}
+
+	// A corrupt index is not reported as a different key.
+	b := append([]byte(nil), buf.Bytes()...)
+	indexStart := binary.BigEndian.Uint64(b[len(b)-8:])
+	b[indexStart+20] ^= 0xff
+	if _, err := open(b); err == nil || err == tsm1.ErrEncryptionKeyMismatch {
+		t.Fatalf("got error %v opening file with corrupt index, exp corruption", err)
+	}
+
+	// A block whose flag is cleared is not read as plaintext. The flag of the
+	// first block follows the header and the checksum of the block.
+	b = append([]byte(nil), buf.Bytes()...)
+	b[5+4] = 0
+	r, err := open(b)
+	if err != nil {
+		t.Fatalf("unexpected error opening file: %v", err)
+	}
+	defer r.Close()
+	if _, err := r.ReadAll([]byte("cpu")); err == nil {
+		t.Fatal("expected error reading block with cleared flag")
+	}
+}
@@ -13,10 +13,16 @@ var (
 	ErrInvalidMappingType = errors.New("unknown mapping type")
 	// ErrUserIDRequired notes that the ID was not provided
 	ErrUserIDRequired = errors.New("user id is required")
+	// ErrUserOrGroupIDRequired notes that exactly one of user id and group id was not provided
+	ErrUserOrGroupIDRequired = errors.New("exactly one of user id or group id is required")
 	// ErrResourceIDRequired notes that the provided ID was not provided
 	ErrResourceIDRequired = errors.New("resource id is required")
 )
 
+// URMMaxPageSize is the maximum number of user resource mappings in a page of
+// results.
+const URMMaxPageSize = 1000
+
 // UserType can either be owner or member.
 type UserType string
 
@@ -101,8 +107,12 @@ type UserResourceMappingService interface {
 }
 
 // UserResourceMapping represents a mapping of a resource to its user.
+//
+// A mapping may instead grant access to a group of users by setting GroupID
+// and leaving UserID unset.
 type UserResourceMapping struct {
-	UserID       ID           `json:"userID"`
+	UserID       ID           `json:"userID,omitempty"`
+	GroupID      *ID          `json:"groupID,omitempty"`
 	UserType     UserType     `json:"userType"`
 	MappingType  MappingType  `json:"mappingType"`
 	ResourceType ResourceType `json:"resourceType"`
@@ -115,7 +125,11 @@ func (m UserResourceMapping) Validate() error {
 		return ErrResourceIDRequired
 	}
 
-	if !m.UserID.Valid() {
+	if m.GroupID != nil {
+		if m.UserID.Valid() || !m.GroupID.Valid() {
+			return ErrUserOrGroupIDRequired
+		}
+	} else if !m.UserID.Valid() {
 		return ErrUserIDRequired
 	}
 
@@ -134,11 +148,20 @@ func (m UserResourceMapping) Validate() error {
 	return nil
 }
 
+// PrincipalID returns the id of the user or group the resource is mapped to.
+func (m UserResourceMapping) PrincipalID() ID {
+	if m.GroupID != nil {
+		return *m.GroupID
+	}
+	return m.UserID
+}
+
 // UserResourceMappingFilter represents a set of filters that restrict the returned results.
 type UserResourceMappingFilter struct {
 	ResourceID   ID
 	ResourceType ResourceType
 	UserID       ID
+	GroupID      *ID
 	UserType     UserType
 }
 
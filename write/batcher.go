@@ -16,6 +16,8 @@ const (
 	DefaultMaxBytes = 500000
 	// DefaultInterval will flush every 10 seconds.
 	DefaultInterval = 10 * time.Second
+	// DefaultRetryInterval waits a second before retrying a failed flush.
+	DefaultRetryInterval = time.Second
 )
 
 // batcher is a write service that batches for another write service.
@@ -24,7 +26,11 @@ var _ platform.WriteService = (*Batcher)(nil)
 // Batcher batches line protocol for sends to output.
 type Batcher struct {
 	MaxFlushBytes    int                   // MaxFlushBytes is the maximum number of bytes to buffer before flushing
+	MaxFlushLines    int                   // MaxFlushLines is the maximum number of points to buffer before flushing, if not zero
 	MaxFlushInterval time.Duration         // MaxFlushInterval is the maximum amount of time to wait before flushing
+	MaxRetries       int                   // MaxRetries is the maximum number of times a failed flush is retried
+	RetryInterval    time.Duration         // RetryInterval is the time to wait before the first retry; it doubles for each retry after it
+	Progress         func(points int)      // Progress is called with the number of points written after each flush, if not nil
 	Service          platform.WriteService // Service receives batches flushed from Batcher.
 }
 
@@ -98,6 +104,10 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 	buf := make([]byte, 0, maxBytes)
 	r := bytes.NewReader(buf)
 
+	// points is the number of points in buf, and written the number of
+	// points flushed.
+	var points, written int
+
 	var line []byte
 	var more = true
 	// if read closes the channel normally, exit the loop
@@ -106,26 +116,33 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 		case line, more = <-lines:
 			if more {
 				buf = append(buf, line...)
+				if isPoint(line) {
+					points++
+				}
 			}
-			// write if we exceed the max lines OR read routine has finished
-			if len(buf) >= maxBytes || (!more && len(buf) > 0) {
-				r.Reset(buf)
+			// write if we exceed the max bytes or points OR read routine has finished
+			if len(buf) >= maxBytes || (b.MaxFlushLines > 0 && points >= b.MaxFlushLines) || (!more && len(buf) > 0) {
 				timer.Reset(flushInterval)
-				if err := b.Service.Write(ctx, org, bucket, r); err != nil {
+				if err := b.flush(ctx, org, bucket, r, buf); err != nil {
 					errC <- err
 					return
 				}
 				buf = buf[:0]
+				written += points
+				points = 0
+				b.progress(written)
 			}
 		case <-timer.C:
 			if len(buf) > 0 {
-				r.Reset(buf)
 				timer.Reset(flushInterval)
-				if err := b.Service.Write(ctx, org, bucket, r); err != nil {
+				if err := b.flush(ctx, org, bucket, r, buf); err != nil {
 					errC <- err
 					return
 				}
 				buf = buf[:0]
+				written += points
+				points = 0
+				b.progress(written)
 			}
 		case <-ctx.Done():
 			errC <- ctx.Err()
@@ -136,6 +153,59 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 	errC <- nil
 }
 
+// flush writes buf to the service with r. If the write fails with an error
+// that may be temporary, it is retried up to MaxRetries times, waiting twice
+// as long before each retry as before the last one.
+func (b *Batcher) flush(ctx context.Context, org, bucket platform.ID, r *bytes.Reader, buf []byte) error {
+	interval := b.RetryInterval
+	if interval == 0 {
+		interval = DefaultRetryInterval
+	}
+
+	for i := 0; ; i++ {
+		r.Reset(buf)
+		err := b.Service.Write(ctx, org, bucket, r)
+		if err == nil || i >= b.MaxRetries || !platform.IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		interval *= 2
+	}
+}
+
+func (b *Batcher) progress(points int) {
+	if b.Progress != nil {
+		b.Progress(points)
+	}
+}
+
+// isPoint returns whether the line of line protocol is a point, rather than
+// a blank line or a comment.
+func isPoint(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return len(line) > 0 && line[0] != '#'
+}
+
+// CountPoints returns the number of points in the line protocol read from r.
+func CountPoints(r io.Reader) (int, error) {
+	var n int
+	scanner := bufio.NewScanner(r)
+	scanner.Split(ScanLines)
+	for scanner.Scan() {
+		if isPoint(scanner.Bytes()) {
+			n++
+		}
+	}
+	return n, scanner.Err()
+}
+
 // ScanLines is used in bufio.Scanner.Split to split lines of line protocol.
 func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
@@ -303,6 +303,7 @@ func TestBatcher_write(t *testing.T) {
 func TestBatcher_Write(t *testing.T) {
 	type fields struct {
 		MaxFlushBytes    int
+		MaxFlushLines    int
 		MaxFlushInterval time.Duration
 	}
 	type args struct {
@@ -345,6 +346,19 @@ func TestBatcher_Write(t *testing.T) {
 			want:        "m3,t3=v3 f3=3",
 			wantFlushes: 3,
 		},
+		{
+			name: "points beyond max lines cause multiple flushes",
+			fields: fields{
+				MaxFlushLines: 2,
+			},
+			args: args{
+				org:    platform.ID(1),
+				bucket: platform.ID(2),
+				r:      strings.NewReader("# comment\nm1,t1=v1 f1=1\n\nm2,t2=v2 f2=2\nm3,t3=v3 f3=3\nm4,t4=v4 f4=4\nm5,t5=v5 f5=5"),
+			},
+			want:        "m5,t5=v5 f5=5",
+			wantFlushes: 3,
+		},
 		{
 			name:   "errors during read return error",
 			fields: fields{},
@@ -378,6 +392,7 @@ func TestBatcher_Write(t *testing.T) {
 
 			b := &Batcher{
 				MaxFlushBytes:    tt.fields.MaxFlushBytes,
+				MaxFlushLines:    tt.fields.MaxFlushLines,
 				MaxFlushInterval: tt.fields.MaxFlushInterval,
 				Service:          svc,
 			}
@@ -397,6 +412,76 @@ func TestBatcher_Write(t *testing.T) {
 	}
 }
 
+func TestBatcher_WriteRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		errs        []error
+		maxRetries  int
+		wantWrites  int
+		wantErr     bool
+		wantWritten []int
+	}{
+		{
+			name:        "temporary errors are retried",
+			errs:        []error{fmt.Errorf("connection refused"), &platform.Error{Code: platform.EUnavailable}},
+			maxRetries:  3,
+			wantWrites:  4,
+			wantWritten: []int{2, 3},
+		},
+		{
+			name:       "writes are retried up to max retries",
+			errs:       []error{fmt.Errorf("error"), fmt.Errorf("error"), fmt.Errorf("error")},
+			maxRetries: 2,
+			wantWrites: 3,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid writes are not retried",
+			errs:       []error{&platform.Error{Code: platform.EInvalid}},
+			maxRetries: 3,
+			wantWrites: 1,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				writes  int
+				written []int
+			)
+			svc := &mock.WriteService{
+				WriteF: func(ctx context.Context, org, bucket platform.ID, r io.Reader) error {
+					writes++
+					if writes <= len(tt.errs) {
+						return tt.errs[writes-1]
+					}
+					_, err := ioutil.ReadAll(r)
+					return err
+				},
+			}
+
+			b := &Batcher{
+				MaxFlushLines: 2,
+				MaxRetries:    tt.maxRetries,
+				RetryInterval: time.Millisecond,
+				Progress:      func(points int) { written = append(written, points) },
+				Service:       svc,
+			}
+
+			err := b.Write(context.Background(), platform.ID(1), platform.ID(2), strings.NewReader("m1 f=1\nm2 f=2\nm3 f=3"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Batcher.Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if writes != tt.wantWrites {
+				t.Errorf("Batcher.Write() writes %d want %d", writes, tt.wantWrites)
+			}
+			if !cmp.Equal(written, tt.wantWritten) {
+				t.Errorf("Batcher.Write() progress -got/+want %s", cmp.Diff(written, tt.wantWritten))
+			}
+		})
+	}
+}
+
 func TestBatcher_WriteTimeout(t *testing.T) {
 	// mocking the write service here to either return an error
 	// or get back all the bytes from the reader.